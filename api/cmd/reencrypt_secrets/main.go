@@ -0,0 +1,99 @@
+// Mã hóa lại mọi secret lưu trong DB bằng key active của keyring (sau khi thêm key mới vào ENCRYPTION_KEYS
+// và đổi ENCRYPTION_ACTIVE_KEY_ID). Plaintext cũ chưa mã hóa cũng được mã hóa.
+//
+// Chạy từ thư mục api (cùng env với server):
+//
+//	go run ./cmd/reencrypt_secrets -dry-run
+//	go run ./cmd/reencrypt_secrets
+//
+// Key cũ chỉ được gỡ khỏi ENCRYPTION_KEYS khi lần chạy không còn "failed" và "updated" = 0.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"meta_commerce/config"
+	metasvc "meta_commerce/internal/api/meta/service"
+	webhooksvc "meta_commerce/internal/api/webhook/service"
+	"meta_commerce/internal/global"
+	"meta_commerce/internal/keyring"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "Chỉ đếm số bản ghi cần mã hóa lại, không ghi")
+	flag.Parse()
+
+	cfg := config.NewConfig()
+	if cfg == nil {
+		log.Fatal("Không thể đọc cấu hình (config.NewConfig)")
+	}
+	if err := keyring.Init(cfg.EncryptionKeys, cfg.EncryptionActiveKeyID, cfg.JwtSecret); err != nil {
+		log.Fatalf("Keyring: %v", err)
+	}
+	kr, _ := keyring.Default()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoDB_ConnectionURI))
+	if err != nil {
+		log.Fatalf("Kết nối MongoDB: %v", err)
+	}
+	defer client.Disconnect(ctx)
+	db := client.Database(cfg.MongoDB_DBName_Auth)
+
+	// Tên collection giống cmd/server/init.go (initColNames)
+	global.MongoDB_ColNames.NotificationSenders = "notification_cfg_senders"
+	global.MongoDB_ColNames.NotificationDigests = "notification_job_digests"
+	global.MongoDB_ColNames.DeliveryQueue = "delivery_job_queue"
+	global.MongoDB_ColNames.DeliveryDeadLetters = "delivery_job_dead_letters"
+	global.MongoDB_ColNames.AIProviderProfiles = "ai_cfg_provider_profiles"
+	global.MongoDB_ColNames.FbPages = "fb_src_pages"
+	global.MongoDB_ColNames.AccessTokens = "auth_core_access_tokens"
+	global.MongoDB_ColNames.OrganizationConfigItems = "auth_cfg_organization_items"
+
+	cols := global.MongoDB_ColNames
+	targets := []keyring.ReencryptTarget{
+		{Collection: db.Collection(cols.NotificationSenders), SecretFields: []string{"smtpPassword", "botToken", "webhookSecret", "webhookAuthToken", "webhookAuthPassword"}},
+		{Collection: db.Collection(cols.NotificationDigests), BlobFields: []string{"senderConfig"}},
+		{Collection: db.Collection(cols.DeliveryQueue), Filter: bson.M{"status": bson.M{"$in": []string{"pending", "processing", "dead_letter"}}}, BlobFields: []string{"senderConfig"}},
+		{Collection: db.Collection(cols.DeliveryDeadLetters), BlobFields: []string{"senderConfig"}},
+		{Collection: db.Collection(cols.AIProviderProfiles), SecretFields: []string{"apiKey"}},
+		{Collection: db.Collection(cols.FbPages), SecretFields: []string{"accessToken", "pageAccessToken"}},
+		{Collection: db.Collection(cols.AccessTokens), SecretFields: []string{"value"}},
+		{Collection: db.Collection(cols.OrganizationConfigItems), Filter: bson.M{"key": bson.M{"$in": []string{webhooksvc.ConfigKeyPancakeWebhookSecret, webhooksvc.ConfigKeyPancakePosWebhookSecret}}}, SecretFields: []string{"value"}},
+	}
+
+	fmt.Printf("DB: %s | key active: %s | keys: %v | dry-run: %v\n\n", cfg.MongoDB_DBName_Auth, kr.ActiveKeyID(), kr.KeyIDs(), *dryRun)
+	failed := false
+	for _, t := range targets {
+		stats, err := kr.Reencrypt(ctx, t, *dryRun)
+		if err != nil {
+			log.Fatalf("Mã hóa lại %s: %v", t.Collection.Name(), err)
+		}
+		fmt.Printf("%-28s scanned=%-6d updated=%-6d failed=%d\n", stats.Collection, stats.Scanned, stats.Updated, stats.Failed)
+		if stats.Failed > 0 {
+			failed = true
+		}
+	}
+
+	changed, err := metasvc.ReencryptMetaTokenFile(cfg.MetaTokenFile, *dryRun)
+	if err != nil {
+		log.Fatalf("Meta token file: %v", err)
+	}
+	fmt.Printf("%-28s updated=%v\n", cfg.MetaTokenFile, changed)
+
+	if failed {
+		fmt.Println("\n⚠️  Có bản ghi không giải mã được (thiếu key cũ trong ENCRYPTION_KEYS) — giữ nguyên, chưa gỡ key cũ.")
+		os.Exit(1)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	adsMigration "meta_commerce/internal/api/ads_meta/migration"
 	basesvc "meta_commerce/internal/api/base/service"
 	aidecisionsvc "meta_commerce/internal/api/aidecision/service"
+	authsvc "meta_commerce/internal/api/auth/service"
 	"meta_commerce/internal/api/initsvc"
 	ruleintelmigration "meta_commerce/internal/api/ruleintel/migration"
 	"meta_commerce/internal/global"
@@ -62,6 +63,11 @@ func InitDefaultData() {
 	} else {
 		log.Info("✅ [INIT] Step 1b: RULE_DATACHANGED_SIDE_EFFECT_POLICY (trì hoãn ingest/report/refresh) đã seed")
 	}
+	if err := ruleintelmigration.SeedRuleTestCases(seedCtx); err != nil {
+		log.WithError(err).Warn("⚠️ [INIT] Step 1b: SeedRuleTestCases thất bại (optional)")
+	} else {
+		log.Info("✅ [INIT] Step 1b: Rule test cases (Ads kill, CRM, CIX) đã seed")
+	}
 
 	// 1c. Backfill priorityRank cho decision_events_queue (bản ghi pending cũ không có trường số).
 	log.Info("🔄 [INIT] Step 1c: Backfill decision_events_queue.priorityRank...")
@@ -73,6 +79,18 @@ func InitDefaultData() {
 		log.Info("✅ [INIT] Step 1c: Không có bản ghi nào cần backfill priorityRank")
 	}
 
+	// 1d. Chuyển token đăng nhập cũ (User.token/tokens, không hết hạn) sang phiên — người dùng không phải đăng nhập lại.
+	// Chỉ chạy khi bật AUTH_LEGACY_TOKEN_MIGRATE (một lần khi nâng cấp); tắt thì token cũ được chuyển ở lần dùng đầu (Bearer hoặc /auth/refresh).
+	if !global.MongoDB_ServerConfig.AuthLegacyTokenMigrate {
+		log.Info("✅ [INIT] Step 1d: Bỏ qua migrate legacy user tokens (AUTH_LEGACY_TOKEN_MIGRATE=false, token cũ chuyển ở lần dùng đầu)")
+	} else if sessionService, err := authsvc.NewAuthSessionService(); err != nil {
+		log.WithError(err).Warn("⚠️ [INIT] Step 1d: Không tạo được AuthSessionService")
+	} else if n, err := sessionService.MigrateLegacyTokens(context.Background()); err != nil {
+		log.WithError(err).Warn("⚠️ [INIT] Step 1d: MigrateLegacyTokens thất bại (sẽ chạy lại lần khởi động sau)")
+	} else {
+		log.Infof("✅ [INIT] Step 1d: Đã chuyển %d token cũ sang phiên", n)
+	}
+
 	// 2. Khởi tạo Permissions (tạo các quyền mới nếu chưa có, bao gồm Customer, FbMessageItem, ...)
 	log.Info("🔄 [INIT] Step 2: Initializing permissions...")
 	if err := initService.InitPermission(); err != nil {
@@ -29,6 +29,7 @@ import (
 	notificationrouter "meta_commerce/internal/api/notification/router"
 	pcrouter "meta_commerce/internal/api/pc/router"
 	reportrouter "meta_commerce/internal/api/report/router"
+	"meta_commerce/internal/api/middleware"
 	"meta_commerce/internal/api/router"
 	ruleintelrouter "meta_commerce/internal/api/ruleintel/router"
 	webhookrouter "meta_commerce/internal/api/webhook/router"
@@ -221,8 +222,10 @@ func InitFiberApp() *fiber.App {
 		return err
 	})
 
-	// 5. Rate Limiting Middleware - Giới hạn số request
+	// 5. Rate Limiting Middleware - Giới hạn số request theo IP
 	// Chỉ bật rate limit nếu được enable và Max > 0
+	// Khi bật rate limit theo tổ chức / user / API key (AuthMiddleware), request có token / API key hợp lệ không bị đếm theo IP
+	// — tránh một agent ồn sau NAT làm nghẽn mọi người dùng chung IP. Token không hợp lệ vẫn bị đếm theo IP.
 	if global.MongoDB_ServerConfig.RateLimit_Enabled && global.MongoDB_ServerConfig.RateLimit_Max > 0 {
 		rateLimitMax := global.MongoDB_ServerConfig.RateLimit_Max
 		rateLimitWindow := time.Duration(global.MongoDB_ServerConfig.RateLimit_Window) * time.Second
@@ -247,7 +250,9 @@ func InitFiberApp() *fiber.App {
 				return c.Path() == "/health" ||
 					c.Path() == "/api/v1/system/health" ||
 					c.Path() == "/api/v1/internal/metrics/job-metrics" ||
-					c.Method() == "OPTIONS"
+					c.Path() == "/api/v1/internal/metrics/cache-metrics" ||
+					c.Method() == "OPTIONS" ||
+					(global.MongoDB_ServerConfig.RateLimit_TenantEnabled && middleware.IsAuthenticatedRequest(c))
 			},
 		}))
 		log := logger.GetAppLogger()
@@ -283,7 +288,8 @@ func InitFiberApp() *fiber.App {
 			return c.Path() == "/health" ||
 				c.Path() == "/metrics" ||
 				c.Path() == "/api/v1/system/health" ||
-				c.Path() == "/api/v1/internal/metrics/job-metrics"
+				c.Path() == "/api/v1/internal/metrics/job-metrics" ||
+				c.Path() == "/api/v1/internal/metrics/cache-metrics"
 		},
 	}))
 
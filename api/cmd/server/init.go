@@ -24,8 +24,10 @@ import (
 	ordermodels "meta_commerce/internal/api/order/models"
 	aidecisionmodels "meta_commerce/internal/api/aidecision/models"
 	learningmodels "meta_commerce/internal/api/learning/models"
+	webhookmodels "meta_commerce/internal/api/webhook/models"
 	"meta_commerce/internal/database"
 	"meta_commerce/internal/global"
+	"meta_commerce/internal/keyring"
 	"meta_commerce/internal/utility"
 	"os"
 	"path/filepath"
@@ -53,6 +55,14 @@ func initColNames() {
 	global.MongoDB_ColNames.Organizations = "auth_core_organizations"
 	global.MongoDB_ColNames.OrganizationConfigItems = "auth_cfg_organization_items"
 	global.MongoDB_ColNames.AccessTokens = "auth_core_access_tokens"
+	global.MongoDB_ColNames.AuthSessions = "auth_core_sessions"
+	global.MongoDB_ColNames.AuthRevokedSessions = "auth_core_revoked_sessions"
+	global.MongoDB_ColNames.ServiceAccounts = "auth_core_service_accounts"
+	global.MongoDB_ColNames.APIKeys = "auth_core_api_keys"
+	global.MongoDB_ColNames.RateLimitPolicies = "auth_core_rate_limit_policies"
+	global.MongoDB_ColNames.RateLimitCounters = "auth_core_rate_limit_counters"
+	global.MongoDB_ColNames.RecordShareGrants = "auth_record_share_grants"
+	global.MongoDB_ColNames.RecordShareAccesses = "auth_record_share_access_logs"
 	global.MongoDB_ColNames.FbPages = "fb_src_pages"
 	global.MongoDB_ColNames.FbConvesations = "fb_src_conversations"
 	global.MongoDB_ColNames.FbMessages = "fb_src_messages"
@@ -80,10 +90,15 @@ func initColNames() {
 	global.MongoDB_ColNames.NotificationChannels = "notification_cfg_channels"
 	global.MongoDB_ColNames.NotificationTemplates = "notification_cfg_templates"
 	global.MongoDB_ColNames.NotificationRoutingRules = "notification_cfg_routing_rules"
+	global.MongoDB_ColNames.NotificationDedupMarks = "notification_run_dedup_marks"
+	global.MongoDB_ColNames.NotificationDigests = "notification_job_digests"
+	global.MongoDB_ColNames.NotificationPreferences = "notification_cfg_preferences"
+	global.MongoDB_ColNames.NotificationInbox = "notification_run_inbox"
 
 	// Delivery System Collections (Hệ thống 1 - Gửi)
 	global.MongoDB_ColNames.DeliveryQueue = "delivery_job_queue"
 	global.MongoDB_ColNames.DeliveryHistory = "delivery_run_history"
+	global.MongoDB_ColNames.DeliveryDeadLetters = "delivery_job_dead_letters"
 
 	// CTA Module Collections
 	global.MongoDB_ColNames.CTALibrary = "cta_core_library"
@@ -98,6 +113,7 @@ func initColNames() {
 
 	// Webhook Logs Collection
 	global.MongoDB_ColNames.WebhookLogs = "webhook_run_logs"
+	global.MongoDB_ColNames.WebhookNonces = "webhook_run_nonces"
 
 	// Module 1: Content Storage Collections (tất cả đều có prefix "content_" để nhất quán)
 	global.MongoDB_ColNames.ContentNodes = "content_core_nodes"
@@ -131,6 +147,15 @@ func initColNames() {
 	global.MongoDB_ColNames.CustomerBulkJobs = "customer_job_bulk"
 	global.MongoDB_ColNames.CustomerIntelCompute = "customer_job_intel"
 	global.MongoDB_ColNames.CustomerIntelRuns = "customer_run_intel"
+	global.MongoDB_ColNames.CustomerDuplicateCandidates = "customer_job_duplicate_candidates"
+	global.MongoDB_ColNames.CustomerMergeHistory = "customer_run_merge_history"
+	global.MongoDB_ColNames.CustomerSegments = "customer_core_segments"
+	global.MongoDB_ColNames.CustomerSegmentMembers = "customer_run_segment_members"
+	global.MongoDB_ColNames.CustomerSegmentCounts = "customer_run_segment_counts"
+	global.MongoDB_ColNames.CustomerPrivacyRequests = "customer_run_privacy_requests"
+	global.MongoDB_ColNames.CustomerPrivacyExportItems = "customer_run_privacy_export_items"
+	global.MongoDB_ColNames.CustomerFieldDefinitions = "customer_core_field_definitions"
+	global.MongoDB_ColNames.CustomerTags = "customer_core_tags"
 
 	// Module Meta Ads
 	global.MongoDB_ColNames.MetaAdAccounts = "meta_src_ad_accounts"
@@ -163,6 +188,8 @@ func initColNames() {
 	global.MongoDB_ColNames.RuleParamSets = "rule_cfg_param_sets"
 	global.MongoDB_ColNames.RuleOutputDefinitions = "rule_cfg_output_definitions"
 	global.MongoDB_ColNames.RuleExecutionLogs = "rule_run_execution_logs"
+	global.MongoDB_ColNames.RulePromotionAudits = "rule_run_promotion_audits"
+	global.MongoDB_ColNames.RuleTestCases = "rule_cfg_test_cases"
 
 	// Module CIX — Contextual Conversation Intelligence
 	global.MongoDB_ColNames.CixAnalysisResults = "cix_run_analysis_results"
@@ -198,6 +225,14 @@ func initConfig() {
 		logrus.Fatalf("Failed to initialize config: config is nil") // Ghi log lỗi nếu khởi tạo cấu hình thất bại
 	}
 	logrus.Info("Initialized server config") // Ghi log thông báo đã khởi tạo cấu hình server
+
+	cfg := global.MongoDB_ServerConfig
+	if err := keyring.Init(cfg.EncryptionKeys, cfg.EncryptionActiveKeyID, cfg.JwtSecret); err != nil {
+		logrus.Fatalf("Failed to initialize encryption keyring: %v", err)
+	}
+	if cfg.EncryptionKeys == "" {
+		logrus.Warn("ENCRYPTION_KEYS chưa cấu hình: secret đang mã hóa bằng key dẫn xuất từ JWT_SECRET (đổi JWT_SECRET sẽ không giải mã được)")
+	}
 }
 
 // Hàm khởi tạo kết nối database
@@ -228,6 +263,14 @@ func initDatabase_MongoDB() {
 	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.Organizations), authmodels.Organization{})
 	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.OrganizationConfigItems), authmodels.OrganizationConfigItem{})
 	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.AccessTokens), pcmodels.AccessToken{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.AuthSessions), authmodels.AuthSession{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.AuthRevokedSessions), authmodels.AuthRevokedSession{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.ServiceAccounts), authmodels.ServiceAccount{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.APIKeys), authmodels.APIKey{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.RateLimitPolicies), authmodels.RateLimitPolicy{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.RateLimitCounters), authmodels.RateLimitCounter{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.RecordShareGrants), authmodels.RecordShareGrant{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.RecordShareAccesses), authmodels.RecordShareAccess{})
 	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.FbPages), fbmodels.FbPage{})
 	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.FbConvesations), fbmodels.FbConversation{})
 	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.FbMessages), fbmodels.FbMessage{})
@@ -250,15 +293,23 @@ func initDatabase_MongoDB() {
 	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.ManualPosWarehouses), pcmodels.PcPosWarehouse{})
 	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.OrderCanonical), ordermodels.CommerceOrder{})
 
+	// Webhook: nonce chống replay (TTL)
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.WebhookNonces), webhookmodels.WebhookNonce{})
+
 	// Notification System Indexes (Hệ thống 2 - Routing/Template)
 	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.NotificationSenders), notifmodels.NotificationChannelSender{})
 	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.NotificationChannels), notifmodels.NotificationChannel{})
 	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.NotificationTemplates), notifmodels.NotificationTemplate{})
 	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.NotificationRoutingRules), notifmodels.NotificationRoutingRule{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.NotificationDedupMarks), notifmodels.NotificationDedupMark{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.NotificationDigests), notifmodels.NotificationDigestEntry{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.NotificationPreferences), notifmodels.NotificationPreference{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.NotificationInbox), notifmodels.NotificationInboxItem{})
 
 	// Delivery System Indexes (Hệ thống 1 - Gửi)
 	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.DeliveryQueue), deliverymodels.DeliveryQueueItem{})
 	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.DeliveryHistory), deliverymodels.DeliveryHistory{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.DeliveryDeadLetters), deliverymodels.DeliveryDeadLetter{})
 
 	// CTA Module Indexes
 	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.CTALibrary), ctamodels.CTALibrary{})
@@ -303,6 +354,15 @@ func initDatabase_MongoDB() {
 	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.CustomerBulkJobs), crmmodels.CrmBulkJob{})
 	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.CustomerIntelCompute), crmmodels.CrmIntelComputeJob{})
 	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.CustomerIntelRuns), crmmodels.CrmCustomerIntelRun{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.CustomerDuplicateCandidates), crmmodels.CrmDuplicateCandidate{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.CustomerMergeHistory), crmmodels.CrmMergeHistory{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.CustomerSegments), crmmodels.CrmSegment{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.CustomerSegmentMembers), crmmodels.CrmSegmentMember{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.CustomerSegmentCounts), crmmodels.CrmSegmentCountSnapshot{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.CustomerPrivacyRequests), crmmodels.CrmPrivacyRequest{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.CustomerPrivacyExportItems), crmmodels.CrmPrivacyExportItem{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.CustomerFieldDefinitions), crmmodels.CrmCustomFieldDefinition{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.CustomerTags), crmmodels.CrmCustomerTag{})
 
 	// Module Meta Ads
 	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.MetaAdAccounts), metamodels.MetaAdAccount{})
@@ -335,6 +395,8 @@ func initDatabase_MongoDB() {
 	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.RuleParamSets), ruleintelmodels.ParamSet{})
 	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.RuleOutputDefinitions), ruleintelmodels.OutputContract{})
 	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.RuleExecutionLogs), ruleintelmodels.RuleExecutionTrace{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.RulePromotionAudits), ruleintelmodels.RulePromotionAudit{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.RuleTestCases), ruleintelmodels.RuleTestCase{})
 
 	// Module CIX — Contextual Conversation Intelligence
 	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.CixAnalysisResults), cixmodels.CixAnalysisResult{})
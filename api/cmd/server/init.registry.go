@@ -11,6 +11,7 @@ import (
 	aidecisionsvc "meta_commerce/internal/api/aidecision/service"
 	crmvc "meta_commerce/internal/api/crm/service"
 	learningsvc "meta_commerce/internal/api/learning/service"
+	ruleintelsvc "meta_commerce/internal/api/ruleintel/service"
 	"meta_commerce/internal/global"
 	"meta_commerce/internal/utility/identity"
 	pkgapproval "meta_commerce/pkg/approval"
@@ -39,6 +40,8 @@ func InitRegistry() {
 	}
 
 	// Rule Intelligence (seed system Ads/CRM/CIX/AI Decision dispatch) — InitDefaultData Step 1b, sau System Organization.
+	// Cache Rule Engine (definition/logic/param/output + program goja) invalidate khi các collection rule đổi.
+	ruleintelsvc.RegisterRuleEngineCacheInvalidation()
 
 	// Một nơi nạp + tài liệu luồng datachanged (chi tiết: internal/api/aidecision/eventpipeline).
 	eventpipeline.EnsureSideEffectModulesLoaded()
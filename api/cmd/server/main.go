@@ -26,6 +26,9 @@ import (
 	_ "meta_commerce/internal/api/ads_meta"   // Đăng ký executor domain ads_meta (approval vẫn DomainAdsApproval "ads") + deferred + event types
 	_ "meta_commerce/internal/executors/cix" // Đăng ký cix executor với approval (init)
 	approval "meta_commerce/internal/approval"
+	approvalworker "meta_commerce/internal/approval/worker"
+	"meta_commerce/internal/notifytrigger"
+	notifyworker "meta_commerce/internal/notifytrigger/worker"
 	"meta_commerce/internal/delivery"
 	"meta_commerce/internal/global"
 	"meta_commerce/internal/livehooks"
@@ -400,6 +403,13 @@ func main() {
 
 	log := logger.GetAppLogger()
 
+	// Throttler thông báo dùng chung (quiet hours / dedup / digest) — tạo một lần, trigger và handler dùng lại
+	if throttler, err := notifytrigger.NewThrottler(); err != nil {
+		log.WithError(err).Warn("🔔 [NOTIFICATION] Không tạo được throttler lúc khởi động, sẽ thử lại khi trigger")
+	} else {
+		notifytrigger.SetDefaultThrottler(throttler)
+	}
+
 	// Đăng ký callback cảnh báo CPU/RAM/disk quá tải (phải gọi trước khi khởi động workers)
 	systemalert.Register()
 
@@ -420,6 +430,9 @@ func main() {
 		reg.Register(worker.WorkerDelivery, processor)
 	}
 
+	// Notification Digest Worker — gom thông báo digest của routing rule thành bản tổng hợp
+	reg.Register(worker.WorkerNotificationDigest, notifyworker.NewNotificationDigestWorker(1*time.Minute, 500))
+
 	// Command Cleanup Worker (Module 2)
 	if w, err := worker.NewCommandCleanupWorker(1*time.Minute, 300); err != nil {
 		log.WithError(err).Error("Failed to create command cleanup worker")
@@ -487,6 +500,9 @@ func main() {
 	reg.Register(worker.WorkerAdsPancakeHeartbeat, adsworker.NewAdsPancakeHeartbeatWorker(15*time.Minute))
 	reg.Register(worker.WorkerAdsCounterfactual, adsworker.NewAdsCounterfactualWorker(30*time.Minute))
 
+	// Approval Expiry Worker (TTL chờ duyệt, auto-decision, SLA escalation — mọi domain)
+	reg.Register(worker.WorkerApprovalExpiry, approvalworker.NewApprovalExpiryWorker(1*time.Minute, 100))
+
 	// Classification Refresh Workers
 	if w, err := worker.NewClassificationRefreshWorker(24*time.Hour, 200, worker.ClassificationRefreshModeFull); err != nil {
 		log.WithError(err).Warn("Failed to create classification refresh full worker")
@@ -16,6 +16,15 @@ type Configuration struct {
 	InitMode               bool   `env:"INITMODE" envDefault:"false"`               // Chế độ khởi tạo
 	Address                string `env:"ADDRESS" envDefault:":8080"`                // Địa chỉ server
 	JwtSecret              string `env:"JWT_SECRET,required"`                       // Bí mật JWT
+	// Keyring mã hóa secret trong DB (sender, API key, access token): "id:base64key32byte,..." — key cũ giữ lại để giải mã khi xoay.
+	// Trống → dùng key dẫn xuất từ JWT_SECRET (định dạng cũ). Sau khi đổi key active: chạy cmd/reencrypt_secrets.
+	EncryptionKeys        string `env:"ENCRYPTION_KEYS"`
+	EncryptionActiveKeyID string `env:"ENCRYPTION_ACTIVE_KEY_ID"` // Key dùng để mã hóa (trống = key đầu tiên trong ENCRYPTION_KEYS)
+	AuthAccessTokenTTL    int    `env:"AUTH_ACCESS_TOKEN_TTL" envDefault:"900"`      // Thời gian sống access token (giây) — xác thực bằng chữ ký, không query DB
+	AuthRefreshTokenTTL   int    `env:"AUTH_REFRESH_TOKEN_TTL" envDefault:"2592000"` // Thời gian sống refresh token / phiên theo hwid (giây, mặc định 30 ngày)
+	// Chuyển toàn bộ token cũ (User.token/tokens) sang phiên lúc khởi động — bật một lần khi nâng cấp rồi tắt.
+	// Tắt: token cũ chưa chuyển được chuyển khi client gửi nó vào /auth/refresh.
+	AuthLegacyTokenMigrate bool `env:"AUTH_LEGACY_TOKEN_MIGRATE" envDefault:"false"`
 	MongoDB_ConnectionURI  string `env:"MONGODB_CONNECTION_URI,required"`           // URL kết nối cơ sở dữ liệu
 	MongoDB_DBName_Auth    string `env:"MONGODB_DBNAME_AUTH,required"`              // Tên cơ sở dữ liệu xác thực
 	MongoDB_DBName_Staging string `env:"MONGODB_DBNAME_STAGING,required"`           // Tên cơ sở dữ liệu staging
@@ -27,6 +36,8 @@ type Configuration struct {
 	RateLimit_Max          int    `env:"RATE_LIMIT_MAX" envDefault:"100"`           // Số request tối đa trong window (0 = disable rate limit)
 	RateLimit_Window       int    `env:"RATE_LIMIT_WINDOW" envDefault:"60"`         // Thời gian window (giây)
 	RateLimit_Enabled      bool   `env:"RATE_LIMIT_ENABLED" envDefault:"true"`      // Bật/tắt rate limiting
+	// RateLimit_TenantEnabled: giới hạn theo tổ chức / user / API key + nhóm route (policy trong auth_core_rate_limit_policies), áp dụng sau khi xác thực
+	RateLimit_TenantEnabled bool `env:"RATE_LIMIT_TENANT_ENABLED" envDefault:"true"`
 	// Firebase Configuration
 	FirebaseProjectID       string `env:"FIREBASE_PROJECT_ID"`       // Firebase Project ID
 	FirebaseCredentialsPath string `env:"FIREBASE_CREDENTIALS_PATH"` // Đường dẫn đến service account JSON
@@ -9,6 +9,8 @@ const (
 	EventTypeActionExecutedFailed = "ads_action_executed_failed"  // Sau khi thực thi thất bại
 	EventTypeActionRejected       = "ads_action_rejected"        // Khi human reject
 	EventTypeActionCancelled      = "ads_action_cancelled"        // Khi user hủy đề xuất pending
+	EventTypeActionExpired        = "ads_action_expired"          // Khi đề xuất quá hạn chờ duyệt (auto-reject)
+	EventTypeApprovalEscalation   = "ads_action_escalation"       // Khi đề xuất chờ duyệt tới mốc SLA
 )
 
 // ActionType loại hành động đề xuất.
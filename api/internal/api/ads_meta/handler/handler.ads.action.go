@@ -9,6 +9,7 @@ import (
 	adsdto "meta_commerce/internal/api/ads_meta/dto"
 	adssvc "meta_commerce/internal/api/ads_meta/service"
 	"meta_commerce/internal/approval"
+	pkgapproval "meta_commerce/pkg/approval"
 	basehdl "meta_commerce/internal/api/base/handler"
 	"meta_commerce/internal/common"
 
@@ -28,12 +29,11 @@ func getActiveOrgID(c fiber.Ctx) *primitive.ObjectID {
 	return &oid
 }
 
-// getRejectedBy lấy user ID hoặc email từ auth context (user_id, user).
-func getRejectedBy(c fiber.Ctx) string {
-	if userIDStr, ok := c.Locals("user_id").(string); ok && userIDStr != "" {
-		return "user:" + userIDStr
-	}
-	return ""
+// getApprover người duyệt/từ chối từ auth context: user_id + active_role_id.
+func getApprover(c fiber.Ctx) approval.Approver {
+	userID, _ := c.Locals("user_id").(string)
+	roleID, _ := c.Locals("active_role_id").(string)
+	return approval.ResolveApprover(c.Context(), userID, roleID)
 }
 
 // HandleCreateCommand tạo lệnh chờ duyệt — user có MetaAdAccount.Read có thể gọi.
@@ -127,7 +127,7 @@ func HandleApprove(c fiber.Ctx) error {
 			})
 			return nil
 		}
-		result, err := approval.Approve(c.Context(), input.ActionId, *orgID)
+		result, err := approval.Approve(c.Context(), input.ActionId, *orgID, getApprover(c))
 		if err != nil {
 			errCode, msg, statusCode := common.GetErrorResponseInfo(err, "Duyệt đề xuất thất bại")
 			c.Status(statusCode).JSON(fiber.Map{
@@ -135,8 +135,12 @@ func HandleApprove(c fiber.Ctx) error {
 			})
 			return nil
 		}
+		msg := "Đã duyệt đề xuất"
+		if result.Status == pkgapproval.StatusPending {
+			msg = "Đã ghi nhận lượt duyệt, chờ thêm người duyệt theo policy"
+		}
 		c.Status(common.StatusOK).JSON(fiber.Map{
-			"code": common.StatusOK, "message": "Đã duyệt đề xuất", "data": result, "status": "success",
+			"code": common.StatusOK, "message": msg, "data": result, "status": "success",
 		})
 		return nil
 	})
@@ -205,8 +209,7 @@ func HandleReject(c fiber.Ctx) error {
 			})
 			return nil
 		}
-		rejectedBy := getRejectedBy(c)
-		result, err := approval.Reject(c.Context(), input.ActionId, *orgID, input.DecisionNote, rejectedBy)
+		result, err := approval.Reject(c.Context(), input.ActionId, *orgID, input.DecisionNote, getApprover(c))
 		if err != nil {
 			errCode, msg, statusCode := common.GetErrorResponseInfo(err, "Từ chối đề xuất thất bại")
 			c.Status(statusCode).JSON(fiber.Map{
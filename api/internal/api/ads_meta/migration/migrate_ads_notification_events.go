@@ -287,6 +287,48 @@ Trân trọng,
 Hệ thống Ads`,
 			variables: []string{"timestamp", "actionType", "campaignName", "campaignId", "adAccountId", "flagsSummary", "rawSummary", "layer1Summary", "layer3Summary", "flagsDetail"},
 		},
+		{
+			eventType: "ads_action_expired",
+			subject:   "⌛ [ADS] Đề xuất hết hạn chờ duyệt — {{actionType}}",
+			content: `Đề xuất không được duyệt trong thời hạn, hệ thống đã tự động từ chối.
+
+▸ Thông tin
+- Thời gian: {{timestamp}}
+- Hành động: {{actionType}}
+- Campaign: {{campaignName}} ({{campaignId}})
+- Ad Account: {{adAccountId}}
+
+▸ Căn cứ đề xuất (đã hết hạn)
+- Flags: {{flagsSummary}}
+- Raw: {{rawSummary}}
+- Layer1: {{layer1Summary}}
+- Layer3: {{layer3Summary}}
+- Chi tiết: {{flagsDetail}}
+
+Trân trọng,
+Hệ thống Ads`,
+			variables: []string{"timestamp", "actionType", "campaignName", "campaignId", "adAccountId", "flagsSummary", "rawSummary", "layer1Summary", "layer3Summary", "flagsDetail"},
+		},
+		{
+			eventType: "ads_action_escalation",
+			subject:   "⏰ [ADS] Đề xuất chờ duyệt quá SLA (lần {{escalationLevel}}) — {{actionType}}",
+			content: `Đề xuất vẫn chờ duyệt sau {{pendingMinutes}} phút. Vui lòng xử lý trước khi hết hạn.
+
+▸ Thông tin
+- Thời gian: {{timestamp}}
+- Hành động: {{actionType}}
+- Campaign: {{campaignName}} ({{campaignId}})
+- Ad Account: {{adAccountId}}
+- Đã duyệt: {{approvalCount}}/{{requiredApprovals}}
+
+▸ Căn cứ đề xuất
+- Flags: {{flagsSummary}}
+- Chi tiết: {{flagsDetail}}
+
+Trân trọng,
+Hệ thống Ads`,
+			variables: []string{"timestamp", "actionType", "campaignName", "campaignId", "adAccountId", "escalationLevel", "pendingMinutes", "approvalCount", "requiredApprovals", "flagsSummary", "flagsDetail"},
+		},
 		{
 			eventType: "ads_predictive_trend_alert",
 			subject:   "⏰ [ADS] Predictive Trend — {{alertType}}",
@@ -391,11 +433,11 @@ Hệ thống Ads`,
 	return created, nil
 }
 
-// isAdsActionEvent kiểm tra eventType có phải ads action (pending, executed, rejected, failed, cancelled) không.
+// isAdsActionEvent kiểm tra eventType có phải ads action (pending, executed, rejected, failed, cancelled, expired, escalation) không.
 func isAdsActionEvent(eventType string) bool {
 	switch eventType {
 	case "ads_action_pending_approval", "ads_action_executed", "ads_action_rejected",
-		"ads_action_executed_failed", "ads_action_cancelled":
+		"ads_action_executed_failed", "ads_action_cancelled", "ads_action_expired", "ads_action_escalation":
 		return true
 	}
 	return false
@@ -17,6 +17,8 @@ import (
 // ExecuteAdsAction thực thi action qua Meta API.
 // Hỗ trợ: KILL, PAUSE, RESUME, ARCHIVE, DELETE, SET_BUDGET, SET_LIFETIME_BUDGET, INCREASE, DECREASE, SET_NAME.
 // Hỗ trợ đầy đủ campaign, adset, ad — ưu tiên ad > adset > campaign theo objectId.
+// Action đảo ngược được (pause/resume, budget, name) đọc trạng thái trước khi ghi và trả Compensation
+// trong response[pkgapproval.ResponseKeyCompensation] để revert. ARCHIVE/DELETE không revert được.
 func ExecuteAdsAction(ctx context.Context, doc *pkgapproval.ActionPending) (map[string]interface{}, error) {
 	payload := doc.Payload
 	if payload == nil {
@@ -63,17 +65,22 @@ func ExecuteAdsAction(ctx context.Context, doc *pkgapproval.ActionPending) (map[
 
 	switch doc.ActionType {
 	case "KILL", "PAUSE":
+		prev := fetchMetaFields(ctx, client, objectId, "status")
 		body, err := client.Post(ctx, objectId, map[string]string{"status": "PAUSED"})
 		if err != nil {
 			return nil, fmt.Errorf("Meta API pause %s %s: %w", objectType, objectId, err)
 		}
-		return map[string]interface{}{
+		resp := map[string]interface{}{
 			"success":    true,
 			"objectType": objectType,
 			"objectId":   objectId,
 			"status":     "PAUSED",
 			"raw":        string(body),
-		}, nil
+		}
+		if prevStatus, _ := prev["status"].(string); prevStatus == "ACTIVE" {
+			resp[pkgapproval.ResponseKeyCompensation] = newAdsCompensation("RESUME", payload, nil, prev, "Bật lại "+objectType+" "+objectId)
+		}
+		return resp, nil
 
 	case "ARCHIVE":
 		body, err := client.Post(ctx, objectId, map[string]string{"status": "ARCHIVED"})
@@ -102,6 +109,7 @@ func ExecuteAdsAction(ctx context.Context, doc *pkgapproval.ActionPending) (map[
 		}, nil
 
 	case "SET_NAME":
+		prev := fetchMetaFields(ctx, client, objectId, "name")
 		newName, _ := payload["value"].(string)
 		if newName == "" {
 			if n, ok := payload["name"].(string); ok && n != "" {
@@ -115,26 +123,35 @@ func ExecuteAdsAction(ctx context.Context, doc *pkgapproval.ActionPending) (map[
 		if err != nil {
 			return nil, fmt.Errorf("Meta API set name %s %s: %w", objectType, objectId, err)
 		}
-		return map[string]interface{}{
+		resp := map[string]interface{}{
 			"success":    true,
 			"objectType": objectType,
 			"objectId":   objectId,
 			"name":       newName,
 			"raw":        string(body),
-		}, nil
+		}
+		if prevName, _ := prev["name"].(string); prevName != "" && prevName != newName {
+			resp[pkgapproval.ResponseKeyCompensation] = newAdsCompensation("SET_NAME", payload, prevName, prev, "Đổi lại tên "+prevName)
+		}
+		return resp, nil
 
 	case "RESUME":
+		prev := fetchMetaFields(ctx, client, objectId, "status")
 		body, err := client.Post(ctx, objectId, map[string]string{"status": "ACTIVE"})
 		if err != nil {
 			return nil, fmt.Errorf("Meta API resume %s %s: %w", objectType, objectId, err)
 		}
-		return map[string]interface{}{
+		resp := map[string]interface{}{
 			"success":    true,
 			"objectType": objectType,
 			"objectId":   objectId,
 			"status":     "ACTIVE",
 			"raw":        string(body),
-		}, nil
+		}
+		if prevStatus, _ := prev["status"].(string); prevStatus == "PAUSED" {
+			resp[pkgapproval.ResponseKeyCompensation] = newAdsCompensation("PAUSE", payload, nil, prev, "Tạm dừng lại "+objectType+" "+objectId)
+		}
+		return resp, nil
 
 	case "SET_BUDGET":
 		// Budget phải đổi ở adset hoặc campaign. Ưu tiên adset. daily_budget (cent).
@@ -149,19 +166,24 @@ func ExecuteAdsAction(ctx context.Context, doc *pkgapproval.ActionPending) (map[
 		if budgetCents <= 0 {
 			return nil, fmt.Errorf("SET_BUDGET value không hợp lệ: %v", value)
 		}
+		prev := fetchMetaFields(ctx, client, budgetObjId, "daily_budget")
 		body, err := client.Post(ctx, budgetObjId, map[string]string{
 			"daily_budget": strconv.FormatInt(budgetCents, 10),
 		})
 		if err != nil {
 			return nil, fmt.Errorf("Meta API set budget %s: %w", budgetObjId, err)
 		}
-		return map[string]interface{}{
+		resp := map[string]interface{}{
 			"success":      true,
 			"objectId":     budgetObjId,
 			"dailyBudget":  budgetCents,
 			"adAccountId":  adAccountId,
 			"raw":          string(body),
-		}, nil
+		}
+		if prevBudget := extractInt64(prev, "daily_budget"); prevBudget > 0 && prevBudget != budgetCents {
+			resp[pkgapproval.ResponseKeyCompensation] = newAdsCompensation("SET_BUDGET", payload, prevBudget, prev, "Khôi phục daily budget "+strconv.FormatInt(prevBudget, 10))
+		}
+		return resp, nil
 
 	case "SET_LIFETIME_BUDGET":
 		// lifetime_budget (cent) — campaign hoặc adset.
@@ -176,19 +198,24 @@ func ExecuteAdsAction(ctx context.Context, doc *pkgapproval.ActionPending) (map[
 		if budgetCents <= 0 {
 			return nil, fmt.Errorf("SET_LIFETIME_BUDGET value không hợp lệ: %v", value)
 		}
+		prev := fetchMetaFields(ctx, client, budgetObjId, "lifetime_budget")
 		body, err := client.Post(ctx, budgetObjId, map[string]string{
 			"lifetime_budget": strconv.FormatInt(budgetCents, 10),
 		})
 		if err != nil {
 			return nil, fmt.Errorf("Meta API set lifetime budget %s: %w", budgetObjId, err)
 		}
-		return map[string]interface{}{
+		resp := map[string]interface{}{
 			"success":         true,
 			"objectId":        budgetObjId,
 			"lifetimeBudget":  budgetCents,
 			"adAccountId":     adAccountId,
 			"raw":             string(body),
-		}, nil
+		}
+		if prevBudget := extractInt64(prev, "lifetime_budget"); prevBudget > 0 && prevBudget != budgetCents {
+			resp[pkgapproval.ResponseKeyCompensation] = newAdsCompensation("SET_LIFETIME_BUDGET", payload, prevBudget, prev, "Khôi phục lifetime budget "+strconv.FormatInt(prevBudget, 10))
+		}
+		return resp, nil
 
 	case "INCREASE", "DECREASE":
 		// Cần lấy budget hiện tại rồi áp dụng %. Đơn giản: value là % (vd 15 = +15%)
@@ -213,6 +240,7 @@ func ExecuteAdsAction(ctx context.Context, doc *pkgapproval.ActionPending) (map[
 			return nil, fmt.Errorf("parse Meta response: %w", err)
 		}
 		currentBudget := extractInt64(metaResp, "daily_budget")
+		hasDailyBudget := currentBudget > 0
 		if currentBudget <= 0 {
 			currentBudget = extractInt64(metaResp, "lifetime_budget") / 30
 		}
@@ -238,7 +266,7 @@ func ExecuteAdsAction(ctx context.Context, doc *pkgapproval.ActionPending) (map[
 		if err != nil {
 			return nil, fmt.Errorf("Meta API update budget %s: %w", budgetObjId, err)
 		}
-		return map[string]interface{}{
+		resp := map[string]interface{}{
 			"success":       true,
 			"objectId":      budgetObjId,
 			"previousBudget": currentBudget,
@@ -246,13 +274,51 @@ func ExecuteAdsAction(ctx context.Context, doc *pkgapproval.ActionPending) (map[
 			"percent":      percent,
 			"adAccountId":   adAccountId,
 			"raw":           string(body),
-		}, nil
+		}
+		// Budget ước lượng từ lifetime/30 không phải trạng thái thật → không revert được
+		if hasDailyBudget && newBudget != currentBudget {
+			resp[pkgapproval.ResponseKeyCompensation] = newAdsCompensation("SET_BUDGET", payload, currentBudget,
+				map[string]interface{}{"daily_budget": currentBudget}, "Khôi phục daily budget "+strconv.FormatInt(currentBudget, 10))
+		}
+		return resp, nil
 
 	default:
 		return nil, fmt.Errorf("actionType chưa hỗ trợ: %s", doc.ActionType)
 	}
 }
 
+// fetchMetaFields đọc field hiện tại của object (trạng thái trước khi ghi). Lỗi → map rỗng (bỏ qua compensation, không chặn thực thi).
+func fetchMetaFields(ctx context.Context, client *metaclient.MetaGraphClient, objectId, fields string) map[string]interface{} {
+	out := map[string]interface{}{}
+	body, err := client.Get(ctx, objectId, map[string]string{"fields": fields})
+	if err != nil {
+		return out
+	}
+	_ = json.Unmarshal(body, &out)
+	return out
+}
+
+// newAdsCompensation action nghịch đảo: giữ target (adAccountId, campaignId, adSetId, adId) của payload gốc.
+// value nil = action không cần value (PAUSE/RESUME).
+func newAdsCompensation(actionType string, original map[string]interface{}, value interface{}, previous map[string]interface{}, description string) *pkgapproval.Compensation {
+	payload := map[string]interface{}{}
+	for _, k := range []string{"adAccountId", "campaignId", "campaignName", "adSetId", "adId"} {
+		if v, ok := original[k]; ok && v != nil && v != "" {
+			payload[k] = v
+		}
+	}
+	if value != nil {
+		payload["value"] = value
+	}
+	delete(previous, "id")
+	return &pkgapproval.Compensation{
+		ActionType:    actionType,
+		Payload:       payload,
+		PreviousState: previous,
+		Description:   description,
+	}
+}
+
 func toBudgetCents(v interface{}) int64 {
 	switch x := v.(type) {
 	case float64:
@@ -157,7 +157,7 @@ func (w *AdsExecutionWorker) processOne(ctx context.Context, doc *pkgapproval.Ac
 	if execErr == nil {
 		// Thành công: cập nhật executed
 		doc.Status = pkgapproval.StatusExecuted
-		doc.ExecuteResponse = resp
+		doc.ExecuteResponse = pkgapproval.CaptureCompensation(doc, resp)
 		doc.ExecutedAt = now
 		doc.ExecuteError = ""
 		doc.NextRetryAt = nil
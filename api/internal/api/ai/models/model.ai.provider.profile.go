@@ -1,6 +1,8 @@
 package models
 
 import (
+	"meta_commerce/internal/keyring"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -46,10 +48,10 @@ type AIProviderProfile struct {
 	Status      string `json:"status" bson:"status" index:"single:1"`              // Trạng thái: "active", "inactive", "archived"
 
 	// ===== AUTHENTICATION =====
-	APIKey          string `json:"apiKey" bson:"apiKey"`                                       // API key (nên được encrypt khi lưu)
-	APIKeyEncrypted bool   `json:"apiKeyEncrypted,omitempty" bson:"apiKeyEncrypted,omitempty"` // Flag để biết API key đã được encrypt chưa
-	BaseURL         string `json:"baseUrl,omitempty" bson:"baseUrl,omitempty"`                 // Base URL của API (nếu custom)
-	OrganizationID  string `json:"organizationId,omitempty" bson:"organizationId,omitempty"`   // Organization ID (cho OpenAI organization billing)
+	APIKey          keyring.Secret `json:"apiKey" bson:"apiKey"`                                       // API key (lưu Mongo dạng ciphertext keyring)
+	APIKeyEncrypted bool           `json:"apiKeyEncrypted,omitempty" bson:"apiKeyEncrypted,omitempty"` // Không còn dùng: APIKey luôn mã hóa bằng keyring
+	BaseURL         string         `json:"baseUrl,omitempty" bson:"baseUrl,omitempty"`                 // Base URL của API (nếu custom)
+	OrganizationID  string         `json:"organizationId,omitempty" bson:"organizationId,omitempty"`   // Organization ID (cho OpenAI organization billing)
 
 	// ===== CONFIGURATION =====
 	AvailableModels []string  `json:"availableModels,omitempty" bson:"availableModels,omitempty"` // Danh sách models có sẵn
@@ -11,6 +11,7 @@ import (
 	"meta_commerce/internal/api/aidecision/eventtypes"
 	"meta_commerce/internal/api/aidecision/queuedepth"
 	aidecisionmodels "meta_commerce/internal/api/aidecision/models"
+	"meta_commerce/internal/utility"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -273,3 +274,36 @@ func EmitCrmIntelligenceClassificationRefreshRequested(ctx context.Context, mode
 		"batchSize": batchSize,
 	}, aidecisionmodels.EventLaneBatch)
 }
+
+// EmitCrmSegmentMembershipChanged — khách vào (crm.segment.entered) hoặc ra (crm.segment.exited) phân khúc động.
+// Emit từ đánh giá phân khúc sau crm_intel_compute / refresh phân khúc; entity là khách, payload mang segmentId.
+func EmitCrmSegmentMembershipChanged(ctx context.Context, eventType string, ownerOrgID primitive.ObjectID, segmentIDHex, segmentName, unifiedID string, atMs int64) (string, error) {
+	if ownerOrgID.IsZero() || strings.TrimSpace(unifiedID) == "" {
+		return "", nil
+	}
+	res, err := eventemit.EmitDecisionEvent(ctx, &eventemit.EmitInput{
+		EventType:     eventType,
+		EventSource:   eventtypes.EventSourceCRM,
+		PipelineStage: eventtypes.PipelineStageDomainIntel,
+		EntityType:    "crm_customer",
+		EntityID:      unifiedID,
+		OrgID:         ownerOrgID.Hex(),
+		OwnerOrgID:    ownerOrgID,
+		Priority:      "normal",
+		Lane:          aidecisionmodels.EventLaneNormal,
+		TraceID:       utility.GenerateUID(utility.UIDPrefixTrace),
+		CorrelationID: utility.GenerateUID(utility.UIDPrefixCorrelation),
+		Payload: map[string]interface{}{
+			"unifiedId":                  unifiedID,
+			"segmentId":                  segmentIDHex,
+			"segmentName":                segmentName,
+			"ownerOrgIdHex":              ownerOrgID.Hex(),
+			PayloadKeyCausalOrderingAtMs: atMs,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	_ = queuedepth.RefreshOrg(ctx, ownerOrgID)
+	return res.EventID, nil
+}
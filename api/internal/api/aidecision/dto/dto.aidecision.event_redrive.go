@@ -0,0 +1,29 @@
+// Package dto — Redrive / replay event queue AI Decision (admin).
+package dto
+
+// RedriveEventFilter bộ lọc chọn event queue (luôn trong org đang chọn). Thời gian là Unix ms.
+type RedriveEventFilter struct {
+	EventTypes    []string `json:"eventTypes,omitempty"`
+	Statuses      []string `json:"statuses,omitempty"` // mặc định failed_terminal + completed_no_handler
+	TraceID       string   `json:"traceId,omitempty"`
+	FromCreatedMs *int64   `json:"fromCreatedMs,omitempty"`
+	ToCreatedMs   *int64   `json:"toCreatedMs,omitempty"`
+	Limit         int      `json:"limit,omitempty"` // mặc định 500, tối đa 5000
+}
+
+// RedriveEventsRequest request cho POST /ai-decision/queue-events/redrive.
+type RedriveEventsRequest struct {
+	RedriveEventFilter
+	Lane     string `json:"lane,omitempty"`     // fast | normal | batch — rỗng giữ lane gốc
+	Priority string `json:"priority,omitempty"` // high | normal | low — rỗng giữ priority gốc
+	DryRun   bool   `json:"dryRun"`
+}
+
+// ReplaySourceRequest request cho POST /ai-decision/queue-events/replay-source.
+// Đúng một trong: sourceCollection + documentIds (tối đa 500), hoặc fromEvents.
+type ReplaySourceRequest struct {
+	SourceCollection string              `json:"sourceCollection,omitempty"`
+	DocumentIDs      []string            `json:"documentIds,omitempty"`
+	FromEvents       *RedriveEventFilter `json:"fromEvents,omitempty"`
+	DryRun           bool                `json:"dryRun"`
+}
@@ -71,6 +71,8 @@ var exactTier = map[string]string{
 	// Vận hành / nền
 	eventtypes.CrmIntelligenceComputeRequested:       TierOperational,
 	eventtypes.CrmIntelligenceRecomputeRequested:     TierOperational,
+	eventtypes.CrmSegmentEntered:                     TierOperational,
+	eventtypes.CrmSegmentExited:                      TierOperational,
 	eventtypes.AdsIntelligenceRecomputeRequested:     TierOperational,
 	eventtypes.AdsIntelligenceRecalculateAllRequested: TierOperational,
 	eventtypes.MetaCampaignChanged:      TierOperational,
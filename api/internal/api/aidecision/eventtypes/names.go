@@ -54,6 +54,8 @@ const (
 	CrmIntelligenceComputeRequested   = "crm.intelligence.compute_requested"
 	CrmIntelligenceRecomputeRequested = "crm.intelligence.recompute_requested"
 	CrmIntelRecomputed                = "crm_intel_recomputed"
+	CrmSegmentEntered                 = "crm.segment.entered" // Khách vào phân khúc động
+	CrmSegmentExited                  = "crm.segment.exited"  // Khách ra khỏi phân khúc động
 
 	// --- CIX ---
 	CixAnalysisRequested = "cix.analysis_requested"
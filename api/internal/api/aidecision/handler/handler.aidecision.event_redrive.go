@@ -0,0 +1,115 @@
+// Package aidecisionhdl — API admin redrive event queue và replay datachanged từ nguồn.
+package aidecisionhdl
+
+import (
+	"github.com/gofiber/fiber/v3"
+
+	aidecisiondto "meta_commerce/internal/api/aidecision/dto"
+	"meta_commerce/internal/api/aidecision/hooks"
+	aidecisionsvc "meta_commerce/internal/api/aidecision/service"
+	basehdl "meta_commerce/internal/api/base/handler"
+	"meta_commerce/internal/common"
+)
+
+// HandleRedriveQueueEvents POST /ai-decision/queue-events/redrive — tạo lại event mới (attempts = 0) cho event đã đóng
+// khớp filter; lane / priority override tuỳ chọn; dryRun=true chỉ đếm (kèm phân bổ theo eventType + status).
+func HandleRedriveQueueEvents(c fiber.Ctx) error {
+	return basehdl.SafeHandlerWrapper(c, func() error {
+		var req aidecisiondto.RedriveEventsRequest
+		if err := c.Bind().JSON(&req); err != nil {
+			c.Status(common.StatusBadRequest).JSON(fiber.Map{
+				"code": common.ErrCodeValidationFormat.Code, "message": "Body JSON không hợp lệ", "status": "error",
+			})
+			return nil
+		}
+		orgID := getActiveOrgID(c)
+		if orgID == nil {
+			c.Status(common.StatusBadRequest).JSON(fiber.Map{
+				"code": common.ErrCodeValidationInput.Code, "message": "Chưa chọn tổ chức", "status": "error",
+			})
+			return nil
+		}
+		userID, _ := c.Locals("user_id").(string)
+		filter := redriveFilterFromDTO(req.RedriveEventFilter)
+		filter.OwnerOrganizationID = *orgID
+		svc := aidecisionsvc.NewAIDecisionService()
+		res, err := svc.RedriveQueueEvents(c.Context(), aidecisionsvc.RedriveQueueEventsInput{
+			Filter:    filter,
+			Lane:      req.Lane,
+			Priority:  req.Priority,
+			DryRun:    req.DryRun,
+			RequestBy: userID,
+		})
+		if err != nil {
+			errCode, msg, statusCode := common.GetErrorResponseInfo(err, "Redrive event thất bại")
+			c.Status(statusCode).JSON(fiber.Map{"code": errCode, "message": msg, "status": "error"})
+			return nil
+		}
+		message := "Đã redrive event"
+		if res.DryRun {
+			message = "Dry run — không ghi queue"
+		}
+		c.Status(common.StatusOK).JSON(fiber.Map{
+			"code": common.StatusOK, "message": message, "data": res, "status": "success",
+		})
+		return nil
+	})
+}
+
+// HandleReplaySourceEvents POST /ai-decision/queue-events/replay-source — emit lại <prefix>.changed cho document nguồn
+// (danh sách id hoặc suy từ event queue) để side effect datachanged chạy lại; dryRun=true chỉ kiểm tra.
+func HandleReplaySourceEvents(c fiber.Ctx) error {
+	return basehdl.SafeHandlerWrapper(c, func() error {
+		var req aidecisiondto.ReplaySourceRequest
+		if err := c.Bind().JSON(&req); err != nil {
+			c.Status(common.StatusBadRequest).JSON(fiber.Map{
+				"code": common.ErrCodeValidationFormat.Code, "message": "Body JSON không hợp lệ", "status": "error",
+			})
+			return nil
+		}
+		orgID := getActiveOrgID(c)
+		if orgID == nil {
+			c.Status(common.StatusBadRequest).JSON(fiber.Map{
+				"code": common.ErrCodeValidationInput.Code, "message": "Chưa chọn tổ chức", "status": "error",
+			})
+			return nil
+		}
+		userID, _ := c.Locals("user_id").(string)
+		in := hooks.ReplaySourceInput{
+			OwnerOrganizationID: *orgID,
+			SourceCollection:    req.SourceCollection,
+			DocumentIDs:         req.DocumentIDs,
+			DryRun:              req.DryRun,
+			RequestBy:           userID,
+		}
+		if req.FromEvents != nil {
+			f := redriveFilterFromDTO(*req.FromEvents)
+			in.FromEvents = &f
+		}
+		res, err := hooks.ReplaySourceDocuments(c.Context(), aidecisionsvc.NewAIDecisionService(), in)
+		if err != nil {
+			errCode, msg, statusCode := common.GetErrorResponseInfo(err, "Replay từ nguồn thất bại")
+			c.Status(statusCode).JSON(fiber.Map{"code": errCode, "message": msg, "status": "error"})
+			return nil
+		}
+		message := "Đã replay từ nguồn"
+		if res.DryRun {
+			message = "Dry run — không ghi queue"
+		}
+		c.Status(common.StatusOK).JSON(fiber.Map{
+			"code": common.StatusOK, "message": message, "data": res, "status": "success",
+		})
+		return nil
+	})
+}
+
+func redriveFilterFromDTO(f aidecisiondto.RedriveEventFilter) aidecisionsvc.RedriveQueueEventsFilter {
+	return aidecisionsvc.RedriveQueueEventsFilter{
+		EventTypes:    f.EventTypes,
+		Statuses:      f.Statuses,
+		TraceID:       f.TraceID,
+		FromCreatedMs: f.FromCreatedMs,
+		ToCreatedMs:   f.ToCreatedMs,
+		Limit:         f.Limit,
+	}
+}
@@ -17,7 +17,6 @@ import (
 	"github.com/fasthttp/websocket"
 	"github.com/gofiber/fiber/v3"
 	"github.com/sirupsen/logrus"
-	"github.com/valyala/fasthttp"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"meta_commerce/internal/api/aidecision/decisionlive"
@@ -25,15 +24,6 @@ import (
 	"meta_commerce/internal/common"
 )
 
-var wsUpgrader = websocket.FastHTTPUpgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 4096,
-	CheckOrigin: func(_ *fasthttp.RequestCtx) bool {
-		// Cho phép cùng origin / dev; production nên siết theo header Origin.
-		return true
-	},
-}
-
 // HandleTraceTimeline GET /ai-decision/traces/:traceId/timeline — Replay timeline một trace từ RAM (decisionlive.Timeline).
 //
 //	Bước 1 — Xác thực org + traceId.
@@ -84,7 +74,7 @@ func HandleTraceLiveWS(c fiber.Ctx) error {
 		})
 	}
 
-	err := wsUpgrader.Upgrade(c.RequestCtx(), func(conn *websocket.Conn) {
+	err := basehdl.WSUpgrader.Upgrade(c.RequestCtx(), func(conn *websocket.Conn) {
 		defer conn.Close()
 		_ = conn.SetReadDeadline(time.Now().Add(90 * time.Second))
 		conn.SetPongHandler(func(string) error {
@@ -327,7 +317,7 @@ func HandleOrgLiveWS(c fiber.Ctx) error {
 		})
 	}
 
-	err := wsUpgrader.Upgrade(c.RequestCtx(), func(conn *websocket.Conn) {
+	err := basehdl.WSUpgrader.Upgrade(c.RequestCtx(), func(conn *websocket.Conn) {
 		defer conn.Close()
 		_ = conn.SetReadDeadline(time.Now().Add(90 * time.Second))
 		conn.SetPongHandler(func(string) error {
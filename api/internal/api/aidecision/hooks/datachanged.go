@@ -96,12 +96,16 @@ func emitUnifiedSourceDataChanged(ctx context.Context, decSvc *aidecisionsvc.AID
 	if events.IsAdsIntelligenceRollupContext(ctx) {
 		payload["adsIntelligenceRollupOnly"] = true
 	}
-	eventType := eventTypeForSourceSync(entityPrefix)
+	_, _ = decSvc.EmitEvent(ctx, sourceChangedEmitInput(entityPrefix, idHex, ownerOrgID, payload))
+}
+
+// sourceChangedEmitInput envelope <prefix>.changed chung cho hook datachanged và replay từ nguồn.
+func sourceChangedEmitInput(entityPrefix, idHex string, ownerOrgID primitive.ObjectID, payload map[string]interface{}) *aidecisionsvc.EmitEventInput {
 	// Một traceId / correlationId gốc cho toàn chuỗi queue → orchestrate → CIX / execute (không ghi đè khi caller đã set).
 	traceID := utility.GenerateUID(utility.UIDPrefixTrace)
 	correlationID := utility.GenerateUID(utility.UIDPrefixCorrelation)
-	_, _ = decSvc.EmitEvent(ctx, &aidecisionsvc.EmitEventInput{
-		EventType:     eventType,
+	return &aidecisionsvc.EmitEventInput{
+		EventType:     eventTypeForSourceSync(entityPrefix),
 		EventSource:   eventtypes.EventSourceL1Datachanged,
 		PipelineStage: eventtypes.PipelineStageAfterL1Change,
 		EntityType:    entityPrefix,
@@ -113,5 +117,5 @@ func emitUnifiedSourceDataChanged(ctx context.Context, decSvc *aidecisionsvc.AID
 		TraceID:       traceID,
 		CorrelationID: correlationID,
 		Payload:       payload,
-	})
+	}
 }
@@ -0,0 +1,243 @@
+// Package hooks — Replay từ nguồn: emit lại <prefix>.changed cho document nguồn để side effect datachanged chạy lại.
+//
+// Khác redrive (sao chép envelope cũ), replay đọc document hiện tại trong collection nguồn và đi qua cùng cổng
+// với hook datachanged (registry + ShouldEmitDatachangedToDecisionQueue) — consumer hydrate dữ liệu mới nhất.
+// Hai cách chọn document: danh sách id trong một collection, hoặc suy từ event queue (payload sourceCollection /
+// normalizedRecordUid) — khi đó event mới nối lineage với event gốc và event gốc được đánh dấu redriven.
+package hooks
+
+import (
+	"context"
+	"strings"
+
+	aidecisionmodels "meta_commerce/internal/api/aidecision/models"
+	aidecisionsvc "meta_commerce/internal/api/aidecision/service"
+	"meta_commerce/internal/api/events"
+	"meta_commerce/internal/common"
+	"meta_commerce/internal/global"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ReplaySourceMaxDocuments trần số id truyền trực tiếp trong một request.
+const ReplaySourceMaxDocuments = 500
+
+// ReplaySourceInput — đúng một trong (SourceCollection + DocumentIDs) hoặc FromEvents.
+type ReplaySourceInput struct {
+	OwnerOrganizationID primitive.ObjectID
+	SourceCollection    string
+	DocumentIDs         []string
+	FromEvents          *aidecisionsvc.RedriveQueueEventsFilter
+	DryRun              bool
+	RequestBy           string // userId hex
+}
+
+// ReplaySourceItem một document đã (hoặc sẽ, khi dry run) replay.
+type ReplaySourceItem struct {
+	SourceCollection string   `json:"sourceCollection"`
+	DocumentID       string   `json:"documentId"`
+	EventType        string   `json:"eventType"`
+	NewEventID       string   `json:"newEventId,omitempty"`
+	ReplayOfEventIDs []string `json:"replayOfEventIds,omitempty"`
+}
+
+// ReplaySourceSkip document / event không replay được và lý do.
+type ReplaySourceSkip struct {
+	SourceCollection string `json:"sourceCollection,omitempty"`
+	DocumentID       string `json:"documentId,omitempty"`
+	EventID          string `json:"eventId,omitempty"`
+	Reason           string `json:"reason"`
+}
+
+// ReplaySourceResult kết quả replay. Matched = số event khớp filter (chế độ FromEvents) hoặc số id truyền vào.
+type ReplaySourceResult struct {
+	DryRun   bool               `json:"dryRun"`
+	Matched  int64              `json:"matched"`
+	Replayed []ReplaySourceItem `json:"replayed"`
+	Skipped  []ReplaySourceSkip `json:"skipped,omitempty"`
+}
+
+// replaySourceRef một document nguồn + các event gốc trỏ tới nó (rỗng khi truyền id trực tiếp).
+type replaySourceRef struct {
+	Collection string
+	ID         primitive.ObjectID
+	Originals  []aidecisionmodels.DecisionEvent
+}
+
+// ReplaySourceDocuments emit lại <prefix>.changed cho từng document nguồn (dry run: chỉ kiểm tra, không emit).
+func ReplaySourceDocuments(ctx context.Context, decSvc *aidecisionsvc.AIDecisionService, in ReplaySourceInput) (*ReplaySourceResult, error) {
+	if in.OwnerOrganizationID.IsZero() {
+		return nil, common.NewError(common.ErrCodeValidationInput, "ownerOrganizationId bắt buộc", common.StatusBadRequest, nil)
+	}
+	explicit := strings.TrimSpace(in.SourceCollection) != "" || len(in.DocumentIDs) > 0
+	if explicit == (in.FromEvents != nil) {
+		return nil, common.NewError(common.ErrCodeValidationInput, "Cần đúng một trong (sourceCollection + documentIds) hoặc fromEvents", common.StatusBadRequest, nil)
+	}
+	out := &ReplaySourceResult{DryRun: in.DryRun, Replayed: []ReplaySourceItem{}}
+
+	var refs []replaySourceRef
+	if explicit {
+		var err error
+		refs, err = replayRefsFromIDs(in.SourceCollection, in.DocumentIDs)
+		if err != nil {
+			return nil, err
+		}
+		out.Matched = int64(len(refs))
+	} else {
+		f := *in.FromEvents
+		f.OwnerOrganizationID = in.OwnerOrganizationID
+		evs, matched, err := decSvc.FindRedriveCandidates(ctx, f)
+		if err != nil {
+			return nil, err
+		}
+		out.Matched = matched
+		var skipped []ReplaySourceSkip
+		refs, skipped = replayRefsFromEvents(evs)
+		out.Skipped = append(out.Skipped, skipped...)
+	}
+
+	for _, ref := range refs {
+		item, reason := replayOneSource(ctx, decSvc, in, ref)
+		if reason != "" {
+			out.Skipped = append(out.Skipped, ReplaySourceSkip{SourceCollection: ref.Collection, DocumentID: ref.ID.Hex(), Reason: reason})
+			continue
+		}
+		out.Replayed = append(out.Replayed, *item)
+	}
+	return out, nil
+}
+
+// replayRefsFromIDs kiểm tra danh sách id truyền trực tiếp (ObjectID hex, bỏ trùng, tối đa ReplaySourceMaxDocuments).
+func replayRefsFromIDs(collection string, ids []string) ([]replaySourceRef, error) {
+	collection = strings.TrimSpace(collection)
+	if collection == "" || len(ids) == 0 {
+		return nil, common.NewError(common.ErrCodeValidationInput, "sourceCollection và documentIds bắt buộc", common.StatusBadRequest, nil)
+	}
+	if len(ids) > ReplaySourceMaxDocuments {
+		return nil, common.NewError(common.ErrCodeValidationInput, "documentIds tối đa 500 phần tử", common.StatusBadRequest, nil)
+	}
+	seen := make(map[primitive.ObjectID]bool, len(ids))
+	refs := make([]replaySourceRef, 0, len(ids))
+	for _, raw := range ids {
+		oid, err := primitive.ObjectIDFromHex(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, common.NewError(common.ErrCodeValidationFormat, "documentId không hợp lệ: "+raw, common.StatusBadRequest, err)
+		}
+		if seen[oid] {
+			continue
+		}
+		seen[oid] = true
+		refs = append(refs, replaySourceRef{Collection: collection, ID: oid})
+	}
+	return refs, nil
+}
+
+// replayRefsFromEvents gom event theo (sourceCollection, normalizedRecordUid) — nhiều event cùng document chỉ replay một lần.
+// Event không có tham chiếu nguồn (không phải <prefix>.changed) bị bỏ qua.
+func replayRefsFromEvents(evs []aidecisionmodels.DecisionEvent) ([]replaySourceRef, []ReplaySourceSkip) {
+	var refs []replaySourceRef
+	var skipped []ReplaySourceSkip
+	index := make(map[string]int)
+	for _, ev := range evs {
+		src, _ := ev.Payload["sourceCollection"].(string)
+		uid, _ := ev.Payload["normalizedRecordUid"].(string)
+		oid, err := primitive.ObjectIDFromHex(strings.TrimSpace(uid))
+		if strings.TrimSpace(src) == "" || err != nil {
+			skipped = append(skipped, ReplaySourceSkip{EventID: ev.EventID, Reason: "event không có sourceCollection / normalizedRecordUid"})
+			continue
+		}
+		key := src + "|" + oid.Hex()
+		if i, ok := index[key]; ok {
+			refs[i].Originals = append(refs[i].Originals, ev)
+			continue
+		}
+		index[key] = len(refs)
+		refs = append(refs, replaySourceRef{Collection: src, ID: oid, Originals: []aidecisionmodels.DecisionEvent{ev}})
+	}
+	return refs, skipped
+}
+
+// replayOneSource kiểm tra cổng emit + document còn tồn tại và thuộc org, rồi emit (trừ dry run).
+// Trả lý do bỏ qua khi không replay được.
+func replayOneSource(ctx context.Context, decSvc *aidecisionsvc.AIDecisionService, in ReplaySourceInput, ref replaySourceRef) (*ReplaySourceItem, string) {
+	prefix, ok := sourceSyncPrefixesMap()[ref.Collection]
+	if !ok {
+		return nil, "collection không thuộc registry datachanged"
+	}
+	if !ShouldEmitDatachangedToDecisionQueue(ref.Collection) {
+		return nil, "collection đang tắt emit datachanged"
+	}
+	coll, ok := global.RegistryCollections.Get(ref.Collection)
+	if !ok {
+		return nil, "không tìm thấy collection"
+	}
+	var doc bson.M
+	if err := coll.FindOne(ctx, bson.M{"_id": ref.ID}).Decode(&doc); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, "document không còn tồn tại"
+		}
+		return nil, err.Error()
+	}
+	if events.GetOwnerOrganizationIDFromDocument(doc) != in.OwnerOrganizationID {
+		return nil, "document không thuộc tổ chức"
+	}
+	item := &ReplaySourceItem{
+		SourceCollection: ref.Collection,
+		DocumentID:       ref.ID.Hex(),
+		EventType:        eventTypeForSourceSync(prefix),
+	}
+	for _, ev := range ref.Originals {
+		item.ReplayOfEventIDs = append(item.ReplayOfEventIDs, ev.EventID)
+	}
+	if in.DryRun {
+		return item, ""
+	}
+
+	// Claim event gốc trước khi emit — request song song không replay trùng.
+	var claimed []aidecisionmodels.DecisionEvent
+	for _, ev := range ref.Originals {
+		got, err := decSvc.ClaimEventForRedrive(ctx, ev.EventID, in.RequestBy)
+		if err == nil && got {
+			claimed = append(claimed, ev)
+		}
+	}
+	if len(ref.Originals) > 0 && len(claimed) == 0 {
+		return nil, "event gốc đã được redrive / replay"
+	}
+	item.ReplayOfEventIDs = nil
+	for _, ev := range claimed {
+		item.ReplayOfEventIDs = append(item.ReplayOfEventIDs, ev.EventID)
+	}
+
+	payload := map[string]interface{}{
+		"sourceCollection":    ref.Collection,
+		"normalizedRecordUid": ref.ID.Hex(),
+		"dataChangeOperation": events.OpUpdate,
+		"replayRequestedBy":   in.RequestBy,
+	}
+	emit := sourceChangedEmitInput(prefix, ref.ID.Hex(), in.OwnerOrganizationID, payload)
+	if len(claimed) > 0 {
+		// Lineage theo event gốc đầu tiên (cũ nhất); trace mới vì side effect chạy lại trên dữ liệu hiện tại.
+		lin := aidecisionsvc.LineageFromOriginal(&claimed[0])
+		emit.CorrelationID = lin.CorrelationID
+		emit.ParentEventID = lin.ParentEventID
+		emit.RootEventID = lin.RootEventID
+		emit.CausationEventID = lin.CausationEventID
+		emit.RedriveOfEventID = claimed[0].EventID
+		payload["replayOfEventId"] = claimed[0].EventID
+	}
+	res, err := decSvc.EmitEvent(ctx, emit)
+	if err != nil {
+		for _, ev := range claimed {
+			decSvc.ReleaseRedriveClaim(ctx, ev.EventID)
+		}
+		return nil, err.Error()
+	}
+	for _, ev := range claimed {
+		decSvc.MarkEventRedrivenTo(ctx, ev.EventID, res.EventID)
+	}
+	item.NewEventID = res.EventID
+	return item, ""
+}
@@ -53,6 +53,14 @@ type DecisionEvent struct {
 
 	Error     string `json:"error,omitempty" bson:"error,omitempty"`
 	CreatedAt int64  `json:"createdAt" bson:"createdAt" index:"single:-1"`
+
+	// Redrive / replay do admin chạy lại (service.aidecision.event_redrive.go).
+	// Event mới: RedriveOfEventID = eventId gốc (cùng correlationId, parent/causation = event gốc).
+	// Event gốc: RedrivenAt, RedrivenBy, RedrivenToEventID — đã redrive thì không redrive lại.
+	RedriveOfEventID  string `json:"redriveOfEventId,omitempty" bson:"redriveOfEventId,omitempty" index:"single:1,sparse"`
+	RedrivenToEventID string `json:"redrivenToEventId,omitempty" bson:"redrivenToEventId,omitempty"`
+	RedrivenAt        *int64 `json:"redrivenAt,omitempty" bson:"redrivenAt,omitempty"`
+	RedrivenBy        string `json:"redrivenBy,omitempty" bson:"redrivenBy,omitempty"` // userId hex
 }
 
 // Event status constants
@@ -14,6 +14,7 @@ func Register(v1 fiber.Router, _ *apirouter.Router) error {
 	orgContextMiddleware := middleware.OrganizationContextMiddleware()
 	actionMiddleware := middleware.AuthMiddleware("MetaAdAccount.Update")
 	readMiddleware := middleware.AuthMiddleware("MetaAdAccount.Read")
+	redriveMiddleware := middleware.AuthMiddleware("AIDecision.Redrive")
 
 	apirouter.RegisterRouteWithMiddleware(v1, "/ai-decision/execute", "POST", "", []fiber.Handler{actionMiddleware, orgContextMiddleware}, aidecisionhdl.HandleExecute)
 	apirouter.RegisterRouteWithMiddleware(v1, "/ai-decision", "GET", "/traces/:traceId/timeline", []fiber.Handler{readMiddleware, orgContextMiddleware}, aidecisionhdl.HandleTraceTimeline)
@@ -28,6 +29,8 @@ func Register(v1 fiber.Router, _ *apirouter.Router) error {
 	apirouter.RegisterRouteWithMiddleware(v1, "/ai-decision", "GET", "/cases/:decisionCaseId", []fiber.Handler{readMiddleware, orgContextMiddleware}, aidecisionhdl.HandleGetDecisionCase)
 	apirouter.RegisterRouteWithMiddleware(v1, "/ai-decision/cases/:decisionCaseId/close", "POST", "", []fiber.Handler{actionMiddleware, orgContextMiddleware}, aidecisionhdl.HandleCloseCase)
 	apirouter.RegisterRouteWithMiddleware(v1, "/ai-decision", "GET", "/queue-events", []fiber.Handler{readMiddleware, orgContextMiddleware}, aidecisionhdl.HandleListQueueEvents)
+	apirouter.RegisterRouteWithMiddleware(v1, "/ai-decision/queue-events/redrive", "POST", "", []fiber.Handler{redriveMiddleware, orgContextMiddleware}, aidecisionhdl.HandleRedriveQueueEvents)
+	apirouter.RegisterRouteWithMiddleware(v1, "/ai-decision/queue-events/replay-source", "POST", "", []fiber.Handler{redriveMiddleware, orgContextMiddleware}, aidecisionhdl.HandleReplaySourceEvents)
 
 	apirouter.RegisterRouteWithMiddleware(v1, "/ai-decision/routing-rules", "GET", "", []fiber.Handler{actionMiddleware, orgContextMiddleware}, aidecisionhdl.HandleListRoutingRules)
 	apirouter.RegisterRouteWithMiddleware(v1, "/ai-decision/routing-rules", "POST", "", []fiber.Handler{actionMiddleware, orgContextMiddleware}, aidecisionhdl.HandleUpsertRoutingRule)
@@ -177,6 +177,7 @@ func (s *AIDecisionService) ListQueueEvents(ctx context.Context, f ListQueueEven
 		"scheduledAt": 1, "attemptCount": 1, "maxAttempts": 1,
 		"leasedBy": 1, "leasedUntil": 1, "error": 1, "createdAt": 1,
 		"parentEventId": 1, "rootEventId": 1, "causationEventId": 1,
+		"redriveOfEventId": 1, "redrivenToEventId": 1, "redrivenAt": 1, "redrivenBy": 1,
 	}
 	if f.IncludePayload {
 		proj["payload"] = 1
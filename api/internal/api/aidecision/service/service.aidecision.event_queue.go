@@ -37,6 +37,11 @@ type EmitEventInput struct {
 	TraceID       string                 `json:"traceId,omitempty"`
 	CorrelationID string                 `json:"correlationId,omitempty"`
 	Payload       map[string]interface{} `json:"payload"`
+	// Lineage (tuỳ chọn) — redrive / replay gắn event mới với event gốc.
+	ParentEventID    string `json:"parentEventId,omitempty"`
+	RootEventID      string `json:"rootEventId,omitempty"`
+	CausationEventID string `json:"causationEventId,omitempty"`
+	RedriveOfEventID string `json:"redriveOfEventId,omitempty"`
 }
 
 // EmitEventResult kết quả emit.
@@ -83,6 +88,10 @@ func (s *AIDecisionService) EmitEvent(ctx context.Context, input *EmitEventInput
 		AttemptCount:       0,
 		MaxAttempts:        5,
 		CreatedAt:          now,
+		ParentEventID:      strings.TrimSpace(input.ParentEventID),
+		RootEventID:        strings.TrimSpace(input.RootEventID),
+		CausationEventID:   strings.TrimSpace(input.CausationEventID),
+		RedriveOfEventID:   strings.TrimSpace(input.RedriveOfEventID),
 	}
 	if tid := strings.TrimSpace(input.TraceID); tid != "" {
 		doc.W3CTraceID = traceutil.W3CTraceIDFromKey(tid)
@@ -0,0 +1,402 @@
+// Package aidecisionsvc — Redrive event queue do admin chạy lại (failed_terminal, completed_no_handler…).
+//
+// Redrive không sửa event gốc thành pending — tạo event mới (attempts = 0) cùng eventType / payload,
+// giữ traceId, correlationId = correlationId gốc (hoặc eventId gốc khi trống), parent / causation = event gốc.
+// Event gốc được đánh dấu redrivenAt / redrivenToEventId — mỗi event chỉ redrive một lần (chạy lại event mới nếu cần).
+package aidecisionsvc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	aidecisionmodels "meta_commerce/internal/api/aidecision/models"
+	"meta_commerce/internal/common"
+	"meta_commerce/internal/global"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	redriveDefaultLimit = 500
+	redriveMaxLimit     = 5000
+)
+
+// redriveAllowedStatuses trạng thái đã đóng — event đang pending / leased / retry không redrive (worker còn xử lý).
+var redriveAllowedStatuses = map[string]bool{
+	aidecisionmodels.EventStatusFailedTerminal:          true,
+	aidecisionmodels.EventStatusCompletedNoHandler:      true,
+	aidecisionmodels.EventStatusCompletedRoutingSkipped: true,
+	aidecisionmodels.EventStatusCompleted:               true,
+}
+
+// redriveDefaultStatuses khi request không truyền statuses.
+var redriveDefaultStatuses = []string{
+	aidecisionmodels.EventStatusFailedTerminal,
+	aidecisionmodels.EventStatusCompletedNoHandler,
+}
+
+// RedriveQueueEventsFilter bộ lọc chọn event cần redrive / replay (luôn trong một org).
+type RedriveQueueEventsFilter struct {
+	OwnerOrganizationID primitive.ObjectID
+	EventTypes          []string
+	Statuses            []string // rỗng → failed_terminal + completed_no_handler
+	TraceID             string
+	FromCreatedMs       *int64
+	ToCreatedMs         *int64
+	Limit               int // mặc định 500, tối đa 5000
+}
+
+// RedriveQueueEventsInput tham số redrive.
+type RedriveQueueEventsInput struct {
+	Filter    RedriveQueueEventsFilter
+	Lane      string // rỗng → giữ lane gốc
+	Priority  string // rỗng → giữ priority gốc
+	DryRun    bool
+	RequestBy string // userId hex
+}
+
+// RedriveBreakdownItem số event khớp theo eventType + status (dry run).
+type RedriveBreakdownItem struct {
+	EventType string `json:"eventType"`
+	Status    string `json:"status"`
+	Count     int64  `json:"count"`
+}
+
+// RedrivenEvent cặp event gốc → event mới.
+type RedrivenEvent struct {
+	OriginalEventID string `json:"originalEventId"`
+	NewEventID      string `json:"newEventId"`
+	EventType       string `json:"eventType"`
+}
+
+// RedriveFailure event không redrive được.
+type RedriveFailure struct {
+	EventID string `json:"eventId"`
+	Error   string `json:"error"`
+}
+
+// RedriveQueueEventsResult kết quả redrive. Matched = tổng event khớp filter (không tính limit).
+type RedriveQueueEventsResult struct {
+	DryRun    bool                   `json:"dryRun"`
+	Matched   int64                  `json:"matched"`
+	Limit     int                    `json:"limit"`
+	Breakdown []RedriveBreakdownItem `json:"breakdown,omitempty"`
+	Redriven  []RedrivenEvent        `json:"redriven,omitempty"`
+	Skipped   int                    `json:"skipped"`
+	Failed    []RedriveFailure       `json:"failed,omitempty"`
+}
+
+// NormalizeRedriveLane kiểm tra lane override — rỗng hợp lệ (giữ lane gốc).
+func NormalizeRedriveLane(lane string) (string, error) {
+	lane = strings.ToLower(strings.TrimSpace(lane))
+	switch lane {
+	case "", aidecisionmodels.EventLaneFast, aidecisionmodels.EventLaneNormal, aidecisionmodels.EventLaneBatch:
+		return lane, nil
+	}
+	return "", common.NewError(common.ErrCodeValidationInput, "lane phải là fast, normal hoặc batch", common.StatusBadRequest, nil)
+}
+
+// NormalizeRedrivePriority kiểm tra priority override — rỗng hợp lệ (giữ priority gốc).
+func NormalizeRedrivePriority(priority string) (string, error) {
+	priority = strings.ToLower(strings.TrimSpace(priority))
+	switch priority {
+	case "", "high", "normal", "low":
+		return priority, nil
+	}
+	return "", common.NewError(common.ErrCodeValidationInput, "priority phải là high, normal hoặc low", common.StatusBadRequest, nil)
+}
+
+// normalizeRedriveLimit áp mặc định / trần cho limit.
+func normalizeRedriveLimit(limit int) int {
+	if limit < 1 {
+		return redriveDefaultLimit
+	}
+	if limit > redriveMaxLimit {
+		return redriveMaxLimit
+	}
+	return limit
+}
+
+// BuildRedriveQueueFilter dựng filter Mongo: org + status đã đóng + chưa redrive, kèm eventType / traceId / khoảng createdAt.
+func BuildRedriveQueueFilter(f RedriveQueueEventsFilter) (bson.M, error) {
+	if f.OwnerOrganizationID.IsZero() {
+		return nil, common.NewError(common.ErrCodeValidationInput, "ownerOrganizationId bắt buộc", common.StatusBadRequest, nil)
+	}
+	statuses := make([]string, 0, len(f.Statuses))
+	for _, s := range f.Statuses {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !redriveAllowedStatuses[s] {
+			return nil, common.NewError(common.ErrCodeValidationInput, "status không redrive được: "+s+" (chỉ failed_terminal, completed_no_handler, completed_routing_skipped, completed)", common.StatusBadRequest, nil)
+		}
+		statuses = append(statuses, s)
+	}
+	if len(statuses) == 0 {
+		statuses = redriveDefaultStatuses
+	}
+	filter := bson.M{
+		"ownerOrganizationId": f.OwnerOrganizationID,
+		"status":              bson.M{"$in": statuses},
+		"redrivenAt":          bson.M{"$exists": false},
+	}
+	eventTypes := make([]string, 0, len(f.EventTypes))
+	for _, t := range f.EventTypes {
+		if t = strings.TrimSpace(t); t != "" {
+			eventTypes = append(eventTypes, t)
+		}
+	}
+	if len(eventTypes) > 0 {
+		filter["eventType"] = bson.M{"$in": eventTypes}
+	}
+	if t := strings.TrimSpace(f.TraceID); t != "" {
+		filter["traceId"] = t
+	}
+	if f.FromCreatedMs != nil && f.ToCreatedMs != nil && *f.FromCreatedMs > *f.ToCreatedMs {
+		return nil, common.NewError(common.ErrCodeValidationInput, "fromCreatedMs phải nhỏ hơn hoặc bằng toCreatedMs", common.StatusBadRequest, nil)
+	}
+	if f.FromCreatedMs != nil || f.ToCreatedMs != nil {
+		rng := bson.M{}
+		if f.FromCreatedMs != nil {
+			rng["$gte"] = *f.FromCreatedMs
+		}
+		if f.ToCreatedMs != nil {
+			rng["$lte"] = *f.ToCreatedMs
+		}
+		filter["createdAt"] = rng
+	}
+	return filter, nil
+}
+
+// RedriveLineage lineage cho event mới sinh từ event gốc (redrive hoặc replay).
+type RedriveLineage struct {
+	ParentEventID    string
+	RootEventID      string
+	CausationEventID string
+	CorrelationID    string
+}
+
+// LineageFromOriginal — parent / causation = event gốc; root giữ root của chuỗi; correlationId gốc (trống → eventId gốc).
+func LineageFromOriginal(orig *aidecisionmodels.DecisionEvent) RedriveLineage {
+	root := strings.TrimSpace(orig.RootEventID)
+	if root == "" {
+		root = orig.EventID
+	}
+	corr := strings.TrimSpace(orig.CorrelationID)
+	if corr == "" {
+		corr = orig.EventID
+	}
+	return RedriveLineage{
+		ParentEventID:    orig.EventID,
+		RootEventID:      root,
+		CausationEventID: orig.EventID,
+		CorrelationID:    corr,
+	}
+}
+
+// RedriveQueueEvents đếm (dry run) hoặc redrive các event khớp filter, cũ trước (createdAt tăng dần).
+func (s *AIDecisionService) RedriveQueueEvents(ctx context.Context, in RedriveQueueEventsInput) (*RedriveQueueEventsResult, error) {
+	coll, ok := global.RegistryCollections.Get(global.MongoDB_ColNames.DecisionEventsQueue)
+	if !ok {
+		return nil, errors.New("không tìm thấy collection decision_events_queue")
+	}
+	lane, err := NormalizeRedriveLane(in.Lane)
+	if err != nil {
+		return nil, err
+	}
+	priority, err := NormalizeRedrivePriority(in.Priority)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := BuildRedriveQueueFilter(in.Filter)
+	if err != nil {
+		return nil, err
+	}
+	limit := normalizeRedriveLimit(in.Filter.Limit)
+	matched, err := coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	out := &RedriveQueueEventsResult{DryRun: in.DryRun, Matched: matched, Limit: limit}
+	if in.DryRun {
+		out.Breakdown, err = redriveBreakdown(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		return out, nil
+	}
+	if matched == 0 {
+		return out, nil
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: 1}}).
+		SetLimit(int64(limit))
+	cur, err := coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var originals []aidecisionmodels.DecisionEvent
+	if err := cur.All(ctx, &originals); err != nil {
+		return nil, err
+	}
+
+	for i := range originals {
+		orig := &originals[i]
+		claimed, err := s.ClaimEventForRedrive(ctx, orig.EventID, in.RequestBy)
+		if err != nil {
+			out.Failed = append(out.Failed, RedriveFailure{EventID: orig.EventID, Error: err.Error()})
+			continue
+		}
+		if !claimed {
+			out.Skipped++
+			continue
+		}
+		lin := LineageFromOriginal(orig)
+		evLane := lane
+		if evLane == "" {
+			evLane = orig.Lane
+		}
+		evPriority := priority
+		if evPriority == "" {
+			evPriority = orig.Priority
+		}
+		emitted, err := s.EmitEvent(ctx, &EmitEventInput{
+			EventType:        orig.EventType,
+			EventSource:      orig.EventSource,
+			PipelineStage:    orig.PipelineStage,
+			EntityType:       orig.EntityType,
+			EntityID:         orig.EntityID,
+			OrgID:            orig.OrgID,
+			OwnerOrgID:       orig.OwnerOrganizationID,
+			Priority:         evPriority,
+			Lane:             evLane,
+			TraceID:          orig.TraceID,
+			CorrelationID:    lin.CorrelationID,
+			Payload:          orig.Payload,
+			ParentEventID:    lin.ParentEventID,
+			RootEventID:      lin.RootEventID,
+			CausationEventID: lin.CausationEventID,
+			RedriveOfEventID: orig.EventID,
+		})
+		if err != nil {
+			s.ReleaseRedriveClaim(ctx, orig.EventID)
+			out.Failed = append(out.Failed, RedriveFailure{EventID: orig.EventID, Error: err.Error()})
+			continue
+		}
+		s.MarkEventRedrivenTo(ctx, orig.EventID, emitted.EventID)
+		out.Redriven = append(out.Redriven, RedrivenEvent{OriginalEventID: orig.EventID, NewEventID: emitted.EventID, EventType: orig.EventType})
+	}
+	return out, nil
+}
+
+// ClaimEventForRedrive đánh dấu redrivenAt / redrivenBy nếu event chưa redrive — claim trước khi emit để
+// hai request song song không chạy trùng một event. false = đã có request khác redrive.
+func (s *AIDecisionService) ClaimEventForRedrive(ctx context.Context, eventID, requestBy string) (bool, error) {
+	coll, ok := global.RegistryCollections.Get(global.MongoDB_ColNames.DecisionEventsQueue)
+	if !ok {
+		return false, errors.New("không tìm thấy collection decision_events_queue")
+	}
+	res, err := coll.UpdateOne(ctx,
+		bson.M{"eventId": eventID, "redrivenAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"redrivenAt": time.Now().UnixMilli(), "redrivenBy": requestBy}})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+// ReleaseRedriveClaim nhả claim khi emit lỗi — lần redrive sau chọn lại event này.
+func (s *AIDecisionService) ReleaseRedriveClaim(ctx context.Context, eventID string) {
+	coll, ok := global.RegistryCollections.Get(global.MongoDB_ColNames.DecisionEventsQueue)
+	if !ok {
+		return
+	}
+	_, _ = coll.UpdateOne(ctx, bson.M{"eventId": eventID}, bson.M{"$unset": bson.M{"redrivenAt": "", "redrivenBy": ""}})
+}
+
+// MarkEventRedrivenTo ghi eventId mới lên event gốc (tra ngược từ event gốc sang lần chạy lại).
+func (s *AIDecisionService) MarkEventRedrivenTo(ctx context.Context, eventID, newEventID string) {
+	coll, ok := global.RegistryCollections.Get(global.MongoDB_ColNames.DecisionEventsQueue)
+	if !ok {
+		return
+	}
+	_, _ = coll.UpdateOne(ctx, bson.M{"eventId": eventID}, bson.M{"$set": bson.M{"redrivenToEventId": newEventID}})
+}
+
+// FindRedriveCandidates trả tối đa limit event khớp filter (createdAt tăng dần) — dùng cho replay từ nguồn.
+// Chỉ đọc eventId / eventType / lineage / payload.
+func (s *AIDecisionService) FindRedriveCandidates(ctx context.Context, f RedriveQueueEventsFilter) ([]aidecisionmodels.DecisionEvent, int64, error) {
+	coll, ok := global.RegistryCollections.Get(global.MongoDB_ColNames.DecisionEventsQueue)
+	if !ok {
+		return nil, 0, errors.New("không tìm thấy collection decision_events_queue")
+	}
+	filter, err := BuildRedriveQueueFilter(f)
+	if err != nil {
+		return nil, 0, err
+	}
+	matched, err := coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	if matched == 0 {
+		return []aidecisionmodels.DecisionEvent{}, 0, nil
+	}
+	opts := options.Find().
+		SetProjection(bson.M{
+			"eventId": 1, "eventType": 1, "status": 1, "ownerOrganizationId": 1,
+			"rootEventId": 1, "correlationId": 1, "traceId": 1, "payload": 1, "createdAt": 1,
+		}).
+		SetSort(bson.D{{Key: "createdAt", Value: 1}}).
+		SetLimit(int64(normalizeRedriveLimit(f.Limit)))
+	cur, err := coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	var out []aidecisionmodels.DecisionEvent
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, 0, err
+	}
+	return out, matched, nil
+}
+
+// redriveBreakdown đếm theo eventType + status cho dry run.
+func redriveBreakdown(ctx context.Context, filter bson.M) ([]RedriveBreakdownItem, error) {
+	coll, ok := global.RegistryCollections.Get(global.MongoDB_ColNames.DecisionEventsQueue)
+	if !ok {
+		return nil, errors.New("không tìm thấy collection decision_events_queue")
+	}
+	pipeline := []bson.M{
+		{"$match": filter},
+		{"$group": bson.M{
+			"_id":   bson.M{"eventType": "$eventType", "status": "$status"},
+			"count": bson.M{"$sum": 1},
+		}},
+		{"$sort": bson.M{"count": -1}},
+	}
+	cur, err := coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	out := []RedriveBreakdownItem{}
+	for cur.Next(ctx) {
+		var row struct {
+			ID struct {
+				EventType string `bson:"eventType"`
+				Status    string `bson:"status"`
+			} `bson:"_id"`
+			Count int64 `bson:"count"`
+		}
+		if err := cur.Decode(&row); err != nil {
+			return nil, err
+		}
+		out = append(out, RedriveBreakdownItem{EventType: row.ID.EventType, Status: row.ID.Status, Count: row.Count})
+	}
+	return out, cur.Err()
+}
@@ -0,0 +1,102 @@
+package aidecisionsvc
+
+import (
+	"testing"
+
+	aidecisionmodels "meta_commerce/internal/api/aidecision/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestBuildRedriveQueueFilter_defaults(t *testing.T) {
+	org := primitive.NewObjectID()
+	f, err := BuildRedriveQueueFilter(RedriveQueueEventsFilter{OwnerOrganizationID: org})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f["ownerOrganizationId"] != org {
+		t.Fatal("thiếu ownerOrganizationId")
+	}
+	st := f["status"].(bson.M)["$in"].([]string)
+	if len(st) != 2 || st[0] != aidecisionmodels.EventStatusFailedTerminal || st[1] != aidecisionmodels.EventStatusCompletedNoHandler {
+		t.Fatalf("status mặc định sai: %v", st)
+	}
+	if _, ok := f["redrivenAt"]; !ok {
+		t.Fatal("phải loại event đã redrive")
+	}
+	if _, ok := f["eventType"]; ok {
+		t.Fatal("không truyền eventTypes thì không lọc eventType")
+	}
+}
+
+func TestBuildRedriveQueueFilter_fields(t *testing.T) {
+	from, to := int64(100), int64(200)
+	f, err := BuildRedriveQueueFilter(RedriveQueueEventsFilter{
+		OwnerOrganizationID: primitive.NewObjectID(),
+		EventTypes:          []string{" order.changed ", ""},
+		Statuses:            []string{aidecisionmodels.EventStatusCompleted},
+		TraceID:             "trc_1",
+		FromCreatedMs:       &from,
+		ToCreatedMs:         &to,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if et := f["eventType"].(bson.M)["$in"].([]string); len(et) != 1 || et[0] != "order.changed" {
+		t.Fatalf("eventType: %v", et)
+	}
+	if f["traceId"] != "trc_1" {
+		t.Fatal("thiếu traceId")
+	}
+	rng := f["createdAt"].(bson.M)
+	if rng["$gte"] != from || rng["$lte"] != to {
+		t.Fatalf("createdAt: %v", rng)
+	}
+}
+
+func TestBuildRedriveQueueFilter_invalid(t *testing.T) {
+	if _, err := BuildRedriveQueueFilter(RedriveQueueEventsFilter{}); err == nil {
+		t.Fatal("thiếu org phải lỗi")
+	}
+	org := primitive.NewObjectID()
+	if _, err := BuildRedriveQueueFilter(RedriveQueueEventsFilter{OwnerOrganizationID: org, Statuses: []string{aidecisionmodels.EventStatusPending}}); err == nil {
+		t.Fatal("pending không được redrive")
+	}
+	from, to := int64(200), int64(100)
+	if _, err := BuildRedriveQueueFilter(RedriveQueueEventsFilter{OwnerOrganizationID: org, FromCreatedMs: &from, ToCreatedMs: &to}); err == nil {
+		t.Fatal("khoảng thời gian ngược phải lỗi")
+	}
+}
+
+func TestNormalizeRedriveOverrides(t *testing.T) {
+	if v, err := NormalizeRedriveLane(" Batch "); err != nil || v != aidecisionmodels.EventLaneBatch {
+		t.Fatalf("lane batch: %q %v", v, err)
+	}
+	if v, err := NormalizeRedriveLane(""); err != nil || v != "" {
+		t.Fatal("lane rỗng giữ lane gốc")
+	}
+	if _, err := NormalizeRedriveLane("slow"); err == nil {
+		t.Fatal("lane không hợp lệ phải lỗi")
+	}
+	if v, err := NormalizeRedrivePriority("HIGH"); err != nil || v != "high" {
+		t.Fatalf("priority high: %q %v", v, err)
+	}
+	if _, err := NormalizeRedrivePriority("urgent"); err == nil {
+		t.Fatal("priority không hợp lệ phải lỗi")
+	}
+	if normalizeRedriveLimit(0) != redriveDefaultLimit || normalizeRedriveLimit(99999) != redriveMaxLimit || normalizeRedriveLimit(10) != 10 {
+		t.Fatal("limit mặc định / trần sai")
+	}
+}
+
+func TestLineageFromOriginal(t *testing.T) {
+	lin := LineageFromOriginal(&aidecisionmodels.DecisionEvent{EventID: "evt_a"})
+	if lin.ParentEventID != "evt_a" || lin.CausationEventID != "evt_a" || lin.RootEventID != "evt_a" || lin.CorrelationID != "evt_a" {
+		t.Fatalf("event gốc không lineage: %+v", lin)
+	}
+	lin = LineageFromOriginal(&aidecisionmodels.DecisionEvent{EventID: "evt_b", RootEventID: "evt_root", CorrelationID: "cor_1"})
+	if lin.ParentEventID != "evt_b" || lin.RootEventID != "evt_root" || lin.CorrelationID != "cor_1" {
+		t.Fatalf("giữ root / correlation: %+v", lin)
+	}
+}
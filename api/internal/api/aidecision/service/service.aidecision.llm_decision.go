@@ -91,7 +91,7 @@ func newAIDecisionLLMFromProfile(ctx context.Context, ownerOrgID primitive.Objec
 			return nil
 		}
 	}
-	if strings.TrimSpace(p.APIKey) == "" {
+	if strings.TrimSpace(p.APIKey.String()) == "" {
 		return nil
 	}
 	model := defaultAIDecisionLLMModel
@@ -102,7 +102,7 @@ func newAIDecisionLLMFromProfile(ctx context.Context, ownerOrgID primitive.Objec
 	} else if len(p.AvailableModels) > 0 {
 		model = p.AvailableModels[0]
 	}
-	return newAIDecisionLLMWithCreds(p.APIKey, model, p.BaseURL)
+	return newAIDecisionLLMWithCreds(p.APIKey.String(), model, p.BaseURL)
 }
 
 func newAIDecisionLLMWithCreds(apiKey, model, baseURL string) *aidecisionLLMClient {
@@ -0,0 +1,24 @@
+package authdto
+
+// RateLimitPolicyCreateInput dùng cho tạo policy rate limit (ownerOrganizationId bỏ trống = tổ chức đang làm việc).
+type RateLimitPolicyCreateInput struct {
+	OwnerOrganizationID    string `json:"ownerOrganizationId,omitempty" transform:"str_objectid,optional"`
+	RouteGroup             string `json:"routeGroup" validate:"required,oneof=crud ingest reports ai"`
+	Scope                  string `json:"scope" validate:"required,oneof=organization user api_key"`
+	BurstLimit             int    `json:"burstLimit,omitempty" validate:"min=0"`
+	BurstWindowSeconds     int    `json:"burstWindowSeconds,omitempty" validate:"min=0"`
+	SustainedLimit         int    `json:"sustainedLimit,omitempty" validate:"min=0"`
+	SustainedWindowSeconds int    `json:"sustainedWindowSeconds,omitempty" validate:"min=0"`
+	DailyQuota             int    `json:"dailyQuota,omitempty" validate:"min=0"`
+	IsDisabled             bool   `json:"isDisabled,omitempty"`
+}
+
+// RateLimitPolicyUpdateInput dùng cho cập nhật policy rate limit.
+type RateLimitPolicyUpdateInput struct {
+	BurstLimit             *int  `json:"burstLimit,omitempty" validate:"omitempty,min=0"`
+	BurstWindowSeconds     *int  `json:"burstWindowSeconds,omitempty" validate:"omitempty,min=0"`
+	SustainedLimit         *int  `json:"sustainedLimit,omitempty" validate:"omitempty,min=0"`
+	SustainedWindowSeconds *int  `json:"sustainedWindowSeconds,omitempty" validate:"omitempty,min=0"`
+	DailyQuota             *int  `json:"dailyQuota,omitempty" validate:"omitempty,min=0"`
+	IsDisabled             *bool `json:"isDisabled,omitempty"`
+}
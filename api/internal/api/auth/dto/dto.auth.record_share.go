@@ -0,0 +1,16 @@
+package authdto
+
+// RecordShareGrantCreateInput tạo grant chia sẻ bản ghi. Chọn bản ghi bằng recordIds hoặc matchField + matchValues;
+// bên nhận là granteeOrganizationId hoặc granteeUserId. ownerOrganizationId bỏ trống = tổ chức đang làm việc.
+type RecordShareGrantCreateInput struct {
+	OwnerOrganizationID   string        `json:"ownerOrganizationId,omitempty"`
+	Collection            string        `json:"collection" validate:"required"`
+	RecordIDs             []string      `json:"recordIds,omitempty"`
+	MatchField            string        `json:"matchField,omitempty"`
+	MatchValues           []interface{} `json:"matchValues,omitempty"`
+	GranteeOrganizationID string        `json:"granteeOrganizationId,omitempty"`
+	GranteeUserID         string        `json:"granteeUserId,omitempty"`
+	Access                string        `json:"access" validate:"required,oneof=read read_write"`
+	ExpiresAt             int64         `json:"expiresAt,omitempty"` // Unix milli, 0 = không hết hạn
+	Description           string        `json:"description,omitempty"`
+}
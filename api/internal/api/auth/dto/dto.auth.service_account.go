@@ -0,0 +1,17 @@
+package authdto
+
+// ServiceAccountCreateInput đầu vào tạo service account (thuộc tổ chức của role đang làm việc).
+type ServiceAccountCreateInput struct {
+	Name        string `json:"name" validate:"required"`
+	Description string `json:"description"`
+	RoleID      string `json:"roleId" validate:"required"` // Role của tổ chức gán cho service account
+}
+
+// APIKeyCreateInput đầu vào tạo API key cho service account.
+type APIKeyCreateInput struct {
+	ServiceAccountID string   `json:"serviceAccountId" validate:"required"`
+	Name             string   `json:"name" validate:"required"`
+	Scopes           []string `json:"scopes" validate:"required,min=1"` // Tập con permission của role service account
+	AllowedIPs       []string `json:"allowedIps"`                       // IP hoặc CIDR; rỗng = mọi IP
+	ExpiresAt        int64    `json:"expiresAt"`                        // Unix milli; 0 = không hết hạn
+}
@@ -16,6 +16,11 @@ type UserLogoutInput struct {
 	Hwid string `json:"hwid" validate:"required"`
 }
 
+// AuthRefreshInput đầu vào đổi refresh token (hoặc token cũ chưa có hạn) lấy cặp token mới.
+type AuthRefreshInput struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
 // UserChangeInfoInput đầu vào thay đổi thông tin người dùng.
 type UserChangeInfoInput struct {
 	Name string `json:"name"`
@@ -0,0 +1,28 @@
+package authhdl
+
+import (
+	"fmt"
+
+	authdto "meta_commerce/internal/api/auth/dto"
+	models "meta_commerce/internal/api/auth/models"
+	authsvc "meta_commerce/internal/api/auth/service"
+	basehdl "meta_commerce/internal/api/base/handler"
+)
+
+// RateLimitPolicyHandler xử lý CRUD policy rate limit. Thay đổi có hiệu lực sau tối đa 30 giây, không cần restart.
+type RateLimitPolicyHandler struct {
+	*basehdl.BaseHandler[models.RateLimitPolicy, authdto.RateLimitPolicyCreateInput, authdto.RateLimitPolicyUpdateInput]
+	RateLimitService *authsvc.RateLimitService
+}
+
+// NewRateLimitPolicyHandler tạo mới RateLimitPolicyHandler
+func NewRateLimitPolicyHandler() (*RateLimitPolicyHandler, error) {
+	rateLimitService, err := authsvc.NewRateLimitService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rate limit service: %v", err)
+	}
+	return &RateLimitPolicyHandler{
+		BaseHandler:      basehdl.NewBaseHandler[models.RateLimitPolicy, authdto.RateLimitPolicyCreateInput, authdto.RateLimitPolicyUpdateInput](rateLimitService),
+		RateLimitService: rateLimitService,
+	}, nil
+}
@@ -0,0 +1,162 @@
+package authhdl
+
+import (
+	"fmt"
+
+	authdto "meta_commerce/internal/api/auth/dto"
+	models "meta_commerce/internal/api/auth/models"
+	authsvc "meta_commerce/internal/api/auth/service"
+	basehdl "meta_commerce/internal/api/base/handler"
+	"meta_commerce/internal/common"
+
+	"github.com/gofiber/fiber/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RecordShareHandler xử lý grant chia sẻ theo bản ghi: CRUD đọc (phía chia sẻ) + tạo, thu hồi, danh sách được nhận.
+type RecordShareHandler struct {
+	*basehdl.BaseHandler[models.RecordShareGrant, authdto.RecordShareGrantCreateInput, authdto.RecordShareGrantCreateInput]
+	RecordShareService *authsvc.RecordShareService
+}
+
+// NewRecordShareHandler tạo mới RecordShareHandler
+func NewRecordShareHandler() (*RecordShareHandler, error) {
+	recordShareService, err := authsvc.NewRecordShareService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create record share service: %v", err)
+	}
+	return &RecordShareHandler{
+		BaseHandler:        basehdl.NewBaseHandler[models.RecordShareGrant, authdto.RecordShareGrantCreateInput, authdto.RecordShareGrantCreateInput](recordShareService),
+		RecordShareService: recordShareService,
+	}, nil
+}
+
+// HandleCreate POST /record-share/create — tạo grant; user phải có quyền với tổ chức chia sẻ.
+func (h *RecordShareHandler) HandleCreate(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		var input authdto.RecordShareGrantCreateInput
+		if err := h.ParseRequestBody(c, &input); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		grant, err := recordShareGrantFromInput(input)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		if grant.OwnerOrganizationID.IsZero() {
+			orgID := h.GetActiveOrganizationID(c)
+			if orgID == nil {
+				h.HandleResponse(c, nil, common.NewError(common.ErrCodeAuthRole, "Không có organization context", common.StatusBadRequest, nil))
+				return nil
+			}
+			grant.OwnerOrganizationID = *orgID
+		}
+		if err := h.ValidateUserHasAccessToOrg(c, grant.OwnerOrganizationID); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		grant.CreatedBy, _, _ = currentSession(c)
+		created, err := h.RecordShareService.CreateGrant(c.Context(), grant)
+		h.HandleResponse(c, created, err)
+		return nil
+	})
+}
+
+// HandleRevoke POST /record-share/revoke/:id — thu hồi grant của tổ chức mình.
+func (h *RecordShareHandler) HandleRevoke(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		grantID, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "Grant ID không hợp lệ", common.StatusBadRequest, err))
+			return nil
+		}
+		grant, err := h.RecordShareService.FindOne(c.Context(), h.ApplyOrganizationFilter(c, bson.M{"_id": grantID}), nil)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		revoked, err := h.RecordShareService.RevokeGrant(c.Context(), grant)
+		h.HandleResponse(c, revoked, err)
+		return nil
+	})
+}
+
+// HandleListReceived GET /record-share-received — grant còn hiệu lực mà user hoặc tổ chức đang làm việc được nhận.
+func (h *RecordShareHandler) HandleListReceived(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		userID, _, err := currentSession(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		grantees := []bson.M{{"granteeUserId": userID}}
+		if orgID := h.GetActiveOrganizationID(c); orgID != nil {
+			grantees = append(grantees, bson.M{"granteeOrganizationId": *orgID})
+		}
+		grants, err := h.RecordShareService.Find(c.Context(), bson.M{"$or": grantees, "revokedAt": 0}, nil)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		h.HandleResponse(c, grants, nil)
+		return nil
+	})
+}
+
+// RecordShareAccessHandler nhật ký truy cập bản ghi được chia sẻ (CRUD chỉ đọc, lọc theo tổ chức chia sẻ).
+type RecordShareAccessHandler struct {
+	*basehdl.BaseHandler[models.RecordShareAccess, models.RecordShareAccess, models.RecordShareAccess]
+}
+
+// NewRecordShareAccessHandler tạo mới RecordShareAccessHandler
+func NewRecordShareAccessHandler() (*RecordShareAccessHandler, error) {
+	recordShareService, err := authsvc.NewRecordShareService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create record share service: %v", err)
+	}
+	return &RecordShareAccessHandler{
+		BaseHandler: basehdl.NewBaseHandler[models.RecordShareAccess, models.RecordShareAccess, models.RecordShareAccess](recordShareService.AccessLogs()),
+	}, nil
+}
+
+// recordShareGrantFromInput chuyển input (id dạng hex) sang model; kiểm tra nghiệp vụ ở service.
+func recordShareGrantFromInput(input authdto.RecordShareGrantCreateInput) (models.RecordShareGrant, error) {
+	grant := models.RecordShareGrant{
+		Collection:  input.Collection,
+		MatchField:  input.MatchField,
+		MatchValues: input.MatchValues,
+		Access:      input.Access,
+		ExpiresAt:   input.ExpiresAt,
+		Description: input.Description,
+	}
+	parse := func(field, hex string) (primitive.ObjectID, error) {
+		if hex == "" {
+			return primitive.NilObjectID, nil
+		}
+		id, err := primitive.ObjectIDFromHex(hex)
+		if err != nil {
+			return primitive.NilObjectID, common.NewError(common.ErrCodeValidationFormat, field+" không hợp lệ", common.StatusBadRequest, err)
+		}
+		return id, nil
+	}
+	var err error
+	if grant.OwnerOrganizationID, err = parse("ownerOrganizationId", input.OwnerOrganizationID); err != nil {
+		return grant, err
+	}
+	if grant.GranteeOrganizationID, err = parse("granteeOrganizationId", input.GranteeOrganizationID); err != nil {
+		return grant, err
+	}
+	if grant.GranteeUserID, err = parse("granteeUserId", input.GranteeUserID); err != nil {
+		return grant, err
+	}
+	for _, hex := range input.RecordIDs {
+		id, err := parse("recordIds", hex)
+		if err != nil {
+			return grant, err
+		}
+		grant.RecordIDs = append(grant.RecordIDs, id)
+	}
+	return grant, nil
+}
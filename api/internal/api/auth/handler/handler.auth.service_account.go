@@ -0,0 +1,167 @@
+package authhdl
+
+import (
+	"fmt"
+
+	authdto "meta_commerce/internal/api/auth/dto"
+	models "meta_commerce/internal/api/auth/models"
+	authsvc "meta_commerce/internal/api/auth/service"
+	basehdl "meta_commerce/internal/api/base/handler"
+	"meta_commerce/internal/common"
+
+	"github.com/gofiber/fiber/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ServiceAccountHandler xử lý service account: CRUD đọc + tạo, tắt/bật.
+type ServiceAccountHandler struct {
+	*basehdl.BaseHandler[models.ServiceAccount, authdto.ServiceAccountCreateInput, authdto.ServiceAccountCreateInput]
+	serviceAccountService *authsvc.ServiceAccountService
+}
+
+// NewServiceAccountHandler tạo mới ServiceAccountHandler
+func NewServiceAccountHandler() (*ServiceAccountHandler, error) {
+	serviceAccountService, err := authsvc.NewServiceAccountService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create service account service: %v", err)
+	}
+	return &ServiceAccountHandler{
+		BaseHandler:           basehdl.NewBaseHandler[models.ServiceAccount, authdto.ServiceAccountCreateInput, authdto.ServiceAccountCreateInput](serviceAccountService),
+		serviceAccountService: serviceAccountService,
+	}, nil
+}
+
+// HandleCreate POST /service-account/create — tạo service account trong tổ chức của role đang làm việc.
+func (h *ServiceAccountHandler) HandleCreate(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		var input authdto.ServiceAccountCreateInput
+		if err := h.ParseRequestBody(c, &input); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		orgID := h.GetActiveOrganizationID(c)
+		if orgID == nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeAuthRole, "Không có organization context", common.StatusBadRequest, nil))
+			return nil
+		}
+		roleID, err := primitive.ObjectIDFromHex(input.RoleID)
+		if err != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "roleId không hợp lệ", common.StatusBadRequest, err))
+			return nil
+		}
+		createdBy, _, _ := currentSession(c)
+		activeRoleID, _ := c.Locals("active_role_id").(string)
+		callerRoleID, _ := primitive.ObjectIDFromHex(activeRoleID)
+		account, err := h.serviceAccountService.Create(c.Context(), *orgID, roleID, createdBy, callerRoleID, input.Name, input.Description)
+		h.HandleResponse(c, account, err)
+		return nil
+	})
+}
+
+// HandleSetDisabled POST /service-account/:id/disable | /:id/enable — tắt thì mọi API key của account bị từ chối.
+func (h *ServiceAccountHandler) HandleSetDisabled(disabled bool) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		return h.SafeHandler(c, func() error {
+			account, err := findServiceAccount(c, h.BaseHandler, h.serviceAccountService, c.Params("id"))
+			if err != nil {
+				h.HandleResponse(c, nil, err)
+				return nil
+			}
+			updated, err := h.serviceAccountService.SetDisabled(c.Context(), *account, disabled)
+			h.HandleResponse(c, updated, err)
+			return nil
+		})
+	}
+}
+
+// APIKeyHandler xử lý API key của service account: CRUD đọc + tạo, thu hồi.
+type APIKeyHandler struct {
+	*basehdl.BaseHandler[models.APIKey, authdto.APIKeyCreateInput, authdto.APIKeyCreateInput]
+	serviceAccountService *authsvc.ServiceAccountService
+	accountHandler        *basehdl.BaseHandler[models.ServiceAccount, authdto.ServiceAccountCreateInput, authdto.ServiceAccountCreateInput]
+}
+
+// NewAPIKeyHandler tạo mới APIKeyHandler
+func NewAPIKeyHandler() (*APIKeyHandler, error) {
+	serviceAccountService, err := authsvc.NewServiceAccountService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create service account service: %v", err)
+	}
+	hdl := &APIKeyHandler{
+		BaseHandler:           basehdl.NewBaseHandler[models.APIKey, authdto.APIKeyCreateInput, authdto.APIKeyCreateInput](serviceAccountService.APIKeys()),
+		serviceAccountService: serviceAccountService,
+		accountHandler:        basehdl.NewBaseHandler[models.ServiceAccount, authdto.ServiceAccountCreateInput, authdto.ServiceAccountCreateInput](serviceAccountService),
+	}
+	hdl.SetFilterOptions(basehdl.FilterOptions{
+		DeniedFields:     []string{"keyHash"},
+		AllowedOperators: []string{"$eq", "$gt", "$gte", "$lt", "$lte", "$in", "$nin", "$exists"},
+		MaxFields:        10,
+	})
+	return hdl, nil
+}
+
+// HandleCreate POST /service-account-key/create — tạo API key; khóa đầy đủ chỉ trả về một lần trong response này.
+func (h *APIKeyHandler) HandleCreate(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		var input authdto.APIKeyCreateInput
+		if err := h.ParseRequestBody(c, &input); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		account, err := findServiceAccount(c, h.accountHandler, h.serviceAccountService, input.ServiceAccountID)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		createdBy, _, _ := currentSession(c)
+		key, plaintext, err := h.serviceAccountService.CreateAPIKey(c.Context(), *account, authsvc.CreateAPIKeyInput{
+			Name:       input.Name,
+			Scopes:     input.Scopes,
+			AllowedIPs: input.AllowedIPs,
+			ExpiresAt:  input.ExpiresAt,
+			CreatedBy:  createdBy,
+		})
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		h.HandleResponse(c, map[string]interface{}{
+			"apiKey": key,
+			"key":    plaintext,
+			"note":   "Lưu khóa ngay — hệ thống chỉ lưu hash, không hiển thị lại.",
+		}, nil)
+		return nil
+	})
+}
+
+// HandleRevoke POST /service-account-key/revoke/:id — thu hồi API key.
+func (h *APIKeyHandler) HandleRevoke(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		keyID, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "API key ID không hợp lệ", common.StatusBadRequest, err))
+			return nil
+		}
+		key, err := h.serviceAccountService.FindAPIKey(c.Context(), h.ApplyOrganizationFilter(c, bson.M{"_id": keyID}))
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		h.HandleResponse(c, nil, h.serviceAccountService.RevokeAPIKey(c.Context(), *key))
+		return nil
+	})
+}
+
+// findServiceAccount tìm service account theo id trong phạm vi tổ chức role hiện tại được phép.
+func findServiceAccount(c fiber.Ctx, h *basehdl.BaseHandler[models.ServiceAccount, authdto.ServiceAccountCreateInput, authdto.ServiceAccountCreateInput], svc *authsvc.ServiceAccountService, id string) (*models.ServiceAccount, error) {
+	accountID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, common.NewError(common.ErrCodeValidationFormat, "Service account ID không hợp lệ", common.StatusBadRequest, err)
+	}
+	account, err := svc.BaseServiceMongoImpl.FindOne(c.Context(), h.ApplyOrganizationFilter(c, bson.M{"_id": accountID}), nil)
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
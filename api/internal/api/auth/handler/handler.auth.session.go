@@ -0,0 +1,108 @@
+package authhdl
+
+import (
+	authdto "meta_commerce/internal/api/auth/dto"
+	authsvc "meta_commerce/internal/api/auth/service"
+	"meta_commerce/internal/common"
+
+	"github.com/gofiber/fiber/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sessionMetaFromRequest thông tin thiết bị ghi kèm phiên (hwid do service gán từ input).
+func sessionMetaFromRequest(c fiber.Ctx) authsvc.SessionMeta {
+	return authsvc.SessionMeta{UserAgent: c.Get("User-Agent"), IP: c.IP()}
+}
+
+// currentSession lấy user id và session id (rỗng với token cũ chưa có phiên) từ AuthMiddleware.
+func currentSession(c fiber.Ctx) (primitive.ObjectID, string, error) {
+	userID, _ := c.Locals("user_id").(string)
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return primitive.NilObjectID, "", common.NewError(common.ErrCodeAuth, "User not authenticated", common.StatusUnauthorized, nil)
+	}
+	sessionID, _ := c.Locals("session_id").(string)
+	return objID, sessionID, nil
+}
+
+// HandleRefreshToken đổi refresh token lấy access token + refresh token mới.
+// Token cũ (trước khi có phiên) cũng đổi được tại đây — client chuyển sang token có hạn mà không phải đăng nhập lại.
+func (h *UserHandler) HandleRefreshToken(c fiber.Ctx) error {
+	var input authdto.AuthRefreshInput
+	if err := h.ParseRequestBody(c, &input); err != nil {
+		h.HandleResponse(c, nil, err)
+		return nil
+	}
+	tokens, err := h.sessionService.Refresh(c.Context(), input.RefreshToken, sessionMetaFromRequest(c))
+	h.HandleResponse(c, tokens, err)
+	return nil
+}
+
+// HandleListSessions liệt kê phiên/thiết bị còn hiệu lực của user hiện tại (đánh dấu phiên đang dùng).
+func (h *UserHandler) HandleListSessions(c fiber.Ctx) error {
+	userID, sessionID, err := currentSession(c)
+	if err != nil {
+		h.HandleResponse(c, nil, err)
+		return nil
+	}
+	sessions, err := h.sessionService.ListActive(c.Context(), userID)
+	if err != nil {
+		h.HandleResponse(c, nil, err)
+		return nil
+	}
+	result := make([]map[string]interface{}, 0, len(sessions))
+	for _, s := range sessions {
+		result = append(result, map[string]interface{}{
+			"id":         s.ID.Hex(),
+			"hwid":       s.Hwid,
+			"userAgent":  s.UserAgent,
+			"ip":         s.IP,
+			"legacy":     s.Legacy,
+			"current":    s.ID.Hex() == sessionID,
+			"lastUsedAt": s.LastUsedAt,
+			"createdAt":  s.CreatedAt,
+			"expiresAt":  s.ExpiresAt.UnixMilli(),
+		})
+	}
+	h.HandleResponse(c, result, nil)
+	return nil
+}
+
+// HandleRevokeSession thu hồi một phiên của user hiện tại.
+func (h *UserHandler) HandleRevokeSession(c fiber.Ctx) error {
+	userID, _, err := currentSession(c)
+	if err != nil {
+		h.HandleResponse(c, nil, err)
+		return nil
+	}
+	sessionID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "Session ID không hợp lệ", common.StatusBadRequest, err))
+		return nil
+	}
+	err = h.sessionService.Revoke(c.Context(), userID, sessionID, authsvc.RevokeReasonRevoke)
+	h.HandleResponse(c, nil, err)
+	return nil
+}
+
+// HandleRevokeAllSessions thu hồi mọi phiên của user hiện tại. Query keep_current=true giữ lại phiên đang dùng.
+func (h *UserHandler) HandleRevokeAllSessions(c fiber.Ctx) error {
+	userID, sessionID, err := currentSession(c)
+	if err != nil {
+		h.HandleResponse(c, nil, err)
+		return nil
+	}
+	var except *primitive.ObjectID
+	if c.Query("keep_current") == "true" {
+		if current, errID := primitive.ObjectIDFromHex(sessionID); errID == nil {
+			except = &current
+		}
+	}
+	revoked, err := h.sessionService.RevokeAll(c.Context(), userID, except, authsvc.RevokeReasonRevokeAll)
+	if err != nil {
+		h.HandleResponse(c, nil, err)
+		return nil
+	}
+	h.HandleResponse(c, map[string]interface{}{"revoked": revoked}, nil)
+	return nil
+}
@@ -22,6 +22,7 @@ type UserHandler struct {
 	userService     *authsvc.UserService
 	roleService     *authsvc.RoleService
 	userRoleService *authsvc.UserRoleService
+	sessionService  *authsvc.AuthSessionService
 }
 
 // NewUserHandler tạo instance mới của UserHandler
@@ -38,12 +39,17 @@ func NewUserHandler() (*UserHandler, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create user role service: %v", err)
 	}
+	sessionService, err := authsvc.NewAuthSessionService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create auth session service: %v", err)
+	}
 	baseHandler := basehdl.NewBaseHandler[models.User, authdto.UserCreateInput, authdto.UserChangeInfoInput](userService)
 	return &UserHandler{
 		BaseHandler:     baseHandler,
 		userService:     userService,
 		roleService:     roleService,
 		userRoleService: userRoleService,
+		sessionService:  sessionService,
 	}, nil
 }
 
@@ -182,7 +188,7 @@ func (h *UserHandler) HandleLoginWithFirebase(c fiber.Ctx) error {
 		h.HandleResponse(c, nil, err)
 		return nil
 	}
-	user, err := h.userService.LoginWithFirebase(c.Context(), &input)
+	user, err := h.userService.LoginWithFirebase(c.Context(), &input, sessionMetaFromRequest(c))
 	if err != nil {
 		h.HandleResponse(c, nil, err)
 		return nil
@@ -0,0 +1,39 @@
+// Package models - phiên đăng nhập (AuthSession) và danh sách thu hồi thuộc domain auth.
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuthSession phiên đăng nhập theo thiết bị (hwid). Access token (JWT ngắn hạn) mang sid = ID phiên;
+// refresh token dài hạn chỉ lưu hash để đổi lấy access token mới (xoay vòng mỗi lần refresh).
+// LegacyTokenHash: token cũ (User.Token/Tokens, không hết hạn) đã chuyển sang phiên — vẫn dùng được tới khi client gọi /auth/refresh.
+type AuthSession struct {
+	ID               primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	UserID           primitive.ObjectID `json:"userId" bson:"userId" index:"compound:user_hwid"`
+	Hwid             string             `json:"hwid" bson:"hwid" index:"compound:user_hwid"`
+	RefreshTokenHash string             `json:"-" bson:"refreshTokenHash,omitempty"`
+	LegacyTokenHash  string             `json:"-" bson:"legacyTokenHash,omitempty" index:"unique,sparse"`
+	UserAgent        string             `json:"userAgent,omitempty" bson:"userAgent,omitempty"`
+	IP               string             `json:"ip,omitempty" bson:"ip,omitempty"`
+	Legacy           bool               `json:"legacy,omitempty" bson:"-"` // Chỉ trả API: phiên còn dùng token cũ
+	LastUsedAt       int64              `json:"lastUsedAt" bson:"lastUsedAt"`
+	RevokedAt        int64              `json:"revokedAt,omitempty" bson:"revokedAt,omitempty"`
+	ExpiresAt        time.Time          `json:"expiresAt" bson:"expiresAt" index:"single:1,ttl:0"` // Hết hạn refresh → TTL xóa
+	CreatedAt        int64              `json:"createdAt" bson:"createdAt"`
+	UpdatedAt        int64              `json:"updatedAt" bson:"updatedAt"`
+}
+
+// AuthRevokedSession danh sách thu hồi: access token có sid nằm ở đây bị từ chối dù chữ ký và hạn còn hợp lệ.
+// Chỉ cần giữ tới khi access token cuối của phiên hết hạn → TTL.
+type AuthRevokedSession struct {
+	ID        primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	SessionID primitive.ObjectID `json:"sessionId" bson:"sessionId" index:"unique"`
+	UserID    primitive.ObjectID `json:"userId" bson:"userId"`
+	Reason    string             `json:"reason,omitempty" bson:"reason,omitempty"` // logout, revoke, revoke_all, blocked, refresh_reuse
+	ExpiresAt time.Time          `json:"expiresAt" bson:"expiresAt" index:"single:1,ttl:0"`
+	CreatedAt int64              `json:"createdAt" bson:"createdAt"`
+	UpdatedAt int64              `json:"updatedAt" bson:"updatedAt"`
+}
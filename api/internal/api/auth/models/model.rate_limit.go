@@ -0,0 +1,48 @@
+// Package models - RateLimitPolicy, RateLimitCounter thuộc domain auth.
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Nhóm route áp dụng rate limit (phân loại theo path trong middleware).
+const (
+	RateLimitGroupCRUD    = "crud"
+	RateLimitGroupIngest  = "ingest"
+	RateLimitGroupReports = "reports"
+	RateLimitGroupAI      = "ai"
+)
+
+// Đối tượng đếm của một policy.
+const (
+	RateLimitScopeOrganization = "organization"
+	RateLimitScopeUser         = "user"
+	RateLimitScopeAPIKey       = "api_key"
+)
+
+// RateLimitPolicy giới hạn request cho một nhóm route theo một đối tượng (tổ chức / user / API key).
+// Mỗi (tổ chức, routeGroup, scope) có tối đa một policy; policy của tổ chức ghi đè policy của System Organization,
+// policy System ghi đè giá trị mặc định trong code. Limit = 0: không giới hạn cửa sổ đó.
+type RateLimitPolicy struct {
+	ID                     primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	RouteGroup             string             `json:"routeGroup" bson:"routeGroup" index:"compound:org_group_scope_unique"` // crud | ingest | reports | ai
+	Scope                  string             `json:"scope" bson:"scope" index:"compound:org_group_scope_unique"`           // organization | user | api_key
+	BurstLimit             int                `json:"burstLimit" bson:"burstLimit"`                                         // Số request tối đa trong BurstWindowSeconds (chặn dồn dập)
+	BurstWindowSeconds     int                `json:"burstWindowSeconds" bson:"burstWindowSeconds"`
+	SustainedLimit         int                `json:"sustainedLimit" bson:"sustainedLimit"` // Số request tối đa trong SustainedWindowSeconds (tải đều)
+	SustainedWindowSeconds int                `json:"sustainedWindowSeconds" bson:"sustainedWindowSeconds"`
+	DailyQuota             int                `json:"dailyQuota" bson:"dailyQuota"` // Số request tối đa mỗi ngày (UTC) — dùng cho endpoint nặng
+	IsDisabled             bool               `json:"isDisabled" bson:"isDisabled"` // Tắt → bỏ giới hạn cho (routeGroup, scope) này, không rơi về mặc định
+	OwnerOrganizationID    primitive.ObjectID `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"compound:org_group_scope_unique"`
+	CreatedAt              int64              `json:"createdAt" bson:"createdAt"`
+	UpdatedAt              int64              `json:"updatedAt" bson:"updatedAt"`
+}
+
+// RateLimitCounter bộ đếm fixed-window dùng chung giữa các instance. ID = policy + đối tượng + cửa sổ + thời điểm bắt đầu.
+type RateLimitCounter struct {
+	ID        string    `json:"id" bson:"_id"`
+	Count     int64     `json:"count" bson:"count"`
+	ExpiresAt time.Time `json:"expiresAt" bson:"expiresAt" index:"single:1,ttl:0"` // Hết cửa sổ → Mongo tự xóa
+}
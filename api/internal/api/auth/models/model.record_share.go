@@ -0,0 +1,51 @@
+// Package models - RecordShareGrant, RecordShareAccess thuộc domain auth.
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Quyền của grant chia sẻ bản ghi.
+const (
+	RecordShareAccessRead      = "read"
+	RecordShareAccessReadWrite = "read_write"
+)
+
+// RecordShareGrant chia sẻ một tập bản ghi (không phải cả permission như OrganizationShare) của tổ chức sở hữu
+// cho một tổ chức khác hoặc một user. Bản ghi chọn theo RecordIDs hoặc MatchField ∈ MatchValues
+// (ví dụ adAccountId của một tài khoản quảng cáo → campaign của nó; pageId → hội thoại của một page),
+// luôn giới hạn trong ownerOrganizationId của grant.
+type RecordShareGrant struct {
+	ID                    primitive.ObjectID   `json:"id,omitempty" bson:"_id,omitempty"`
+	Collection            string               `json:"collection" bson:"collection" index:"single:1"` // Tên collection chứa bản ghi được chia sẻ
+	RecordIDs             []primitive.ObjectID `json:"recordIds,omitempty" bson:"recordIds,omitempty"`
+	MatchField            string               `json:"matchField,omitempty" bson:"matchField,omitempty"`
+	MatchValues           []interface{}        `json:"matchValues,omitempty" bson:"matchValues,omitempty"`
+	GranteeOrganizationID primitive.ObjectID   `json:"granteeOrganizationId,omitempty" bson:"granteeOrganizationId,omitempty" index:"single:1"`
+	GranteeUserID         primitive.ObjectID   `json:"granteeUserId,omitempty" bson:"granteeUserId,omitempty" index:"single:1"`
+	Access                string               `json:"access" bson:"access"`       // read | read_write
+	ExpiresAt             int64                `json:"expiresAt" bson:"expiresAt"` // Unix milli, 0 = không hết hạn
+	RevokedAt             int64                `json:"revokedAt" bson:"revokedAt"` // 0 = còn hiệu lực
+	Description           string               `json:"description,omitempty" bson:"description,omitempty"`
+	OwnerOrganizationID   primitive.ObjectID   `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1"` // Tổ chức chia sẻ (sở hữu bản ghi)
+	CreatedBy             primitive.ObjectID   `json:"createdBy,omitempty" bson:"createdBy,omitempty"`
+	CreatedAt             int64                `json:"createdAt" bson:"createdAt"`
+	UpdatedAt             int64                `json:"updatedAt" bson:"updatedAt"`
+}
+
+// RecordShareAccess nhật ký truy cập bản ghi qua grant — tổ chức chia sẻ xem được ai đã đọc/ghi dữ liệu của mình.
+type RecordShareAccess struct {
+	ID                  primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	GrantID             primitive.ObjectID `json:"grantId" bson:"grantId" index:"single:1"`
+	Collection          string             `json:"collection" bson:"collection"`
+	RecordID            primitive.ObjectID `json:"recordId,omitempty" bson:"recordId,omitempty"` // Rỗng với truy vấn danh sách (grant được áp vào filter)
+	UserID              primitive.ObjectID `json:"userId" bson:"userId" index:"single:1"`
+	Permission          string             `json:"permission" bson:"permission"`
+	Method              string             `json:"method" bson:"method"`
+	Path                string             `json:"path" bson:"path"`
+	OwnerOrganizationID primitive.ObjectID `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"compound:owner_accessed"` // Tổ chức chia sẻ
+	AccessedAt          int64              `json:"accessedAt" bson:"accessedAt" index:"compound:owner_accessed,order:-1"`
+	ExpiresAt           time.Time          `json:"-" bson:"expiresAt" index:"single:1,ttl:0"` // Giữ nhật ký recordShareAccessRetention
+}
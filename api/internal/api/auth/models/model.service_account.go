@@ -0,0 +1,43 @@
+// Package models - ServiceAccount, APIKey thuộc domain auth.
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ServiceAccount tài khoản máy (agent đồng bộ, bot ingest Pancake/Meta) thuộc một tổ chức.
+// Mỗi service account có một User đại diện (UserID, không đăng nhập Firebase) được gán RoleID —
+// nhờ đó phân quyền theo role/organization dùng chung với người dùng.
+type ServiceAccount struct {
+	ID                  primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	Name                string             `json:"name" bson:"name"`
+	Description         string             `json:"description,omitempty" bson:"description,omitempty"`
+	UserID              primitive.ObjectID `json:"userId" bson:"userId" index:"unique"`
+	RoleID              primitive.ObjectID `json:"roleId" bson:"roleId"`
+	IsDisabled          bool               `json:"isDisabled" bson:"isDisabled"` // Tắt → mọi API key của account bị từ chối
+	OwnerOrganizationID primitive.ObjectID `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1"`
+	CreatedBy           primitive.ObjectID `json:"createdBy,omitempty" bson:"createdBy,omitempty"`
+	CreatedAt           int64              `json:"createdAt" bson:"createdAt"`
+	UpdatedAt           int64              `json:"updatedAt" bson:"updatedAt"`
+}
+
+// APIKey khóa API của service account. Chỉ lưu hash (SHA-256); khóa đầy đủ chỉ trả một lần khi tạo.
+// Scopes: tập con permission của role service account — request cần permission ngoài Scopes bị từ chối.
+// AllowedIPs: IP hoặc CIDR được phép gọi (rỗng = mọi IP). ExpiresAt = 0: không hết hạn.
+type APIKey struct {
+	ID                  primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	ServiceAccountID    primitive.ObjectID `json:"serviceAccountId" bson:"serviceAccountId" index:"single:1"`
+	Name                string             `json:"name" bson:"name"`
+	Prefix              string             `json:"prefix" bson:"prefix"` // Phần đầu khóa để nhận diện (an toàn để hiển thị)
+	KeyHash             string             `json:"-" bson:"keyHash" index:"unique"`
+	Scopes              []string           `json:"scopes" bson:"scopes"`
+	AllowedIPs          []string           `json:"allowedIps,omitempty" bson:"allowedIps,omitempty"`
+	ExpiresAt           int64              `json:"expiresAt,omitempty" bson:"expiresAt,omitempty"` // Unix milli
+	LastUsedAt          int64              `json:"lastUsedAt,omitempty" bson:"lastUsedAt,omitempty"`
+	LastUsedIP          string             `json:"lastUsedIp,omitempty" bson:"lastUsedIp,omitempty"`
+	RevokedAt           int64              `json:"revokedAt,omitempty" bson:"revokedAt,omitempty"`
+	OwnerOrganizationID primitive.ObjectID `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1"`
+	CreatedBy           primitive.ObjectID `json:"createdBy,omitempty" bson:"createdBy,omitempty"`
+	CreatedAt           int64              `json:"createdAt" bson:"createdAt"`
+	UpdatedAt           int64              `json:"updatedAt" bson:"updatedAt"`
+}
@@ -3,15 +3,26 @@ package models
 
 import "github.com/dgrijalva/jwt-go"
 
+// Loại JWT theo phiên (claim "typ"). Token cũ không có typ/sid/exp.
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
 // JwtToken chứa data được mã hóa trong JWT token.
+// Token theo phiên có SessionID (sid), TokenType và ExpiresAt (exp); Id (jti) ngẫu nhiên để mỗi lần cấp khác nhau.
 type JwtToken struct {
 	UserID       string `json:"userId"`
 	Time         string `json:"time"`
 	RandomNumber string `json:"randomNumber"`
+	SessionID    string `json:"sid,omitempty"`
+	Hwid         string `json:"hwid,omitempty"`
+	TokenType    string `json:"typ,omitempty"`
 	jwt.StandardClaims
 }
 
 // Token token theo hwid (mỗi thiết bị một token).
+// Định dạng cũ — khi khởi động được chuyển sang AuthSession (LegacyTokenHash) rồi xóa khỏi User.
 type Token struct {
 	Hwid     string `json:"hwid" bson:"hwid,omitempty"`
 	RoleID   string `json:"roleId" bson:"roleId,omitempty"`
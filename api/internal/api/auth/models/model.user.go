@@ -6,8 +6,8 @@ import (
 )
 
 // User định nghĩa mô hình người dùng
-// Token chứa token xác thực mới nhất của người dùng
-// Tokens chứa danh sách các token, mỗi thiết bị khác nhau sẽ có một token riêng để xác thực (bằng hwid)
+// Token: khi đăng nhập/refresh trả access token (không lưu DB); giá trị cũ trong DB chỉ còn để chuyển sang AuthSession
+// Tokens: token theo hwid định dạng cũ — đã chuyển sang collection phiên (auth_core_sessions)
 type User struct {
 	_Relationships struct{}          `relationship:"collection:user_roles,field:userId,message:Không thể xóa user vì có %d role đang được gán cho user này. Vui lòng gỡ các role trước."`
 	ID             primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
@@ -22,6 +22,9 @@ type User struct {
 	AvatarURL      string             `json:"avatarUrl" bson:"avatarUrl"`
 	Token          string             `json:"token" bson:"token"`
 	Tokens         []Token            `json:"-" bson:"tokens"`
+	RefreshToken   string             `json:"refreshToken,omitempty" bson:"-"`   // Chỉ trả khi đăng nhập
+	TokenExpiresAt int64              `json:"tokenExpiresAt,omitempty" bson:"-"` // Hạn access token (unix giây), chỉ trả khi đăng nhập
+	ServiceAccountID primitive.ObjectID `json:"serviceAccountId,omitempty" bson:"serviceAccountId,omitempty"` // User đại diện của service account (không đăng nhập Firebase)
 	IsBlock        bool               `json:"-" bson:"isBlock"`
 	BlockNote      string             `json:"-" bson:"blockNote"`
 	CreatedAt      int64              `json:"createdAt" bson:"createdAt"`
@@ -66,6 +66,8 @@ func registerSystemRoutes(router fiber.Router) error {
 	}
 	router.Get("/system/health", systemHandler.HandleHealth)
 	router.Get("/internal/metrics/job-metrics", systemHandler.HandleJobMetrics)
+	router.Get("/internal/metrics/cache-metrics", systemHandler.HandleCacheMetrics)
+	router.Get("/internal/metrics/gauges", systemHandler.HandleGaugeMetrics)
 	// Worker config: cần auth, quyền MongoDB.Manage (admin)
 	workerConfigMiddleware := middleware.AuthMiddleware("MongoDB.Manage")
 	apirouter.RegisterRouteWithMiddleware(router, "/system", "GET", "/worker-config", []fiber.Handler{workerConfigMiddleware}, systemHandler.HandleGetWorkerConfig)
@@ -79,8 +81,13 @@ func registerAuthRoutes(router fiber.Router, r *apirouter.Router) error {
 		return fmt.Errorf("failed to create user handler: %w", err)
 	}
 	router.Post("/auth/login/firebase", userHandler.HandleLoginWithFirebase)
+	// Refresh không cần access token (access có thể đã hết hạn) — đăng ký trước middleware của nhóm /auth
+	router.Post("/auth/refresh", userHandler.HandleRefreshToken)
 	authOnlyMiddleware := middleware.AuthMiddleware("")
 	apirouter.RegisterRouteWithMiddleware(router, "/auth", "POST", "/logout", []fiber.Handler{authOnlyMiddleware}, userHandler.HandleLogout)
+	apirouter.RegisterRouteWithMiddleware(router, "/auth", "GET", "/sessions", []fiber.Handler{authOnlyMiddleware}, userHandler.HandleListSessions)
+	apirouter.RegisterRouteWithMiddleware(router, "/auth", "DELETE", "/sessions/:id", []fiber.Handler{authOnlyMiddleware}, userHandler.HandleRevokeSession)
+	apirouter.RegisterRouteWithMiddleware(router, "/auth", "DELETE", "/sessions", []fiber.Handler{authOnlyMiddleware}, userHandler.HandleRevokeAllSessions)
 	apirouter.RegisterRouteWithMiddleware(router, "/auth", "GET", "/profile", []fiber.Handler{authOnlyMiddleware}, userHandler.HandleGetProfile)
 	apirouter.RegisterRouteWithMiddleware(router, "/auth", "PUT", "/profile", []fiber.Handler{authOnlyMiddleware}, userHandler.HandleUpdateProfile)
 	authRolesMiddleware := middleware.AuthMiddleware("")
@@ -143,6 +150,50 @@ func registerRBACRoutes(router fiber.Router, r *apirouter.Router) error {
 		return fmt.Errorf("failed to create organization share handler: %w", err)
 	}
 	r.RegisterCRUDRoutes(router, "/organization-share", organizationShareHandler, apirouter.ReadWriteConfig, "OrganizationShare")
+
+	// Service account + API key (agent đồng bộ, bot ingest): CRUD chỉ đọc, tạo/tắt/thu hồi qua route riêng.
+	serviceAccountHandler, err := authhdl.NewServiceAccountHandler()
+	if err != nil {
+		return fmt.Errorf("failed to create service account handler: %w", err)
+	}
+	serviceAccountInsertMiddleware := middleware.AuthMiddleware("ServiceAccount.Insert")
+	serviceAccountUpdateMiddleware := middleware.AuthMiddleware("ServiceAccount.Update")
+	r.RegisterCRUDRoutes(router, "/service-account", serviceAccountHandler, apirouter.ReadOnlyConfig, "ServiceAccount")
+	apirouter.RegisterRouteWithMiddleware(router, "/service-account", "POST", "/create", []fiber.Handler{serviceAccountInsertMiddleware, orgContextMiddleware}, serviceAccountHandler.HandleCreate)
+	apirouter.RegisterRouteWithMiddleware(router, "/service-account", "POST", "/:id/disable", []fiber.Handler{serviceAccountUpdateMiddleware, orgContextMiddleware}, serviceAccountHandler.HandleSetDisabled(true))
+	apirouter.RegisterRouteWithMiddleware(router, "/service-account", "POST", "/:id/enable", []fiber.Handler{serviceAccountUpdateMiddleware, orgContextMiddleware}, serviceAccountHandler.HandleSetDisabled(false))
+
+	apiKeyHandler, err := authhdl.NewAPIKeyHandler()
+	if err != nil {
+		return fmt.Errorf("failed to create api key handler: %w", err)
+	}
+	r.RegisterCRUDRoutes(router, "/service-account-key", apiKeyHandler, apirouter.ReadOnlyConfig, "ServiceAccount")
+	apirouter.RegisterRouteWithMiddleware(router, "/service-account-key", "POST", "/create", []fiber.Handler{serviceAccountUpdateMiddleware, orgContextMiddleware}, apiKeyHandler.HandleCreate)
+	apirouter.RegisterRouteWithMiddleware(router, "/service-account-key", "POST", "/revoke/:id", []fiber.Handler{serviceAccountUpdateMiddleware, orgContextMiddleware}, apiKeyHandler.HandleRevoke)
+
+	rateLimitPolicyHandler, err := authhdl.NewRateLimitPolicyHandler()
+	if err != nil {
+		return fmt.Errorf("failed to create rate limit policy handler: %w", err)
+	}
+	r.RegisterCRUDRoutes(router, "/rate-limit-policy", rateLimitPolicyHandler, apirouter.ReadWriteConfig, "RateLimitPolicy")
+
+	// Chia sẻ theo bản ghi: phía chia sẻ đọc/tạo/thu hồi grant + xem nhật ký; phía nhận xem grant được nhận
+	recordShareHandler, err := authhdl.NewRecordShareHandler()
+	if err != nil {
+		return fmt.Errorf("failed to create record share handler: %w", err)
+	}
+	recordShareInsertMiddleware := middleware.AuthMiddleware("RecordShare.Insert")
+	recordShareUpdateMiddleware := middleware.AuthMiddleware("RecordShare.Update")
+	r.RegisterCRUDRoutes(router, "/record-share", recordShareHandler, apirouter.ReadOnlyConfig, "RecordShare")
+	apirouter.RegisterRouteWithMiddleware(router, "/record-share", "POST", "/create", []fiber.Handler{recordShareInsertMiddleware, orgContextMiddleware}, recordShareHandler.HandleCreate)
+	apirouter.RegisterRouteWithMiddleware(router, "/record-share", "POST", "/revoke/:id", []fiber.Handler{recordShareUpdateMiddleware, orgContextMiddleware}, recordShareHandler.HandleRevoke)
+	apirouter.RegisterRouteWithMiddleware(router, "/record-share-received", "GET", "", []fiber.Handler{middleware.AuthMiddleware(""), orgContextMiddleware}, recordShareHandler.HandleListReceived)
+
+	recordShareAccessHandler, err := authhdl.NewRecordShareAccessHandler()
+	if err != nil {
+		return fmt.Errorf("failed to create record share access handler: %w", err)
+	}
+	r.RegisterCRUDRoutes(router, "/record-share-access-log", recordShareAccessHandler, apirouter.ReadOnlyConfig, "RecordShare")
 	return nil
 }
 
@@ -102,6 +102,12 @@ func (s *AdminService) BlockUser(ctx context.Context, email string, block bool,
 	if err != nil {
 		return nil, err
 	}
+	// Access token xác thực bằng chữ ký (không đọc User) → khóa tài khoản phải thu hồi mọi phiên
+	if block {
+		if _, err := s.userService.sessionService.RevokeAll(ctx, user.ID, nil, RevokeReasonBlocked); err != nil {
+			return nil, err
+		}
+	}
 	return &updatedUser, nil
 }
 
@@ -0,0 +1,404 @@
+// Package authsvc - service account và API key cho agent / bot tích hợp.
+package authsvc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	models "meta_commerce/internal/api/auth/models"
+	basesvc "meta_commerce/internal/api/base/service"
+	"meta_commerce/internal/common"
+	"meta_commerce/internal/global"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// APIKeyPrefix tiền tố của mọi API key — middleware dùng để phân biệt với JWT.
+const APIKeyPrefix = "mcsk_"
+
+// apiKeyDisplayLen số ký tự đầu khóa lưu lại để nhận diện (prefix + 8 ký tự).
+const apiKeyDisplayLen = len(APIKeyPrefix) + 8
+
+// ServiceAccountService quản lý service account, User đại diện và API key.
+type ServiceAccountService struct {
+	*basesvc.BaseServiceMongoImpl[models.ServiceAccount]
+	apiKeyService         *basesvc.BaseServiceMongoImpl[models.APIKey]
+	userService           *basesvc.BaseServiceMongoImpl[models.User]
+	userRoleService       *basesvc.BaseServiceMongoImpl[models.UserRole]
+	roleService           *basesvc.BaseServiceMongoImpl[models.Role]
+	rolePermissionService *basesvc.BaseServiceMongoImpl[models.RolePermission]
+	permissionService     *basesvc.BaseServiceMongoImpl[models.Permission]
+}
+
+// APIKeyIdentity API key kèm service account sở hữu (kết quả tra cứu theo hash).
+type APIKeyIdentity struct {
+	Key            models.APIKey
+	ServiceAccount models.ServiceAccount
+}
+
+// CreateAPIKeyInput tham số tạo API key.
+type CreateAPIKeyInput struct {
+	Name       string
+	Scopes     []string
+	AllowedIPs []string
+	ExpiresAt  int64 // Unix milli, 0 = không hết hạn
+	CreatedBy  primitive.ObjectID
+}
+
+var (
+	apiKeyInvalidatedCallbacks   []func(keyHash string)
+	apiKeyInvalidatedCallbacksMu sync.RWMutex
+)
+
+// RegisterAPIKeyInvalidatedCallback đăng ký hàm được gọi khi API key bị thu hồi hoặc service account bị tắt/bật
+// (middleware xóa cache của key ngay trên instance này).
+func RegisterAPIKeyInvalidatedCallback(fn func(keyHash string)) {
+	apiKeyInvalidatedCallbacksMu.Lock()
+	apiKeyInvalidatedCallbacks = append(apiKeyInvalidatedCallbacks, fn)
+	apiKeyInvalidatedCallbacksMu.Unlock()
+}
+
+func notifyAPIKeyInvalidated(keyHashes ...string) {
+	apiKeyInvalidatedCallbacksMu.RLock()
+	callbacks := apiKeyInvalidatedCallbacks
+	apiKeyInvalidatedCallbacksMu.RUnlock()
+	for _, hash := range keyHashes {
+		for _, fn := range callbacks {
+			fn(hash)
+		}
+	}
+}
+
+// NewServiceAccountService tạo mới ServiceAccountService
+func NewServiceAccountService() (*ServiceAccountService, error) {
+	collections := make(map[string]*mongo.Collection)
+	for _, name := range []string{
+		global.MongoDB_ColNames.ServiceAccounts,
+		global.MongoDB_ColNames.APIKeys,
+		global.MongoDB_ColNames.Users,
+		global.MongoDB_ColNames.UserRoles,
+		global.MongoDB_ColNames.Roles,
+		global.MongoDB_ColNames.RolePermissions,
+		global.MongoDB_ColNames.Permissions,
+	} {
+		collection, exist := global.RegistryCollections.Get(name)
+		if !exist {
+			return nil, fmt.Errorf("failed to get %s collection: %v", name, common.ErrNotFound)
+		}
+		collections[name] = collection
+	}
+
+	return &ServiceAccountService{
+		BaseServiceMongoImpl:  basesvc.NewBaseServiceMongo[models.ServiceAccount](collections[global.MongoDB_ColNames.ServiceAccounts]),
+		apiKeyService:         basesvc.NewBaseServiceMongo[models.APIKey](collections[global.MongoDB_ColNames.APIKeys]),
+		userService:           basesvc.NewBaseServiceMongo[models.User](collections[global.MongoDB_ColNames.Users]),
+		userRoleService:       basesvc.NewBaseServiceMongo[models.UserRole](collections[global.MongoDB_ColNames.UserRoles]),
+		roleService:           basesvc.NewBaseServiceMongo[models.Role](collections[global.MongoDB_ColNames.Roles]),
+		rolePermissionService: basesvc.NewBaseServiceMongo[models.RolePermission](collections[global.MongoDB_ColNames.RolePermissions]),
+		permissionService:     basesvc.NewBaseServiceMongo[models.Permission](collections[global.MongoDB_ColNames.Permissions]),
+	}, nil
+}
+
+// APIKeys service CRUD của collection API key (handler dùng cho route đọc).
+func (s *ServiceAccountService) APIKeys() *basesvc.BaseServiceMongoImpl[models.APIKey] {
+	return s.apiKeyService
+}
+
+// Create tạo service account thuộc orgID: tạo User đại diện (không đăng nhập Firebase) và gán roleID.
+// roleID phải thuộc chính tổ chức đó, và permission của role phải là tập con permission của role người tạo
+// (callerRoleID — role đang làm việc) trừ khi người tạo là Administrator — không tự cấp quyền cao hơn qua service account.
+func (s *ServiceAccountService) Create(ctx context.Context, orgID, roleID, createdBy, callerRoleID primitive.ObjectID, name, description string) (*models.ServiceAccount, error) {
+	role, err := s.roleService.FindOneById(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+	if role.OwnerOrganizationID != orgID {
+		return nil, common.NewError(common.ErrCodeValidationInput, "Role không thuộc tổ chức của service account", common.StatusBadRequest, nil)
+	}
+	if err := s.validateRoleGrantable(ctx, roleID, createdBy, callerRoleID); err != nil {
+		return nil, err
+	}
+
+	accountID := primitive.NewObjectID()
+	user, err := s.userService.InsertOne(ctx, models.User{
+		Name:             name,
+		FirebaseUID:      "service-account:" + accountID.Hex(), // firebaseUid unique (không sparse) → giá trị riêng, không trùng UID Firebase thật
+		ServiceAccountID: accountID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.userRoleService.InsertOne(ctx, models.UserRole{UserID: user.ID, RoleID: roleID}); err != nil {
+		return nil, err
+	}
+	account, err := s.BaseServiceMongoImpl.InsertOne(ctx, models.ServiceAccount{
+		ID:                  accountID,
+		Name:                name,
+		Description:         description,
+		UserID:              user.ID,
+		RoleID:              roleID,
+		OwnerOrganizationID: orgID,
+		CreatedBy:           createdBy,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// validateRoleGrantable người tạo chỉ gán được role có permission nằm trong permission của role đang làm việc của họ.
+// Administrator được gán mọi role của tổ chức.
+func (s *ServiceAccountService) validateRoleGrantable(ctx context.Context, roleID, callerID, callerRoleID primitive.ObjectID) error {
+	isAdmin, err := IsUserAdministrator(ctx, callerID)
+	if err != nil {
+		return err
+	}
+	if isAdmin {
+		return nil
+	}
+	if callerRoleID.IsZero() {
+		return common.NewError(common.ErrCodeAuthRole, "Thiếu role đang làm việc của người tạo", common.StatusForbidden, nil)
+	}
+	if _, err := s.userRoleService.FindOne(ctx, bson.M{"userId": callerID, "roleId": callerRoleID}, nil); err != nil {
+		if errors.Is(err, common.ErrNotFound) {
+			return common.NewError(common.ErrCodeAuthRole, "Người tạo không có role đang làm việc", common.StatusForbidden, nil)
+		}
+		return err
+	}
+	granted, err := s.RolePermissionNames(ctx, roleID)
+	if err != nil {
+		return err
+	}
+	held, err := s.RolePermissionNames(ctx, callerRoleID)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(granted))
+	for name := range granted {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if missing := ScopesOutsideRole(names, held); len(missing) > 0 {
+		return common.NewError(common.ErrCodeAuthRole, "Role có permission vượt quyền người tạo: "+strings.Join(missing, ", "), common.StatusForbidden, nil)
+	}
+	return nil
+}
+
+// SetDisabled tắt/bật service account; tắt → mọi API key bị từ chối (không cần thu hồi từng key).
+func (s *ServiceAccountService) SetDisabled(ctx context.Context, account models.ServiceAccount, disabled bool) (*models.ServiceAccount, error) {
+	updated, err := s.BaseServiceMongoImpl.UpdateById(ctx, account.ID, &basesvc.UpdateData{Set: map[string]interface{}{"isDisabled": disabled}})
+	if err != nil {
+		return nil, err
+	}
+	keys, err := s.apiKeyService.Find(ctx, bson.M{"serviceAccountId": account.ID}, nil)
+	if err == nil {
+		hashes := make([]string, 0, len(keys))
+		for _, key := range keys {
+			hashes = append(hashes, key.KeyHash)
+		}
+		notifyAPIKeyInvalidated(hashes...)
+	}
+	return &updated, nil
+}
+
+// RolePermissionNames tên các permission của role (dùng kiểm tra scope API key là tập con).
+func (s *ServiceAccountService) RolePermissionNames(ctx context.Context, roleID primitive.ObjectID) (map[string]bool, error) {
+	rolePermissions, err := s.rolePermissionService.Find(ctx, bson.M{"roleId": roleID}, nil)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, 0, len(rolePermissions))
+	for _, rp := range rolePermissions {
+		ids = append(ids, rp.PermissionID)
+	}
+	names := make(map[string]bool, len(ids))
+	if len(ids) == 0 {
+		return names, nil
+	}
+	permissions, err := s.permissionService.FindManyByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range permissions {
+		names[p.Name] = true
+	}
+	return names, nil
+}
+
+// CreateAPIKey tạo API key cho service account. Trả về key đã lưu và khóa đầy đủ (chỉ trả một lần).
+func (s *ServiceAccountService) CreateAPIKey(ctx context.Context, account models.ServiceAccount, input CreateAPIKeyInput) (*models.APIKey, string, error) {
+	if account.IsDisabled {
+		return nil, "", common.NewError(common.ErrCodeBusinessOperation, "Service account đang bị tắt", common.StatusBadRequest, nil)
+	}
+	if len(input.Scopes) == 0 {
+		return nil, "", common.NewError(common.ErrCodeValidationInput, "API key cần ít nhất một scope (permission)", common.StatusBadRequest, nil)
+	}
+	allowed, err := s.RolePermissionNames(ctx, account.RoleID)
+	if err != nil {
+		return nil, "", err
+	}
+	if outside := ScopesOutsideRole(input.Scopes, allowed); len(outside) > 0 {
+		return nil, "", common.NewError(common.ErrCodeValidationInput, "Scope không thuộc role của service account: "+strings.Join(outside, ", "), common.StatusBadRequest, nil)
+	}
+	if err := ValidateAllowedIPs(input.AllowedIPs); err != nil {
+		return nil, "", err
+	}
+	if input.ExpiresAt != 0 && input.ExpiresAt <= time.Now().UnixMilli() {
+		return nil, "", common.NewError(common.ErrCodeValidationInput, "expiresAt phải ở tương lai", common.StatusBadRequest, nil)
+	}
+
+	plaintext, err := generateAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+	key, err := s.apiKeyService.InsertOne(ctx, models.APIKey{
+		ServiceAccountID:    account.ID,
+		Name:                input.Name,
+		Prefix:              plaintext[:apiKeyDisplayLen],
+		KeyHash:             TokenHash(plaintext),
+		Scopes:              input.Scopes,
+		AllowedIPs:          input.AllowedIPs,
+		ExpiresAt:           input.ExpiresAt,
+		OwnerOrganizationID: account.OwnerOrganizationID,
+		CreatedBy:           input.CreatedBy,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return &key, plaintext, nil
+}
+
+// FindAPIKey tìm API key trong phạm vi filter (handler truyền filter đã giới hạn tổ chức).
+func (s *ServiceAccountService) FindAPIKey(ctx context.Context, filter bson.M) (*models.APIKey, error) {
+	key, err := s.apiKeyService.FindOne(ctx, filter, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// RevokeAPIKey thu hồi API key (không xóa để giữ lịch sử sử dụng).
+func (s *ServiceAccountService) RevokeAPIKey(ctx context.Context, key models.APIKey) error {
+	if key.RevokedAt != 0 {
+		return nil
+	}
+	update := &basesvc.UpdateData{Set: map[string]interface{}{"revokedAt": time.Now().UnixMilli()}}
+	if _, err := s.apiKeyService.UpdateById(ctx, key.ID, update); err != nil {
+		return err
+	}
+	notifyAPIKeyInvalidated(key.KeyHash)
+	return nil
+}
+
+// ResolveAPIKey tra API key theo hash kèm service account. Không có → ErrTokenInvalid.
+// Trạng thái (thu hồi, hết hạn, tắt, IP) kiểm tra bằng CheckAPIKeyUse.
+func (s *ServiceAccountService) ResolveAPIKey(ctx context.Context, keyHash string) (*APIKeyIdentity, error) {
+	key, err := s.apiKeyService.FindOne(ctx, bson.M{"keyHash": keyHash}, nil)
+	if err != nil {
+		if errors.Is(err, common.ErrNotFound) {
+			return nil, common.ErrTokenInvalid
+		}
+		return nil, err
+	}
+	account, err := s.BaseServiceMongoImpl.FindOneById(ctx, key.ServiceAccountID)
+	if err != nil {
+		if errors.Is(err, common.ErrNotFound) {
+			return nil, common.ErrTokenInvalid
+		}
+		return nil, err
+	}
+	return &APIKeyIdentity{Key: key, ServiceAccount: account}, nil
+}
+
+// TouchAPIKey ghi lần dùng cuối (middleware gọi có giới hạn tần suất, không phải mỗi request).
+func (s *ServiceAccountService) TouchAPIKey(ctx context.Context, keyID primitive.ObjectID, ip string) error {
+	_, err := s.apiKeyService.Collection().UpdateOne(ctx, bson.M{"_id": keyID},
+		bson.M{"$set": bson.M{"lastUsedAt": time.Now().UnixMilli(), "lastUsedIp": ip}},
+		options.Update())
+	return common.ConvertMongoError(err)
+}
+
+// CheckAPIKeyUse kiểm tra API key còn dùng được từ ip tại thời điểm now.
+func CheckAPIKeyUse(identity *APIKeyIdentity, ip string, now time.Time) error {
+	key := identity.Key
+	if key.RevokedAt != 0 {
+		return common.NewError(common.ErrCodeAuthToken, "API key đã bị thu hồi", common.StatusUnauthorized, nil)
+	}
+	if key.ExpiresAt != 0 && key.ExpiresAt <= now.UnixMilli() {
+		return common.NewError(common.ErrCodeAuthToken, "API key đã hết hạn", common.StatusUnauthorized, nil)
+	}
+	if identity.ServiceAccount.IsDisabled {
+		return common.NewError(common.ErrCodeAuthCredentials, "Service account đang bị tắt", common.StatusForbidden, nil)
+	}
+	if !IPAllowed(key.AllowedIPs, ip) {
+		return common.NewError(common.ErrCodeAuthCredentials, "IP "+ip+" không được phép dùng API key này", common.StatusForbidden, nil)
+	}
+	return nil
+}
+
+// ScopesOutsideRole các scope không có trong permission của role.
+func ScopesOutsideRole(scopes []string, rolePermissions map[string]bool) []string {
+	var outside []string
+	for _, scope := range scopes {
+		if !rolePermissions[scope] {
+			outside = append(outside, scope)
+		}
+	}
+	return outside
+}
+
+// ValidateAllowedIPs mỗi mục phải là IP hoặc CIDR hợp lệ.
+func ValidateAllowedIPs(allowed []string) error {
+	for _, entry := range allowed {
+		if _, _, err := net.ParseCIDR(entry); err == nil {
+			continue
+		}
+		if net.ParseIP(entry) == nil {
+			return common.NewError(common.ErrCodeValidationFormat, "allowedIps: '"+entry+"' không phải IP hoặc CIDR", common.StatusBadRequest, nil)
+		}
+	}
+	return nil
+}
+
+// IPAllowed ip nằm trong danh sách IP/CIDR (danh sách rỗng = cho phép mọi IP).
+func IPAllowed(allowed []string, ip string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, entry := range allowed {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if network.Contains(parsed) {
+				return true
+			}
+			continue
+		}
+		if other := net.ParseIP(entry); other != nil && other.Equal(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// generateAPIKey "mcsk_" + 32 byte ngẫu nhiên (base64 URL, không padding).
+func generateAPIKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	return APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
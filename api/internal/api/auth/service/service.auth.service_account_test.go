@@ -0,0 +1,78 @@
+package authsvc
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	models "meta_commerce/internal/api/auth/models"
+)
+
+func TestIPAllowed(t *testing.T) {
+	allowed := []string{"10.0.0.0/24", "203.0.113.7", "2001:db8::/32"}
+	cases := map[string]bool{
+		"10.0.0.15":   true,
+		"10.0.1.1":    false,
+		"203.0.113.7": true,
+		"203.0.113.8": false,
+		"2001:db8::1": true,
+		"not-an-ip":   false,
+	}
+	for ip, want := range cases {
+		if got := IPAllowed(allowed, ip); got != want {
+			t.Errorf("IPAllowed(%q) = %v, want %v", ip, got, want)
+		}
+	}
+	if !IPAllowed(nil, "198.51.100.1") {
+		t.Error("danh sách rỗng phải cho phép mọi IP")
+	}
+}
+
+func TestValidateAllowedIPs(t *testing.T) {
+	if err := ValidateAllowedIPs([]string{"10.0.0.0/8", "::1", "192.168.1.1"}); err != nil {
+		t.Fatalf("IP/CIDR hợp lệ bị từ chối: %v", err)
+	}
+	if err := ValidateAllowedIPs([]string{"10.0.0.0/33"}); err == nil {
+		t.Fatal("CIDR sai phải lỗi")
+	}
+}
+
+func TestScopesOutsideRole(t *testing.T) {
+	role := map[string]bool{"AgentManagement.CheckIn": true, "CustomerIntelligence.Ingest": true}
+	outside := ScopesOutsideRole([]string{"AgentManagement.CheckIn", "User.Read"}, role)
+	if len(outside) != 1 || outside[0] != "User.Read" {
+		t.Fatalf("outside = %v", outside)
+	}
+	if outside := ScopesOutsideRole([]string{"AgentManagement.CheckIn"}, role); len(outside) != 0 {
+		t.Fatalf("scope con của role không được báo: %v", outside)
+	}
+}
+
+func TestCheckAPIKeyUse(t *testing.T) {
+	now := time.Now()
+	valid := func() *APIKeyIdentity {
+		return &APIKeyIdentity{Key: models.APIKey{AllowedIPs: []string{"10.0.0.0/24"}, ExpiresAt: now.Add(time.Hour).UnixMilli()}}
+	}
+
+	if err := CheckAPIKeyUse(valid(), "10.0.0.2", now); err != nil {
+		t.Fatalf("key hợp lệ bị từ chối: %v", err)
+	}
+
+	cases := map[string]func(*APIKeyIdentity) string{
+		"thu hồi": func(id *APIKeyIdentity) string { id.Key.RevokedAt = now.UnixMilli(); return "10.0.0.2" },
+		"hết hạn": func(id *APIKeyIdentity) string {
+			id.Key.ExpiresAt = now.Add(-time.Second).UnixMilli()
+			return "10.0.0.2"
+		},
+		"bị tắt":          func(id *APIKeyIdentity) string { id.ServiceAccount.IsDisabled = true; return "10.0.0.2" },
+		"không được phép": func(*APIKeyIdentity) string { return "10.0.9.9" },
+	}
+	for want, mutate := range cases {
+		identity := valid()
+		ip := mutate(identity)
+		err := CheckAPIKeyUse(identity, ip, now)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,469 @@
+// Package authsvc - phiên đăng nhập (AuthSession): access token ngắn hạn + refresh token theo hwid, thu hồi phiên.
+package authsvc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	models "meta_commerce/internal/api/auth/models"
+	basesvc "meta_commerce/internal/api/base/service"
+	"meta_commerce/internal/common"
+	"meta_commerce/internal/global"
+	"meta_commerce/internal/utility"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Lý do thu hồi phiên (lưu vào danh sách thu hồi để tra cứu).
+const (
+	RevokeReasonLogout       = "logout"
+	RevokeReasonRelogin      = "relogin"
+	RevokeReasonRevoke       = "revoke"
+	RevokeReasonRevokeAll    = "revoke_all"
+	RevokeReasonBlocked      = "blocked"
+	RevokeReasonRefreshReuse = "refresh_reuse"
+)
+
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// SessionTokens cặp token trả cho client khi đăng nhập / refresh.
+type SessionTokens struct {
+	SessionID             string `json:"sessionId"`
+	Token                 string `json:"token"` // Access token (Bearer)
+	TokenExpiresAt        int64  `json:"tokenExpiresAt"`
+	RefreshToken          string `json:"refreshToken"`
+	RefreshTokenExpiresAt int64  `json:"refreshTokenExpiresAt"`
+}
+
+// SessionMeta thông tin thiết bị ghi kèm phiên (hiển thị trong danh sách phiên).
+type SessionMeta struct {
+	Hwid      string
+	UserAgent string
+	IP        string
+}
+
+// AuthSessionService quản lý phiên đăng nhập và danh sách thu hồi.
+type AuthSessionService struct {
+	*basesvc.BaseServiceMongoImpl[models.AuthSession]
+	revokedService *basesvc.BaseServiceMongoImpl[models.AuthRevokedSession]
+	userService    *basesvc.BaseServiceMongoImpl[models.User]
+}
+
+var (
+	sessionRevokedCallbacks   []func(sessionID string)
+	sessionRevokedCallbacksMu sync.RWMutex
+)
+
+// RegisterSessionRevokedCallback đăng ký hàm được gọi khi một phiên bị thu hồi trên instance này
+// (middleware dùng để xóa cache ngay, instance khác thấy sau khi cache hết hạn).
+func RegisterSessionRevokedCallback(fn func(sessionID string)) {
+	sessionRevokedCallbacksMu.Lock()
+	sessionRevokedCallbacks = append(sessionRevokedCallbacks, fn)
+	sessionRevokedCallbacksMu.Unlock()
+}
+
+// NewAuthSessionService tạo mới AuthSessionService
+func NewAuthSessionService() (*AuthSessionService, error) {
+	sessionCollection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.AuthSessions)
+	if !exist {
+		return nil, fmt.Errorf("failed to get auth_sessions collection: %v", common.ErrNotFound)
+	}
+	revokedCollection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.AuthRevokedSessions)
+	if !exist {
+		return nil, fmt.Errorf("failed to get auth_revoked_sessions collection: %v", common.ErrNotFound)
+	}
+	userCollection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.Users)
+	if !exist {
+		return nil, fmt.Errorf("failed to get users collection: %v", common.ErrNotFound)
+	}
+	return &AuthSessionService{
+		BaseServiceMongoImpl: basesvc.NewBaseServiceMongo[models.AuthSession](sessionCollection),
+		revokedService:       basesvc.NewBaseServiceMongo[models.AuthRevokedSession](revokedCollection),
+		userService:          basesvc.NewBaseServiceMongo[models.User](userCollection),
+	}, nil
+}
+
+// AccessTokenTTL thời gian sống access token (AUTH_ACCESS_TOKEN_TTL).
+func AccessTokenTTL() time.Duration {
+	if cfg := global.MongoDB_ServerConfig; cfg != nil && cfg.AuthAccessTokenTTL > 0 {
+		return time.Duration(cfg.AuthAccessTokenTTL) * time.Second
+	}
+	return defaultAccessTokenTTL
+}
+
+// RefreshTokenTTL thời gian sống refresh token / phiên (AUTH_REFRESH_TOKEN_TTL).
+func RefreshTokenTTL() time.Duration {
+	if cfg := global.MongoDB_ServerConfig; cfg != nil && cfg.AuthRefreshTokenTTL > 0 {
+		return time.Duration(cfg.AuthRefreshTokenTTL) * time.Second
+	}
+	return defaultRefreshTokenTTL
+}
+
+// TokenHash hash SHA-256 (hex) của token — DB chỉ lưu hash, không lưu token.
+func TokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// activeSessionFilter phiên chưa thu hồi và chưa hết hạn.
+func activeSessionFilter(now time.Time) bson.M {
+	return bson.M{"revokedAt": bson.M{"$exists": false}, "expiresAt": bson.M{"$gt": now}}
+}
+
+// Issue tạo phiên mới cho user trên thiết bị hwid (đăng nhập). Phiên cũ cùng hwid bị thu hồi.
+func (s *AuthSessionService) Issue(ctx context.Context, userID primitive.ObjectID, meta SessionMeta) (*SessionTokens, error) {
+	if _, err := s.RevokeByHwid(ctx, userID, meta.Hwid, RevokeReasonRelogin); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	session := models.AuthSession{
+		ID:         primitive.NewObjectID(),
+		UserID:     userID,
+		Hwid:       meta.Hwid,
+		UserAgent:  meta.UserAgent,
+		IP:         meta.IP,
+		LastUsedAt: now.UnixMilli(),
+		ExpiresAt:  now.Add(RefreshTokenTTL()),
+	}
+	tokens, err := signSessionTokens(session, now)
+	if err != nil {
+		return nil, err
+	}
+	session.RefreshTokenHash = TokenHash(tokens.RefreshToken)
+	if _, err := s.BaseServiceMongoImpl.InsertOne(ctx, session); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Refresh đổi refresh token lấy cặp token mới (xoay vòng refresh token).
+// Chấp nhận cả token cũ (không exp, đã chuyển sang phiên qua LegacyTokenHash) — client chuyển sang token mới mà không phải đăng nhập lại.
+// Refresh token đã dùng bị trình lại (hash không khớp) → coi như bị lộ, thu hồi cả phiên.
+func (s *AuthSessionService) Refresh(ctx context.Context, refreshToken string, meta SessionMeta) (*SessionTokens, error) {
+	claims, err := utility.ParseToken(global.MongoDB_ServerConfig.JwtSecret, refreshToken)
+	if err != nil {
+		return nil, common.ErrTokenInvalid
+	}
+	now := time.Now()
+	hash := TokenHash(refreshToken)
+
+	var session models.AuthSession
+	var match bson.M
+	if claims.SessionID == "" {
+		session, err = s.BaseServiceMongoImpl.FindOne(ctx, bson.M{"legacyTokenHash": hash}, nil)
+		if errors.Is(err, common.ErrNotFound) {
+			session, err = s.migrateLegacyTokenOnUse(ctx, refreshToken, hash)
+		}
+		if err != nil {
+			return nil, common.ErrTokenInvalid
+		}
+		match = bson.M{"_id": session.ID, "legacyTokenHash": hash}
+	} else {
+		if claims.TokenType != models.TokenTypeRefresh {
+			return nil, common.ErrTokenInvalid
+		}
+		sessionID, errID := primitive.ObjectIDFromHex(claims.SessionID)
+		if errID != nil {
+			return nil, common.ErrTokenInvalid
+		}
+		session, err = s.BaseServiceMongoImpl.FindOneById(ctx, sessionID)
+		if err != nil {
+			return nil, common.ErrTokenInvalid
+		}
+		if session.RevokedAt == 0 && session.RefreshTokenHash != hash {
+			logrus.WithFields(logrus.Fields{"session_id": session.ID.Hex(), "user_id": session.UserID.Hex()}).Warn("🔐 [AUTH] Refresh token đã dùng bị trình lại, thu hồi phiên")
+			_ = s.revoke(ctx, session, RevokeReasonRefreshReuse)
+			return nil, common.ErrTokenInvalid
+		}
+		match = bson.M{"_id": session.ID, "refreshTokenHash": hash}
+	}
+	if session.RevokedAt != 0 || !session.ExpiresAt.After(now) {
+		return nil, common.ErrTokenInvalid
+	}
+
+	user, err := s.userService.FindOneById(ctx, session.UserID)
+	if err != nil {
+		return nil, common.ErrTokenInvalid
+	}
+	if user.IsBlock {
+		_ = s.revoke(ctx, session, RevokeReasonBlocked)
+		return nil, common.NewError(common.ErrCodeAuthCredentials, "Tài khoản đã bị khóa: "+user.BlockNote, common.StatusForbidden, nil)
+	}
+
+	session.ExpiresAt = now.Add(RefreshTokenTTL())
+	tokens, err := signSessionTokens(session, now)
+	if err != nil {
+		return nil, err
+	}
+	set := map[string]interface{}{
+		"refreshTokenHash": TokenHash(tokens.RefreshToken),
+		"lastUsedAt":       now.UnixMilli(),
+		"expiresAt":        session.ExpiresAt,
+	}
+	if meta.UserAgent != "" {
+		set["userAgent"] = meta.UserAgent
+	}
+	if meta.IP != "" {
+		set["ip"] = meta.IP
+	}
+	update := &basesvc.UpdateData{Set: set}
+	if claims.SessionID == "" {
+		update.Unset = map[string]interface{}{"legacyTokenHash": ""}
+	}
+	// Điều kiện trên hash cũ: hai request refresh đồng thời chỉ một bên thắng
+	if _, err := s.BaseServiceMongoImpl.UpdateOne(ctx, match, update, nil); err != nil {
+		if errors.Is(err, common.ErrNotFound) {
+			return nil, common.ErrTokenInvalid
+		}
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// ResolveLegacyToken tìm phiên còn hiệu lực của token cũ (không exp). Token chưa được chuyển sang phiên thì chuyển ngay
+// (migrateLegacyTokenOnUse) — client không gọi /auth/refresh vẫn dùng tiếp được. Không có → ErrTokenInvalid.
+func (s *AuthSessionService) ResolveLegacyToken(ctx context.Context, token string) (*models.AuthSession, error) {
+	hash := TokenHash(token)
+	filter := activeSessionFilter(time.Now())
+	filter["legacyTokenHash"] = hash
+	session, err := s.BaseServiceMongoImpl.FindOne(ctx, filter, nil)
+	if errors.Is(err, common.ErrNotFound) {
+		// Token đã đổi qua /auth/refresh hoặc phiên bị thu hồi: User không còn giữ token → không chuyển lại được
+		if _, errMigrate := s.migrateLegacyTokenOnUse(ctx, token, hash); errMigrate == nil {
+			session, err = s.BaseServiceMongoImpl.FindOne(ctx, filter, nil)
+		} else if !errors.Is(errMigrate, common.ErrNotFound) {
+			return nil, errMigrate
+		}
+	}
+	if err != nil {
+		if errors.Is(err, common.ErrNotFound) {
+			return nil, common.ErrTokenInvalid
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// IsRevoked phiên có trong danh sách thu hồi không.
+func (s *AuthSessionService) IsRevoked(ctx context.Context, sessionID primitive.ObjectID) (bool, error) {
+	return s.revokedService.DocumentExists(ctx, bson.M{"sessionId": sessionID})
+}
+
+// ListActive danh sách phiên/thiết bị còn hiệu lực của user (mới dùng gần nhất trước).
+func (s *AuthSessionService) ListActive(ctx context.Context, userID primitive.ObjectID) ([]models.AuthSession, error) {
+	filter := activeSessionFilter(time.Now())
+	filter["userId"] = userID
+	sessions, err := s.BaseServiceMongoImpl.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "lastUsedAt", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Legacy = sessions[i].LegacyTokenHash != ""
+	}
+	return sessions, nil
+}
+
+// Revoke thu hồi một phiên của user. Phiên không thuộc user hoặc đã thu hồi → ErrNotFound.
+func (s *AuthSessionService) Revoke(ctx context.Context, userID, sessionID primitive.ObjectID, reason string) error {
+	filter := activeSessionFilter(time.Now())
+	filter["_id"] = sessionID
+	filter["userId"] = userID
+	session, err := s.BaseServiceMongoImpl.FindOne(ctx, filter, nil)
+	if err != nil {
+		return err
+	}
+	return s.revoke(ctx, session, reason)
+}
+
+// RevokeAll thu hồi mọi phiên còn hiệu lực của user, trừ except (phiên hiện tại, có thể nil). Trả số phiên đã thu hồi.
+func (s *AuthSessionService) RevokeAll(ctx context.Context, userID primitive.ObjectID, except *primitive.ObjectID, reason string) (int, error) {
+	filter := activeSessionFilter(time.Now())
+	filter["userId"] = userID
+	if except != nil {
+		filter["_id"] = bson.M{"$ne": *except}
+	}
+	return s.revokeMatching(ctx, filter, reason)
+}
+
+// RevokeByHwid thu hồi phiên của user trên một thiết bị (đăng xuất / đăng nhập lại cùng hwid).
+func (s *AuthSessionService) RevokeByHwid(ctx context.Context, userID primitive.ObjectID, hwid string, reason string) (int, error) {
+	filter := activeSessionFilter(time.Now())
+	filter["userId"] = userID
+	filter["hwid"] = hwid
+	return s.revokeMatching(ctx, filter, reason)
+}
+
+func (s *AuthSessionService) revokeMatching(ctx context.Context, filter bson.M, reason string) (int, error) {
+	sessions, err := s.BaseServiceMongoImpl.Find(ctx, filter, nil)
+	if err != nil {
+		return 0, err
+	}
+	revoked := 0
+	for _, session := range sessions {
+		if err := s.revoke(ctx, session, reason); err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
+// revoke đánh dấu phiên đã thu hồi (refresh không dùng được nữa) và đưa sid vào danh sách thu hồi
+// tới khi access token cuối cùng của phiên hết hạn.
+func (s *AuthSessionService) revoke(ctx context.Context, session models.AuthSession, reason string) error {
+	now := time.Now()
+	update := &basesvc.UpdateData{Set: map[string]interface{}{"revokedAt": now.UnixMilli()}}
+	if _, err := s.BaseServiceMongoImpl.UpdateOne(ctx, bson.M{"_id": session.ID}, update, nil); err != nil && !errors.Is(err, common.ErrNotFound) {
+		return err
+	}
+	entry := models.AuthRevokedSession{
+		SessionID: session.ID,
+		UserID:    session.UserID,
+		Reason:    reason,
+		ExpiresAt: now.Add(AccessTokenTTL()),
+	}
+	if _, err := s.revokedService.InsertOne(ctx, entry); err != nil && !errors.Is(err, common.ErrMongoDuplicate) {
+		return err
+	}
+
+	sessionRevokedCallbacksMu.RLock()
+	callbacks := sessionRevokedCallbacks
+	sessionRevokedCallbacksMu.RUnlock()
+	for _, fn := range callbacks {
+		fn(session.ID.Hex())
+	}
+	return nil
+}
+
+// MigrateLegacyTokens chuyển token cũ trên User (token/tokens, không hết hạn) sang AuthSession với LegacyTokenHash,
+// rồi xóa khỏi User — người dùng không phải đăng nhập lại. Chỉ quét user còn token cũ; chạy lúc khởi động khi bật
+// AUTH_LEGACY_TOKEN_MIGRATE (một lần khi nâng cấp). Chạy lại an toàn (unique legacyTokenHash). Trả số phiên đã tạo.
+func (s *AuthSessionService) MigrateLegacyTokens(ctx context.Context) (int, error) {
+	users, err := s.userService.Find(ctx, bson.M{"$or": []bson.M{
+		{"tokens.0": bson.M{"$exists": true}},
+		{"token": bson.M{"$nin": []interface{}{"", nil}}},
+	}}, nil)
+	if err != nil {
+		return 0, err
+	}
+	created := 0
+	now := time.Now()
+	for _, user := range users {
+		n, err := s.migrateUserLegacyTokens(ctx, user, now)
+		created += n
+		if err != nil {
+			return created, err
+		}
+	}
+	return created, nil
+}
+
+// migrateLegacyTokenOnUse token cũ chưa được chuyển lúc khởi động: chuyển token cũ của user sở hữu nó ngay lần đầu
+// client dùng token (Bearer hoặc /auth/refresh), rồi trả phiên của token. User bị khóa không tạo phiên → ErrNotFound.
+func (s *AuthSessionService) migrateLegacyTokenOnUse(ctx context.Context, token, hash string) (models.AuthSession, error) {
+	user, err := s.userService.FindOne(ctx, bson.M{"$or": []bson.M{
+		{"token": token},
+		{"tokens.jwtToken": token},
+	}}, nil)
+	if err != nil {
+		return models.AuthSession{}, err
+	}
+	if _, err := s.migrateUserLegacyTokens(ctx, user, time.Now()); err != nil {
+		return models.AuthSession{}, err
+	}
+	return s.BaseServiceMongoImpl.FindOne(ctx, bson.M{"legacyTokenHash": hash}, nil)
+}
+
+// migrateUserLegacyTokens tạo phiên cho từng token cũ của user (bỏ qua user bị khóa) rồi xóa token/tokens khỏi User.
+func (s *AuthSessionService) migrateUserLegacyTokens(ctx context.Context, user models.User, now time.Time) (int, error) {
+	created := 0
+	if !user.IsBlock {
+		seen := make(map[string]bool)
+		legacy := append([]models.Token(nil), user.Tokens...)
+		legacy = append(legacy, models.Token{JwtToken: user.Token})
+		for _, t := range legacy {
+			if t.JwtToken == "" || seen[t.JwtToken] {
+				continue
+			}
+			seen[t.JwtToken] = true
+			session := models.AuthSession{
+				UserID:          user.ID,
+				Hwid:            t.Hwid,
+				LegacyTokenHash: TokenHash(t.JwtToken),
+				LastUsedAt:      now.UnixMilli(),
+				ExpiresAt:       now.Add(RefreshTokenTTL()),
+			}
+			if _, err := s.BaseServiceMongoImpl.InsertOne(ctx, session); err != nil {
+				if errors.Is(err, common.ErrMongoDuplicate) {
+					continue
+				}
+				return created, err
+			}
+			created++
+		}
+	}
+	unset := &basesvc.UpdateData{Unset: map[string]interface{}{"token": "", "tokens": ""}}
+	if _, err := s.userService.UpdateById(ctx, user.ID, unset); err != nil {
+		return created, err
+	}
+	return created, nil
+}
+
+// signSessionTokens ký access token (hạn AccessTokenTTL) và refresh token (hạn = hạn phiên) cho phiên.
+func signSessionTokens(session models.AuthSession, now time.Time) (*SessionTokens, error) {
+	secret := global.MongoDB_ServerConfig.JwtSecret
+	accessExpiresAt := now.Add(AccessTokenTTL())
+	if accessExpiresAt.After(session.ExpiresAt) {
+		accessExpiresAt = session.ExpiresAt
+	}
+	base := models.JwtToken{
+		UserID:    session.UserID.Hex(),
+		Time:      strconv.FormatInt(now.Unix(), 16),
+		SessionID: session.ID.Hex(),
+		Hwid:      session.Hwid,
+	}
+
+	access := base
+	access.TokenType = models.TokenTypeAccess
+	access.StandardClaims = jwt.StandardClaims{Id: randomTokenID(), IssuedAt: now.Unix(), ExpiresAt: accessExpiresAt.Unix()}
+	accessToken, err := utility.SignToken(secret, access)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh := base
+	refresh.TokenType = models.TokenTypeRefresh
+	refresh.StandardClaims = jwt.StandardClaims{Id: randomTokenID(), IssuedAt: now.Unix(), ExpiresAt: session.ExpiresAt.Unix()}
+	refreshToken, err := utility.SignToken(secret, refresh)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SessionTokens{
+		SessionID:             session.ID.Hex(),
+		Token:                 accessToken,
+		TokenExpiresAt:        accessExpiresAt.Unix(),
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: session.ExpiresAt.Unix(),
+	}, nil
+}
+
+func randomTokenID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,76 @@
+package authsvc
+
+import (
+	"testing"
+	"time"
+
+	"meta_commerce/config"
+	models "meta_commerce/internal/api/auth/models"
+	"meta_commerce/internal/global"
+	"meta_commerce/internal/utility"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func withServerConfig(t *testing.T, cfg *config.Configuration) {
+	prev := global.MongoDB_ServerConfig
+	global.MongoDB_ServerConfig = cfg
+	t.Cleanup(func() { global.MongoDB_ServerConfig = prev })
+}
+
+func TestSignSessionTokens_VerifiedInMemory(t *testing.T) {
+	withServerConfig(t, &config.Configuration{JwtSecret: "secret", AuthAccessTokenTTL: 60, AuthRefreshTokenTTL: 3600})
+	now := time.Now()
+	session := models.AuthSession{ID: primitive.NewObjectID(), UserID: primitive.NewObjectID(), Hwid: "dev-1", ExpiresAt: now.Add(RefreshTokenTTL())}
+
+	tokens, err := signSessionTokens(session, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	access, err := utility.ParseToken("secret", tokens.Token)
+	if err != nil {
+		t.Fatalf("access token: %v", err)
+	}
+	if access.TokenType != models.TokenTypeAccess || access.SessionID != session.ID.Hex() || access.UserID != session.UserID.Hex() {
+		t.Fatalf("claims access sai: %+v", access)
+	}
+	if access.ExpiresAt != now.Add(time.Minute).Unix() {
+		t.Fatalf("exp access = %d", access.ExpiresAt)
+	}
+	refresh, err := utility.ParseToken("secret", tokens.RefreshToken)
+	if err != nil || refresh.TokenType != models.TokenTypeRefresh || refresh.ExpiresAt != session.ExpiresAt.Unix() {
+		t.Fatalf("refresh token: %+v, %v", refresh, err)
+	}
+	if _, err := utility.ParseToken("other-secret", tokens.Token); err == nil {
+		t.Fatal("sai secret phải lỗi")
+	}
+}
+
+func TestSignSessionTokens_ExpiredAccessRejected(t *testing.T) {
+	withServerConfig(t, &config.Configuration{JwtSecret: "secret", AuthAccessTokenTTL: 60})
+	issued := time.Now().Add(-2 * time.Hour)
+	session := models.AuthSession{ID: primitive.NewObjectID(), UserID: primitive.NewObjectID(), ExpiresAt: issued.Add(time.Hour)}
+
+	tokens, err := signSessionTokens(session, issued)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = utility.ParseToken("secret", tokens.Token)
+	if !utility.IsTokenExpired(err) {
+		t.Fatalf("access hết hạn phải báo expired, got %v", err)
+	}
+}
+
+func TestParseToken_LegacyTokenHasNoSession(t *testing.T) {
+	legacy, err := utility.CreateToken("secret", primitive.NewObjectID().Hex(), "18f", "7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := utility.ParseToken("secret", legacy["token"])
+	if err != nil {
+		t.Fatalf("token cũ không exp vẫn hợp lệ chữ ký: %v", err)
+	}
+	if claims.SessionID != "" || claims.ExpiresAt != 0 {
+		t.Fatalf("token cũ không có sid/exp: %+v", claims)
+	}
+}
@@ -5,9 +5,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"math/rand"
-	"strconv"
-	"time"
 
 	authdto "meta_commerce/internal/api/auth/dto"
 	models "meta_commerce/internal/api/auth/models"
@@ -26,6 +23,7 @@ import (
 type UserService struct {
 	*basesvc.BaseServiceMongoImpl[models.User]
 	userRoleService *basesvc.BaseServiceMongoImpl[models.UserRole]
+	sessionService  *AuthSessionService
 }
 
 // NewUserService tạo mới UserService
@@ -38,37 +36,26 @@ func NewUserService() (*UserService, error) {
 	if !exist {
 		return nil, fmt.Errorf("failed to get user_roles collection: %v", common.ErrNotFound)
 	}
+	sessionService, err := NewAuthSessionService()
+	if err != nil {
+		return nil, err
+	}
 
 	return &UserService{
 		BaseServiceMongoImpl: basesvc.NewBaseServiceMongo[models.User](userCollection),
 		userRoleService:      basesvc.NewBaseServiceMongo[models.UserRole](userRoleCollection),
+		sessionService:       sessionService,
 	}, nil
 }
 
-// Logout đăng xuất người dùng (xóa token theo hwid)
+// Logout đăng xuất người dùng (thu hồi phiên theo hwid)
 func (s *UserService) Logout(ctx context.Context, userID primitive.ObjectID, input *authdto.UserLogoutInput) error {
-	user, err := s.BaseServiceMongoImpl.FindOneById(ctx, userID)
-	if err != nil {
-		return err
-	}
-	newTokens := make([]models.Token, 0)
-	for _, t := range user.Tokens {
-		if t.Hwid != input.Hwid {
-			newTokens = append(newTokens, t)
-		}
-	}
-	updateData := &basesvc.UpdateData{
-		Set: map[string]interface{}{
-			"tokens": newTokens,
-			"token":  "",
-		},
-	}
-	_, err = s.BaseServiceMongoImpl.UpdateById(ctx, userID, updateData)
+	_, err := s.sessionService.RevokeByHwid(ctx, userID, input.Hwid, RevokeReasonLogout)
 	return err
 }
 
-// LoginWithFirebase đăng nhập bằng Firebase ID token
-func (s *UserService) LoginWithFirebase(ctx context.Context, input *authdto.FirebaseLoginInput) (*models.User, error) {
+// LoginWithFirebase đăng nhập bằng Firebase ID token — tạo phiên theo hwid, trả access token (User.Token) + refresh token
+func (s *UserService) LoginWithFirebase(ctx context.Context, input *authdto.FirebaseLoginInput, meta SessionMeta) (*models.User, error) {
 	token, err := utility.VerifyIDToken(ctx, input.IDToken)
 	if err != nil {
 		logrus.WithError(err).Error("LoginWithFirebase: Lỗi verify Firebase ID token")
@@ -167,38 +154,16 @@ func (s *UserService) LoginWithFirebase(ctx context.Context, input *authdto.Fire
 		return nil, common.NewError(common.ErrCodeAuth, "Tài khoản đã bị khóa", common.StatusForbidden, nil)
 	}
 
-	rdNumber := rand.Intn(100)
-	currentTime := time.Now().Unix()
-	tokenMap, err := utility.CreateToken(global.MongoDB_ServerConfig.JwtSecret, user.ID.Hex(), strconv.FormatInt(currentTime, 16), strconv.Itoa(rdNumber))
-	if err != nil {
-		return nil, err
-	}
-
-	user.Token = tokenMap["token"]
-	var idTokenExist int = -1
-	for i, _token := range user.Tokens {
-		if _token.Hwid == input.Hwid {
-			idTokenExist = i
-			break
-		}
-	}
-	if idTokenExist == -1 {
-		user.Tokens = append(user.Tokens, models.Token{Hwid: input.Hwid, JwtToken: tokenMap["token"]})
-	} else {
-		user.Tokens[idTokenExist].JwtToken = tokenMap["token"]
-	}
-
-	tokenUpdateData := &basesvc.UpdateData{
-		Set: map[string]interface{}{
-			"token":  user.Token,
-			"tokens": user.Tokens,
-		},
-	}
-	updatedUser, err := s.BaseServiceMongoImpl.UpdateById(ctx, user.ID, tokenUpdateData)
+	meta.Hwid = input.Hwid
+	tokens, err := s.sessionService.Issue(ctx, user.ID, meta)
 	if err != nil {
-		logrus.WithFields(logrus.Fields{"user_id": user.ID.Hex(), "error": err.Error()}).Error("LoginWithFirebase: Lỗi khi cập nhật token vào user")
+		logrus.WithFields(logrus.Fields{"user_id": user.ID.Hex(), "error": err.Error()}).Error("LoginWithFirebase: Lỗi khi tạo phiên đăng nhập")
 		return nil, err
 	}
+	updatedUser := user
+	updatedUser.Token = tokens.Token
+	updatedUser.TokenExpiresAt = tokens.TokenExpiresAt
+	updatedUser.RefreshToken = tokens.RefreshToken
 
 	// Ghi chú: Logic "first user becomes admin" được xử lý ở auth handler (tránh import cycle authsvc -> services)
 
@@ -8,11 +8,35 @@ import (
 	basesvc "meta_commerce/internal/api/base/service"
 	"meta_commerce/internal/common"
 	"meta_commerce/internal/global"
+	"meta_commerce/internal/keyring"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// secretConfigKeys key config item chứa secret — value lưu Mongo dạng ciphertext keyring, không lưu plaintext.
+var secretConfigKeys = map[string]bool{}
+
+// RegisterSecretConfigKeys đánh dấu key config item là secret. Domain sở hữu key gọi trong init().
+func RegisterSecretConfigKeys(keys ...string) {
+	for _, k := range keys {
+		secretConfigKeys[k] = true
+	}
+}
+
+// IsSecretConfigKey key config item có value mã hóa bằng keyring.
+func IsSecretConfigKey(key string) bool {
+	return secretConfigKeys[key]
+}
+
+// secretConfigValue bọc value chuỗi của key secret thành keyring.Secret (mã hóa khi ghi Mongo).
+func secretConfigValue(key string, value interface{}) interface{} {
+	if v, ok := value.(string); ok && IsSecretConfigKey(key) {
+		return keyring.Secret(v)
+	}
+	return value
+}
+
 // OrganizationConfigItemService xử lý config theo từng key (1 document per key).
 type OrganizationConfigItemService struct {
 	*basesvc.BaseServiceMongoImpl[models.OrganizationConfigItem]
@@ -123,6 +147,13 @@ func (s *OrganizationConfigItemService) Upsert(ctx context.Context, filter inter
 				}
 			}
 		}
+		if value, ok := updateData.Set["value"]; ok {
+			keyStr, _ := updateData.Set["key"].(string)
+			if keyStr == "" {
+				keyStr = configKeyFromFilter(filter)
+			}
+			updateData.Set["value"] = secretConfigValue(keyStr, value)
+		}
 		return s.BaseServiceMongoImpl.Upsert(ctx, filter, updateData)
 	}
 	item, ok := data.(*models.OrganizationConfigItem)
@@ -143,13 +174,28 @@ func (s *OrganizationConfigItemService) Upsert(ctx context.Context, filter inter
 		}
 	}
 	item.IsSystem = false
-	result, err := s.BaseServiceMongoImpl.Upsert(ctx, filter, item)
+	stored := *item
+	stored.Value = secretConfigValue(item.Key, item.Value)
+	result, err := s.BaseServiceMongoImpl.Upsert(ctx, filter, &stored)
 	if err != nil {
 		return zero, err
 	}
 	return result, nil
 }
 
+// configKeyFromFilter key trong filter upsert (khi body không gửi key).
+func configKeyFromFilter(filter interface{}) string {
+	switch f := filter.(type) {
+	case bson.M:
+		key, _ := f["key"].(string)
+		return key
+	case map[string]interface{}:
+		key, _ := f["key"].(string)
+		return key
+	}
+	return ""
+}
+
 // UpsertItem tạo hoặc cập nhật một config item.
 func (s *OrganizationConfigItemService) UpsertItem(ctx context.Context, item *models.OrganizationConfigItem) (*models.OrganizationConfigItem, error) {
 	filter := bson.M{"ownerOrganizationId": item.OwnerOrganizationID, "key": item.Key}
@@ -0,0 +1,38 @@
+package authsvc
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"meta_commerce/internal/keyring"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Value của key secret ghi Mongo dạng ciphertext keyring; key thường giữ nguyên.
+func TestSecretConfigValue_EncryptsRegisteredKeys(t *testing.T) {
+	kr, err := keyring.New("k1:"+base64.StdEncoding.EncodeToString([]byte(strings.Repeat("a", 32))), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring.SetDefault(kr)
+	defer keyring.SetDefault(nil)
+	RegisterSecretConfigKeys("test_webhook_secret")
+
+	raw, err := bson.Marshal(bson.M{"value": secretConfigValue("test_webhook_secret", "s3cret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := bson.Raw(raw).Lookup("value").StringValueOK()
+	if !keyring.IsEncrypted(stored) {
+		t.Fatalf("secret lưu plaintext: %q", stored)
+	}
+	if plain, err := keyring.DecryptString(stored); err != nil || plain != "s3cret" {
+		t.Fatalf("giải mã: %q %v", plain, err)
+	}
+
+	if v := secretConfigValue("webhook_signature_mode", "grace"); v != "grace" {
+		t.Fatalf("key thường không bọc: %#v", v)
+	}
+}
@@ -0,0 +1,252 @@
+// Package authsvc - rate limit theo tổ chức / user / API key, bộ đếm dùng chung qua MongoDB.
+package authsvc
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	models "meta_commerce/internal/api/auth/models"
+	basesvc "meta_commerce/internal/api/base/service"
+	"meta_commerce/internal/common"
+	"meta_commerce/internal/global"
+	"meta_commerce/internal/utility"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// rateLimitPolicyCacheTTL thời gian tối đa một thay đổi policy chưa có hiệu lực (không cần restart).
+const rateLimitPolicyCacheTTL = 30 * time.Second
+
+// DefaultRateLimitPolicies giới hạn mặc định khi cả tổ chức lẫn System Organization chưa cấu hình (routeGroup, scope).
+var DefaultRateLimitPolicies = []models.RateLimitPolicy{
+	{RouteGroup: models.RateLimitGroupCRUD, Scope: models.RateLimitScopeOrganization, SustainedLimit: 3000, SustainedWindowSeconds: 60},
+	{RouteGroup: models.RateLimitGroupCRUD, Scope: models.RateLimitScopeUser, BurstLimit: 60, BurstWindowSeconds: 5, SustainedLimit: 600, SustainedWindowSeconds: 60},
+	{RouteGroup: models.RateLimitGroupCRUD, Scope: models.RateLimitScopeAPIKey, BurstLimit: 100, BurstWindowSeconds: 5, SustainedLimit: 1200, SustainedWindowSeconds: 60},
+	{RouteGroup: models.RateLimitGroupIngest, Scope: models.RateLimitScopeOrganization, SustainedLimit: 3000, SustainedWindowSeconds: 60},
+	{RouteGroup: models.RateLimitGroupIngest, Scope: models.RateLimitScopeAPIKey, BurstLimit: 50, BurstWindowSeconds: 5, SustainedLimit: 600, SustainedWindowSeconds: 60},
+	{RouteGroup: models.RateLimitGroupReports, Scope: models.RateLimitScopeOrganization, DailyQuota: 5000},
+	{RouteGroup: models.RateLimitGroupReports, Scope: models.RateLimitScopeUser, BurstLimit: 10, BurstWindowSeconds: 5, SustainedLimit: 60, SustainedWindowSeconds: 60},
+	{RouteGroup: models.RateLimitGroupAI, Scope: models.RateLimitScopeOrganization, DailyQuota: 1000},
+	{RouteGroup: models.RateLimitGroupAI, Scope: models.RateLimitScopeUser, SustainedLimit: 30, SustainedWindowSeconds: 60},
+}
+
+// rateLimitGroupPrefixes path → nhóm route; path không khớp thuộc nhóm crud.
+var rateLimitGroupPrefixes = []struct {
+	prefix string
+	group  string
+}{
+	{"/api/v1/cio/ingest", models.RateLimitGroupIngest},
+	{"/api/v1/agent-management/check-in", models.RateLimitGroupIngest},
+	{"/api/v1/reports/", models.RateLimitGroupReports},
+	{"/api/v1/dashboard/", models.RateLimitGroupReports},
+	{"/api/v1/ai/", models.RateLimitGroupAI},
+	{"/api/v2/ai/", models.RateLimitGroupAI},
+	{"/api/v1/ai-decision/execute", models.RateLimitGroupAI},
+	{"/api/v1/cix/analyze", models.RateLimitGroupAI},
+}
+
+// RateLimitRouteGroup nhóm route của path.
+func RateLimitRouteGroup(path string) string {
+	for _, p := range rateLimitGroupPrefixes {
+		if strings.HasPrefix(path, p.prefix) {
+			return p.group
+		}
+	}
+	return models.RateLimitGroupCRUD
+}
+
+// RateLimitSubject đối tượng bị đếm: Scope + ID (org id / user id / API key id dạng hex).
+type RateLimitSubject struct {
+	Scope string
+	ID    string
+}
+
+// RateLimitWindow một cửa sổ đếm của policy.
+type RateLimitWindow struct {
+	Name   string // burst | sustained | daily
+	Limit  int
+	Window time.Duration
+}
+
+// RateLimitResult kết quả kiểm tra — cửa sổ chặt nhất (còn ít lượt nhất) để trả header RateLimit-*.
+type RateLimitResult struct {
+	Allowed   bool
+	Scope     string
+	Window    RateLimitWindow
+	Remaining int
+	Reset     time.Duration // Thời gian tới khi cửa sổ được reset
+}
+
+// RateLimitService đọc policy (cache) và tăng bộ đếm fixed-window trên MongoDB.
+type RateLimitService struct {
+	*basesvc.BaseServiceMongoImpl[models.RateLimitPolicy]
+	counterService      *basesvc.BaseServiceMongoImpl[models.RateLimitCounter]
+	organizationService *OrganizationService
+	policyCache         *utility.Cache
+}
+
+// NewRateLimitService tạo mới RateLimitService
+func NewRateLimitService() (*RateLimitService, error) {
+	policyCollection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.RateLimitPolicies)
+	if !exist {
+		return nil, fmt.Errorf("failed to get rate_limit_policies collection: %v", common.ErrNotFound)
+	}
+	counterCollection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.RateLimitCounters)
+	if !exist {
+		return nil, fmt.Errorf("failed to get rate_limit_counters collection: %v", common.ErrNotFound)
+	}
+	organizationService, err := NewOrganizationService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create organization service: %w", err)
+	}
+	return &RateLimitService{
+		BaseServiceMongoImpl: basesvc.NewBaseServiceMongo[models.RateLimitPolicy](policyCollection),
+		counterService:       basesvc.NewBaseServiceMongo[models.RateLimitCounter](counterCollection),
+		organizationService:  organizationService,
+		policyCache:          utility.NewCache(rateLimitPolicyCacheTTL, rateLimitPolicyCacheTTL),
+	}, nil
+}
+
+// Check tăng bộ đếm của từng đối tượng trong nhóm route group và trả về cửa sổ chặt nhất.
+// Dừng ở cửa sổ đầu tiên vượt giới hạn (Allowed = false). Không có policy nào áp dụng → nil.
+func (s *RateLimitService) Check(ctx context.Context, orgID primitive.ObjectID, group string, subjects []RateLimitSubject, now time.Time) (*RateLimitResult, error) {
+	policies, err := s.policiesFor(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	var tightest *RateLimitResult
+	for _, subject := range subjects {
+		policy, ok := policies[rateLimitPolicyKey(group, subject.Scope)]
+		if !ok || policy.IsDisabled {
+			continue
+		}
+		for _, window := range RateLimitWindows(policy) {
+			start := now.Truncate(window.Window)
+			end := start.Add(window.Window)
+			id := fmt.Sprintf("%s:%s:%s:%s:%d", group, subject.Scope, subject.ID, window.Name, start.Unix())
+			count, err := s.increment(ctx, id, end)
+			if err != nil {
+				return nil, err
+			}
+			result := &RateLimitResult{
+				Allowed:   count <= int64(window.Limit),
+				Scope:     subject.Scope,
+				Window:    window,
+				Remaining: max(window.Limit-int(count), 0),
+				Reset:     end.Sub(now),
+			}
+			if !result.Allowed {
+				return result, nil
+			}
+			if tightest == nil || result.Remaining < tightest.Remaining {
+				tightest = result
+			}
+		}
+	}
+	return tightest, nil
+}
+
+// RateLimitWindows các cửa sổ có giới hạn (> 0) của policy.
+func RateLimitWindows(policy models.RateLimitPolicy) []RateLimitWindow {
+	var windows []RateLimitWindow
+	if policy.BurstLimit > 0 && policy.BurstWindowSeconds > 0 {
+		windows = append(windows, RateLimitWindow{Name: "burst", Limit: policy.BurstLimit, Window: time.Duration(policy.BurstWindowSeconds) * time.Second})
+	}
+	if policy.SustainedLimit > 0 && policy.SustainedWindowSeconds > 0 {
+		windows = append(windows, RateLimitWindow{Name: "sustained", Limit: policy.SustainedLimit, Window: time.Duration(policy.SustainedWindowSeconds) * time.Second})
+	}
+	if policy.DailyQuota > 0 {
+		windows = append(windows, RateLimitWindow{Name: "daily", Limit: policy.DailyQuota, Window: 24 * time.Hour})
+	}
+	return windows
+}
+
+// ResolveRateLimitPolicies gộp policy theo thứ tự ưu tiên: tổ chức > System Organization > mặc định.
+// Key của map: routeGroup + "|" + scope.
+func ResolveRateLimitPolicies(defaults, system, org []models.RateLimitPolicy) map[string]models.RateLimitPolicy {
+	resolved := make(map[string]models.RateLimitPolicy, len(defaults))
+	for _, layer := range [][]models.RateLimitPolicy{defaults, system, org} {
+		for _, p := range layer {
+			resolved[rateLimitPolicyKey(p.RouteGroup, p.Scope)] = p
+		}
+	}
+	return resolved
+}
+
+// FormatRateLimitPolicy giá trị header RateLimit-Policy, ví dụ "100;w=60".
+func FormatRateLimitPolicy(window RateLimitWindow) string {
+	return strconv.Itoa(window.Limit) + ";w=" + strconv.Itoa(int(window.Window.Seconds()))
+}
+
+func rateLimitPolicyKey(group, scope string) string {
+	return group + "|" + scope
+}
+
+// policiesFor policy đã gộp cho orgID (NilObjectID: chỉ System + mặc định), cache rateLimitPolicyCacheTTL.
+func (s *RateLimitService) policiesFor(ctx context.Context, orgID primitive.ObjectID) (map[string]models.RateLimitPolicy, error) {
+	cacheKey := "policies:" + orgID.Hex()
+	if cached, ok := s.policyCache.Get(cacheKey); ok {
+		return cached.(map[string]models.RateLimitPolicy), nil
+	}
+
+	systemOrgID := s.systemOrganizationID(ctx)
+	ownerIDs := []primitive.ObjectID{}
+	if !systemOrgID.IsZero() {
+		ownerIDs = append(ownerIDs, systemOrgID)
+	}
+	if !orgID.IsZero() && orgID != systemOrgID {
+		ownerIDs = append(ownerIDs, orgID)
+	}
+	var system, org []models.RateLimitPolicy
+	if len(ownerIDs) > 0 {
+		docs, err := s.BaseServiceMongoImpl.Find(ctx, bson.M{"ownerOrganizationId": bson.M{"$in": ownerIDs}}, nil)
+		if err != nil && err != common.ErrNotFound {
+			return nil, err
+		}
+		for _, doc := range docs {
+			if doc.OwnerOrganizationID == systemOrgID {
+				system = append(system, doc)
+			} else {
+				org = append(org, doc)
+			}
+		}
+	}
+
+	resolved := ResolveRateLimitPolicies(DefaultRateLimitPolicies, system, org)
+	s.policyCache.Set(cacheKey, resolved)
+	return resolved, nil
+}
+
+// systemOrganizationID id System Organization (cache); chưa init → NilObjectID.
+func (s *RateLimitService) systemOrganizationID(ctx context.Context) primitive.ObjectID {
+	if cached, ok := s.policyCache.Get("system_org"); ok {
+		return cached.(primitive.ObjectID)
+	}
+	systemOrg, err := s.organizationService.FindOne(ctx, bson.M{"level": -1, "code": "SYSTEM", "type": models.OrganizationTypeSystem}, nil)
+	if err != nil {
+		return primitive.NilObjectID
+	}
+	s.policyCache.Set("system_org", systemOrg.ID)
+	return systemOrg.ID
+}
+
+// increment tăng bộ đếm id (upsert), trả về giá trị sau khi tăng. Hai instance cùng upsert lần đầu → thử lại một lần.
+func (s *RateLimitService) increment(ctx context.Context, id string, expiresAt time.Time) (int64, error) {
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	update := bson.M{"$inc": bson.M{"count": 1}, "$setOnInsert": bson.M{"expiresAt": expiresAt}}
+	var counter models.RateLimitCounter
+	err := s.counterService.Collection().FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts).Decode(&counter)
+	if mongo.IsDuplicateKeyError(err) {
+		err = s.counterService.Collection().FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts).Decode(&counter)
+	}
+	if err != nil {
+		return 0, common.ConvertMongoError(err)
+	}
+	return counter.Count, nil
+}
@@ -0,0 +1,72 @@
+package authsvc
+
+import (
+	"testing"
+	"time"
+
+	models "meta_commerce/internal/api/auth/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRateLimitRouteGroup(t *testing.T) {
+	cases := map[string]string{
+		"/api/v1/cio/ingest":                     models.RateLimitGroupIngest,
+		"/api/v1/agent-management/check-in":      models.RateLimitGroupIngest,
+		"/api/v1/dashboard/orders/funnel":        models.RateLimitGroupReports,
+		"/api/v1/reports/recompute":              models.RateLimitGroupReports,
+		"/api/v1/ai/workflow-runs/insert-one":    models.RateLimitGroupAI,
+		"/api/v2/ai/steps/1/render-prompt":       models.RateLimitGroupAI,
+		"/api/v1/customers/find":                 models.RateLimitGroupCRUD,
+		"/api/v1/ai-decision/routing-rules/find": models.RateLimitGroupCRUD,
+	}
+	for path, want := range cases {
+		if got := RateLimitRouteGroup(path); got != want {
+			t.Errorf("RateLimitRouteGroup(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestResolveRateLimitPolicies_OrgOverridesSystemOverridesDefault(t *testing.T) {
+	defaults := []models.RateLimitPolicy{
+		{RouteGroup: models.RateLimitGroupIngest, Scope: models.RateLimitScopeAPIKey, SustainedLimit: 600, SustainedWindowSeconds: 60},
+		{RouteGroup: models.RateLimitGroupAI, Scope: models.RateLimitScopeOrganization, DailyQuota: 1000},
+		{RouteGroup: models.RateLimitGroupCRUD, Scope: models.RateLimitScopeUser, SustainedLimit: 600, SustainedWindowSeconds: 60},
+	}
+	system := []models.RateLimitPolicy{
+		{RouteGroup: models.RateLimitGroupAI, Scope: models.RateLimitScopeOrganization, DailyQuota: 500},
+		{RouteGroup: models.RateLimitGroupIngest, Scope: models.RateLimitScopeAPIKey, SustainedLimit: 300, SustainedWindowSeconds: 60},
+	}
+	org := []models.RateLimitPolicy{
+		{RouteGroup: models.RateLimitGroupIngest, Scope: models.RateLimitScopeAPIKey, SustainedLimit: 5000, SustainedWindowSeconds: 60, OwnerOrganizationID: primitive.NewObjectID()},
+		{RouteGroup: models.RateLimitGroupCRUD, Scope: models.RateLimitScopeUser, IsDisabled: true},
+	}
+
+	resolved := ResolveRateLimitPolicies(defaults, system, org)
+	if p := resolved[rateLimitPolicyKey(models.RateLimitGroupIngest, models.RateLimitScopeAPIKey)]; p.SustainedLimit != 5000 {
+		t.Errorf("ingest/api_key phải lấy policy tổ chức, got %+v", p)
+	}
+	if p := resolved[rateLimitPolicyKey(models.RateLimitGroupAI, models.RateLimitScopeOrganization)]; p.DailyQuota != 500 {
+		t.Errorf("ai/organization phải lấy policy System, got %+v", p)
+	}
+	if p := resolved[rateLimitPolicyKey(models.RateLimitGroupCRUD, models.RateLimitScopeUser)]; !p.IsDisabled {
+		t.Errorf("policy tắt của tổ chức phải thay mặc định, got %+v", p)
+	}
+}
+
+func TestRateLimitWindows(t *testing.T) {
+	windows := RateLimitWindows(models.RateLimitPolicy{
+		BurstLimit: 10, BurstWindowSeconds: 5,
+		SustainedLimit: 100, SustainedWindowSeconds: 0, // thiếu window → bỏ qua
+		DailyQuota: 1000,
+	})
+	if len(windows) != 2 || windows[0].Name != "burst" || windows[1].Name != "daily" {
+		t.Fatalf("windows = %+v", windows)
+	}
+	if windows[1].Window != 24*time.Hour {
+		t.Fatalf("daily window = %v", windows[1].Window)
+	}
+	if got := FormatRateLimitPolicy(windows[0]); got != "10;w=5" {
+		t.Fatalf("policy header = %q", got)
+	}
+}
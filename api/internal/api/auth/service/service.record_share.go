@@ -0,0 +1,251 @@
+// Package authsvc - chia sẻ theo bản ghi (record-level) bổ sung cho OrganizationShare.
+package authsvc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	models "meta_commerce/internal/api/auth/models"
+	basesvc "meta_commerce/internal/api/base/service"
+	"meta_commerce/internal/common"
+	"meta_commerce/internal/global"
+	"meta_commerce/internal/logger"
+	"meta_commerce/internal/utility"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// recordShareAccessRetention thời gian giữ nhật ký truy cập bản ghi được chia sẻ.
+const recordShareAccessRetention = 180 * 24 * time.Hour
+
+// recordShareCollectionsTTL chu kỳ làm mới danh sách collection đang có grant hiệu lực
+// (collection không có grant nào thì filter không tốn thêm truy vấn).
+const recordShareCollectionsTTL = 30 * time.Second
+
+// recordShareCollectionsCache cache danh sách collection có grant còn hiệu lực (dùng chung mọi handler).
+var recordShareCollectionsCache = utility.NewCache(recordShareCollectionsTTL, recordShareCollectionsTTL)
+
+// RecordShareService quản lý grant chia sẻ bản ghi và nhật ký truy cập.
+type RecordShareService struct {
+	*basesvc.BaseServiceMongoImpl[models.RecordShareGrant]
+	accessService *basesvc.BaseServiceMongoImpl[models.RecordShareAccess]
+}
+
+// NewRecordShareService tạo mới RecordShareService
+func NewRecordShareService() (*RecordShareService, error) {
+	grantCollection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.RecordShareGrants)
+	if !exist {
+		return nil, fmt.Errorf("failed to get record_share_grants collection: %v", common.ErrNotFound)
+	}
+	accessCollection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.RecordShareAccesses)
+	if !exist {
+		return nil, fmt.Errorf("failed to get record_share_access_logs collection: %v", common.ErrNotFound)
+	}
+	return &RecordShareService{
+		BaseServiceMongoImpl: basesvc.NewBaseServiceMongo[models.RecordShareGrant](grantCollection),
+		accessService:        basesvc.NewBaseServiceMongo[models.RecordShareAccess](accessCollection),
+	}, nil
+}
+
+// AccessLogs service CRUD của nhật ký truy cập (handler dùng cho route đọc).
+func (s *RecordShareService) AccessLogs() *basesvc.BaseServiceMongoImpl[models.RecordShareAccess] {
+	return s.accessService
+}
+
+// CreateGrant kiểm tra và lưu grant. Bản ghi theo RecordIDs phải thuộc ownerOrganizationId của grant.
+func (s *RecordShareService) CreateGrant(ctx context.Context, grant models.RecordShareGrant) (*models.RecordShareGrant, error) {
+	if err := ValidateRecordShareGrant(grant, time.Now()); err != nil {
+		return nil, err
+	}
+	collection, exist := global.RegistryCollections.Get(grant.Collection)
+	if !exist || strings.HasPrefix(grant.Collection, "auth_") {
+		return nil, common.NewError(common.ErrCodeValidationInput, "collection '"+grant.Collection+"' không chia sẻ được", common.StatusBadRequest, nil)
+	}
+	if len(grant.RecordIDs) > 0 {
+		count, err := collection.CountDocuments(ctx, bson.M{"_id": bson.M{"$in": grant.RecordIDs}, "ownerOrganizationId": grant.OwnerOrganizationID})
+		if err != nil {
+			return nil, common.ConvertMongoError(err)
+		}
+		if count != int64(len(grant.RecordIDs)) {
+			return nil, common.NewError(common.ErrCodeValidationInput, "recordIds có bản ghi không tồn tại hoặc không thuộc tổ chức chia sẻ", common.StatusBadRequest, nil)
+		}
+	}
+
+	created, err := s.BaseServiceMongoImpl.InsertOne(ctx, grant)
+	if err != nil {
+		return nil, err
+	}
+	recordShareCollectionsCache.Delete("collections")
+	return &created, nil
+}
+
+// RevokeGrant thu hồi grant (giữ lại document để đối chiếu nhật ký truy cập).
+func (s *RecordShareService) RevokeGrant(ctx context.Context, grant models.RecordShareGrant) (*models.RecordShareGrant, error) {
+	if grant.RevokedAt != 0 {
+		return &grant, nil
+	}
+	updated, err := s.BaseServiceMongoImpl.UpdateById(ctx, grant.ID, &basesvc.UpdateData{Set: map[string]interface{}{"revokedAt": time.Now().UnixMilli()}})
+	if err != nil {
+		return nil, err
+	}
+	recordShareCollectionsCache.Delete("collections")
+	return &updated, nil
+}
+
+// ValidateRecordShareGrant kiểm tra cấu trúc grant: đúng một đối tượng nhận, có cách chọn bản ghi, access hợp lệ.
+func ValidateRecordShareGrant(grant models.RecordShareGrant, now time.Time) error {
+	invalid := func(msg string) error {
+		return common.NewError(common.ErrCodeValidationInput, msg, common.StatusBadRequest, nil)
+	}
+	if grant.OwnerOrganizationID.IsZero() || grant.Collection == "" {
+		return invalid("Thiếu tổ chức chia sẻ hoặc collection")
+	}
+	if grant.GranteeOrganizationID.IsZero() == grant.GranteeUserID.IsZero() {
+		return invalid("Cần đúng một trong granteeOrganizationId hoặc granteeUserId")
+	}
+	if grant.GranteeOrganizationID == grant.OwnerOrganizationID {
+		return invalid("Không chia sẻ cho chính tổ chức sở hữu")
+	}
+	hasMatch := grant.MatchField != "" || len(grant.MatchValues) > 0
+	if (len(grant.RecordIDs) > 0) == hasMatch {
+		return invalid("Chọn bản ghi bằng recordIds hoặc matchField + matchValues (không dùng cả hai)")
+	}
+	if hasMatch {
+		if grant.MatchField == "" || len(grant.MatchValues) == 0 {
+			return invalid("matchField và matchValues phải đi cùng nhau")
+		}
+		if strings.Contains(grant.MatchField, "$") || grant.MatchField == "ownerOrganizationId" {
+			return invalid("matchField không hợp lệ")
+		}
+	}
+	if grant.Access != models.RecordShareAccessRead && grant.Access != models.RecordShareAccessReadWrite {
+		return invalid("access phải là read hoặc read_write")
+	}
+	if grant.ExpiresAt != 0 && grant.ExpiresAt <= now.UnixMilli() {
+		return invalid("expiresAt phải ở tương lai")
+	}
+	return nil
+}
+
+// RecordShareGrantFilter filter Mongo chọn đúng các bản ghi của grant (luôn giới hạn trong tổ chức chia sẻ).
+func RecordShareGrantFilter(grant models.RecordShareGrant) bson.M {
+	filter := bson.M{"ownerOrganizationId": grant.OwnerOrganizationID}
+	if len(grant.RecordIDs) > 0 {
+		filter["_id"] = bson.M{"$in": grant.RecordIDs}
+	} else {
+		filter[grant.MatchField] = bson.M{"$in": grant.MatchValues}
+	}
+	return filter
+}
+
+// IsWritePermission permission ghi (khác *.Read) → chỉ grant read_write được áp.
+func IsWritePermission(permissionName string) bool {
+	return permissionName != "" && !strings.HasSuffix(permissionName, ".Read")
+}
+
+// GetActiveRecordShareGrants grant còn hiệu lực trên collection cho user hoặc một trong granteeOrgIDs.
+// write = true chỉ lấy grant read_write. Collection không có grant nào → trả rỗng mà không truy vấn grant.
+func GetActiveRecordShareGrants(ctx context.Context, collection string, granteeOrgIDs []primitive.ObjectID, userID primitive.ObjectID, write bool) ([]models.RecordShareGrant, error) {
+	s, err := NewRecordShareService()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UnixMilli()
+	if !s.collectionHasGrants(ctx, collection, now) {
+		return nil, nil
+	}
+
+	grantees := []bson.M{}
+	if len(granteeOrgIDs) > 0 {
+		grantees = append(grantees, bson.M{"granteeOrganizationId": bson.M{"$in": granteeOrgIDs}})
+	}
+	if !userID.IsZero() {
+		grantees = append(grantees, bson.M{"granteeUserId": userID})
+	}
+	if len(grantees) == 0 {
+		return nil, nil
+	}
+	filter := bson.M{
+		"collection": collection,
+		"revokedAt":  0,
+		"$and": []bson.M{
+			{"$or": grantees},
+			{"$or": []bson.M{{"expiresAt": 0}, {"expiresAt": bson.M{"$gt": now}}}},
+		},
+	}
+	if write {
+		filter["access"] = models.RecordShareAccessReadWrite
+	}
+	grants, err := s.BaseServiceMongoImpl.Find(ctx, filter, nil)
+	if err != nil && err != common.ErrNotFound {
+		return nil, err
+	}
+	return grants, nil
+}
+
+// collectionHasGrants collection có ít nhất một grant còn hiệu lực (cache recordShareCollectionsTTL).
+func (s *RecordShareService) collectionHasGrants(ctx context.Context, collection string, now int64) bool {
+	cached, ok := recordShareCollectionsCache.Get("collections")
+	if !ok {
+		values, err := s.BaseServiceMongoImpl.Distinct(ctx, "collection", bson.M{
+			"revokedAt": 0,
+			"$or":       []bson.M{{"expiresAt": 0}, {"expiresAt": bson.M{"$gt": now}}},
+		})
+		if err != nil {
+			return true // Không rõ → vẫn tra grant, không bỏ sót quyền
+		}
+		set := make(map[string]bool, len(values))
+		for _, v := range values {
+			if name, ok := v.(string); ok {
+				set[name] = true
+			}
+		}
+		recordShareCollectionsCache.Set("collections", set)
+		cached = set
+	}
+	return cached.(map[string]bool)[collection]
+}
+
+// RecordShareAccessEntry một lần truy cập qua grant (RecordID rỗng với truy vấn danh sách).
+type RecordShareAccessEntry struct {
+	Grant      models.RecordShareGrant
+	RecordID   primitive.ObjectID
+	UserID     primitive.ObjectID
+	Permission string
+	Method     string
+	Path       string
+}
+
+// LogRecordShareAccess ghi nhật ký truy cập (bất đồng bộ — không làm chậm request; lỗi chỉ ghi log).
+func LogRecordShareAccess(entries ...RecordShareAccessEntry) {
+	if len(entries) == 0 {
+		return
+	}
+	go func() {
+		s, err := NewRecordShareService()
+		if err != nil {
+			return
+		}
+		now := time.Now()
+		for _, e := range entries {
+			_, err := s.accessService.InsertOne(context.Background(), models.RecordShareAccess{
+				GrantID:             e.Grant.ID,
+				Collection:          e.Grant.Collection,
+				RecordID:            e.RecordID,
+				UserID:              e.UserID,
+				Permission:          e.Permission,
+				Method:              e.Method,
+				Path:                e.Path,
+				OwnerOrganizationID: e.Grant.OwnerOrganizationID,
+				AccessedAt:          now.UnixMilli(),
+				ExpiresAt:           now.Add(recordShareAccessRetention),
+			})
+			if err != nil {
+				logger.GetAppLogger().WithError(err).WithField("grant_id", e.Grant.ID.Hex()).Warn("⚠️ [RECORD_SHARE] Không ghi được nhật ký truy cập")
+			}
+		}
+	}()
+}
@@ -0,0 +1,84 @@
+package authsvc
+
+import (
+	"testing"
+	"time"
+
+	models "meta_commerce/internal/api/auth/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func validRecordShareGrant() models.RecordShareGrant {
+	return models.RecordShareGrant{
+		Collection:            "meta_src_campaigns",
+		MatchField:            "adAccountId",
+		MatchValues:           []interface{}{"act_123"},
+		GranteeOrganizationID: primitive.NewObjectID(),
+		Access:                models.RecordShareAccessRead,
+		OwnerOrganizationID:   primitive.NewObjectID(),
+	}
+}
+
+func TestValidateRecordShareGrant(t *testing.T) {
+	now := time.Now()
+	if err := ValidateRecordShareGrant(validRecordShareGrant(), now); err != nil {
+		t.Fatalf("grant hợp lệ bị từ chối: %v", err)
+	}
+
+	cases := map[string]func(*models.RecordShareGrant){
+		"cả org lẫn user nhận": func(g *models.RecordShareGrant) { g.GranteeUserID = primitive.NewObjectID() },
+		"thiếu bên nhận":       func(g *models.RecordShareGrant) { g.GranteeOrganizationID = primitive.NilObjectID },
+		"chia sẻ cho chính mình": func(g *models.RecordShareGrant) {
+			g.GranteeOrganizationID = g.OwnerOrganizationID
+		},
+		"cả recordIds lẫn match": func(g *models.RecordShareGrant) { g.RecordIDs = []primitive.ObjectID{primitive.NewObjectID()} },
+		"match thiếu giá trị":    func(g *models.RecordShareGrant) { g.MatchValues = nil },
+		"matchField có $":        func(g *models.RecordShareGrant) { g.MatchField = "$where" },
+		"match ownerOrganizationId": func(g *models.RecordShareGrant) {
+			g.MatchField = "ownerOrganizationId"
+		},
+		"access sai":       func(g *models.RecordShareGrant) { g.Access = "write" },
+		"đã hết hạn":       func(g *models.RecordShareGrant) { g.ExpiresAt = now.Add(-time.Minute).UnixMilli() },
+		"thiếu collection": func(g *models.RecordShareGrant) { g.Collection = "" },
+	}
+	for name, mutate := range cases {
+		grant := validRecordShareGrant()
+		mutate(&grant)
+		if err := ValidateRecordShareGrant(grant, now); err == nil {
+			t.Errorf("%s: phải lỗi", name)
+		}
+	}
+}
+
+func TestRecordShareGrantFilter_ScopedToOwnerOrganization(t *testing.T) {
+	grant := validRecordShareGrant()
+	filter := RecordShareGrantFilter(grant)
+	if filter["ownerOrganizationId"] != grant.OwnerOrganizationID {
+		t.Fatalf("filter phải giới hạn trong tổ chức chia sẻ: %v", filter)
+	}
+	if _, ok := filter["adAccountId"].(bson.M); !ok {
+		t.Fatalf("filter phải chọn theo matchField: %v", filter)
+	}
+
+	ids := []primitive.ObjectID{primitive.NewObjectID()}
+	grant.MatchField, grant.MatchValues, grant.RecordIDs = "", nil, ids
+	filter = RecordShareGrantFilter(grant)
+	if in, ok := filter["_id"].(bson.M); !ok || len(in["$in"].([]primitive.ObjectID)) != 1 {
+		t.Fatalf("filter phải chọn theo recordIds: %v", filter)
+	}
+}
+
+func TestIsWritePermission(t *testing.T) {
+	for name, want := range map[string]bool{
+		"MetaCampaign.Read":     false,
+		"MetaCampaign.Update":   true,
+		"FbConversation.Delete": true,
+		"":                      false,
+	} {
+		if got := IsWritePermission(name); got != want {
+			t.Errorf("IsWritePermission(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
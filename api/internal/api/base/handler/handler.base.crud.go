@@ -164,6 +164,9 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) FindOne(c fiber.Ctx) error {
 		}
 
 		data, err := h.BaseService.FindOne(c.Context(), filter, options.(*mongoopts.FindOneOptions))
+		if err == nil {
+			h.logRecordShareRows(c, data)
+		}
 		h.HandleResponse(c, data, err)
 		return nil
 	})
@@ -310,6 +313,9 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) FindWithPagination(c fiber.Ct
 		findOptions := options.(*mongoopts.FindOptions)
 
 		data, err := h.BaseService.FindWithPagination(c.Context(), filter, page, limit, findOptions)
+		if err == nil && data != nil {
+			h.logRecordShareRows(c, data.Items...)
+		}
 		h.HandleResponse(c, data, err)
 		return nil
 	})
@@ -358,6 +364,7 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) Find(c fiber.Ctx) error {
 		if data == nil {
 			data = []T{}
 		}
+		h.logRecordShareRows(c, data...)
 
 		h.HandleResponse(c, data, nil)
 		return nil
@@ -448,10 +455,18 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) UpdateOne(c fiber.Ctx) error
 			}
 		}
 
+		// Filter có bản ghi được chia sẻ → không đổi tổ chức sở hữu (grantee không chiếm bản ghi)
+		if h.recordShareInFilter(c) {
+			delete(updateData.Set, "ownerOrganizationId")
+		}
+
 		// Tạo update data với $set operator
 		update := updateData
 
 		data, err := h.BaseService.UpdateOne(c.Context(), filter, update, nil)
+		if err == nil {
+			h.logRecordShareRows(c, data)
+		}
 		h.HandleResponse(c, data, err)
 		return nil
 	})
@@ -519,6 +534,11 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) UpdateMany(c fiber.Ctx) error
 			}
 		}
 
+		if h.recordShareInFilter(c) {
+			delete(updateData.Set, "ownerOrganizationId")
+			h.logRecordShareMatches(c, filter)
+		}
+
 		count, err := h.BaseService.UpdateMany(c.Context(), filter, updateData, nil)
 		h.HandleResponse(c, count, err)
 		return nil
@@ -558,7 +578,8 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) UpdateById(c fiber.Ctx) error
 		}
 
 		// ✅ Validate quyền với document hiện tại trước khi update
-		if err := h.ValidateOrganizationAccess(c, id); err != nil {
+		viaShare, err := h.organizationAccess(c, id)
+		if err != nil {
 			h.HandleResponse(c, nil, err)
 			return nil
 		}
@@ -590,17 +611,20 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) UpdateById(c fiber.Ctx) error
 			return nil
 		}
 
-		// Xử lý ownerOrganizationId: validate quyền và gán từ context nếu cần
-		ownerOrgIDFromModel := h.GetOwnerOrganizationIDFromModel(model)
-		if ownerOrgIDFromModel != nil && !ownerOrgIDFromModel.IsZero() {
-			if err := h.ValidateUserHasAccessToOrg(c, *ownerOrgIDFromModel); err != nil {
-				h.HandleResponse(c, nil, err)
-				return nil
-			}
-		} else if h.hasOrganizationIDField() {
-			activeOrgID := h.GetActiveOrganizationID(c)
-			if activeOrgID != nil && !activeOrgID.IsZero() {
-				h.SetOrganizationID(model, *activeOrgID)
+		// Xử lý ownerOrganizationId: validate quyền và gán từ context nếu cần.
+		// Quyền đến từ RecordShareGrant → bỏ qua: tổ chức sở hữu giữ nguyên (xóa khỏi $set bên dưới).
+		if !viaShare {
+			ownerOrgIDFromModel := h.GetOwnerOrganizationIDFromModel(model)
+			if ownerOrgIDFromModel != nil && !ownerOrgIDFromModel.IsZero() {
+				if err := h.ValidateUserHasAccessToOrg(c, *ownerOrgIDFromModel); err != nil {
+					h.HandleResponse(c, nil, err)
+					return nil
+				}
+			} else if h.hasOrganizationIDField() {
+				activeOrgID := h.GetActiveOrganizationID(c)
+				if activeOrgID != nil && !activeOrgID.IsZero() {
+					h.SetOrganizationID(model, *activeOrgID)
+				}
 			}
 		}
 
@@ -622,6 +646,9 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) UpdateById(c fiber.Ctx) error
 				updateData.Set[k] = v
 			}
 		}
+		if viaShare {
+			delete(updateData.Set, "ownerOrganizationId")
+		}
 
 		ctx := c.Context()
 		if userIDStr, ok := c.Locals("user_id").(string); ok && userIDStr != "" {
@@ -676,6 +703,7 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) DeleteMany(c fiber.Ctx) error
 
 		// ✅ Tự động thêm filter ownerOrganizationId nếu model có field OwnerOrganizationID (phân quyền dữ liệu)
 		filter = h.applyOrganizationFilter(c, filter)
+		h.logRecordShareMatches(c, filter)
 
 		count, err := h.BaseService.DeleteMany(c.Context(), filter)
 		h.HandleResponse(c, count, err)
@@ -790,7 +818,14 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) FindOneAndUpdate(c fiber.Ctx)
 			}
 		}
 
+		if h.recordShareInFilter(c) {
+			delete(updateData.Set, "ownerOrganizationId")
+		}
+
 		data, err := h.BaseService.FindOneAndUpdate(c.Context(), filter, updateData, nil)
+		if err == nil {
+			h.logRecordShareRows(c, data)
+		}
 		h.HandleResponse(c, data, err)
 		return nil
 	})
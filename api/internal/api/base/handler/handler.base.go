@@ -220,7 +220,7 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) ValidateUserHasAccessToOrg(c
 	}
 	permissionName := h.getPermissionNameFromRoute(c)
 
-	allowedOrgIDs, err := authsvc.GetAllowedOrganizationIDsFromRole(c.Context(), activeRoleID, permissionName)
+	allowedOrgIDs, err := allowedOrgIDsFromRole(c.Context(), activeRoleID, permissionName)
 	if err != nil {
 		return err
 	}
@@ -248,6 +248,11 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) ValidateUserHasAccessToOrg(c
 	)
 }
 
+// ApplyOrganizationFilter wrapper để handler domain (action ngoài CRUD, vd. replay dead-letter) giới hạn filter theo org được phép.
+func (h *BaseHandler[T, CreateInput, UpdateInput]) ApplyOrganizationFilter(c fiber.Ctx, baseFilter bson.M) bson.M {
+	return h.applyOrganizationFilter(c, baseFilter)
+}
+
 // applyOrganizationFilter tự động thêm filter ownerOrganizationId
 // CHỈ áp dụng nếu model có field OwnerOrganizationID (phân quyền dữ liệu)
 func (h *BaseHandler[T, CreateInput, UpdateInput]) applyOrganizationFilter(c fiber.Ctx, baseFilter bson.M) bson.M {
@@ -270,13 +275,13 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) applyOrganizationFilter(c fib
 	permissionName := h.getPermissionNameFromRoute(c)
 
 	// Lấy allowed organization IDs từ active role (đơn giản hơn, chỉ từ role context)
-	allowedOrgIDs, err := authsvc.GetAllowedOrganizationIDsFromRole(c.Context(), activeRoleID, permissionName)
+	allowedOrgIDs, err := allowedOrgIDsFromRole(c.Context(), activeRoleID, permissionName)
 	if err != nil || len(allowedOrgIDs) == 0 {
 		return baseFilter
 	}
 
 	// Lấy organizations được share với user's organizations
-	sharedOrgIDs, err := authsvc.GetSharedOrganizationIDs(c.Context(), allowedOrgIDs, permissionName)
+	sharedOrgIDs, err := sharedOrganizationIDs(c.Context(), allowedOrgIDs, permissionName)
 	if err == nil && len(sharedOrgIDs) > 0 {
 		// Hợp nhất allowedOrgIDs và sharedOrgIDs
 		allOrgIDsMap := make(map[primitive.ObjectID]bool)
@@ -298,6 +303,11 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) applyOrganizationFilter(c fib
 	// Thêm filter ownerOrganizationId (phân quyền dữ liệu)
 	orgFilter := bson.M{"ownerOrganizationId": bson.M{"$in": allowedOrgIDs}}
 
+	// Bản ghi được chia sẻ riêng (RecordShareGrant) cho user / tổ chức của user
+	if shareFilters := h.recordShareFilters(c, allowedOrgIDs, permissionName); len(shareFilters) > 0 {
+		orgFilter = bson.M{"$or": append([]bson.M{orgFilter}, shareFilters...)}
+	}
+
 	// Kết hợp với baseFilter
 	if len(baseFilter) == 0 {
 		return orgFilter
@@ -314,55 +324,67 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) applyOrganizationFilter(c fib
 // ValidateOrganizationAccess validate user có quyền truy cập document này không (export để domain handler gọi).
 // CHỈ validate nếu model có field OwnerOrganizationID (phân quyền dữ liệu)
 func (h *BaseHandler[T, CreateInput, UpdateInput]) ValidateOrganizationAccess(c fiber.Ctx, documentID string) error {
+	_, err := h.organizationAccess(c, documentID)
+	return err
+}
+
+// organizationAccess như ValidateOrganizationAccess, kèm viaShare = true khi quyền chỉ đến từ RecordShareGrant
+// (document thuộc tổ chức khác) — thao tác ghi khi đó không được đổi ownerOrganizationId.
+func (h *BaseHandler[T, CreateInput, UpdateInput]) organizationAccess(c fiber.Ctx, documentID string) (viaShare bool, err error) {
 	// ✅ QUAN TRỌNG: Kiểm tra model có field OwnerOrganizationID không
 	if !h.hasOrganizationIDField() {
-		return nil // Model không có OwnerOrganizationID, không cần validate
+		return false, nil // Model không có OwnerOrganizationID, không cần validate
 	}
 
 	// Lấy document
 	id, err := primitive.ObjectIDFromHex(documentID)
 	if err != nil {
-		return common.NewError(common.ErrCodeValidationInput, "ID không hợp lệ", common.StatusBadRequest, err)
+		return false, common.NewError(common.ErrCodeValidationInput, "ID không hợp lệ", common.StatusBadRequest, err)
 	}
 
 	doc, err := h.BaseService.FindOneById(c.Context(), id)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	// Lấy organizationId từ document (dùng reflection)
 	docOrgID := h.getOrganizationIDFromModel(doc)
 	if docOrgID == nil {
-		return nil // Không có organizationId, không cần validate
+		return false, nil // Không có organizationId, không cần validate
 	}
 
 	// Lấy active role ID từ context (đã được middleware set)
 	activeRoleIDStr, ok := c.Locals("active_role_id").(string)
 	if !ok || activeRoleIDStr == "" {
-		return common.NewError(common.ErrCodeAuthRole, "Không có role context", common.StatusUnauthorized, nil)
+		return false, common.NewError(common.ErrCodeAuthRole, "Không có role context", common.StatusUnauthorized, nil)
 	}
 	activeRoleID, err := primitive.ObjectIDFromHex(activeRoleIDStr)
 	if err != nil {
-		return common.NewError(common.ErrCodeAuthRole, "Role ID không hợp lệ", common.StatusUnauthorized, err)
+		return false, common.NewError(common.ErrCodeAuthRole, "Role ID không hợp lệ", common.StatusUnauthorized, err)
 	}
 
 	// Lấy permission name từ context (đã được middleware set)
 	permissionName := h.getPermissionNameFromRoute(c)
 
 	// Lấy allowed organization IDs từ active role (đơn giản hơn, chỉ từ role context)
-	allowedOrgIDs, err := authsvc.GetAllowedOrganizationIDsFromRole(c.Context(), activeRoleID, permissionName)
+	allowedOrgIDs, err := allowedOrgIDsFromRole(c.Context(), activeRoleID, permissionName)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	// Kiểm tra document có thuộc allowed organizations không
 	for _, allowedOrgID := range allowedOrgIDs {
 		if allowedOrgID == *docOrgID {
-			return nil // Có quyền truy cập
+			return false, nil // Có quyền truy cập
 		}
 	}
 
-	return common.NewError(common.ErrCodeAuthRole, "Không có quyền truy cập", common.StatusForbidden, nil)
+	// Document được chia sẻ riêng cho user / tổ chức của user (RecordShareGrant)
+	if h.hasRecordShareAccess(c, id, allowedOrgIDs, permissionName) {
+		return true, nil
+	}
+
+	return false, common.NewError(common.ErrCodeAuthRole, "Không có quyền truy cập", common.StatusForbidden, nil)
 }
 
 // FilterOptions cấu hình cho việc validate filter
@@ -0,0 +1,180 @@
+package basehdl
+
+import (
+	"reflect"
+
+	authmodels "meta_commerce/internal/api/auth/models"
+	authsvc "meta_commerce/internal/api/auth/service"
+
+	"github.com/gofiber/fiber/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Nguồn phân quyền dùng trong base handler — biến gói để test thay bằng bản giả (không cần Mongo).
+var (
+	allowedOrgIDsFromRole   = authsvc.GetAllowedOrganizationIDsFromRole
+	sharedOrganizationIDs   = authsvc.GetSharedOrganizationIDs
+	activeRecordShareGrants = authsvc.GetActiveRecordShareGrants
+	logRecordShareAccess    = authsvc.LogRecordShareAccess
+)
+
+// localsRecordShare key Locals giữ grant đã OR vào filter của request (applyOrganizationFilter).
+const localsRecordShare = "record_share_applied"
+
+// recordShareApplied grant được áp vào filter + org sở hữu của role (bản ghi ngoài các org này chỉ đến qua grant).
+type recordShareApplied struct {
+	grants        []authmodels.RecordShareGrant
+	allowedOrgIDs []primitive.ObjectID
+}
+
+// collectionName tên collection của BaseService (service nhúng *BaseServiceMongoImpl đều có Collection()).
+func (h *BaseHandler[T, CreateInput, UpdateInput]) collectionName() string {
+	if svc, ok := h.BaseService.(interface{ Collection() *mongo.Collection }); ok && svc.Collection() != nil {
+		return svc.Collection().Name()
+	}
+	return ""
+}
+
+// recordShareGrants grant chia sẻ bản ghi (RecordShareGrant) áp cho request: user hiện tại hoặc tổ chức
+// của role đang làm việc là bên nhận; permission ghi chỉ lấy grant read_write.
+func (h *BaseHandler[T, CreateInput, UpdateInput]) recordShareGrants(c fiber.Ctx, granteeOrgIDs []primitive.ObjectID, permissionName string) []authmodels.RecordShareGrant {
+	collection := h.collectionName()
+	if collection == "" {
+		return nil
+	}
+	userID, _ := primitive.ObjectIDFromHex(stringLocal(c, "user_id"))
+	grants, err := activeRecordShareGrants(c.Context(), collection, granteeOrgIDs, userID, authsvc.IsWritePermission(permissionName))
+	if err != nil {
+		return nil
+	}
+	return grants
+}
+
+// recordShareFilters filter bản ghi được chia sẻ để OR với filter ownerOrganizationId. Grant được lưu vào Locals —
+// nhật ký chỉ ghi cho bản ghi thực sự đến qua grant (logRecordShareRows / logRecordShareMatches).
+func (h *BaseHandler[T, CreateInput, UpdateInput]) recordShareFilters(c fiber.Ctx, granteeOrgIDs []primitive.ObjectID, permissionName string) []bson.M {
+	grants := h.recordShareGrants(c, granteeOrgIDs, permissionName)
+	if len(grants) == 0 {
+		return nil
+	}
+	filters := make([]bson.M, 0, len(grants))
+	for _, grant := range grants {
+		filters = append(filters, authsvc.RecordShareGrantFilter(grant))
+	}
+	c.Locals(localsRecordShare, &recordShareApplied{grants: grants, allowedOrgIDs: granteeOrgIDs})
+	return filters
+}
+
+// hasRecordShareAccess document id nằm trong một grant còn hiệu lực cho request hiện tại (ghi nhật ký nếu có).
+func (h *BaseHandler[T, CreateInput, UpdateInput]) hasRecordShareAccess(c fiber.Ctx, id primitive.ObjectID, granteeOrgIDs []primitive.ObjectID, permissionName string) bool {
+	for _, grant := range h.recordShareGrants(c, granteeOrgIDs, permissionName) {
+		filter := authsvc.RecordShareGrantFilter(grant)
+		filter = bson.M{"$and": []bson.M{{"_id": id}, filter}}
+		if exists, err := h.BaseService.DocumentExists(c.Context(), filter); err == nil && exists {
+			logRecordShareAccess(h.recordShareAccessEntry(c, grant, id, permissionName))
+			return true
+		}
+	}
+	return false
+}
+
+// recordShareInFilter filter của request có OR grant chia sẻ — thao tác ghi theo filter không được đổi
+// ownerOrganizationId (bản ghi khớp có thể thuộc tổ chức chia sẻ).
+func (h *BaseHandler[T, CreateInput, UpdateInput]) recordShareInFilter(c fiber.Ctx) bool {
+	applied, _ := c.Locals(localsRecordShare).(*recordShareApplied)
+	return applied != nil
+}
+
+// logRecordShareRows ghi nhật ký cho các bản ghi trả về nằm ngoài org của role (chỉ có thể đến qua grant).
+func (h *BaseHandler[T, CreateInput, UpdateInput]) logRecordShareRows(c fiber.Ctx, rows ...T) {
+	applied, _ := c.Locals(localsRecordShare).(*recordShareApplied)
+	if applied == nil || len(rows) == 0 {
+		return
+	}
+	owned := make(map[primitive.ObjectID]bool, len(applied.allowedOrgIDs))
+	for _, id := range applied.allowedOrgIDs {
+		owned[id] = true
+	}
+	var ids []primitive.ObjectID
+	for i := range rows {
+		orgID := h.getOrganizationIDFromModel(rows[i])
+		if orgID == nil || owned[*orgID] {
+			continue
+		}
+		if id := modelObjectID(rows[i]); !id.IsZero() {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	h.logRecordShareMatches(c, bson.M{"_id": bson.M{"$in": ids}})
+}
+
+// logRecordShareMatches ghi nhật ký một dòng cho mỗi bản ghi khớp filter đến qua grant (trước thao tác ghi theo filter,
+// hoặc sau khi đọc với filter _id các dòng trả về). Không có grant trong request → không truy vấn.
+func (h *BaseHandler[T, CreateInput, UpdateInput]) logRecordShareMatches(c fiber.Ctx, filter bson.M) {
+	applied, _ := c.Locals(localsRecordShare).(*recordShareApplied)
+	if applied == nil {
+		return
+	}
+	permissionName := h.getPermissionNameFromRoute(c)
+	notOwned := bson.M{"ownerOrganizationId": bson.M{"$nin": applied.allowedOrgIDs}}
+	seen := make(map[primitive.ObjectID]bool)
+	var entries []authsvc.RecordShareAccessEntry
+	for _, grant := range applied.grants {
+		matchFilter := bson.M{"$and": []bson.M{filter, notOwned, authsvc.RecordShareGrantFilter(grant)}}
+		values, err := h.BaseService.Distinct(c.Context(), "_id", matchFilter)
+		if err != nil {
+			continue
+		}
+		for _, v := range values {
+			id, ok := v.(primitive.ObjectID)
+			if !ok || seen[id] {
+				continue
+			}
+			seen[id] = true
+			entries = append(entries, h.recordShareAccessEntry(c, grant, id, permissionName))
+		}
+	}
+	logRecordShareAccess(entries...)
+}
+
+func (h *BaseHandler[T, CreateInput, UpdateInput]) recordShareAccessEntry(c fiber.Ctx, grant authmodels.RecordShareGrant, recordID primitive.ObjectID, permissionName string) authsvc.RecordShareAccessEntry {
+	userID, _ := primitive.ObjectIDFromHex(stringLocal(c, "user_id"))
+	return authsvc.RecordShareAccessEntry{
+		Grant:      grant,
+		RecordID:   recordID,
+		UserID:     userID,
+		Permission: permissionName,
+		Method:     c.Method(),
+		Path:       c.Path(),
+	}
+}
+
+// modelObjectID field ID (bson _id) của model; zero nếu không có.
+func modelObjectID(model interface{}) primitive.ObjectID {
+	val := reflect.ValueOf(model)
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return primitive.NilObjectID
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return primitive.NilObjectID
+	}
+	if f := val.FieldByName("ID"); f.IsValid() {
+		if id, ok := f.Interface().(primitive.ObjectID); ok {
+			return id
+		}
+	}
+	return primitive.NilObjectID
+}
+
+func stringLocal(c fiber.Ctx, key string) string {
+	s, _ := c.Locals(key).(string)
+	return s
+}
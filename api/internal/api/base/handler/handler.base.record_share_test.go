@@ -0,0 +1,207 @@
+package basehdl
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	authmodels "meta_commerce/internal/api/auth/models"
+	authsvc "meta_commerce/internal/api/auth/service"
+	basesvc "meta_commerce/internal/api/base/service"
+	"meta_commerce/internal/global"
+
+	"github.com/gofiber/fiber/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type shareTestModel struct {
+	ID                  primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	Name                string             `json:"name" bson:"name"`
+	OwnerOrganizationID primitive.ObjectID `json:"ownerOrganizationId" bson:"ownerOrganizationId"`
+}
+
+type shareTestUpdate struct {
+	Name                string `json:"name,omitempty"`
+	OwnerOrganizationID string `json:"ownerOrganizationId,omitempty" transform:"str_objectid,optional"`
+}
+
+// fakeShareService BaseServiceMongo giả: chỉ cài các hàm handler dùng, ghi lại update gửi xuống.
+type fakeShareService struct {
+	basesvc.BaseServiceMongo[shareTestModel]
+	coll       *mongo.Collection
+	docs       []shareTestModel
+	lastUpdate *basesvc.UpdateData
+}
+
+func (s *fakeShareService) Collection() *mongo.Collection { return s.coll }
+
+func (s *fakeShareService) FindOneById(_ context.Context, id primitive.ObjectID) (shareTestModel, error) {
+	for _, d := range s.docs {
+		if d.ID == id {
+			return d, nil
+		}
+	}
+	return shareTestModel{}, mongo.ErrNoDocuments
+}
+
+func (s *fakeShareService) DocumentExists(context.Context, interface{}) (bool, error) {
+	return true, nil
+}
+
+func (s *fakeShareService) UpdateById(_ context.Context, id primitive.ObjectID, data interface{}) (shareTestModel, error) {
+	s.lastUpdate = data.(*basesvc.UpdateData)
+	return s.FindOneById(context.Background(), id)
+}
+
+func (s *fakeShareService) Find(context.Context, interface{}, *options.FindOptions) ([]shareTestModel, error) {
+	return s.docs, nil
+}
+
+// Distinct trả _id của các doc khớp filter $in _id (đủ cho logRecordShareMatches trong test).
+func (s *fakeShareService) Distinct(_ context.Context, _ string, filter interface{}) ([]interface{}, error) {
+	and := filter.(bson.M)["$and"].([]bson.M)
+	ids := and[0]["_id"].(bson.M)["$in"].([]primitive.ObjectID)
+	out := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, id)
+	}
+	return out, nil
+}
+
+type shareTestEnv struct {
+	svc        *fakeShareService
+	app        *fiber.App
+	ownerOrg   primitive.ObjectID
+	granteeOrg primitive.ObjectID
+	logged     []authsvc.RecordShareAccessEntry
+}
+
+func newShareTestEnv(t *testing.T, docs []shareTestModel, grants []authmodels.RecordShareGrant) *shareTestEnv {
+	t.Helper()
+	global.InitValidator()
+	client, err := mongo.NewClient(options.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := &shareTestEnv{
+		svc: &fakeShareService{coll: client.Database("test").Collection("share_test_records"), docs: docs},
+	}
+	savedAllowed, savedShared, savedGrants, savedLog := allowedOrgIDsFromRole, sharedOrganizationIDs, activeRecordShareGrants, logRecordShareAccess
+	t.Cleanup(func() {
+		allowedOrgIDsFromRole, sharedOrganizationIDs, activeRecordShareGrants, logRecordShareAccess = savedAllowed, savedShared, savedGrants, savedLog
+	})
+	allowedOrgIDsFromRole = func(context.Context, primitive.ObjectID, string) ([]primitive.ObjectID, error) {
+		return []primitive.ObjectID{env.granteeOrg}, nil
+	}
+	sharedOrganizationIDs = func(context.Context, []primitive.ObjectID, string) ([]primitive.ObjectID, error) {
+		return nil, nil
+	}
+	activeRecordShareGrants = func(context.Context, string, []primitive.ObjectID, primitive.ObjectID, bool) ([]authmodels.RecordShareGrant, error) {
+		return grants, nil
+	}
+	logRecordShareAccess = func(entries ...authsvc.RecordShareAccessEntry) {
+		env.logged = append(env.logged, entries...)
+	}
+
+	h := NewBaseHandler[shareTestModel, shareTestUpdate, shareTestUpdate](env.svc)
+	env.app = fiber.New()
+	withLocals := func(permission string, next fiber.Handler) fiber.Handler {
+		return func(c fiber.Ctx) error {
+			c.Locals("active_role_id", primitive.NewObjectID().Hex())
+			c.Locals("user_id", primitive.NewObjectID().Hex())
+			c.Locals("active_organization_id", env.granteeOrg.Hex())
+			c.Locals("permission_name", permission)
+			return next(c)
+		}
+	}
+	env.app.Put("/records/:id", withLocals("Records.Update", h.UpdateById))
+	env.app.Get("/records", withLocals("Records.Read", h.Find))
+	return env
+}
+
+func TestUpdateById_recordShareKeepsOwner(t *testing.T) {
+	ownerOrg, granteeOrg := primitive.NewObjectID(), primitive.NewObjectID()
+	doc := shareTestModel{ID: primitive.NewObjectID(), Name: "old", OwnerOrganizationID: ownerOrg}
+	grant := authmodels.RecordShareGrant{
+		ID: primitive.NewObjectID(), Collection: "share_test_records", RecordIDs: []primitive.ObjectID{doc.ID},
+		GranteeOrganizationID: granteeOrg, Access: authmodels.RecordShareAccessReadWrite, OwnerOrganizationID: ownerOrg,
+	}
+	env := newShareTestEnv(t, []shareTestModel{doc}, []authmodels.RecordShareGrant{grant})
+	env.ownerOrg, env.granteeOrg = ownerOrg, granteeOrg
+
+	body := `{"name":"new","ownerOrganizationId":"` + granteeOrg.Hex() + `"}`
+	req := httptest.NewRequest("PUT", "/records/"+doc.ID.Hex(), strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := env.app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	if env.svc.lastUpdate == nil {
+		t.Fatal("UpdateById không được gọi")
+	}
+	if _, ok := env.svc.lastUpdate.Set["ownerOrganizationId"]; ok {
+		t.Fatalf("grantee không được đổi tổ chức sở hữu: %v", env.svc.lastUpdate.Set)
+	}
+	if env.svc.lastUpdate.Set["name"] != "new" {
+		t.Fatalf("field thường vẫn cập nhật: %v", env.svc.lastUpdate.Set)
+	}
+	if len(env.logged) != 1 || env.logged[0].RecordID != doc.ID {
+		t.Fatalf("ghi nhật ký truy cập qua grant: %+v", env.logged)
+	}
+}
+
+func TestUpdateById_ownRecordStillSetsOrg(t *testing.T) {
+	granteeOrg := primitive.NewObjectID()
+	doc := shareTestModel{ID: primitive.NewObjectID(), Name: "old", OwnerOrganizationID: granteeOrg}
+	env := newShareTestEnv(t, []shareTestModel{doc}, nil)
+	env.granteeOrg = granteeOrg
+
+	req := httptest.NewRequest("PUT", "/records/"+doc.ID.Hex(), strings.NewReader(`{"name":"new"}`))
+	req.Header.Set("Content-Type", "application/json")
+	if _, err := env.app.Test(req); err != nil {
+		t.Fatal(err)
+	}
+	if env.svc.lastUpdate == nil || env.svc.lastUpdate.Set["ownerOrganizationId"] != granteeOrg {
+		t.Fatalf("bản ghi của chính tổ chức giữ hành vi cũ: %+v", env.svc.lastUpdate)
+	}
+	if len(env.logged) != 0 {
+		t.Fatalf("không có grant → không ghi nhật ký: %+v", env.logged)
+	}
+}
+
+func TestFind_logsOnlySharedRows(t *testing.T) {
+	ownerOrg, granteeOrg := primitive.NewObjectID(), primitive.NewObjectID()
+	own := shareTestModel{ID: primitive.NewObjectID(), OwnerOrganizationID: granteeOrg}
+	shared := shareTestModel{ID: primitive.NewObjectID(), OwnerOrganizationID: ownerOrg}
+	grant := authmodels.RecordShareGrant{
+		ID: primitive.NewObjectID(), Collection: "share_test_records", RecordIDs: []primitive.ObjectID{shared.ID},
+		GranteeOrganizationID: granteeOrg, Access: authmodels.RecordShareAccessRead, OwnerOrganizationID: ownerOrg,
+	}
+
+	// Kết quả chỉ có bản ghi của chính tổ chức → không ghi nhật ký dù grant được áp vào filter
+	env := newShareTestEnv(t, []shareTestModel{own}, []authmodels.RecordShareGrant{grant})
+	env.granteeOrg = granteeOrg
+	if _, err := env.app.Test(httptest.NewRequest("GET", "/records", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if len(env.logged) != 0 {
+		t.Fatalf("không có dòng chia sẻ → không ghi nhật ký: %+v", env.logged)
+	}
+
+	// Có một dòng đến qua grant → đúng một bản ghi nhật ký cho dòng đó
+	env = newShareTestEnv(t, []shareTestModel{own, shared}, []authmodels.RecordShareGrant{grant})
+	env.granteeOrg = granteeOrg
+	if _, err := env.app.Test(httptest.NewRequest("GET", "/records", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if len(env.logged) != 1 || env.logged[0].RecordID != shared.ID || env.logged[0].Grant.ID != grant.ID {
+		t.Fatalf("chỉ ghi dòng chia sẻ: %+v", env.logged)
+	}
+}
@@ -0,0 +1,44 @@
+package basehdl
+
+import (
+	"net/url"
+	"strings"
+
+	"meta_commerce/internal/global"
+
+	"github.com/fasthttp/websocket"
+	"github.com/valyala/fasthttp"
+)
+
+// WSUpgrader upgrader WebSocket dùng chung cho mọi endpoint realtime (AI Decision live, hộp thư thông báo).
+var WSUpgrader = websocket.FastHTTPUpgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+	CheckOrigin:     checkWSOrigin,
+}
+
+// checkWSOrigin chấp nhận request không có Origin (client không phải trình duyệt), cùng origin với server,
+// hoặc origin liệt kê trong CORS_ORIGINS. CORS_ORIGINS="*" không mở WebSocket cho mọi origin — chỉ cùng origin.
+func checkWSOrigin(ctx *fasthttp.RequestCtx) bool {
+	origin := string(ctx.Request.Header.Peek("Origin"))
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, string(ctx.Host())) {
+		return true
+	}
+	if global.MongoDB_ServerConfig == nil {
+		return false
+	}
+	for _, allowed := range strings.Split(global.MongoDB_ServerConfig.CORS_Origins, ",") {
+		allowed = strings.TrimSpace(allowed)
+		if allowed != "" && allowed != "*" && strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
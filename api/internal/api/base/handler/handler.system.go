@@ -88,6 +88,28 @@ func (h *SystemHandler) HandleJobMetrics(c fiber.Ctx) error {
 	})
 }
 
+// HandleCacheMetrics trả về thống kê các cache in-process (hit/miss/size) — vd. Rule Engine definition/program cache.
+func (h *SystemHandler) HandleCacheMetrics(c fiber.Ctx) error {
+	data := metrics.GetAllCacheMetrics()
+	return c.Status(common.StatusOK).JSON(fiber.Map{
+		"code":    common.StatusOK,
+		"message": "Thành công",
+		"data":    data,
+		"status":  "success",
+	})
+}
+
+// HandleGaugeMetrics trả về các gauge hiện tại (vd. delivery_dlq_depth — số item dead-letter chưa xử lý).
+func (h *SystemHandler) HandleGaugeMetrics(c fiber.Ctx) error {
+	data := metrics.GetAllGauges()
+	return c.Status(common.StatusOK).JSON(fiber.Map{
+		"code":    common.StatusOK,
+		"message": "Thành công",
+		"data":    data,
+		"status":  "success",
+	})
+}
+
 // HandleGetWorkerConfig trả về cấu hình worker hiện tại (ngưỡng throttle + priorities + active + report schedules + state).
 // GET /api/v1/system/worker-config
 func (h *SystemHandler) HandleGetWorkerConfig(c fiber.Ctx) error {
@@ -24,7 +24,7 @@ func Register(v1 fiber.Router, r *apirouter.Router) error {
 	}
 
 	apirouter.RegisterRouteWithMiddleware(v1, "/cio/ingest", "POST", "",
-		[]fiber.Handler{middleware.AuthMiddleware(""), orgContextMiddleware},
+		[]fiber.Handler{middleware.DomainPermissionAuthMiddleware(), orgContextMiddleware},
 		ingestHandler.HandleIngest)
 
 	_ = r
@@ -152,6 +152,8 @@ func (s *CixAnalysisService) getCustomerContext(ctx context.Context, customerIdO
 		"valueTier":      profile.ValueTier,
 		"lifecycleStage": profile.LifecycleStage,
 		"journeyStage":   profile.JourneyStage,
+		"tags":           profile.Tags,         // Tag shop gắn (ctx.layers.cix_customer_context.tags)
+		"customFields":   profile.CustomFields, // Trường tùy chỉnh theo tổ chức (ctx.layers.cix_customer_context.customFields.<key>)
 	}
 }
 
@@ -87,7 +87,7 @@ func NewCixLLMServiceFromProfile(ctx context.Context, ownerOrgID primitive.Objec
 		}
 	}
 
-	if strings.TrimSpace(p.APIKey) == "" {
+	if strings.TrimSpace(p.APIKey.String()) == "" {
 		return nil
 	}
 
@@ -98,7 +98,7 @@ func NewCixLLMServiceFromProfile(ctx context.Context, ownerOrgID primitive.Objec
 		model = p.AvailableModels[0]
 	}
 
-	return newCixLLMServiceWithCreds(p.APIKey, model, p.BaseURL)
+	return newCixLLMServiceWithCreds(p.APIKey.String(), model, p.BaseURL)
 }
 
 // newCixLLMServiceWithCreds tạo service từ apiKey, model, baseURL (baseURL rỗng = dùng mặc định OpenAI).
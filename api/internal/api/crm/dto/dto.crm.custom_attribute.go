@@ -0,0 +1,55 @@
+// Package dto - DTO thuộc tính khách do tổ chức định nghĩa: trường tùy chỉnh và tag.
+package dto
+
+import (
+	crmmodels "meta_commerce/internal/api/crm/models"
+)
+
+// CrmCustomFieldCreateInput dữ liệu tạo trường tùy chỉnh. Type: string | number | boolean | date | enum | multi_enum.
+type CrmCustomFieldCreateInput struct {
+	Key         string   `json:"key" validate:"required"`
+	Label       string   `json:"label,omitempty"`
+	Type        string   `json:"type" validate:"required"`
+	Options     []string `json:"options,omitempty"` // Bắt buộc với enum / multi_enum
+	Required    bool     `json:"required,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+// CrmCustomFieldUpdateInput dữ liệu cập nhật trường tùy chỉnh — không đổi key, type.
+type CrmCustomFieldUpdateInput struct {
+	Label       string   `json:"label,omitempty"`
+	Options     []string `json:"options,omitempty"`
+	Required    *bool    `json:"required,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+// CrmCustomFieldValuesInput body PUT /crm-custom-fields/values/:unifiedId — chỉ các key gửi lên được ghi; null = xóa giá trị.
+type CrmCustomFieldValuesInput struct {
+	Values map[string]interface{} `json:"values"`
+}
+
+// CrmCustomerTagCreateInput dữ liệu tạo tag.
+type CrmCustomerTagCreateInput struct {
+	Name        string `json:"name" validate:"required"`
+	Color       string `json:"color,omitempty"` // #RRGGBB
+	Description string `json:"description,omitempty"`
+}
+
+// CrmCustomerTagUpdateInput dữ liệu cập nhật tag. Đổi tên → đổi theo trên mọi khách đang gắn tag.
+type CrmCustomerTagUpdateInput struct {
+	Name        string `json:"name,omitempty"`
+	Color       string `json:"color,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// CrmTagApplyInput body POST /crm-tags/apply — gắn / gỡ tag. Cần đúng một trong:
+// UnifiedIds (đồng bộ, tối đa 500 khách), SegmentID (thành viên hiện tại của phân khúc) hoặc Conditions (DSL phân khúc);
+// hai cách sau chạy bằng bulk job tag_apply.
+type CrmTagApplyInput struct {
+	Action     string                         `json:"action" validate:"required"` // add | remove
+	Tags       []string                       `json:"tags" validate:"required"`
+	UnifiedIds []string                       `json:"unifiedIds,omitempty"`
+	SegmentID  string                         `json:"segmentId,omitempty"`
+	Conditions *crmmodels.CrmSegmentCondition `json:"conditions,omitempty"`
+	IsPriority bool                           `json:"isPriority,omitempty"`
+}
@@ -51,6 +51,8 @@ type CrmCustomerProfileResponse struct {
 	Channel                   string             `json:"channel,omitempty"` // online | offline | omnichannel (rỗng nếu chưa mua)
 	LoyaltyStage              string             `json:"loyaltyStage,omitempty"`
 	MomentumStage             string             `json:"momentumStage,omitempty"`
+	Tags                      []string               `json:"tags,omitempty"`         // Tag shop gắn (danh mục crm-tags)
+	CustomFields              map[string]interface{} `json:"customFields,omitempty"` // Trường tùy chỉnh theo tổ chức (crm-custom-fields)
 	SourceIds                 map[string]interface{} `json:"sourceIds,omitempty"` // pos, fb, zalo (string); fbByPage, zaloByPage (map[string]string)
 	OwnerOrganizationId      primitive.ObjectID `json:"ownerOrganizationId,omitempty"`
 }
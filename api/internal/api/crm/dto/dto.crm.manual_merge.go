@@ -0,0 +1,33 @@
+// Package dto - DTO gộp/tách khách thủ công và hàng đợi nghi trùng.
+package dto
+
+// CrmCustomerMergeInput body POST /customers/:unifiedId/merge — khách MergeUnifiedId bị gộp vào :unifiedId.
+type CrmCustomerMergeInput struct {
+	MergeUnifiedId string `json:"mergeUnifiedId" validate:"required"`
+	CandidateId    string `json:"candidateId,omitempty"` // Cặp trong hàng đợi nghi trùng (tuỳ chọn)
+	Reason         string `json:"reason,omitempty"`
+}
+
+// CrmCustomerUnmergeInput body POST /customers/:unifiedId/unmerge — cần MergeHistoryId hoặc MergedUnifiedId.
+type CrmCustomerUnmergeInput struct {
+	MergeHistoryId  string `json:"mergeHistoryId,omitempty"`
+	MergedUnifiedId string `json:"mergedUnifiedId,omitempty"`
+}
+
+// CrmDuplicateScanInput body POST /crm-duplicate-candidates/scan.
+type CrmDuplicateScanInput struct {
+	Limit      int  `json:"limit,omitempty"` // 0 = quét toàn bộ khách của tổ chức
+	IsPriority bool `json:"isPriority,omitempty"`
+}
+
+// CrmDuplicateCandidateCreateInput DTO cho Insert — cặp nghi trùng do job quét tạo, dùng struct rỗng cho ReadOnly CRUD.
+type CrmDuplicateCandidateCreateInput struct{}
+
+// CrmDuplicateCandidateUpdateInput DTO cho Update — trạng thái đổi qua merge/dismiss, dùng struct rỗng cho ReadOnly CRUD.
+type CrmDuplicateCandidateUpdateInput struct{}
+
+// CrmMergeHistoryCreateInput DTO cho Insert — lịch sử ghi khi merge, dùng struct rỗng cho ReadOnly CRUD.
+type CrmMergeHistoryCreateInput struct{}
+
+// CrmMergeHistoryUpdateInput DTO cho Update — lịch sử cập nhật khi unmerge, dùng struct rỗng cho ReadOnly CRUD.
+type CrmMergeHistoryUpdateInput struct{}
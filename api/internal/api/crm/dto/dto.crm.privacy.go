@@ -0,0 +1,17 @@
+// Package dto - DTO yêu cầu export / xóa dữ liệu cá nhân của khách.
+package dto
+
+// CrmPrivacyRequestInput body POST /crm-privacy-requests/export, /erasure — nhập một trong unifiedId, phone, email.
+type CrmPrivacyRequestInput struct {
+	UnifiedId  string `json:"unifiedId,omitempty"`
+	Phone      string `json:"phone,omitempty"`
+	Email      string `json:"email,omitempty"`
+	Reason     string `json:"reason,omitempty"` // Bắt buộc với erasure (lưu vào bản ghi kiểm toán)
+	IsPriority bool   `json:"isPriority,omitempty"`
+}
+
+// CrmPrivacyRequestCreateInput DTO cho Insert — yêu cầu tạo qua /export, /erasure, dùng struct rỗng cho ReadOnly CRUD.
+type CrmPrivacyRequestCreateInput struct{}
+
+// CrmPrivacyRequestUpdateInput DTO cho Update — trạng thái do worker cập nhật, dùng struct rỗng cho ReadOnly CRUD.
+type CrmPrivacyRequestUpdateInput struct{}
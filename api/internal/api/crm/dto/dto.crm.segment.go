@@ -0,0 +1,41 @@
+// Package dto - DTO phân khúc khách động.
+package dto
+
+import (
+	crmmodels "meta_commerce/internal/api/crm/models"
+)
+
+// CrmSegmentCreateInput dữ liệu tạo phân khúc. Conditions theo DSL phân khúc (field/op/value, nhóm all/any).
+type CrmSegmentCreateInput struct {
+	Name        string                        `json:"name" validate:"required"`
+	Description string                        `json:"description,omitempty"`
+	Conditions  crmmodels.CrmSegmentCondition `json:"conditions"`
+	IsDisabled  bool                          `json:"isDisabled,omitempty"`
+}
+
+// CrmSegmentUpdateInput dữ liệu cập nhật phân khúc. Đổi conditions → thành viên được ghi lại từ đầu.
+// Bật/tắt qua POST /crm-segments/enable/:id, /disable/:id (update bỏ qua giá trị false).
+type CrmSegmentUpdateInput struct {
+	Name        string                        `json:"name,omitempty"`
+	Description string                        `json:"description,omitempty"`
+	Conditions  crmmodels.CrmSegmentCondition `json:"conditions,omitempty"`
+}
+
+// CrmSegmentRefreshInput body POST /crm-segments/refresh/:id.
+type CrmSegmentRefreshInput struct {
+	IsPriority bool `json:"isPriority,omitempty"`
+}
+
+// CrmSegmentMemberItem một thành viên phân khúc kèm thông tin khách để hiển thị.
+type CrmSegmentMemberItem struct {
+	UnifiedId      string   `json:"unifiedId"`
+	EnteredAt      int64    `json:"enteredAt"`
+	Name           string   `json:"name,omitempty"`
+	PhoneNumbers   []string `json:"phoneNumbers,omitempty"`
+	ValueTier      string   `json:"valueTier,omitempty"`
+	JourneyStage   string   `json:"journeyStage,omitempty"`
+	LifecycleStage string   `json:"lifecycleStage,omitempty"`
+	TotalSpent     float64  `json:"totalSpent"`
+	OrderCount     int      `json:"orderCount"`
+	LastOrderAt    int64    `json:"lastOrderAt,omitempty"`
+}
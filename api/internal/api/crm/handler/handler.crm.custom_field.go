@@ -0,0 +1,68 @@
+// Package crmhdl — Handler trường tùy chỉnh của khách: CRUD định nghĩa theo tổ chức và ghi giá trị trên khách.
+package crmhdl
+
+import (
+	"fmt"
+
+	basehdl "meta_commerce/internal/api/base/handler"
+	crmdto "meta_commerce/internal/api/crm/dto"
+	crmmodels "meta_commerce/internal/api/crm/models"
+	crmvc "meta_commerce/internal/api/crm/service"
+	"meta_commerce/internal/common"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// CrmCustomFieldHandler CRUD định nghĩa trường tùy chỉnh (customer_core_field_definitions) và ghi giá trị.
+type CrmCustomFieldHandler struct {
+	*basehdl.BaseHandler[crmmodels.CrmCustomFieldDefinition, crmdto.CrmCustomFieldCreateInput, crmdto.CrmCustomFieldUpdateInput]
+	CustomFieldService *crmvc.CrmCustomFieldService
+}
+
+// NewCrmCustomFieldHandler tạo CrmCustomFieldHandler mới.
+func NewCrmCustomFieldHandler() (*CrmCustomFieldHandler, error) {
+	svc, err := crmvc.NewCrmCustomFieldService()
+	if err != nil {
+		return nil, fmt.Errorf("tạo CrmCustomFieldService: %w", err)
+	}
+	hdl := &CrmCustomFieldHandler{
+		// Truyền svc (không phải BaseServiceMongoImpl) để InsertOne/UpdateById/DeleteById override được dùng.
+		BaseHandler:        basehdl.NewBaseHandler[crmmodels.CrmCustomFieldDefinition, crmdto.CrmCustomFieldCreateInput, crmdto.CrmCustomFieldUpdateInput](svc),
+		CustomFieldService: svc,
+	}
+	hdl.SetFilterOptions(basehdl.FilterOptions{
+		DeniedFields:     []string{},
+		AllowedOperators: []string{"$eq", "$ne", "$in", "$exists", "$regex"},
+		MaxFields:        10,
+	})
+	return hdl, nil
+}
+
+// HandleSetValues xử lý PUT /crm-custom-fields/values/:unifiedId — ghi giá trị trường tùy chỉnh của một khách
+// (kiểm tra kiểu, option, bắt buộc). Trả về profile khách sau khi ghi.
+func (h *CrmCustomFieldHandler) HandleSetValues(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		orgID := getActiveOrganizationID(c)
+		if orgID == nil || orgID.IsZero() {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationInput, "Vui lòng chọn tổ chức", common.StatusBadRequest, nil))
+			return nil
+		}
+		unifiedId := c.Params("unifiedId")
+		if unifiedId == "" {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationInput, "Thiếu unifiedId", common.StatusBadRequest, nil))
+			return nil
+		}
+		var input crmdto.CrmCustomFieldValuesInput
+		if err := h.ParseRequestBody(c, &input); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		customer, err := h.CustomFieldService.SetCustomerValues(c.Context(), *orgID, unifiedId, input.Values)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		h.HandleResponse(c, fiber.Map{"unifiedId": customer.UnifiedId, "customFields": customer.CustomFields}, nil)
+		return nil
+	})
+}
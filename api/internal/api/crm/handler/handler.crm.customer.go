@@ -48,10 +48,11 @@ func NewCrmCustomerHandler() (*CrmCustomerHandler, error) {
 		BulkJobService:  bulkJobSvc,
 		IntelRunService: intelRunSvc,
 	}
-	// Filter cho CRUD: cho phép filter theo classification và unifiedId (dashboard, bảng khách).
+	// Filter cho CRUD: cho phép filter theo classification và unifiedId (dashboard, bảng khách),
+	// tags ($in / $all / $nin) và customFields.<key>; sort theo customFields.<key> qua options.sort.
 	hdl.SetFilterOptions(basehdl.FilterOptions{
 		DeniedFields:     []string{},
-		AllowedOperators: []string{"$eq", "$in", "$gt", "$gte", "$lt", "$lte", "$exists", "$regex", "$or"},
+		AllowedOperators: []string{"$eq", "$ne", "$in", "$nin", "$all", "$gt", "$gte", "$lt", "$lte", "$exists", "$regex", "$or"},
 		MaxFields:        15,
 	})
 	return hdl, nil
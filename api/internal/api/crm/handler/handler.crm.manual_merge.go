@@ -0,0 +1,188 @@
+// Package crmhdl — Handler gộp/tách khách thủ công, lịch sử gộp và hàng đợi cặp nghi trùng.
+package crmhdl
+
+import (
+	"fmt"
+
+	basehdl "meta_commerce/internal/api/base/handler"
+	crmdto "meta_commerce/internal/api/crm/dto"
+	crmmodels "meta_commerce/internal/api/crm/models"
+	crmvc "meta_commerce/internal/api/crm/service"
+	"meta_commerce/internal/common"
+
+	"github.com/gofiber/fiber/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CrmMergeHistoryHandler CRUD đọc lịch sử gộp (customer_run_merge_history) và merge/unmerge khách.
+type CrmMergeHistoryHandler struct {
+	*basehdl.BaseHandler[crmmodels.CrmMergeHistory, crmdto.CrmMergeHistoryCreateInput, crmdto.CrmMergeHistoryUpdateInput]
+	MergeService *crmvc.CrmMergeHistoryService
+}
+
+// NewCrmMergeHistoryHandler tạo CrmMergeHistoryHandler mới.
+func NewCrmMergeHistoryHandler() (*CrmMergeHistoryHandler, error) {
+	svc, err := crmvc.NewCrmMergeHistoryService()
+	if err != nil {
+		return nil, fmt.Errorf("tạo CrmMergeHistoryService: %w", err)
+	}
+	hdl := &CrmMergeHistoryHandler{
+		BaseHandler:  basehdl.NewBaseHandler[crmmodels.CrmMergeHistory, crmdto.CrmMergeHistoryCreateInput, crmdto.CrmMergeHistoryUpdateInput](svc.BaseServiceMongoImpl),
+		MergeService: svc,
+	}
+	hdl.SetFilterOptions(basehdl.FilterOptions{
+		DeniedFields:     []string{},
+		AllowedOperators: []string{"$eq", "$ne", "$gt", "$gte", "$lt", "$lte", "$in", "$exists"},
+		MaxFields:        10,
+	})
+	return hdl, nil
+}
+
+// HandleMerge xử lý POST /customers/:unifiedId/merge — gộp khách mergeUnifiedId vào :unifiedId.
+func (h *CrmMergeHistoryHandler) HandleMerge(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		orgID, userID, err := mergeRequestContext(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		var input crmdto.CrmCustomerMergeInput
+		if err := h.ParseRequestBody(c, &input); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		mergeInput := crmvc.MergeCustomersInput{
+			SurvivorId: c.Params("unifiedId"),
+			MergedId:   input.MergeUnifiedId,
+			Reason:     input.Reason,
+			By:         userID,
+		}
+		if input.CandidateId != "" {
+			if mergeInput.CandidateID, err = primitive.ObjectIDFromHex(input.CandidateId); err != nil {
+				h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "candidateId không hợp lệ", common.StatusBadRequest, err))
+				return nil
+			}
+		}
+		history, err := h.MergeService.MergeCustomers(c.Context(), orgID, mergeInput)
+		h.HandleResponse(c, history, err)
+		return nil
+	})
+}
+
+// HandleUnmerge xử lý POST /customers/:unifiedId/unmerge — tách một lần gộp vào :unifiedId.
+func (h *CrmMergeHistoryHandler) HandleUnmerge(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		orgID, userID, err := mergeRequestContext(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		var input crmdto.CrmCustomerUnmergeInput
+		if err := h.ParseRequestBody(c, &input); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		var historyID primitive.ObjectID
+		if input.MergeHistoryId != "" {
+			if historyID, err = primitive.ObjectIDFromHex(input.MergeHistoryId); err != nil {
+				h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "mergeHistoryId không hợp lệ", common.StatusBadRequest, err))
+				return nil
+			}
+		}
+		history, err := h.MergeService.UnmergeCustomers(c.Context(), orgID, c.Params("unifiedId"), historyID, input.MergedUnifiedId, userID)
+		h.HandleResponse(c, history, err)
+		return nil
+	})
+}
+
+// CrmDuplicateCandidateHandler CRUD đọc hàng đợi cặp nghi trùng, quét và bỏ qua cặp.
+type CrmDuplicateCandidateHandler struct {
+	*basehdl.BaseHandler[crmmodels.CrmDuplicateCandidate, crmdto.CrmDuplicateCandidateCreateInput, crmdto.CrmDuplicateCandidateUpdateInput]
+	CandidateService *crmvc.CrmDuplicateCandidateService
+	BulkJobService   *crmvc.CrmBulkJobService
+}
+
+// NewCrmDuplicateCandidateHandler tạo CrmDuplicateCandidateHandler mới.
+func NewCrmDuplicateCandidateHandler() (*CrmDuplicateCandidateHandler, error) {
+	svc, err := crmvc.NewCrmDuplicateCandidateService()
+	if err != nil {
+		return nil, fmt.Errorf("tạo CrmDuplicateCandidateService: %w", err)
+	}
+	bulkJobSvc, err := crmvc.NewCrmBulkJobService()
+	if err != nil {
+		return nil, fmt.Errorf("tạo CrmBulkJobService: %w", err)
+	}
+	hdl := &CrmDuplicateCandidateHandler{
+		BaseHandler:      basehdl.NewBaseHandler[crmmodels.CrmDuplicateCandidate, crmdto.CrmDuplicateCandidateCreateInput, crmdto.CrmDuplicateCandidateUpdateInput](svc.BaseServiceMongoImpl),
+		CandidateService: svc,
+		BulkJobService:   bulkJobSvc,
+	}
+	hdl.SetFilterOptions(basehdl.FilterOptions{
+		DeniedFields:     []string{},
+		AllowedOperators: []string{"$eq", "$ne", "$gt", "$gte", "$lt", "$lte", "$in", "$exists"},
+		MaxFields:        10,
+	})
+	return hdl, nil
+}
+
+// HandleScan xử lý POST /crm-duplicate-candidates/scan — tạo bulk job quét cặp nghi trùng cho tổ chức đang chọn.
+func (h *CrmDuplicateCandidateHandler) HandleScan(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		orgID := getActiveOrganizationID(c)
+		if orgID == nil || orgID.IsZero() {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationInput, "Vui lòng chọn tổ chức", common.StatusBadRequest, nil))
+			return nil
+		}
+		var input crmdto.CrmDuplicateScanInput
+		if len(c.Body()) > 0 {
+			if err := h.ParseRequestBody(c, &input); err != nil {
+				h.HandleResponse(c, nil, err)
+				return nil
+			}
+		}
+		params := bson.M{}
+		if input.Limit > 0 {
+			params["limit"] = input.Limit
+		}
+		jobID, err := h.BulkJobService.Enqueue(c.Context(), crmmodels.CrmBulkJobDuplicateScan, *orgID, params, input.IsPriority)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		h.HandleResponse(c, fiber.Map{"jobId": jobID.Hex(), "jobType": crmmodels.CrmBulkJobDuplicateScan}, nil)
+		return nil
+	})
+}
+
+// HandleDismiss xử lý POST /crm-duplicate-candidates/dismiss/:id — đánh dấu cặp không trùng.
+func (h *CrmDuplicateCandidateHandler) HandleDismiss(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		orgID, userID, err := mergeRequestContext(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "ID không hợp lệ", common.StatusBadRequest, err))
+			return nil
+		}
+		err = h.CandidateService.Dismiss(c.Context(), orgID, id, userID)
+		h.HandleResponse(c, nil, err)
+		return nil
+	})
+}
+
+// mergeRequestContext tổ chức đang chọn và user thực hiện — bắt buộc cho thao tác duyệt/gộp.
+func mergeRequestContext(c fiber.Ctx) (primitive.ObjectID, primitive.ObjectID, error) {
+	orgID := getActiveOrganizationID(c)
+	if orgID == nil || orgID.IsZero() {
+		return primitive.NilObjectID, primitive.NilObjectID, common.NewError(common.ErrCodeValidationInput, "Vui lòng chọn tổ chức", common.StatusBadRequest, nil)
+	}
+	userID := getUserIDFromContext(c)
+	if userID == nil {
+		return primitive.NilObjectID, primitive.NilObjectID, common.NewError(common.ErrCodeAuthToken, "Chưa đăng nhập", common.StatusUnauthorized, nil)
+	}
+	return *orgID, *userID, nil
+}
@@ -0,0 +1,123 @@
+// Package crmhdl — Handler yêu cầu dữ liệu cá nhân của khách: tạo export / xóa, xem trạng thái, tải bản export.
+package crmhdl
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"time"
+
+	basehdl "meta_commerce/internal/api/base/handler"
+	crmdto "meta_commerce/internal/api/crm/dto"
+	crmmodels "meta_commerce/internal/api/crm/models"
+	crmvc "meta_commerce/internal/api/crm/service"
+	"meta_commerce/internal/common"
+	"meta_commerce/internal/logger"
+
+	"github.com/gofiber/fiber/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CrmPrivacyRequestHandler CRUD đọc yêu cầu (customer_run_privacy_requests) và tạo / tải export, xóa dữ liệu.
+type CrmPrivacyRequestHandler struct {
+	*basehdl.BaseHandler[crmmodels.CrmPrivacyRequest, crmdto.CrmPrivacyRequestCreateInput, crmdto.CrmPrivacyRequestUpdateInput]
+	PrivacyService *crmvc.CrmPrivacyRequestService
+}
+
+// NewCrmPrivacyRequestHandler tạo CrmPrivacyRequestHandler mới.
+func NewCrmPrivacyRequestHandler() (*CrmPrivacyRequestHandler, error) {
+	svc, err := crmvc.NewCrmPrivacyRequestService()
+	if err != nil {
+		return nil, fmt.Errorf("tạo CrmPrivacyRequestService: %w", err)
+	}
+	hdl := &CrmPrivacyRequestHandler{
+		BaseHandler:    basehdl.NewBaseHandler[crmmodels.CrmPrivacyRequest, crmdto.CrmPrivacyRequestCreateInput, crmdto.CrmPrivacyRequestUpdateInput](svc.BaseServiceMongoImpl),
+		PrivacyService: svc,
+	}
+	hdl.SetFilterOptions(basehdl.FilterOptions{
+		DeniedFields:     []string{},
+		AllowedOperators: []string{"$eq", "$ne", "$gt", "$gte", "$lt", "$lte", "$in", "$exists"},
+		MaxFields:        10,
+	})
+	return hdl, nil
+}
+
+// HandleCreateExport xử lý POST /crm-privacy-requests/export — tạo yêu cầu export và bulk job data_export.
+func (h *CrmPrivacyRequestHandler) HandleCreateExport(c fiber.Ctx) error {
+	return h.handleCreate(c, crmmodels.CrmPrivacyKindExport)
+}
+
+// HandleCreateErasure xử lý POST /crm-privacy-requests/erasure — tạo yêu cầu xóa và bulk job data_erasure.
+func (h *CrmPrivacyRequestHandler) HandleCreateErasure(c fiber.Ctx) error {
+	return h.handleCreate(c, crmmodels.CrmPrivacyKindErasure)
+}
+
+func (h *CrmPrivacyRequestHandler) handleCreate(c fiber.Ctx, kind string) error {
+	return h.SafeHandler(c, func() error {
+		orgID, userID, err := mergeRequestContext(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		var input crmdto.CrmPrivacyRequestInput
+		if err := h.ParseRequestBody(c, &input); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		req, err := h.PrivacyService.CreateRequest(c.Context(), orgID, crmvc.CreatePrivacyRequestInput{
+			Kind:        kind,
+			UnifiedId:   input.UnifiedId,
+			Phone:       input.Phone,
+			Email:       input.Email,
+			Reason:      input.Reason,
+			RequestedBy: userID,
+			IsPriority:  input.IsPriority,
+		})
+		h.HandleResponse(c, req, err)
+		return nil
+	})
+}
+
+// HandleDownload xử lý GET /crm-privacy-requests/download/:id — tải bản export. Query: format=zip (mặc định) | json
+func (h *CrmPrivacyRequestHandler) HandleDownload(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		orgID := getActiveOrganizationID(c)
+		if orgID == nil || orgID.IsZero() {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationInput, "Vui lòng chọn tổ chức", common.StatusBadRequest, nil))
+			return nil
+		}
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "ID không hợp lệ", common.StatusBadRequest, err))
+			return nil
+		}
+		format := c.Query("format", crmvc.ExportFormatZip)
+		if format != crmvc.ExportFormatZip && format != crmvc.ExportFormatJSON {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationInput, "format phải là zip hoặc json", common.StatusBadRequest, nil))
+			return nil
+		}
+		req, err := h.PrivacyService.FindRequest(c.Context(), *orgID, id)
+		if err == nil {
+			err = crmvc.CheckExportReady(req)
+		}
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		filename := fmt.Sprintf("customer-data-%s-%s.%s", req.ID.Hex(), time.Now().Format("20060102"), format)
+		if format == crmvc.ExportFormatJSON {
+			c.Set("Content-Type", "application/json; charset=utf-8")
+		} else {
+			c.Set("Content-Type", "application/zip")
+		}
+		c.Set("Content-Disposition", "attachment; filename=\""+filename+"\"")
+		return c.SendStreamWriter(func(bw *bufio.Writer) {
+			// Stream chạy sau khi handler trả về — không dùng context của request.
+			ctx := context.Background()
+			if err := h.PrivacyService.WriteExport(ctx, req, format, bw); err != nil {
+				logger.GetAppLogger().WithError(err).WithField("requestId", req.ID.Hex()).Error("[CRM] Tải bản export dữ liệu khách thất bại")
+			}
+			_ = bw.Flush()
+		})
+	})
+}
@@ -0,0 +1,240 @@
+// Package crmhdl — Handler phân khúc khách động: CRUD định nghĩa, bật/tắt, refresh, thành viên, export và lịch sử số thành viên.
+package crmhdl
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	basehdl "meta_commerce/internal/api/base/handler"
+	crmdto "meta_commerce/internal/api/crm/dto"
+	crmmodels "meta_commerce/internal/api/crm/models"
+	crmvc "meta_commerce/internal/api/crm/service"
+	"meta_commerce/internal/common"
+	"meta_commerce/internal/logger"
+
+	"github.com/gofiber/fiber/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CrmSegmentHandler CRUD phân khúc (customer_core_segments) và các thao tác trên thành viên.
+type CrmSegmentHandler struct {
+	*basehdl.BaseHandler[crmmodels.CrmSegment, crmdto.CrmSegmentCreateInput, crmdto.CrmSegmentUpdateInput]
+	SegmentService *crmvc.CrmSegmentService
+	BulkJobService *crmvc.CrmBulkJobService
+}
+
+// NewCrmSegmentHandler tạo CrmSegmentHandler mới.
+func NewCrmSegmentHandler() (*CrmSegmentHandler, error) {
+	svc, err := crmvc.NewCrmSegmentService()
+	if err != nil {
+		return nil, fmt.Errorf("tạo CrmSegmentService: %w", err)
+	}
+	bulkJobSvc, err := crmvc.NewCrmBulkJobService()
+	if err != nil {
+		return nil, fmt.Errorf("tạo CrmBulkJobService: %w", err)
+	}
+	hdl := &CrmSegmentHandler{
+		// Truyền svc (không phải BaseServiceMongoImpl) để InsertOne/UpdateById/DeleteById override được dùng.
+		BaseHandler:    basehdl.NewBaseHandler[crmmodels.CrmSegment, crmdto.CrmSegmentCreateInput, crmdto.CrmSegmentUpdateInput](svc),
+		SegmentService: svc,
+		BulkJobService: bulkJobSvc,
+	}
+	hdl.SetFilterOptions(basehdl.FilterOptions{
+		DeniedFields:     []string{},
+		AllowedOperators: []string{"$eq", "$ne", "$gt", "$gte", "$lt", "$lte", "$in", "$exists", "$regex"},
+		MaxFields:        10,
+	})
+	return hdl, nil
+}
+
+// segmentFromRequest tổ chức đang chọn và phân khúc :id thuộc tổ chức đó.
+func (h *CrmSegmentHandler) segmentFromRequest(c fiber.Ctx) (*crmmodels.CrmSegment, error) {
+	orgID := getActiveOrganizationID(c)
+	if orgID == nil || orgID.IsZero() {
+		return nil, common.NewError(common.ErrCodeValidationInput, "Vui lòng chọn tổ chức", common.StatusBadRequest, nil)
+	}
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, common.NewError(common.ErrCodeValidationFormat, "ID không hợp lệ", common.StatusBadRequest, err)
+	}
+	return h.SegmentService.FindSegment(c.Context(), *orgID, id)
+}
+
+// HandleRefresh xử lý POST /crm-segments/refresh/:id — tạo bulk job segment_refresh đánh giá lại toàn bộ khách.
+func (h *CrmSegmentHandler) HandleRefresh(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		seg, err := h.segmentFromRequest(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		if seg.IsDisabled {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeBusinessState, "Phân khúc đang tắt", common.StatusConflict, nil))
+			return nil
+		}
+		var input crmdto.CrmSegmentRefreshInput
+		if len(c.Body()) > 0 {
+			if err := h.ParseRequestBody(c, &input); err != nil {
+				h.HandleResponse(c, nil, err)
+				return nil
+			}
+		}
+		jobID, err := h.BulkJobService.Enqueue(c.Context(), crmmodels.CrmBulkJobSegmentRefresh, seg.OwnerOrganizationID, bson.M{"segmentId": seg.ID.Hex()}, input.IsPriority)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		h.HandleResponse(c, fiber.Map{"jobId": jobID.Hex(), "jobType": crmmodels.CrmBulkJobSegmentRefresh}, nil)
+		return nil
+	})
+}
+
+// HandleEnable xử lý POST /crm-segments/enable/:id — bật phân khúc và ghi lại thành viên từ đầu.
+func (h *CrmSegmentHandler) HandleEnable(c fiber.Ctx) error {
+	return h.handleSetDisabled(c, false)
+}
+
+// HandleDisable xử lý POST /crm-segments/disable/:id — tắt phân khúc (giữ thành viên hiện tại, ngừng đánh giá).
+func (h *CrmSegmentHandler) HandleDisable(c fiber.Ctx) error {
+	return h.handleSetDisabled(c, true)
+}
+
+func (h *CrmSegmentHandler) handleSetDisabled(c fiber.Ctx, disabled bool) error {
+	return h.SafeHandler(c, func() error {
+		seg, err := h.segmentFromRequest(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		updated, err := h.SegmentService.SetDisabled(c.Context(), seg.OwnerOrganizationID, seg.ID, disabled)
+		h.HandleResponse(c, updated, err)
+		return nil
+	})
+}
+
+// HandleListMembers xử lý GET /crm-segments/members/:id — thành viên phân khúc (mới vào trước), phân trang page/limit.
+func (h *CrmSegmentHandler) HandleListMembers(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		seg, err := h.segmentFromRequest(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		page, limit := int64(1), int64(50)
+		if n, err := strconv.ParseInt(c.Query("page"), 10, 64); err == nil && n > 0 {
+			page = n
+		}
+		if n, err := strconv.ParseInt(c.Query("limit"), 10, 64); err == nil && n > 0 {
+			limit = min(n, 500)
+		}
+		members, total, err := h.SegmentService.ListMembers(c.Context(), seg.ID, page, limit)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		customers, err := h.SegmentService.MemberCustomers(c.Context(), seg.OwnerOrganizationID, members)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		items := make([]crmdto.CrmSegmentMemberItem, 0, len(members))
+		for i := range members {
+			items = append(items, toSegmentMemberItem(&members[i], customers[members[i].UnifiedId]))
+		}
+		h.HandleResponse(c, fiber.Map{
+			"page": page, "limit": limit, "itemCount": len(items), "items": items, "total": total,
+			"totalPage": (total + limit - 1) / limit,
+		}, nil)
+		return nil
+	})
+}
+
+// HandleExportMembers xử lý GET /crm-segments/export/:id — tải CSV toàn bộ thành viên (UTF-8 BOM cho Excel).
+func (h *CrmSegmentHandler) HandleExportMembers(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		seg, err := h.segmentFromRequest(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		filename := fmt.Sprintf("segment-%s-%s.csv", seg.ID.Hex(), time.Now().Format("20060102"))
+		c.Set("Content-Type", "text/csv; charset=utf-8")
+		c.Set("Content-Disposition", "attachment; filename=\""+filename+"\"")
+		return c.SendStreamWriter(func(bw *bufio.Writer) {
+			// Stream chạy sau khi handler trả về — không dùng context của request.
+			ctx := context.Background()
+			bw.WriteString("\ufeff")
+			w := csv.NewWriter(bw)
+			_ = w.Write([]string{"unifiedId", "name", "phoneNumbers", "emails", "valueTier", "journeyStage", "lifecycleStage", "totalSpent", "orderCount", "lastOrderAt", "enteredAt"})
+			err := h.SegmentService.ForEachMemberCustomer(ctx, seg, func(m *crmmodels.CrmSegmentMember, cust *crmmodels.CrmCustomer) error {
+				item := toSegmentMemberItem(m, cust)
+				var emails []string
+				if cust != nil {
+					emails = cust.Profile.Emails
+				}
+				if err := w.Write([]string{
+					item.UnifiedId, item.Name, strings.Join(item.PhoneNumbers, " "), strings.Join(emails, " "),
+					item.ValueTier, item.JourneyStage, item.LifecycleStage,
+					strconv.FormatFloat(item.TotalSpent, 'f', -1, 64), strconv.Itoa(item.OrderCount),
+					formatSegmentExportTime(item.LastOrderAt), formatSegmentExportTime(item.EnteredAt),
+				}); err != nil {
+					return err
+				}
+				w.Flush()
+				return w.Error()
+			})
+			w.Flush()
+			if err != nil {
+				logger.GetAppLogger().WithError(err).WithField("segmentId", seg.ID.Hex()).Error("[CRM] Export thành viên phân khúc thất bại")
+			}
+		})
+	})
+}
+
+// HandleCountHistory xử lý GET /crm-segments/counts/:id — số thành viên theo ngày. Query: from, to (YYYY-MM-DD).
+func (h *CrmSegmentHandler) HandleCountHistory(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		seg, err := h.segmentFromRequest(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		counts, err := h.SegmentService.FindCountHistory(c.Context(), seg.ID, c.Query("from"), c.Query("to"))
+		h.HandleResponse(c, counts, err)
+		return nil
+	})
+}
+
+func toSegmentMemberItem(m *crmmodels.CrmSegmentMember, c *crmmodels.CrmCustomer) crmdto.CrmSegmentMemberItem {
+	item := crmdto.CrmSegmentMemberItem{UnifiedId: m.UnifiedId, EnteredAt: m.EnteredAt}
+	if c == nil {
+		return item
+	}
+	item.Name = c.Profile.Name
+	item.PhoneNumbers = c.Profile.PhoneNumbers
+	item.ValueTier = c.ValueTier
+	item.JourneyStage = c.JourneyStage
+	item.LifecycleStage = c.LifecycleStage
+	item.TotalSpent = crmvc.GetTotalSpentFromCustomer(c)
+	item.OrderCount = crmvc.GetOrderCountFromCustomer(c)
+	item.LastOrderAt = crmvc.GetLastOrderAtFromCustomer(c)
+	return item
+}
+
+// formatSegmentExportTime unix ms → RFC3339 giờ Việt Nam; 0 → rỗng.
+func formatSegmentExportTime(ms int64) string {
+	if ms <= 0 {
+		return ""
+	}
+	loc, err := time.LoadLocation("Asia/Ho_Chi_Minh")
+	if err != nil {
+		loc = time.FixedZone("ICT", 7*3600)
+	}
+	return time.UnixMilli(ms).In(loc).Format(time.RFC3339)
+}
@@ -0,0 +1,92 @@
+// Package crmhdl — Handler tag khách: CRUD danh mục tag và gắn / gỡ tag (theo danh sách khách, phân khúc hoặc điều kiện).
+package crmhdl
+
+import (
+	"fmt"
+
+	basehdl "meta_commerce/internal/api/base/handler"
+	crmdto "meta_commerce/internal/api/crm/dto"
+	crmmodels "meta_commerce/internal/api/crm/models"
+	crmvc "meta_commerce/internal/api/crm/service"
+	"meta_commerce/internal/common"
+
+	"github.com/gofiber/fiber/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CrmCustomerTagHandler CRUD danh mục tag (customer_core_tags) và gắn / gỡ tag trên khách.
+type CrmCustomerTagHandler struct {
+	*basehdl.BaseHandler[crmmodels.CrmCustomerTag, crmdto.CrmCustomerTagCreateInput, crmdto.CrmCustomerTagUpdateInput]
+	TagService *crmvc.CrmCustomerTagService
+}
+
+// NewCrmCustomerTagHandler tạo CrmCustomerTagHandler mới.
+func NewCrmCustomerTagHandler() (*CrmCustomerTagHandler, error) {
+	svc, err := crmvc.NewCrmCustomerTagService()
+	if err != nil {
+		return nil, fmt.Errorf("tạo CrmCustomerTagService: %w", err)
+	}
+	hdl := &CrmCustomerTagHandler{
+		// Truyền svc (không phải BaseServiceMongoImpl) để InsertOne/UpdateById/DeleteById override được dùng.
+		BaseHandler: basehdl.NewBaseHandler[crmmodels.CrmCustomerTag, crmdto.CrmCustomerTagCreateInput, crmdto.CrmCustomerTagUpdateInput](svc),
+		TagService:  svc,
+	}
+	hdl.SetFilterOptions(basehdl.FilterOptions{
+		DeniedFields:     []string{},
+		AllowedOperators: []string{"$eq", "$ne", "$in", "$exists", "$regex"},
+		MaxFields:        10,
+	})
+	return hdl, nil
+}
+
+// HandleApply xử lý POST /crm-tags/apply — gắn / gỡ tag. unifiedIds → chạy ngay, trả số khách đổi;
+// segmentId / conditions → tạo bulk job tag_apply, trả jobId.
+func (h *CrmCustomerTagHandler) HandleApply(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		orgID := getActiveOrganizationID(c)
+		if orgID == nil || orgID.IsZero() {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationInput, "Vui lòng chọn tổ chức", common.StatusBadRequest, nil))
+			return nil
+		}
+		var input crmdto.CrmTagApplyInput
+		if err := h.ParseRequestBody(c, &input); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		if len(input.UnifiedIds) > 0 {
+			if input.SegmentID != "" || input.Conditions != nil {
+				h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationInput, "Cần đúng một trong unifiedIds, segmentId, conditions", common.StatusBadRequest, nil))
+				return nil
+			}
+			modified, err := h.TagService.ApplyTagsToCustomers(c.Context(), *orgID, input.Action, input.Tags, input.UnifiedIds)
+			if err != nil {
+				h.HandleResponse(c, nil, err)
+				return nil
+			}
+			h.HandleResponse(c, fiber.Map{"action": input.Action, "modified": modified}, nil)
+			return nil
+		}
+		var segmentID primitive.ObjectID
+		if input.SegmentID != "" {
+			id, err := primitive.ObjectIDFromHex(input.SegmentID)
+			if err != nil {
+				h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "segmentId không hợp lệ", common.StatusBadRequest, err))
+				return nil
+			}
+			segmentID = id
+		}
+		jobID, err := h.TagService.EnqueueTagApply(c.Context(), *orgID, crmvc.CrmTagApplyInput{
+			Action:     input.Action,
+			Tags:       input.Tags,
+			SegmentID:  segmentID,
+			Conditions: input.Conditions,
+			IsPriority: input.IsPriority,
+		})
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		h.HandleResponse(c, fiber.Map{"jobId": jobID.Hex(), "jobType": crmmodels.CrmBulkJobTagApply}, nil)
+		return nil
+	})
+}
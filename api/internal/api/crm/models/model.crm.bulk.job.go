@@ -14,6 +14,11 @@ const (
 	CrmBulkJobRecalculateOne   = "recalculate_one"
 	CrmBulkJobRecalculateAll   = "recalculate_all"
 	CrmBulkJobRecalculateBatch = "recalculate_batch" // Job batch: params { offset, limit } — dùng cho recalculate-all chunking
+	CrmBulkJobDuplicateScan    = "duplicate_scan"    // Quét cặp nghi trùng vào hàng đợi duyệt gộp: params { limit }
+	CrmBulkJobSegmentRefresh   = "segment_refresh"   // Đánh giá lại toàn bộ thành viên phân khúc: params { segmentId } (rỗng = mọi phân khúc của org)
+	CrmBulkJobDataExport       = "data_export"       // Export dữ liệu cá nhân khách: params { requestId } (customer_run_privacy_requests)
+	CrmBulkJobDataErasure      = "data_erasure"      // Ẩn danh hoá PII khách trên mọi collection liên kết: params { requestId }
+	CrmBulkJobTagApply         = "tag_apply"         // Gắn / gỡ tag hàng loạt: params { action, tags, segmentId | conditions }
 )
 
 // CrmBulkJob job bulk CRM: sync, backfill, rebuild, recalculate.
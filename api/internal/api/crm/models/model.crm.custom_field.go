@@ -0,0 +1,44 @@
+// Package models — Thuộc tính khách do tổ chức định nghĩa: trường tùy chỉnh (customer_core_field_definitions)
+// và danh mục tag (customer_core_tags). Giá trị lưu trên khách: CrmCustomer.CustomFields, CrmCustomer.Tags.
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kiểu trường tùy chỉnh.
+const (
+	CrmCustomFieldTypeString    = "string"
+	CrmCustomFieldTypeNumber    = "number"
+	CrmCustomFieldTypeBoolean   = "boolean"
+	CrmCustomFieldTypeDate      = "date"       // Lưu unix ms; nhận số ms, RFC3339 hoặc YYYY-MM-DD (giờ Việt Nam)
+	CrmCustomFieldTypeEnum      = "enum"       // Một giá trị trong Options
+	CrmCustomFieldTypeMultiEnum = "multi_enum" // Mảng giá trị trong Options
+)
+
+// CrmCustomFieldDefinition định nghĩa một trường tùy chỉnh của khách. Key không đổi sau khi tạo (là path customFields.<key>
+// dùng để filter/sort và trong Rule Engine); Type không đổi để giá trị đã lưu luôn đúng kiểu.
+type CrmCustomFieldDefinition struct {
+	ID                  primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	Key                 string             `json:"key" bson:"key" index:"compound:customer_field_org_key_unique"`
+	Label               string             `json:"label" bson:"label"`
+	Type                string             `json:"type" bson:"type"`
+	Options             []string           `json:"options,omitempty" bson:"options,omitempty"` // enum / multi_enum
+	Required            bool               `json:"required" bson:"required"`                   // Bắt buộc có giá trị khi ghi trường tùy chỉnh của khách
+	Description         string             `json:"description,omitempty" bson:"description,omitempty"`
+	OwnerOrganizationID primitive.ObjectID `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1,compound:customer_field_org_key_unique"`
+	CreatedAt           int64              `json:"createdAt" bson:"createdAt"`
+	UpdatedAt           int64              `json:"updatedAt" bson:"updatedAt"`
+}
+
+// CrmCustomerTag một tag trong danh mục của tổ chức. Khách chỉ gắn được tag có trong danh mục;
+// đổi tên / xóa tag cập nhật theo trên mọi khách.
+type CrmCustomerTag struct {
+	ID                  primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	Name                string             `json:"name" bson:"name" index:"compound:customer_tag_org_name_unique"`
+	Color               string             `json:"color,omitempty" bson:"color,omitempty"` // #RRGGBB
+	Description         string             `json:"description,omitempty" bson:"description,omitempty"`
+	OwnerOrganizationID primitive.ObjectID `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1,compound:customer_tag_org_name_unique"`
+	CreatedAt           int64              `json:"createdAt" bson:"createdAt"`
+	UpdatedAt           int64              `json:"updatedAt" bson:"updatedAt"`
+}
@@ -121,12 +121,23 @@ type CrmCustomer struct {
 	LoyaltyStage   string `json:"loyaltyStage,omitempty" bson:"loyaltyStage,omitempty" index:"single:1,compound:customer_profile_org_loyalty"`       // core|repeat|one_time
 	MomentumStage  string `json:"momentumStage,omitempty" bson:"momentumStage,omitempty" index:"single:1,compound:customer_profile_org_momentum"`    // rising|stable|declining|lost
 
+	// Thuộc tính do shop gán — không bị sync/recalculate ghi đè.
+	// Tags: tên tag trong danh mục customer_core_tags. CustomFields: key → giá trị theo customer_core_field_definitions
+	// (date lưu unix ms, multi_enum lưu mảng chuỗi).
+	Tags         []string               `json:"tags,omitempty" bson:"tags,omitempty" index:"compound:customer_profile_org_tags"`
+	CustomFields map[string]interface{} `json:"customFields,omitempty" bson:"customFields,omitempty"`
+
 	// Merge metadata
 	MergeMethod string `json:"mergeMethod" bson:"mergeMethod"` // customer_id | fb_id | phone | single_source
 	MergedAt    int64  `json:"mergedAt" bson:"mergedAt"`
+	// MergedFrom: unifiedId/uid/customerId nguồn của khách đã bị gộp thủ công vào khách này — id cũ vẫn resolve về đây.
+	MergedFrom []string `json:"mergedFrom,omitempty" bson:"mergedFrom,omitempty" index:"single:1,sparse"`
+
+	// ErasedAt: PII đã bị ẩn danh hoá theo yêu cầu xóa dữ liệu (customer_run_privacy_requests); metrics giữ nguyên.
+	ErasedAt int64 `json:"erasedAt,omitempty" bson:"erasedAt,omitempty"`
 
 	// Phân quyền
-	OwnerOrganizationID primitive.ObjectID `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1,compound:customer_profile_org_unified_unique,compound:customer_profile_org_uid,compound:customer_profile_org_lastorder,compound:customer_profile_org_totalspent,compound:customer_profile_org_value,compound:customer_profile_org_journey,compound:customer_profile_org_lifecycle,compound:customer_profile_org_channel,compound:customer_profile_org_loyalty,compound:customer_profile_org_momentum"`
+	OwnerOrganizationID primitive.ObjectID `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1,compound:customer_profile_org_unified_unique,compound:customer_profile_org_uid,compound:customer_profile_org_lastorder,compound:customer_profile_org_totalspent,compound:customer_profile_org_value,compound:customer_profile_org_journey,compound:customer_profile_org_lifecycle,compound:customer_profile_org_channel,compound:customer_profile_org_loyalty,compound:customer_profile_org_momentum,compound:customer_profile_org_tags"`
 
 	// Metadata
 	CreatedAt int64 `json:"createdAt" bson:"createdAt" index:"single:1"`
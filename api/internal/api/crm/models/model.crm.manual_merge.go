@@ -0,0 +1,76 @@
+// Package models — Gộp/tách khách thủ công: hàng đợi nghi trùng (customer_job_duplicate_candidates)
+// và lịch sử gộp có thể đảo ngược (customer_run_merge_history).
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Trạng thái cặp nghi trùng trong hàng đợi duyệt.
+const (
+	CrmDuplicateStatusPending   = "pending"
+	CrmDuplicateStatusMerged    = "merged"
+	CrmDuplicateStatusDismissed = "dismissed"
+)
+
+// Lý do chấm điểm nghi trùng.
+const (
+	CrmDuplicateReasonPhone              = "phone"
+	CrmDuplicateReasonEmail              = "email"
+	CrmDuplicateReasonName               = "name"
+	CrmDuplicateReasonSharedConversation = "shared_conversation"
+)
+
+// CrmDuplicateCandidate cặp khách nghi trùng chờ nhân viên duyệt.
+// PairKey = unifiedIdA|unifiedIdB (A < B) — mỗi cặp chỉ một bản ghi; quét lại không ghi đè cặp đã dismissed/merged.
+type CrmDuplicateCandidate struct {
+	ID                  primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	PairKey             string             `json:"pairKey" bson:"pairKey" index:"compound:customer_dup_org_pair_unique"`
+	UnifiedIdA          string             `json:"unifiedIdA" bson:"unifiedIdA" index:"single:1"`
+	UnifiedIdB          string             `json:"unifiedIdB" bson:"unifiedIdB" index:"single:1"`
+	Score               float64            `json:"score" bson:"score" index:"compound:customer_dup_org_status_score,order:-1"`
+	Reasons             []string           `json:"reasons" bson:"reasons"` // phone | email | name | shared_conversation
+	NameSimilarity      float64            `json:"nameSimilarity" bson:"nameSimilarity"`
+	SharedConversations int                `json:"sharedConversations" bson:"sharedConversations"`
+	Status              string             `json:"status" bson:"status" index:"compound:customer_dup_org_status_score"` // pending | merged | dismissed
+	MergeHistoryID      primitive.ObjectID `json:"mergeHistoryId,omitempty" bson:"mergeHistoryId,omitempty"`
+	ReviewedBy          primitive.ObjectID `json:"reviewedBy,omitempty" bson:"reviewedBy,omitempty"`
+	ReviewedAt          int64              `json:"reviewedAt,omitempty" bson:"reviewedAt,omitempty"`
+	OwnerOrganizationID primitive.ObjectID `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1,compound:customer_dup_org_pair_unique,compound:customer_dup_org_status_score"`
+	CreatedAt           int64              `json:"createdAt" bson:"createdAt"`
+	UpdatedAt           int64              `json:"updatedAt" bson:"updatedAt"`
+}
+
+// CrmMergeMovedRef tham chiếu đã chuyển từ khách bị gộp sang khách giữ lại: Field của các document IDs
+// trong Collection đổi từ From sang To. Unmerge đổi ngược lại (chỉ document còn giá trị To).
+type CrmMergeMovedRef struct {
+	Collection string               `json:"collection" bson:"collection"`
+	Field      string               `json:"field" bson:"field"`
+	From       string               `json:"from" bson:"from"`
+	To         string               `json:"to" bson:"to"`
+	IDs        []primitive.ObjectID `json:"ids" bson:"ids"`
+}
+
+// CrmMergeHistory một lần gộp thủ công. MergedCustomer là snapshot nguyên document khách bị gộp (đã xóa khỏi
+// customer_core_records) — unmerge chèn lại đúng document này. UnmergedAt > 0: đã tách.
+type CrmMergeHistory struct {
+	ID                  primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	SurvivorUnifiedId   string             `json:"survivorUnifiedId" bson:"survivorUnifiedId" index:"single:1"`
+	SurvivorUid         string             `json:"survivorUid" bson:"survivorUid"`
+	MergedUnifiedId     string             `json:"mergedUnifiedId" bson:"mergedUnifiedId" index:"single:1"`
+	MergedUid           string             `json:"mergedUid" bson:"mergedUid"`
+	MergedCustomer      bson.M             `json:"mergedCustomer" bson:"mergedCustomer"`
+	AddedSourceIds      []string           `json:"addedSourceIds,omitempty" bson:"addedSourceIds,omitempty"`       // customerId nguồn thêm vào sourceIds khách giữ lại
+	AddedMergedFrom     []string           `json:"addedMergedFrom,omitempty" bson:"addedMergedFrom,omitempty"`     // id thêm vào mergedFrom khách giữ lại
+	AddedTags           []string           `json:"addedTags,omitempty" bson:"addedTags,omitempty"`                 // tag của khách bị gộp mà khách giữ lại chưa có
+	AddedCustomFields   []string           `json:"addedCustomFields,omitempty" bson:"addedCustomFields,omitempty"` // key trường tùy chỉnh khách giữ lại chưa có, lấy từ khách bị gộp
+	MovedRefs           []CrmMergeMovedRef `json:"movedRefs,omitempty" bson:"movedRefs,omitempty"`
+	CandidateID         primitive.ObjectID `json:"candidateId,omitempty" bson:"candidateId,omitempty"`
+	Reason              string             `json:"reason,omitempty" bson:"reason,omitempty"`
+	MergedBy            primitive.ObjectID `json:"mergedBy,omitempty" bson:"mergedBy,omitempty"`
+	MergedAt            int64              `json:"mergedAt" bson:"mergedAt" index:"single:-1"`
+	UnmergedBy          primitive.ObjectID `json:"unmergedBy,omitempty" bson:"unmergedBy,omitempty"`
+	UnmergedAt          int64              `json:"unmergedAt" bson:"unmergedAt"`
+	OwnerOrganizationID primitive.ObjectID `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1"`
+}
@@ -0,0 +1,75 @@
+// Package models — Yêu cầu quyền dữ liệu cá nhân của khách: export toàn bộ dữ liệu và xóa (ẩn danh hoá) PII
+// trên mọi collection liên kết (customer_run_privacy_requests), kèm bản chụp export (customer_run_privacy_export_items).
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Loại yêu cầu dữ liệu cá nhân.
+const (
+	CrmPrivacyKindExport  = "export"
+	CrmPrivacyKindErasure = "erasure"
+)
+
+// Trạng thái yêu cầu — queued → running → completed | failed.
+const (
+	CrmPrivacyStatusQueued    = "queued"
+	CrmPrivacyStatusRunning   = "running"
+	CrmPrivacyStatusCompleted = "completed"
+	CrmPrivacyStatusFailed    = "failed"
+)
+
+// CrmPrivacyErasedName tên thay thế sau khi ẩn danh hoá.
+const CrmPrivacyErasedName = "[đã xóa]"
+
+// CrmPrivacySubject chủ thể yêu cầu — nhập một trong ba (unifiedId ưu tiên).
+type CrmPrivacySubject struct {
+	UnifiedId string `json:"unifiedId,omitempty" bson:"unifiedId,omitempty"`
+	Phone     string `json:"phone,omitempty" bson:"phone,omitempty"` // Đã chuẩn hoá 84…
+	Email     string `json:"email,omitempty" bson:"email,omitempty"` // Chữ thường
+}
+
+// CrmPrivacyCollectionResult số document đã export / ẩn danh hoá trên một collection.
+type CrmPrivacyCollectionResult struct {
+	Key        string `json:"key" bson:"key"`               // customers | activities | notes | fb_customers | ...
+	Collection string `json:"collection" bson:"collection"` // Tên collection Mongo
+	Matched    int64  `json:"matched" bson:"matched"`       // Document liên kết với khách
+	Processed  int64  `json:"processed" bson:"processed"`   // Export: đã chụp; erasure: đã ẩn danh hoá (0 nếu collection không có PII)
+}
+
+// CrmPrivacyRequest bản ghi kiểm toán một yêu cầu export/xóa dữ liệu khách. Khách được resolve lúc tạo yêu cầu
+// (ResolvedUnifiedIds) để người duyệt thấy chính xác ai sẽ bị xóa; worker crm_bulk_jobs chạy theo danh sách này.
+type CrmPrivacyRequest struct {
+	ID                  primitive.ObjectID           `json:"id,omitempty" bson:"_id,omitempty"`
+	Kind                string                       `json:"kind" bson:"kind" index:"compound:customer_privacy_org_kind_created"`
+	Status              string                       `json:"status" bson:"status" index:"single:1"`
+	Subject             CrmPrivacySubject            `json:"subject" bson:"subject"`
+	ResolvedUnifiedIds  []string                     `json:"resolvedUnifiedIds" bson:"resolvedUnifiedIds" index:"single:1"`
+	Reason              string                       `json:"reason,omitempty" bson:"reason,omitempty"`
+	BulkJobID           primitive.ObjectID           `json:"bulkJobId,omitempty" bson:"bulkJobId,omitempty"`
+	Collections         []CrmPrivacyCollectionResult `json:"collections,omitempty" bson:"collections,omitempty"`
+	Error               string                       `json:"error,omitempty" bson:"error,omitempty"`
+	RequestedBy         primitive.ObjectID           `json:"requestedBy,omitempty" bson:"requestedBy,omitempty"`
+	StartedAt           int64                        `json:"startedAt,omitempty" bson:"startedAt,omitempty"`
+	CompletedAt         int64                        `json:"completedAt,omitempty" bson:"completedAt,omitempty"`
+	ExportExpiresAt     int64                        `json:"exportExpiresAt,omitempty" bson:"exportExpiresAt,omitempty"` // Export: hạn tải bản chụp
+	OwnerOrganizationID primitive.ObjectID           `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1,compound:customer_privacy_org_kind_created"`
+	CreatedAt           int64                        `json:"createdAt" bson:"createdAt" index:"compound:customer_privacy_org_kind_created,order:-1"`
+	UpdatedAt           int64                        `json:"updatedAt" bson:"updatedAt"`
+}
+
+// CrmPrivacyExportItem bản chụp một document nguồn của yêu cầu export. Unique (requestId, key, sourceId) —
+// chạy lại batch sau khi worker restart không tạo bản trùng. Tự xóa khi hết hạn (TTL).
+type CrmPrivacyExportItem struct {
+	ID                  primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	RequestID           primitive.ObjectID `json:"requestId" bson:"requestId" index:"compound:customer_privacy_item_unique"`
+	Key                 string             `json:"key" bson:"key" index:"compound:customer_privacy_item_unique"`
+	SourceID            primitive.ObjectID `json:"sourceId" bson:"sourceId" index:"compound:customer_privacy_item_unique"`
+	Document            bson.Raw           `json:"document" bson:"document"`
+	OwnerOrganizationID primitive.ObjectID `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1"`
+	ExpiresAt           time.Time          `json:"expiresAt" bson:"expiresAt" index:"single:1,ttl:0"`
+}
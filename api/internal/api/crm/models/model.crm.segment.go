@@ -0,0 +1,80 @@
+// Package models — Phân khúc khách động: định nghĩa (customer_core_segments), thành viên hiện tại
+// (customer_run_segment_members) và số thành viên theo ngày (customer_run_segment_counts).
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Toán tử điều kiện phân khúc (DSL an toàn — không nhận query Mongo thô).
+const (
+	CrmSegmentOpEq            = "eq"
+	CrmSegmentOpNe            = "ne"
+	CrmSegmentOpIn            = "in"
+	CrmSegmentOpNin           = "nin"
+	CrmSegmentOpGt            = "gt"
+	CrmSegmentOpGte           = "gte"
+	CrmSegmentOpLt            = "lt"
+	CrmSegmentOpLte           = "lte"
+	CrmSegmentOpExists        = "exists"
+	CrmSegmentOpOlderThanDays = "older_than_days" // Không có mốc thời gian trong N ngày gần nhất (chưa từng cũng tính)
+	CrmSegmentOpWithinDays    = "within_days"     // Có mốc thời gian trong N ngày gần nhất
+)
+
+// CrmSegmentCondition một nút điều kiện: lá (Field + Op + Value) hoặc nhóm (All / Any).
+// Key dùng cho field dạng map — ownedSkuQuantities: Key = SKU; customFields: Key = key trường tùy chỉnh.
+//
+// Ví dụ "REPEAT, chi > 2tr, 45 ngày chưa mua, có SKU X":
+//
+//	{"all": [
+//	  {"field": "journeyStage", "op": "eq", "value": "repeat"},
+//	  {"field": "totalSpent", "op": "gt", "value": 2000000},
+//	  {"field": "lastOrderAt", "op": "older_than_days", "value": 45},
+//	  {"field": "ownedSkuQuantities", "key": "X", "op": "exists"}
+//	]}
+type CrmSegmentCondition struct {
+	Field string                `json:"field,omitempty" bson:"field,omitempty"`
+	Key   string                `json:"key,omitempty" bson:"key,omitempty"`
+	Op    string                `json:"op,omitempty" bson:"op,omitempty"`
+	Value interface{}           `json:"value,omitempty" bson:"value,omitempty"`
+	All   []CrmSegmentCondition `json:"all,omitempty" bson:"all,omitempty"` // Tất cả điều kiện con đúng
+	Any   []CrmSegmentCondition `json:"any,omitempty" bson:"any,omitempty"` // Ít nhất một điều kiện con đúng
+}
+
+// CrmSegment định nghĩa phân khúc đã lưu. Thành viên được đánh giá lại mỗi khi worker intel tính lại khách
+// và toàn bộ khi refresh (tạo/sửa phân khúc, classification refresh định kỳ).
+// LastEvaluatedAt = 0: chưa refresh lần nào — lần đầu ghi thành viên không emit sự kiện vào/ra.
+type CrmSegment struct {
+	ID                  primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	Name                string              `json:"name" bson:"name"`
+	Description         string              `json:"description,omitempty" bson:"description,omitempty"`
+	Conditions          CrmSegmentCondition `json:"conditions" bson:"conditions"`
+	IsDisabled          bool                `json:"isDisabled" bson:"isDisabled" index:"compound:customer_segment_org_active"`
+	MemberCount         int64               `json:"memberCount" bson:"memberCount"`
+	LastEvaluatedAt     int64               `json:"lastEvaluatedAt,omitempty" bson:"lastEvaluatedAt,omitempty"`
+	OwnerOrganizationID primitive.ObjectID  `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1,compound:customer_segment_org_active"`
+	CreatedAt           int64               `json:"createdAt" bson:"createdAt"`
+	UpdatedAt           int64               `json:"updatedAt" bson:"updatedAt"`
+}
+
+// CrmSegmentMember khách đang thuộc phân khúc. Ra khỏi phân khúc → xóa bản ghi (sự kiện crm.segment.exited giữ dấu vết).
+type CrmSegmentMember struct {
+	ID                  primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	SegmentID           primitive.ObjectID `json:"segmentId" bson:"segmentId" index:"compound:customer_segment_member_unique,compound:customer_segment_member_entered"`
+	UnifiedId           string             `json:"unifiedId" bson:"unifiedId" index:"single:1,compound:customer_segment_member_unique"`
+	EnteredAt           int64              `json:"enteredAt" bson:"enteredAt" index:"compound:customer_segment_member_entered,order:-1"`
+	OwnerOrganizationID primitive.ObjectID `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1"`
+}
+
+// CrmSegmentCountSnapshot số thành viên của phân khúc theo ngày (giờ Việt Nam). Count là giá trị cuối ngày
+// (ghi đè mỗi lần membership đổi); Entered/Exited cộng dồn trong ngày.
+type CrmSegmentCountSnapshot struct {
+	ID                  primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	SegmentID           primitive.ObjectID `json:"segmentId" bson:"segmentId" index:"compound:customer_segment_count_day_unique"`
+	Day                 string             `json:"day" bson:"day" index:"compound:customer_segment_count_day_unique"` // YYYY-MM-DD
+	Count               int64              `json:"count" bson:"count"`
+	Entered             int64              `json:"entered" bson:"entered"`
+	Exited              int64              `json:"exited" bson:"exited"`
+	OwnerOrganizationID primitive.ObjectID `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1"`
+	UpdatedAt           int64              `json:"updatedAt" bson:"updatedAt"`
+}
@@ -1,4 +1,4 @@
-// Package router đăng ký các route thuộc domain CRM: customers profile, notes.
+// Package router đăng ký các route thuộc domain CRM: customers profile, notes, gộp/tách thủ công, phân khúc động, yêu cầu dữ liệu cá nhân.
 package router
 
 import (
@@ -29,10 +29,35 @@ func Register(v1 fiber.Router, r *apirouter.Router) error {
 	if err != nil {
 		return fmt.Errorf("tạo CrmBulkJobHandler: %w", err)
 	}
+	mergeHistoryHandler, err := crmhdl.NewCrmMergeHistoryHandler()
+	if err != nil {
+		return fmt.Errorf("tạo CrmMergeHistoryHandler: %w", err)
+	}
+	duplicateHandler, err := crmhdl.NewCrmDuplicateCandidateHandler()
+	if err != nil {
+		return fmt.Errorf("tạo CrmDuplicateCandidateHandler: %w", err)
+	}
+	segmentHandler, err := crmhdl.NewCrmSegmentHandler()
+	if err != nil {
+		return fmt.Errorf("tạo CrmSegmentHandler: %w", err)
+	}
+	privacyHandler, err := crmhdl.NewCrmPrivacyRequestHandler()
+	if err != nil {
+		return fmt.Errorf("tạo CrmPrivacyRequestHandler: %w", err)
+	}
+	customFieldHandler, err := crmhdl.NewCrmCustomFieldHandler()
+	if err != nil {
+		return fmt.Errorf("tạo CrmCustomFieldHandler: %w", err)
+	}
+	tagHandler, err := crmhdl.NewCrmCustomerTagHandler()
+	if err != nil {
+		return fmt.Errorf("tạo CrmCustomerTagHandler: %w", err)
+	}
 
 	crmReadMiddleware := middleware.AuthMiddleware("Report.Read")
 	orgContextMiddleware := middleware.OrganizationContextMiddleware()
 	middlewares := []fiber.Handler{crmReadMiddleware, orgContextMiddleware}
+	mergeMiddlewares := []fiber.Handler{middleware.AuthMiddleware("CrmCustomer.Merge"), orgContextMiddleware}
 
 	// CRUD customers (chỉ đọc) — find, find-one, find-by-id, find-with-pagination, count. Filter theo ownerOrganizationId + classification.
 	// Đăng ký trước các route /:unifiedId để tránh conflict.
@@ -44,6 +69,52 @@ func Register(v1 fiber.Router, r *apirouter.Router) error {
 	// CRUD crm-bulk-jobs — đọc + update-by-id (retry, isPriority). Queue sync/backfill/recalculate.
 	r.RegisterCRUDRoutes(v1, "/crm-bulk-jobs", bulkJobHandler, apirouter.CrmBulkJobConfig, "Report")
 
+	// CRUD crm-merge-history (chỉ đọc) — lịch sử gộp thủ công, kèm snapshot khách bị gộp.
+	r.RegisterCRUDRoutes(v1, "/crm-merge-history", mergeHistoryHandler, apirouter.ReadOnlyConfig, "Report")
+
+	// CRUD crm-duplicate-candidates (chỉ đọc) — hàng đợi cặp nghi trùng; sort theo score để duyệt.
+	r.RegisterCRUDRoutes(v1, "/crm-duplicate-candidates", duplicateHandler, apirouter.ReadOnlyConfig, "Report")
+	// POST /crm-duplicate-candidates/scan — tạo bulk job duplicate_scan. Body: limit, isPriority
+	apirouter.RegisterRouteWithMiddleware(v1, "/crm-duplicate-candidates", "POST", "/scan", mergeMiddlewares, duplicateHandler.HandleScan)
+	// POST /crm-duplicate-candidates/dismiss/:id — đánh dấu cặp không trùng
+	apirouter.RegisterRouteWithMiddleware(v1, "/crm-duplicate-candidates", "POST", "/dismiss/:id", mergeMiddlewares, duplicateHandler.HandleDismiss)
+
+	// CRUD crm-segments — phân khúc khách động (DSL điều kiện). Tạo/sửa điều kiện → bulk job segment_refresh.
+	r.RegisterCRUDRoutes(v1, "/crm-segments", segmentHandler, apirouter.CrmSegmentConfig, "CrmSegment")
+	segmentReadMiddlewares := []fiber.Handler{middleware.AuthMiddleware("CrmSegment.Read"), orgContextMiddleware}
+	segmentUpdateMiddlewares := []fiber.Handler{middleware.AuthMiddleware("CrmSegment.Update"), orgContextMiddleware}
+	// GET /crm-segments/members/:id — thành viên hiện tại. Query: page, limit (tối đa 500)
+	apirouter.RegisterRouteWithMiddleware(v1, "/crm-segments", "GET", "/members/:id", segmentReadMiddlewares, segmentHandler.HandleListMembers)
+	// GET /crm-segments/export/:id — tải CSV toàn bộ thành viên
+	apirouter.RegisterRouteWithMiddleware(v1, "/crm-segments", "GET", "/export/:id", segmentReadMiddlewares, segmentHandler.HandleExportMembers)
+	// GET /crm-segments/counts/:id — số thành viên theo ngày. Query: from, to (YYYY-MM-DD)
+	apirouter.RegisterRouteWithMiddleware(v1, "/crm-segments", "GET", "/counts/:id", segmentReadMiddlewares, segmentHandler.HandleCountHistory)
+	// POST /crm-segments/refresh/:id — đánh giá lại toàn bộ khách (bulk job). Body: isPriority
+	apirouter.RegisterRouteWithMiddleware(v1, "/crm-segments", "POST", "/refresh/:id", segmentUpdateMiddlewares, segmentHandler.HandleRefresh)
+	// POST /crm-segments/enable/:id, /disable/:id — bật (ghi lại thành viên từ đầu) / tắt (ngừng đánh giá)
+	apirouter.RegisterRouteWithMiddleware(v1, "/crm-segments", "POST", "/enable/:id", segmentUpdateMiddlewares, segmentHandler.HandleEnable)
+	apirouter.RegisterRouteWithMiddleware(v1, "/crm-segments", "POST", "/disable/:id", segmentUpdateMiddlewares, segmentHandler.HandleDisable)
+
+	// CRUD crm-privacy-requests (chỉ đọc) — bản ghi kiểm toán yêu cầu export / xóa dữ liệu khách (bulk job data_export, data_erasure).
+	r.RegisterCRUDRoutes(v1, "/crm-privacy-requests", privacyHandler, apirouter.ReadOnlyConfig, "CrmPrivacy")
+	privacyExportMiddlewares := []fiber.Handler{middleware.AuthMiddleware("CrmPrivacy.Export"), orgContextMiddleware}
+	// POST /crm-privacy-requests/export — Body: unifiedId | phone | email, reason, isPriority
+	apirouter.RegisterRouteWithMiddleware(v1, "/crm-privacy-requests", "POST", "/export", privacyExportMiddlewares, privacyHandler.HandleCreateExport)
+	// GET /crm-privacy-requests/download/:id — tải bản export. Query: format=zip|json
+	apirouter.RegisterRouteWithMiddleware(v1, "/crm-privacy-requests", "GET", "/download/:id", privacyExportMiddlewares, privacyHandler.HandleDownload)
+	// POST /crm-privacy-requests/erasure — Body: unifiedId | phone | email, reason (bắt buộc), isPriority. Đăng ký cuối nhóm (quyền chặt nhất).
+	apirouter.RegisterRouteWithMiddleware(v1, "/crm-privacy-requests", "POST", "/erasure", []fiber.Handler{middleware.AuthMiddleware("CrmPrivacy.Erase"), orgContextMiddleware}, privacyHandler.HandleCreateErasure)
+
+	// CRUD crm-custom-fields — định nghĩa trường tùy chỉnh của khách (key, type, options, required). Xóa → gỡ giá trị trên mọi khách.
+	r.RegisterCRUDRoutes(v1, "/crm-custom-fields", customFieldHandler, apirouter.CrmCustomerAttributeConfig, "CrmCustomField")
+	// PUT /crm-custom-fields/values/:unifiedId — ghi giá trị trên khách. Body: values { key: value | null }
+	apirouter.RegisterRouteWithMiddleware(v1, "/crm-custom-fields", "PUT", "/values/:unifiedId", []fiber.Handler{middleware.AuthMiddleware("CrmCustomField.SetValue"), orgContextMiddleware}, customFieldHandler.HandleSetValues)
+
+	// CRUD crm-tags — danh mục tag (name, color). Đổi tên / xóa → cập nhật trên mọi khách.
+	r.RegisterCRUDRoutes(v1, "/crm-tags", tagHandler, apirouter.CrmCustomerAttributeConfig, "CrmTag")
+	// POST /crm-tags/apply — gắn / gỡ tag. Body: action, tags, một trong unifiedIds | segmentId | conditions, isPriority
+	apirouter.RegisterRouteWithMiddleware(v1, "/crm-tags", "POST", "/apply", []fiber.Handler{middleware.AuthMiddleware("CrmTag.Apply"), orgContextMiddleware}, tagHandler.HandleApply)
+
 	// POST /customers/rebuild — tạo 2 job: sync + backfill. Query/Body: sources=pos,fb,order,conversation,note (rỗng=tất cả)
 	apirouter.RegisterRouteWithMiddleware(v1, "/customers", "POST", "/rebuild", middlewares, customerHandler.HandleRebuildCrm)
 
@@ -66,5 +137,11 @@ func Register(v1 fiber.Router, r *apirouter.Router) error {
 	// DELETE /customers/:unifiedId/notes/:noteId
 	apirouter.RegisterRouteWithMiddleware(v1, "/customers", "DELETE", "/:unifiedId/notes/:noteId", middlewares, noteHandler.HandleDeleteNote)
 
+	// Gộp/tách thủ công — đăng ký cuối cùng: middleware CrmCustomer.Merge gắn vào group /customers không chặn các route đọc phía trên.
+	// POST /customers/:unifiedId/merge — Body: mergeUnifiedId, candidateId, reason
+	apirouter.RegisterRouteWithMiddleware(v1, "/customers", "POST", "/:unifiedId/merge", mergeMiddlewares, mergeHistoryHandler.HandleMerge)
+	// POST /customers/:unifiedId/unmerge — Body: mergeHistoryId hoặc mergedUnifiedId
+	apirouter.RegisterRouteWithMiddleware(v1, "/customers", "POST", "/:unifiedId/unmerge", mergeMiddlewares, mergeHistoryHandler.HandleUnmerge)
+
 	return nil
 }
@@ -18,8 +18,11 @@ import (
 	crmmodels "meta_commerce/internal/api/crm/models"
 	ruleintelmodels "meta_commerce/internal/api/ruleintel/models"
 	ruleintelsvc "meta_commerce/internal/api/ruleintel/service"
+	"meta_commerce/internal/global"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongoopts "go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // GetClassificationFromCustomer gọi Rule Engine RULE_CRM_CLASSIFICATION — vỏ duy nhất khi có customer.
@@ -40,12 +43,45 @@ func GetClassificationFromCustomer(ctx context.Context, c *crmmodels.CrmCustomer
 		"hasConversation":   GetBoolFromCustomer(c, "hasConversation"),
 		"conversationTags":  c.ConversationTags,
 	}
-	if class := computeClassificationViaRuleEngine(ctx, raw, c.UnifiedId, c.OwnerOrganizationID); class != nil {
+	if class := computeClassificationViaRuleEngine(ctx, raw, buildCustomLayer(c.Tags, c.CustomFields), c.UnifiedId, c.OwnerOrganizationID); class != nil {
 		return class
 	}
 	return map[string]interface{}{}
 }
 
+// buildCustomLayer layer "custom" cho Rule Engine — tag và trường tùy chỉnh shop gắn cho khách
+// (script đọc ctx.layers.custom.tags, ctx.layers.custom.fields.<key>). Luôn có đủ 2 key để script không phải kiểm tra nil.
+func buildCustomLayer(tags []string, fields map[string]interface{}) map[string]interface{} {
+	if tags == nil {
+		tags = []string{}
+	}
+	if fields == nil {
+		fields = map[string]interface{}{}
+	}
+	return map[string]interface{}{"tags": tags, "fields": fields}
+}
+
+// loadCustomLayer đọc tags, customFields của khách (projection) để dựng layer "custom" khi caller chỉ có metrics.
+// Lỗi / không tìm thấy → layer rỗng (không chặn phân loại).
+func loadCustomLayer(ctx context.Context, unifiedId string, ownerOrgID primitive.ObjectID) map[string]interface{} {
+	coll, ok := global.RegistryCollections.Get(global.MongoDB_ColNames.CustomerCustomers)
+	if !ok || unifiedId == "" {
+		return buildCustomLayer(nil, nil)
+	}
+	var doc struct {
+		Tags         []string               `bson:"tags"`
+		CustomFields map[string]interface{} `bson:"customFields"`
+	}
+	err := coll.FindOne(ctx,
+		bson.M{"ownerOrganizationId": ownerOrgID, "unifiedId": unifiedId},
+		mongoopts.FindOne().SetProjection(bson.M{"tags": 1, "customFields": 1}),
+	).Decode(&doc)
+	if err != nil {
+		return buildCustomLayer(nil, nil)
+	}
+	return buildCustomLayer(doc.Tags, doc.CustomFields)
+}
+
 // getStrFromMap trích string từ map — dùng cho classification map.
 func getStrFromMap(m map[string]interface{}, key string) string {
 	if m == nil {
@@ -62,8 +98,9 @@ func getStrFromMap(m map[string]interface{}, key string) string {
 }
 
 // computeClassificationViaRuleEngine gọi Rule Engine RULE_CRM_CLASSIFICATION.
+// Layers: raw (metrics), custom (tags + customFields — xem buildCustomLayer).
 // Trả về map classification hoặc nil khi lỗi (caller dùng fallback ComputeClassificationFromMetrics).
-func computeClassificationViaRuleEngine(ctx context.Context, raw map[string]interface{}, unifiedId string, ownerOrgID primitive.ObjectID) map[string]interface{} {
+func computeClassificationViaRuleEngine(ctx context.Context, raw, custom map[string]interface{}, unifiedId string, ownerOrgID primitive.ObjectID) map[string]interface{} {
 	svc, err := ruleintelsvc.NewRuleEngineService()
 	if err != nil {
 		return nil
@@ -71,11 +108,14 @@ func computeClassificationViaRuleEngine(ctx context.Context, raw map[string]inte
 	if raw == nil {
 		raw = map[string]interface{}{}
 	}
+	if custom == nil {
+		custom = buildCustomLayer(nil, nil)
+	}
 	input := &ruleintelsvc.RunInput{
 		RuleID:    "RULE_CRM_CLASSIFICATION",
 		Domain:    "crm",
 		EntityRef: ruleintelmodels.EntityRef{Domain: "crm", ObjectType: "customer", ObjectID: unifiedId, OwnerOrganizationID: ownerOrgID.Hex()},
-		Layers:    map[string]interface{}{"raw": raw},
+		Layers:    map[string]interface{}{"raw": raw, "custom": custom},
 	}
 	result, err := svc.Run(ctx, input)
 	if err != nil || result == nil || result.Result == nil {
@@ -112,7 +152,7 @@ func ComputeClassificationFromMetricsOrRuleEngine(ctx context.Context, totalSpen
 		"hasConversation":   hasConversation,
 		"conversationTags":  conversationTags,
 	}
-	if class := computeClassificationViaRuleEngine(ctx, raw, unifiedId, ownerOrgID); class != nil {
+	if class := computeClassificationViaRuleEngine(ctx, raw, loadCustomLayer(ctx, unifiedId, ownerOrgID), unifiedId, ownerOrgID); class != nil {
 		return class
 	}
 	return map[string]interface{}{}
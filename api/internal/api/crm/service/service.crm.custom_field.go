@@ -0,0 +1,333 @@
+// Package crmvc — Trường tùy chỉnh của khách: định nghĩa theo tổ chức (customer_core_field_definitions)
+// và ghi giá trị vào CrmCustomer.CustomFields có kiểm tra kiểu / enum / bắt buộc.
+package crmvc
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"meta_commerce/internal/api/aidecision/crmqueue"
+	basesvc "meta_commerce/internal/api/base/service"
+	crmmodels "meta_commerce/internal/api/crm/models"
+	"meta_commerce/internal/common"
+	"meta_commerce/internal/global"
+	"meta_commerce/internal/logger"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongoopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Giới hạn định nghĩa trường tùy chỉnh.
+const (
+	crmCustomFieldMaxOptions     = 100
+	crmCustomFieldMaxStringLen   = 1000
+	crmCustomFieldMaxPerOrg      = 100
+	crmCustomFieldDateOnlyLayout = "2006-01-02"
+)
+
+// crmCustomFieldKeyPattern key dùng làm path customFields.<key> — không có dấu chấm / $.
+var crmCustomFieldKeyPattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]{0,39}$`)
+
+var crmCustomFieldTypes = []string{
+	crmmodels.CrmCustomFieldTypeString, crmmodels.CrmCustomFieldTypeNumber, crmmodels.CrmCustomFieldTypeBoolean,
+	crmmodels.CrmCustomFieldTypeDate, crmmodels.CrmCustomFieldTypeEnum, crmmodels.CrmCustomFieldTypeMultiEnum,
+}
+
+// CrmCustomFieldService CRUD định nghĩa trường tùy chỉnh và ghi giá trị trên khách.
+type CrmCustomFieldService struct {
+	*basesvc.BaseServiceMongoImpl[crmmodels.CrmCustomFieldDefinition]
+	customerSvc *CrmCustomerService
+}
+
+// NewCrmCustomFieldService tạo CrmCustomFieldService mới.
+func NewCrmCustomFieldService() (*CrmCustomFieldService, error) {
+	coll, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.CustomerFieldDefinitions)
+	if !exist {
+		return nil, fmt.Errorf("không tìm thấy collection %s: %w", global.MongoDB_ColNames.CustomerFieldDefinitions, common.ErrNotFound)
+	}
+	customerSvc, err := NewCrmCustomerService()
+	if err != nil {
+		return nil, err
+	}
+	return &CrmCustomFieldService{
+		BaseServiceMongoImpl: basesvc.NewBaseServiceMongo[crmmodels.CrmCustomFieldDefinition](coll),
+		customerSvc:          customerSvc,
+	}, nil
+}
+
+// InsertOne override — kiểm tra key, kiểu, options; giới hạn số trường mỗi tổ chức.
+func (s *CrmCustomFieldService) InsertOne(ctx context.Context, data crmmodels.CrmCustomFieldDefinition) (crmmodels.CrmCustomFieldDefinition, error) {
+	data.Key = strings.TrimSpace(data.Key)
+	data.Label = strings.TrimSpace(data.Label)
+	if !crmCustomFieldKeyPattern.MatchString(data.Key) {
+		return data, common.NewError(common.ErrCodeValidationInput, "key phải bắt đầu bằng chữ, chỉ gồm chữ, số, _ (tối đa 40 ký tự)", common.StatusBadRequest, nil)
+	}
+	if data.Label == "" {
+		data.Label = data.Key
+	}
+	if !contains(crmCustomFieldTypes, data.Type) {
+		return data, common.NewError(common.ErrCodeValidationInput, fmt.Sprintf("type phải là một trong: %s", strings.Join(crmCustomFieldTypes, ", ")), common.StatusBadRequest, nil)
+	}
+	options, err := normalizeCustomFieldOptions(data.Type, data.Options)
+	if err != nil {
+		return data, err
+	}
+	data.Options = options
+	count, err := s.CountDocuments(ctx, bson.M{"ownerOrganizationId": data.OwnerOrganizationID})
+	if err != nil {
+		return data, err
+	}
+	if count >= crmCustomFieldMaxPerOrg {
+		return data, common.NewError(common.ErrCodeBusinessState, fmt.Sprintf("Tối đa %d trường tùy chỉnh mỗi tổ chức", crmCustomFieldMaxPerOrg), common.StatusConflict, nil)
+	}
+	return s.BaseServiceMongoImpl.InsertOne(ctx, data)
+}
+
+// UpdateById override — key và type không đổi; options được kiểm tra theo type hiện tại.
+// Giá trị đã lưu trên khách không bị sửa khi bỏ option — lần ghi sau mới bị kiểm tra lại.
+func (s *CrmCustomFieldService) UpdateById(ctx context.Context, id primitive.ObjectID, data interface{}) (crmmodels.CrmCustomFieldDefinition, error) {
+	var zero crmmodels.CrmCustomFieldDefinition
+	updateData, err := basesvc.ToUpdateData(data)
+	if err != nil {
+		return zero, err
+	}
+	if updateData.Set != nil {
+		delete(updateData.Set, "key")
+		delete(updateData.Set, "type")
+		if raw, ok := updateData.Set["options"]; ok {
+			current, err := s.FindOneById(ctx, id)
+			if err != nil {
+				return zero, err
+			}
+			list, _ := segmentStrings(raw)
+			options, err := normalizeCustomFieldOptions(current.Type, list)
+			if err != nil {
+				return zero, err
+			}
+			updateData.Set["options"] = options
+		}
+	}
+	return s.BaseServiceMongoImpl.UpdateById(ctx, id, updateData)
+}
+
+// DeleteById override — xóa định nghĩa và giá trị của trường trên mọi khách của tổ chức.
+func (s *CrmCustomFieldService) DeleteById(ctx context.Context, id primitive.ObjectID) error {
+	def, err := s.FindOneById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.BaseServiceMongoImpl.DeleteById(ctx, id); err != nil {
+		return err
+	}
+	path := "customFields." + def.Key
+	if _, err := s.customerSvc.Collection().UpdateMany(ctx,
+		bson.M{"ownerOrganizationId": def.OwnerOrganizationID, path: bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{path: ""}},
+	); err != nil {
+		return common.ConvertMongoError(err)
+	}
+	return nil
+}
+
+func normalizeCustomFieldOptions(fieldType string, options []string) ([]string, error) {
+	if fieldType != crmmodels.CrmCustomFieldTypeEnum && fieldType != crmmodels.CrmCustomFieldTypeMultiEnum {
+		if len(options) > 0 {
+			return nil, common.NewError(common.ErrCodeValidationInput, "options chỉ dùng cho enum / multi_enum", common.StatusBadRequest, nil)
+		}
+		return nil, nil
+	}
+	out := make([]string, 0, len(options))
+	seen := make(map[string]bool, len(options))
+	for _, o := range options {
+		o = strings.TrimSpace(o)
+		if o == "" || seen[strings.ToLower(o)] {
+			continue
+		}
+		seen[strings.ToLower(o)] = true
+		out = append(out, o)
+	}
+	if len(out) == 0 || len(out) > crmCustomFieldMaxOptions {
+		return nil, common.NewError(common.ErrCodeValidationInput, fmt.Sprintf("enum cần 1..%d options", crmCustomFieldMaxOptions), common.StatusBadRequest, nil)
+	}
+	return out, nil
+}
+
+// FindOrgDefinitions định nghĩa trường tùy chỉnh của tổ chức theo key.
+func (s *CrmCustomFieldService) FindOrgDefinitions(ctx context.Context, ownerOrgID primitive.ObjectID) (map[string]crmmodels.CrmCustomFieldDefinition, error) {
+	defs, err := s.Find(ctx, bson.M{"ownerOrganizationId": ownerOrgID}, nil)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]crmmodels.CrmCustomFieldDefinition, len(defs))
+	for _, d := range defs {
+		out[d.Key] = d
+	}
+	return out, nil
+}
+
+// ValidateCustomFieldValues kiểm tra và chuẩn hoá giá trị ghi lên khách. values[key] = nil → xóa giá trị.
+// Trả về $set (customFields.<key>) và danh sách key cần $unset. Trường required phải còn giá trị sau khi ghi
+// (tính cả giá trị hiện có trên khách).
+func ValidateCustomFieldValues(defs map[string]crmmodels.CrmCustomFieldDefinition, current, values map[string]interface{}) (bson.M, []string, error) {
+	set := bson.M{}
+	var unset []string
+	for key, raw := range values {
+		def, ok := defs[key]
+		if !ok {
+			return nil, nil, common.NewError(common.ErrCodeValidationInput, fmt.Sprintf("Trường %q chưa được định nghĩa", key), common.StatusBadRequest, nil)
+		}
+		if raw == nil {
+			unset = append(unset, key)
+			continue
+		}
+		v, err := normalizeCustomFieldValue(def, raw)
+		if err != nil {
+			return nil, nil, common.NewError(common.ErrCodeValidationInput, fmt.Sprintf("%s: %s", key, err.Error()), common.StatusBadRequest, nil)
+		}
+		set["customFields."+key] = v
+	}
+	for key, def := range defs {
+		if !def.Required {
+			continue
+		}
+		_, setNow := set["customFields."+key]
+		_, had := current[key]
+		if !setNow && (!had || contains(unset, key)) {
+			return nil, nil, common.NewError(common.ErrCodeValidationInput, fmt.Sprintf("Thiếu trường bắt buộc %q", key), common.StatusBadRequest, nil)
+		}
+	}
+	return set, unset, nil
+}
+
+// normalizeCustomFieldValue ép giá trị JSON về kiểu lưu: string, float64, bool, int64 (date, unix ms),
+// option gốc (enum, không phân biệt hoa thường), []string (multi_enum).
+func normalizeCustomFieldValue(def crmmodels.CrmCustomFieldDefinition, raw interface{}) (interface{}, error) {
+	switch def.Type {
+	case crmmodels.CrmCustomFieldTypeString:
+		s, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("cần chuỗi")
+		}
+		s = strings.TrimSpace(s)
+		if len([]rune(s)) > crmCustomFieldMaxStringLen {
+			return nil, fmt.Errorf("tối đa %d ký tự", crmCustomFieldMaxStringLen)
+		}
+		return s, nil
+	case crmmodels.CrmCustomFieldTypeNumber:
+		n, ok := segmentNumber(raw)
+		if !ok {
+			return nil, fmt.Errorf("cần số")
+		}
+		return n, nil
+	case crmmodels.CrmCustomFieldTypeBoolean:
+		b, ok := raw.(bool)
+		if !ok {
+			return nil, fmt.Errorf("cần true/false")
+		}
+		return b, nil
+	case crmmodels.CrmCustomFieldTypeDate:
+		if n, ok := segmentNumber(raw); ok {
+			return int64(n), nil
+		}
+		s, _ := raw.(string)
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return t.UnixMilli(), nil
+		}
+		loc, err := time.LoadLocation("Asia/Ho_Chi_Minh")
+		if err != nil {
+			loc = time.FixedZone("ICT", 7*3600)
+		}
+		if t, err := time.ParseInLocation(crmCustomFieldDateOnlyLayout, s, loc); err == nil {
+			return t.UnixMilli(), nil
+		}
+		return nil, fmt.Errorf("cần unix ms, RFC3339 hoặc YYYY-MM-DD")
+	case crmmodels.CrmCustomFieldTypeEnum:
+		s, _ := raw.(string)
+		if opt, ok := matchCustomFieldOption(def.Options, s); ok {
+			return opt, nil
+		}
+		return nil, fmt.Errorf("giá trị phải thuộc: %s", strings.Join(def.Options, ", "))
+	case crmmodels.CrmCustomFieldTypeMultiEnum:
+		list, ok := segmentStrings(raw)
+		if !ok {
+			return nil, fmt.Errorf("cần mảng chuỗi")
+		}
+		out := make([]string, 0, len(list))
+		for _, item := range list {
+			opt, ok := matchCustomFieldOption(def.Options, item)
+			if !ok {
+				return nil, fmt.Errorf("%q không thuộc: %s", item, strings.Join(def.Options, ", "))
+			}
+			if !contains(out, opt) {
+				out = append(out, opt)
+			}
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("kiểu %q không hỗ trợ", def.Type)
+}
+
+func matchCustomFieldOption(options []string, s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	for _, o := range options {
+		if strings.EqualFold(o, s) {
+			return o, true
+		}
+	}
+	return "", false
+}
+
+// SetCustomerValues ghi giá trị trường tùy chỉnh của một khách (chỉ các key gửi lên; nil = xóa).
+// Xong thì xếp tính lại khách — classification và phân khúc dùng customFields được cập nhật theo.
+func (s *CrmCustomFieldService) SetCustomerValues(ctx context.Context, ownerOrgID primitive.ObjectID, unifiedId string, values map[string]interface{}) (*crmmodels.CrmCustomer, error) {
+	if len(values) == 0 {
+		return nil, common.NewError(common.ErrCodeValidationInput, "values rỗng", common.StatusBadRequest, nil)
+	}
+	customer, err := s.customerSvc.FindOne(ctx, buildCustomerFilterByIdOrUid(unifiedId, ownerOrgID), nil)
+	if err != nil {
+		if err == common.ErrNotFound {
+			return nil, common.NewError(common.ErrCodeValidationInput, "Không tìm thấy khách", common.StatusNotFound, nil)
+		}
+		return nil, err
+	}
+	defs, err := s.FindOrgDefinitions(ctx, ownerOrgID)
+	if err != nil {
+		return nil, err
+	}
+	set, unset, err := ValidateCustomFieldValues(defs, customer.CustomFields, values)
+	if err != nil {
+		return nil, err
+	}
+	set["updatedAt"] = time.Now().UnixMilli()
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		fields := bson.M{}
+		for _, key := range unset {
+			fields["customFields."+key] = ""
+		}
+		update["$unset"] = fields
+	}
+	var updated crmmodels.CrmCustomer
+	if err := s.customerSvc.Collection().FindOneAndUpdate(ctx, bson.M{"_id": customer.ID}, update,
+		mongoopts.FindOneAndUpdate().SetReturnDocument(mongoopts.After),
+	).Decode(&updated); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, common.NewError(common.ErrCodeValidationInput, "Không tìm thấy khách", common.StatusNotFound, nil)
+		}
+		return nil, common.ConvertMongoError(err)
+	}
+	requestCustomerRecalculate(ctx, updated.UnifiedId, ownerOrgID)
+	return &updated, nil
+}
+
+// requestCustomerRecalculate xếp tính lại một khách qua queue AI Decision (không chặn API nếu lỗi).
+func requestCustomerRecalculate(ctx context.Context, unifiedId string, ownerOrgID primitive.ObjectID) {
+	if _, err := crmqueue.EmitCrmIntelligenceRecalculateOneRequested(ctx, unifiedId, ownerOrgID); err != nil {
+		logger.GetAppLogger().WithError(err).WithField("unifiedId", unifiedId).Warn("[CRM] Không xếp được tính lại khách sau khi đổi thuộc tính")
+	}
+}
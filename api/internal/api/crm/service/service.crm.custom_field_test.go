@@ -0,0 +1,71 @@
+// Package crmvc - Test kiểm tra giá trị trường tùy chỉnh của khách.
+package crmvc
+
+import (
+	"testing"
+	"time"
+
+	crmmodels "meta_commerce/internal/api/crm/models"
+)
+
+func testCustomFieldDefs() map[string]crmmodels.CrmCustomFieldDefinition {
+	return map[string]crmmodels.CrmCustomFieldDefinition{
+		"skinType":      {Key: "skinType", Type: crmmodels.CrmCustomFieldTypeEnum, Options: []string{"Dầu", "Khô", "Hỗn hợp"}, Required: true},
+		"preferredSize": {Key: "preferredSize", Type: crmmodels.CrmCustomFieldTypeMultiEnum, Options: []string{"S", "M", "L"}},
+		"wholesale":     {Key: "wholesale", Type: crmmodels.CrmCustomFieldTypeBoolean},
+		"creditLimit":   {Key: "creditLimit", Type: crmmodels.CrmCustomFieldTypeNumber},
+		"firstVisit":    {Key: "firstVisit", Type: crmmodels.CrmCustomFieldTypeDate},
+		"note":          {Key: "note", Type: crmmodels.CrmCustomFieldTypeString},
+	}
+}
+
+func TestValidateCustomFieldValues_Normalizes(t *testing.T) {
+	set, unset, err := ValidateCustomFieldValues(testCustomFieldDefs(), map[string]interface{}{"note": "cũ"}, map[string]interface{}{
+		"skinType":      "khô",
+		"preferredSize": []interface{}{"m", "S", "M"},
+		"wholesale":     true,
+		"creditLimit":   float64(5000000),
+		"firstVisit":    "2026-10-01",
+		"note":          nil,
+	})
+	if err != nil {
+		t.Fatalf("giá trị hợp lệ bị từ chối: %v", err)
+	}
+	if set["customFields.skinType"] != "Khô" {
+		t.Errorf("enum phải về option gốc, got %v", set["customFields.skinType"])
+	}
+	sizes, _ := set["customFields.preferredSize"].([]string)
+	if len(sizes) != 2 || sizes[0] != "M" || sizes[1] != "S" {
+		t.Errorf("multi_enum phải chuẩn hoá và bỏ trùng, got %v", sizes)
+	}
+	ict := time.FixedZone("ICT", 7*3600)
+	if want := time.Date(2026, 10, 1, 0, 0, 0, 0, ict).UnixMilli(); set["customFields.firstVisit"] != want {
+		t.Errorf("date YYYY-MM-DD theo giờ Việt Nam, got %v want %d", set["customFields.firstVisit"], want)
+	}
+	if len(unset) != 1 || unset[0] != "note" {
+		t.Errorf("null phải xóa giá trị, got %v", unset)
+	}
+}
+
+func TestValidateCustomFieldValues_Rejects(t *testing.T) {
+	defs := testCustomFieldDefs()
+	has := map[string]interface{}{"skinType": "Dầu"}
+	invalid := map[string]struct {
+		current map[string]interface{}
+		values  map[string]interface{}
+	}{
+		"key chưa định nghĩa":     {has, map[string]interface{}{"height": 170.0}},
+		"enum ngoài options":      {has, map[string]interface{}{"skinType": "Nhạy cảm"}},
+		"multi_enum ngoài option": {has, map[string]interface{}{"preferredSize": []interface{}{"XL"}}},
+		"bool sai kiểu":           {has, map[string]interface{}{"wholesale": "true"}},
+		"number sai kiểu":         {has, map[string]interface{}{"creditLimit": "nhiều"}},
+		"date sai định dạng":      {has, map[string]interface{}{"firstVisit": "01/10/2026"}},
+		"thiếu trường bắt buộc":   {nil, map[string]interface{}{"wholesale": true}},
+		"xóa trường bắt buộc":     {has, map[string]interface{}{"skinType": nil}},
+	}
+	for name, tc := range invalid {
+		if _, _, err := ValidateCustomFieldValues(defs, tc.current, tc.values); err == nil {
+			t.Errorf("%s: phải trả lỗi", name)
+		}
+	}
+}
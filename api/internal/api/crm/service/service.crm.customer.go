@@ -156,6 +156,7 @@ func buildCustomerFilterByIdOrUid(idOrUid string, ownerOrgID primitive.ObjectID)
 		"$or": []bson.M{
 			{"uid": idOrUid},
 			{"unifiedId": idOrUid},
+			{"mergedFrom": idOrUid},
 		},
 	}
 }
@@ -199,6 +200,8 @@ func (s *CrmCustomerService) toProfileResponse(ctx context.Context, c *crmmodels
 		LastMessageFromCustomer:   GetBoolFromCustomer(c, "lastMessageFromCustomer"),
 		ConversationFromAds:       GetBoolFromCustomer(c, "conversationFromAds"),
 		ConversationTags:          c.ConversationTags,
+		Tags:                      c.Tags,
+		CustomFields:              c.CustomFields,
 		SourceIds: map[string]interface{}{
 			"pos":        c.SourceIds.Pos,
 			"fb":         c.SourceIds.Fb,
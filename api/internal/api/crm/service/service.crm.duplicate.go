@@ -0,0 +1,346 @@
+// Package crmvc — Tìm cặp khách nghi trùng (SĐT, email, tên gần giống, hội thoại chung) cho hàng đợi duyệt gộp thủ công.
+package crmvc
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	basesvc "meta_commerce/internal/api/base/service"
+	crmmodels "meta_commerce/internal/api/crm/models"
+	"meta_commerce/internal/common"
+	"meta_commerce/internal/global"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongoopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Trọng số chấm điểm cặp nghi trùng. Chỉ trùng tên không đủ vào hàng đợi (cần thêm SĐT/email/hội thoại chung).
+const (
+	crmDuplicateWeightPhone              = 0.5
+	crmDuplicateWeightEmail              = 0.3
+	crmDuplicateWeightName               = 0.2
+	crmDuplicateWeightSharedConversation = 0.3
+	// crmDuplicateNameThreshold — độ giống tên tối thiểu (0..1) để tính điểm tên.
+	crmDuplicateNameThreshold = 0.85
+	// crmDuplicateMaxBlockSize — nhóm cùng SĐT/email/tên lớn hơn ngưỡng này bị bỏ qua (SĐT tổng đài, tên chung chung).
+	crmDuplicateMaxBlockSize = 50
+)
+
+// CrmDuplicateMinScore điểm tối thiểu để cặp vào hàng đợi duyệt.
+const CrmDuplicateMinScore = 0.5
+
+// CrmDuplicateScore kết quả chấm một cặp khách.
+type CrmDuplicateScore struct {
+	Score               float64
+	Reasons             []string
+	NameSimilarity      float64
+	SharedConversations int
+}
+
+// ScoreDuplicatePair chấm điểm nghi trùng (0..1) cho hai khách: SĐT trùng, email trùng, tên gần giống, số hội thoại chung.
+func ScoreDuplicatePair(a, b *crmmodels.CrmCustomer, sharedConversations int) CrmDuplicateScore {
+	var out CrmDuplicateScore
+	if a == nil || b == nil {
+		return out
+	}
+	if intersects(normalizePhones(GetPhoneNumbersFromCustomer(a)), normalizePhones(GetPhoneNumbersFromCustomer(b))) {
+		out.Score += crmDuplicateWeightPhone
+		out.Reasons = append(out.Reasons, crmmodels.CrmDuplicateReasonPhone)
+	}
+	if intersects(normalizeEmails(GetEmailsFromCustomer(a)), normalizeEmails(GetEmailsFromCustomer(b))) {
+		out.Score += crmDuplicateWeightEmail
+		out.Reasons = append(out.Reasons, crmmodels.CrmDuplicateReasonEmail)
+	}
+	out.NameSimilarity = NameSimilarity(GetNameFromCustomer(a), GetNameFromCustomer(b))
+	if out.NameSimilarity >= crmDuplicateNameThreshold {
+		out.Score += crmDuplicateWeightName
+		out.Reasons = append(out.Reasons, crmmodels.CrmDuplicateReasonName)
+	}
+	if sharedConversations > 0 {
+		out.SharedConversations = sharedConversations
+		out.Score += crmDuplicateWeightSharedConversation
+		out.Reasons = append(out.Reasons, crmmodels.CrmDuplicateReasonSharedConversation)
+	}
+	out.Score = math.Round(math.Min(out.Score, 1)*100) / 100
+	out.NameSimilarity = math.Round(out.NameSimilarity*100) / 100
+	return out
+}
+
+// NameSimilarity độ giống hai tên (0..1) theo khoảng cách Levenshtein trên tên đã chuẩn hoá (bỏ dấu, chữ thường, gộp khoảng trắng).
+func NameSimilarity(a, b string) float64 {
+	ra, rb := []rune(normalizeCustomerName(a)), []rune(normalizeCustomerName(b))
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+	maxLen := len(ra)
+	if len(rb) > maxLen {
+		maxLen = len(rb)
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(maxLen)
+}
+
+// DuplicatePairKey khóa cặp không phụ thuộc thứ tự: unifiedId nhỏ|lớn.
+func DuplicatePairKey(a, b string) (key, first, second string) {
+	if b < a {
+		a, b = b, a
+	}
+	return a + "|" + b, a, b
+}
+
+var vietnameseFold = func() map[rune]rune {
+	groups := map[rune]string{
+		'a': "àáạảãâầấậẩẫăằắặẳẵ",
+		'e': "èéẹẻẽêềếệểễ",
+		'i': "ìíịỉĩ",
+		'o': "òóọỏõôồốộổỗơờớợởỡ",
+		'u': "ùúụủũưừứựửữ",
+		'y': "ỳýỵỷỹ",
+		'd': "đ",
+	}
+	m := make(map[rune]rune)
+	for base, chars := range groups {
+		for _, r := range chars {
+			m[r] = base
+		}
+	}
+	return m
+}()
+
+// normalizeCustomerName chữ thường, bỏ dấu tiếng Việt, gộp khoảng trắng.
+func normalizeCustomerName(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	var b strings.Builder
+	for _, r := range s {
+		if f, ok := vietnameseFold[r]; ok {
+			r = f
+		}
+		b.WriteRune(r)
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func normalizeEmails(emails []string) []string {
+	out := make([]string, 0, len(emails))
+	for _, e := range uniqueStrings(emails) {
+		out = append(out, strings.ToLower(e))
+	}
+	return out
+}
+
+func intersects(a, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return false
+	}
+	set := make(map[string]bool, len(a))
+	for _, v := range a {
+		set[v] = true
+	}
+	for _, v := range b {
+		if set[v] {
+			return true
+		}
+	}
+	return false
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// CrmDuplicateCandidateService hàng đợi cặp nghi trùng (customer_job_duplicate_candidates).
+type CrmDuplicateCandidateService struct {
+	*basesvc.BaseServiceMongoImpl[crmmodels.CrmDuplicateCandidate]
+	customerSvc *CrmCustomerService
+}
+
+// NewCrmDuplicateCandidateService tạo CrmDuplicateCandidateService mới.
+func NewCrmDuplicateCandidateService() (*CrmDuplicateCandidateService, error) {
+	coll, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.CustomerDuplicateCandidates)
+	if !exist {
+		return nil, fmt.Errorf("không tìm thấy collection %s: %w", global.MongoDB_ColNames.CustomerDuplicateCandidates, common.ErrNotFound)
+	}
+	customerSvc, err := NewCrmCustomerService()
+	if err != nil {
+		return nil, err
+	}
+	return &CrmDuplicateCandidateService{
+		BaseServiceMongoImpl: basesvc.NewBaseServiceMongo[crmmodels.CrmDuplicateCandidate](coll),
+		customerSvc:          customerSvc,
+	}, nil
+}
+
+// CrmDuplicateScanResult kết quả một lần quét nghi trùng.
+type CrmDuplicateScanResult struct {
+	CustomersScanned int `json:"customersScanned"`
+	PairsScored      int `json:"pairsScored"`
+	Candidates       int `json:"candidates"` // Số cặp đạt ngưỡng (đã upsert)
+}
+
+// ScanDuplicates quét khách trong tổ chức, ghép cặp theo nhóm cùng SĐT/email/tên chuẩn hoá, chấm điểm và upsert cặp
+// đạt minScore vào hàng đợi. Cặp đã dismissed/merged chỉ được cập nhật điểm, không mở lại. limit = 0: quét toàn bộ.
+func (s *CrmDuplicateCandidateService) ScanDuplicates(ctx context.Context, ownerOrgID primitive.ObjectID, minScore float64, limit int) (*CrmDuplicateScanResult, error) {
+	if minScore <= 0 {
+		minScore = CrmDuplicateMinScore
+	}
+	opts := mongoopts.Find().SetProjection(bson.M{
+		"unifiedId": 1, "uid": 1, "profile": 1, "name": 1, "phoneNumbers": 1, "emails": 1, "sourceIds": 1, "mergedFrom": 1,
+	}).SetSort(bson.M{"_id": 1})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	cursor, err := s.customerSvc.Collection().Find(ctx, bson.M{"ownerOrganizationId": ownerOrgID}, opts)
+	if err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	var customers []crmmodels.CrmCustomer
+	if err := cursor.All(ctx, &customers); err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	result := &CrmDuplicateScanResult{CustomersScanned: len(customers)}
+
+	pairs := buildDuplicatePairs(customers)
+	convCache := make(map[int]map[string]bool)
+	conversations := func(i int) map[string]bool {
+		if set, ok := convCache[i]; ok {
+			return set
+		}
+		set := s.customerConversationSet(ctx, &customers[i], ownerOrgID)
+		convCache[i] = set
+		return set
+	}
+	now := time.Now().UnixMilli()
+	for _, p := range pairs {
+		a, b := &customers[p[0]], &customers[p[1]]
+		shared := 0
+		setB := conversations(p[1])
+		for id := range conversations(p[0]) {
+			if setB[id] {
+				shared++
+			}
+		}
+		score := ScoreDuplicatePair(a, b, shared)
+		result.PairsScored++
+		if score.Score < minScore {
+			continue
+		}
+		key, first, second := DuplicatePairKey(a.UnifiedId, b.UnifiedId)
+		_, err := s.Collection().UpdateOne(ctx,
+			bson.M{"ownerOrganizationId": ownerOrgID, "pairKey": key},
+			bson.M{
+				"$set": bson.M{
+					"unifiedIdA": first, "unifiedIdB": second,
+					"score": score.Score, "reasons": score.Reasons,
+					"nameSimilarity": score.NameSimilarity, "sharedConversations": score.SharedConversations,
+					"updatedAt": now,
+				},
+				"$setOnInsert": bson.M{"status": crmmodels.CrmDuplicateStatusPending, "createdAt": now},
+			},
+			mongoopts.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return result, common.ConvertMongoError(err)
+		}
+		result.Candidates++
+	}
+	return result, nil
+}
+
+// buildDuplicatePairs ghép cặp (i<j) từ các nhóm cùng SĐT, email hoặc tên chuẩn hoá.
+func buildDuplicatePairs(customers []crmmodels.CrmCustomer) [][2]int {
+	blocks := make(map[string][]int)
+	for i := range customers {
+		c := &customers[i]
+		if c.UnifiedId == "" {
+			continue
+		}
+		keys := make(map[string]bool)
+		for _, p := range normalizePhones(GetPhoneNumbersFromCustomer(c)) {
+			keys["p:"+p] = true
+		}
+		for _, e := range normalizeEmails(GetEmailsFromCustomer(c)) {
+			keys["e:"+e] = true
+		}
+		if n := normalizeCustomerName(GetNameFromCustomer(c)); n != "" {
+			keys["n:"+n] = true
+		}
+		for k := range keys {
+			blocks[k] = append(blocks[k], i)
+		}
+	}
+	seen := make(map[[2]int]bool)
+	var pairs [][2]int
+	for _, members := range blocks {
+		if len(members) < 2 || len(members) > crmDuplicateMaxBlockSize {
+			continue
+		}
+		for x := 0; x < len(members); x++ {
+			for y := x + 1; y < len(members); y++ {
+				p := [2]int{members[x], members[y]}
+				if !seen[p] {
+					seen[p] = true
+					pairs = append(pairs, p)
+				}
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	return pairs
+}
+
+// customerConversationSet conversationId gắn với khách: hội thoại theo customerId nguồn + posData.fb_id của khách POS.
+func (s *CrmDuplicateCandidateService) customerConversationSet(ctx context.Context, c *crmmodels.CrmCustomer, ownerOrgID primitive.ObjectID) map[string]bool {
+	set := make(map[string]bool)
+	for _, id := range s.customerSvc.getConversationIdsFromFbMatch(ctx, buildCustomerIdsForQuery(c), ownerOrgID) {
+		set[id] = true
+	}
+	if c.SourceIds.Pos != "" {
+		for _, id := range s.customerSvc.getConversationIdsFromPosCustomers(ctx, []string{c.SourceIds.Pos}, ownerOrgID) {
+			set[id] = true
+		}
+	}
+	return set
+}
+
+// Dismiss đánh dấu cặp không phải trùng (chỉ cặp đang pending).
+func (s *CrmDuplicateCandidateService) Dismiss(ctx context.Context, ownerOrgID, id, reviewedBy primitive.ObjectID) error {
+	res, err := s.Collection().UpdateOne(ctx,
+		bson.M{"_id": id, "ownerOrganizationId": ownerOrgID, "status": crmmodels.CrmDuplicateStatusPending},
+		bson.M{"$set": bson.M{
+			"status": crmmodels.CrmDuplicateStatusDismissed, "reviewedBy": reviewedBy,
+			"reviewedAt": time.Now().UnixMilli(), "updatedAt": time.Now().UnixMilli(),
+		}},
+	)
+	if err != nil {
+		return common.ConvertMongoError(err)
+	}
+	if res.MatchedCount == 0 {
+		return common.NewError(common.ErrCodeDatabaseQuery, "Không tìm thấy cặp nghi trùng đang chờ duyệt", common.StatusNotFound, nil)
+	}
+	return nil
+}
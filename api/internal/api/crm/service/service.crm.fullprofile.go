@@ -146,6 +146,9 @@ func buildCustomerIdsForQuery(c *crmmodels.CrmCustomer) []string {
 	for _, v := range c.SourceIds.ZaloByPage {
 		add(v)
 	}
+	for _, id := range c.MergedFrom {
+		add(id)
+	}
 	return ids
 }
 
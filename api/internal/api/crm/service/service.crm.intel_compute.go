@@ -120,11 +120,42 @@ func RunCrmIntelComputeJob(ctx context.Context, job *crmmodels.CrmIntelComputeJo
 	}
 	if ran {
 		uid, _ := job.Payload["unifiedId"].(string)
+		evaluateCrmSegmentsAfterJob(ctx, op, uid, ownerOrgID)
 		_ = intelrecomputed.EmitCrmIntelRecomputed(ctx, ownerOrgID, job.ID.Hex(), job.ParentDecisionEventID, op, uid)
 	}
 	return nil
 }
 
+// evaluateCrmSegmentsAfterJob cập nhật thành viên phân khúc động sau job intel: một khách → đánh giá tăng dần;
+// recalculate toàn org / classification refresh định kỳ → refresh toàn bộ (làm mới điều kiện theo số ngày).
+// Batch/mismatch bỏ qua — chu kỳ classification refresh bù. Lỗi chỉ ghi log, không làm hỏng job intel.
+func evaluateCrmSegmentsAfterJob(ctx context.Context, op, unifiedId string, ownerOrgID primitive.ObjectID) {
+	var run func(segSvc *CrmSegmentService) error
+	switch op {
+	case crmqueue.CrmComputeOpRefresh, crmqueue.CrmComputeOpRecalculateOne:
+		run = func(segSvc *CrmSegmentService) error { return segSvc.EvaluateCustomer(ctx, ownerOrgID, unifiedId) }
+	case crmqueue.CrmComputeOpRecalculateAll:
+		run = func(segSvc *CrmSegmentService) error {
+			_, err := segSvc.RefreshOrgSegments(ctx, ownerOrgID)
+			return err
+		}
+	case crmqueue.CrmComputeOpRecalculateAllOrgs, crmqueue.CrmComputeOpClassificationRefresh:
+		run = func(segSvc *CrmSegmentService) error {
+			_, err := segSvc.RefreshAllSegments(ctx)
+			return err
+		}
+	default:
+		return
+	}
+	segSvc, err := NewCrmSegmentService()
+	if err == nil {
+		err = run(segSvc)
+	}
+	if err != nil {
+		logger.GetAppLogger().WithError(err).WithFields(map[string]interface{}{"operation": op, "unifiedId": unifiedId}).Warn("[CRM] Đánh giá phân khúc sau intel compute thất bại")
+	}
+}
+
 // runCrmIntelComputePayload chạy nghiệp vụ job; ran=true khi đã gọi tầng CRM thật sự (để emit crm_intel_recomputed).
 // batchStats != nil và multi=true: ghi một bản ghi lịch sử cho cả job (không cập nhật intelLastRunId từng khách).
 func runCrmIntelComputePayload(ctx context.Context, job *crmmodels.CrmIntelComputeJob, op string, svc *CrmCustomerService, ownerOrgID primitive.ObjectID) (ran bool, batchStats *crmIntelBatchStats, err error) {
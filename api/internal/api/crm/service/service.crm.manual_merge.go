@@ -0,0 +1,494 @@
+// Package crmvc — Gộp/tách khách thủ công: chuyển sourceIds, activity, ghi chú, liên kết đơn/hội thoại sang khách giữ lại,
+// lưu lịch sử (snapshot khách bị gộp) để tách lại được.
+package crmvc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	basesvc "meta_commerce/internal/api/base/service"
+	crmmodels "meta_commerce/internal/api/crm/models"
+	"meta_commerce/internal/common"
+	"meta_commerce/internal/global"
+	"meta_commerce/internal/logger"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongoopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CrmMergeHistoryService lịch sử gộp thủ công (customer_run_merge_history) và thao tác merge/unmerge.
+type CrmMergeHistoryService struct {
+	*basesvc.BaseServiceMongoImpl[crmmodels.CrmMergeHistory]
+	customerSvc  *CrmCustomerService
+	candidateSvc *CrmDuplicateCandidateService
+}
+
+// NewCrmMergeHistoryService tạo CrmMergeHistoryService mới.
+func NewCrmMergeHistoryService() (*CrmMergeHistoryService, error) {
+	coll, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.CustomerMergeHistory)
+	if !exist {
+		return nil, fmt.Errorf("không tìm thấy collection %s: %w", global.MongoDB_ColNames.CustomerMergeHistory, common.ErrNotFound)
+	}
+	candidateSvc, err := NewCrmDuplicateCandidateService()
+	if err != nil {
+		return nil, err
+	}
+	return &CrmMergeHistoryService{
+		BaseServiceMongoImpl: basesvc.NewBaseServiceMongo[crmmodels.CrmMergeHistory](coll),
+		customerSvc:          candidateSvc.customerSvc,
+		candidateSvc:         candidateSvc,
+	}, nil
+}
+
+// MergeCustomersInput tham số gộp: khách MergedId bị gộp vào SurvivorId (khách giữ lại).
+type MergeCustomersInput struct {
+	SurvivorId  string
+	MergedId    string
+	CandidateID primitive.ObjectID // Cặp trong hàng đợi nghi trùng (tuỳ chọn) — đánh dấu merged
+	Reason      string
+	By          primitive.ObjectID
+}
+
+// crmMergePlan kết quả tính toán trước khi ghi: sourceIds mới của khách giữ lại và các tham chiếu cần chuyển.
+type crmMergePlan struct {
+	SourceIds       crmmodels.CrmCustomerSourceIds
+	AddedSourceIds  []string
+	AddedMergedFrom []string
+	AddedTags       []string
+	AddedFields     map[string]interface{} // customFields khách giữ lại chưa có
+	Refs            []crmmodels.CrmMergeMovedRef
+}
+
+// planCustomerMerge gộp sourceIds (khách giữ lại ưu tiên; page/nguồn còn trống lấy từ khách bị gộp),
+// mọi id của khách bị gộp không nằm trong sourceIds mới đưa vào mergedFrom để vẫn resolve về khách giữ lại.
+// Tag hợp lại; trường tùy chỉnh khách giữ lại chưa có thì lấy từ khách bị gộp.
+func planCustomerMerge(survivor, merged *crmmodels.CrmCustomer) crmMergePlan {
+	out := copySourceIds(&survivor.SourceIds)
+	src := &merged.SourceIds
+	if out.Pos == "" {
+		out.Pos = src.Pos
+	}
+	if out.Fb == "" {
+		out.Fb = src.Fb
+	}
+	if out.Zalo == "" {
+		out.Zalo = src.Zalo
+	}
+	for page, id := range src.FbByPage {
+		if _, ok := out.FbByPage[page]; !ok {
+			if out.FbByPage == nil {
+				out.FbByPage = make(map[string]string)
+			}
+			out.FbByPage[page] = id
+		}
+	}
+	for page, id := range src.ZaloByPage {
+		if _, ok := out.ZaloByPage[page]; !ok {
+			if out.ZaloByPage == nil {
+				out.ZaloByPage = make(map[string]string)
+			}
+			out.ZaloByPage[page] = id
+		}
+	}
+	out.AllInboxIds = uniqueStrings(append(append([]string(nil), survivor.SourceIds.AllInboxIds...), buildAllInboxIds(&out)...))
+	sort.Strings(out.AllInboxIds)
+
+	before := make(map[string]bool)
+	for _, id := range sourceIdList(&survivor.SourceIds) {
+		before[id] = true
+	}
+	after := make(map[string]bool)
+	for _, id := range sourceIdList(&out) {
+		after[id] = true
+	}
+	known := map[string]bool{survivor.UnifiedId: true, survivor.Uid: true}
+	for _, id := range survivor.MergedFrom {
+		known[id] = true
+	}
+
+	plan := crmMergePlan{SourceIds: out}
+	for _, id := range sourceIdList(src) {
+		if after[id] && !before[id] {
+			plan.AddedSourceIds = append(plan.AddedSourceIds, id)
+		}
+	}
+	candidates := append([]string{merged.UnifiedId, merged.Uid}, merged.MergedFrom...)
+	candidates = append(candidates, sourceIdList(src)...)
+	for _, id := range uniqueStrings(candidates) {
+		if !after[id] && !known[id] {
+			plan.AddedMergedFrom = append(plan.AddedMergedFrom, id)
+		}
+	}
+
+	for _, tag := range merged.Tags {
+		if !contains(survivor.Tags, tag) && !contains(plan.AddedTags, tag) {
+			plan.AddedTags = append(plan.AddedTags, tag)
+		}
+	}
+	for key, v := range merged.CustomFields {
+		if _, ok := survivor.CustomFields[key]; !ok {
+			if plan.AddedFields == nil {
+				plan.AddedFields = make(map[string]interface{})
+			}
+			plan.AddedFields[key] = v
+		}
+	}
+
+	refs := []crmmodels.CrmMergeMovedRef{
+		{Collection: global.MongoDB_ColNames.CustomerActivityHistory, Field: "unifiedId", From: merged.UnifiedId, To: survivor.UnifiedId},
+		{Collection: global.MongoDB_ColNames.CustomerNotes, Field: "customerId", From: merged.UnifiedId, To: survivor.UnifiedId},
+		{Collection: global.MongoDB_ColNames.CustomerNotes, Field: "links.customer.uid", From: merged.Uid, To: survivor.Uid},
+		{Collection: global.MongoDB_ColNames.OrderCanonical, Field: "links.customer.uid", From: merged.Uid, To: survivor.Uid},
+		{Collection: global.MongoDB_ColNames.PcPosOrders, Field: "links.customer.uid", From: merged.Uid, To: survivor.Uid},
+		{Collection: global.MongoDB_ColNames.FbConvesations, Field: "links.customer.uid", From: merged.Uid, To: survivor.Uid},
+	}
+	for _, r := range refs {
+		if r.Collection != "" && r.From != "" && r.To != "" && r.From != r.To {
+			plan.Refs = append(plan.Refs, r)
+		}
+	}
+	return plan
+}
+
+// sortedMapKeys key của map theo thứ tự (lưu lịch sử gộp ổn định).
+func sortedMapKeys(m map[string]interface{}) []string {
+	if len(m) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// revertMergedAttributes update trả khách giữ lại về trước khi gộp: set (sourceIds...), bỏ mergedFrom / tag đã thêm,
+// xóa trường tùy chỉnh lấy từ khách bị gộp.
+func revertMergedAttributes(set bson.M, addedMergedFrom, addedTags, addedCustomFields []string) bson.M {
+	update := bson.M{"$set": set}
+	pull := bson.M{}
+	if len(addedMergedFrom) > 0 {
+		pull["mergedFrom"] = bson.M{"$in": addedMergedFrom}
+	}
+	if len(addedTags) > 0 {
+		pull["tags"] = bson.M{"$in": addedTags}
+	}
+	if len(pull) > 0 {
+		update["$pull"] = pull
+	}
+	if len(addedCustomFields) > 0 {
+		unset := bson.M{}
+		for _, key := range addedCustomFields {
+			unset["customFields."+key] = ""
+		}
+		update["$unset"] = unset
+	}
+	return update
+}
+
+// removeSourceIds bỏ các customerId nguồn đã thêm khi gộp (unmerge). Primary Fb/Zalo bị bỏ thì lấy lại từ page còn lại.
+func removeSourceIds(cur *crmmodels.CrmCustomerSourceIds, removed []string) crmmodels.CrmCustomerSourceIds {
+	out := copySourceIds(cur)
+	drop := make(map[string]bool, len(removed))
+	for _, id := range removed {
+		drop[id] = true
+	}
+	if drop[out.Pos] {
+		out.Pos = ""
+	}
+	for page, id := range out.FbByPage {
+		if drop[id] {
+			delete(out.FbByPage, page)
+		}
+	}
+	for page, id := range out.ZaloByPage {
+		if drop[id] {
+			delete(out.ZaloByPage, page)
+		}
+	}
+	if drop[out.Fb] {
+		out.Fb = firstByPage(out.FbByPage)
+	}
+	if drop[out.Zalo] {
+		out.Zalo = firstByPage(out.ZaloByPage)
+	}
+	var inbox []string
+	for _, id := range out.AllInboxIds {
+		if !drop[id] {
+			inbox = append(inbox, id)
+		}
+	}
+	out.AllInboxIds = inbox
+	return out
+}
+
+func copySourceIds(src *crmmodels.CrmCustomerSourceIds) crmmodels.CrmCustomerSourceIds {
+	out := crmmodels.CrmCustomerSourceIds{Pos: src.Pos, Fb: src.Fb, Zalo: src.Zalo}
+	if len(src.FbByPage) > 0 {
+		out.FbByPage = make(map[string]string, len(src.FbByPage))
+		for k, v := range src.FbByPage {
+			out.FbByPage[k] = v
+		}
+	}
+	if len(src.ZaloByPage) > 0 {
+		out.ZaloByPage = make(map[string]string, len(src.ZaloByPage))
+		for k, v := range src.ZaloByPage {
+			out.ZaloByPage[k] = v
+		}
+	}
+	out.AllInboxIds = append([]string(nil), src.AllInboxIds...)
+	return out
+}
+
+// sourceIdList mọi customerId nguồn của khách (thứ tự ổn định).
+func sourceIdList(s *crmmodels.CrmCustomerSourceIds) []string {
+	ids := []string{s.Pos, s.Fb, s.Zalo}
+	for _, m := range []map[string]string{s.FbByPage, s.ZaloByPage} {
+		pages := make([]string, 0, len(m))
+		for page := range m {
+			pages = append(pages, page)
+		}
+		sort.Strings(pages)
+		for _, page := range pages {
+			ids = append(ids, m[page])
+		}
+	}
+	ids = append(ids, s.AllInboxIds...)
+	return uniqueStrings(ids)
+}
+
+func firstByPage(m map[string]string) string {
+	pages := make([]string, 0, len(m))
+	for page := range m {
+		pages = append(pages, page)
+	}
+	sort.Strings(pages)
+	if len(pages) == 0 {
+		return ""
+	}
+	return m[pages[0]]
+}
+
+// MergeCustomers gộp khách MergedId vào SurvivorId: chuyển sourceIds, activity, ghi chú, liên kết đơn/hội thoại;
+// xóa khách bị gộp (snapshot lưu trong lịch sử); unifiedId/customerId cũ resolve về khách giữ lại qua mergedFrom;
+// tính lại metrics khách giữ lại.
+func (s *CrmMergeHistoryService) MergeCustomers(ctx context.Context, ownerOrgID primitive.ObjectID, input MergeCustomersInput) (*crmmodels.CrmMergeHistory, error) {
+	survivor, err := s.customerSvc.FindOne(ctx, buildCustomerFilterByIdOrUid(input.SurvivorId, ownerOrgID), nil)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := s.customerSvc.FindOne(ctx, buildCustomerFilterByIdOrUid(input.MergedId, ownerOrgID), nil)
+	if err != nil {
+		return nil, err
+	}
+	if survivor.ID == merged.ID {
+		return nil, common.NewError(common.ErrCodeBusinessState, "Hai khách đã là cùng một khách", common.StatusBadRequest, nil)
+	}
+	var snapshot bson.M
+	if err := s.customerSvc.Collection().FindOne(ctx, bson.M{"_id": merged.ID}).Decode(&snapshot); err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+
+	plan := planCustomerMerge(&survivor, &merged)
+	now := time.Now().UnixMilli()
+	history := crmmodels.CrmMergeHistory{
+		SurvivorUnifiedId:   survivor.UnifiedId,
+		SurvivorUid:         survivor.Uid,
+		MergedUnifiedId:     merged.UnifiedId,
+		MergedUid:           merged.Uid,
+		MergedCustomer:      snapshot,
+		AddedSourceIds:      plan.AddedSourceIds,
+		AddedMergedFrom:     plan.AddedMergedFrom,
+		AddedTags:           plan.AddedTags,
+		AddedCustomFields:   sortedMapKeys(plan.AddedFields),
+		CandidateID:         input.CandidateID,
+		Reason:              input.Reason,
+		MergedBy:            input.By,
+		MergedAt:            now,
+		OwnerOrganizationID: ownerOrgID,
+	}
+	// Ghi lịch sử trước — snapshot không mất nếu các bước sau lỗi giữa chừng.
+	history, err = s.InsertOne(ctx, history)
+	if err != nil {
+		return nil, err
+	}
+
+	moved, err := moveMergeRefs(ctx, ownerOrgID, plan.Refs)
+	if err == nil {
+		set := bson.M{"sourceIds": plan.SourceIds, "mergeMethod": "manual", "mergedAt": now, "updatedAt": now}
+		for key, v := range plan.AddedFields {
+			set["customFields."+key] = v
+		}
+		update := bson.M{"$set": set}
+		addToSet := bson.M{}
+		if len(plan.AddedMergedFrom) > 0 {
+			addToSet["mergedFrom"] = bson.M{"$each": plan.AddedMergedFrom}
+		}
+		if len(plan.AddedTags) > 0 {
+			addToSet["tags"] = bson.M{"$each": plan.AddedTags}
+		}
+		if len(addToSet) > 0 {
+			update["$addToSet"] = addToSet
+		}
+		_, err = s.customerSvc.Collection().UpdateOne(ctx, bson.M{"_id": survivor.ID}, update)
+	}
+	if err == nil {
+		_, err = s.customerSvc.Collection().DeleteOne(ctx, bson.M{"_id": merged.ID})
+	}
+	if err != nil {
+		// Hoàn tác tham chiếu đã chuyển, khách giữ lại giữ nguyên sourceIds cũ
+		revertMergeRefs(ctx, moved)
+		_, _ = s.customerSvc.Collection().UpdateOne(ctx, bson.M{"_id": survivor.ID}, revertMergedAttributes(
+			bson.M{"sourceIds": survivor.SourceIds}, plan.AddedMergedFrom, plan.AddedTags, history.AddedCustomFields,
+		))
+		_ = s.DeleteById(ctx, history.ID)
+		return nil, common.ConvertMongoError(err)
+	}
+	history.MovedRefs = moved
+	if _, errUpd := s.Collection().UpdateOne(ctx, bson.M{"_id": history.ID}, bson.M{"$set": bson.M{"movedRefs": moved}}); errUpd != nil {
+		logger.GetAppLogger().WithError(errUpd).WithField("historyId", history.ID.Hex()).Warn("📋 [CRM_MERGE] Không ghi được movedRefs")
+	}
+
+	s.updateCandidatesAfterMerge(ctx, ownerOrgID, &history, input.By)
+	s.recalculate(ctx, survivor.UnifiedId, ownerOrgID)
+	return &history, nil
+}
+
+// UnmergeCustomers tách một lần gộp: chèn lại khách bị gộp từ snapshot, bỏ sourceIds/mergedFrom đã thêm vào khách giữ lại,
+// trả activity/ghi chú/liên kết đơn về khách cũ, tính lại metrics cả hai. historyID zero: lấy lần gộp gần nhất
+// của mergedUnifiedId vào khách giữ lại.
+func (s *CrmMergeHistoryService) UnmergeCustomers(ctx context.Context, ownerOrgID primitive.ObjectID, survivorId string, historyID primitive.ObjectID, mergedUnifiedId string, by primitive.ObjectID) (*crmmodels.CrmMergeHistory, error) {
+	survivor, err := s.customerSvc.FindOne(ctx, buildCustomerFilterByIdOrUid(survivorId, ownerOrgID), nil)
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.M{"ownerOrganizationId": ownerOrgID, "survivorUnifiedId": survivor.UnifiedId, "unmergedAt": 0}
+	if !historyID.IsZero() {
+		filter["_id"] = historyID
+	} else if mergedUnifiedId != "" {
+		filter["mergedUnifiedId"] = mergedUnifiedId
+	} else {
+		return nil, common.NewError(common.ErrCodeValidationInput, "Cần mergeHistoryId hoặc mergedUnifiedId", common.StatusBadRequest, nil)
+	}
+	history, err := s.FindOne(ctx, filter, mongoopts.FindOne().SetSort(bson.M{"mergedAt": -1}))
+	if err != nil {
+		if errors.Is(err, common.ErrNotFound) {
+			return nil, common.NewError(common.ErrCodeDatabaseQuery, "Không tìm thấy lần gộp chưa tách của khách này", common.StatusNotFound, nil)
+		}
+		return nil, err
+	}
+	exists, err := s.customerSvc.DocumentExists(ctx, bson.M{"ownerOrganizationId": ownerOrgID, "unifiedId": history.MergedUnifiedId})
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, common.NewError(common.ErrCodeBusinessState, "Đã có khách mang unifiedId "+history.MergedUnifiedId+" — không thể tách", common.StatusConflict, nil)
+	}
+
+	now := time.Now().UnixMilli()
+	update := revertMergedAttributes(
+		bson.M{"sourceIds": removeSourceIds(&survivor.SourceIds, history.AddedSourceIds), "updatedAt": now},
+		history.AddedMergedFrom, history.AddedTags, history.AddedCustomFields,
+	)
+	// Chèn lại khách bị gộp trước: chèn lỗi (trùng unifiedId...) thì khách giữ lại chưa bị đổi
+	if _, err := s.customerSvc.Collection().InsertOne(ctx, history.MergedCustomer); err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	if _, err := s.customerSvc.Collection().UpdateOne(ctx, bson.M{"_id": survivor.ID}, update); err != nil {
+		// Hoàn tác: bỏ khách vừa chèn lại, lần gộp vẫn chưa tách
+		_, _ = s.customerSvc.Collection().DeleteOne(ctx, bson.M{"_id": history.MergedCustomer["_id"]})
+		return nil, common.ConvertMongoError(err)
+	}
+	revertMergeRefs(ctx, history.MovedRefs)
+
+	history.UnmergedAt = now
+	history.UnmergedBy = by
+	if _, err := s.Collection().UpdateOne(ctx, bson.M{"_id": history.ID}, bson.M{"$set": bson.M{"unmergedAt": now, "unmergedBy": by}}); err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	if !history.CandidateID.IsZero() {
+		_, _ = s.candidateSvc.Collection().UpdateOne(ctx, bson.M{"_id": history.CandidateID, "ownerOrganizationId": ownerOrgID}, bson.M{
+			"$set": bson.M{"status": crmmodels.CrmDuplicateStatusDismissed, "reviewedBy": by, "reviewedAt": now, "updatedAt": now},
+		})
+	}
+	s.recalculate(ctx, survivor.UnifiedId, ownerOrgID)
+	s.recalculate(ctx, history.MergedUnifiedId, ownerOrgID)
+	return &history, nil
+}
+
+// updateCandidatesAfterMerge đánh dấu cặp được duyệt là merged; bỏ các cặp pending khác của khách bị gộp (lần quét sau
+// ghép lại theo khách giữ lại).
+func (s *CrmMergeHistoryService) updateCandidatesAfterMerge(ctx context.Context, ownerOrgID primitive.ObjectID, history *crmmodels.CrmMergeHistory, by primitive.ObjectID) {
+	now := time.Now().UnixMilli()
+	if !history.CandidateID.IsZero() {
+		_, _ = s.candidateSvc.Collection().UpdateOne(ctx, bson.M{"_id": history.CandidateID, "ownerOrganizationId": ownerOrgID}, bson.M{
+			"$set": bson.M{"status": crmmodels.CrmDuplicateStatusMerged, "mergeHistoryId": history.ID, "reviewedBy": by, "reviewedAt": now, "updatedAt": now},
+		})
+	}
+	_, _ = s.candidateSvc.Collection().DeleteMany(ctx, bson.M{
+		"ownerOrganizationId": ownerOrgID,
+		"status":              crmmodels.CrmDuplicateStatusPending,
+		"$or": []bson.M{
+			{"unifiedIdA": history.MergedUnifiedId},
+			{"unifiedIdB": history.MergedUnifiedId},
+		},
+	})
+}
+
+// recalculate tính lại profile + metrics; lỗi chỉ log (merge/unmerge đã ghi xong).
+func (s *CrmMergeHistoryService) recalculate(ctx context.Context, unifiedId string, ownerOrgID primitive.ObjectID) {
+	if _, err := s.customerSvc.RecalculateCustomerFromAllSources(ctx, unifiedId, ownerOrgID); err != nil {
+		logger.GetAppLogger().WithError(err).WithField("unifiedId", unifiedId).Warn("📋 [CRM_MERGE] Recalculate sau merge/unmerge thất bại")
+	}
+}
+
+// moveMergeRefs đổi Field từ From sang To trên từng collection, ghi lại _id đã đổi để unmerge.
+func moveMergeRefs(ctx context.Context, ownerOrgID primitive.ObjectID, refs []crmmodels.CrmMergeMovedRef) ([]crmmodels.CrmMergeMovedRef, error) {
+	var moved []crmmodels.CrmMergeMovedRef
+	for _, ref := range refs {
+		coll, ok := global.RegistryCollections.Get(ref.Collection)
+		if !ok {
+			continue
+		}
+		cursor, err := coll.Find(ctx, bson.M{"ownerOrganizationId": ownerOrgID, ref.Field: ref.From}, mongoopts.Find().SetProjection(bson.M{"_id": 1}))
+		if err != nil {
+			return moved, err
+		}
+		var docs []struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.All(ctx, &docs); err != nil {
+			return moved, err
+		}
+		if len(docs) == 0 {
+			continue
+		}
+		ref.IDs = make([]primitive.ObjectID, 0, len(docs))
+		for _, d := range docs {
+			ref.IDs = append(ref.IDs, d.ID)
+		}
+		if _, err := coll.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ref.IDs}}, bson.M{"$set": bson.M{ref.Field: ref.To}}); err != nil {
+			return moved, err
+		}
+		moved = append(moved, ref)
+	}
+	return moved, nil
+}
+
+// revertMergeRefs trả Field về From cho các document vẫn còn giá trị To (document đã bị gán lại sau merge giữ nguyên).
+func revertMergeRefs(ctx context.Context, refs []crmmodels.CrmMergeMovedRef) {
+	for _, ref := range refs {
+		coll, ok := global.RegistryCollections.Get(ref.Collection)
+		if !ok || len(ref.IDs) == 0 {
+			continue
+		}
+		if _, err := coll.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ref.IDs}, ref.Field: ref.To}, bson.M{"$set": bson.M{ref.Field: ref.From}}); err != nil {
+			logger.GetAppLogger().WithError(err).WithField("collection", ref.Collection).Warn("📋 [CRM_MERGE] Trả tham chiếu về khách cũ thất bại")
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Package crmvc - Test chấm điểm nghi trùng và kế hoạch gộp/tách thủ công.
+package crmvc
+
+import (
+	"reflect"
+	"testing"
+
+	crmmodels "meta_commerce/internal/api/crm/models"
+	"meta_commerce/internal/global"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestScoreDuplicatePair(t *testing.T) {
+	a := &crmmodels.CrmCustomer{Profile: crmmodels.CrmCustomerProfile{Name: "Nguyễn Văn An", PhoneNumbers: []string{"0901 234 567"}}}
+	b := &crmmodels.CrmCustomer{Profile: crmmodels.CrmCustomerProfile{Name: "nguyen van an", PhoneNumbers: []string{"84901234567"}, Emails: []string{"an@x.vn"}}}
+
+	got := ScoreDuplicatePair(a, b, 0)
+	if got.Score != 0.7 || !reflect.DeepEqual(got.Reasons, []string{"phone", "name"}) || got.NameSimilarity != 1 {
+		t.Fatalf("SĐT (chuẩn hoá) + tên bỏ dấu: %+v", got)
+	}
+
+	c := &crmmodels.CrmCustomer{Profile: crmmodels.CrmCustomerProfile{Name: "Nguyen Van Ann"}}
+	if got := ScoreDuplicatePair(a, c, 0); got.Score >= CrmDuplicateMinScore {
+		t.Fatalf("chỉ giống tên không được vào hàng đợi: %+v", got)
+	}
+	if got := ScoreDuplicatePair(a, c, 2); got.Score < CrmDuplicateMinScore || got.SharedConversations != 2 {
+		t.Fatalf("tên + hội thoại chung phải vào hàng đợi: %+v", got)
+	}
+
+	d := &crmmodels.CrmCustomer{Profile: crmmodels.CrmCustomerProfile{Name: "Trần Thị Bình", PhoneNumbers: []string{"0901234567"}, Emails: []string{"AN@x.vn"}}}
+	if got := ScoreDuplicatePair(b, d, 1); got.Score != 1 {
+		t.Fatalf("điểm tối đa 1: %+v", got)
+	}
+}
+
+func TestBuildDuplicatePairs_SkipsLargeBlocks(t *testing.T) {
+	customers := []crmmodels.CrmCustomer{
+		{UnifiedId: "u1", Profile: crmmodels.CrmCustomerProfile{PhoneNumbers: []string{"0901234567"}}},
+		{UnifiedId: "u2", Profile: crmmodels.CrmCustomerProfile{Emails: []string{"x@y.vn"}, PhoneNumbers: []string{"84901234567"}}},
+		{UnifiedId: "u3", Profile: crmmodels.CrmCustomerProfile{Emails: []string{"X@y.vn"}}},
+	}
+	for i := 0; i <= crmDuplicateMaxBlockSize; i++ {
+		customers = append(customers, crmmodels.CrmCustomer{UnifiedId: "bulk", Profile: crmmodels.CrmCustomerProfile{Name: "Khách lẻ"}})
+	}
+	got := buildDuplicatePairs(customers)
+	want := [][2]int{{0, 1}, {1, 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("pairs = %v, want %v", got, want)
+	}
+}
+
+func TestPlanCustomerMerge_AndRemoveSourceIds(t *testing.T) {
+	prev := global.MongoDB_ColNames
+	t.Cleanup(func() { global.MongoDB_ColNames = prev })
+	global.MongoDB_ColNames.CustomerActivityHistory = "activity"
+	global.MongoDB_ColNames.CustomerNotes = "notes"
+	global.MongoDB_ColNames.OrderCanonical = "orders"
+
+	survivor := &crmmodels.CrmCustomer{
+		UnifiedId: "pos-1", Uid: "cust_1",
+		SourceIds: crmmodels.CrmCustomerSourceIds{Pos: "pos-1", Fb: "fb-1", FbByPage: map[string]string{"p1": "fb-1"}, AllInboxIds: []string{"fb-1"}},
+	}
+	merged := &crmmodels.CrmCustomer{
+		UnifiedId: "pos-2", Uid: "cust_2", MergedFrom: []string{"old-3"},
+		SourceIds: crmmodels.CrmCustomerSourceIds{Pos: "pos-2", Fb: "fb-2", FbByPage: map[string]string{"p1": "fb-9", "p2": "fb-2"}, Zalo: "zl-2", ZaloByPage: map[string]string{"pzl_1": "zl-2"}},
+	}
+	plan := planCustomerMerge(survivor, merged)
+
+	if plan.SourceIds.Pos != "pos-1" || plan.SourceIds.Fb != "fb-1" || plan.SourceIds.Zalo != "zl-2" {
+		t.Fatalf("khách giữ lại ưu tiên primary: %+v", plan.SourceIds)
+	}
+	if plan.SourceIds.FbByPage["p1"] != "fb-1" || plan.SourceIds.FbByPage["p2"] != "fb-2" {
+		t.Fatalf("page trùng giữ của khách giữ lại, page mới lấy từ khách bị gộp: %v", plan.SourceIds.FbByPage)
+	}
+	if !reflect.DeepEqual(plan.AddedSourceIds, []string{"fb-2", "zl-2"}) {
+		t.Fatalf("addedSourceIds = %v", plan.AddedSourceIds)
+	}
+	if !reflect.DeepEqual(plan.AddedMergedFrom, []string{"pos-2", "cust_2", "old-3", "fb-9"}) {
+		t.Fatalf("addedMergedFrom = %v", plan.AddedMergedFrom)
+	}
+	if len(plan.Refs) != 4 || plan.Refs[0].Field != "unifiedId" || plan.Refs[0].From != "pos-2" || plan.Refs[0].To != "pos-1" {
+		t.Fatalf("refs (bỏ collection chưa cấu hình) = %+v", plan.Refs)
+	}
+
+	restored := removeSourceIds(&plan.SourceIds, plan.AddedSourceIds)
+	if restored.Pos != "pos-1" || restored.Fb != "fb-1" || restored.Zalo != "" || len(restored.ZaloByPage) != 0 {
+		t.Fatalf("unmerge phải bỏ id đã thêm: %+v", restored)
+	}
+	if !reflect.DeepEqual(restored.FbByPage, map[string]string{"p1": "fb-1"}) || !reflect.DeepEqual(restored.AllInboxIds, []string{"fb-1"}) {
+		t.Fatalf("unmerge byPage/allInboxIds: %+v", restored)
+	}
+}
+
+func TestPlanCustomerMerge_TagsAndCustomFields(t *testing.T) {
+	survivor := &crmmodels.CrmCustomer{UnifiedId: "s", Tags: []string{"VIP"}, CustomFields: map[string]interface{}{"skinType": "Dầu"}}
+	merged := &crmmodels.CrmCustomer{UnifiedId: "m", Tags: []string{"VIP", "Sỉ"}, CustomFields: map[string]interface{}{"skinType": "Khô", "wholesale": true}}
+	plan := planCustomerMerge(survivor, merged)
+	if !reflect.DeepEqual(plan.AddedTags, []string{"Sỉ"}) {
+		t.Errorf("AddedTags = %v, want [Sỉ]", plan.AddedTags)
+	}
+	if !reflect.DeepEqual(plan.AddedFields, map[string]interface{}{"wholesale": true}) {
+		t.Errorf("AddedFields = %v — khách giữ lại ưu tiên, chỉ lấy key còn thiếu", plan.AddedFields)
+	}
+
+	update := revertMergedAttributes(bson.M{}, nil, plan.AddedTags, sortedMapKeys(plan.AddedFields))
+	if !reflect.DeepEqual(update["$unset"], bson.M{"customFields.wholesale": ""}) {
+		t.Errorf("unmerge phải xóa trường đã thêm, got %v", update["$unset"])
+	}
+	if _, ok := update["$pull"].(bson.M)["tags"]; !ok {
+		t.Errorf("unmerge phải gỡ tag đã thêm, got %v", update["$pull"])
+	}
+}
@@ -176,6 +176,10 @@ func (s *CrmCustomerService) MergeFromPosCustomer(ctx context.Context, doc *pcmo
 	for k, v := range class {
 		setFields[k] = v
 	}
+	if s.isCustomerErased(ctx, existing, unifiedId, ownerOrgID) {
+		omitProfileFields(setFields)
+		name = crmmodels.CrmPrivacyErasedName
+	}
 	unsetAll := make(bson.M)
 	for k, v := range unsetRawFields {
 		unsetAll[k] = v
@@ -380,6 +384,10 @@ func (s *CrmCustomerService) MergeFromFbCustomer(ctx context.Context, doc *fbmod
 	for k, v := range fbClass {
 		fbSetFields[k] = v
 	}
+	if s.isCustomerErased(ctx, existing, unifiedId, ownerOrgID) {
+		omitProfileFields(fbSetFields)
+		name = crmmodels.CrmPrivacyErasedName
+	}
 	fbUnsetAll := make(bson.M)
 	for k, v := range unsetRawFields {
 		fbUnsetAll[k] = v
@@ -614,6 +622,9 @@ func (s *CrmCustomerService) MergeProfileFromOrder(ctx context.Context, unifiedI
 			return
 		}
 	}
+	if existing.ErasedAt > 0 {
+		return // Khách đã ẩn danh hoá — không fill lại PII
+	}
 	setFields := bson.M{}
 	if GetNameFromCustomer(&existing) == "" && custData.Name != "" {
 		setFields["profile.name"] = custData.Name
@@ -669,6 +680,9 @@ func (s *CrmCustomerService) MergeProfileFromConversation(ctx context.Context, u
 			return
 		}
 	}
+	if existing.ErasedAt > 0 {
+		return // Khách đã ẩn danh hoá — không fill lại PII
+	}
 	setFields := bson.M{}
 	if GetNameFromCustomer(&existing) == "" && custData.Name != "" {
 		setFields["profile.name"] = custData.Name
@@ -706,6 +720,25 @@ func (s *CrmCustomerService) MergeProfileFromConversation(ctx context.Context, u
 	}
 }
 
+// isCustomerErased khách unifiedId đã ẩn danh hoá (erasedAt > 0, yêu cầu xóa dữ liệu cá nhân).
+// Dùng existing khi đúng khách; ngược lại (chưa tìm thấy theo id nguồn, link qua SĐT) đọc crm_customers.
+func (s *CrmCustomerService) isCustomerErased(ctx context.Context, existing *crmmodels.CrmCustomer, unifiedId string, ownerOrgID primitive.ObjectID) bool {
+	if existing != nil && existing.UnifiedId == unifiedId {
+		return existing.ErasedAt > 0
+	}
+	c, err := s.FindOne(ctx, bson.M{"unifiedId": unifiedId, "ownerOrganizationId": ownerOrgID}, mongoopts.FindOne().SetProjection(bson.M{"erasedAt": 1}))
+	return err == nil && c.ErasedAt > 0
+}
+
+// omitProfileFields bỏ "profile" / "profile.*" khỏi $set — sync, rebuild, merge không ghi lại PII cho khách đã ẩn danh hoá.
+func omitProfileFields(setFields bson.M) {
+	for k := range setFields {
+		if k == "profile" || strings.HasPrefix(k, "profile.") {
+			delete(setFields, k)
+		}
+	}
+}
+
 // applyMergeToCustomer áp dụng các field đã merge vào customer in-memory (fill gaps).
 func applyMergeToCustomer(c *crmmodels.CrmCustomer, custData convCustomerData) {
 	if GetNameFromCustomer(c) == "" && custData.Name != "" {
@@ -810,6 +843,7 @@ func (s *CrmCustomerService) findByPosId(ctx context.Context, posId string, owne
 			{"sourceIds.pos": posId},
 			{"uid": posId},
 			{"unifiedId": posId},
+			{"mergedFrom": posId},
 		},
 	}
 	c, err := s.FindOne(ctx, filter, nil)
@@ -828,6 +862,7 @@ func (s *CrmCustomerService) findByFbId(ctx context.Context, fbId string, ownerO
 			{"sourceIds.allInboxIds": fbId},
 			{"uid": fbId},
 			{"unifiedId": fbId},
+			{"mergedFrom": fbId},
 		},
 	}
 	c, err := s.FindOne(ctx, filter, nil)
@@ -846,6 +881,7 @@ func (s *CrmCustomerService) findByZaloId(ctx context.Context, zaloId string, ow
 			{"sourceIds.allInboxIds": zaloId},
 			{"uid": zaloId},
 			{"unifiedId": zaloId},
+			{"mergedFrom": zaloId},
 		},
 	}
 	c, err := s.FindOne(ctx, filter, nil)
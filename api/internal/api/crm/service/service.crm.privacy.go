@@ -0,0 +1,492 @@
+// Package crmvc — Yêu cầu dữ liệu cá nhân của khách: export toàn bộ dữ liệu (JSON/ZIP) và xóa (ẩn danh hoá PII)
+// trên mọi collection liên kết. Chạy bằng crm_bulk_jobs (data_export / data_erasure), có progress để resume.
+package crmvc
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	basesvc "meta_commerce/internal/api/base/service"
+	crmmodels "meta_commerce/internal/api/crm/models"
+	"meta_commerce/internal/common"
+	"meta_commerce/internal/global"
+	"meta_commerce/internal/logger"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongoopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	crmPrivacyBatchSize       = 200 // Số document mỗi batch — progress lưu sau mỗi batch
+	crmPrivacyMaxSubjectMatch = 20  // Tra theo SĐT/email khớp quá số khách này → từ chối (SĐT tổng đài, email chung)
+)
+
+// CrmPrivacyRequestService tạo và thực thi yêu cầu export / xóa dữ liệu khách.
+type CrmPrivacyRequestService struct {
+	*basesvc.BaseServiceMongoImpl[crmmodels.CrmPrivacyRequest]
+	itemSvc     *basesvc.BaseServiceMongoImpl[crmmodels.CrmPrivacyExportItem]
+	customerSvc *CrmCustomerService
+	bulkJobSvc  *CrmBulkJobService
+}
+
+// NewCrmPrivacyRequestService tạo CrmPrivacyRequestService mới.
+func NewCrmPrivacyRequestService() (*CrmPrivacyRequestService, error) {
+	coll, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.CustomerPrivacyRequests)
+	if !exist {
+		return nil, fmt.Errorf("không tìm thấy collection %s: %w", global.MongoDB_ColNames.CustomerPrivacyRequests, common.ErrNotFound)
+	}
+	itemColl, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.CustomerPrivacyExportItems)
+	if !exist {
+		return nil, fmt.Errorf("không tìm thấy collection %s: %w", global.MongoDB_ColNames.CustomerPrivacyExportItems, common.ErrNotFound)
+	}
+	customerSvc, err := NewCrmCustomerService()
+	if err != nil {
+		return nil, err
+	}
+	bulkJobSvc, err := NewCrmBulkJobService()
+	if err != nil {
+		return nil, err
+	}
+	return &CrmPrivacyRequestService{
+		BaseServiceMongoImpl: basesvc.NewBaseServiceMongo[crmmodels.CrmPrivacyRequest](coll),
+		itemSvc:              basesvc.NewBaseServiceMongo[crmmodels.CrmPrivacyExportItem](itemColl),
+		customerSvc:          customerSvc,
+		bulkJobSvc:           bulkJobSvc,
+	}, nil
+}
+
+// CreatePrivacyRequestInput tham số tạo yêu cầu.
+type CreatePrivacyRequestInput struct {
+	Kind        string
+	UnifiedId   string
+	Phone       string
+	Email       string
+	Reason      string
+	RequestedBy primitive.ObjectID
+	IsPriority  bool
+}
+
+// CreateRequest resolve khách theo unifiedId / SĐT / email, ghi yêu cầu (queued) và xếp bulk job.
+// Không nhận yêu cầu cùng loại khi khách đang có yêu cầu chưa xong.
+func (s *CrmPrivacyRequestService) CreateRequest(ctx context.Context, ownerOrgID primitive.ObjectID, input CreatePrivacyRequestInput) (*crmmodels.CrmPrivacyRequest, error) {
+	jobType := crmmodels.CrmBulkJobDataExport
+	if input.Kind == crmmodels.CrmPrivacyKindErasure {
+		jobType = crmmodels.CrmBulkJobDataErasure
+		if strings.TrimSpace(input.Reason) == "" {
+			return nil, common.NewError(common.ErrCodeValidationInput, "Yêu cầu xóa dữ liệu bắt buộc có lý do", common.StatusBadRequest, nil)
+		}
+	}
+	subject, unifiedIds, err := s.resolveSubject(ctx, ownerOrgID, input)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := s.DocumentExists(ctx, bson.M{
+		"ownerOrganizationId": ownerOrgID,
+		"kind":                input.Kind,
+		"status":              bson.M{"$in": []string{crmmodels.CrmPrivacyStatusQueued, crmmodels.CrmPrivacyStatusRunning}},
+		"resolvedUnifiedIds":  bson.M{"$in": unifiedIds},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if pending {
+		return nil, common.NewError(common.ErrCodeBusinessState, "Khách đang có yêu cầu cùng loại chưa hoàn tất", common.StatusConflict, nil)
+	}
+
+	req, err := s.InsertOne(ctx, crmmodels.CrmPrivacyRequest{
+		Kind:                input.Kind,
+		Status:              crmmodels.CrmPrivacyStatusQueued,
+		Subject:             subject,
+		ResolvedUnifiedIds:  unifiedIds,
+		Reason:              strings.TrimSpace(input.Reason),
+		RequestedBy:         input.RequestedBy,
+		OwnerOrganizationID: ownerOrgID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	jobID, err := s.bulkJobSvc.Enqueue(ctx, jobType, ownerOrgID, bson.M{"requestId": req.ID.Hex()}, input.IsPriority)
+	if err != nil {
+		_, _ = s.Collection().UpdateOne(ctx, bson.M{"_id": req.ID}, bson.M{"$set": bson.M{"status": crmmodels.CrmPrivacyStatusFailed, "error": err.Error(), "updatedAt": time.Now().UnixMilli()}})
+		return nil, err
+	}
+	req.BulkJobID = jobID
+	if _, err := s.Collection().UpdateOne(ctx, bson.M{"_id": req.ID}, bson.M{"$set": bson.M{"bulkJobId": jobID}}); err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	return &req, nil
+}
+
+// resolveSubject tìm khách của chủ thể. unifiedId (kể cả id cũ đã gộp) → một khách; SĐT / email → mọi khách khớp.
+func (s *CrmPrivacyRequestService) resolveSubject(ctx context.Context, ownerOrgID primitive.ObjectID, input CreatePrivacyRequestInput) (crmmodels.CrmPrivacySubject, []string, error) {
+	var subject crmmodels.CrmPrivacySubject
+	var filter bson.M
+	switch {
+	case strings.TrimSpace(input.UnifiedId) != "":
+		subject.UnifiedId = strings.TrimSpace(input.UnifiedId)
+		filter = buildCustomerFilterByIdOrUid(subject.UnifiedId, ownerOrgID)
+	case strings.TrimSpace(input.Phone) != "":
+		subject.Phone = normalizePhone(input.Phone)
+		if subject.Phone == "" {
+			return subject, nil, common.NewError(common.ErrCodeValidationInput, "Số điện thoại không hợp lệ", common.StatusBadRequest, nil)
+		}
+		filter = bson.M{"ownerOrganizationId": ownerOrgID, "$or": []bson.M{
+			{"profile.phoneNumbers": subject.Phone},
+			{"phoneNumbers": subject.Phone},
+		}}
+	case strings.TrimSpace(input.Email) != "":
+		subject.Email = strings.ToLower(strings.TrimSpace(input.Email))
+		pattern := primitive.Regex{Pattern: "^" + regexp.QuoteMeta(subject.Email) + "$", Options: "i"}
+		filter = bson.M{"ownerOrganizationId": ownerOrgID, "$or": []bson.M{
+			{"profile.emails": pattern},
+			{"emails": pattern},
+		}}
+	default:
+		return subject, nil, common.NewError(common.ErrCodeValidationInput, "Cần unifiedId, phone hoặc email", common.StatusBadRequest, nil)
+	}
+	customers, err := s.customerSvc.Find(ctx, filter, mongoopts.Find().SetLimit(crmPrivacyMaxSubjectMatch+1).SetProjection(bson.M{"unifiedId": 1}))
+	if err != nil {
+		return subject, nil, err
+	}
+	if len(customers) == 0 {
+		return subject, nil, common.NewError(common.ErrCodeValidationInput, "Không tìm thấy khách", common.StatusNotFound, nil)
+	}
+	if len(customers) > crmPrivacyMaxSubjectMatch {
+		return subject, nil, common.NewError(common.ErrCodeValidationInput, fmt.Sprintf("Quá %d khách khớp — dùng unifiedId", crmPrivacyMaxSubjectMatch), common.StatusBadRequest, nil)
+	}
+	ids := make([]string, 0, len(customers))
+	for i := range customers {
+		ids = append(ids, customers[i].UnifiedId)
+	}
+	return subject, uniqueStrings(ids), nil
+}
+
+// FindRequest yêu cầu theo id trong tổ chức.
+func (s *CrmPrivacyRequestService) FindRequest(ctx context.Context, ownerOrgID, id primitive.ObjectID) (*crmmodels.CrmPrivacyRequest, error) {
+	req, err := s.FindOne(ctx, bson.M{"_id": id, "ownerOrganizationId": ownerOrgID}, nil)
+	if err != nil {
+		if err == common.ErrNotFound {
+			return nil, common.NewError(common.ErrCodeValidationInput, "Không tìm thấy yêu cầu", common.StatusNotFound, nil)
+		}
+		return nil, err
+	}
+	return &req, nil
+}
+
+// crmPrivacyProgress progress bulk job: bước (chỉ số crmPrivacyTargets), _id cuối đã xử lý trong bước, kết quả cộng dồn.
+type crmPrivacyProgress struct {
+	Step            int                                    `bson:"step"`
+	LastID          primitive.ObjectID                     `bson:"lastId,omitempty"`
+	ConversationIds []string                               `bson:"conversationIds"`
+	Collections     []crmmodels.CrmPrivacyCollectionResult `bson:"collections"`
+}
+
+func decodeCrmPrivacyProgress(m bson.M) crmPrivacyProgress {
+	var p crmPrivacyProgress
+	if len(m) == 0 {
+		return p
+	}
+	if raw, err := bson.Marshal(m); err == nil {
+		_ = bson.Unmarshal(raw, &p)
+	}
+	return p
+}
+
+func (p *crmPrivacyProgress) toBson() bson.M {
+	out := bson.M{}
+	if raw, err := bson.Marshal(p); err == nil {
+		_ = bson.Unmarshal(raw, &out)
+	}
+	return out
+}
+
+// RunRequest thực thi yêu cầu từ progress (nil = từ đầu). onProgress gọi sau mỗi batch để resume khi worker restart.
+func (s *CrmPrivacyRequestService) RunRequest(ctx context.Context, ownerOrgID, requestID primitive.ObjectID, progress bson.M, onProgress func(bson.M)) (bson.M, error) {
+	req, err := s.FindRequest(ctx, ownerOrgID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Status == crmmodels.CrmPrivacyStatusCompleted {
+		return bson.M{"requestId": req.ID.Hex(), "status": req.Status, "skipped": "already_completed"}, nil
+	}
+	now := time.Now().UnixMilli()
+	set := bson.M{"status": crmmodels.CrmPrivacyStatusRunning, "error": "", "updatedAt": now}
+	if req.StartedAt == 0 {
+		set["startedAt"] = now
+	}
+	if _, err := s.Collection().UpdateOne(ctx, bson.M{"_id": req.ID}, bson.M{"$set": set}); err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+
+	collections, err := s.runTargets(ctx, req, decodeCrmPrivacyProgress(progress), onProgress)
+	if err != nil {
+		_, _ = s.Collection().UpdateOne(ctx, bson.M{"_id": req.ID}, bson.M{"$set": bson.M{"status": crmmodels.CrmPrivacyStatusFailed, "error": err.Error(), "updatedAt": time.Now().UnixMilli()}})
+		return nil, err
+	}
+
+	result := bson.M{"requestId": req.ID.Hex(), "kind": req.Kind}
+	if req.Kind == crmmodels.CrmPrivacyKindErasure {
+		// Bản chụp export cũ của cùng khách cũng là bản sao PII.
+		deleted, err := s.deleteExportItemsOf(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		result["exportItemsDeleted"] = deleted
+	}
+	done := time.Now()
+	set = bson.M{"status": crmmodels.CrmPrivacyStatusCompleted, "collections": collections, "completedAt": done.UnixMilli(), "updatedAt": done.UnixMilli()}
+	update := bson.M{"$set": set}
+	if req.Kind == crmmodels.CrmPrivacyKindExport {
+		set["exportExpiresAt"] = done.Add(crmPrivacyExportTTL).UnixMilli()
+	}
+	if req.Kind == crmmodels.CrmPrivacyKindErasure {
+		// SĐT / email tra cứu của chủ thể cũng là PII — chỉ giữ unifiedId + resolvedUnifiedIds làm bằng chứng đã xóa.
+		update["$unset"] = bson.M{"subject.phone": "", "subject.email": ""}
+	}
+	if _, err := s.Collection().UpdateOne(ctx, bson.M{"_id": req.ID}, update); err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	logger.GetAppLogger().WithFields(map[string]interface{}{
+		"requestId":  req.ID.Hex(),
+		"kind":       req.Kind,
+		"unifiedIds": req.ResolvedUnifiedIds,
+	}).Info("[CRM] Hoàn tất yêu cầu dữ liệu cá nhân")
+	for _, c := range collections {
+		result[c.Key] = bson.M{"matched": c.Matched, "processed": c.Processed}
+	}
+	return result, nil
+}
+
+// runTargets duyệt crmPrivacyTargets từ progress.Step, mỗi collection theo _id tăng dần từng batch.
+func (s *CrmPrivacyRequestService) runTargets(ctx context.Context, req *crmmodels.CrmPrivacyRequest, p crmPrivacyProgress, onProgress func(bson.M)) ([]crmmodels.CrmPrivacyCollectionResult, error) {
+	sc, err := s.buildScope(ctx, req, p.ConversationIds)
+	if err != nil {
+		return nil, err
+	}
+	p.ConversationIds = sc.ConversationIds
+	if len(p.Collections) != len(crmPrivacyTargets) {
+		p.Collections = make([]crmmodels.CrmPrivacyCollectionResult, len(crmPrivacyTargets))
+		for i, t := range crmPrivacyTargets {
+			p.Collections[i] = crmmodels.CrmPrivacyCollectionResult{Key: t.Key, Collection: t.Collection()}
+		}
+	}
+	for ; p.Step < len(crmPrivacyTargets); p.Step, p.LastID = p.Step+1, primitive.NilObjectID {
+		t := crmPrivacyTargets[p.Step]
+		coll, ok := global.RegistryCollections.Get(t.Collection())
+		if !ok {
+			return nil, fmt.Errorf("collection %s chưa đăng ký", t.Collection())
+		}
+		for {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			filter := t.Filter(sc)
+			if !p.LastID.IsZero() {
+				filter = bson.M{"$and": []bson.M{filter, {"_id": bson.M{"$gt": p.LastID}}}}
+			}
+			cursor, err := coll.Find(ctx, filter, mongoopts.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(crmPrivacyBatchSize))
+			if err != nil {
+				return nil, common.ConvertMongoError(err)
+			}
+			var docs []bson.Raw
+			var ids []primitive.ObjectID
+			for cursor.Next(ctx) {
+				id, ok := cursor.Current.Lookup("_id").ObjectIDOK()
+				if !ok {
+					continue
+				}
+				docs = append(docs, append(bson.Raw(nil), cursor.Current...))
+				ids = append(ids, id)
+			}
+			err = cursor.Err()
+			cursor.Close(ctx)
+			if err != nil {
+				return nil, common.ConvertMongoError(err)
+			}
+			if len(ids) == 0 {
+				break
+			}
+			processed, err := s.processBatch(ctx, req, t, coll, ids, docs)
+			if err != nil {
+				return nil, err
+			}
+			p.Collections[p.Step].Matched += int64(len(ids))
+			p.Collections[p.Step].Processed += processed
+			p.LastID = ids[len(ids)-1]
+			if onProgress != nil {
+				onProgress(p.toBson())
+			}
+			if len(ids) < crmPrivacyBatchSize {
+				break
+			}
+		}
+	}
+	return p.Collections, nil
+}
+
+// processBatch export: chụp document (bỏ qua bản đã chụp khi chạy lại); erasure: ẩn danh hoá theo _id.
+func (s *CrmPrivacyRequestService) processBatch(ctx context.Context, req *crmmodels.CrmPrivacyRequest, t crmPrivacyTarget, coll *mongo.Collection, ids []primitive.ObjectID, docs []bson.Raw) (int64, error) {
+	if req.Kind == crmmodels.CrmPrivacyKindExport {
+		expiresAt := time.Now().Add(crmPrivacyExportTTL)
+		items := make([]interface{}, len(docs))
+		for i := range docs {
+			items[i] = crmmodels.CrmPrivacyExportItem{
+				RequestID:           req.ID,
+				Key:                 t.Key,
+				SourceID:            ids[i],
+				Document:            docs[i],
+				OwnerOrganizationID: req.OwnerOrganizationID,
+				ExpiresAt:           expiresAt,
+			}
+		}
+		if _, err := s.itemSvc.Collection().InsertMany(ctx, items, mongoopts.InsertMany().SetOrdered(false)); err != nil && !mongo.IsDuplicateKeyError(err) {
+			return 0, common.ConvertMongoError(err)
+		}
+		return int64(len(docs)), nil
+	}
+	if t.Erase == nil {
+		return 0, nil
+	}
+	res, err := coll.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, t.Erase(time.Now().UnixMilli()))
+	if err != nil {
+		return 0, common.ConvertMongoError(err)
+	}
+	return res.ModifiedCount, nil
+}
+
+// deleteExportItemsOf xóa bản chụp của mọi yêu cầu export có chung khách với yêu cầu xóa.
+func (s *CrmPrivacyRequestService) deleteExportItemsOf(ctx context.Context, req *crmmodels.CrmPrivacyRequest) (int64, error) {
+	exportIDs, err := s.Distinct(ctx, "_id", bson.M{
+		"ownerOrganizationId": req.OwnerOrganizationID,
+		"kind":                crmmodels.CrmPrivacyKindExport,
+		"resolvedUnifiedIds":  bson.M{"$in": nonNilStrings(req.ResolvedUnifiedIds)},
+	})
+	if err != nil || len(exportIDs) == 0 {
+		return 0, err
+	}
+	res, err := s.itemSvc.Collection().DeleteMany(ctx, bson.M{"requestId": bson.M{"$in": exportIDs}})
+	if err != nil {
+		return 0, common.ConvertMongoError(err)
+	}
+	return res.DeletedCount, nil
+}
+
+// ExportFormatZip / ExportFormatJSON định dạng tải bản export.
+const (
+	ExportFormatZip  = "zip"
+	ExportFormatJSON = "json"
+)
+
+// CheckExportReady bản export đã hoàn tất và còn hạn tải.
+func CheckExportReady(req *crmmodels.CrmPrivacyRequest) error {
+	if req.Kind != crmmodels.CrmPrivacyKindExport {
+		return common.NewError(common.ErrCodeValidationInput, "Yêu cầu không phải export", common.StatusBadRequest, nil)
+	}
+	if req.Status != crmmodels.CrmPrivacyStatusCompleted {
+		return common.NewError(common.ErrCodeBusinessState, "Export chưa hoàn tất", common.StatusConflict, nil)
+	}
+	if req.ExportExpiresAt > 0 && time.Now().UnixMilli() > req.ExportExpiresAt {
+		return common.NewError(common.ErrCodeBusinessState, "Bản export đã hết hạn — tạo yêu cầu mới", common.StatusConflict, nil)
+	}
+	return nil
+}
+
+// WriteExport ghi bản export: zip — manifest.json + <key>.json (mảng document) cho từng collection;
+// json — một object {manifest, collections: {key: [...]}}. Document ở dạng MongoDB Extended JSON (relaxed).
+func (s *CrmPrivacyRequestService) WriteExport(ctx context.Context, req *crmmodels.CrmPrivacyRequest, format string, w io.Writer) error {
+	manifest, err := json.MarshalIndent(bson.M{
+		"requestId":          req.ID.Hex(),
+		"subject":            req.Subject,
+		"resolvedUnifiedIds": req.ResolvedUnifiedIds,
+		"collections":        req.Collections,
+		"completedAt":        req.CompletedAt,
+		"exportedAt":         time.Now().UnixMilli(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if format == ExportFormatJSON {
+		if _, err := fmt.Fprintf(w, "{\"manifest\":%s,\"collections\":{", manifest); err != nil {
+			return err
+		}
+		for i, t := range crmPrivacyTargets {
+			if i > 0 {
+				if _, err := io.WriteString(w, ","); err != nil {
+					return err
+				}
+			}
+			if _, err := fmt.Fprintf(w, "%q:", t.Key); err != nil {
+				return err
+			}
+			if err := s.writeExportItems(ctx, req.ID, t.Key, w); err != nil {
+				return err
+			}
+		}
+		_, err := io.WriteString(w, "}}")
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	f, err := zw.Create("manifest.json")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(manifest); err != nil {
+		return err
+	}
+	for _, t := range crmPrivacyTargets {
+		f, err := zw.Create(t.Key + ".json")
+		if err != nil {
+			return err
+		}
+		if err := s.writeExportItems(ctx, req.ID, t.Key, f); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// writeExportItems ghi mảng JSON các document đã chụp của một collection.
+func (s *CrmPrivacyRequestService) writeExportItems(ctx context.Context, requestID primitive.ObjectID, key string, w io.Writer) error {
+	cursor, err := s.itemSvc.Collection().Find(ctx, bson.M{"requestId": requestID, "key": key}, mongoopts.Find().SetSort(bson.D{{Key: "sourceId", Value: 1}}))
+	if err != nil {
+		return common.ConvertMongoError(err)
+	}
+	defer cursor.Close(ctx)
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	first := true
+	for cursor.Next(ctx) {
+		var item crmmodels.CrmPrivacyExportItem
+		if err := cursor.Decode(&item); err != nil {
+			return err
+		}
+		data, err := bson.MarshalExtJSON(item.Document, false, false)
+		if err != nil {
+			return err
+		}
+		if !first {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return common.ConvertMongoError(err)
+	}
+	_, err = io.WriteString(w, "]")
+	return err
+}
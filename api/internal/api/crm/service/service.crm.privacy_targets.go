@@ -0,0 +1,302 @@
+// Package crmvc — Danh sách collection chứa dữ liệu khách cho export / xóa dữ liệu cá nhân: filter theo phạm vi khách
+// và phép ẩn danh hoá từng collection (giữ id liên kết và số liệu đơn để metrics tổng hợp không đổi).
+package crmvc
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	crmmodels "meta_commerce/internal/api/crm/models"
+	"meta_commerce/internal/global"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// crmPrivacyScope phạm vi dữ liệu của các khách trong một yêu cầu. Mọi slice khác nil ($in không nhận null).
+type crmPrivacyScope struct {
+	OwnerOrgID      primitive.ObjectID
+	CustomerIDs     []primitive.ObjectID // _id customer_core_records
+	UnifiedIds      []string             // unifiedId + mergedFrom (activity, ghi chú, lịch sử gộp)
+	Uids            []string             // cust_xxx
+	LinkIds         []string             // Mọi id nguồn (uid, unifiedId, pos, fb, zalo, theo page, mergedFrom)
+	ConversationIds []string             // conversationId hội thoại của khách — chốt lần đầu, lưu trong progress
+}
+
+// crmPrivacyTarget một collection liên kết: filter theo scope, ẩn danh hoá (nil = không có trường PII, chỉ đếm).
+type crmPrivacyTarget struct {
+	Key        string
+	Collection func() string
+	Filter     func(sc *crmPrivacyScope) bson.M
+	Erase      func(now int64) bson.M
+}
+
+// crmPrivacyUnset tạo $unset cho danh sách field.
+func crmPrivacyUnset(fields ...string) bson.M {
+	m := bson.M{}
+	for _, f := range fields {
+		m[f] = ""
+	}
+	return m
+}
+
+// crmPrivacyPrefixed thêm tiền tố cho từng field (posData. / customerInfo. ...).
+func crmPrivacyPrefixed(prefix string, fields ...string) []string {
+	out := make([]string, len(fields))
+	for i, f := range fields {
+		out[i] = prefix + f
+	}
+	return out
+}
+
+// Field PII trong dữ liệu gốc Pancake POS (posData đơn / khách).
+var (
+	crmPrivacyPosOrderFields    = []string{"bill_full_name", "bill_phone_number", "bill_email", "shipping_address", "note", "customer.name", "customer.phone_numbers", "customer.emails", "customer.date_of_birth", "customer.shop_customer_address"}
+	crmPrivacyPosCustomerFields = []string{"name", "phone_numbers", "emails", "date_of_birth", "gender", "shop_customer_address", "referral_code"}
+	crmPrivacyProfileFields     = []string{"phoneNumbers", "emails", "birthday", "gender", "livesIn", "addresses", "referralCode"}
+	crmPrivacyPancakeConvFields = []string{"customers", "page_customer", "snippet", "recent_phone_numbers", "last_sent_by", "from"}
+)
+
+func crmPrivacyOrderFilter(sc *crmPrivacyScope) bson.M {
+	return bson.M{
+		"ownerOrganizationId": sc.OwnerOrgID,
+		"$or": []bson.M{
+			{"customerId": bson.M{"$in": sc.LinkIds}},
+			{"links.customer.uid": bson.M{"$in": sc.LinkIds}},
+			{"posData.customer.id": bson.M{"$in": sc.LinkIds}},
+		},
+	}
+}
+
+func crmPrivacySourceCustomerFilter(sc *crmPrivacyScope) bson.M {
+	return bson.M{
+		"ownerOrganizationId": sc.OwnerOrgID,
+		"$or": []bson.M{
+			{"customerId": bson.M{"$in": sc.LinkIds}},
+			{"uid": bson.M{"$in": sc.LinkIds}},
+		},
+	}
+}
+
+func crmPrivacyConversationFilter(sc *crmPrivacyScope) bson.M {
+	return bson.M{"ownerOrganizationId": sc.OwnerOrgID, "conversationId": bson.M{"$in": sc.ConversationIds}}
+}
+
+// crmPrivacyTargets thứ tự xử lý — progress của bulk job lưu chỉ số bước trong danh sách này, chỉ thêm vào cuối.
+var crmPrivacyTargets = []crmPrivacyTarget{
+	{
+		Key:        "customers",
+		Collection: func() string { return global.MongoDB_ColNames.CustomerCustomers },
+		Filter:     func(sc *crmPrivacyScope) bson.M { return bson.M{"_id": bson.M{"$in": sc.CustomerIDs}} },
+		Erase: func(now int64) bson.M {
+			unset := append(crmPrivacyPrefixed("profile.", crmPrivacyProfileFields...), "name")
+			unset = append(unset, crmPrivacyProfileFields...)
+			unset = append(unset, "customFields") // Trường tùy chỉnh có thể chứa thông tin cá nhân (ghi chú, ngày sinh...)
+			return bson.M{
+				"$set":   bson.M{"profile.name": crmmodels.CrmPrivacyErasedName, "erasedAt": now, "updatedAt": now},
+				"$unset": crmPrivacyUnset(unset...),
+			}
+		},
+	},
+	{
+		Key:        "activities",
+		Collection: func() string { return global.MongoDB_ColNames.CustomerActivityHistory },
+		Filter: func(sc *crmPrivacyScope) bson.M {
+			return bson.M{"ownerOrganizationId": sc.OwnerOrgID, "unifiedId": bson.M{"$in": sc.UnifiedIds}}
+		},
+		Erase: func(int64) bson.M {
+			return bson.M{"$unset": crmPrivacyUnset("snapshot.profile", "changes", "context", "display.subtext")}
+		},
+	},
+	{
+		Key:        "notes",
+		Collection: func() string { return global.MongoDB_ColNames.CustomerNotes },
+		Filter: func(sc *crmPrivacyScope) bson.M {
+			return bson.M{
+				"ownerOrganizationId": sc.OwnerOrgID,
+				"$or": []bson.M{
+					{"customerId": bson.M{"$in": sc.UnifiedIds}},
+					{"links.customer.uid": bson.M{"$in": sc.Uids}},
+				},
+			}
+		},
+		Erase: func(now int64) bson.M {
+			return bson.M{"$set": bson.M{"noteText": crmmodels.CrmPrivacyErasedName, "updatedAt": now}, "$unset": crmPrivacyUnset("nextAction")}
+		},
+	},
+	{
+		Key:        "merge_history",
+		Collection: func() string { return global.MongoDB_ColNames.CustomerMergeHistory },
+		Filter: func(sc *crmPrivacyScope) bson.M {
+			return bson.M{
+				"ownerOrganizationId": sc.OwnerOrgID,
+				"$or": []bson.M{
+					{"survivorUnifiedId": bson.M{"$in": sc.UnifiedIds}},
+					{"mergedUnifiedId": bson.M{"$in": sc.UnifiedIds}},
+				},
+			}
+		},
+		Erase: func(int64) bson.M {
+			fields := append([]string{"profile", "name"}, crmPrivacyProfileFields...)
+			return bson.M{"$unset": crmPrivacyUnset(crmPrivacyPrefixed("mergedCustomer.", fields...)...)}
+		},
+	},
+	{
+		Key:        "fb_customers",
+		Collection: func() string { return global.MongoDB_ColNames.FbCustomers },
+		Filter:     crmPrivacySourceCustomerFilter,
+		Erase: func(now int64) bson.M {
+			unset := append([]string{"birthday", "gender", "livesIn"}, crmPrivacyPrefixed("panCakeData.", "name", "phone_numbers", "email", "birthday", "gender", "lives_in", "personal_info")...)
+			return bson.M{
+				"$set":   bson.M{"name": crmmodels.CrmPrivacyErasedName, "phoneNumbers": []string{}, "email": "", "updatedAt": now},
+				"$unset": crmPrivacyUnset(unset...),
+			}
+		},
+	},
+	{
+		Key:        "fb_conversations",
+		Collection: func() string { return global.MongoDB_ColNames.FbConvesations },
+		Filter:     crmPrivacyConversationFilter,
+		Erase: func(int64) bson.M {
+			return bson.M{"$unset": crmPrivacyUnset(crmPrivacyPrefixed("panCakeData.", crmPrivacyPancakeConvFields...)...)}
+		},
+	},
+	{
+		Key:        "fb_messages",
+		Collection: func() string { return global.MongoDB_ColNames.FbMessages },
+		Filter:     crmPrivacyConversationFilter,
+		Erase: func(int64) bson.M {
+			return bson.M{"$unset": crmPrivacyUnset(crmPrivacyPrefixed("panCakeData.", crmPrivacyPancakeConvFields...)...)}
+		},
+	},
+	{
+		Key:        "fb_message_items",
+		Collection: func() string { return global.MongoDB_ColNames.FbMessageItems },
+		Filter:     crmPrivacyConversationFilter,
+		Erase: func(int64) bson.M {
+			// Giữ messageData.from.id — metrics hội thoại phân biệt tin của page / khách theo id người gửi.
+			return bson.M{"$unset": crmPrivacyUnset(crmPrivacyPrefixed("messageData.", "message", "original_message", "attachments", "rich_message", "phone_info", "from.name", "from.email")...)}
+		},
+	},
+	{
+		Key:        "pos_customers",
+		Collection: func() string { return global.MongoDB_ColNames.PcPosCustomers },
+		Filter:     crmPrivacySourceCustomerFilter,
+		Erase: func(now int64) bson.M {
+			// Giữ posData.fb_id — liên kết khách POS với hội thoại.
+			unset := append([]string{"dateOfBirth", "gender", "addresses", "referralCode"}, crmPrivacyPrefixed("posData.", crmPrivacyPosCustomerFields...)...)
+			return bson.M{
+				"$set":   bson.M{"name": crmmodels.CrmPrivacyErasedName, "phoneNumbers": []string{}, "emails": []string{}, "updatedAt": now},
+				"$unset": crmPrivacyUnset(unset...),
+			}
+		},
+	},
+	{
+		Key:        "pos_orders",
+		Collection: func() string { return global.MongoDB_ColNames.PcPosOrders },
+		Filter:     crmPrivacyOrderFilter,
+		Erase: func(now int64) bson.M {
+			unset := append([]string{"shippingAddress", "note"}, crmPrivacyPrefixed("customerInfo.", crmPrivacyPosCustomerFields...)...)
+			unset = append(unset, crmPrivacyPrefixed("posData.", crmPrivacyPosOrderFields...)...)
+			return bson.M{
+				"$set":   bson.M{"billFullName": crmmodels.CrmPrivacyErasedName, "billPhoneNumber": "", "billEmail": "", "updatedAt": now},
+				"$unset": crmPrivacyUnset(unset...),
+			}
+		},
+	},
+	{
+		Key:        "orders",
+		Collection: func() string { return global.MongoDB_ColNames.OrderCanonical },
+		Filter:     crmPrivacyOrderFilter,
+		Erase: func(now int64) bson.M {
+			return bson.M{"$set": bson.M{"updatedAt": now}, "$unset": crmPrivacyUnset(crmPrivacyPrefixed("posData.", crmPrivacyPosOrderFields...)...)}
+		},
+	},
+	{
+		// Kết quả phân tích CIX chỉ gồm nhãn suy ra (stage, intent, flags) — không có PII, chỉ export.
+		Key:        "cix_analyses",
+		Collection: func() string { return global.MongoDB_ColNames.CixAnalysisResults },
+		Filter: func(sc *crmPrivacyScope) bson.M {
+			return bson.M{"ownerOrganizationId": sc.OwnerOrgID, "customerUid": bson.M{"$in": sc.LinkIds}}
+		},
+	},
+	{
+		Key:        "decision_cases",
+		Collection: func() string { return global.MongoDB_ColNames.DecisionCasesRuntime },
+		Filter: func(sc *crmPrivacyScope) bson.M {
+			return bson.M{"ownerOrganizationId": sc.OwnerOrgID, "entityRefs.customerId": bson.M{"$in": sc.LinkIds}}
+		},
+		Erase: func(now int64) bson.M {
+			// contextPackets chụp profile / hội thoại khách lúc mở case; giữ quyết định và kết quả.
+			return bson.M{"$set": bson.M{"updatedAt": now}, "$unset": crmPrivacyUnset("contextPackets")}
+		},
+	},
+}
+
+// buildCrmPrivacyScope nạp các khách đã resolve và gom mọi id liên kết. conversationIds != nil (resume) → dùng lại,
+// không tính lại sau khi hội thoại đã bị ẩn danh hoá một phần.
+func (s *CrmPrivacyRequestService) buildScope(ctx context.Context, req *crmmodels.CrmPrivacyRequest, conversationIds []string) (*crmPrivacyScope, error) {
+	sc := &crmPrivacyScope{
+		OwnerOrgID:  req.OwnerOrganizationID,
+		CustomerIDs: []primitive.ObjectID{},
+		UnifiedIds:  []string{},
+		Uids:        []string{},
+		LinkIds:     []string{},
+	}
+	customers, err := s.customerSvc.Find(ctx, bson.M{"ownerOrganizationId": req.OwnerOrganizationID, "unifiedId": bson.M{"$in": nonNilStrings(req.ResolvedUnifiedIds)}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	var posIds []string
+	for i := range customers {
+		c := &customers[i]
+		sc.CustomerIDs = append(sc.CustomerIDs, c.ID)
+		sc.UnifiedIds = append(sc.UnifiedIds, c.UnifiedId)
+		sc.UnifiedIds = append(sc.UnifiedIds, c.MergedFrom...)
+		if c.Uid != "" {
+			sc.Uids = append(sc.Uids, c.Uid)
+		}
+		sc.LinkIds = append(sc.LinkIds, buildCustomerIdsForQuery(c)...)
+		sc.LinkIds = append(sc.LinkIds, c.MergedFrom...)
+		if c.SourceIds.Pos != "" {
+			posIds = append(posIds, c.SourceIds.Pos)
+		}
+	}
+	// Khách đã bị xóa khỏi customer_core_records vẫn còn dữ liệu nguồn theo unifiedId.
+	sc.UnifiedIds = nonNilStrings(uniqueStrings(append(sc.UnifiedIds, req.ResolvedUnifiedIds...)))
+	sc.LinkIds = nonNilStrings(uniqueStrings(append(sc.LinkIds, req.ResolvedUnifiedIds...)))
+	sc.Uids = nonNilStrings(uniqueStrings(sc.Uids))
+
+	if conversationIds != nil {
+		sc.ConversationIds = conversationIds
+		return sc, nil
+	}
+	convIds := s.customerSvc.getConversationIdsFromPosCustomers(ctx, posIds, req.OwnerOrganizationID)
+	coll, ok := global.RegistryCollections.Get(global.MongoDB_ColNames.FbConvesations)
+	if !ok {
+		sc.ConversationIds = nonNilStrings(convIds)
+		return sc, nil
+	}
+	values, err := coll.Distinct(ctx, "conversationId", buildConversationFilterForCustomerIds(sc.LinkIds, req.OwnerOrganizationID, convIds))
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range values {
+		if id, ok := v.(string); ok && strings.TrimSpace(id) != "" {
+			convIds = append(convIds, id)
+		}
+	}
+	sc.ConversationIds = nonNilStrings(uniqueStrings(convIds))
+	return sc, nil
+}
+
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// crmPrivacyExportTTL thời gian giữ bản chụp export để tải về.
+const crmPrivacyExportTTL = 7 * 24 * time.Hour
@@ -0,0 +1,101 @@
+// Package crmvc - Test danh sách collection của yêu cầu export / xóa dữ liệu khách.
+package crmvc
+
+import (
+	"strings"
+	"testing"
+
+	crmmodels "meta_commerce/internal/api/crm/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Mongo từ chối update có hai path chồng nhau (vd $set "profile.name" cùng $unset "profile").
+func TestCrmPrivacyTargets_EraseHasNoConflictingPaths(t *testing.T) {
+	seen := map[string]bool{}
+	for _, target := range crmPrivacyTargets {
+		if seen[target.Key] {
+			t.Fatalf("key trùng: %s", target.Key)
+		}
+		seen[target.Key] = true
+		if target.Erase == nil {
+			continue
+		}
+		var paths []string
+		for op, v := range target.Erase(1) {
+			fields, ok := v.(bson.M)
+			if !ok {
+				t.Fatalf("%s: %s phải là bson.M", target.Key, op)
+			}
+			for f := range fields {
+				paths = append(paths, f)
+			}
+		}
+		for i, a := range paths {
+			for j, b := range paths {
+				if i != j && (a == b || strings.HasPrefix(b, a+".")) {
+					t.Errorf("%s: path %q chồng %q", target.Key, a, b)
+				}
+			}
+		}
+	}
+}
+
+func TestCrmPrivacyTargets_EraseKeepsLinkFields(t *testing.T) {
+	keep := map[string]bool{
+		"_id": true, "uid": true, "unifiedId": true, "customerId": true, "conversationId": true,
+		"ownerOrganizationId": true, "posData.fb_id": true, "messageData.from.id": true,
+		"totalSpent": true, "orderCount": true, "posData.total_price": true, "posData.status": true,
+	}
+	for _, target := range crmPrivacyTargets {
+		if target.Erase == nil {
+			continue
+		}
+		for _, v := range target.Erase(1) {
+			for f := range v.(bson.M) {
+				if keep[f] {
+					t.Errorf("%s: không được đổi %q", target.Key, f)
+				}
+			}
+		}
+	}
+	customers := crmPrivacyTargets[0].Erase(1)
+	if customers["$set"].(bson.M)["profile.name"] != crmmodels.CrmPrivacyErasedName {
+		t.Error("customers: tên phải được thay bằng CrmPrivacyErasedName")
+	}
+	if _, ok := customers["$unset"].(bson.M)["profile.phoneNumbers"]; !ok {
+		t.Error("customers: phải xóa profile.phoneNumbers")
+	}
+}
+
+func TestCrmPrivacyProgress_RoundTrip(t *testing.T) {
+	p := crmPrivacyProgress{
+		Step:            3,
+		LastID:          primitive.NewObjectID(),
+		ConversationIds: []string{"c1", "c2"},
+		Collections:     []crmmodels.CrmPrivacyCollectionResult{{Key: "customers", Collection: "x", Matched: 2, Processed: 2}},
+	}
+	got := decodeCrmPrivacyProgress(p.toBson())
+	if got.Step != p.Step || got.LastID != p.LastID || len(got.ConversationIds) != 2 || len(got.Collections) != 1 || got.Collections[0].Processed != 2 {
+		t.Fatalf("progress sau round-trip khác: %+v", got)
+	}
+	if empty := decodeCrmPrivacyProgress(nil); empty.Step != 0 || !empty.LastID.IsZero() || empty.ConversationIds != nil {
+		t.Fatalf("progress rỗng phải bắt đầu từ đầu: %+v", empty)
+	}
+}
+
+// Khách đã ẩn danh hoá: sync / rebuild bỏ mọi path profile khỏi $set, giữ metrics.
+func TestOmitProfileFields(t *testing.T) {
+	set := bson.M{"profile": crmmodels.CrmCustomerProfile{Name: "A"}, "profile.phoneNumbers": []string{"84901234567"}, "profileScore": 1, "totalSpent": 10}
+	omitProfileFields(set)
+	if _, ok := set["profile"]; ok {
+		t.Fatalf("còn profile: %v", set)
+	}
+	if _, ok := set["profile.phoneNumbers"]; ok {
+		t.Fatalf("còn profile.phoneNumbers: %v", set)
+	}
+	if set["totalSpent"] != 10 || set["profileScore"] != 1 {
+		t.Fatalf("field khác phải giữ: %v", set)
+	}
+}
@@ -67,8 +67,12 @@ func (s *CrmCustomerService) RecalculateCustomerFromAllSources(ctx context.Conte
 		return nil, err
 	}
 
-	// 2. Rebuild profile từ tất cả nguồn
-	profile := s.rebuildProfileFromAllSources(ctx, &customer)
+	// 2. Rebuild profile từ tất cả nguồn — khách đã ẩn danh hoá (erasedAt) giữ nguyên profile, chỉ tính lại metrics
+	erased := customer.ErasedAt > 0
+	profile := customer.Profile
+	if !erased {
+		profile = s.rebuildProfileFromAllSources(ctx, &customer)
+	}
 
 	// 3. Mở rộng ids cho conversation/order: thêm FB/POS customer tìm qua phone khi chưa merge
 	ids := buildCustomerIdsForRecalculate(&customer)
@@ -127,6 +131,9 @@ func (s *CrmCustomerService) RecalculateCustomerFromAllSources(ctx context.Conte
 	for k, v := range class {
 		setFields[k] = v
 	}
+	if erased {
+		omitProfileFields(setFields)
+	}
 	unsetAll := make(bson.M)
 	for k, v := range unsetRawFields {
 		unsetAll[k] = v
@@ -155,7 +162,7 @@ func (s *CrmCustomerService) RecalculateCustomerFromAllSources(ctx context.Conte
 	return &RecalculateCustomerResult{
 		UnifiedId:             unifiedId,
 		UpdatedAt:             now,
-		ProfileUpdated:        true,
+		ProfileUpdated:        !erased,
 		MetricsUpdated:        true,
 		ClassificationUpdated: true,
 		ActivitiesBackfilled:  activitiesBackfilled,
@@ -771,6 +778,10 @@ func buildCustomerIdsForRecalculate(c *crmmodels.CrmCustomer) []string {
 			add(v)
 		}
 	}
+	// Id của khách đã gộp thủ công — đơn/hội thoại cũ vẫn gắn customerId nguồn của khách bị gộp
+	for _, id := range c.MergedFrom {
+		add(id)
+	}
 	return ids
 }
 
@@ -35,6 +35,7 @@ func GetCustomerIdForReply(c *crmmodels.CrmCustomer, pageId string) string {
 }
 
 // ResolveUnifiedId tìm unifiedId từ customerId (có thể là pos, fb hoặc zalo).
+// customerId/unifiedId của khách đã bị gộp thủ công resolve về khách giữ lại (mergedFrom).
 // Trả về ("", false) nếu không tìm thấy.
 func (s *CrmCustomerService) ResolveUnifiedId(ctx context.Context, customerId string, ownerOrgID primitive.ObjectID) (string, bool) {
 	if customerId == "" {
@@ -49,6 +50,7 @@ func (s *CrmCustomerService) ResolveUnifiedId(ctx context.Context, customerId st
 			{"sourceIds.allInboxIds": customerId},
 			{"unifiedId": customerId},
 			{"uid": customerId},
+			{"mergedFrom": customerId}, // id của khách đã gộp thủ công → khách giữ lại
 		},
 	}, nil)
 	if err != nil {
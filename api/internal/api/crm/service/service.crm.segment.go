@@ -0,0 +1,532 @@
+// Package crmvc — Phân khúc khách động: lưu định nghĩa (DSL), theo dõi thành viên, số thành viên theo ngày
+// và emit crm.segment.entered / crm.segment.exited vào decision_events_queue.
+package crmvc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"meta_commerce/internal/api/aidecision/crmqueue"
+	"meta_commerce/internal/api/aidecision/eventtypes"
+	basesvc "meta_commerce/internal/api/base/service"
+	crmmodels "meta_commerce/internal/api/crm/models"
+	"meta_commerce/internal/common"
+	"meta_commerce/internal/global"
+	"meta_commerce/internal/logger"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongoopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// crmSegmentWriteBatch — số thành viên ghi/xóa mỗi lần khi refresh toàn bộ phân khúc.
+const crmSegmentWriteBatch = 500
+
+// CrmSegmentService CRUD định nghĩa phân khúc và đánh giá thành viên.
+type CrmSegmentService struct {
+	*basesvc.BaseServiceMongoImpl[crmmodels.CrmSegment]
+	memberSvc   *basesvc.BaseServiceMongoImpl[crmmodels.CrmSegmentMember]
+	countSvc    *basesvc.BaseServiceMongoImpl[crmmodels.CrmSegmentCountSnapshot]
+	customerSvc *CrmCustomerService
+}
+
+// NewCrmSegmentService tạo CrmSegmentService mới.
+func NewCrmSegmentService() (*CrmSegmentService, error) {
+	names := []string{global.MongoDB_ColNames.CustomerSegments, global.MongoDB_ColNames.CustomerSegmentMembers, global.MongoDB_ColNames.CustomerSegmentCounts}
+	colls := make([]*mongo.Collection, len(names))
+	for i, name := range names {
+		coll, exist := global.RegistryCollections.Get(name)
+		if !exist {
+			return nil, fmt.Errorf("không tìm thấy collection %s: %w", name, common.ErrNotFound)
+		}
+		colls[i] = coll
+	}
+	customerSvc, err := NewCrmCustomerService()
+	if err != nil {
+		return nil, err
+	}
+	return &CrmSegmentService{
+		BaseServiceMongoImpl: basesvc.NewBaseServiceMongo[crmmodels.CrmSegment](colls[0]),
+		memberSvc:            basesvc.NewBaseServiceMongo[crmmodels.CrmSegmentMember](colls[1]),
+		countSvc:             basesvc.NewBaseServiceMongo[crmmodels.CrmSegmentCountSnapshot](colls[2]),
+		customerSvc:          customerSvc,
+	}, nil
+}
+
+// InsertOne override — từ chối điều kiện không hợp lệ; tạo xong xếp job refresh để ghi thành viên ban đầu.
+func (s *CrmSegmentService) InsertOne(ctx context.Context, data crmmodels.CrmSegment) (crmmodels.CrmSegment, error) {
+	if err := ValidateSegmentConditions(data.Conditions); err != nil {
+		return data, err
+	}
+	data.MemberCount = 0
+	data.LastEvaluatedAt = 0
+	created, err := s.BaseServiceMongoImpl.InsertOne(ctx, data)
+	if err != nil {
+		return created, err
+	}
+	s.enqueueRefresh(ctx, &created)
+	return created, nil
+}
+
+// UpdateById override — kiểm tra conditions mới (nếu có). Đổi conditions → ghi lại thành viên từ đầu (không emit vào/ra
+// cho thay đổi do sửa định nghĩa).
+func (s *CrmSegmentService) UpdateById(ctx context.Context, id primitive.ObjectID, data interface{}) (crmmodels.CrmSegment, error) {
+	var zero crmmodels.CrmSegment
+	updateData, err := basesvc.ToUpdateData(data)
+	if err != nil {
+		return zero, err
+	}
+	if updateData.Set != nil {
+		delete(updateData.Set, "memberCount")
+		delete(updateData.Set, "lastEvaluatedAt")
+		if raw, ok := updateData.Set["conditions"]; ok {
+			cond, err := decodeSegmentConditions(raw)
+			if err != nil {
+				return zero, common.NewError(common.ErrCodeValidationFormat, "conditions không đúng định dạng", common.StatusBadRequest, err)
+			}
+			if err := ValidateSegmentConditions(cond); err != nil {
+				return zero, err
+			}
+			updateData.Set["lastEvaluatedAt"] = int64(0)
+		}
+	}
+	updated, err := s.BaseServiceMongoImpl.UpdateById(ctx, id, updateData)
+	if err != nil {
+		return updated, err
+	}
+	s.enqueueRefresh(ctx, &updated)
+	return updated, nil
+}
+
+// SetDisabled bật/tắt phân khúc. Phân khúc tắt giữ nguyên thành viên, không được đánh giá; bật lại → ghi lại thành viên từ đầu.
+func (s *CrmSegmentService) SetDisabled(ctx context.Context, ownerOrgID, id primitive.ObjectID, disabled bool) (*crmmodels.CrmSegment, error) {
+	set := bson.M{"isDisabled": disabled, "updatedAt": time.Now().UnixMilli()}
+	if !disabled {
+		set["lastEvaluatedAt"] = int64(0)
+	}
+	var updated crmmodels.CrmSegment
+	err := s.Collection().FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "ownerOrganizationId": ownerOrgID},
+		bson.M{"$set": set},
+		mongoopts.FindOneAndUpdate().SetReturnDocument(mongoopts.After),
+	).Decode(&updated)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, common.NewError(common.ErrCodeValidationInput, "Không tìm thấy phân khúc", common.StatusNotFound, nil)
+		}
+		return nil, common.ConvertMongoError(err)
+	}
+	s.enqueueRefresh(ctx, &updated)
+	return &updated, nil
+}
+
+// DeleteById override — xóa kèm thành viên và số đếm theo ngày của phân khúc.
+func (s *CrmSegmentService) DeleteById(ctx context.Context, id primitive.ObjectID) error {
+	if err := s.BaseServiceMongoImpl.DeleteById(ctx, id); err != nil {
+		return err
+	}
+	if _, err := s.memberSvc.Collection().DeleteMany(ctx, bson.M{"segmentId": id}); err != nil {
+		return common.ConvertMongoError(err)
+	}
+	if _, err := s.countSvc.Collection().DeleteMany(ctx, bson.M{"segmentId": id}); err != nil {
+		return common.ConvertMongoError(err)
+	}
+	return nil
+}
+
+// decodeSegmentConditions chuyển conditions trong $set (map sau utility.ToMap) về struct để kiểm tra.
+func decodeSegmentConditions(raw interface{}) (crmmodels.CrmSegmentCondition, error) {
+	var wrapper struct {
+		Conditions crmmodels.CrmSegmentCondition `bson:"conditions"`
+	}
+	b, err := bson.Marshal(bson.M{"conditions": raw})
+	if err != nil {
+		return wrapper.Conditions, err
+	}
+	err = bson.Unmarshal(b, &wrapper)
+	return wrapper.Conditions, err
+}
+
+// enqueueRefresh xếp bulk job segment_refresh (không chặn API nếu lỗi — refresh định kỳ sẽ bù).
+func (s *CrmSegmentService) enqueueRefresh(ctx context.Context, seg *crmmodels.CrmSegment) {
+	if seg == nil || seg.ID.IsZero() || seg.IsDisabled {
+		return
+	}
+	bulkSvc, err := NewCrmBulkJobService()
+	if err == nil {
+		_, err = bulkSvc.Enqueue(ctx, crmmodels.CrmBulkJobSegmentRefresh, seg.OwnerOrganizationID, bson.M{"segmentId": seg.ID.Hex()}, false)
+	}
+	if err != nil {
+		logger.GetAppLogger().WithError(err).WithField("segmentId", seg.ID.Hex()).Warn("[CRM] Không xếp được job refresh phân khúc")
+	}
+}
+
+// FindActiveSegments phân khúc đang bật của tổ chức.
+func (s *CrmSegmentService) FindActiveSegments(ctx context.Context, ownerOrgID primitive.ObjectID) ([]crmmodels.CrmSegment, error) {
+	return s.Find(ctx, bson.M{"ownerOrganizationId": ownerOrgID, "isDisabled": bson.M{"$ne": true}}, nil)
+}
+
+// FindSegment phân khúc theo id trong tổ chức.
+func (s *CrmSegmentService) FindSegment(ctx context.Context, ownerOrgID, id primitive.ObjectID) (*crmmodels.CrmSegment, error) {
+	seg, err := s.FindOne(ctx, bson.M{"_id": id, "ownerOrganizationId": ownerOrgID}, nil)
+	if err != nil {
+		if err == common.ErrNotFound {
+			return nil, common.NewError(common.ErrCodeValidationInput, "Không tìm thấy phân khúc", common.StatusNotFound, nil)
+		}
+		return nil, err
+	}
+	return &seg, nil
+}
+
+// EvaluateCustomer đánh giá lại một khách với mọi phân khúc đang bật của tổ chức (gọi sau crm_intel_compute refresh /
+// recalculate_one). Khách không còn (bị gộp/xóa) → ra khỏi mọi phân khúc. Phân khúc chưa refresh lần đầu bị bỏ qua.
+func (s *CrmSegmentService) EvaluateCustomer(ctx context.Context, ownerOrgID primitive.ObjectID, unifiedId string) error {
+	if ownerOrgID.IsZero() || unifiedId == "" {
+		return nil
+	}
+	segments, err := s.FindActiveSegments(ctx, ownerOrgID)
+	if err != nil || len(segments) == 0 {
+		return err
+	}
+	var customer *crmmodels.CrmCustomer
+	c, err := s.customerSvc.FindOne(ctx, bson.M{"ownerOrganizationId": ownerOrgID, "unifiedId": unifiedId}, nil)
+	if err == nil {
+		customer = &c
+	} else if err != common.ErrNotFound {
+		return err
+	}
+	members, err := s.memberSvc.Find(ctx, bson.M{"ownerOrganizationId": ownerOrgID, "unifiedId": unifiedId}, nil)
+	if err != nil {
+		return err
+	}
+	isMember := make(map[primitive.ObjectID]bool, len(members))
+	for _, m := range members {
+		isMember[m.SegmentID] = true
+	}
+	now := time.Now()
+	for i := range segments {
+		seg := &segments[i]
+		if seg.LastEvaluatedAt == 0 {
+			continue
+		}
+		matched := customer != nil && EvaluateSegmentCondition(seg.Conditions, customer, now)
+		switch {
+		case matched && !isMember[seg.ID]:
+			err = s.enterSegment(ctx, seg, unifiedId, now)
+		case !matched && isMember[seg.ID]:
+			err = s.exitSegment(ctx, seg, unifiedId, now)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *CrmSegmentService) enterSegment(ctx context.Context, seg *crmmodels.CrmSegment, unifiedId string, now time.Time) error {
+	res, err := s.memberSvc.Collection().UpdateOne(ctx,
+		bson.M{"segmentId": seg.ID, "unifiedId": unifiedId},
+		bson.M{"$setOnInsert": bson.M{"enteredAt": now.UnixMilli(), "ownerOrganizationId": seg.OwnerOrganizationID}},
+		mongoopts.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return common.ConvertMongoError(err)
+	}
+	if res.UpsertedCount == 0 {
+		return nil
+	}
+	return s.afterMembershipChange(ctx, seg, unifiedId, eventtypes.CrmSegmentEntered, 1, now)
+}
+
+func (s *CrmSegmentService) exitSegment(ctx context.Context, seg *crmmodels.CrmSegment, unifiedId string, now time.Time) error {
+	res, err := s.memberSvc.Collection().DeleteOne(ctx, bson.M{"segmentId": seg.ID, "unifiedId": unifiedId})
+	if err != nil {
+		return common.ConvertMongoError(err)
+	}
+	if res.DeletedCount == 0 {
+		return nil
+	}
+	return s.afterMembershipChange(ctx, seg, unifiedId, eventtypes.CrmSegmentExited, -1, now)
+}
+
+// afterMembershipChange cập nhật memberCount, số đếm trong ngày và emit sự kiện vào/ra.
+func (s *CrmSegmentService) afterMembershipChange(ctx context.Context, seg *crmmodels.CrmSegment, unifiedId, eventType string, delta int64, now time.Time) error {
+	var updated crmmodels.CrmSegment
+	err := s.Collection().FindOneAndUpdate(ctx,
+		bson.M{"_id": seg.ID},
+		bson.M{"$inc": bson.M{"memberCount": delta}},
+		mongoopts.FindOneAndUpdate().SetReturnDocument(mongoopts.After),
+	).Decode(&updated)
+	if err != nil {
+		return common.ConvertMongoError(err)
+	}
+	entered, exited := int64(0), int64(0)
+	if delta > 0 {
+		entered = delta
+	} else {
+		exited = -delta
+	}
+	if err := s.recordCount(ctx, seg, updated.MemberCount, entered, exited, now); err != nil {
+		return err
+	}
+	if _, err := crmqueue.EmitCrmSegmentMembershipChanged(ctx, eventType, seg.OwnerOrganizationID, seg.ID.Hex(), seg.Name, unifiedId, now.UnixMilli()); err != nil {
+		logger.GetAppLogger().WithError(err).WithFields(map[string]interface{}{"segmentId": seg.ID.Hex(), "unifiedId": unifiedId}).Warn("[CRM] Emit sự kiện phân khúc thất bại")
+	}
+	return nil
+}
+
+// recordCount ghi số thành viên vào bản đếm của ngày (giờ Việt Nam); entered/exited cộng dồn.
+func (s *CrmSegmentService) recordCount(ctx context.Context, seg *crmmodels.CrmSegment, count, entered, exited int64, now time.Time) error {
+	_, err := s.countSvc.Collection().UpdateOne(ctx,
+		bson.M{"segmentId": seg.ID, "day": crmSegmentDay(now)},
+		bson.M{
+			"$set":         bson.M{"count": count, "ownerOrganizationId": seg.OwnerOrganizationID, "updatedAt": now.UnixMilli()},
+			"$inc":         bson.M{"entered": entered, "exited": exited},
+			"$setOnInsert": bson.M{"createdAt": now.UnixMilli()},
+		},
+		mongoopts.Update().SetUpsert(true),
+	)
+	return common.ConvertMongoError(err)
+}
+
+// crmSegmentDay ngày theo giờ Việt Nam (YYYY-MM-DD) cho bản đếm thành viên.
+func crmSegmentDay(now time.Time) string {
+	loc, err := time.LoadLocation("Asia/Ho_Chi_Minh")
+	if err != nil {
+		loc = time.FixedZone("ICT", 7*3600)
+	}
+	return now.In(loc).Format("2006-01-02")
+}
+
+// CrmSegmentRefreshResult kết quả refresh toàn bộ một phân khúc.
+type CrmSegmentRefreshResult struct {
+	SegmentID        string `json:"segmentId"`
+	CustomersScanned int    `json:"customersScanned"`
+	MemberCount      int64  `json:"memberCount"`
+	Entered          int    `json:"entered"`
+	Exited           int    `json:"exited"`
+	Baseline         bool   `json:"baseline"` // Lần đầu (hoặc sau khi đổi conditions): ghi thành viên, không emit sự kiện
+}
+
+// RefreshSegment đánh giá lại toàn bộ khách của tổ chức với một phân khúc: ghi thành viên mới, xóa thành viên không còn
+// khớp (kể cả khách đã bị gộp/xóa), cập nhật memberCount và bản đếm trong ngày. Điều kiện theo số ngày được làm mới ở đây.
+func (s *CrmSegmentService) RefreshSegment(ctx context.Context, seg *crmmodels.CrmSegment) (*CrmSegmentRefreshResult, error) {
+	now := time.Now()
+	result := &CrmSegmentRefreshResult{SegmentID: seg.ID.Hex(), Baseline: seg.LastEvaluatedAt == 0}
+
+	cursor, err := s.customerSvc.Collection().Find(ctx, bson.M{"ownerOrganizationId": seg.OwnerOrganizationID})
+	if err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	defer cursor.Close(ctx)
+	matched := make(map[string]bool)
+	for cursor.Next(ctx) {
+		var c crmmodels.CrmCustomer
+		if err := cursor.Decode(&c); err != nil {
+			continue
+		}
+		result.CustomersScanned++
+		if c.UnifiedId != "" && EvaluateSegmentCondition(seg.Conditions, &c, now) {
+			matched[c.UnifiedId] = true
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+
+	existingIDs, err := s.memberSvc.Distinct(ctx, "unifiedId", bson.M{"segmentId": seg.ID})
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]bool, len(existingIDs))
+	var exits []string
+	for _, v := range existingIDs {
+		uid, _ := v.(string)
+		existing[uid] = true
+		if !matched[uid] {
+			exits = append(exits, uid)
+		}
+	}
+	var enters []string
+	for uid := range matched {
+		if !existing[uid] {
+			enters = append(enters, uid)
+		}
+	}
+
+	nowMs := now.UnixMilli()
+	for start := 0; start < len(enters); start += crmSegmentWriteBatch {
+		end := min(start+crmSegmentWriteBatch, len(enters))
+		docs := make([]interface{}, 0, end-start)
+		for _, uid := range enters[start:end] {
+			docs = append(docs, crmmodels.CrmSegmentMember{SegmentID: seg.ID, UnifiedId: uid, EnteredAt: nowMs, OwnerOrganizationID: seg.OwnerOrganizationID})
+		}
+		// ordered=false: bản ghi đã được EvaluateCustomer thêm song song → bỏ qua lỗi trùng, ghi tiếp phần còn lại.
+		if _, err := s.memberSvc.Collection().InsertMany(ctx, docs, mongoopts.InsertMany().SetOrdered(false)); err != nil && !mongo.IsDuplicateKeyError(err) {
+			return nil, common.ConvertMongoError(err)
+		}
+	}
+	for start := 0; start < len(exits); start += crmSegmentWriteBatch {
+		end := min(start+crmSegmentWriteBatch, len(exits))
+		if _, err := s.memberSvc.Collection().DeleteMany(ctx, bson.M{"segmentId": seg.ID, "unifiedId": bson.M{"$in": exits[start:end]}}); err != nil {
+			return nil, common.ConvertMongoError(err)
+		}
+	}
+	result.Entered, result.Exited = len(enters), len(exits)
+	result.MemberCount = int64(len(matched))
+
+	if _, err := s.Collection().UpdateOne(ctx, bson.M{"_id": seg.ID}, bson.M{"$set": bson.M{"memberCount": result.MemberCount, "lastEvaluatedAt": nowMs}}); err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	if result.Baseline {
+		if err := s.recordCount(ctx, seg, result.MemberCount, 0, 0, now); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+	if err := s.recordCount(ctx, seg, result.MemberCount, int64(len(enters)), int64(len(exits)), now); err != nil {
+		return nil, err
+	}
+	emit := func(eventType string, uids []string) {
+		for _, uid := range uids {
+			if _, err := crmqueue.EmitCrmSegmentMembershipChanged(ctx, eventType, seg.OwnerOrganizationID, seg.ID.Hex(), seg.Name, uid, nowMs); err != nil {
+				logger.GetAppLogger().WithError(err).WithFields(map[string]interface{}{"segmentId": seg.ID.Hex(), "unifiedId": uid}).Warn("[CRM] Emit sự kiện phân khúc thất bại")
+			}
+		}
+	}
+	emit(eventtypes.CrmSegmentEntered, enters)
+	emit(eventtypes.CrmSegmentExited, exits)
+	return result, nil
+}
+
+// RefreshOrgSegments refresh mọi phân khúc đang bật của tổ chức. Trả số phân khúc đã refresh.
+func (s *CrmSegmentService) RefreshOrgSegments(ctx context.Context, ownerOrgID primitive.ObjectID) (int, error) {
+	segments, err := s.FindActiveSegments(ctx, ownerOrgID)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for i := range segments {
+		if _, err := s.RefreshSegment(ctx, &segments[i]); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// RefreshAllSegments refresh phân khúc của mọi tổ chức có phân khúc đang bật (sau classification refresh định kỳ).
+func (s *CrmSegmentService) RefreshAllSegments(ctx context.Context) (int, error) {
+	orgIDs, err := s.Distinct(ctx, "ownerOrganizationId", bson.M{"isDisabled": bson.M{"$ne": true}})
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, v := range orgIDs {
+		orgID, ok := v.(primitive.ObjectID)
+		if !ok {
+			continue
+		}
+		refreshed, err := s.RefreshOrgSegments(ctx, orgID)
+		n += refreshed
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// ListMembers thành viên phân khúc, mới vào trước, kèm tổng số.
+func (s *CrmSegmentService) ListMembers(ctx context.Context, segmentID primitive.ObjectID, page, limit int64) ([]crmmodels.CrmSegmentMember, int64, error) {
+	filter := bson.M{"segmentId": segmentID}
+	total, err := s.memberSvc.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	opts := mongoopts.Find().SetSort(bson.D{{Key: "enteredAt", Value: -1}, {Key: "_id", Value: -1}}).SetSkip((page - 1) * limit).SetLimit(limit)
+	members, err := s.memberSvc.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return members, total, nil
+}
+
+// ForEachMemberCustomer duyệt khách thuộc phân khúc theo lô (cho export). fn nhận thành viên và khách (nil nếu khách đã bị xóa).
+func (s *CrmSegmentService) ForEachMemberCustomer(ctx context.Context, seg *crmmodels.CrmSegment, fn func(m *crmmodels.CrmSegmentMember, c *crmmodels.CrmCustomer) error) error {
+	cursor, err := s.memberSvc.Collection().Find(ctx, bson.M{"segmentId": seg.ID}, mongoopts.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return common.ConvertMongoError(err)
+	}
+	defer cursor.Close(ctx)
+	batch := make([]crmmodels.CrmSegmentMember, 0, crmSegmentWriteBatch)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		customers, err := s.MemberCustomers(ctx, seg.OwnerOrganizationID, batch)
+		if err != nil {
+			return err
+		}
+		for i := range batch {
+			if err := fn(&batch[i], customers[batch[i].UnifiedId]); err != nil {
+				return err
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+	for cursor.Next(ctx) {
+		var m crmmodels.CrmSegmentMember
+		if err := cursor.Decode(&m); err != nil {
+			continue
+		}
+		batch = append(batch, m)
+		if len(batch) == crmSegmentWriteBatch {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return common.ConvertMongoError(err)
+	}
+	return flush()
+}
+
+// MemberCustomers khách (theo unifiedId) của danh sách thành viên.
+func (s *CrmSegmentService) MemberCustomers(ctx context.Context, ownerOrgID primitive.ObjectID, members []crmmodels.CrmSegmentMember) (map[string]*crmmodels.CrmCustomer, error) {
+	uids := make([]string, 0, len(members))
+	for _, m := range members {
+		uids = append(uids, m.UnifiedId)
+	}
+	out := make(map[string]*crmmodels.CrmCustomer, len(uids))
+	if len(uids) == 0 {
+		return out, nil
+	}
+	customers, err := s.customerSvc.Find(ctx, bson.M{"ownerOrganizationId": ownerOrgID, "unifiedId": bson.M{"$in": uids}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	for i := range customers {
+		out[customers[i].UnifiedId] = &customers[i]
+	}
+	return out, nil
+}
+
+// FindCountHistory số thành viên theo ngày trong khoảng [from, to] (YYYY-MM-DD, rỗng = không giới hạn).
+func (s *CrmSegmentService) FindCountHistory(ctx context.Context, segmentID primitive.ObjectID, from, to string) ([]crmmodels.CrmSegmentCountSnapshot, error) {
+	filter := bson.M{"segmentId": segmentID}
+	day := bson.M{}
+	if from != "" {
+		day["$gte"] = from
+	}
+	if to != "" {
+		day["$lte"] = to
+	}
+	if len(day) > 0 {
+		filter["day"] = day
+	}
+	return s.countSvc.Find(ctx, filter, mongoopts.Find().SetSort(bson.M{"day": 1}))
+}
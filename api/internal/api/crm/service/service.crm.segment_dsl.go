@@ -0,0 +1,439 @@
+// Package crmvc — DSL điều kiện phân khúc khách: kiểm tra khi lưu và đánh giá trên một khách trong bộ nhớ.
+// Chỉ nhận field trong danh sách cho phép và toán tử theo kiểu field — không bao giờ chuyển thẳng thành query Mongo.
+package crmvc
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	crmmodels "meta_commerce/internal/api/crm/models"
+	"meta_commerce/internal/common"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Giới hạn độ phức tạp định nghĩa phân khúc (đánh giá chạy mỗi lần worker intel tính lại khách).
+const (
+	crmSegmentMaxDepth      = 4
+	crmSegmentMaxConditions = 30
+	crmSegmentMaxListValues = 100
+)
+
+// Kiểu field DSL — quyết định toán tử và kiểu value hợp lệ.
+const (
+	crmSegmentKindString = "string" // eq, ne, in, nin, exists (không phân biệt hoa thường)
+	crmSegmentKindNumber = "number" // eq, ne, gt, gte, lt, lte
+	crmSegmentKindTime   = "time"   // older_than_days, within_days, exists, gt/gte/lt/lte (unix ms)
+	crmSegmentKindBool   = "bool"   // eq
+	crmSegmentKindTags   = "tags"   // in (có ít nhất một), nin (không có cái nào), exists
+	crmSegmentKindSku    = "sku"    // key = SKU; exists (đang sở hữu), eq, gt, gte, lt, lte theo số lượng
+	crmSegmentKindCustom = "custom" // key = key trường tùy chỉnh; toán tử theo kiểu giá trị đang lưu trên khách
+)
+
+var crmSegmentKindOps = map[string][]string{
+	crmSegmentKindString: {crmmodels.CrmSegmentOpEq, crmmodels.CrmSegmentOpNe, crmmodels.CrmSegmentOpIn, crmmodels.CrmSegmentOpNin, crmmodels.CrmSegmentOpExists},
+	crmSegmentKindNumber: {crmmodels.CrmSegmentOpEq, crmmodels.CrmSegmentOpNe, crmmodels.CrmSegmentOpGt, crmmodels.CrmSegmentOpGte, crmmodels.CrmSegmentOpLt, crmmodels.CrmSegmentOpLte},
+	crmSegmentKindTime:   {crmmodels.CrmSegmentOpOlderThanDays, crmmodels.CrmSegmentOpWithinDays, crmmodels.CrmSegmentOpExists, crmmodels.CrmSegmentOpGt, crmmodels.CrmSegmentOpGte, crmmodels.CrmSegmentOpLt, crmmodels.CrmSegmentOpLte},
+	crmSegmentKindBool:   {crmmodels.CrmSegmentOpEq},
+	crmSegmentKindTags:   {crmmodels.CrmSegmentOpIn, crmmodels.CrmSegmentOpNin, crmmodels.CrmSegmentOpExists},
+	crmSegmentKindSku:    {crmmodels.CrmSegmentOpExists, crmmodels.CrmSegmentOpEq, crmmodels.CrmSegmentOpGt, crmmodels.CrmSegmentOpGte, crmmodels.CrmSegmentOpLt, crmmodels.CrmSegmentOpLte},
+	crmSegmentKindCustom: {crmmodels.CrmSegmentOpEq, crmmodels.CrmSegmentOpNe, crmmodels.CrmSegmentOpIn, crmmodels.CrmSegmentOpNin, crmmodels.CrmSegmentOpExists, crmmodels.CrmSegmentOpGt, crmmodels.CrmSegmentOpGte, crmmodels.CrmSegmentOpLt, crmmodels.CrmSegmentOpLte, crmmodels.CrmSegmentOpOlderThanDays, crmmodels.CrmSegmentOpWithinDays},
+}
+
+type crmSegmentField struct {
+	kind string
+	get  func(c *crmmodels.CrmCustomer) interface{}
+}
+
+func crmSegmentNumberField(key string) crmSegmentField {
+	return crmSegmentField{kind: crmSegmentKindNumber, get: func(c *crmmodels.CrmCustomer) interface{} { return GetFloatFromCustomer(c, key) }}
+}
+
+func crmSegmentCountField(key string) crmSegmentField {
+	return crmSegmentField{kind: crmSegmentKindNumber, get: func(c *crmmodels.CrmCustomer) interface{} { return float64(GetIntFromCustomer(c, key)) }}
+}
+
+func crmSegmentTimeField(key string) crmSegmentField {
+	return crmSegmentField{kind: crmSegmentKindTime, get: func(c *crmmodels.CrmCustomer) interface{} { return GetInt64FromCustomer(c, key) }}
+}
+
+func crmSegmentBoolField(key string) crmSegmentField {
+	return crmSegmentField{kind: crmSegmentKindBool, get: func(c *crmmodels.CrmCustomer) interface{} { return GetBoolFromCustomer(c, key) }}
+}
+
+// crmSegmentFields field cho phép trong DSL. Metrics đọc qua getter currentMetrics (cùng nguồn với classification).
+var crmSegmentFields = map[string]crmSegmentField{
+	"valueTier":      {kind: crmSegmentKindString, get: func(c *crmmodels.CrmCustomer) interface{} { return c.ValueTier }},
+	"lifecycleStage": {kind: crmSegmentKindString, get: func(c *crmmodels.CrmCustomer) interface{} { return c.LifecycleStage }},
+	"journeyStage":   {kind: crmSegmentKindString, get: func(c *crmmodels.CrmCustomer) interface{} { return c.JourneyStage }},
+	"channel":        {kind: crmSegmentKindString, get: func(c *crmmodels.CrmCustomer) interface{} { return c.Channel }},
+	"loyaltyStage":   {kind: crmSegmentKindString, get: func(c *crmmodels.CrmCustomer) interface{} { return c.LoyaltyStage }},
+	"momentumStage":  {kind: crmSegmentKindString, get: func(c *crmmodels.CrmCustomer) interface{} { return c.MomentumStage }},
+
+	"totalSpent":          {kind: crmSegmentKindNumber, get: func(c *crmmodels.CrmCustomer) interface{} { return GetTotalSpentFromCustomer(c) }},
+	"orderCount":          {kind: crmSegmentKindNumber, get: func(c *crmmodels.CrmCustomer) interface{} { return float64(GetOrderCountFromCustomer(c)) }},
+	"avgOrderValue":       crmSegmentNumberField("avgOrderValue"),
+	"revenueLast30d":      crmSegmentNumberField("revenueLast30d"),
+	"revenueLast90d":      crmSegmentNumberField("revenueLast90d"),
+	"ordersLast30d":       crmSegmentCountField("ordersLast30d"),
+	"ordersLast90d":       crmSegmentCountField("ordersLast90d"),
+	"cancelledOrderCount": crmSegmentCountField("cancelledOrderCount"),
+	"conversationCount":   crmSegmentCountField("conversationCount"),
+	"totalMessages":       crmSegmentCountField("totalMessages"),
+
+	"lastOrderAt":         {kind: crmSegmentKindTime, get: func(c *crmmodels.CrmCustomer) interface{} { return GetLastOrderAtFromCustomer(c) }},
+	"secondLastOrderAt":   crmSegmentTimeField("secondLastOrderAt"),
+	"lastConversationAt":  crmSegmentTimeField("lastConversationAt"),
+	"firstConversationAt": crmSegmentTimeField("firstConversationAt"),
+
+	"hasOrder":            crmSegmentBoolField("hasOrder"),
+	"hasConversation":     crmSegmentBoolField("hasConversation"),
+	"isOmnichannel":       crmSegmentBoolField("isOmnichannel"),
+	"conversationFromAds": crmSegmentBoolField("conversationFromAds"),
+
+	"conversationTags":   {kind: crmSegmentKindTags, get: func(c *crmmodels.CrmCustomer) interface{} { return c.ConversationTags }},
+	"ownedSkuQuantities": {kind: crmSegmentKindSku, get: func(c *crmmodels.CrmCustomer) interface{} { return c.OwnedSkuQuantities }},
+
+	"tags":         {kind: crmSegmentKindTags, get: func(c *crmmodels.CrmCustomer) interface{} { return c.Tags }},
+	"customFields": {kind: crmSegmentKindCustom, get: func(c *crmmodels.CrmCustomer) interface{} { return c.CustomFields }},
+}
+
+// ValidateSegmentConditions kiểm tra định nghĩa phân khúc trước khi lưu: field/toán tử/value hợp lệ, không rỗng, không quá sâu.
+func ValidateSegmentConditions(cond crmmodels.CrmSegmentCondition) error {
+	leaves := 0
+	if err := validateSegmentNode(cond, "conditions", 1, &leaves); err != nil {
+		return common.NewError(common.ErrCodeValidationInput, err.Error(), common.StatusBadRequest, nil)
+	}
+	if leaves == 0 {
+		return common.NewError(common.ErrCodeValidationInput, "conditions: cần ít nhất một điều kiện", common.StatusBadRequest, nil)
+	}
+	return nil
+}
+
+func validateSegmentNode(cond crmmodels.CrmSegmentCondition, path string, depth int, leaves *int) error {
+	if depth > crmSegmentMaxDepth {
+		return fmt.Errorf("%s: lồng nhóm quá %d cấp", path, crmSegmentMaxDepth)
+	}
+	isGroup := len(cond.All) > 0 || len(cond.Any) > 0
+	if isGroup {
+		if cond.Field != "" || cond.Op != "" {
+			return fmt.Errorf("%s: nút nhóm (all/any) không được có field/op", path)
+		}
+		for i, sub := range cond.All {
+			if err := validateSegmentNode(sub, fmt.Sprintf("%s.all[%d]", path, i), depth+1, leaves); err != nil {
+				return err
+			}
+		}
+		for i, sub := range cond.Any {
+			if err := validateSegmentNode(sub, fmt.Sprintf("%s.any[%d]", path, i), depth+1, leaves); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if cond.Field == "" {
+		return fmt.Errorf("%s: thiếu field hoặc all/any", path)
+	}
+	*leaves++
+	if *leaves > crmSegmentMaxConditions {
+		return fmt.Errorf("tối đa %d điều kiện", crmSegmentMaxConditions)
+	}
+	f, ok := crmSegmentFields[cond.Field]
+	if !ok {
+		return fmt.Errorf("%s: field %q không được hỗ trợ", path, cond.Field)
+	}
+	if !contains(crmSegmentKindOps[f.kind], cond.Op) {
+		return fmt.Errorf("%s: toán tử %q không dùng được với %s (cho phép: %s)", path, cond.Op, cond.Field, strings.Join(crmSegmentKindOps[f.kind], ", "))
+	}
+	if f.kind == crmSegmentKindSku || f.kind == crmSegmentKindCustom {
+		if strings.TrimSpace(cond.Key) == "" {
+			return fmt.Errorf("%s: %s cần key", path, cond.Field)
+		}
+	} else if cond.Key != "" {
+		return fmt.Errorf("%s: %s không dùng key", path, cond.Field)
+	}
+	return validateSegmentValue(f.kind, cond, path)
+}
+
+func validateSegmentValue(kind string, cond crmmodels.CrmSegmentCondition, path string) error {
+	switch cond.Op {
+	case crmmodels.CrmSegmentOpExists:
+		if cond.Value == nil {
+			return nil
+		}
+		if _, ok := cond.Value.(bool); !ok {
+			return fmt.Errorf("%s: exists cần value true/false hoặc bỏ trống", path)
+		}
+	case crmmodels.CrmSegmentOpIn, crmmodels.CrmSegmentOpNin:
+		list, ok := segmentStrings(cond.Value)
+		if !ok || len(list) == 0 || len(list) > crmSegmentMaxListValues {
+			return fmt.Errorf("%s: %s cần danh sách chuỗi (1..%d phần tử)", path, cond.Op, crmSegmentMaxListValues)
+		}
+	case crmmodels.CrmSegmentOpOlderThanDays, crmmodels.CrmSegmentOpWithinDays:
+		if n, ok := segmentNumber(cond.Value); !ok || n <= 0 {
+			return fmt.Errorf("%s: %s cần số ngày > 0", path, cond.Op)
+		}
+	case crmmodels.CrmSegmentOpEq, crmmodels.CrmSegmentOpNe:
+		switch kind {
+		case crmSegmentKindString:
+			if _, ok := cond.Value.(string); !ok {
+				return fmt.Errorf("%s: %s cần value chuỗi", path, cond.Op)
+			}
+		case crmSegmentKindBool:
+			if _, ok := cond.Value.(bool); !ok {
+				return fmt.Errorf("%s: %s cần value true/false", path, cond.Op)
+			}
+		case crmSegmentKindCustom:
+			_, isStr := cond.Value.(string)
+			_, isBool := cond.Value.(bool)
+			if _, isNum := segmentNumber(cond.Value); !isStr && !isBool && !isNum {
+				return fmt.Errorf("%s: %s cần value chuỗi, số hoặc true/false", path, cond.Op)
+			}
+		default:
+			if _, ok := segmentNumber(cond.Value); !ok {
+				return fmt.Errorf("%s: %s cần value số", path, cond.Op)
+			}
+		}
+	default: // gt, gte, lt, lte
+		if _, ok := segmentNumber(cond.Value); !ok {
+			return fmt.Errorf("%s: %s cần value số", path, cond.Op)
+		}
+	}
+	return nil
+}
+
+// EvaluateSegmentCondition đánh giá điều kiện trên một khách tại thời điểm now (cho toán tử theo số ngày).
+// Nhóm: mọi điều kiện trong all đúng và (nếu có any) ít nhất một điều kiện trong any đúng. Field lạ → false.
+func EvaluateSegmentCondition(cond crmmodels.CrmSegmentCondition, c *crmmodels.CrmCustomer, now time.Time) bool {
+	if c == nil {
+		return false
+	}
+	if len(cond.All) > 0 || len(cond.Any) > 0 {
+		for _, sub := range cond.All {
+			if !EvaluateSegmentCondition(sub, c, now) {
+				return false
+			}
+		}
+		if len(cond.Any) == 0 {
+			return true
+		}
+		for _, sub := range cond.Any {
+			if EvaluateSegmentCondition(sub, c, now) {
+				return true
+			}
+		}
+		return false
+	}
+	f, ok := crmSegmentFields[cond.Field]
+	if !ok {
+		return false
+	}
+	v := f.get(c)
+	switch f.kind {
+	case crmSegmentKindString:
+		s, _ := v.(string)
+		return evalSegmentString(cond, s)
+	case crmSegmentKindNumber:
+		n, _ := segmentNumber(v)
+		return evalSegmentCompare(cond, n)
+	case crmSegmentKindTime:
+		ms, _ := v.(int64)
+		return evalSegmentTime(cond, ms, now)
+	case crmSegmentKindBool:
+		b, _ := v.(bool)
+		want, _ := cond.Value.(bool)
+		return b == want
+	case crmSegmentKindTags:
+		tags, _ := v.([]string)
+		return evalSegmentTags(cond, tags)
+	case crmSegmentKindSku:
+		owned, _ := v.(map[string]int)
+		qty := float64(owned[cond.Key])
+		if cond.Op == crmmodels.CrmSegmentOpExists {
+			return (qty > 0) == segmentExistsWant(cond)
+		}
+		return evalSegmentCompare(cond, qty)
+	case crmSegmentKindCustom:
+		fields, _ := v.(map[string]interface{})
+		return evalSegmentCustom(cond, fields[cond.Key], now)
+	}
+	return false
+}
+
+func evalSegmentString(cond crmmodels.CrmSegmentCondition, s string) bool {
+	switch cond.Op {
+	case crmmodels.CrmSegmentOpEq:
+		want, _ := cond.Value.(string)
+		return strings.EqualFold(s, want)
+	case crmmodels.CrmSegmentOpNe:
+		want, _ := cond.Value.(string)
+		return !strings.EqualFold(s, want)
+	case crmmodels.CrmSegmentOpIn, crmmodels.CrmSegmentOpNin:
+		list, _ := segmentStrings(cond.Value)
+		found := false
+		for _, item := range list {
+			if strings.EqualFold(s, item) {
+				found = true
+				break
+			}
+		}
+		return found == (cond.Op == crmmodels.CrmSegmentOpIn)
+	case crmmodels.CrmSegmentOpExists:
+		return (s != "") == segmentExistsWant(cond)
+	}
+	return false
+}
+
+func evalSegmentCompare(cond crmmodels.CrmSegmentCondition, n float64) bool {
+	want, ok := segmentNumber(cond.Value)
+	if !ok {
+		return false
+	}
+	switch cond.Op {
+	case crmmodels.CrmSegmentOpEq:
+		return n == want
+	case crmmodels.CrmSegmentOpNe:
+		return n != want
+	case crmmodels.CrmSegmentOpGt:
+		return n > want
+	case crmmodels.CrmSegmentOpGte:
+		return n >= want
+	case crmmodels.CrmSegmentOpLt:
+		return n < want
+	case crmmodels.CrmSegmentOpLte:
+		return n <= want
+	}
+	return false
+}
+
+// evalSegmentTime — older_than_days đúng cả khi chưa có mốc (chưa từng mua = "N ngày chưa mua").
+func evalSegmentTime(cond crmmodels.CrmSegmentCondition, ms int64, now time.Time) bool {
+	switch cond.Op {
+	case crmmodels.CrmSegmentOpOlderThanDays, crmmodels.CrmSegmentOpWithinDays:
+		days, _ := segmentNumber(cond.Value)
+		cutoff := now.Add(-time.Duration(days * float64(24*time.Hour))).UnixMilli()
+		if cond.Op == crmmodels.CrmSegmentOpOlderThanDays {
+			return ms <= 0 || ms < cutoff
+		}
+		return ms > 0 && ms >= cutoff
+	case crmmodels.CrmSegmentOpExists:
+		return (ms > 0) == segmentExistsWant(cond)
+	}
+	return evalSegmentCompare(cond, float64(ms))
+}
+
+func evalSegmentTags(cond crmmodels.CrmSegmentCondition, tags []string) bool {
+	if cond.Op == crmmodels.CrmSegmentOpExists {
+		return (len(tags) > 0) == segmentExistsWant(cond)
+	}
+	list, _ := segmentStrings(cond.Value)
+	found := false
+	for _, tag := range tags {
+		for _, item := range list {
+			if strings.EqualFold(tag, item) {
+				found = true
+				break
+			}
+		}
+	}
+	return found == (cond.Op == crmmodels.CrmSegmentOpIn)
+}
+
+// evalSegmentCustom đánh giá theo kiểu giá trị đang lưu: mảng (multi_enum) như tag, số / ngày (unix ms) như số / thời gian,
+// chuỗi như chuỗi. Khách chưa có giá trị: chỉ exists=false, ne, nin, older_than_days đúng.
+func evalSegmentCustom(cond crmmodels.CrmSegmentCondition, v interface{}, now time.Time) bool {
+	if v == nil {
+		switch cond.Op {
+		case crmmodels.CrmSegmentOpExists:
+			return !segmentExistsWant(cond)
+		case crmmodels.CrmSegmentOpNe, crmmodels.CrmSegmentOpNin, crmmodels.CrmSegmentOpOlderThanDays:
+			return true
+		}
+		return false
+	}
+	if list, ok := segmentStrings(v); ok {
+		switch cond.Op {
+		case crmmodels.CrmSegmentOpEq, crmmodels.CrmSegmentOpNe:
+			want, _ := cond.Value.(string)
+			return evalSegmentTags(crmmodels.CrmSegmentCondition{Op: crmmodels.CrmSegmentOpIn, Value: []string{want}}, list) == (cond.Op == crmmodels.CrmSegmentOpEq)
+		case crmmodels.CrmSegmentOpIn, crmmodels.CrmSegmentOpNin, crmmodels.CrmSegmentOpExists:
+			return evalSegmentTags(cond, list)
+		}
+		return false
+	}
+	if n, ok := segmentNumber(v); ok {
+		switch cond.Op {
+		case crmmodels.CrmSegmentOpExists:
+			return segmentExistsWant(cond)
+		case crmmodels.CrmSegmentOpOlderThanDays, crmmodels.CrmSegmentOpWithinDays:
+			return evalSegmentTime(cond, int64(n), now)
+		}
+		return evalSegmentCompare(cond, n)
+	}
+	if b, ok := v.(bool); ok {
+		want, _ := cond.Value.(bool)
+		switch cond.Op {
+		case crmmodels.CrmSegmentOpEq:
+			return b == want
+		case crmmodels.CrmSegmentOpNe:
+			return b != want
+		case crmmodels.CrmSegmentOpExists:
+			return segmentExistsWant(cond)
+		}
+		return false
+	}
+	if str, ok := v.(string); ok {
+		return evalSegmentString(cond, str)
+	}
+	return false
+}
+
+// segmentExistsWant value của exists — bỏ trống nghĩa là true.
+func segmentExistsWant(cond crmmodels.CrmSegmentCondition) bool {
+	if b, ok := cond.Value.(bool); ok {
+		return b
+	}
+	return true
+}
+
+// segmentNumber đọc value số từ JSON (float64) hoặc BSON (int32/int64/float64).
+func segmentNumber(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case float32:
+		return float64(x), true
+	case int:
+		return float64(x), true
+	case int32:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	}
+	return 0, false
+}
+
+// segmentStrings đọc danh sách chuỗi từ JSON ([]interface{}) hoặc BSON (primitive.A).
+func segmentStrings(v interface{}) ([]string, bool) {
+	var items []interface{}
+	switch x := v.(type) {
+	case []string:
+		return x, true
+	case []interface{}:
+		items = x
+	case primitive.A:
+		items = x
+	default:
+		return nil, false
+	}
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, false
+		}
+		out = append(out, s)
+	}
+	return out, true
+}
@@ -0,0 +1,167 @@
+// Package crmvc - Test DSL điều kiện phân khúc khách động.
+package crmvc
+
+import (
+	"testing"
+	"time"
+
+	crmmodels "meta_commerce/internal/api/crm/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestValidateSegmentConditions(t *testing.T) {
+	valid := crmmodels.CrmSegmentCondition{All: []crmmodels.CrmSegmentCondition{
+		{Field: "journeyStage", Op: "eq", Value: "repeat"},
+		{Any: []crmmodels.CrmSegmentCondition{
+			{Field: "conversationTags", Op: "in", Value: []interface{}{"vip"}},
+			{Field: "hasOrder", Op: "eq", Value: true},
+		}},
+	}}
+	if err := ValidateSegmentConditions(valid); err != nil {
+		t.Fatalf("điều kiện hợp lệ bị từ chối: %v", err)
+	}
+
+	invalid := map[string]crmmodels.CrmSegmentCondition{
+		"rỗng":                  {},
+		"field lạ":              {Field: "passwordHash", Op: "eq", Value: "x"},
+		"toán tử sai loại":      {Field: "totalSpent", Op: "in", Value: []interface{}{1}},
+		"thiếu key SKU":         {Field: "ownedSkuQuantities", Op: "exists"},
+		"số ngày không phải số": {Field: "lastOrderAt", Op: "older_than_days", Value: "45"},
+		"bool sai kiểu":         {Field: "hasOrder", Op: "eq", Value: "true"},
+		"vừa lá vừa nhóm":       {Field: "valueTier", Op: "eq", Value: "vip", All: []crmmodels.CrmSegmentCondition{{Field: "hasOrder", Op: "eq", Value: true}}},
+	}
+	for name, cond := range invalid {
+		if err := ValidateSegmentConditions(cond); err == nil {
+			t.Errorf("%s: phải trả lỗi", name)
+		}
+	}
+
+	deep := crmmodels.CrmSegmentCondition{Field: "hasOrder", Op: "eq", Value: true}
+	for i := 0; i < 5; i++ {
+		deep = crmmodels.CrmSegmentCondition{All: []crmmodels.CrmSegmentCondition{deep}}
+	}
+	if err := ValidateSegmentConditions(deep); err == nil {
+		t.Error("quá sâu: phải trả lỗi")
+	}
+}
+
+func TestEvaluateSegmentCondition_RepeatHighSpenderLapsedWithSku(t *testing.T) {
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	day := int64(24 * time.Hour / time.Millisecond)
+	cond := crmmodels.CrmSegmentCondition{All: []crmmodels.CrmSegmentCondition{
+		{Field: "journeyStage", Op: "eq", Value: "REPEAT"},
+		{Field: "totalSpent", Op: "gt", Value: 2000000},
+		{Field: "lastOrderAt", Op: "older_than_days", Value: 45},
+		{Field: "ownedSkuQuantities", Key: "X", Op: "exists"},
+	}}
+	if err := ValidateSegmentConditions(cond); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	base := func() *crmmodels.CrmCustomer {
+		return &crmmodels.CrmCustomer{
+			JourneyStage:       "repeat",
+			TotalSpent:         2500000,
+			LastOrderAt:        now.UnixMilli() - 60*day,
+			OwnedSkuQuantities: map[string]int{"X": 2},
+		}
+	}
+	if !EvaluateSegmentCondition(cond, base(), now) {
+		t.Fatal("khách thỏa mọi điều kiện phải thuộc phân khúc")
+	}
+
+	c := base()
+	c.LastOrderAt = now.UnixMilli() - 10*day
+	if EvaluateSegmentCondition(cond, c, now) {
+		t.Error("mua 10 ngày trước không phải 45 ngày chưa mua")
+	}
+	c = base()
+	c.TotalSpent = 2000000
+	if EvaluateSegmentCondition(cond, c, now) {
+		t.Error("gt là so sánh chặt")
+	}
+	c = base()
+	c.OwnedSkuQuantities = map[string]int{"Y": 1}
+	if EvaluateSegmentCondition(cond, c, now) {
+		t.Error("không có SKU X")
+	}
+}
+
+func TestEvaluateSegmentCondition_Operators(t *testing.T) {
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	day := int64(24 * time.Hour / time.Millisecond)
+	c := &crmmodels.CrmCustomer{
+		ValueTier:        "vip",
+		ConversationTags: []string{"Hỏi giá", "vip"},
+		LastOrderAt:      now.UnixMilli() - 3*day,
+	}
+
+	cases := []struct {
+		name string
+		cond crmmodels.CrmSegmentCondition
+		want bool
+	}{
+		{"in với primitive.A (đọc từ Mongo)", crmmodels.CrmSegmentCondition{Field: "valueTier", Op: "in", Value: primitive.A{"VIP", "high"}}, true},
+		{"nin", crmmodels.CrmSegmentCondition{Field: "valueTier", Op: "nin", Value: []interface{}{"vip"}}, false},
+		{"tag in", crmmodels.CrmSegmentCondition{Field: "conversationTags", Op: "in", Value: []interface{}{"hỏi giá"}}, true},
+		{"tag nin", crmmodels.CrmSegmentCondition{Field: "conversationTags", Op: "nin", Value: []interface{}{"spam"}}, true},
+		{"within_days", crmmodels.CrmSegmentCondition{Field: "lastOrderAt", Op: "within_days", Value: 7}, true},
+		{"older_than_days khi chưa từng có", crmmodels.CrmSegmentCondition{Field: "lastConversationAt", Op: "older_than_days", Value: 30}, true},
+		{"exists false", crmmodels.CrmSegmentCondition{Field: "lastConversationAt", Op: "exists", Value: false}, true},
+		{"any không điều kiện nào đúng", crmmodels.CrmSegmentCondition{Any: []crmmodels.CrmSegmentCondition{
+			{Field: "valueTier", Op: "eq", Value: "low"},
+			{Field: "orderCount", Op: "gte", Value: 1},
+		}}, false},
+		{"all + any", crmmodels.CrmSegmentCondition{
+			All: []crmmodels.CrmSegmentCondition{{Field: "valueTier", Op: "eq", Value: "vip"}},
+			Any: []crmmodels.CrmSegmentCondition{
+				{Field: "valueTier", Op: "eq", Value: "low"},
+				{Field: "lastOrderAt", Op: "within_days", Value: 7},
+			},
+		}, true},
+	}
+	for _, tc := range cases {
+		if got := EvaluateSegmentCondition(tc.cond, c, now); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestEvaluateSegmentCondition_TagsAndCustomFields(t *testing.T) {
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	day := int64(24 * time.Hour / time.Millisecond)
+	cond := crmmodels.CrmSegmentCondition{All: []crmmodels.CrmSegmentCondition{
+		{Field: "tags", Op: "in", Value: []interface{}{"Sỉ"}},
+		{Field: "customFields", Key: "skinType", Op: "eq", Value: "Khô"},
+		{Field: "customFields", Key: "creditLimit", Op: "gte", Value: 1000000},
+		{Field: "customFields", Key: "preferredSize", Op: "in", Value: []interface{}{"M", "L"}},
+		{Field: "customFields", Key: "firstVisit", Op: "within_days", Value: 30},
+	}}
+	if err := ValidateSegmentConditions(cond); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	c := &crmmodels.CrmCustomer{
+		Tags: []string{"Sỉ", "VIP"},
+		CustomFields: map[string]interface{}{
+			"skinType":      "Khô",
+			"creditLimit":   int64(2000000),
+			"preferredSize": []string{"S", "M"},
+			"firstVisit":    now.UnixMilli() - 10*day,
+		},
+	}
+	if !EvaluateSegmentCondition(cond, c, now) {
+		t.Fatal("khách thoả mọi điều kiện phải thuộc phân khúc")
+	}
+	c.CustomFields["skinType"] = "Dầu"
+	if EvaluateSegmentCondition(cond, c, now) {
+		t.Error("skinType khác: không thuộc phân khúc")
+	}
+
+	missing := crmmodels.CrmSegmentCondition{Field: "customFields", Key: "wholesale", Op: "exists", Value: false}
+	if !EvaluateSegmentCondition(missing, c, now) {
+		t.Error("exists=false: khách chưa có trường phải khớp")
+	}
+	if err := ValidateSegmentConditions(crmmodels.CrmSegmentCondition{Field: "customFields", Op: "exists"}); err == nil {
+		t.Error("customFields thiếu key: phải trả lỗi")
+	}
+}
@@ -0,0 +1,398 @@
+// Package crmvc — Tag khách: danh mục theo tổ chức (customer_core_tags, tên + màu), gắn / gỡ trên từng khách
+// hoặc hàng loạt theo phân khúc / điều kiện DSL (bulk job tag_apply).
+package crmvc
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	basesvc "meta_commerce/internal/api/base/service"
+	crmmodels "meta_commerce/internal/api/crm/models"
+	"meta_commerce/internal/common"
+	"meta_commerce/internal/global"
+	"meta_commerce/internal/logger"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongoopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Hành động gắn / gỡ tag.
+const (
+	CrmTagActionAdd    = "add"
+	CrmTagActionRemove = "remove"
+)
+
+const (
+	crmTagMaxNameLen     = 50
+	crmTagMaxPerRequest  = 20
+	crmTagMaxDirectApply = 500 // Số khách tối đa khi gắn theo danh sách unifiedIds (đồng bộ); nhiều hơn dùng phân khúc / điều kiện
+	crmTagApplyBatch     = 500
+)
+
+var crmTagColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// CrmCustomerTagService CRUD danh mục tag và gắn / gỡ tag trên khách.
+type CrmCustomerTagService struct {
+	*basesvc.BaseServiceMongoImpl[crmmodels.CrmCustomerTag]
+	customerSvc *CrmCustomerService
+}
+
+// NewCrmCustomerTagService tạo CrmCustomerTagService mới.
+func NewCrmCustomerTagService() (*CrmCustomerTagService, error) {
+	coll, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.CustomerTags)
+	if !exist {
+		return nil, fmt.Errorf("không tìm thấy collection %s: %w", global.MongoDB_ColNames.CustomerTags, common.ErrNotFound)
+	}
+	customerSvc, err := NewCrmCustomerService()
+	if err != nil {
+		return nil, err
+	}
+	return &CrmCustomerTagService{
+		BaseServiceMongoImpl: basesvc.NewBaseServiceMongo[crmmodels.CrmCustomerTag](coll),
+		customerSvc:          customerSvc,
+	}, nil
+}
+
+func validateTagNameAndColor(name, color string) error {
+	if name == "" || len([]rune(name)) > crmTagMaxNameLen {
+		return common.NewError(common.ErrCodeValidationInput, fmt.Sprintf("Tên tag 1..%d ký tự", crmTagMaxNameLen), common.StatusBadRequest, nil)
+	}
+	if color != "" && !crmTagColorPattern.MatchString(color) {
+		return common.NewError(common.ErrCodeValidationInput, "color phải dạng #RRGGBB", common.StatusBadRequest, nil)
+	}
+	return nil
+}
+
+// InsertOne override — kiểm tra tên, màu. Tên trùng trong tổ chức bị index unique chặn.
+func (s *CrmCustomerTagService) InsertOne(ctx context.Context, data crmmodels.CrmCustomerTag) (crmmodels.CrmCustomerTag, error) {
+	data.Name = strings.TrimSpace(data.Name)
+	if err := validateTagNameAndColor(data.Name, data.Color); err != nil {
+		return data, err
+	}
+	return s.BaseServiceMongoImpl.InsertOne(ctx, data)
+}
+
+// UpdateById override — đổi tên tag đổi theo trên mọi khách đang gắn tag.
+func (s *CrmCustomerTagService) UpdateById(ctx context.Context, id primitive.ObjectID, data interface{}) (crmmodels.CrmCustomerTag, error) {
+	var zero crmmodels.CrmCustomerTag
+	updateData, err := basesvc.ToUpdateData(data)
+	if err != nil {
+		return zero, err
+	}
+	current, err := s.FindOneById(ctx, id)
+	if err != nil {
+		return zero, err
+	}
+	newName := current.Name
+	if updateData.Set != nil {
+		if raw, ok := updateData.Set["name"].(string); ok {
+			newName = strings.TrimSpace(raw)
+			updateData.Set["name"] = newName
+		}
+		color, _ := updateData.Set["color"].(string)
+		if err := validateTagNameAndColor(newName, color); err != nil {
+			return zero, err
+		}
+	}
+	updated, err := s.BaseServiceMongoImpl.UpdateById(ctx, id, updateData)
+	if err != nil {
+		return updated, err
+	}
+	if newName != current.Name {
+		// Khách đã có cả tên mới (gắn trùng) → chỉ gỡ tên cũ.
+		if _, err := s.customerSvc.Collection().UpdateMany(ctx,
+			bson.M{"ownerOrganizationId": current.OwnerOrganizationID, "tags": bson.M{"$eq": current.Name, "$ne": newName}},
+			bson.M{"$set": bson.M{"tags.$": newName}},
+		); err != nil {
+			return updated, common.ConvertMongoError(err)
+		}
+		if _, err := s.customerSvc.Collection().UpdateMany(ctx,
+			bson.M{"ownerOrganizationId": current.OwnerOrganizationID, "tags": current.Name},
+			bson.M{"$pull": bson.M{"tags": current.Name}},
+		); err != nil {
+			return updated, common.ConvertMongoError(err)
+		}
+		s.enqueueSegmentRefresh(ctx, current.OwnerOrganizationID)
+	}
+	return updated, nil
+}
+
+// DeleteById override — gỡ tag khỏi mọi khách của tổ chức.
+func (s *CrmCustomerTagService) DeleteById(ctx context.Context, id primitive.ObjectID) error {
+	tag, err := s.FindOneById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.BaseServiceMongoImpl.DeleteById(ctx, id); err != nil {
+		return err
+	}
+	if _, err := s.customerSvc.Collection().UpdateMany(ctx,
+		bson.M{"ownerOrganizationId": tag.OwnerOrganizationID, "tags": tag.Name},
+		bson.M{"$pull": bson.M{"tags": tag.Name}},
+	); err != nil {
+		return common.ConvertMongoError(err)
+	}
+	s.enqueueSegmentRefresh(ctx, tag.OwnerOrganizationID)
+	return nil
+}
+
+// ResolveTags tên tag theo danh mục (không phân biệt hoa thường, trả tên gốc). Tag chưa có trong danh mục → lỗi.
+func (s *CrmCustomerTagService) ResolveTags(ctx context.Context, ownerOrgID primitive.ObjectID, names []string) ([]string, error) {
+	names = uniqueStrings(names)
+	if len(names) == 0 || len(names) > crmTagMaxPerRequest {
+		return nil, common.NewError(common.ErrCodeValidationInput, fmt.Sprintf("Cần 1..%d tag", crmTagMaxPerRequest), common.StatusBadRequest, nil)
+	}
+	catalog, err := s.Find(ctx, bson.M{"ownerOrganizationId": ownerOrgID}, nil)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(names))
+	var unknown []string
+	for _, n := range names {
+		n = strings.TrimSpace(n)
+		found := ""
+		for _, t := range catalog {
+			if strings.EqualFold(t.Name, n) {
+				found = t.Name
+				break
+			}
+		}
+		if found == "" {
+			unknown = append(unknown, n)
+			continue
+		}
+		if !contains(out, found) {
+			out = append(out, found)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, common.NewError(common.ErrCodeValidationInput, "Tag chưa có trong danh mục: "+strings.Join(unknown, ", "), common.StatusBadRequest, nil)
+	}
+	return out, nil
+}
+
+// tagUpdate $addToSet / $pull theo action.
+func tagUpdate(action string, tags []string) bson.M {
+	if action == CrmTagActionRemove {
+		return bson.M{"$pull": bson.M{"tags": bson.M{"$in": tags}}}
+	}
+	return bson.M{"$addToSet": bson.M{"tags": bson.M{"$each": tags}}}
+}
+
+// ApplyTagsToCustomers gắn / gỡ tag trên danh sách khách (unifiedId hoặc uid). Một khách → xếp tính lại khách đó;
+// nhiều khách → xếp refresh phân khúc của tổ chức.
+func (s *CrmCustomerTagService) ApplyTagsToCustomers(ctx context.Context, ownerOrgID primitive.ObjectID, action string, tagNames, unifiedIds []string) (int64, error) {
+	if action != CrmTagActionAdd && action != CrmTagActionRemove {
+		return 0, common.NewError(common.ErrCodeValidationInput, "action phải là add hoặc remove", common.StatusBadRequest, nil)
+	}
+	unifiedIds = uniqueStrings(unifiedIds)
+	if len(unifiedIds) == 0 || len(unifiedIds) > crmTagMaxDirectApply {
+		return 0, common.NewError(common.ErrCodeValidationInput, fmt.Sprintf("unifiedIds cần 1..%d khách — nhiều hơn dùng segmentId hoặc conditions", crmTagMaxDirectApply), common.StatusBadRequest, nil)
+	}
+	tags, err := s.ResolveTags(ctx, ownerOrgID, tagNames)
+	if err != nil {
+		return 0, err
+	}
+	res, err := s.customerSvc.Collection().UpdateMany(ctx, bson.M{
+		"ownerOrganizationId": ownerOrgID,
+		"$or":                 []bson.M{{"unifiedId": bson.M{"$in": unifiedIds}}, {"uid": bson.M{"$in": unifiedIds}}},
+	}, tagUpdate(action, tags))
+	if err != nil {
+		return 0, common.ConvertMongoError(err)
+	}
+	if res.ModifiedCount > 0 {
+		if len(unifiedIds) == 1 {
+			requestCustomerRecalculate(ctx, unifiedIds[0], ownerOrgID)
+		} else {
+			s.enqueueSegmentRefresh(ctx, ownerOrgID)
+		}
+	}
+	return res.ModifiedCount, nil
+}
+
+// CrmTagApplyInput gắn / gỡ tag hàng loạt — một trong SegmentID (thành viên hiện tại) hoặc Conditions (DSL phân khúc).
+type CrmTagApplyInput struct {
+	Action     string
+	Tags       []string
+	SegmentID  primitive.ObjectID
+	Conditions *crmmodels.CrmSegmentCondition
+	IsPriority bool
+}
+
+// EnqueueTagApply kiểm tra input và xếp bulk job tag_apply.
+func (s *CrmCustomerTagService) EnqueueTagApply(ctx context.Context, ownerOrgID primitive.ObjectID, input CrmTagApplyInput) (primitive.ObjectID, error) {
+	if input.Action != CrmTagActionAdd && input.Action != CrmTagActionRemove {
+		return primitive.NilObjectID, common.NewError(common.ErrCodeValidationInput, "action phải là add hoặc remove", common.StatusBadRequest, nil)
+	}
+	tags, err := s.ResolveTags(ctx, ownerOrgID, input.Tags)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	params := bson.M{"action": input.Action, "tags": tags}
+	switch {
+	case !input.SegmentID.IsZero() && input.Conditions == nil:
+		segSvc, err := NewCrmSegmentService()
+		if err != nil {
+			return primitive.NilObjectID, err
+		}
+		if _, err := segSvc.FindSegment(ctx, ownerOrgID, input.SegmentID); err != nil {
+			return primitive.NilObjectID, err
+		}
+		params["segmentId"] = input.SegmentID.Hex()
+	case input.SegmentID.IsZero() && input.Conditions != nil:
+		if err := ValidateSegmentConditions(*input.Conditions); err != nil {
+			return primitive.NilObjectID, err
+		}
+		params["conditions"] = *input.Conditions
+	default:
+		return primitive.NilObjectID, common.NewError(common.ErrCodeValidationInput, "Cần đúng một trong unifiedIds, segmentId, conditions", common.StatusBadRequest, nil)
+	}
+	bulkSvc, err := NewCrmBulkJobService()
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return bulkSvc.Enqueue(ctx, crmmodels.CrmBulkJobTagApply, ownerOrgID, params, input.IsPriority)
+}
+
+// crmTagApplyProgress progress job tag_apply — _id cuối đã xử lý (thành viên phân khúc hoặc khách) và số đếm cộng dồn.
+type crmTagApplyProgress struct {
+	LastID   primitive.ObjectID `bson:"lastId,omitempty"`
+	Scanned  int64              `bson:"scanned"`
+	Matched  int64              `bson:"matched"`
+	Modified int64              `bson:"modified"`
+}
+
+// RunTagApply chạy job tag_apply theo lô _id tăng dần; progress lưu sau mỗi lô để resume.
+// Xong thì xếp refresh phân khúc của tổ chức (phân khúc có điều kiện theo tags).
+func (s *CrmCustomerTagService) RunTagApply(ctx context.Context, ownerOrgID primitive.ObjectID, params, progress bson.M, onProgress func(bson.M)) (bson.M, error) {
+	action, _ := params["action"].(string)
+	tags, _ := segmentStrings(params["tags"])
+	if (action != CrmTagActionAdd && action != CrmTagActionRemove) || len(tags) == 0 {
+		return nil, fmt.Errorf("params tag_apply không hợp lệ")
+	}
+	var p crmTagApplyProgress
+	if len(progress) > 0 {
+		if raw, err := bson.Marshal(progress); err == nil {
+			_ = bson.Unmarshal(raw, &p)
+		}
+	}
+	report := func() {
+		if onProgress == nil {
+			return
+		}
+		out := bson.M{}
+		if raw, err := bson.Marshal(p); err == nil {
+			_ = bson.Unmarshal(raw, &out)
+		}
+		onProgress(out)
+	}
+
+	var batchFn func(ctx context.Context) ([]string, int, error)
+	if segIDHex, _ := params["segmentId"].(string); segIDHex != "" {
+		segID, err := primitive.ObjectIDFromHex(segIDHex)
+		if err != nil {
+			return nil, fmt.Errorf("segmentId không hợp lệ: %w", err)
+		}
+		membersColl, ok := global.RegistryCollections.Get(global.MongoDB_ColNames.CustomerSegmentMembers)
+		if !ok {
+			return nil, fmt.Errorf("collection %s chưa đăng ký", global.MongoDB_ColNames.CustomerSegmentMembers)
+		}
+		batchFn = func(ctx context.Context) ([]string, int, error) {
+			filter := bson.M{"segmentId": segID, "ownerOrganizationId": ownerOrgID}
+			if !p.LastID.IsZero() {
+				filter["_id"] = bson.M{"$gt": p.LastID}
+			}
+			cursor, err := membersColl.Find(ctx, filter, mongoopts.Find().SetSort(bson.M{"_id": 1}).SetLimit(crmTagApplyBatch))
+			if err != nil {
+				return nil, 0, common.ConvertMongoError(err)
+			}
+			var members []crmmodels.CrmSegmentMember
+			if err := cursor.All(ctx, &members); err != nil {
+				return nil, 0, common.ConvertMongoError(err)
+			}
+			ids := make([]string, 0, len(members))
+			for _, m := range members {
+				ids = append(ids, m.UnifiedId)
+			}
+			if len(members) > 0 {
+				p.LastID = members[len(members)-1].ID
+			}
+			return ids, len(members), nil
+		}
+	} else {
+		cond, err := decodeSegmentConditions(params["conditions"])
+		if err != nil {
+			return nil, fmt.Errorf("conditions không hợp lệ: %w", err)
+		}
+		if err := ValidateSegmentConditions(cond); err != nil {
+			return nil, err
+		}
+		batchFn = func(ctx context.Context) ([]string, int, error) {
+			filter := bson.M{"ownerOrganizationId": ownerOrgID}
+			if !p.LastID.IsZero() {
+				filter["_id"] = bson.M{"$gt": p.LastID}
+			}
+			customers, err := s.customerSvc.Find(ctx, filter, mongoopts.Find().SetSort(bson.M{"_id": 1}).SetLimit(crmTagApplyBatch))
+			if err != nil {
+				return nil, 0, err
+			}
+			now := time.Now()
+			var ids []string
+			for i := range customers {
+				if customers[i].UnifiedId != "" && EvaluateSegmentCondition(cond, &customers[i], now) {
+					ids = append(ids, customers[i].UnifiedId)
+				}
+			}
+			if len(customers) > 0 {
+				p.LastID = customers[len(customers)-1].ID
+			}
+			return ids, len(customers), nil
+		}
+	}
+
+	update := tagUpdate(action, tags)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		ids, scanned, err := batchFn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if scanned == 0 {
+			break
+		}
+		p.Scanned += int64(scanned)
+		p.Matched += int64(len(ids))
+		if len(ids) > 0 {
+			res, err := s.customerSvc.Collection().UpdateMany(ctx, bson.M{"ownerOrganizationId": ownerOrgID, "unifiedId": bson.M{"$in": ids}}, update)
+			if err != nil {
+				return nil, common.ConvertMongoError(err)
+			}
+			p.Modified += res.ModifiedCount
+		}
+		report()
+		if scanned < crmTagApplyBatch {
+			break
+		}
+	}
+	if p.Modified > 0 {
+		s.enqueueSegmentRefresh(ctx, ownerOrgID)
+	}
+	return bson.M{"action": action, "tags": tags, "scanned": p.Scanned, "matched": p.Matched, "modified": p.Modified}, nil
+}
+
+// enqueueSegmentRefresh xếp refresh mọi phân khúc của tổ chức (không chặn thao tác nếu lỗi).
+func (s *CrmCustomerTagService) enqueueSegmentRefresh(ctx context.Context, ownerOrgID primitive.ObjectID) {
+	bulkSvc, err := NewCrmBulkJobService()
+	if err == nil {
+		_, err = bulkSvc.Enqueue(ctx, crmmodels.CrmBulkJobSegmentRefresh, ownerOrgID, bson.M{}, false)
+	}
+	if err != nil {
+		logger.GetAppLogger().WithError(err).WithField("ownerOrganizationId", ownerOrgID.Hex()).Warn("[CRM] Không xếp được refresh phân khúc sau khi đổi tag")
+	}
+}
@@ -0,0 +1,15 @@
+// Package deliverydto - DTO replay dead-letter (xem dto.delivery.send.go cho package doc).
+// File: dto.delivery.deadletter.go - giữ tên cấu trúc cũ (dto.<domain>.<entity>.go).
+package deliverydto
+
+// DeadLetterReplayRequest là request replay dead-letter: theo ids, hoặc theo filter (channelType, eventType, org).
+// Recipient / SenderID (cùng channelType) để gửi lại tới người nhận hoặc sender khác.
+type DeadLetterReplayRequest struct {
+	IDs                 []string `json:"ids,omitempty"`
+	ChannelType         string   `json:"channelType,omitempty"`
+	EventType           string   `json:"eventType,omitempty"`
+	OwnerOrganizationID string   `json:"ownerOrganizationId,omitempty"`
+	Limit               int      `json:"limit,omitempty"` // Mặc định / tối đa 500
+	Recipient           string   `json:"recipient,omitempty"`
+	SenderID            string   `json:"senderId,omitempty"`
+}
@@ -0,0 +1,130 @@
+// Package deliveryhdl - DeliveryDeadLetterHandler (xem handler.delivery.send.go cho package doc).
+package deliveryhdl
+
+import (
+	"fmt"
+
+	basehdl "meta_commerce/internal/api/base/handler"
+	deliverydto "meta_commerce/internal/api/delivery/dto"
+	deliverymodels "meta_commerce/internal/api/delivery/models"
+	deliverysvc "meta_commerce/internal/api/delivery/service"
+	"meta_commerce/internal/common"
+
+	"github.com/gofiber/fiber/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DeliveryDeadLetterHandler xử lý dead-letter của Delivery: CRUD đọc + purge, replay.
+type DeliveryDeadLetterHandler struct {
+	*basehdl.BaseHandler[deliverymodels.DeliveryDeadLetter, deliverymodels.DeliveryDeadLetter, deliverymodels.DeliveryDeadLetter]
+	deadLetterService *deliverysvc.DeliveryDeadLetterService
+}
+
+// NewDeliveryDeadLetterHandler tạo mới DeliveryDeadLetterHandler
+func NewDeliveryDeadLetterHandler() (*DeliveryDeadLetterHandler, error) {
+	deadLetterService, err := deliverysvc.NewDeliveryDeadLetterService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create delivery dead-letter service: %v", err)
+	}
+	hdl := &DeliveryDeadLetterHandler{
+		BaseHandler:       basehdl.NewBaseHandler[deliverymodels.DeliveryDeadLetter, deliverymodels.DeliveryDeadLetter, deliverymodels.DeliveryDeadLetter](deadLetterService),
+		deadLetterService: deadLetterService,
+	}
+	hdl.SetFilterOptions(basehdl.FilterOptions{
+		DeniedFields:     []string{"senderConfig"},
+		AllowedOperators: []string{"$eq", "$gt", "$gte", "$lt", "$lte", "$in", "$nin", "$exists"},
+		MaxFields:        10,
+	})
+	return hdl, nil
+}
+
+// HandleReplay POST /notification/dead-letter/replay — đưa lại entry dead vào delivery queue.
+// Body: {ids} hoặc filter {channelType, eventType, ownerOrganizationId, limit}; tùy chọn {recipient, senderId}.
+func (h *DeliveryDeadLetterHandler) HandleReplay(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		var req deliverydto.DeadLetterReplayRequest
+		if err := h.ParseRequestBody(c, &req); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		filter := bson.M{}
+		if len(req.IDs) > 0 {
+			ids, err := parseObjectIDs(req.IDs)
+			if err != nil {
+				h.HandleResponse(c, nil, err)
+				return nil
+			}
+			filter["_id"] = bson.M{"$in": ids}
+		}
+		if req.ChannelType != "" {
+			filter["channelType"] = req.ChannelType
+		}
+		if req.EventType != "" {
+			filter["eventType"] = req.EventType
+		}
+		if req.OwnerOrganizationID != "" {
+			orgID, err := parseObjectID(req.OwnerOrganizationID)
+			if err != nil {
+				h.HandleResponse(c, nil, err)
+				return nil
+			}
+			filter["ownerOrganizationId"] = orgID
+		}
+		// Chỉ replay entry thuộc các org role hiện tại được phép (giao với filter trên)
+		filter = h.ApplyOrganizationFilter(c, filter)
+
+		opts := deliverysvc.ReplayOptions{Recipient: req.Recipient}
+		if req.SenderID != "" {
+			senderID, err := parseObjectID(req.SenderID)
+			if err != nil {
+				h.HandleResponse(c, nil, err)
+				return nil
+			}
+			opts.SenderID = &senderID
+		}
+		if userIDStr, ok := c.Locals("user_id").(string); ok {
+			if userID, err := primitive.ObjectIDFromHex(userIDStr); err == nil {
+				opts.ReplayedBy = &userID
+			}
+		}
+
+		results, err := h.deadLetterService.Replay(c.Context(), filter, req.Limit, opts)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		summary := map[string]int{}
+		for _, r := range results {
+			summary[r.Result]++
+		}
+		h.HandleResponse(c, fiber.Map{"total": len(results), "summary": summary, "results": results}, nil)
+		return nil
+	})
+}
+
+func parseObjectID(id string) (primitive.ObjectID, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return primitive.NilObjectID, common.NewError(
+			common.ErrCodeValidationFormat,
+			fmt.Sprintf("ID '%s' không đúng định dạng MongoDB ObjectID (phải là chuỗi hex 24 ký tự)", id),
+			common.StatusBadRequest,
+			err,
+		)
+	}
+	return oid, nil
+}
+
+func parseObjectIDs(ids []string) ([]primitive.ObjectID, error) {
+	result := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		oid, err := parseObjectID(id)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, oid)
+	}
+	return result, nil
+}
@@ -0,0 +1,61 @@
+// Package models - DeliveryDeadLetter thuộc domain Delivery.
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Trạng thái DeliveryDeadLetter.
+const (
+	DeadLetterStatusDead     = "dead"     // Chờ xem xét / replay
+	DeadLetterStatusReplayed = "replayed" // Đã đưa lại vào queue (giữ lại để audit đến khi purge)
+)
+
+// DeliveryDeadLetter - Queue item gửi thất bại hẳn (hết retry hoặc lỗi vĩnh viễn), chuyển khỏi delivery queue.
+// Giữ nguyên nội dung đã render + sender để replay, kèm lỗi cuối, các lần thử và response của kênh.
+type DeliveryDeadLetter struct {
+	ID                  primitive.ObjectID     `json:"id,omitempty" bson:"_id,omitempty"`
+	QueueItemID         primitive.ObjectID     `json:"queueItemId" bson:"queueItemId" index:"single:1"` // Queue item gốc (đã xóa khỏi queue)
+	EventType           string                 `json:"eventType" bson:"eventType" index:"single:1"`
+	OwnerOrganizationID primitive.ObjectID     `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1"`
+	SenderID            primitive.ObjectID     `json:"senderId" bson:"senderId"`
+	SenderConfig        string                 `json:"-" bson:"senderConfig,omitempty"` // Sender config đã encrypt — không trả ra API
+	ChannelType         string                 `json:"channelType" bson:"channelType" index:"single:1"`
+	Recipient           string                 `json:"recipient" bson:"recipient"`
+	Subject             string                 `json:"subject,omitempty" bson:"subject,omitempty"`
+	Content             string                 `json:"content,omitempty" bson:"content,omitempty"`
+	ContentFormat       string                 `json:"contentFormat,omitempty" bson:"contentFormat,omitempty"`
+	CTAs                []string               `json:"ctas,omitempty" bson:"ctas,omitempty"`
+	Payload             map[string]interface{} `json:"payload" bson:"payload"`
+	Priority            int                    `json:"priority" bson:"priority"`
+	MaxRetries          int                    `json:"maxRetries" bson:"maxRetries"`
+	IdempotencyKey      string                 `json:"idempotencyKey,omitempty" bson:"idempotencyKey,omitempty"` // Có khi item là bản replay
+
+	// Lý do vào dead-letter
+	RetryCount         int               `json:"retryCount" bson:"retryCount"`
+	LastError          string            `json:"lastError" bson:"lastError"`
+	Permanent          bool              `json:"permanent" bson:"permanent"` // Lỗi vĩnh viễn (4xx, PSID sai...) — không phải hết retry
+	LastResponseStatus int               `json:"lastResponseStatus,omitempty" bson:"lastResponseStatus,omitempty"`
+	LastResponseBody   string            `json:"lastResponseBody,omitempty" bson:"lastResponseBody,omitempty"`
+	Attempts           []DeliveryAttempt `json:"attempts,omitempty" bson:"attempts,omitempty"` // Từ delivery history của queue item
+
+	Status                string              `json:"status" bson:"status" index:"single:1"` // dead, replayed
+	ReplayCount           int                 `json:"replayCount" bson:"replayCount"`
+	LastReplayedAt        *int64              `json:"lastReplayedAt,omitempty" bson:"lastReplayedAt,omitempty"`
+	LastReplayedBy        *primitive.ObjectID `json:"lastReplayedBy,omitempty" bson:"lastReplayedBy,omitempty"`
+	LastReplayQueueItemID *primitive.ObjectID `json:"lastReplayQueueItemId,omitempty" bson:"lastReplayQueueItemId,omitempty"`
+
+	DeadLetteredAt int64 `json:"deadLetteredAt" bson:"deadLetteredAt" index:"single:-1"`
+	CreatedAt      int64 `json:"createdAt" bson:"createdAt"`
+	UpdatedAt      int64 `json:"updatedAt" bson:"updatedAt"`
+}
+
+// DeliveryAttempt một lần gửi thử của queue item (lấy từ DeliveryHistory).
+type DeliveryAttempt struct {
+	HistoryID      primitive.ObjectID `json:"historyId" bson:"historyId"`
+	RetryCount     int                `json:"retryCount" bson:"retryCount"`
+	Error          string             `json:"error,omitempty" bson:"error,omitempty"`
+	ResponseStatus int                `json:"responseStatus,omitempty" bson:"responseStatus,omitempty"`
+	ResponseBody   string             `json:"responseBody,omitempty" bson:"responseBody,omitempty"`
+	AttemptedAt    int64              `json:"attemptedAt" bson:"attemptedAt"`
+}
@@ -15,12 +15,21 @@ type DeliveryHistory struct {
 	Severity            string             `json:"severity,omitempty" bson:"severity,omitempty" index:"single:1"` // Severity để reporting (optional, có thể infer từ EventType)
 	ChannelType         string             `json:"channelType" bson:"channelType" index:"single:1"`
 	Recipient           string             `json:"recipient" bson:"recipient"`
-	Status              string             `json:"status" bson:"status" index:"single:1"` // sent, failed
+	Status              string             `json:"status" bson:"status" index:"single:1"` // sent, failed, suppressed, digested
 	Content             string             `json:"content" bson:"content"`               // Content đã render
 	Error               string             `json:"error,omitempty" bson:"error,omitempty"`
 	RetryCount          int                `json:"retryCount" bson:"retryCount"`
+	IdempotencyKey      string             `json:"idempotencyKey,omitempty" bson:"idempotencyKey,omitempty" index:"single:1"` // Copy từ queue item (bản replay)
 	SentAt              *int64             `json:"sentAt,omitempty" bson:"sentAt,omitempty"`
 
+	// Chống spam theo routing rule (status suppressed / digested)
+	RoutingRuleID  *primitive.ObjectID `json:"routingRuleId,omitempty" bson:"routingRuleId,omitempty"`
+	ThrottleReason string              `json:"throttleReason,omitempty" bson:"throttleReason,omitempty"` // dedup, quiet_hours, digest
+
+	// Response của bên nhận (webhook) — body cắt tối đa 4KB
+	ResponseStatus int    `json:"responseStatus,omitempty" bson:"responseStatus,omitempty"`
+	ResponseBody   string `json:"responseBody,omitempty" bson:"responseBody,omitempty"`
+
 	// Open Tracking (Email only)
 	OpenedAt  *int64 `json:"openedAt,omitempty" bson:"openedAt,omitempty"` // Thời gian mở email đầu tiên
 	OpenCount int    `json:"openCount" bson:"openCount"`                   // Số lần mở email
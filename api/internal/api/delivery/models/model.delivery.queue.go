@@ -18,16 +18,19 @@ type DeliveryQueueItem struct {
 	Recipient           string                 `json:"recipient" bson:"recipient"`
 	Subject             string                 `json:"subject,omitempty" bson:"subject,omitempty"`
 	Content             string                 `json:"content,omitempty" bson:"content,omitempty"`
+	ContentFormat       string                 `json:"contentFormat,omitempty" bson:"contentFormat,omitempty"` // text | html | markdown | json — Telegram dùng để chọn parse_mode
 	CTAs                []string               `json:"ctas,omitempty" bson:"ctas,omitempty"` // CTAs đã render sẵn (có tracking URLs)
 	Payload             map[string]interface{} `json:"payload" bson:"payload"`
+	IdempotencyKey      string                 `json:"idempotencyKey,omitempty" bson:"idempotencyKey,omitempty" index:"unique,sparse"` // Bản replay từ dead-letter: đã có history sent cùng key → không gửi lại
 
-	Status      string `json:"status" bson:"status" index:"single:1"` // pending, processing, completed, failed
+	Status      string `json:"status" bson:"status" index:"single:1"` // pending, processing, completed, failed (dead-letter chuyển sang delivery_job_dead_letters)
 	RetryCount  int    `json:"retryCount" bson:"retryCount"`
 	MaxRetries  int    `json:"maxRetries" bson:"maxRetries"` // Số lần retry tối đa (tính từ Severity)
 	Priority    int    `json:"priority" bson:"priority" index:"single:1"` // Priority để sort queue (1=critical, 2=high, 3=medium, 4=low, 5=info)
 	NextRetryAt *int64 `json:"nextRetryAt,omitempty" bson:"nextRetryAt,omitempty" index:"single:1"`
 
-	Error     string `json:"error,omitempty" bson:"error,omitempty"`
-	CreatedAt int64  `json:"createdAt" bson:"createdAt"`
-	UpdatedAt int64  `json:"updatedAt" bson:"updatedAt"`
+	Error          string `json:"error,omitempty" bson:"error,omitempty"`
+	DeadLetteredAt *int64 `json:"deadLetteredAt,omitempty" bson:"deadLetteredAt,omitempty"` // Thời điểm vào dead-letter (hết retry hoặc lỗi vĩnh viễn)
+	CreatedAt      int64  `json:"createdAt" bson:"createdAt"`
+	UpdatedAt      int64  `json:"updatedAt" bson:"updatedAt"`
 }
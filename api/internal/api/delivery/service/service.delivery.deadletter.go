@@ -0,0 +1,330 @@
+// Package deliverysvc - DeliveryDeadLetterService (xem service.delivery.queue.go cho package doc).
+// File: service.delivery.deadletter.go - giữ tên cấu trúc cũ (service.<domain>.<entity>.go).
+package deliverysvc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	basesvc "meta_commerce/internal/api/base/service"
+	deliverymodels "meta_commerce/internal/api/delivery/models"
+	notifsvc "meta_commerce/internal/api/notification/service"
+	"meta_commerce/internal/common"
+	"meta_commerce/internal/global"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MaxReplayBatch số entry tối đa mỗi lần replay theo filter.
+const MaxReplayBatch = 500
+
+// Kết quả replay từng entry.
+const (
+	ReplayResultQueued           = "queued"
+	ReplayResultSkippedDuplicate = "skipped_duplicate" // Đã có bản replay cùng idempotency key đang chờ trong queue
+	ReplayResultSkippedNotDead   = "skipped_not_dead"  // Entry đã được replay (hoặc đang được replay bởi request khác)
+	ReplayResultError            = "error"
+)
+
+// idempotencyKeyPrefix tiền tố idempotency key của bản replay.
+const idempotencyKeyPrefix = "dlq:"
+
+// DeliveryDeadLetterService là service quản lý dead-letter của Delivery (item gửi thất bại hẳn).
+type DeliveryDeadLetterService struct {
+	*basesvc.BaseServiceMongoImpl[deliverymodels.DeliveryDeadLetter]
+	queueService   *DeliveryQueueService
+	historyService *DeliveryHistoryService
+}
+
+// ReplayOptions tùy chọn khi replay: đổi người nhận / sender (cùng channelType).
+type ReplayOptions struct {
+	Recipient  string
+	SenderID   *primitive.ObjectID
+	ReplayedBy *primitive.ObjectID
+}
+
+// ReplayResult kết quả replay một entry.
+type ReplayResult struct {
+	DeadLetterID   primitive.ObjectID  `json:"deadLetterId"`
+	Result         string              `json:"result"`
+	QueueItemID    *primitive.ObjectID `json:"queueItemId,omitempty"`
+	IdempotencyKey string              `json:"idempotencyKey,omitempty"`
+	Error          string              `json:"error,omitempty"`
+}
+
+// NewDeliveryDeadLetterService tạo mới DeliveryDeadLetterService
+func NewDeliveryDeadLetterService() (*DeliveryDeadLetterService, error) {
+	collection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.DeliveryDeadLetters)
+	if !exist {
+		return nil, fmt.Errorf("failed to get delivery_dead_letters collection: %v", common.ErrNotFound)
+	}
+	queueService, err := NewDeliveryQueueService()
+	if err != nil {
+		return nil, err
+	}
+	historyService, err := NewDeliveryHistoryService()
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeliveryDeadLetterService{
+		BaseServiceMongoImpl: basesvc.NewBaseServiceMongo[deliverymodels.DeliveryDeadLetter](collection),
+		queueService:         queueService,
+		historyService:       historyService,
+	}, nil
+}
+
+// ReplayIdempotencyKey key của bản replay: theo queue item gốc + kênh + người nhận.
+// existingKey (entry đã là bản replay) giữ nguyên queue item gốc qua nhiều lần replay.
+func ReplayIdempotencyKey(existingKey string, queueItemID primitive.ObjectID, channelType, recipient string) string {
+	root := queueItemID.Hex()
+	if rest, ok := strings.CutPrefix(existingKey, idempotencyKeyPrefix); ok {
+		if id, _, found := strings.Cut(rest, ":"); found && id != "" {
+			root = id
+		}
+	}
+	return idempotencyKeyPrefix + root + ":" + channelType + ":" + recipient
+}
+
+// MoveFromQueue chuyển queue item (hết retry / lỗi vĩnh viễn) sang dead-letter kèm lỗi cuối và các lần thử, rồi xóa khỏi queue.
+func (s *DeliveryDeadLetterService) MoveFromQueue(ctx context.Context, item *deliverymodels.DeliveryQueueItem, lastErr string, permanent bool) (deliverymodels.DeliveryDeadLetter, error) {
+	now := time.Now().Unix()
+	entry := deliverymodels.DeliveryDeadLetter{
+		QueueItemID:         item.ID,
+		EventType:           item.EventType,
+		OwnerOrganizationID: item.OwnerOrganizationID,
+		SenderID:            item.SenderID,
+		SenderConfig:        item.SenderConfig,
+		ChannelType:         item.ChannelType,
+		Recipient:           item.Recipient,
+		Subject:             item.Subject,
+		Content:             item.Content,
+		ContentFormat:       item.ContentFormat,
+		CTAs:                item.CTAs,
+		Payload:             item.Payload,
+		Priority:            item.Priority,
+		MaxRetries:          item.MaxRetries,
+		IdempotencyKey:      item.IdempotencyKey,
+		RetryCount:          item.RetryCount,
+		LastError:           lastErr,
+		Permanent:           permanent,
+		Status:              deliverymodels.DeadLetterStatusDead,
+		DeadLetteredAt:      now,
+	}
+	if item.DeadLetteredAt != nil {
+		entry.DeadLetteredAt = *item.DeadLetteredAt
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}})
+	histories, err := s.historyService.Find(ctx, bson.M{"queueItemId": item.ID}, opts)
+	if err != nil {
+		return entry, err
+	}
+	for _, h := range histories {
+		entry.Attempts = append(entry.Attempts, deliverymodels.DeliveryAttempt{
+			HistoryID:      h.ID,
+			RetryCount:     h.RetryCount,
+			Error:          h.Error,
+			ResponseStatus: h.ResponseStatus,
+			ResponseBody:   h.ResponseBody,
+			AttemptedAt:    h.CreatedAt,
+		})
+	}
+	if n := len(entry.Attempts); n > 0 {
+		entry.LastResponseStatus = entry.Attempts[n-1].ResponseStatus
+		entry.LastResponseBody = entry.Attempts[n-1].ResponseBody
+	}
+
+	saved, err := s.InsertOne(ctx, entry)
+	if err != nil {
+		return entry, err
+	}
+	if err := s.queueService.DeleteOne(ctx, bson.M{"_id": item.ID}); err != nil {
+		return saved, err
+	}
+	return saved, nil
+}
+
+// MigrateLegacy chuyển các queue item còn status dead_letter (trước khi có collection dead-letter) sang collection mới.
+func (s *DeliveryDeadLetterService) MigrateLegacy(ctx context.Context, limit int) (int, error) {
+	items, err := s.queueService.Find(ctx, bson.M{"status": "dead_letter"}, options.Find().SetLimit(int64(limit)))
+	if err != nil {
+		return 0, err
+	}
+	moved := 0
+	for i := range items {
+		if _, err := s.MoveFromQueue(ctx, &items[i], items[i].Error, false); err != nil {
+			return moved, err
+		}
+		moved++
+	}
+	return moved, nil
+}
+
+// DepthByChannel đếm entry đang dead theo channelType.
+func (s *DeliveryDeadLetterService) DepthByChannel(ctx context.Context) (map[string]int64, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": deliverymodels.DeadLetterStatusDead}}},
+		{{Key: "$group", Value: bson.M{"_id": "$channelType", "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := s.Collection().Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	defer cursor.Close(ctx)
+	var rows []struct {
+		ChannelType string `bson:"_id"`
+		Count       int64  `bson:"count"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	result := make(map[string]int64, len(rows))
+	for _, r := range rows {
+		result[r.ChannelType] = r.Count
+	}
+	return result, nil
+}
+
+// Replay đưa lại các entry dead khớp filter vào delivery queue (tối đa limit, cũ nhất trước).
+// Mỗi entry được claim nguyên tử (dead → replayed) nên hai request song song không replay trùng;
+// bản replay mang idempotency key — processor bỏ qua nếu đã có history sent cùng key.
+func (s *DeliveryDeadLetterService) Replay(ctx context.Context, filter bson.M, limit int, opts ReplayOptions) ([]ReplayResult, error) {
+	if limit <= 0 || limit > MaxReplayBatch {
+		limit = MaxReplayBatch
+	}
+	if filter == nil {
+		filter = bson.M{}
+	}
+	filter["status"] = deliverymodels.DeadLetterStatusDead
+
+	var senderChannel string
+	var senderOrgID *primitive.ObjectID
+	senderIsSystem := false
+	if opts.SenderID != nil {
+		senderService, err := notifsvc.NewNotificationSenderService()
+		if err != nil {
+			return nil, err
+		}
+		sender, err := senderService.FindOneById(ctx, *opts.SenderID)
+		if err != nil {
+			return nil, common.NewError(common.ErrCodeValidationInput, "Không tìm thấy sender "+opts.SenderID.Hex(), common.StatusBadRequest, err)
+		}
+		if !sender.IsActive {
+			return nil, common.NewError(common.ErrCodeValidationInput, "Sender "+opts.SenderID.Hex()+" không active", common.StatusBadRequest, nil)
+		}
+		senderChannel = sender.ChannelType
+		senderOrgID, senderIsSystem = sender.OwnerOrganizationID, sender.IsSystem
+	}
+
+	entries, err := s.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "deadLetteredAt", Value: 1}}).SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ReplayResult, 0, len(entries))
+	for _, entry := range entries {
+		if senderChannel != "" && senderChannel != entry.ChannelType {
+			results = append(results, ReplayResult{
+				DeadLetterID: entry.ID,
+				Result:       ReplayResultError,
+				Error:        fmt.Sprintf("sender kênh %s không dùng được cho entry kênh %s", senderChannel, entry.ChannelType),
+			})
+			continue
+		}
+		// Sender thay thế phải thuộc tổ chức của entry (sender hệ thống dùng chung được cho mọi tổ chức)
+		if opts.SenderID != nil && !senderIsSystem && (senderOrgID == nil || *senderOrgID != entry.OwnerOrganizationID) {
+			results = append(results, ReplayResult{
+				DeadLetterID: entry.ID,
+				Result:       ReplayResultError,
+				Error:        fmt.Sprintf("sender %s không thuộc tổ chức của entry", opts.SenderID.Hex()),
+			})
+			continue
+		}
+		results = append(results, s.replayOne(ctx, entry, opts))
+	}
+	return results, nil
+}
+
+// replayOne claim một entry rồi tạo queue item mới; lỗi insert (trừ trùng key) trả entry về dead.
+func (s *DeliveryDeadLetterService) replayOne(ctx context.Context, entry deliverymodels.DeliveryDeadLetter, opts ReplayOptions) ReplayResult {
+	result := ReplayResult{DeadLetterID: entry.ID}
+	now := time.Now().Unix()
+
+	claim := bson.M{
+		"$set": bson.M{
+			"status":         deliverymodels.DeadLetterStatusReplayed,
+			"lastReplayedAt": now,
+			"lastReplayedBy": opts.ReplayedBy,
+			"updatedAt":      now,
+		},
+		"$inc": bson.M{"replayCount": 1},
+	}
+	err := s.Collection().FindOneAndUpdate(ctx, bson.M{"_id": entry.ID, "status": deliverymodels.DeadLetterStatusDead}, claim).Err()
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			result.Result = ReplayResultSkippedNotDead
+			return result
+		}
+		result.Result = ReplayResultError
+		result.Error = err.Error()
+		return result
+	}
+
+	recipient := entry.Recipient
+	if opts.Recipient != "" {
+		recipient = opts.Recipient
+	}
+	senderID, senderConfig := entry.SenderID, entry.SenderConfig
+	if opts.SenderID != nil {
+		// Sender mới: bỏ config đã encrypt, processor query theo SenderID
+		senderID, senderConfig = *opts.SenderID, ""
+	}
+	key := ReplayIdempotencyKey(entry.IdempotencyKey, entry.QueueItemID, entry.ChannelType, recipient)
+	result.IdempotencyKey = key
+
+	queued, err := s.queueService.InsertOne(ctx, deliverymodels.DeliveryQueueItem{
+		EventType:           entry.EventType,
+		OwnerOrganizationID: entry.OwnerOrganizationID,
+		SenderID:            senderID,
+		SenderConfig:        senderConfig,
+		ChannelType:         entry.ChannelType,
+		Recipient:           recipient,
+		Subject:             entry.Subject,
+		Content:             entry.Content,
+		ContentFormat:       entry.ContentFormat,
+		CTAs:                entry.CTAs,
+		Payload:             entry.Payload,
+		IdempotencyKey:      key,
+		Status:              "pending",
+		MaxRetries:          entry.MaxRetries,
+		Priority:            entry.Priority,
+		CreatedAt:           now,
+		UpdatedAt:           now,
+	})
+	if err != nil {
+		if errors.Is(err, common.ErrMongoDuplicate) {
+			// Bản replay cùng key đang chờ trong queue — entry coi như đã replay
+			result.Result = ReplayResultSkippedDuplicate
+			return result
+		}
+		_, _ = s.Collection().UpdateOne(ctx, bson.M{"_id": entry.ID}, bson.M{
+			"$set": bson.M{"status": deliverymodels.DeadLetterStatusDead, "updatedAt": time.Now().Unix()},
+			"$inc": bson.M{"replayCount": -1},
+		})
+		result.Result = ReplayResultError
+		result.Error = err.Error()
+		return result
+	}
+
+	_, _ = s.Collection().UpdateOne(ctx, bson.M{"_id": entry.ID}, bson.M{"$set": bson.M{"lastReplayQueueItemId": queued.ID}})
+	result.Result = ReplayResultQueued
+	result.QueueItemID = &queued.ID
+	return result
+}
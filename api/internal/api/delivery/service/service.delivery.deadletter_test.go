@@ -0,0 +1,28 @@
+package deliverysvc
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestReplayIdempotencyKey(t *testing.T) {
+	root := primitive.NewObjectID()
+	replayItem := primitive.NewObjectID()
+
+	first := ReplayIdempotencyKey("", root, "email", "a@x.com")
+	if want := "dlq:" + root.Hex() + ":email:a@x.com"; first != want {
+		t.Fatalf("key = %q, want %q", first, want)
+	}
+
+	// Bản replay lại thất bại: giữ queue item gốc, không dùng id của bản replay
+	again := ReplayIdempotencyKey(first, replayItem, "email", "a@x.com")
+	if again != first {
+		t.Fatalf("replay lần 2 key = %q, want %q", again, first)
+	}
+
+	// Đổi người nhận → key mới (người nhận mới chưa từng nhận)
+	if other := ReplayIdempotencyKey(first, replayItem, "email", "b@x.com"); other == first {
+		t.Fatal("đổi recipient phải đổi key")
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"meta_commerce/internal/global"
 	basesvc "meta_commerce/internal/api/base/service"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -45,3 +46,11 @@ func (s *DeliveryHistoryService) InsertOne(ctx context.Context, data deliverymod
 func (s *DeliveryHistoryService) UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts *options.UpdateOptions) (deliverymodels.DeliveryHistory, error) {
 	return s.BaseServiceMongoImpl.UpdateOne(ctx, filter, update, opts)
 }
+
+// AlreadySent kiểm tra đã có history gửi thành công với idempotency key (bản replay trùng → không gửi lại).
+func (s *DeliveryHistoryService) AlreadySent(ctx context.Context, idempotencyKey string) (bool, error) {
+	if idempotencyKey == "" {
+		return false, nil
+	}
+	return s.DocumentExists(ctx, bson.M{"idempotencyKey": idempotencyKey, "status": "sent"})
+}
@@ -8,6 +8,7 @@ import (
 
 	aidecisionsvc "meta_commerce/internal/api/aidecision/service"
 	approval "meta_commerce/internal/approval"
+	pkgapproval "meta_commerce/pkg/approval"
 	basehdl "meta_commerce/internal/api/base/handler"
 	"meta_commerce/internal/common"
 
@@ -26,6 +27,13 @@ func getActiveOrgID(c fiber.Ctx) *primitive.ObjectID {
 	return &oid
 }
 
+// getApprover người duyệt từ auth context: user_id + active_role_id.
+func getApprover(c fiber.Ctx) approval.Approver {
+	userID, _ := c.Locals("user_id").(string)
+	roleID, _ := c.Locals("active_role_id").(string)
+	return approval.ResolveApprover(c.Context(), userID, roleID)
+}
+
 // ProposeInput body cho propose.
 type ProposeInput struct {
 	Domain           string                 `json:"domain"`
@@ -53,6 +61,12 @@ type ExecuteInput struct {
 	ActionId string `json:"actionId"`
 }
 
+// RevertInput body cho revert (tùy chọn).
+type RevertInput struct {
+	Reason  string `json:"reason"`
+	Execute bool   `json:"execute"` // true = duyệt luôn bằng user hiện tại (vẫn qua policy nhiều bước)
+}
+
 // HandlePropose POST /executor/actions/propose
 // Chỉ enqueue AI Decision (executor.propose_requested); consumer gọi approval.Propose — không gọi trực tiếp từ handler.
 func HandlePropose(c fiber.Ctx) error {
@@ -131,15 +145,19 @@ func HandleApprove(c fiber.Ctx) error {
 			})
 			return nil
 		}
-		result, err := approval.Approve(c.Context(), input.ActionId, *orgID)
+		result, err := approval.Approve(c.Context(), input.ActionId, *orgID, getApprover(c))
 		if err != nil {
 			c.Status(common.StatusBadRequest).JSON(fiber.Map{
 				"code": common.ErrCodeValidationFormat.Code, "message": err.Error(), "status": "error",
 			})
 			return nil
 		}
+		msg := "Đã duyệt đề xuất"
+		if result.Status == pkgapproval.StatusPending {
+			msg = "Đã ghi nhận lượt duyệt, chờ thêm người duyệt theo policy"
+		}
 		c.Status(common.StatusOK).JSON(fiber.Map{
-			"code": common.StatusOK, "message": "Đã duyệt đề xuất", "data": result, "status": "success",
+			"code": common.StatusOK, "message": msg, "data": result, "status": "success",
 		})
 		return nil
 	})
@@ -205,7 +223,7 @@ func HandleReject(c fiber.Ctx) error {
 			})
 			return nil
 		}
-		result, err := approval.Reject(c.Context(), input.ActionId, *orgID, input.DecisionNote, "")
+		result, err := approval.Reject(c.Context(), input.ActionId, *orgID, input.DecisionNote, getApprover(c))
 		if err != nil {
 			c.Status(common.StatusBadRequest).JSON(fiber.Map{
 				"code": common.ErrCodeValidationFormat.Code, "message": err.Error(), "status": "error",
@@ -220,6 +238,60 @@ func HandleReject(c fiber.Ctx) error {
 	})
 }
 
+// HandleRevert POST /executor/actions/:id/revert
+// Tạo action nghịch đảo từ compensation của action đã thực thi; mặc định chờ duyệt, execute=true thì duyệt luôn.
+func HandleRevert(c fiber.Ctx) error {
+	return basehdl.SafeHandlerWrapper(c, func() error {
+		id := c.Params("id")
+		if id == "" {
+			c.Status(common.StatusBadRequest).JSON(fiber.Map{
+				"code": common.ErrCodeValidationFormat.Code, "message": "id không được để trống", "status": "error",
+			})
+			return nil
+		}
+		var input RevertInput
+		if len(c.Body()) > 0 {
+			if err := c.Bind().JSON(&input); err != nil {
+				c.Status(common.StatusBadRequest).JSON(fiber.Map{
+					"code": common.ErrCodeValidationFormat.Code, "message": "Dữ liệu gửi lên không đúng định dạng JSON", "status": "error",
+				})
+				return nil
+			}
+		}
+		orgID := getActiveOrgID(c)
+		if orgID == nil {
+			c.Status(common.StatusBadRequest).JSON(fiber.Map{
+				"code": common.ErrCodeValidationFormat.Code, "message": "Chưa chọn tổ chức", "status": "error",
+			})
+			return nil
+		}
+		baseURL := c.Protocol() + "://" + c.Host()
+		result, err := approval.Revert(c.Context(), id, *orgID, getApprover(c), approval.RevertInput{
+			Reason:  input.Reason,
+			Execute: input.Execute,
+		}, baseURL)
+		if err != nil {
+			c.Status(common.StatusBadRequest).JSON(fiber.Map{
+				"code": common.ErrCodeValidationFormat.Code, "message": err.Error(), "status": "error",
+			})
+			return nil
+		}
+		msg := "Đã tạo đề xuất revert, chờ duyệt"
+		switch result.Status {
+		case pkgapproval.StatusExecuted:
+			msg = "Đã revert thành công"
+		case pkgapproval.StatusQueued:
+			msg = "Đã duyệt revert, đang chờ thực thi"
+		case pkgapproval.StatusFailed:
+			msg = "Revert thực thi thất bại"
+		}
+		c.Status(common.StatusOK).JSON(fiber.Map{
+			"code": common.StatusOK, "message": msg, "data": result, "status": "success",
+		})
+		return nil
+	})
+}
+
 // HandleFindById GET /executor/actions/find-by-id/:id
 func HandleFindById(c fiber.Ctx) error {
 	return basehdl.SafeHandlerWrapper(c, func() error {
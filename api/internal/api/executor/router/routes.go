@@ -29,6 +29,7 @@ func Register(v1 fiber.Router, r *apirouter.Router) error {
 	apirouter.RegisterRouteWithMiddleware(v1, "/executor/actions", "POST", "/approve", []fiber.Handler{actionMiddleware, orgContextMiddleware}, executorhdl.HandleApprove)
 	apirouter.RegisterRouteWithMiddleware(v1, "/executor/actions", "POST", "/reject", []fiber.Handler{actionMiddleware, orgContextMiddleware}, executorhdl.HandleReject)
 	apirouter.RegisterRouteWithMiddleware(v1, "/executor/actions", "POST", "/execute", []fiber.Handler{actionMiddleware, orgContextMiddleware}, executorhdl.HandleExecute)
+	apirouter.RegisterRouteWithMiddleware(v1, "/executor/actions", "POST", "/:id/revert", []fiber.Handler{actionMiddleware, orgContextMiddleware}, executorhdl.HandleRevert)
 	apirouter.RegisterRouteWithMiddleware(v1, "/executor/actions", "GET", "/pending", []fiber.Handler{readMiddleware, orgContextMiddleware}, executorhdl.HandleListPending)
 
 	// Executor send, execute (từ delivery)
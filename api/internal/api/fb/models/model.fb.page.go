@@ -1,6 +1,8 @@
 package models
 
 import (
+	"meta_commerce/internal/keyring"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -13,8 +15,8 @@ type FbPage struct {
 	PageUsername    string                 `json:"pageUsername" bson:"pageUsername" extract:"PanCakeData\\.username"`   // Tên người dùng của trang (extract từ PanCakeData["username"])
 	PageId          string                 `json:"pageId" bson:"pageId" index:"unique;text" extract:"PanCakeData\\.id"` // ID của trang (extract từ PanCakeData["id"])
 	IsSync          bool                   `json:"isSync" bson:"isSync" default:"true"`                                 // Trạng thái đồng bộ (chỉ set khi tạo mới, qua struct tag default)
-	AccessToken     string                 `json:"accessToken" bson:"accessToken"`
-	PageAccessToken string                 `json:"pageAccessToken" bson:"pageAccessToken"` // Mã truy cập của trang
+	AccessToken     keyring.Secret         `json:"accessToken" bson:"accessToken"`
+	PageAccessToken keyring.Secret         `json:"pageAccessToken" bson:"pageAccessToken"` // Mã truy cập của trang
 	PanCakeData     map[string]interface{} `json:"panCakeData" bson:"panCakeData"`         // Dữ liệu API
 
 	// ===== ORGANIZATION =====
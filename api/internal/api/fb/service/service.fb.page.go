@@ -13,6 +13,7 @@ import (
 	"meta_commerce/internal/common"
 	"meta_commerce/internal/global"
 	basesvc "meta_commerce/internal/api/base/service"
+	"meta_commerce/internal/keyring"
 )
 
 // FbPageService là cấu trúc chứa các phương thức liên quan đến Facebook page
@@ -84,7 +85,7 @@ func (s *FbPageService) UpdateToken(ctx context.Context, input *fbdto.FbPageUpda
 		return nil, err
 	}
 	updateData := &basesvc.UpdateData{
-		Set: map[string]interface{}{"pageAccessToken": input.PageAccessToken},
+		Set: map[string]interface{}{"pageAccessToken": keyring.Secret(input.PageAccessToken)},
 	}
 	updatedPage, err := s.BaseServiceMongoImpl.UpdateById(ctx, page.ID, updateData)
 	if err != nil {
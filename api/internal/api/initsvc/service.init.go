@@ -23,6 +23,7 @@ import (
 	"meta_commerce/internal/logger"
 	"meta_commerce/internal/notification"
 	"meta_commerce/internal/utility"
+	"meta_commerce/internal/keyring"
 
 	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
@@ -298,6 +299,22 @@ var InitialPermissions = []authmodels.Permission{
 	// Quyền đặc biệt cho route CreateShare (có validation riêng về quyền với fromOrg)
 	{Name: "OrganizationShare.Create", Describe: "Quyền tạo chia sẻ dữ liệu giữa các tổ chức (route đặc biệt)", Group: "Auth", Category: "OrganizationShare"},
 
+	// Quản lý service account (agent, bot tích hợp) và API key: xem, tạo, tắt/bật + tạo/thu hồi key (Update)
+	{Name: "ServiceAccount.Insert", Describe: "Quyền tạo service account", Group: "Auth", Category: "ServiceAccount"},
+	{Name: "ServiceAccount.Read", Describe: "Quyền xem service account và API key", Group: "Auth", Category: "ServiceAccount"},
+	{Name: "ServiceAccount.Update", Describe: "Quyền tắt/bật service account, tạo và thu hồi API key", Group: "Auth", Category: "ServiceAccount"},
+
+	// Rate Limit Policy: Quản lý giới hạn request theo tổ chức / user / API key
+	{Name: "RateLimitPolicy.Insert", Describe: "Quyền tạo policy rate limit", Group: "Auth", Category: "RateLimitPolicy"},
+	{Name: "RateLimitPolicy.Read", Describe: "Quyền xem policy rate limit", Group: "Auth", Category: "RateLimitPolicy"},
+	{Name: "RateLimitPolicy.Update", Describe: "Quyền cập nhật policy rate limit", Group: "Auth", Category: "RateLimitPolicy"},
+	{Name: "RateLimitPolicy.Delete", Describe: "Quyền xóa policy rate limit", Group: "Auth", Category: "RateLimitPolicy"},
+
+	// Record Share: Chia sẻ từng bản ghi (tài khoản quảng cáo, page...) cho tổ chức khác hoặc một user
+	{Name: "RecordShare.Insert", Describe: "Quyền tạo grant chia sẻ bản ghi", Group: "Auth", Category: "RecordShare"},
+	{Name: "RecordShare.Read", Describe: "Quyền xem grant chia sẻ bản ghi và nhật ký truy cập", Group: "Auth", Category: "RecordShare"},
+	{Name: "RecordShare.Update", Describe: "Quyền thu hồi grant chia sẻ bản ghi", Group: "Auth", Category: "RecordShare"},
+
 	// Quản lý vai trò: Thêm, xem, sửa, xóa vai trò
 	{Name: "Role.Insert", Describe: "Quyền tạo vai trò", Group: "Auth", Category: "Role"},
 	{Name: "Role.Read", Describe: "Quyền xem danh sách vai trò", Group: "Auth", Category: "Role"},
@@ -464,6 +481,24 @@ var InitialPermissions = []authmodels.Permission{
 	// Báo cáo theo chu kỳ (Phase 1)
 	{Name: "Report.Read", Describe: "Quyền xem báo cáo trend", Group: "Report", Category: "Report"},
 	{Name: "Report.Recompute", Describe: "Quyền chạy lại tính toán báo cáo", Group: "Report", Category: "Report"},
+	{Name: "CrmCustomer.Merge", Describe: "Quyền gộp/tách khách CRM và duyệt hàng đợi nghi trùng", Group: "Report", Category: "CrmCustomer"},
+	{Name: "CrmSegment.Insert", Describe: "Quyền tạo phân khúc khách động", Group: "Report", Category: "CrmSegment"},
+	{Name: "CrmSegment.Read", Describe: "Quyền xem phân khúc, thành viên và export danh sách", Group: "Report", Category: "CrmSegment"},
+	{Name: "CrmSegment.Update", Describe: "Quyền sửa, bật/tắt và refresh phân khúc", Group: "Report", Category: "CrmSegment"},
+	{Name: "CrmSegment.Delete", Describe: "Quyền xóa phân khúc", Group: "Report", Category: "CrmSegment"},
+	{Name: "CrmPrivacy.Read", Describe: "Quyền xem yêu cầu export / xóa dữ liệu khách", Group: "Report", Category: "CrmPrivacy"},
+	{Name: "CrmPrivacy.Export", Describe: "Quyền tạo và tải bản export toàn bộ dữ liệu của khách", Group: "Report", Category: "CrmPrivacy"},
+	{Name: "CrmPrivacy.Erase", Describe: "Quyền xóa (ẩn danh hoá) dữ liệu cá nhân của khách", Group: "Report", Category: "CrmPrivacy"},
+	{Name: "CrmCustomField.Insert", Describe: "Quyền tạo trường tùy chỉnh của khách", Group: "Report", Category: "CrmCustomField"},
+	{Name: "CrmCustomField.Read", Describe: "Quyền xem định nghĩa trường tùy chỉnh", Group: "Report", Category: "CrmCustomField"},
+	{Name: "CrmCustomField.Update", Describe: "Quyền sửa trường tùy chỉnh (nhãn, options, bắt buộc)", Group: "Report", Category: "CrmCustomField"},
+	{Name: "CrmCustomField.Delete", Describe: "Quyền xóa trường tùy chỉnh và giá trị trên khách", Group: "Report", Category: "CrmCustomField"},
+	{Name: "CrmCustomField.SetValue", Describe: "Quyền ghi giá trị trường tùy chỉnh trên khách", Group: "Report", Category: "CrmCustomField"},
+	{Name: "CrmTag.Insert", Describe: "Quyền tạo tag khách", Group: "Report", Category: "CrmTag"},
+	{Name: "CrmTag.Read", Describe: "Quyền xem danh mục tag khách", Group: "Report", Category: "CrmTag"},
+	{Name: "CrmTag.Update", Describe: "Quyền sửa tag (tên, màu)", Group: "Report", Category: "CrmTag"},
+	{Name: "CrmTag.Delete", Describe: "Quyền xóa tag và gỡ khỏi mọi khách", Group: "Report", Category: "CrmTag"},
+	{Name: "CrmTag.Apply", Describe: "Quyền gắn / gỡ tag trên khách, kể cả hàng loạt", Group: "Report", Category: "CrmTag"},
 
 	// ==================================== NOTIFICATION MODULE ===========================================
 	// Quản lý Notification Sender: Thêm, xem, sửa, xóa
@@ -528,6 +563,10 @@ var InitialPermissions = []authmodels.Permission{
 	{Name: "OutputContract.Read", Describe: "Quyền xem Output Contract", Group: "RuleIntelligence", Category: "OutputContract"},
 	{Name: "OutputContract.Update", Describe: "Quyền cập nhật Output Contract", Group: "RuleIntelligence", Category: "OutputContract"},
 	{Name: "OutputContract.Delete", Describe: "Quyền xóa Output Contract", Group: "RuleIntelligence", Category: "OutputContract"},
+	{Name: "RuleTestCase.Insert", Describe: "Quyền tạo test case Logic Script", Group: "RuleIntelligence", Category: "RuleTestCase"},
+	{Name: "RuleTestCase.Read", Describe: "Quyền xem và chạy test case Logic Script", Group: "RuleIntelligence", Category: "RuleTestCase"},
+	{Name: "RuleTestCase.Update", Describe: "Quyền cập nhật test case Logic Script", Group: "RuleIntelligence", Category: "RuleTestCase"},
+	{Name: "RuleTestCase.Delete", Describe: "Quyền xóa test case Logic Script", Group: "RuleIntelligence", Category: "RuleTestCase"},
 
 	// ==================================== DELIVERY MODULE ===========================================
 	// Delivery Send: Gửi notification trực tiếp
@@ -542,6 +581,11 @@ var InitialPermissions = []authmodels.Permission{
 	// Quản lý Delivery History: Chỉ xem
 	{Name: "DeliveryHistory.Read", Describe: "Quyền xem lịch sử delivery", Group: "Delivery", Category: "DeliveryHistory"},
 
+	// Quản lý Delivery Dead-letter: Xem, replay (Update), purge (Delete)
+	{Name: "DeliveryDeadLetter.Read", Describe: "Quyền xem dead-letter delivery", Group: "Delivery", Category: "DeliveryDeadLetter"},
+	{Name: "DeliveryDeadLetter.Update", Describe: "Quyền replay dead-letter delivery", Group: "Delivery", Category: "DeliveryDeadLetter"},
+	{Name: "DeliveryDeadLetter.Delete", Describe: "Quyền purge dead-letter delivery", Group: "Delivery", Category: "DeliveryDeadLetter"},
+
 	// ==================================== AGENT MANAGEMENT MODULE ===========================================
 	// Quản lý Agent Registry (Bot Registry): Thêm, xem, sửa, xóa
 	{Name: "AgentRegistry.Insert", Describe: "Quyền tạo bot registry", Group: "AgentManagement", Category: "AgentRegistry"},
@@ -675,6 +719,9 @@ var InitialPermissions = []authmodels.Permission{
 	{Name: "AIWorkflowCommands.Update", Describe: "Quyền cập nhật AI workflow command", Group: "AI", Category: "AIWorkflowCommands"},
 	{Name: "AIWorkflowCommands.Delete", Describe: "Quyền xóa AI workflow command", Group: "AI", Category: "AIWorkflowCommands"},
 
+	// AI Decision queue: redrive event đã đóng (failed_terminal, completed_no_handler…) và replay datachanged từ nguồn
+	{Name: "AIDecision.Redrive", Describe: "Quyền redrive event queue AI Decision và replay datachanged từ nguồn", Group: "AI", Category: "AIDecision"},
+
 	// ==================================== WEBHOOK LOGS MODULE ===========================================
 	// Quản lý Webhook Log: Thêm, xem, sửa, xóa (để debug và tracking webhooks)
 	{Name: "WebhookLog.Insert", Describe: "Quyền tạo webhook log", Group: "Webhook", Category: "WebhookLog"},
@@ -1355,7 +1402,7 @@ func (h *InitService) InitNotificationData() error {
 			Description:         "Cấu hình Telegram bot mặc định của hệ thống. Dùng để gửi thông báo qua Telegram. Bot token có thể được cấu hình từ environment variables.",
 			IsActive:            isActive,    // Tự động bật nếu có bot token từ env, ngược lại tắt mặc định
 			IsSystem:            true,        // Đánh dấu là dữ liệu hệ thống, không thể xóa
-			BotToken:            botToken,    // Lấy từ env nếu có, ngược lại để trống
+			BotToken:            keyring.Secret(botToken),    // Lấy từ env nếu có, ngược lại để trống
 			BotUsername:         botUsername, // Lấy từ env nếu có, ngược lại để trống
 			CreatedAt:           currentTime,
 			UpdatedAt:           currentTime,
@@ -1741,6 +1788,25 @@ Trân trọng,
 Hệ thống thông báo`,
 			variables: []string{"timestamp", "state", "cpuPercent", "ramPercent", "diskPercent"},
 		},
+		{
+			eventType: "system_delivery_dlq_backlog",
+			subject:   "⚠️ [Delivery] Dead-letter queue vượt ngưỡng",
+			content: `Xin chào,
+
+Số thông báo gửi thất bại hẳn (dead-letter) đang vượt ngưỡng cảnh báo.
+
+Thông tin:
+- Thời gian: {{timestamp}}
+- Số item dead-letter: {{depth}}
+- Ngưỡng: {{threshold}}
+- Theo kênh: {{byChannel}}
+
+Vui lòng kiểm tra cấu hình kênh / sender, sau đó replay hoặc purge tại /notification/dead-letter.
+
+Trân trọng,
+Hệ thống thông báo`,
+			variables: []string{"timestamp", "depth", "threshold", "byChannel"},
+		},
 	}
 
 	// Tạo templates cho mỗi system event (Email, Telegram, Webhook)
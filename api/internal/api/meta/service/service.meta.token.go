@@ -10,11 +10,12 @@ import (
 	"time"
 
 	metaclient "meta_commerce/internal/api/meta/client"
+	"meta_commerce/internal/keyring"
 )
 
 // MetaTokenFile cấu trúc file JSON lưu token dài hạn.
 type MetaTokenFile struct {
-	AccessToken string `json:"access_token"`
+	AccessToken string `json:"access_token"` // Ciphertext keyring (file cũ chưa mã hóa vẫn đọc được)
 	ExpiresIn   int    `json:"expires_in"`   // Giây còn lại khi lưu
 	UpdatedAt   int64  `json:"updated_at"`   // Unix timestamp khi lưu
 	ExpiresAt   int64  `json:"expires_at"`   // Unix timestamp khi hết hạn (updated_at + expires_in)
@@ -41,12 +42,16 @@ func LoadMetaToken(filePath string) (string, error) {
 	if f.AccessToken == "" {
 		return "", nil
 	}
+	token, err := keyring.DecryptString(f.AccessToken)
+	if err != nil {
+		return "", fmt.Errorf("giải mã token: %w", err)
+	}
 	// Kiểm tra hết hạn: nếu expires_at đã qua (trừ 1 ngày buffer) thì coi như hết hạn
 	now := time.Now().Unix()
 	if f.ExpiresAt > 0 && now > f.ExpiresAt-86400 {
 		return "", nil // Token sắp hết hạn hoặc đã hết, không dùng
 	}
-	return f.AccessToken, nil
+	return token, nil
 }
 
 // SaveMetaToken ghi token vào file.
@@ -54,10 +59,14 @@ func SaveMetaToken(filePath string, accessToken string, expiresIn int) error {
 	if filePath == "" || accessToken == "" {
 		return fmt.Errorf("cần filePath và accessToken")
 	}
+	encrypted, err := keyring.EncryptString(accessToken)
+	if err != nil {
+		return fmt.Errorf("mã hóa token: %w", err)
+	}
 	path := resolveTokenFilePath(filePath)
 	now := time.Now().Unix()
 	f := MetaTokenFile{
-		AccessToken: accessToken,
+		AccessToken: encrypted,
 		ExpiresIn:   expiresIn,
 		UpdatedAt:   now,
 		ExpiresAt:   now + int64(expiresIn),
@@ -125,3 +134,48 @@ func ExchangeAndSaveMetaToken(ctx context.Context, appID, appSecret, shortLivedT
 	}
 	return token, nil
 }
+
+// ReencryptMetaTokenFile mã hóa lại access_token trong file bằng key active (xoay key / file cũ chưa mã hóa).
+// Trả true nếu file cần (dryRun) hoặc đã được ghi lại.
+func ReencryptMetaTokenFile(filePath string, dryRun bool) (bool, error) {
+	if filePath == "" {
+		return false, nil
+	}
+	path := resolveTokenFilePath(filePath)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("đọc file token: %w", err)
+	}
+	var f MetaTokenFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return false, fmt.Errorf("parse file token: %w", err)
+	}
+	kr, err := keyring.Default()
+	if err != nil {
+		return false, err
+	}
+	if f.AccessToken == "" || kr.IsCurrent(f.AccessToken) {
+		return false, nil
+	}
+	plain, err := keyring.DecryptString(f.AccessToken)
+	if err != nil {
+		return false, fmt.Errorf("giải mã token: %w", err)
+	}
+	if dryRun {
+		return true, nil
+	}
+	if f.AccessToken, err = kr.Encrypt([]byte(plain)); err != nil {
+		return false, err
+	}
+	out, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return false, fmt.Errorf("marshal token: %w", err)
+	}
+	if err := os.WriteFile(path, out, 0600); err != nil {
+		return false, fmt.Errorf("ghi file token: %w", err)
+	}
+	return true, nil
+}
@@ -2,7 +2,9 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -15,6 +17,7 @@ import (
 	authmodels "meta_commerce/internal/api/auth/models"
 	authsvc "meta_commerce/internal/api/auth/service"
 	"meta_commerce/internal/common"
+	"meta_commerce/internal/global"
 	"meta_commerce/internal/logger"
 	"meta_commerce/internal/utility"
 )
@@ -26,7 +29,31 @@ type AuthManager struct {
 	PermissionCRUD     *authsvc.PermissionService
 	RolePermissionCRUD *authsvc.RolePermissionService
 	UserRoleCRUD       *authsvc.UserRoleService
+	SessionCRUD        *authsvc.AuthSessionService
+	ServiceAccountCRUD *authsvc.ServiceAccountService
+	RateLimitCRUD      *authsvc.RateLimitService
 	Cache              *utility.Cache
+	// SessionCache cache ngắn cho danh sách thu hồi, token cũ → phiên và API key; instance khác thấy thu hồi sau tối đa sessionCacheTTL
+	SessionCache *utility.Cache
+	// apiKeyTouchedAt lần ghi lastUsedAt gần nhất theo API key (giới hạn ghi DB)
+	apiKeyTouchedAt sync.Map
+}
+
+// apiKeyTouchInterval khoảng tối thiểu giữa hai lần ghi lastUsedAt của một API key.
+const apiKeyTouchInterval = time.Minute
+
+// sessionCacheTTL thời gian tối đa một thu hồi phiên (từ instance khác) chưa có hiệu lực trên instance này.
+const sessionCacheTTL = 30 * time.Second
+
+// tokenIdentity kết quả xác thực Bearer token.
+type tokenIdentity struct {
+	UserID    primitive.ObjectID
+	SessionID string
+	// API key của service account: scope giới hạn permission, RoleID là role mặc định khi client không gửi X-Active-Role-ID
+	APIKeyID         string
+	ServiceAccountID string
+	Scopes           []string
+	RoleID           primitive.ObjectID
 }
 
 var (
@@ -81,8 +108,34 @@ func newAuthManager() (*AuthManager, error) {
 	}
 	newManager.UserRoleCRUD = userRoleService
 
+	sessionService, err := authsvc.NewAuthSessionService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create auth session service: %v", err)
+	}
+	newManager.SessionCRUD = sessionService
+
+	serviceAccountService, err := authsvc.NewServiceAccountService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create service account service: %v", err)
+	}
+	newManager.ServiceAccountCRUD = serviceAccountService
+
+	rateLimitService, err := authsvc.NewRateLimitService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rate limit service: %v", err)
+	}
+	newManager.RateLimitCRUD = rateLimitService
+
 	// Khởi tạo cache với thời gian sống 5 phút và thời gian dọn dẹp 10 phút
 	newManager.Cache = utility.NewCache(5*time.Minute, 10*time.Minute)
+	newManager.SessionCache = utility.NewCache(sessionCacheTTL, sessionCacheTTL)
+	// Thu hồi trên chính instance này có hiệu lực ngay, không chờ cache hết hạn
+	authsvc.RegisterSessionRevokedCallback(func(sessionID string) {
+		newManager.SessionCache.Set("revoked:"+sessionID, true)
+	})
+	authsvc.RegisterAPIKeyInvalidatedCallback(func(keyHash string) {
+		newManager.SessionCache.Delete("apikey:" + keyHash)
+	})
 
 	return newManager, nil
 }
@@ -166,6 +219,156 @@ func (am *AuthManager) getUserPermissions(userID string, activeRoleID *primitive
 	return permissions, nil
 }
 
+// authenticateToken xác thực Bearer token.
+// Token theo phiên: kiểm tra chữ ký + hạn trong bộ nhớ, rồi danh sách thu hồi qua SessionCache (DB chỉ khi cache miss).
+// Token cũ (không exp/sid): tra phiên đã chuyển đổi qua hash token, cũng qua SessionCache.
+// API key (prefix mcsk_): tra theo hash qua SessionCache, kiểm tra thu hồi/hạn/IP.
+func (am *AuthManager) authenticateToken(ctx context.Context, token string, ip string) (*tokenIdentity, error) {
+	if strings.HasPrefix(token, authsvc.APIKeyPrefix) {
+		return am.authenticateAPIKey(ctx, token, ip)
+	}
+	claims, err := utility.ParseToken(global.MongoDB_ServerConfig.JwtSecret, token)
+	if err != nil {
+		if utility.IsTokenExpired(err) {
+			return nil, common.ErrTokenExpired
+		}
+		return nil, common.ErrTokenInvalid
+	}
+
+	if claims.SessionID == "" {
+		return am.resolveLegacyToken(ctx, token)
+	}
+	if claims.TokenType != authmodels.TokenTypeAccess {
+		return nil, common.ErrTokenInvalid
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return nil, common.ErrTokenInvalid
+	}
+	revoked, err := am.isSessionRevoked(ctx, claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, common.ErrTokenExpired
+	}
+	return &tokenIdentity{UserID: userID, SessionID: claims.SessionID}, nil
+}
+
+// isSessionRevoked tra danh sách thu hồi qua SessionCache.
+func (am *AuthManager) isSessionRevoked(ctx context.Context, sessionID string) (bool, error) {
+	cacheKey := "revoked:" + sessionID
+	if cached, found := am.SessionCache.Get(cacheKey); found {
+		return cached.(bool), nil
+	}
+	objID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return true, nil
+	}
+	revoked, err := am.SessionCRUD.IsRevoked(ctx, objID)
+	if err != nil {
+		return false, err
+	}
+	am.SessionCache.Set(cacheKey, revoked)
+	return revoked, nil
+}
+
+// resolveLegacyToken xác thực token cũ qua phiên chuyển đổi (LegacyTokenHash); token chưa chuyển được chuyển ở lần dùng đầu.
+// Token đã đổi qua /auth/refresh hoặc phiên bị thu hồi → từ chối.
+func (am *AuthManager) resolveLegacyToken(ctx context.Context, token string) (*tokenIdentity, error) {
+	cacheKey := "legacy:" + authsvc.TokenHash(token)
+	identity, found := am.SessionCache.Get(cacheKey)
+	if !found {
+		session, err := am.SessionCRUD.ResolveLegacyToken(ctx, token)
+		if err != nil {
+			if !errors.Is(err, common.ErrTokenInvalid) {
+				return nil, err
+			}
+			identity = (*tokenIdentity)(nil)
+		} else {
+			identity = &tokenIdentity{UserID: session.UserID, SessionID: session.ID.Hex()}
+		}
+		am.SessionCache.Set(cacheKey, identity)
+	}
+	resolved := identity.(*tokenIdentity)
+	if resolved == nil {
+		return nil, common.ErrTokenInvalid
+	}
+	if revoked, found := am.SessionCache.Get("revoked:" + resolved.SessionID); found && revoked.(bool) {
+		return nil, common.ErrTokenExpired
+	}
+	return resolved, nil
+}
+
+// authenticateAPIKey xác thực API key của service account.
+func (am *AuthManager) authenticateAPIKey(ctx context.Context, key string, ip string) (*tokenIdentity, error) {
+	hash := authsvc.TokenHash(key)
+	cacheKey := "apikey:" + hash
+	cached, found := am.SessionCache.Get(cacheKey)
+	if !found {
+		identity, err := am.ServiceAccountCRUD.ResolveAPIKey(ctx, hash)
+		if err != nil && !errors.Is(err, common.ErrTokenInvalid) {
+			return nil, err
+		}
+		cached = identity
+		am.SessionCache.Set(cacheKey, identity)
+	}
+	identity := cached.(*authsvc.APIKeyIdentity)
+	if identity == nil {
+		return nil, common.ErrTokenInvalid
+	}
+	now := time.Now()
+	if err := authsvc.CheckAPIKeyUse(identity, ip, now); err != nil {
+		return nil, err
+	}
+
+	keyID := identity.Key.ID.Hex()
+	if last, ok := am.apiKeyTouchedAt.Load(keyID); !ok || now.Sub(last.(time.Time)) >= apiKeyTouchInterval {
+		am.apiKeyTouchedAt.Store(keyID, now)
+		if err := am.ServiceAccountCRUD.TouchAPIKey(ctx, identity.Key.ID, ip); err != nil {
+			logger.GetAppLogger().WithError(err).WithField("api_key_id", keyID).Warn("⚠️ [AUTH] Không ghi được lastUsedAt của API key")
+		}
+	}
+
+	return &tokenIdentity{
+		UserID:           identity.ServiceAccount.UserID,
+		APIKeyID:         keyID,
+		ServiceAccountID: identity.ServiceAccount.ID.Hex(),
+		Scopes:           identity.Key.Scopes,
+		RoleID:           identity.ServiceAccount.RoleID,
+	}, nil
+}
+
+// localsAuthResult key Locals giữ kết quả xác thực token của request — limiter theo IP và AuthMiddleware dùng chung, xác thực một lần.
+const localsAuthResult = "auth_result"
+
+type authResult struct {
+	token    string
+	identity *tokenIdentity
+	err      error
+}
+
+// authenticateRequestToken authenticateToken có cache trong Locals theo token của request.
+func (am *AuthManager) authenticateRequestToken(c fiber.Ctx, token string) (*tokenIdentity, error) {
+	if r, ok := c.Locals(localsAuthResult).(*authResult); ok && r.token == token {
+		return r.identity, r.err
+	}
+	identity, err := am.authenticateToken(context.Background(), token, c.IP())
+	c.Locals(localsAuthResult, &authResult{token: token, identity: identity, err: err})
+	return identity, err
+}
+
+// IsAuthenticatedRequest request có token / API key hợp lệ — limiter theo IP chỉ nhường cho rate limit theo tenant khi
+// xác thực thành công; token rác hoặc hết hạn vẫn bị đếm theo IP.
+func IsAuthenticatedRequest(c fiber.Ctx) bool {
+	parts := strings.Split(bearerAuthFromRequest(c), " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return false
+	}
+	identity, err := GetAuthManager().authenticateRequestToken(c, parts[1])
+	return err == nil && identity != nil
+}
+
 // bearerAuthFromRequest ưu tiên header Authorization; nếu rỗng thì dùng query access_token hoặc token.
 // WebSocket trên Chrome/Flutter Web không gửi được custom header trên handshake — client thường truyền ?access_token=...
 func bearerAuthFromRequest(c fiber.Ctx) string {
@@ -173,6 +376,10 @@ func bearerAuthFromRequest(c fiber.Ctx) string {
 	if h != "" {
 		return h
 	}
+	// API key của service account có thể gửi qua header riêng
+	if k := strings.TrimSpace(c.Get("X-API-Key")); k != "" {
+		return "Bearer " + k
+	}
 	if t := strings.TrimSpace(c.Query("access_token")); t != "" {
 		return "Bearer " + t
 	}
@@ -190,8 +397,27 @@ func activeRoleIDFromRequest(c fiber.Ctx) string {
 	return strings.TrimSpace(c.Query("role_id"))
 }
 
-// AuthMiddleware middleware xác thực cho Fiber
+// AuthMiddleware middleware xác thực cho Fiber.
+// requirePermission rỗng: chỉ cần đăng nhập; API key bị từ chối vì không có permission để đối chiếu scope
+// (route API key cần gọi dùng AuthOnlyMiddleware hoặc DomainPermissionAuthMiddleware).
 func AuthMiddleware(requirePermission string) fiber.Handler {
+	return newAuthMiddleware(requirePermission, requirePermission, false)
+}
+
+// AuthOnlyMiddleware chỉ cần đăng nhập, không kiểm quyền role; API key phải có apiKeyScope trong scopes.
+func AuthOnlyMiddleware(apiKeyScope string) fiber.Handler {
+	return newAuthMiddleware("", apiKeyScope, false)
+}
+
+// DomainPermissionAuthMiddleware chỉ xác thực — handler kiểm quyền (kèm scope API key) qua EnforceActiveRolePermission
+// khi đã biết permission theo domain (ví dụ CIO ingest).
+func DomainPermissionAuthMiddleware() fiber.Handler {
+	return newAuthMiddleware("", "", true)
+}
+
+// newAuthMiddleware xác thực + rate limit + kiểm quyền. apiKeyScope: scope API key phải có khi requirePermission rỗng;
+// deferScope true: handler tự kiểm scope qua EnforceActiveRolePermission.
+func newAuthMiddleware(requirePermission, apiKeyScope string, deferScope bool) fiber.Handler {
 	// Sử dụng singleton instance của AuthManager
 	authManager := GetAuthManager()
 
@@ -215,64 +441,43 @@ func AuthMiddleware(requirePermission string) fiber.Handler {
 
 		token := parts[1]
 
-		// Tìm user có token
-		// Ưu tiên query field "token" (token mới nhất) trước vì nó được cập nhật mỗi lần login
-		// Nếu không tìm thấy, query trong array "tokens" (tokens theo hwid)
-		var user authmodels.User
-		var err error
-		var query bson.M
-
-		// Cách 1: Query field "token" (token mới nhất) - ĐÂY LÀ CÁCH CHÍNH
-		query = bson.M{"token": token}
-		user, err = authManager.UserCRUD.BaseServiceMongoImpl.FindOne(context.Background(), query, nil)
-
+		// Xác thực chữ ký + hạn trong bộ nhớ; khóa tài khoản được xử lý bằng thu hồi phiên (AdminService.BlockUser)
+		identity, err := authManager.authenticateRequestToken(c, token)
 		if err != nil {
-
-			// Cách 2: Query trong array "tokens" với dot notation
-			query = bson.M{"tokens.jwtToken": token}
-			user, err = authManager.UserCRUD.BaseServiceMongoImpl.FindOne(context.Background(), query, nil)
-
-			if err != nil {
-				// Cách 3: Query với $elemMatch
-				query = bson.M{
-					"tokens": bson.M{
-						"$elemMatch": bson.M{
-							"jwtToken": token,
-						},
-					},
-				}
-				user, err = authManager.UserCRUD.BaseServiceMongoImpl.FindOne(context.Background(), query, nil)
-			}
-		}
-
-		if err != nil {
-			// Chỉ log khi không tìm thấy token (lỗi quan trọng)
 			logger.GetAppLogger().WithFields(logrus.Fields{
 				"path":  c.Path(),
 				"error": err.Error(),
-			}).Warn("❌ [AUTH] Token not found in database")
-			HandleErrorResponse(c, common.ErrTokenInvalid)
+			}).Warn("❌ [AUTH] Token rejected")
+			HandleErrorResponse(c, err)
 			return nil
 		}
 
-		// Kiểm tra user có bị block không
-		if user.IsBlock {
-			HandleErrorResponse(c, common.NewError(
-				common.ErrCodeAuthCredentials,
-				"Tài khoản đã bị khóa: "+user.BlockNote,
-				common.StatusForbidden,
-				nil,
-			))
-			return nil
-		}
-
-		// Lưu thông tin user vào context
+		// Lưu thông tin user vào context (user chỉ có ID — handler cần đầy đủ thì tự đọc DB)
+		user := authmodels.User{ID: identity.UserID}
 		c.Locals("user_id", user.ID.Hex())
 		c.Locals("user", user)
+		c.Locals("session_id", identity.SessionID)
+		if identity.APIKeyID != "" {
+			c.Locals("api_key_id", identity.APIKeyID)
+			c.Locals("service_account_id", identity.ServiceAccountID)
+			c.Locals("api_key_scopes", identity.Scopes)
+			// Agent không gửi role context → dùng role của service account (enforceActiveRolePermission, OrganizationContextMiddleware đọc header này)
+			if activeRoleIDFromRequest(c) == "" {
+				c.Request().Header.Set("X-Active-Role-ID", identity.RoleID.Hex())
+			}
+		}
+
+		// Rate limit theo tổ chức / user / API key (sau xác thực, trước kiểm quyền)
+		if !enforceRateLimit(c, authManager, identity) {
+			return nil
+		}
 
 		// Nếu không yêu cầu permission cụ thể, cho phép truy cập NGAY
 		// Đây là endpoint đặc biệt như /auth/roles - chỉ cần xác thực, không cần permission
 		if requirePermission == "" {
+			if !deferScope && !enforceAPIKeyScope(c, apiKeyScope) {
+				return nil
+			}
 			return c.Next()
 		}
 
@@ -290,6 +495,11 @@ func enforceActiveRolePermission(c fiber.Ctx, authManager *AuthManager, user aut
 		return true
 	}
 
+	// API key: permission phải nằm trong scope của key (scope là tập con permission của role)
+	if !enforceAPIKeyScope(c, requirePermission) {
+		return false
+	}
+
 	activeRoleIDStr := activeRoleIDFromRequest(c)
 
 	if activeRoleIDStr == "" {
@@ -427,11 +637,31 @@ func enforceActiveRolePermission(c fiber.Ctx, authManager *AuthManager, user aut
 	return true
 }
 
-// EnforceActiveRolePermission dùng sau AuthMiddleware(""): kiểm tra quyền theo domain (ví dụ CIO ingest thống nhất).
+// enforceAPIKeyScope request bằng API key phải có scope trong scopes của key (scope rỗng → từ chối).
+// Request bằng token người dùng luôn qua. Trả về false nếu đã gọi HandleErrorResponse.
+func enforceAPIKeyScope(c fiber.Ctx, scope string) bool {
+	scopes, ok := c.Locals("api_key_scopes").([]string)
+	if !ok || (scope != "" && slices.Contains(scopes, scope)) {
+		return true
+	}
+	logger.GetAppLogger().WithFields(logrus.Fields{
+		"api_key_id": c.Locals("api_key_id"),
+		"path":       c.Path(),
+		"permission": scope,
+	}).Warn("❌ [AUTH] API key thiếu scope")
+	message := "API key không có scope " + scope
+	if scope == "" {
+		message = "API key không được dùng cho route này"
+	}
+	HandleErrorResponse(c, common.NewError(common.ErrCodeAuthRole, message, common.StatusForbidden, nil))
+	return false
+}
+
+// EnforceActiveRolePermission dùng sau DomainPermissionAuthMiddleware: kiểm tra quyền theo domain (ví dụ CIO ingest thống nhất).
 // Trả về false nếu đã gửi response lỗi — handler nên return nil ngay.
 func EnforceActiveRolePermission(c fiber.Ctx, requirePermission string) bool {
 	if requirePermission == "" {
-		return true
+		return enforceAPIKeyScope(c, "")
 	}
 	userVal := c.Locals("user")
 	user, ok := userVal.(authmodels.User)
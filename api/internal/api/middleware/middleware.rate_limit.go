@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	authmodels "meta_commerce/internal/api/auth/models"
+	authsvc "meta_commerce/internal/api/auth/service"
+	"meta_commerce/internal/common"
+	"meta_commerce/internal/global"
+	"meta_commerce/internal/logger"
+)
+
+// enforceRateLimit áp policy rate limit của nhóm route hiện tại cho tổ chức (theo role đang làm việc), user và API key.
+// Trả header RateLimit-Limit / RateLimit-Remaining / RateLimit-Reset / RateLimit-Policy theo cửa sổ chặt nhất.
+// Trả về false nếu đã trả 429. Lỗi đọc/ghi bộ đếm → cho qua (không chặn toàn hệ thống vì Mongo chậm).
+func enforceRateLimit(c fiber.Ctx, authManager *AuthManager, identity *tokenIdentity) bool {
+	if !global.MongoDB_ServerConfig.RateLimit_TenantEnabled || authManager.RateLimitCRUD == nil {
+		return true
+	}
+	// Route có nhiều AuthMiddleware (group + route) chỉ tính một lần mỗi request
+	if checked, _ := c.Locals("rate_limit_checked").(bool); checked {
+		return true
+	}
+	c.Locals("rate_limit_checked", true)
+	ctx := context.Background()
+
+	var subjects []authsvc.RateLimitSubject
+	if identity.APIKeyID != "" {
+		subjects = append(subjects, authsvc.RateLimitSubject{Scope: authmodels.RateLimitScopeAPIKey, ID: identity.APIKeyID})
+	} else {
+		subjects = append(subjects, authsvc.RateLimitSubject{Scope: authmodels.RateLimitScopeUser, ID: identity.UserID.Hex()})
+	}
+	orgID := authManager.rateLimitOrganization(ctx, identity.UserID, activeRoleIDFromRequest(c))
+	if !orgID.IsZero() {
+		subjects = append(subjects, authsvc.RateLimitSubject{Scope: authmodels.RateLimitScopeOrganization, ID: orgID.Hex()})
+	}
+
+	group := authsvc.RateLimitRouteGroup(c.Path())
+	result, err := authManager.RateLimitCRUD.Check(ctx, orgID, group, subjects, time.Now())
+	if err != nil {
+		logger.GetAppLogger().WithFields(logrus.Fields{
+			"path":  c.Path(),
+			"error": err.Error(),
+		}).Warn("⚠️ [RATE_LIMIT] Không kiểm tra được bộ đếm, cho qua")
+		return true
+	}
+	if result == nil {
+		return true
+	}
+
+	resetSeconds := strconv.Itoa(int((result.Reset + time.Second - 1) / time.Second))
+	c.Set("RateLimit-Limit", strconv.Itoa(result.Window.Limit))
+	c.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Set("RateLimit-Reset", resetSeconds)
+	c.Set("RateLimit-Policy", authsvc.FormatRateLimitPolicy(result.Window))
+	if result.Allowed {
+		return true
+	}
+
+	logger.GetAppLogger().WithFields(logrus.Fields{
+		"path":   c.Path(),
+		"group":  group,
+		"scope":  result.Scope,
+		"window": result.Window.Name,
+		"limit":  result.Window.Limit,
+	}).Warn("❌ [RATE_LIMIT] Vượt giới hạn")
+	c.Set("Retry-After", resetSeconds)
+	HandleErrorResponse(c, common.NewError(
+		common.ErrCodeBusinessOperation,
+		common.MsgTooManyRequests+", vui lòng thử lại sau",
+		common.StatusTooManyRequests,
+		map[string]interface{}{
+			"routeGroup": group,
+			"scope":      result.Scope,
+			"window":     result.Window.Name,
+			"limit":      result.Window.Limit,
+			"retryAfter": resetSeconds,
+		},
+	))
+	return false
+}
+
+// rateLimitOrganization tổ chức của role đang làm việc, chỉ khi user thực sự có role đó
+// (tránh gửi role của tổ chức khác để tiêu hạn mức của họ). Không gửi role / role không hợp lệ → role đầu tiên
+// của user như OrganizationContextMiddleware. Cache theo user + role.
+func (am *AuthManager) rateLimitOrganization(ctx context.Context, userID primitive.ObjectID, activeRoleID string) primitive.ObjectID {
+	cacheKey := "ratelimit_org:" + userID.Hex() + ":" + activeRoleID
+	if cached, ok := am.Cache.Get(cacheKey); ok {
+		return cached.(primitive.ObjectID)
+	}
+	roleID := primitive.NilObjectID
+	if id, err := primitive.ObjectIDFromHex(activeRoleID); err == nil {
+		if _, err := am.UserRoleCRUD.BaseServiceMongoImpl.FindOne(ctx, bson.M{"userId": userID, "roleId": id}, nil); err == nil {
+			roleID = id
+		}
+	}
+	if roleID.IsZero() {
+		if userRole, err := am.UserRoleCRUD.BaseServiceMongoImpl.FindOne(ctx, bson.M{"userId": userID}, nil); err == nil {
+			roleID = userRole.RoleID
+		}
+	}
+	orgID := primitive.NilObjectID
+	if !roleID.IsZero() {
+		if role, err := am.RoleCRUD.FindOneById(ctx, roleID); err == nil {
+			orgID = role.OwnerOrganizationID
+		}
+	}
+	am.Cache.Set(cacheKey, orgID)
+	return orgID
+}
@@ -9,6 +9,9 @@ type NotificationChannelCreateInput struct {
 	Recipients  []string `json:"recipients,omitempty"`
 	// ChatIDs: Telegram - mỗi phần tử "chatID" hoặc "chatID:topicID" (topic trong forum supergroup). VD: ["-123456789:12345"]
 	ChatIDs     []string `json:"chatIds,omitempty"`
+	// PSIDs: Messenger - page-scoped ID của nhân viên
+	PSIDs       []string `json:"psids,omitempty"`
+	// WebhookURL: webhook / Slack / Discord incoming webhook
 	WebhookURL  string   `json:"webhookUrl,omitempty"`
 	WebhookHeaders map[string]string `json:"webhookHeaders,omitempty"`
 }
@@ -22,6 +25,7 @@ type NotificationChannelUpdateInput struct {
 	Recipients     []string          `json:"recipients,omitempty"`
 	// ChatIDs: Telegram - "chatID" hoặc "chatID:topicID". VD: ["-123456789:12345"]
 	ChatIDs        []string          `json:"chatIds,omitempty"`
+	PSIDs          []string          `json:"psids,omitempty"`
 	WebhookURL     string            `json:"webhookUrl,omitempty"`
 	WebhookHeaders map[string]string `json:"webhookHeaders,omitempty"`
 }
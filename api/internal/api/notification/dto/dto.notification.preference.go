@@ -0,0 +1,18 @@
+package notifdto
+
+// NotificationPreferenceInput dùng cho đăng ký / hủy đăng ký thông báo của user hiện tại (upsert theo eventType + domain).
+// EventType và Domain cùng rỗng = tùy chọn mặc định cho mọi event.
+type NotificationPreferenceInput struct {
+	EventType  string `json:"eventType,omitempty"`
+	Domain     string `json:"domain,omitempty"`
+	Subscribed *bool  `json:"subscribed" validate:"required"`
+	// Channels: in_app, email, telegram — rỗng = in_app
+	Channels       []string `json:"channels,omitempty"`
+	TelegramChatID string   `json:"telegramChatId,omitempty"`
+}
+
+// NotificationInboxMarkReadInput dùng cho đánh dấu đã đọc thông báo in-app
+type NotificationInboxMarkReadInput struct {
+	IDs []string `json:"ids,omitempty"`
+	All bool     `json:"all"`
+}
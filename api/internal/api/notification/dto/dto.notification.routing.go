@@ -8,6 +8,11 @@ type NotificationRoutingRuleCreateInput struct {
 	ChannelTypes    []string `json:"channelTypes,omitempty"`
 	Severities      []string `json:"severities,omitempty"`
 	IsActive        bool     `json:"isActive"`
+
+	QuietHours         *RoutingQuietHoursInput `json:"quietHours,omitempty" transform:"nested_struct"`
+	DedupKey           string                  `json:"dedupKey,omitempty"`
+	DedupWindowMinutes int                     `json:"dedupWindowMinutes,omitempty" validate:"omitempty,min=0"`
+	DigestMinutes      int                     `json:"digestMinutes,omitempty" validate:"omitempty,min=0"`
 }
 
 // NotificationRoutingRuleUpdateInput dùng cho cập nhật notification routing rule (tầng transport)
@@ -18,4 +23,17 @@ type NotificationRoutingRuleUpdateInput struct {
 	ChannelTypes    []string `json:"channelTypes,omitempty"`
 	Severities      []string `json:"severities,omitempty"`
 	IsActive        *bool    `json:"isActive,omitempty"`
+
+	QuietHours         *RoutingQuietHoursInput `json:"quietHours,omitempty" transform:"nested_struct"`
+	DedupKey           string                  `json:"dedupKey,omitempty"`
+	DedupWindowMinutes int                     `json:"dedupWindowMinutes,omitempty" validate:"omitempty,min=0"`
+	DigestMinutes      int                     `json:"digestMinutes,omitempty" validate:"omitempty,min=0"`
+}
+
+// RoutingQuietHoursInput khung giờ yên lặng của routing rule (HH:MM, theo múi giờ tổ chức)
+type RoutingQuietHoursInput struct {
+	Start            string   `json:"start" validate:"required"`
+	End              string   `json:"end" validate:"required"`
+	Timezone         string   `json:"timezone,omitempty"`
+	BypassSeverities []string `json:"bypassSeverities,omitempty"`
 }
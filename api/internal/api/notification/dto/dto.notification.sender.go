@@ -13,6 +13,18 @@ type NotificationChannelSenderCreateInput struct {
 	FromName      string `json:"fromName,omitempty"`
 	BotToken      string `json:"botToken,omitempty"`
 	BotUsername   string `json:"botUsername,omitempty"`
+	WebhookSecret          string            `json:"webhookSecret,omitempty"`
+	WebhookHeaders         map[string]string `json:"webhookHeaders,omitempty"`
+	WebhookAuthType        string            `json:"webhookAuthType,omitempty" validate:"omitempty,oneof=bearer basic"`
+	WebhookAuthToken       string            `json:"webhookAuthToken,omitempty"`
+	WebhookAuthUsername    string            `json:"webhookAuthUsername,omitempty"`
+	WebhookAuthPassword    string            `json:"webhookAuthPassword,omitempty"`
+	WebhookPayloadTemplate string            `json:"webhookPayloadTemplate,omitempty"`
+	WebhookTimeoutSeconds  int               `json:"webhookTimeoutSeconds,omitempty" validate:"omitempty,min=1,max=60"`
+	ChatUsername           string            `json:"chatUsername,omitempty"`
+	ChatIconURL            string            `json:"chatIconUrl,omitempty" validate:"omitempty,url"`
+	FacebookPageID         string            `json:"facebookPageId,omitempty"`
+	MessengerTag           string            `json:"messengerTag,omitempty" validate:"omitempty,oneof=ACCOUNT_UPDATE CONFIRMED_EVENT_UPDATE POST_PURCHASE_UPDATE HUMAN_AGENT"`
 }
 
 // NotificationChannelSenderUpdateInput dùng cho cập nhật notification sender (tầng transport)
@@ -28,4 +40,16 @@ type NotificationChannelSenderUpdateInput struct {
 	FromName      string `json:"fromName,omitempty"`
 	BotToken      string `json:"botToken,omitempty"`
 	BotUsername   string `json:"botUsername,omitempty"`
+	WebhookSecret          string            `json:"webhookSecret,omitempty"`
+	WebhookHeaders         map[string]string `json:"webhookHeaders,omitempty"`
+	WebhookAuthType        string            `json:"webhookAuthType,omitempty" validate:"omitempty,oneof=bearer basic"`
+	WebhookAuthToken       string            `json:"webhookAuthToken,omitempty"`
+	WebhookAuthUsername    string            `json:"webhookAuthUsername,omitempty"`
+	WebhookAuthPassword    string            `json:"webhookAuthPassword,omitempty"`
+	WebhookPayloadTemplate string            `json:"webhookPayloadTemplate,omitempty"`
+	WebhookTimeoutSeconds  *int              `json:"webhookTimeoutSeconds,omitempty" validate:"omitempty,min=1,max=60"`
+	ChatUsername           string            `json:"chatUsername,omitempty"`
+	ChatIconURL            string            `json:"chatIconUrl,omitempty" validate:"omitempty,url"`
+	FacebookPageID         string            `json:"facebookPageId,omitempty"`
+	MessengerTag           string            `json:"messengerTag,omitempty" validate:"omitempty,oneof=ACCOUNT_UPDATE CONFIRMED_EVENT_UPDATE POST_PURCHASE_UPDATE HUMAN_AGENT"`
 }
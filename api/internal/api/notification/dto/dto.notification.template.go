@@ -6,6 +6,7 @@ type NotificationTemplateCreateInput struct {
 	ChannelType string                      `json:"channelType" validate:"required"`
 	Subject     string                      `json:"subject,omitempty"`
 	Content     string                      `json:"content" validate:"required"`
+	Format      string                      `json:"format,omitempty" validate:"omitempty,oneof=text html markdown json"`
 	Variables   []string                    `json:"variables,omitempty"`
 	CTAs        []NotificationCTACreateInput `json:"ctas,omitempty"`
 	IsActive    bool                        `json:"isActive"`
@@ -17,6 +18,7 @@ type NotificationTemplateUpdateInput struct {
 	ChannelType string                      `json:"channelType"`
 	Subject     string                      `json:"subject,omitempty"`
 	Content     string                      `json:"content"`
+	Format      string                      `json:"format,omitempty" validate:"omitempty,oneof=text html markdown json"`
 	Variables   []string                    `json:"variables,omitempty"`
 	CTAs        []NotificationCTACreateInput `json:"ctas,omitempty"`
 	IsActive    *bool                       `json:"isActive"`
@@ -28,3 +30,9 @@ type NotificationCTACreateInput struct {
 	Action string `json:"action" validate:"required"`
 	Style  string `json:"style,omitempty"`
 }
+
+// NotificationTemplatePreviewInput body của POST /notification/template/:id/preview
+type NotificationTemplatePreviewInput struct {
+	Payload map[string]interface{} `json:"payload"`
+	Format  string                 `json:"format,omitempty" validate:"omitempty,oneof=text html markdown json"` // Ghi đè format của template
+}
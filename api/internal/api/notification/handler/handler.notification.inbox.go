@@ -0,0 +1,202 @@
+package notifhdl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	basehdl "meta_commerce/internal/api/base/handler"
+	notifdto "meta_commerce/internal/api/notification/dto"
+	notifmodels "meta_commerce/internal/api/notification/models"
+	notifsvc "meta_commerce/internal/api/notification/service"
+	"meta_commerce/internal/common"
+	"meta_commerce/internal/notification/inboxlive"
+
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v3"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NotificationInboxHandler xử lý hộp thư in-app của user hiện tại (list, unread-count, mark-read, WebSocket realtime)
+type NotificationInboxHandler struct {
+	*basehdl.BaseHandler[notifmodels.NotificationInboxItem, notifmodels.NotificationInboxItem, notifmodels.NotificationInboxItem]
+	inboxService *notifsvc.NotificationInboxService
+}
+
+// NewNotificationInboxHandler tạo mới NotificationInboxHandler
+func NewNotificationInboxHandler() (*NotificationInboxHandler, error) {
+	inboxService, err := notifsvc.NewNotificationInboxService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notification inbox service: %v", err)
+	}
+	return &NotificationInboxHandler{
+		BaseHandler:  basehdl.NewBaseHandler[notifmodels.NotificationInboxItem, notifmodels.NotificationInboxItem, notifmodels.NotificationInboxItem](inboxService),
+		inboxService: inboxService,
+	}, nil
+}
+
+// HandleList GET /notification/inbox?page=&limit=&unread=true — hộp thư của user trong org đang chọn, mới nhất trước.
+func (h *NotificationInboxHandler) HandleList(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		orgID, userID, err := userOrgFromContext(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		page, limit := h.ParsePagination(c)
+		result, err := h.inboxService.ListForUser(c.Context(), orgID, userID, c.Query("unread") == "true", page, limit)
+		h.HandleResponse(c, result, err)
+		return nil
+	})
+}
+
+// HandleUnreadCount GET /notification/inbox/unread-count
+func (h *NotificationInboxHandler) HandleUnreadCount(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		orgID, userID, err := userOrgFromContext(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		count, err := h.inboxService.UnreadCount(c.Context(), orgID, userID)
+		h.HandleResponse(c, fiber.Map{"unreadCount": count}, err)
+		return nil
+	})
+}
+
+// HandleMarkRead POST /notification/inbox/read — body: {ids: [...]} hoặc {all: true}.
+func (h *NotificationInboxHandler) HandleMarkRead(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		orgID, userID, err := userOrgFromContext(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		var input notifdto.NotificationInboxMarkReadInput
+		if err := h.ParseRequestBody(c, &input); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		ids := make([]primitive.ObjectID, 0, len(input.IDs))
+		for _, id := range input.IDs {
+			oid, err := primitive.ObjectIDFromHex(id)
+			if err != nil {
+				h.HandleResponse(c, nil, common.NewError(
+					common.ErrCodeValidationFormat,
+					fmt.Sprintf("ID '%s' không đúng định dạng MongoDB ObjectID (phải là chuỗi hex 24 ký tự)", id),
+					common.StatusBadRequest,
+					err,
+				))
+				return nil
+			}
+			ids = append(ids, oid)
+		}
+
+		marked, unread, err := h.inboxService.MarkRead(c.Context(), orgID, userID, ids, input.All)
+		h.HandleResponse(c, fiber.Map{"marked": marked, "unreadCount": unread}, err)
+		return nil
+	})
+}
+
+// HandleLiveWS GET /notification/inbox/live — WS: gửi unread_count ban đầu → stream thông báo mới / unread_count.
+// Trình duyệt không gửi được header: truyền token qua ?access_token=, org qua ?role_id= (như WS AI Decision).
+func (h *NotificationInboxHandler) HandleLiveWS(c fiber.Ctx) error {
+	orgID, userID, err := userOrgFromContext(c)
+	if err != nil {
+		h.HandleResponse(c, nil, err)
+		return nil
+	}
+
+	err = basehdl.WSUpgrader.Upgrade(c.RequestCtx(), func(conn *websocket.Conn) {
+		defer conn.Close()
+		_ = conn.SetReadDeadline(time.Now().Add(90 * time.Second))
+		conn.SetPongHandler(func(string) error {
+			_ = conn.SetReadDeadline(time.Now().Add(120 * time.Second))
+			return nil
+		})
+
+		// Subscribe trước khi đếm: thông báo tới giữa hai bước vẫn nằm trong liveCh
+		liveCh, cancel := inboxlive.Subscribe(orgID, userID)
+		defer cancel()
+
+		unread, err := h.inboxService.UnreadCount(context.Background(), orgID, userID)
+		if err == nil {
+			if err := writeInboxJSON(conn, inboxlive.InboxLiveEvent{Type: inboxlive.EventUnreadCount, UnreadCount: unread}); err != nil {
+				return
+			}
+		}
+
+		done := make(chan struct{})
+		go pingInboxWS(conn, done)
+		go func() {
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					close(done)
+					return
+				}
+			}
+		}()
+
+		for {
+			select {
+			case <-done:
+				return
+			case ev, ok := <-liveCh:
+				if !ok {
+					return
+				}
+				if err := writeInboxJSON(conn, ev); err != nil {
+					logrus.WithError(err).Debug("Notification inbox live: gửi stream thất bại")
+					return
+				}
+			}
+		}
+	})
+	if err != nil {
+		logrus.WithError(err).Warn("Notification inbox live: WebSocket upgrade thất bại")
+	}
+	return nil
+}
+
+func writeInboxJSON(conn *websocket.Conn, ev inboxlive.InboxLiveEvent) error {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_ = conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return conn.WriteMessage(websocket.TextMessage, b)
+}
+
+// pingInboxWS gửi Ping định kỳ để client trả Pong — gia hạn read deadline.
+func pingInboxWS(conn *websocket.Conn, done <-chan struct{}) {
+	ticker := time.NewTicker(25 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			_ = conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// userOrgFromContext lấy org đang chọn và user đăng nhập — inbox / preference luôn thuộc chính user này.
+func userOrgFromContext(c fiber.Ctx) (primitive.ObjectID, primitive.ObjectID, error) {
+	orgIDStr, _ := c.Locals("active_organization_id").(string)
+	orgID, err := primitive.ObjectIDFromHex(orgIDStr)
+	if err != nil {
+		return primitive.NilObjectID, primitive.NilObjectID, common.NewError(common.ErrCodeValidationInput, "Chưa chọn tổ chức", common.StatusBadRequest, nil)
+	}
+	userIDStr, _ := c.Locals("user_id").(string)
+	userID, err := primitive.ObjectIDFromHex(userIDStr)
+	if err != nil {
+		return primitive.NilObjectID, primitive.NilObjectID, common.NewError(common.ErrCodeAuthToken, "Chưa đăng nhập", common.StatusUnauthorized, nil)
+	}
+	return orgID, userID, nil
+}
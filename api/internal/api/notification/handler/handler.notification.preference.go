@@ -0,0 +1,102 @@
+package notifhdl
+
+import (
+	"fmt"
+	basehdl "meta_commerce/internal/api/base/handler"
+	notifdto "meta_commerce/internal/api/notification/dto"
+	notifmodels "meta_commerce/internal/api/notification/models"
+	notifsvc "meta_commerce/internal/api/notification/service"
+	"meta_commerce/internal/common"
+	"meta_commerce/internal/utility"
+
+	"github.com/gofiber/fiber/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NotificationPreferenceHandler xử lý tùy chọn nhận thông báo của user hiện tại (chỉ đọc / sửa preference của chính mình)
+type NotificationPreferenceHandler struct {
+	*basehdl.BaseHandler[notifmodels.NotificationPreference, notifdto.NotificationPreferenceInput, notifdto.NotificationPreferenceInput]
+	preferenceService *notifsvc.NotificationPreferenceService
+}
+
+// NewNotificationPreferenceHandler tạo mới NotificationPreferenceHandler
+func NewNotificationPreferenceHandler() (*NotificationPreferenceHandler, error) {
+	preferenceService, err := notifsvc.NewNotificationPreferenceService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notification preference service: %v", err)
+	}
+	return &NotificationPreferenceHandler{
+		BaseHandler:       basehdl.NewBaseHandler[notifmodels.NotificationPreference, notifdto.NotificationPreferenceInput, notifdto.NotificationPreferenceInput](preferenceService),
+		preferenceService: preferenceService,
+	}, nil
+}
+
+// HandleList GET /notification/preference — preference của user trong org đang chọn.
+func (h *NotificationPreferenceHandler) HandleList(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		orgID, userID, err := userOrgFromContext(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		prefs, err := h.preferenceService.ListForUser(c.Context(), orgID, userID)
+		h.HandleResponse(c, prefs, err)
+		return nil
+	})
+}
+
+// HandleSave PUT /notification/preference — đăng ký / hủy đăng ký theo eventType hoặc domain (upsert).
+func (h *NotificationPreferenceHandler) HandleSave(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		orgID, userID, err := userOrgFromContext(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		var input notifdto.NotificationPreferenceInput
+		if err := h.ParseRequestBody(c, &input); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		if input.EventType != "" && input.Domain != "" {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationInput, "Chỉ chọn eventType hoặc domain", common.StatusBadRequest, nil))
+			return nil
+		}
+
+		saved, err := h.preferenceService.Save(c.Context(), notifmodels.NotificationPreference{
+			UserID:              userID,
+			OwnerOrganizationID: orgID,
+			EventType:           input.EventType,
+			Domain:              input.Domain,
+			Subscribed:          *input.Subscribed,
+			Channels:            input.Channels,
+			TelegramChatID:      input.TelegramChatID,
+		})
+		h.HandleResponse(c, saved, err)
+		return nil
+	})
+}
+
+// HandleDelete DELETE /notification/preference/:id — xóa preference (quay về tùy chọn rộng hơn).
+func (h *NotificationPreferenceHandler) HandleDelete(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		orgID, userID, err := userOrgFromContext(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		id := c.Params("id")
+		if !primitive.IsValidObjectID(id) {
+			h.HandleResponse(c, nil, common.NewError(
+				common.ErrCodeValidationFormat,
+				fmt.Sprintf("ID '%s' không đúng định dạng MongoDB ObjectID (phải là chuỗi hex 24 ký tự)", id),
+				common.StatusBadRequest,
+				nil,
+			))
+			return nil
+		}
+		err = h.preferenceService.DeleteForUser(c.Context(), orgID, userID, utility.String2ObjectID(id))
+		h.HandleResponse(c, nil, err)
+		return nil
+	})
+}
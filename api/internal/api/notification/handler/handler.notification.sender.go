@@ -24,7 +24,7 @@ func NewNotificationSenderHandler() (*NotificationSenderHandler, error) {
 		BaseHandler: basehdl.NewBaseHandler[notifmodels.NotificationChannelSender, notifdto.NotificationChannelSenderCreateInput, notifdto.NotificationChannelSenderUpdateInput](senderService),
 	}
 	hdl.SetFilterOptions(basehdl.FilterOptions{
-		DeniedFields: []string{"smtpPassword", "botToken"},
+		DeniedFields: []string{"smtpPassword", "botToken", "webhookSecret", "webhookAuthToken", "webhookAuthPassword"},
 		AllowedOperators: []string{"$eq", "$gt", "$gte", "$lt", "$lte", "$in", "$nin", "$exists"},
 		MaxFields: 10,
 	})
@@ -6,6 +6,12 @@ import (
 	notifmodels "meta_commerce/internal/api/notification/models"
 	notifdto "meta_commerce/internal/api/notification/dto"
 	notifsvc "meta_commerce/internal/api/notification/service"
+	"meta_commerce/internal/common"
+	"meta_commerce/internal/notification"
+	"meta_commerce/internal/utility"
+
+	"github.com/gofiber/fiber/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // NotificationTemplateHandler xử lý các request liên quan đến Notification Template
@@ -30,3 +36,46 @@ func NewNotificationTemplateHandler() (*NotificationTemplateHandler, error) {
 	})
 	return hdl, nil
 }
+
+// HandlePreview render template với payload mẫu, báo biến thiếu / không dùng / chưa khai báo.
+// POST /notification/template/:id/preview — body: {payload, format?}.
+func (h *NotificationTemplateHandler) HandlePreview(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		id := c.Params("id")
+		if !primitive.IsValidObjectID(id) {
+			h.HandleResponse(c, nil, common.NewError(
+				common.ErrCodeValidationFormat,
+				fmt.Sprintf("ID '%s' không đúng định dạng MongoDB ObjectID (phải là chuỗi hex 24 ký tự)", id),
+				common.StatusBadRequest,
+				nil,
+			))
+			return nil
+		}
+		if err := h.ValidateOrganizationAccess(c, id); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		var input notifdto.NotificationTemplatePreviewInput
+		if err := h.ParseRequestBody(c, &input); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		if input.Payload == nil {
+			input.Payload = map[string]interface{}{}
+		}
+
+		template, err := h.BaseService.FindOneById(c.Context(), utility.String2ObjectID(id))
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		preview, err := notification.PreviewTemplate(&template, input.Payload, input.Format)
+		if err != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, err.Error(), common.StatusBadRequest, err))
+			return nil
+		}
+		h.HandleResponse(c, preview, nil)
+		return nil
+	})
+}
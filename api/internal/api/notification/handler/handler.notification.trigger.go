@@ -16,6 +16,7 @@ import (
 	"meta_commerce/internal/delivery"
 	"meta_commerce/internal/logger"
 	"meta_commerce/internal/notification"
+	"meta_commerce/internal/notifytrigger"
 
 	"github.com/gofiber/fiber/v3"
 	"go.mongodb.org/mongo-driver/bson"
@@ -24,9 +25,10 @@ import (
 
 // NotificationTriggerHandler xử lý việc trigger notification (Hệ thống 2)
 type NotificationTriggerHandler struct {
-	router   *notification.Router
-	template *notification.Template
-	queue    *delivery.Queue
+	router    *notification.Router
+	template  *notification.Template
+	queue     *delivery.Queue
+	throttler *notifytrigger.Throttler
 }
 
 // NewNotificationTriggerHandler tạo mới NotificationTriggerHandler
@@ -46,10 +48,16 @@ func NewNotificationTriggerHandler() (*NotificationTriggerHandler, error) {
 		return nil, fmt.Errorf("failed to create delivery queue: %w", err)
 	}
 
+	throttler, err := notifytrigger.DefaultThrottler()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notification throttler: %w", err)
+	}
+
 	return &NotificationTriggerHandler{
-		router:   router,
-		template: template,
-		queue:    queue,
+		router:    router,
+		template:  template,
+		queue:     queue,
+		throttler: throttler,
 	}, nil
 }
 
@@ -227,10 +235,12 @@ func (h *NotificationTriggerHandler) HandleTriggerNotification(c fiber.Ctx) erro
 					}).Warn("🔔 [NOTIFICATION] Telegram channel không có ChatIDs, bỏ qua")
 					continue
 				}
-			case "webhook":
+			case "webhook", "slack", "discord":
 				if channel.WebhookURL != "" {
 					recipients = []string{channel.WebhookURL}
 				}
+			case "messenger":
+				recipients = channel.PSIDs
 			default:
 				log.WithField("channelType", channel.ChannelType).Warn("🔔 [NOTIFICATION] Channel type không được hỗ trợ, bỏ qua")
 				continue
@@ -260,8 +270,9 @@ func (h *NotificationTriggerHandler) HandleTriggerNotification(c fiber.Ctx) erro
 			priority := notification.GetPriorityFromSeverity(severity)
 			maxRetries := notification.GetMaxRetriesFromSeverity(severity)
 
+			routeItems := make([]*deliverymodels.DeliveryQueueItem, 0, len(recipients))
 			for _, recipient := range recipients {
-				queueItems = append(queueItems, &deliverymodels.DeliveryQueueItem{
+				routeItems = append(routeItems, &deliverymodels.DeliveryQueueItem{
 					ID:                  primitive.NewObjectID(),
 					EventType:           req.EventType,
 					OwnerOrganizationID: route.OrganizationID,
@@ -271,6 +282,7 @@ func (h *NotificationTriggerHandler) HandleTriggerNotification(c fiber.Ctx) erro
 					Recipient:           recipient,
 					Subject:             rendered.Subject,
 					Content:             rendered.Content,
+					ContentFormat:       rendered.Format,
 					CTAs:                ctaJSONs,
 					Payload:             req.Payload,
 					Status:              "pending",
@@ -281,6 +293,7 @@ func (h *NotificationTriggerHandler) HandleTriggerNotification(c fiber.Ctx) erro
 					UpdatedAt:           time.Now().Unix(),
 				})
 			}
+			queueItems = append(queueItems, h.throttler.Apply(c.Context(), route, severity, routeItems)...)
 		}
 
 		if len(renderErrors) > 0 {
@@ -22,7 +22,10 @@ type NotificationChannel struct {
 	// ChatIDs: danh sách đích nhận Telegram. Mỗi phần tử: "chatID" (chat chính) hoặc "chatID:topicID" (topic cụ thể trong forum supergroup).
 	// Ví dụ: ["-123456789"] hoặc ["-123456789:12345"] để gửi vào topic 12345.
 	ChatIDs []string `json:"chatIds,omitempty" bson:"chatIds,omitempty"`
+	// PSIDs: Messenger - page-scoped ID của nhân viên (đã nhắn tin với page của sender)
+	PSIDs []string `json:"psids,omitempty" bson:"psids,omitempty"`
 
+	// WebhookURL: webhook (generic) và incoming webhook của Slack / Discord
 	WebhookURL     string            `json:"webhookUrl,omitempty" bson:"webhookUrl,omitempty"`
 	WebhookHeaders map[string]string `json:"webhookHeaders,omitempty" bson:"webhookHeaders,omitempty"`
 
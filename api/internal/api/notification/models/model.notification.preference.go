@@ -0,0 +1,58 @@
+// Package models - Tùy chọn nhận thông báo của từng user và hộp thư in-app.
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kênh user có thể chọn trong NotificationPreference.
+const (
+	PreferenceChannelInApp    = "in_app"   // Hộp thư trong app (notification_run_inbox) + đẩy realtime qua WebSocket
+	PreferenceChannelEmail    = "email"    // Email của tài khoản user
+	PreferenceChannelTelegram = "telegram" // TelegramChatID trong preference
+)
+
+// ValidPreferenceChannels các kênh hợp lệ của NotificationPreference.
+var ValidPreferenceChannels = []string{PreferenceChannelInApp, PreferenceChannelEmail, PreferenceChannelTelegram}
+
+// NotificationPreference đăng ký / hủy đăng ký thông báo của một user trong một org.
+// Phạm vi: EventType (cụ thể nhất) → Domain → mặc định (cả hai rỗng). Khi resolve, bản ghi cụ thể nhất thắng,
+// nên có thể đăng ký cả domain nhưng hủy riêng một eventType.
+type NotificationPreference struct {
+	ID                  primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	UserID              primitive.ObjectID `json:"userId" bson:"userId" index:"compound:user_org_event_domain_unique"`
+	OwnerOrganizationID primitive.ObjectID `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1,compound:user_org_event_domain_unique"`
+	EventType           string             `json:"eventType" bson:"eventType" index:"compound:user_org_event_domain_unique"` // Rỗng = theo Domain / mặc định
+	Domain              string             `json:"domain" bson:"domain" index:"compound:user_org_event_domain_unique"`       // Rỗng = mặc định (mọi event)
+	Subscribed          bool               `json:"subscribed" bson:"subscribed"`
+	Channels            []string           `json:"channels,omitempty" bson:"channels,omitempty"`             // in_app, email, telegram — rỗng = in_app
+	TelegramChatID      string             `json:"telegramChatId,omitempty" bson:"telegramChatId,omitempty"` // Bắt buộc khi Channels có telegram
+	CreatedAt           int64              `json:"createdAt" bson:"createdAt"`
+	UpdatedAt           int64              `json:"updatedAt" bson:"updatedAt"`
+}
+
+// NotificationInboxItem một thông báo trong hộp thư in-app của user (kênh in_app).
+type NotificationInboxItem struct {
+	ID                  primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	UserID              primitive.ObjectID `json:"userId" bson:"userId" index:"compound:user_org_read"`
+	OwnerOrganizationID primitive.ObjectID `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"compound:user_org_read"`
+	EventType           string             `json:"eventType" bson:"eventType"`
+	Domain              string             `json:"domain,omitempty" bson:"domain,omitempty"`
+	Severity            string             `json:"severity,omitempty" bson:"severity,omitempty"`
+	Subject             string             `json:"subject,omitempty" bson:"subject,omitempty"`
+	Content             string             `json:"content" bson:"content"`
+	CTAs                []InboxCTA         `json:"ctas,omitempty" bson:"ctas,omitempty"`
+	IsRead              bool               `json:"isRead" bson:"isRead" index:"compound:user_org_read"`
+	ReadAt              *int64             `json:"readAt,omitempty" bson:"readAt,omitempty"`
+	ExpiresAt           time.Time          `json:"expiresAt" bson:"expiresAt" index:"single:1,ttl:0"` // Hết hạn lưu → TTL xóa
+	CreatedAt           int64              `json:"createdAt" bson:"createdAt" index:"single:-1"`
+}
+
+// InboxCTA nút hành động đã render của thông báo in-app.
+type InboxCTA struct {
+	Label  string `json:"label" bson:"label"`
+	Action string `json:"action" bson:"action"`
+	Style  string `json:"style,omitempty" bson:"style,omitempty"`
+}
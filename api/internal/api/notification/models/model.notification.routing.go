@@ -16,7 +16,22 @@ type NotificationRoutingRule struct {
 	ChannelTypes        []string             `json:"channelTypes,omitempty" bson:"channelTypes,omitempty"`
 	Severities          []string             `json:"severities,omitempty" bson:"severities,omitempty"`
 	IsActive            bool                 `json:"isActive" bson:"isActive" index:"single:1"`
-	IsSystem            bool                 `json:"-" bson:"isSystem" index:"single:1"`
-	CreatedAt           int64                `json:"createdAt" bson:"createdAt"`
-	UpdatedAt           int64                `json:"updatedAt" bson:"updatedAt"`
+
+	// Chống spam: giờ yên lặng, chống trùng, gom digest (event bị chặn / gom vẫn ghi delivery history)
+	QuietHours         *RoutingQuietHours `json:"quietHours,omitempty" bson:"quietHours,omitempty"`
+	DedupKey           string             `json:"dedupKey,omitempty" bson:"dedupKey,omitempty"`                     // Template key chống trùng, VD: "{{eventType}}:{{campaignId}}"
+	DedupWindowMinutes int                `json:"dedupWindowMinutes,omitempty" bson:"dedupWindowMinutes,omitempty"` // Cùng key trong cửa sổ này → suppressed
+	DigestMinutes      int                `json:"digestMinutes,omitempty" bson:"digestMinutes,omitempty"`           // > 0: gom event, gửi một bản tổng hợp mỗi N phút
+
+	IsSystem  bool  `json:"-" bson:"isSystem" index:"single:1"`
+	CreatedAt int64 `json:"createdAt" bson:"createdAt"`
+	UpdatedAt int64 `json:"updatedAt" bson:"updatedAt"`
+}
+
+// RoutingQuietHours - Khung giờ yên lặng (theo múi giờ tổ chức nhận). Start > End nghĩa là qua đêm (VD: 22:00 → 07:00).
+type RoutingQuietHours struct {
+	Start            string   `json:"start" bson:"start"`                                           // HH:MM
+	End              string   `json:"end" bson:"end"`                                               // HH:MM
+	Timezone         string   `json:"timezone,omitempty" bson:"timezone,omitempty"`                 // Rỗng → config "timezone" của tổ chức, mặc định Asia/Ho_Chi_Minh
+	BypassSeverities []string `json:"bypassSeverities,omitempty" bson:"bypassSeverities,omitempty"` // Severity vẫn gửi trong giờ yên lặng; rỗng → [critical]
 }
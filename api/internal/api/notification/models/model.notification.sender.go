@@ -2,6 +2,8 @@
 package models
 
 import (
+	"meta_commerce/internal/keyring"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -15,15 +17,33 @@ type NotificationChannelSender struct {
 	IsActive            bool                `json:"isActive" bson:"isActive" index:"single:1"`
 	IsSystem            bool                `json:"-" bson:"isSystem" index:"single:1"`
 
-	SMTPHost     string `json:"smtpHost,omitempty" bson:"smtpHost,omitempty"`
-	SMTPPort     int    `json:"smtpPort,omitempty" bson:"smtpPort,omitempty"`
-	SMTPUsername string `json:"smtpUsername,omitempty" bson:"smtpUsername,omitempty"`
-	SMTPPassword string `json:"smtpPassword,omitempty" bson:"smtpPassword,omitempty"`
-	FromEmail    string `json:"fromEmail,omitempty" bson:"fromEmail,omitempty"`
-	FromName     string `json:"fromName,omitempty" bson:"fromName,omitempty"`
+	SMTPHost     string         `json:"smtpHost,omitempty" bson:"smtpHost,omitempty"`
+	SMTPPort     int            `json:"smtpPort,omitempty" bson:"smtpPort,omitempty"`
+	SMTPUsername string         `json:"smtpUsername,omitempty" bson:"smtpUsername,omitempty"`
+	SMTPPassword keyring.Secret `json:"smtpPassword,omitempty" bson:"smtpPassword,omitempty"`
+	FromEmail    string         `json:"fromEmail,omitempty" bson:"fromEmail,omitempty"`
+	FromName     string         `json:"fromName,omitempty" bson:"fromName,omitempty"`
+
+	BotToken    keyring.Secret `json:"botToken,omitempty" bson:"botToken,omitempty"`
+	BotUsername string         `json:"botUsername,omitempty" bson:"botUsername,omitempty"`
+
+	// Webhook: ký HMAC (X-Signature), header/auth tùy chỉnh, payload template (text/template, trống = body mặc định)
+	WebhookSecret          keyring.Secret    `json:"webhookSecret,omitempty" bson:"webhookSecret,omitempty"`
+	WebhookHeaders         map[string]string `json:"webhookHeaders,omitempty" bson:"webhookHeaders,omitempty"`
+	WebhookAuthType        string            `json:"webhookAuthType,omitempty" bson:"webhookAuthType,omitempty"` // "", bearer, basic
+	WebhookAuthToken       keyring.Secret    `json:"webhookAuthToken,omitempty" bson:"webhookAuthToken,omitempty"`
+	WebhookAuthUsername    string            `json:"webhookAuthUsername,omitempty" bson:"webhookAuthUsername,omitempty"`
+	WebhookAuthPassword    keyring.Secret    `json:"webhookAuthPassword,omitempty" bson:"webhookAuthPassword,omitempty"`
+	WebhookPayloadTemplate string            `json:"webhookPayloadTemplate,omitempty" bson:"webhookPayloadTemplate,omitempty"`
+	WebhookTimeoutSeconds  int               `json:"webhookTimeoutSeconds,omitempty" bson:"webhookTimeoutSeconds,omitempty"` // Mặc định 10s, tối đa 60s
+
+	// Slack / Discord: tên và avatar hiển thị của tin nhắn (rỗng = mặc định của webhook)
+	ChatUsername string `json:"chatUsername,omitempty" bson:"chatUsername,omitempty"`
+	ChatIconURL  string `json:"chatIconUrl,omitempty" bson:"chatIconUrl,omitempty"`
 
-	BotToken    string `json:"botToken,omitempty" bson:"botToken,omitempty"`
-	BotUsername string `json:"botUsername,omitempty" bson:"botUsername,omitempty"`
+	// Messenger: page gửi (fb_pages.pageId, token lấy từ fb_pages lúc gửi); MessengerTag (vd ACCOUNT_UPDATE) cho phép gửi ngoài cửa sổ 24h
+	FacebookPageID string `json:"facebookPageId,omitempty" bson:"facebookPageId,omitempty"`
+	MessengerTag   string `json:"messengerTag,omitempty" bson:"messengerTag,omitempty"`
 
 	CreatedAt int64 `json:"createdAt" bson:"createdAt"`
 	UpdatedAt int64 `json:"updatedAt" bson:"updatedAt"`
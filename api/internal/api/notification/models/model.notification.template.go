@@ -14,6 +14,7 @@ type NotificationTemplate struct {
 	Description         string              `json:"description,omitempty" bson:"description,omitempty"`
 	Subject             string              `json:"subject,omitempty" bson:"subject,omitempty"`
 	Content             string              `json:"content" bson:"content"`
+	Format              string              `json:"format,omitempty" bson:"format,omitempty"` // Escape output: text | html | markdown | json (rỗng → mặc định theo channelType)
 	Variables           []string            `json:"variables" bson:"variables"`
 	CTACodes            []string            `json:"ctaCodes,omitempty" bson:"ctaCodes,omitempty"`
 	CTAs                []NotificationCTA   `json:"ctas,omitempty" bson:"ctas,omitempty"`
@@ -0,0 +1,54 @@
+// Package models - Dedup mark và digest buffer của routing rule (chống spam thông báo).
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NotificationDedupMark đánh dấu một dedup key đã gửi trong cửa sổ chống trùng của routing rule.
+// Unique (ruleId, ownerOrganizationId, channelId, key): insert trùng khi chưa hết hạn = event trùng → suppressed.
+type NotificationDedupMark struct {
+	ID                  primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	RuleID              primitive.ObjectID `json:"ruleId" bson:"ruleId" index:"compound:rule_org_channel_key_unique"`
+	OwnerOrganizationID primitive.ObjectID `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"compound:rule_org_channel_key_unique"` // Org nhận thông báo
+	ChannelID           primitive.ObjectID `json:"channelId" bson:"channelId" index:"compound:rule_org_channel_key_unique"`
+	Key                 string             `json:"key" bson:"key" index:"compound:rule_org_channel_key_unique"` // DedupKey đã render
+	EventType           string             `json:"eventType" bson:"eventType"`
+	ExpiresAt           time.Time          `json:"expiresAt" bson:"expiresAt" index:"single:1,ttl:0"` // Hết cửa sổ chống trùng → TTL xóa
+	CreatedAt           int64              `json:"createdAt" bson:"createdAt"`
+}
+
+// Trạng thái NotificationDigestEntry.
+const (
+	DigestEntryStatusPending = "pending" // Chờ tới FlushAt
+	DigestEntryStatusFlushed = "flushed" // Đã gom vào bản tổng hợp
+)
+
+// NotificationDigestEntry một thông báo đã render, chờ gom vào bản tổng hợp của routing rule.
+// Các entry cùng (rule, org, channel, recipient, flushAt) được gửi thành một queue item.
+type NotificationDigestEntry struct {
+	ID                  primitive.ObjectID     `json:"id,omitempty" bson:"_id,omitempty"`
+	RuleID              primitive.ObjectID     `json:"ruleId" bson:"ruleId" index:"single:1"`
+	OwnerOrganizationID primitive.ObjectID     `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1"` // Org nhận thông báo
+	ChannelID           primitive.ObjectID     `json:"channelId" bson:"channelId"`
+	ChannelType         string                 `json:"channelType" bson:"channelType"`
+	Recipient           string                 `json:"recipient" bson:"recipient"`
+	SenderID            primitive.ObjectID     `json:"senderId" bson:"senderId"`
+	SenderConfig        string                 `json:"senderConfig,omitempty" bson:"senderConfig,omitempty"` // Sender config đã encrypt (giữ nguyên từ queue item)
+	EventType           string                 `json:"eventType" bson:"eventType"`
+	Severity            string                 `json:"severity,omitempty" bson:"severity,omitempty"`
+	Subject             string                 `json:"subject,omitempty" bson:"subject,omitempty"`
+	Content             string                 `json:"content" bson:"content"` // Nội dung đã render của event
+	Payload             map[string]interface{} `json:"payload,omitempty" bson:"payload,omitempty"`
+	Priority            int                    `json:"priority" bson:"priority"`
+	MaxRetries          int                    `json:"maxRetries" bson:"maxRetries"`
+	HistoryID           primitive.ObjectID     `json:"historyId" bson:"historyId"` // Delivery history status=digested của event này
+
+	Status            string              `json:"status" bson:"status" index:"single:1,compound:status_flushAt"`
+	FlushAt           int64               `json:"flushAt" bson:"flushAt" index:"compound:status_flushAt"` // Unix giây — cuối cửa sổ digest (đã dời qua giờ yên lặng)
+	DigestQueueItemID *primitive.ObjectID `json:"digestQueueItemId,omitempty" bson:"digestQueueItemId,omitempty"`
+	FlushedAt         *int64              `json:"flushedAt,omitempty" bson:"flushedAt,omitempty"`
+	CreatedAt         int64               `json:"createdAt" bson:"createdAt"`
+}
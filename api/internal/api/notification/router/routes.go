@@ -1,4 +1,4 @@
-// Package router đăng ký các route thuộc domain Notification: Sender, Channel, Template, Routing, History, Trigger, Tracking.
+// Package router đăng ký các route thuộc domain Notification: Sender, Channel, Template, Routing, History, Dead-letter, Trigger, Preference, Inbox, Tracking.
 package router
 
 import (
@@ -30,7 +30,8 @@ func Register(v1 fiber.Router, r *apirouter.Router) error {
 	if err != nil {
 		return fmt.Errorf("create notification template handler: %w", err)
 	}
-	r.RegisterCRUDRoutes(v1, "/notification/template", templateHandler, apirouter.ReadWriteConfig, "NotificationTemplate")
+	r.RegisterCRUDRoutes(v1, "/notification/template", templateHandler, apirouter.GatedWriteConfig, "NotificationTemplate")
+	apirouter.RegisterRouteWithMiddleware(v1, "/notification/template", "POST", "/:id/preview", []fiber.Handler{middleware.AuthMiddleware("NotificationTemplate.Read"), middleware.OrganizationContextMiddleware()}, templateHandler.HandlePreview)
 
 	routingHandler, err := notifhdl.NewNotificationRoutingHandler()
 	if err != nil {
@@ -44,6 +45,34 @@ func Register(v1 fiber.Router, r *apirouter.Router) error {
 	}
 	r.RegisterCRUDRoutes(v1, "/notification/history", historyHandler, apirouter.ReadOnlyConfig, "DeliveryHistory")
 
+	// Dead-letter: replay đăng ký trước CRUD để chạy với DeliveryDeadLetter.Update (middleware group theo thứ tự đăng ký)
+	deadLetterHandler, err := deliveryhdl.NewDeliveryDeadLetterHandler()
+	if err != nil {
+		return fmt.Errorf("create delivery dead-letter handler: %w", err)
+	}
+	apirouter.RegisterRouteWithMiddleware(v1, "/notification/dead-letter", "POST", "/replay", []fiber.Handler{middleware.AuthMiddleware("DeliveryDeadLetter.Update"), middleware.OrganizationContextMiddleware()}, deadLetterHandler.HandleReplay)
+	r.RegisterCRUDRoutes(v1, "/notification/dead-letter", deadLetterHandler, apirouter.DeadLetterConfig, "DeliveryDeadLetter")
+
+	// Preference + hộp thư in-app: chỉ cần đăng nhập, dữ liệu luôn lọc theo user hiện tại trong org đang chọn.
+	// Đăng ký trước group /notification (Notification.Trigger) vì middleware của group đó áp dụng cho mọi path con
+	userScopedMiddleware := []fiber.Handler{middleware.AuthMiddleware(""), middleware.OrganizationContextMiddleware()}
+	preferenceHandler, err := notifhdl.NewNotificationPreferenceHandler()
+	if err != nil {
+		return fmt.Errorf("create notification preference handler: %w", err)
+	}
+	apirouter.RegisterRouteWithMiddleware(v1, "/notification/preference", "GET", "", userScopedMiddleware, preferenceHandler.HandleList)
+	apirouter.RegisterRouteWithMiddleware(v1, "/notification/preference", "PUT", "", userScopedMiddleware, preferenceHandler.HandleSave)
+	apirouter.RegisterRouteWithMiddleware(v1, "/notification/preference", "DELETE", "/:id", userScopedMiddleware, preferenceHandler.HandleDelete)
+
+	inboxHandler, err := notifhdl.NewNotificationInboxHandler()
+	if err != nil {
+		return fmt.Errorf("create notification inbox handler: %w", err)
+	}
+	apirouter.RegisterRouteWithMiddleware(v1, "/notification/inbox", "GET", "", userScopedMiddleware, inboxHandler.HandleList)
+	apirouter.RegisterRouteWithMiddleware(v1, "/notification/inbox", "GET", "/unread-count", userScopedMiddleware, inboxHandler.HandleUnreadCount)
+	apirouter.RegisterRouteWithMiddleware(v1, "/notification/inbox", "POST", "/read", userScopedMiddleware, inboxHandler.HandleMarkRead)
+	apirouter.RegisterRouteWithMiddleware(v1, "/notification/inbox", "GET", "/live", userScopedMiddleware, inboxHandler.HandleLiveWS)
+
 	triggerHandler, err := notifhdl.NewNotificationTriggerHandler()
 	if err != nil {
 		return fmt.Errorf("create notification trigger handler: %w", err)
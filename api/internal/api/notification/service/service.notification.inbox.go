@@ -0,0 +1,102 @@
+package notifsvc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	basemodels "meta_commerce/internal/api/base/models"
+	basesvc "meta_commerce/internal/api/base/service"
+	notifmodels "meta_commerce/internal/api/notification/models"
+	"meta_commerce/internal/common"
+	"meta_commerce/internal/global"
+	"meta_commerce/internal/notification/inboxlive"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// inboxRetention thời gian giữ thông báo in-app trước khi TTL xóa.
+const inboxRetention = 90 * 24 * time.Hour
+
+// NotificationInboxService là cấu trúc chứa các phương thức liên quan đến hộp thư in-app của user
+type NotificationInboxService struct {
+	*basesvc.BaseServiceMongoImpl[notifmodels.NotificationInboxItem]
+}
+
+// NewNotificationInboxService tạo mới NotificationInboxService
+func NewNotificationInboxService() (*NotificationInboxService, error) {
+	collection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.NotificationInbox)
+	if !exist {
+		return nil, fmt.Errorf("failed to get notification_inbox collection: %v", common.ErrNotFound)
+	}
+
+	return &NotificationInboxService{
+		BaseServiceMongoImpl: basesvc.NewBaseServiceMongo[notifmodels.NotificationInboxItem](collection),
+	}, nil
+}
+
+// Deliver lưu thông báo vào hộp thư của user và đẩy realtime tới các WebSocket đang mở.
+func (s *NotificationInboxService) Deliver(ctx context.Context, item notifmodels.NotificationInboxItem) (notifmodels.NotificationInboxItem, error) {
+	now := time.Now()
+	item.IsRead = false
+	item.ReadAt = nil
+	item.CreatedAt = now.Unix()
+	item.ExpiresAt = now.Add(inboxRetention)
+	saved, err := s.InsertOne(ctx, item)
+	if err != nil {
+		return saved, err
+	}
+
+	unread, err := s.UnreadCount(ctx, saved.OwnerOrganizationID, saved.UserID)
+	if err != nil {
+		unread = -1 // Client tự gọi unread-count
+	}
+	inboxlive.Publish(saved.OwnerOrganizationID, saved.UserID, inboxlive.InboxLiveEvent{
+		Type:        inboxlive.EventNotification,
+		Item:        &saved,
+		UnreadCount: unread,
+	})
+	return saved, nil
+}
+
+// ListForUser trả hộp thư của user trong org, mới nhất trước. unreadOnly = chỉ thông báo chưa đọc.
+func (s *NotificationInboxService) ListForUser(ctx context.Context, orgID, userID primitive.ObjectID, unreadOnly bool, page, limit int64) (*basemodels.PaginateResult[notifmodels.NotificationInboxItem], error) {
+	filter := bson.M{"ownerOrganizationId": orgID, "userId": userID}
+	if unreadOnly {
+		filter["isRead"] = false
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}})
+	return s.FindWithPagination(ctx, filter, page, limit, opts)
+}
+
+// UnreadCount đếm thông báo chưa đọc của user trong org.
+func (s *NotificationInboxService) UnreadCount(ctx context.Context, orgID, userID primitive.ObjectID) (int64, error) {
+	return s.CountDocuments(ctx, bson.M{"ownerOrganizationId": orgID, "userId": userID, "isRead": false})
+}
+
+// MarkRead đánh dấu đã đọc các thông báo ids (all = toàn bộ hộp thư) của user.
+// Trả số thông báo vừa chuyển sang đã đọc và số chưa đọc còn lại; đẩy unread_count cho các tab khác.
+func (s *NotificationInboxService) MarkRead(ctx context.Context, orgID, userID primitive.ObjectID, ids []primitive.ObjectID, all bool) (int64, int64, error) {
+	filter := bson.M{"ownerOrganizationId": orgID, "userId": userID, "isRead": false}
+	if !all {
+		if len(ids) == 0 {
+			return 0, 0, common.NewError(common.ErrCodeValidationInput, "Cần ids hoặc all=true", common.StatusBadRequest, nil)
+		}
+		filter["_id"] = bson.M{"$in": ids}
+	}
+	res, err := s.Collection().UpdateMany(ctx, filter, bson.M{"$set": bson.M{"isRead": true, "readAt": time.Now().Unix()}})
+	if err != nil {
+		return 0, 0, common.ConvertMongoError(err)
+	}
+
+	unread, err := s.UnreadCount(ctx, orgID, userID)
+	if err != nil {
+		return res.ModifiedCount, 0, err
+	}
+	if res.ModifiedCount > 0 {
+		inboxlive.Publish(orgID, userID, inboxlive.InboxLiveEvent{Type: inboxlive.EventUnreadCount, UnreadCount: unread})
+	}
+	return res.ModifiedCount, unread, nil
+}
@@ -0,0 +1,101 @@
+package notifsvc
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	basesvc "meta_commerce/internal/api/base/service"
+	notifmodels "meta_commerce/internal/api/notification/models"
+	"meta_commerce/internal/common"
+	"meta_commerce/internal/global"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NotificationPreferenceService là cấu trúc chứa các phương thức liên quan đến tùy chọn nhận thông báo của user
+type NotificationPreferenceService struct {
+	*basesvc.BaseServiceMongoImpl[notifmodels.NotificationPreference]
+}
+
+// NewNotificationPreferenceService tạo mới NotificationPreferenceService
+func NewNotificationPreferenceService() (*NotificationPreferenceService, error) {
+	collection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.NotificationPreferences)
+	if !exist {
+		return nil, fmt.Errorf("failed to get notification_preferences collection: %v", common.ErrNotFound)
+	}
+
+	return &NotificationPreferenceService{
+		BaseServiceMongoImpl: basesvc.NewBaseServiceMongo[notifmodels.NotificationPreference](collection),
+	}, nil
+}
+
+// ValidatePreference kiểm tra kênh hợp lệ và telegramChatId khi chọn telegram.
+func ValidatePreference(pref *notifmodels.NotificationPreference) error {
+	for _, ch := range pref.Channels {
+		if !slices.Contains(notifmodels.ValidPreferenceChannels, ch) {
+			return common.NewError(common.ErrCodeValidationFormat, fmt.Sprintf("Kênh '%s' không hợp lệ (chỉ nhận %v)", ch, notifmodels.ValidPreferenceChannels), common.StatusBadRequest, nil)
+		}
+	}
+	if slices.Contains(pref.Channels, notifmodels.PreferenceChannelTelegram) && pref.TelegramChatID == "" {
+		return common.NewError(common.ErrCodeValidationFormat, "Chọn kênh telegram phải có telegramChatId", common.StatusBadRequest, nil)
+	}
+	return nil
+}
+
+// ListForUser trả các preference của user trong org.
+func (s *NotificationPreferenceService) ListForUser(ctx context.Context, orgID, userID primitive.ObjectID) ([]notifmodels.NotificationPreference, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "domain", Value: 1}, {Key: "eventType", Value: 1}})
+	return s.Find(ctx, bson.M{"ownerOrganizationId": orgID, "userId": userID}, opts)
+}
+
+// Save upsert preference theo (user, org, eventType, domain).
+func (s *NotificationPreferenceService) Save(ctx context.Context, pref notifmodels.NotificationPreference) (notifmodels.NotificationPreference, error) {
+	if err := ValidatePreference(&pref); err != nil {
+		return pref, err
+	}
+	now := time.Now().Unix()
+	filter := bson.M{
+		"userId":              pref.UserID,
+		"ownerOrganizationId": pref.OwnerOrganizationID,
+		"eventType":           pref.EventType,
+		"domain":              pref.Domain,
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"subscribed":     pref.Subscribed,
+			"channels":       pref.Channels,
+			"telegramChatId": pref.TelegramChatID,
+			"updatedAt":      now,
+		},
+		"$setOnInsert": bson.M{"createdAt": now},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var saved notifmodels.NotificationPreference
+	if err := s.Collection().FindOneAndUpdate(ctx, filter, update, opts).Decode(&saved); err != nil {
+		return pref, common.ConvertMongoError(err)
+	}
+	return saved, nil
+}
+
+// DeleteForUser xóa preference theo id, chỉ khi thuộc user + org (user không xóa được preference của người khác).
+func (s *NotificationPreferenceService) DeleteForUser(ctx context.Context, orgID, userID, id primitive.ObjectID) error {
+	return s.DeleteOne(ctx, bson.M{"_id": id, "ownerOrganizationId": orgID, "userId": userID})
+}
+
+// FindCandidates trả mọi preference của org có thể áp dụng cho event: đúng eventType, đúng domain, hoặc mặc định.
+// Chọn bản ghi thắng cho từng user: notification.ResolvePreferences.
+func (s *NotificationPreferenceService) FindCandidates(ctx context.Context, orgID primitive.ObjectID, eventType, domain string) ([]notifmodels.NotificationPreference, error) {
+	filter := bson.M{
+		"ownerOrganizationId": orgID,
+		"$or": []bson.M{
+			{"eventType": eventType},
+			{"eventType": "", "domain": domain},
+			{"eventType": "", "domain": ""},
+		},
+	}
+	return s.Find(ctx, filter, nil)
+}
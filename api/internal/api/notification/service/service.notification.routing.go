@@ -3,6 +3,7 @@ package notifsvc
 import (
 	"context"
 	"fmt"
+	"time"
 
 	authmodels "meta_commerce/internal/api/auth/models"
 	authsvc "meta_commerce/internal/api/auth/service"
@@ -185,5 +186,32 @@ func (s *NotificationRoutingService) InsertOne(ctx context.Context, data notifmo
 	if err := s.ValidateUniqueness(ctx, data); err != nil {
 		return data, err
 	}
+	if err := ValidateRoutingThrottle(data); err != nil {
+		return data, err
+	}
 	return s.BaseServiceMongoImpl.InsertOne(ctx, data)
 }
+
+// ValidateRoutingThrottle kiểm tra cấu hình chống spam của rule: quiet hours HH:MM + timezone hợp lệ,
+// dedupKey đi kèm dedupWindowMinutes, số phút không âm.
+func ValidateRoutingThrottle(rule notifmodels.NotificationRoutingRule) error {
+	if rule.DedupWindowMinutes < 0 || rule.DigestMinutes < 0 {
+		return common.NewError(common.ErrCodeValidationFormat, "dedupWindowMinutes / digestMinutes không được âm", common.StatusBadRequest, nil)
+	}
+	if (rule.DedupKey == "") != (rule.DedupWindowMinutes == 0) {
+		return common.NewError(common.ErrCodeValidationFormat, "dedupKey và dedupWindowMinutes phải khai báo cùng nhau", common.StatusBadRequest, nil)
+	}
+	if qh := rule.QuietHours; qh != nil {
+		for _, v := range []string{qh.Start, qh.End} {
+			if _, err := time.Parse("15:04", v); err != nil {
+				return common.NewError(common.ErrCodeValidationFormat, fmt.Sprintf("quietHours: giờ '%s' không đúng định dạng HH:MM", v), common.StatusBadRequest, err)
+			}
+		}
+		if qh.Timezone != "" {
+			if _, err := time.LoadLocation(qh.Timezone); err != nil {
+				return common.NewError(common.ErrCodeValidationFormat, fmt.Sprintf("quietHours: timezone '%s' không hợp lệ", qh.Timezone), common.StatusBadRequest, err)
+			}
+		}
+	}
+	return nil
+}
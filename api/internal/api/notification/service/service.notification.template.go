@@ -1,12 +1,18 @@
 package notifsvc
 
 import (
+	"context"
 	"fmt"
 
 	notifmodels "meta_commerce/internal/api/notification/models"
 	basesvc "meta_commerce/internal/api/base/service"
 	"meta_commerce/internal/common"
 	"meta_commerce/internal/global"
+	"meta_commerce/internal/notification/tmpl"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // NotificationTemplateService là cấu trúc chứa các phương thức liên quan đến Notification Template
@@ -25,3 +31,94 @@ func NewNotificationTemplateService() (*NotificationTemplateService, error) {
 		BaseServiceMongoImpl: basesvc.NewBaseServiceMongo[notifmodels.NotificationTemplate](collection),
 	}, nil
 }
+
+// InsertOne override — từ chối template không parse được.
+func (s *NotificationTemplateService) InsertOne(ctx context.Context, data notifmodels.NotificationTemplate) (notifmodels.NotificationTemplate, error) {
+	if err := ValidateTemplateSyntax(data.Subject, data.Content, data.CTAs); err != nil {
+		return data, err
+	}
+	return s.BaseServiceMongoImpl.InsertOne(ctx, data)
+}
+
+// InsertMany override — như InsertOne, kiểm tra từng template.
+func (s *NotificationTemplateService) InsertMany(ctx context.Context, data []notifmodels.NotificationTemplate) ([]notifmodels.NotificationTemplate, error) {
+	for i := range data {
+		if err := ValidateTemplateSyntax(data[i].Subject, data[i].Content, data[i].CTAs); err != nil {
+			return nil, err
+		}
+	}
+	return s.BaseServiceMongoImpl.InsertMany(ctx, data)
+}
+
+// UpdateOne override — kiểm tra subject/content/ctas mới (nếu có).
+func (s *NotificationTemplateService) UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts *options.UpdateOptions) (notifmodels.NotificationTemplate, error) {
+	if err := validateTemplateUpdate(update); err != nil {
+		var zero notifmodels.NotificationTemplate
+		return zero, err
+	}
+	return s.BaseServiceMongoImpl.UpdateOne(ctx, filter, update, opts)
+}
+
+// UpdateById override — như UpdateOne.
+func (s *NotificationTemplateService) UpdateById(ctx context.Context, id primitive.ObjectID, data interface{}) (notifmodels.NotificationTemplate, error) {
+	if err := validateTemplateUpdate(data); err != nil {
+		var zero notifmodels.NotificationTemplate
+		return zero, err
+	}
+	return s.BaseServiceMongoImpl.UpdateById(ctx, id, data)
+}
+
+// Upsert override — như UpdateOne (seed template hệ thống cũng đi qua đây).
+func (s *NotificationTemplateService) Upsert(ctx context.Context, filter interface{}, data interface{}) (notifmodels.NotificationTemplate, error) {
+	if err := validateTemplateUpdate(data); err != nil {
+		var zero notifmodels.NotificationTemplate
+		return zero, err
+	}
+	return s.BaseServiceMongoImpl.Upsert(ctx, filter, data)
+}
+
+// ValidateTemplateSyntax parse subject, content và action của CTA cũ bằng ngôn ngữ template (tmpl).
+func ValidateTemplateSyntax(subject, content string, ctas []notifmodels.NotificationCTA) error {
+	fields := []struct{ name, src string }{{"subject", subject}, {"content", content}}
+	for i, c := range ctas {
+		fields = append(fields, struct{ name, src string }{fmt.Sprintf("ctas[%d].action", i), c.Action})
+	}
+	for _, f := range fields {
+		if _, err := tmpl.Parse(f.src); err != nil {
+			return common.NewError(common.ErrCodeValidationFormat, fmt.Sprintf("%s: %v", f.name, err), common.StatusBadRequest, err)
+		}
+	}
+	return nil
+}
+
+func validateTemplateUpdate(update interface{}) error {
+	updateData, err := basesvc.ToUpdateData(update)
+	if err != nil || updateData.Set == nil {
+		return nil
+	}
+	subject, _ := updateData.Set["subject"].(string)
+	content, _ := updateData.Set["content"].(string)
+	return ValidateTemplateSyntax(subject, content, templateCTAsFromSet(updateData.Set["ctas"]))
+}
+
+// templateCTAsFromSet đọc ctas trong $set ở mọi dạng: []NotificationCTA, hoặc primitive.A / []interface{}
+// của bson.M / bson.D / map sau utility.ToMap — chuẩn hoá qua bson Marshal/Unmarshal.
+func templateCTAsFromSet(v interface{}) []notifmodels.NotificationCTA {
+	if v == nil {
+		return nil
+	}
+	if ctas, ok := v.([]notifmodels.NotificationCTA); ok {
+		return ctas
+	}
+	raw, err := bson.Marshal(bson.M{"ctas": v})
+	if err != nil {
+		return nil
+	}
+	var out struct {
+		CTAs []notifmodels.NotificationCTA `bson:"ctas"`
+	}
+	if err := bson.Unmarshal(raw, &out); err != nil {
+		return nil
+	}
+	return out.CTAs
+}
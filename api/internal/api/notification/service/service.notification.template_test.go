@@ -0,0 +1,32 @@
+package notifsvc
+
+import (
+	"testing"
+
+	notifmodels "meta_commerce/internal/api/notification/models"
+	"meta_commerce/internal/utility"
+)
+
+// Update dạng struct đi qua ToUpdateData → utility.ToMap: ctas thành primitive.A các document, vẫn phải được kiểm tra cú pháp.
+func TestValidateTemplateUpdate_CTAsThroughToMap(t *testing.T) {
+	bad := notifmodels.NotificationTemplate{
+		Content: "Xin chào {{customer.name}}",
+		CTAs:    []notifmodels.NotificationCTA{{Label: "Xem", Action: "/orders/{{#if orderId}"}},
+	}
+	m, err := utility.ToMap(bad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templateCTAsFromSet(m["ctas"])) != 1 {
+		t.Fatalf("không đọc được ctas từ ToMap: %T", m["ctas"])
+	}
+	if err := validateTemplateUpdate(bad); err == nil {
+		t.Fatal("action sai cú pháp phải bị từ chối")
+	}
+
+	good := bad
+	good.CTAs = []notifmodels.NotificationCTA{{Label: "Xem", Action: "/orders/{{orderId}}"}}
+	if err := validateTemplateUpdate(good); err != nil {
+		t.Fatalf("template hợp lệ: %v", err)
+	}
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"meta_commerce/internal/keyring"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -10,7 +12,7 @@ type AccessToken struct {
 	Name          string               `json:"name" bson:"name" index:"unique"`    // Tên của access token
 	Describe      string               `json:"describe" bson:"describe"`           // Mô tả access token
 	System        string               `json:"system" bson:"system"`               // Hệ thống của access token
-	Value         string               `json:"value" bson:"value"`                 // Giá trị của access token
+	Value         keyring.Secret       `json:"value" bson:"value"`                 // Giá trị của access token
 	AssignedUsers []primitive.ObjectID `json:"assignedUsers" bson:"assignedUsers"` // Danh sách người dùng được gán access token
 	Status        byte                 `json:"status" bson:"status"`               // Trạng thái của access token (0 = active, 1 = inactive)
 
@@ -11,6 +11,7 @@ import (
 	"meta_commerce/internal/common"
 	"meta_commerce/internal/global"
 	"meta_commerce/internal/utility"
+	"meta_commerce/internal/keyring"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -68,7 +69,7 @@ func (s *AccessTokenService) Create(ctx context.Context, input *pcdto.AccessToke
 		Name:          input.Name,
 		Describe:      input.Describe,
 		System:        input.System,
-		Value:         input.Value,
+		Value:         keyring.Secret(input.Value),
 		AssignedUsers: assignedUsers,
 		Status:        0,
 		CreatedAt:     time.Now().Unix(),
@@ -109,7 +110,7 @@ func (s *AccessTokenService) Update(ctx context.Context, id primitive.ObjectID,
 		set["system"] = input.System
 	}
 	if input.Value != "" {
-		set["value"] = input.Value
+		set["value"] = keyring.Secret(input.Value)
 	}
 	if len(input.AssignedUsers) > 0 {
 		assignedUsers := make([]primitive.ObjectID, 0)
@@ -154,6 +154,60 @@ var (
 		Upsert: false, UpsMany: false, Exists: true,
 	}
 
+	// CrmSegmentConfig cho customer_core_segments: tạo, đọc, sửa/xóa theo id. Bật/tắt, refresh, thành viên là route riêng.
+	CrmSegmentConfig = CRUDConfig{
+		InsOne: true, InsMany: false,
+		Find: true, FindOne: true, FindById: true,
+		FindIds: true, Paginate: true,
+		UpdOne: false, UpdMany: false, UpdById: true,
+		FindUpd: false,
+		DelOne: false, DelMany: false, DelById: true,
+		FindDel: false,
+		Count: true, Distinct: true,
+		Upsert: false, UpsMany: false, Exists: true,
+	}
+
+	// CrmCustomerAttributeConfig cho customer_core_field_definitions, customer_core_tags: tạo, đọc, sửa/xóa theo id.
+	// Ghi giá trị trường tùy chỉnh và gắn / gỡ tag trên khách là route riêng.
+	CrmCustomerAttributeConfig = CRUDConfig{
+		InsOne: true, InsMany: false,
+		Find: true, FindOne: true, FindById: true,
+		FindIds: true, Paginate: true,
+		UpdOne: false, UpdMany: false, UpdById: true,
+		FindUpd: false,
+		DelOne: false, DelMany: false, DelById: true,
+		FindDel: false,
+		Count: true, Distinct: true,
+		Upsert: false, UpsMany: false, Exists: true,
+	}
+
+	// DeadLetterConfig cho delivery dead-letter: đọc + purge (delete-by-id, delete-many theo filter). Replay là route riêng.
+	DeadLetterConfig = CRUDConfig{
+		InsOne: false, InsMany: false,
+		Find: true, FindOne: true, FindById: true,
+		FindIds: true, Paginate: true,
+		UpdOne: false, UpdMany: false, UpdById: false,
+		FindUpd: false,
+		DelOne: false, DelMany: true, DelById: true,
+		FindDel: false,
+		Count: true, Distinct: true,
+		Upsert: false, UpsMany: false, Exists: true,
+	}
+
+	// GatedWriteConfig cho collection có kiểm tra khi lưu ở service (LogicScript: publish phải pass test case; NotificationTemplate: kiểm tra cú pháp template):
+	// chỉ ghi qua insert-one, update-one, update-by-id, upsert-one — ghi hàng loạt / find-one-and-update không đi qua kiểm tra nên tắt.
+	GatedWriteConfig = CRUDConfig{
+		InsOne: true, InsMany: false,
+		Find: true, FindOne: true, FindById: true,
+		FindIds: true, Paginate: true,
+		UpdOne: true, UpdMany: false, UpdById: true,
+		FindUpd: false,
+		DelOne: true, DelMany: true, DelById: true,
+		FindDel: true,
+		Count: true, Distinct: true,
+		Upsert: true, UpsMany: false, Exists: true,
+	}
+
 	// OrgConfigItemConfig cho Organization Config Items (1 document per key): find-one, find, upsert-one, delete-one (+ resolved).
 	OrgConfigItemConfig = CRUDConfig{
 		InsOne: false, InsMany: false,
@@ -295,7 +349,7 @@ func (r *Router) RegisterCRUDRoutes(router fiber.Router, prefix string, h CRUDHa
 	// Other operations
 	if config.Count {
 		// Count chá»‰ cáº§n Ä‘Äƒng nháº­p, khÃ´ng cáº§n permission cá»¥ thá»ƒ
-		authOnlyMiddleware := middleware.AuthMiddleware("")
+		authOnlyMiddleware := middleware.AuthOnlyMiddleware(permissionPrefix + ".Read")
 		RegisterRouteWithMiddleware(router, prefix, "GET", "/count", []fiber.Handler{authOnlyMiddleware}, h.CountDocuments)
 	}
 	if config.Distinct {
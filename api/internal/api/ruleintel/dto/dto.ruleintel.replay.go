@@ -0,0 +1,29 @@
+package dto
+
+// ReplayRuleRequest request dry-run / replay rule trên trace lịch sử.
+type ReplayRuleRequest struct {
+	Candidate ReplayCandidateDTO `json:"candidate"`
+	Selection ReplaySelectionDTO `json:"selection"`
+}
+
+// ReplayCandidateDTO logic / param ứng viên. Bỏ trống = dùng version đã ghi trong từng trace.
+type ReplayCandidateDTO struct {
+	LogicID        string                 `json:"logic_id,omitempty"`
+	LogicVersion   int                    `json:"logic_version,omitempty"`
+	Script         string                 `json:"script,omitempty"` // Script chưa lưu — ưu tiên hơn logic_id
+	ParamSetID     string                 `json:"param_set_id,omitempty"`
+	ParamVersion   int                    `json:"param_version,omitempty"`
+	ParamsOverride map[string]interface{} `json:"params_override,omitempty"`
+}
+
+// ReplaySelectionDTO tiêu chí chọn trace: trace_ids, rule_id/rule_code, cửa sổ thời gian (ms), entity.
+type ReplaySelectionDTO struct {
+	TraceIDs       []string `json:"trace_ids,omitempty"`
+	RuleID         string   `json:"rule_id,omitempty"`
+	RuleCode       string   `json:"rule_code,omitempty"`
+	From           int64    `json:"from,omitempty"`
+	To             int64    `json:"to,omitempty"`
+	EntityObjectID string   `json:"entity_object_id,omitempty"`
+	OnlySuccess    bool     `json:"only_success,omitempty"`
+	Limit          int      `json:"limit,omitempty"`
+}
@@ -0,0 +1,18 @@
+package dto
+
+// RuleStageRequest request đổi stage rule: draft | shadow | canary | active (promote) | retired.
+// Version bỏ trống (0) = giữ version ứng viên hiện tại, hoặc version active khi chưa có ứng viên.
+type RuleStageRequest struct {
+	Stage         string   `json:"stage"`
+	LogicVersion  int      `json:"logic_version,omitempty"`
+	ParamVersion  int      `json:"param_version,omitempty"`
+	OutputVersion int      `json:"output_version,omitempty"`
+	CanaryPercent int      `json:"canary_percent,omitempty"` // 0–100, bucket ổn định theo entity
+	CanaryOrgIDs  []string `json:"canary_org_ids,omitempty"` // Org luôn chạy version canary
+	Reason        string   `json:"reason,omitempty"`
+}
+
+// RuleRollbackRequest request rollback rule về version active trước đó.
+type RuleRollbackRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
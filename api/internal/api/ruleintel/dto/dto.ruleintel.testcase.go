@@ -0,0 +1,46 @@
+// Package dto — DTO cho Rule Test Case CRUD và chạy test Logic Script.
+package dto
+
+import "meta_commerce/internal/api/ruleintel/models"
+
+// RuleTestCaseCreateInput input tạo test case.
+type RuleTestCaseCreateInput struct {
+	TestCaseID          string                 `json:"testCaseId" validate:"required"`
+	LogicID             string                 `json:"logicId" validate:"required"`
+	LogicVersion        int                    `json:"logicVersion"` // 0 = mọi version
+	Name                string                 `json:"name" validate:"required"`
+	Description         string                 `json:"description,omitempty"`
+	Layers              map[string]interface{} `json:"layers"`
+	ParamSetID          string                 `json:"paramSetId,omitempty"`
+	ParamVersion        int                    `json:"paramVersion,omitempty"`
+	Params              map[string]interface{} `json:"params,omitempty"`
+	EntityRef           models.EntityRef       `json:"entityRef"`
+	ExpectedOutput      interface{}            `json:"expectedOutput"`
+	MatchMode           string                 `json:"matchMode"` // exact | partial
+	ExpectedReportKeys  []string               `json:"expectedReportKeys,omitempty"`
+	OwnerOrganizationID string                 `json:"ownerOrganizationId,omitempty" transform:"str_objectid,optional"`
+}
+
+// RuleTestCaseUpdateInput input cập nhật test case.
+type RuleTestCaseUpdateInput struct {
+	LogicVersion       *int                    `json:"logicVersion,omitempty"`
+	Name               *string                 `json:"name,omitempty"`
+	Description        *string                 `json:"description,omitempty"`
+	Layers             *map[string]interface{} `json:"layers,omitempty"`
+	ParamSetID         *string                 `json:"paramSetId,omitempty"`
+	ParamVersion       *int                    `json:"paramVersion,omitempty"`
+	Params             *map[string]interface{} `json:"params,omitempty"`
+	EntityRef          *models.EntityRef       `json:"entityRef,omitempty"`
+	ExpectedOutput     interface{}             `json:"expectedOutput,omitempty"`
+	MatchMode          *string                 `json:"matchMode,omitempty"`
+	ExpectedReportKeys *[]string               `json:"expectedReportKeys,omitempty"`
+}
+
+// RunLogicTestsRequest request chạy test case cho một version logic.
+// Script khác rỗng: chạy bản chưa lưu (soạn thảo) thay cho script đã lưu của version.
+type RunLogicTestsRequest struct {
+	LogicID       string `json:"logic_id"`
+	LogicVersion  int    `json:"logic_version"`
+	Script        string `json:"script,omitempty"`
+	EntryFunction string `json:"entry_function,omitempty"`
+}
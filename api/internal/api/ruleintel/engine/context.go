@@ -32,4 +32,6 @@ type RunResult struct {
 	LogicVersion int                    `json:"logic_version"`
 	ParamSetID   string                 `json:"param_set_id"`
 	ParamVersion int                    `json:"param_version"`
+	// RolloutStage version đã chạy: active | canary.
+	RolloutStage string `json:"rollout_stage,omitempty"`
 }
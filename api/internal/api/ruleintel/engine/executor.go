@@ -3,6 +3,7 @@ package engine
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"time"
 
 	"github.com/dop251/goja"
@@ -13,92 +14,257 @@ const (
 	ScriptTimeout = 100
 )
 
-// ScriptExecutor chạy Logic Script với goja.
+// ScriptExecutor chạy Logic Script với goja trong sandbox (timeout, trần bộ nhớ, ngân sách lệnh, ctx chỉ đọc).
 type ScriptExecutor struct {
 	entryFunction string
+	limits        ScriptLimits
 }
 
-// NewScriptExecutor tạo executor với entry function name.
+// NewScriptExecutor tạo executor với entry function name, giới hạn mặc định DefaultScriptLimits.
 func NewScriptExecutor(entryFunction string) *ScriptExecutor {
 	if entryFunction == "" {
 		entryFunction = "evaluate"
 	}
-	return &ScriptExecutor{entryFunction: entryFunction}
+	return &ScriptExecutor{entryFunction: entryFunction, limits: DefaultScriptLimits()}
+}
+
+// WithLimits trả về bản sao executor dùng giới hạn sandbox khác.
+func (e *ScriptExecutor) WithLimits(limits ScriptLimits) *ScriptExecutor {
+	cp := *e
+	cp.limits = limits
+	return &cp
+}
+
+// Limits giới hạn sandbox đang áp dụng.
+func (e *ScriptExecutor) Limits() ScriptLimits {
+	return e.limits
+}
+
+// Compile instrument ngân sách lệnh rồi biên dịch script. Program không gắn với VM nào, dùng lại được.
+// Script được bọc thành hàm factory (wrapScript) — mỗi lần chạy top-level chạy lại trong scope mới.
+func (e *ScriptExecutor) Compile(script string) (*goja.Program, error) {
+	src := script
+	if e.limits.MaxInstructions > 0 {
+		instrumented, err := instrumentScript(script)
+		if err != nil {
+			return nil, fmt.Errorf("script parse/load: %w", err)
+		}
+		src = instrumented
+	} else if _, err := goja.Parse("", script); err != nil {
+		// Script phải là program độc lập — không đóng ngoặc factory để thoát ra global
+		return nil, fmt.Errorf("script parse/load: %w", err)
+	}
+	prg, err := goja.Compile("", wrapScript(src, e.entryFunction), false)
+	if err != nil {
+		return nil, fmt.Errorf("script parse/load: %w", err)
+	}
+	return prg, nil
 }
 
 // Run chạy script với context, trả về output và report.
+// Vượt giới hạn sandbox trả về *ScriptLimitError (errors.Is với ErrScriptTimeout, ErrScriptMemoryLimit, ...).
 func (e *ScriptExecutor) Run(ctx context.Context, script string, evalCtx *EvalContext) (*EvalResult, int64, error) {
-	vm := goja.New()
 	start := time.Now()
-
-	// Expose ctx vào VM
-	ctxObj := vm.NewObject()
-	layersObj := vm.NewObject()
-	for k, v := range evalCtx.Layers {
-		layersObj.Set(k, v)
+	prg, err := e.Compile(script)
+	if err != nil {
+		return nil, time.Since(start).Milliseconds(), err
 	}
-	ctxObj.Set("layers", layersObj)
+	res, err := e.RunProgram(ctx, prg, evalCtx)
+	return res, time.Since(start).Milliseconds(), err
+}
 
-	paramsObj := vm.NewObject()
-	for k, v := range evalCtx.Params {
-		paramsObj.Set(k, v)
-	}
-	ctxObj.Set("params", paramsObj)
+// RunProgram chạy program đã biên dịch trên một VM mới.
+func (e *ScriptExecutor) RunProgram(ctx context.Context, prg *goja.Program, evalCtx *EvalContext) (*EvalResult, error) {
+	res, _, err := e.runOnSlot(ctx, newVMSlot(e.limits), prg, evalCtx)
+	return res, err
+}
 
-	entityRefObj := vm.NewObject()
-	entityRefObj.Set("domain", evalCtx.EntityRef.Domain)
-	entityRefObj.Set("objectType", evalCtx.EntityRef.ObjectType)
-	entityRefObj.Set("objectId", evalCtx.EntityRef.ObjectID)
-	entityRefObj.Set("ownerOrganizationId", evalCtx.EntityRef.OwnerOrganizationID)
-	ctxObj.Set("entity_ref", entityRefObj)
+// wrapScript bọc script thành biểu thức hàm: mỗi lần gọi chạy lại toàn bộ top-level trong scope mới và trả entry function,
+// nên var / let / const / function top-level không giữ giá trị giữa các lần chạy trên VM warm. Cùng dòng đầu để giữ số dòng lỗi.
+func wrapScript(src, entry string) string {
+	return "(function () {" + src + "\nreturn typeof " + entry + " === \"function\" ? " + entry + " : undefined;\n})"
+}
 
-	vm.Set("ctx", ctxObj)
+// runOnSlot chạy một lần trên slot (VM mới hoặc VM đã warm từ pool) trong sandbox.
+// reusable=false khi slot không được dùng lại (lỗi, bị ngắt).
+func (e *ScriptExecutor) runOnSlot(ctx context.Context, slot *vmSlot, prg *goja.Program, evalCtx *EvalContext) (res *EvalResult, reusable bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, fmt.Errorf("script execution: %w", err)
+	}
+	sb := newSandbox(slot.vm, e.limits)
+	slot.sb = sb
+	stop := sb.watch(ctx)
+	res, err = e.evaluate(slot, prg, evalCtx)
+	stop()
+	slot.sb = nil
+	if err != nil || sb.hasTripped() {
+		return res, false, sb.wrapError(err)
+	}
+	if !slot.resetGlobals() {
+		return res, false, nil
+	}
+	slot.vm.ClearInterrupt()
+	return res, true, nil
+}
 
-	// Chạy script
-	_, err := vm.RunString(script)
+// evaluate expose ctx (đóng băng) vào VM, lấy factory của program (nếu slot chưa warm), chạy lại top-level
+// qua factory rồi gọi entry function.
+func (e *ScriptExecutor) evaluate(slot *vmSlot, prg *goja.Program, evalCtx *EvalContext) (*EvalResult, error) {
+	vm := slot.vm
+	ctxObj, err := newFrozenContext(vm, evalCtx)
 	if err != nil {
-		return nil, time.Since(start).Milliseconds(), fmt.Errorf("script parse/load: %w", err)
+		return nil, fmt.Errorf("script context: %w", err)
+	}
+	vm.Set("ctx", ctxObj)
+
+	if slot.factory == nil {
+		v, err := vm.RunProgram(prg)
+		if err != nil {
+			return nil, fmt.Errorf("script parse/load: %w", err)
+		}
+		factory, ok := goja.AssertFunction(v)
+		if !ok {
+			return nil, fmt.Errorf("script parse/load: program không phải factory")
+		}
+		slot.factory = factory
 	}
 
-	// Gọi entry function
-	entryFn, ok := goja.AssertFunction(vm.Get(e.entryFunction))
+	// Chạy top-level của script (scope mới mỗi lần)
+	entry, err := slot.factory(goja.Undefined())
+	if err != nil {
+		return nil, fmt.Errorf("script parse/load: %w", err)
+	}
+	entryFn, ok := goja.AssertFunction(entry)
 	if !ok {
-		return nil, time.Since(start).Milliseconds(), fmt.Errorf("entry function %s không tồn tại", e.entryFunction)
+		return nil, fmt.Errorf("entry function %s không tồn tại", e.entryFunction)
 	}
 
+	// Gọi entry function
 	result, err := entryFn(goja.Undefined(), ctxObj)
 	if err != nil {
-		return nil, time.Since(start).Milliseconds(), fmt.Errorf("script execution: %w", err)
+		return nil, fmt.Errorf("script execution: %w", err)
 	}
 
 	// Parse result { output, report }
 	if result == nil || goja.IsNull(result) || goja.IsUndefined(result) {
-		return nil, time.Since(start).Milliseconds(), fmt.Errorf("script không trả về kết quả")
+		return nil, fmt.Errorf("script không trả về kết quả")
 	}
 
 	resultObj, ok := result.Export().(map[string]interface{})
 	if !ok {
-		return nil, time.Since(start).Milliseconds(), fmt.Errorf("script trả về không phải object")
+		return nil, fmt.Errorf("script trả về không phải object")
 	}
 
-	output, _ := resultObj["output"]
-	report, _ := resultObj["report"]
+	output := resultObj["output"]
+	report := resultObj["report"]
 	if report == nil {
-		return nil, time.Since(start).Milliseconds(), fmt.Errorf("script bắt buộc trả về report")
+		return nil, fmt.Errorf("script bắt buộc trả về report")
 	}
 
 	reportMap, ok := report.(map[string]interface{})
 	if !ok {
-		return nil, time.Since(start).Milliseconds(), fmt.Errorf("report phải là object")
+		return nil, fmt.Errorf("report phải là object")
 	}
 
 	// Kiểm tra report có log
 	if _, hasLog := reportMap["log"]; !hasLog {
-		return nil, time.Since(start).Milliseconds(), fmt.Errorf("report phải có field log")
+		return nil, fmt.Errorf("report phải có field log")
 	}
 
 	return &EvalResult{
 		Output: output,
 		Report: reportMap,
-	}, time.Since(start).Milliseconds(), nil
+	}, nil
+}
+
+// newFrozenContext dựng object ctx cho script: copy sâu layers/params/entity_ref thành object JS thuần
+// rồi Object.freeze toàn bộ — script không sửa được ctx và không chạm tới map Go của caller.
+func newFrozenContext(vm *goja.Runtime, evalCtx *EvalContext) (*goja.Object, error) {
+	freezeFn, ok := goja.AssertFunction(vm.Get("Object").ToObject(vm).Get("freeze"))
+	if !ok {
+		return nil, fmt.Errorf("Object.freeze không khả dụng")
+	}
+	fz := &freezer{vm: vm, freeze: freezeFn}
+
+	ctxObj := vm.NewObject()
+	layers, err := fz.value(reflect.ValueOf(evalCtx.Layers))
+	if err != nil {
+		return nil, err
+	}
+	params, err := fz.value(reflect.ValueOf(evalCtx.Params))
+	if err != nil {
+		return nil, err
+	}
+	ctxObj.Set("layers", layers)
+	ctxObj.Set("params", params)
+
+	entityRefObj := vm.NewObject()
+	entityRefObj.Set("domain", evalCtx.EntityRef.Domain)
+	entityRefObj.Set("objectType", evalCtx.EntityRef.ObjectType)
+	entityRefObj.Set("objectId", evalCtx.EntityRef.ObjectID)
+	entityRefObj.Set("ownerOrganizationId", evalCtx.EntityRef.OwnerOrganizationID)
+	if err := fz.freezeObj(entityRefObj); err != nil {
+		return nil, err
+	}
+	ctxObj.Set("entity_ref", entityRefObj)
+
+	if err := fz.freezeObj(ctxObj); err != nil {
+		return nil, err
+	}
+	return ctxObj, nil
+}
+
+type freezer struct {
+	vm     *goja.Runtime
+	freeze goja.Callable
+}
+
+func (f *freezer) freezeObj(o *goja.Object) error {
+	_, err := f.freeze(goja.Undefined(), o)
+	return err
+}
+
+// value chuyển map (key string) / slice thành object/array JS đóng băng; giá trị khác dùng vm.ToValue.
+// Map/slice nil thành object/array rỗng để script đọc ctx.layers.x không lỗi.
+func (f *freezer) value(v reflect.Value) (goja.Value, error) {
+	for v.IsValid() && v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return goja.Null(), nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return goja.Null(), nil
+	}
+	switch {
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		obj := f.vm.NewObject()
+		iter := v.MapRange()
+		for iter.Next() {
+			child, err := f.value(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			if err := obj.Set(iter.Key().String(), child); err != nil {
+				return nil, err
+			}
+		}
+		return obj, f.freezeObj(obj)
+	case (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return f.vm.NewArray(), nil
+		}
+		items := make([]interface{}, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			child, err := f.value(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			items[i] = child
+		}
+		arr := f.vm.NewArray(items...)
+		return arr, f.freezeObj(arr)
+	default:
+		return f.vm.ToValue(v.Interface()), nil
+	}
 }
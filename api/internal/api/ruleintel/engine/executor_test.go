@@ -0,0 +1,232 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestEvalContext() *EvalContext {
+	return &EvalContext{
+		Layers: map[string]interface{}{
+			"raw": map[string]interface{}{"orderCount": 3, "tags": []interface{}{"vip"}},
+		},
+		Params: map[string]interface{}{"threshold": 2},
+	}
+}
+
+func TestScriptExecutor_Run_Success(t *testing.T) {
+	script := `function evaluate(ctx) {
+  var n = 0;
+  for (var i = 0; i < ctx.layers.raw.tags.length; i++) n++;
+  return { output: { match: ctx.layers.raw.orderCount >= ctx.params.threshold, tags: n }, report: { log: 'ok' } };
+}`
+	res, _, err := NewScriptExecutor("").Run(context.Background(), script, newTestEvalContext())
+	if err != nil {
+		t.Fatalf("Run lỗi: %v", err)
+	}
+	out, ok := res.Output.(map[string]interface{})
+	if !ok || out["match"] != true {
+		t.Fatalf("output không đúng: %#v", res.Output)
+	}
+}
+
+func TestScriptExecutor_Run_InfiniteLoopTimesOut(t *testing.T) {
+	limits := DefaultScriptLimits()
+	limits.MaxInstructions = 0
+	exec := NewScriptExecutor("").WithLimits(limits)
+
+	start := time.Now()
+	_, _, err := exec.Run(context.Background(), `function evaluate(ctx) { while (true) {} }`, newTestEvalContext())
+	if !errors.Is(err, ErrScriptTimeout) {
+		t.Fatalf("vòng lặp vô hạn phải trả ErrScriptTimeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("VM không bị ngắt kịp thời: %v", elapsed)
+	}
+}
+
+func TestScriptExecutor_Run_HonorsCallerDeadline(t *testing.T) {
+	limits := DefaultScriptLimits()
+	limits.Timeout = 10 * time.Second
+	limits.MaxInstructions = 0
+	exec := NewScriptExecutor("").WithLimits(limits)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, _, err := exec.Run(ctx, `function evaluate(ctx) { for (;;) {} }`, newTestEvalContext())
+	if !IsScriptTimeout(err) {
+		t.Fatalf("deadline ctx caller phải ngắt script, got %v", err)
+	}
+}
+
+func TestScriptExecutor_Run_InstructionBudget(t *testing.T) {
+	limits := DefaultScriptLimits()
+	limits.Timeout = 10 * time.Second
+	limits.MaxInstructions = 1000
+	exec := NewScriptExecutor("").WithLimits(limits)
+
+	_, _, err := exec.Run(context.Background(), `function evaluate(ctx) { var i = 0; while (true) i++; }`, newTestEvalContext())
+	if !errors.Is(err, ErrScriptInstructionLimit) {
+		t.Fatalf("phải vượt ngân sách lệnh, got %v", err)
+	}
+}
+
+func TestScriptExecutor_Run_RecursionBudget(t *testing.T) {
+	limits := DefaultScriptLimits()
+	limits.Timeout = 10 * time.Second
+	limits.MaxInstructions = 1000
+	limits.MaxCallStackSize = 0
+	exec := NewScriptExecutor("").WithLimits(limits)
+
+	script := `function loop(n) { return loop(n) + loop(n); }
+function evaluate(ctx) { try { loop(1); } catch (e) {} return { output: null, report: { log: '' } }; }`
+	_, _, err := exec.Run(context.Background(), script, newTestEvalContext())
+	if !IsScriptLimitError(err) {
+		t.Fatalf("đệ quy phải bị chặn bởi sandbox, got %v", err)
+	}
+}
+
+func TestScriptExecutor_Run_MemoryCeiling(t *testing.T) {
+	limits := DefaultScriptLimits()
+	limits.Timeout = 10 * time.Second
+	limits.MaxInstructions = 0
+	limits.MaxAllocBytes = 8 << 20
+	exec := NewScriptExecutor("").WithLimits(limits)
+
+	script := `function evaluate(ctx) { var a = []; for (;;) { a.push({ s: 'xxxxxxxxxxxxxxxx' + a.length }); } }`
+	_, _, err := exec.Run(context.Background(), script, newTestEvalContext())
+	if !errors.Is(err, ErrScriptMemoryLimit) {
+		t.Fatalf("phải vượt trần bộ nhớ, got %v", err)
+	}
+}
+
+func TestScriptExecutor_Run_ContextIsFrozen(t *testing.T) {
+	evalCtx := newTestEvalContext()
+	script := `function evaluate(ctx) {
+  'use strict';
+  var errs = 0;
+  try { ctx.params.threshold = 100; } catch (e) { errs++; }
+  try { ctx.layers.raw.orderCount = 0; } catch (e) { errs++; }
+  try { ctx.layers.raw.tags.push('x'); } catch (e) { errs++; }
+  try { ctx.layers = {}; } catch (e) { errs++; }
+  return { output: { errs: errs, threshold: ctx.params.threshold }, report: { log: '' } };
+}`
+	res, _, err := NewScriptExecutor("").Run(context.Background(), script, evalCtx)
+	if err != nil {
+		t.Fatalf("Run lỗi: %v", err)
+	}
+	out := res.Output.(map[string]interface{})
+	if out["errs"] != int64(4) {
+		t.Fatalf("mọi thao tác ghi ctx phải bị chặn, got %#v", out)
+	}
+	raw := evalCtx.Layers["raw"].(map[string]interface{})
+	if raw["orderCount"] != 3 || len(raw["tags"].([]interface{})) != 1 {
+		t.Fatalf("map Go của caller bị script sửa: %#v", raw)
+	}
+}
+
+func TestInstrumentScript_NonBlockBodies(t *testing.T) {
+	script := `function evaluate(ctx) {
+  var n = 0;
+  for (var i = 0; i < 3; i++) n++;
+  while (n < 10) n += 2;
+  do n--; while (n > 5);
+  for (var k in ctx.params) n++;
+  var f = (x) => { return x + 1; };
+  return { output: f(n), report: { log: '' } };
+}`
+	res, _, err := NewScriptExecutor("").Run(context.Background(), script, newTestEvalContext())
+	if err != nil {
+		t.Fatalf("script instrument phải giữ nguyên ngữ nghĩa: %v", err)
+	}
+	if res.Output != int64(7) {
+		t.Fatalf("output sai sau instrument: %#v", res.Output)
+	}
+}
+
+func TestProgramPool_ReusesWarmVMAndDiscardsInterrupted(t *testing.T) {
+	exec := NewScriptExecutor("")
+	prg, err := exec.Compile(`function evaluate(ctx) {
+  if (ctx.params.spin) { for (;;) {} }
+  return { output: ctx.layers.raw.orderCount, report: { log: '' } };
+}`)
+	if err != nil {
+		t.Fatalf("Compile lỗi: %v", err)
+	}
+	pool := NewProgramPool(exec, prg)
+	for i := 0; i < 3; i++ {
+		res, err := pool.Run(context.Background(), newTestEvalContext())
+		if err != nil || res.Output != int64(3) {
+			t.Fatalf("lần %d: res=%#v err=%v", i, res, err)
+		}
+	}
+	spin := newTestEvalContext()
+	spin.Params["spin"] = true
+	if _, err := pool.Run(context.Background(), spin); !IsScriptLimitError(err) {
+		t.Fatalf("script lặp vô hạn phải bị ngắt, got %v", err)
+	}
+	if _, err := pool.Run(context.Background(), newTestEvalContext()); err != nil {
+		t.Fatalf("VM mới sau khi bỏ VM bị ngắt phải chạy được: %v", err)
+	}
+	st := pool.Stats()
+	if st.Discarded != 1 || st.Created < 2 {
+		t.Fatalf("thống kê pool không đúng: %+v", st)
+	}
+}
+
+// VM warm không giữ trạng thái top-level / global giữa các lần chạy.
+func TestProgramPool_WarmVMDoesNotKeepState(t *testing.T) {
+	exec := NewScriptExecutor("")
+	prg, err := exec.Compile(`var calls = 0;
+let seen = [];
+function evaluate(ctx) {
+  calls++;
+  seen.push(1);
+  leaked = (typeof leaked === 'undefined' ? 0 : leaked) + 1;
+  globalThis.extra = (globalThis.extra || 0) + 1;
+  return { output: calls * 1000 + seen.length * 100 + leaked * 10 + globalThis.extra, report: { log: '' } };
+}`)
+	if err != nil {
+		t.Fatalf("Compile lỗi: %v", err)
+	}
+	pool := NewProgramPool(exec, prg)
+	for i := 0; i < 3; i++ {
+		res, err := pool.Run(context.Background(), newTestEvalContext())
+		if err != nil {
+			t.Fatalf("lần %d: %v", i, err)
+		}
+		if res.Output != int64(1111) {
+			t.Fatalf("lần %d: trạng thái rò giữa các lần chạy, output=%#v", i, res.Output)
+		}
+	}
+	if st := pool.Stats(); st.Reused != 2 {
+		t.Fatalf("VM phải được dùng lại: %+v", st)
+	}
+}
+
+// Script không được đóng factory bọc ngoài để chạy code ở global.
+func TestCompile_RejectsWrapperEscape(t *testing.T) {
+	script := `}); globalThis.x = 1; (function () {
+function evaluate(ctx) { return { output: 1, report: { log: '' } }; }`
+	for _, exec := range []*ScriptExecutor{NewScriptExecutor(""), NewScriptExecutor("").WithLimits(ScriptLimits{})} {
+		if _, err := exec.Compile(script); err == nil {
+			t.Fatalf("script thoát factory phải bị từ chối (limits %+v)", exec.Limits())
+		}
+	}
+}
+
+// Biến / tham số trùng tên hàm đếm ngân sách sẽ che hàm native → vòng lặp không bị đếm.
+func TestCompile_RejectsBudgetFuncShadowing(t *testing.T) {
+	exec := NewScriptExecutor("")
+	for _, script := range []string{
+		`function evaluate(ctx) { var __ruleintel_budget__ = function () {}; while (true) {} }`,
+		`function evaluate(__ruleintel_budget__) { while (true) {} }`,
+		`function evaluate(ctx) { return { output: ctx.__ruleintel_budget__, report: { log: '' } }; }`,
+	} {
+		if _, err := exec.Compile(script); err == nil {
+			t.Errorf("script dùng %s phải bị từ chối: %s", budgetFuncName, script)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dop251/goja"
+)
+
+// vmSlot một goja.Runtime kèm sandbox của lần chạy hiện tại.
+// factory != nil khi VM đã nạp program (warm) — lần sau chỉ gọi factory (chạy lại top-level) rồi entry function.
+// globals: tên property của global object lúc tạo VM — property thêm sau đó bị xóa sau mỗi lần chạy.
+type vmSlot struct {
+	vm      *goja.Runtime
+	sb      *sandbox
+	factory goja.Callable
+	globals map[string]bool
+}
+
+// newVMSlot tạo VM mới: đặt call stack tối đa và hàm đếm ngân sách lệnh (trỏ vào sandbox của lần chạy hiện tại).
+func newVMSlot(limits ScriptLimits) *vmSlot {
+	slot := &vmSlot{vm: goja.New()}
+	if limits.MaxCallStackSize > 0 {
+		slot.vm.SetMaxCallStackSize(limits.MaxCallStackSize)
+	}
+	budget := func(goja.FunctionCall) goja.Value {
+		if slot.sb != nil {
+			slot.sb.tick()
+		}
+		return goja.Undefined()
+	}
+	_ = slot.vm.GlobalObject().DefineDataProperty(budgetFuncName, slot.vm.ToValue(budget), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
+	slot.globals = make(map[string]bool)
+	for _, name := range slot.vm.GlobalObject().GetOwnPropertyNames() {
+		slot.globals[name] = true
+	}
+	return slot
+}
+
+// resetGlobals xóa global do lần chạy tạo ra (gán biến không khai báo, globalThis.x, ctx).
+// Trả false khi còn global không xóa được (non-configurable) — slot không được dùng lại.
+func (slot *vmSlot) resetGlobals() bool {
+	global := slot.vm.GlobalObject()
+	for _, name := range global.GetOwnPropertyNames() {
+		if slot.globals[name] {
+			continue
+		}
+		if err := global.Delete(name); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// PoolStats thống kê tái sử dụng VM của một ProgramPool.
+type PoolStats struct {
+	Reused    int64 `json:"reused"`
+	Created   int64 `json:"created"`
+	Discarded int64 `json:"discarded"`
+}
+
+// ProgramPool pool VM đã warm cho một program: mỗi VM chỉ từng chạy đúng program này,
+// nên global của script khác không rò sang. Mỗi lần chạy top-level chạy lại trong scope mới và global phát sinh
+// bị xóa, nên biến top-level không giữ giá trị giữa các lần gọi. VM bị ngắt, lỗi hoặc còn global không xóa được
+// sẽ bị bỏ, không trả lại pool.
+type ProgramPool struct {
+	exec *ScriptExecutor
+	prg  *goja.Program
+	pool sync.Pool
+
+	reused, created, discarded atomic.Int64
+}
+
+// NewProgramPool tạo pool cho program đã biên dịch bằng exec.Compile.
+func NewProgramPool(exec *ScriptExecutor, prg *goja.Program) *ProgramPool {
+	return &ProgramPool{exec: exec, prg: prg}
+}
+
+// Program program của pool.
+func (p *ProgramPool) Program() *goja.Program {
+	return p.prg
+}
+
+// Run chạy program trên một VM lấy từ pool (hoặc VM mới nếu pool rỗng).
+func (p *ProgramPool) Run(ctx context.Context, evalCtx *EvalContext) (*EvalResult, error) {
+	slot, _ := p.pool.Get().(*vmSlot)
+	if slot != nil {
+		p.reused.Add(1)
+	} else {
+		slot = newVMSlot(p.exec.limits)
+		p.created.Add(1)
+	}
+	res, reusable, err := p.exec.runOnSlot(ctx, slot, p.prg, evalCtx)
+	if reusable {
+		p.pool.Put(slot)
+	} else {
+		p.discarded.Add(1)
+	}
+	return res, err
+}
+
+// Stats thống kê pool.
+func (p *ProgramPool) Stats() PoolStats {
+	return PoolStats{Reused: p.reused.Load(), Created: p.created.Load(), Discarded: p.discarded.Load()}
+}
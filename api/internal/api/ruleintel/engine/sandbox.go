@@ -0,0 +1,382 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime/metrics"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja/ast"
+	"github.com/dop251/goja/file"
+	"github.com/dop251/goja/parser"
+)
+
+const (
+	// DefaultMaxAllocBytes trần cấp phát heap của toàn process trong thời gian một lần chạy — chốt chặn best-effort,
+	// không phải giới hạn bộ nhớ theo VM (xem ScriptLimits.MaxAllocBytes).
+	DefaultMaxAllocBytes = 256 << 20
+	// DefaultMaxInstructions ngân sách lệnh: số lần vào thân vòng lặp / thân hàm trong một lần chạy.
+	DefaultMaxInstructions = 1_000_000
+	// DefaultMaxCallStackSize độ sâu call stack tối đa (chặn đệ quy vô hạn).
+	DefaultMaxCallStackSize = 512
+
+	// budgetFuncName hàm native được chèn vào đầu mỗi thân vòng lặp / hàm để đếm ngân sách lệnh.
+	budgetFuncName = "__ruleintel_budget__"
+	// allocSampleInterval chu kỳ watchdog lấy mẫu heap.
+	allocSampleInterval = 5 * time.Millisecond
+	allocMetricName     = "/gc/heap/allocs:bytes"
+)
+
+// ScriptLimits giới hạn sandbox cho một lần chạy Logic Script. Giá trị 0 = không giới hạn chiều đó.
+type ScriptLimits struct {
+	// Timeout thời gian wall-clock tối đa; deadline của ctx caller (nếu sớm hơn) được ưu tiên.
+	Timeout time.Duration
+	// MaxAllocBytes chốt chặn best-effort trên toàn process: byte heap cả process cấp phát kể từ lúc lần chạy bắt đầu
+	// (runtime/metrics /gc/heap/allocs:bytes). goja và Go runtime không đếm cấp phát theo VM / goroutine nên đây
+	// KHÔNG phải giới hạn bộ nhớ theo VM: cấp phát của goroutine khác (worker khác, request HTTP, script song song)
+	// cũng được tính, nên một script nhỏ có thể bị ngắt khi process đang bận, và nhiều script cùng cấp phát lớn
+	// không bị chặn riêng từng cái. Đặt trần rộng rãi — chỉ để bắt script cấp phát mất kiểm soát;
+	// giới hạn chính của sandbox là Timeout + MaxInstructions.
+	MaxAllocBytes uint64
+	// MaxInstructions ngân sách lệnh (đếm mỗi lần vào thân vòng lặp / thân hàm).
+	MaxInstructions int64
+	// MaxCallStackSize độ sâu call stack tối đa của VM.
+	MaxCallStackSize int
+}
+
+// DefaultScriptLimits giới hạn mặc định cho Rule Engine.
+func DefaultScriptLimits() ScriptLimits {
+	return ScriptLimits{
+		Timeout:          ScriptTimeout * time.Millisecond,
+		MaxAllocBytes:    DefaultMaxAllocBytes,
+		MaxInstructions:  DefaultMaxInstructions,
+		MaxCallStackSize: DefaultMaxCallStackSize,
+	}
+}
+
+// LimitKind loại giới hạn sandbox bị vượt.
+type LimitKind string
+
+const (
+	LimitTimeout      LimitKind = "timeout"
+	LimitCanceled     LimitKind = "canceled"
+	LimitMemory       LimitKind = "memory"
+	LimitInstructions LimitKind = "instructions"
+)
+
+var (
+	// ErrScriptTimeout script chạy quá thời gian cho phép (hoặc ctx caller hết hạn).
+	ErrScriptTimeout = errors.New("script vượt thời gian cho phép")
+	// ErrScriptCanceled ctx caller bị hủy khi script đang chạy.
+	ErrScriptCanceled = errors.New("script bị hủy theo context")
+	// ErrScriptMemoryLimit cấp phát heap của process vượt trần trong lúc script chạy (chốt chặn toàn process, không theo VM).
+	ErrScriptMemoryLimit = errors.New("cấp phát bộ nhớ của process vượt trần trong lúc script chạy")
+	// ErrScriptInstructionLimit script vượt ngân sách lệnh.
+	ErrScriptInstructionLimit = errors.New("script vượt ngân sách lệnh")
+)
+
+// ScriptLimitError lỗi khi VM bị ngắt do vượt giới hạn sandbox. Dùng errors.Is với ErrScript* để phân loại.
+type ScriptLimitError struct {
+	Kind    LimitKind
+	Limit   string
+	Elapsed time.Duration
+}
+
+func (e *ScriptLimitError) Error() string {
+	return fmt.Sprintf("%s (giới hạn %s, đã chạy %dms)", e.sentinel().Error(), e.Limit, e.Elapsed.Milliseconds())
+}
+
+// Is cho phép errors.Is(err, ErrScriptTimeout) ...
+func (e *ScriptLimitError) Is(target error) bool {
+	return target == e.sentinel()
+}
+
+func (e *ScriptLimitError) sentinel() error {
+	switch e.Kind {
+	case LimitTimeout:
+		return ErrScriptTimeout
+	case LimitCanceled:
+		return ErrScriptCanceled
+	case LimitMemory:
+		return ErrScriptMemoryLimit
+	default:
+		return ErrScriptInstructionLimit
+	}
+}
+
+// IsScriptTimeout true khi lỗi do script chạy quá thời gian.
+func IsScriptTimeout(err error) bool {
+	return errors.Is(err, ErrScriptTimeout)
+}
+
+// IsScriptLimitError true khi lỗi do vượt bất kỳ giới hạn sandbox nào.
+func IsScriptLimitError(err error) bool {
+	var le *ScriptLimitError
+	return errors.As(err, &le)
+}
+
+// sandbox trạng thái giới hạn của một lần chạy trên một VM.
+type sandbox struct {
+	vm     *goja.Runtime
+	limits ScriptLimits
+	start  time.Time
+
+	mu       sync.Mutex
+	tripped  *ScriptLimitError
+	counter  int64
+	done     chan struct{}
+	finished sync.WaitGroup
+}
+
+// newSandbox tạo trạng thái giới hạn cho một lần chạy trên vm.
+func newSandbox(vm *goja.Runtime, limits ScriptLimits) *sandbox {
+	return &sandbox{vm: vm, limits: limits, start: time.Now(), done: make(chan struct{})}
+}
+
+// tick được script đã instrument gọi ở đầu mỗi thân vòng lặp / hàm.
+func (sb *sandbox) tick() {
+	sb.counter++
+	if sb.limits.MaxInstructions > 0 && sb.counter > sb.limits.MaxInstructions {
+		sb.trip(LimitInstructions, fmt.Sprintf("%d", sb.limits.MaxInstructions))
+	}
+}
+
+// hasTripped true khi lần chạy đã bị sandbox ngắt (kể cả ngay sau khi script vừa xong).
+func (sb *sandbox) hasTripped() bool {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.tripped != nil
+}
+
+// trip ghi nhận giới hạn bị vượt đầu tiên và ngắt VM.
+func (sb *sandbox) trip(kind LimitKind, limit string) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	if sb.tripped != nil {
+		return
+	}
+	sb.tripped = &ScriptLimitError{Kind: kind, Limit: limit, Elapsed: time.Since(sb.start)}
+	sb.vm.Interrupt(sb.tripped)
+}
+
+// watch chạy watchdog: ngắt VM khi hết thời gian, ctx caller bị hủy hoặc cấp phát vượt trần.
+// Caller phải gọi stop() khi chạy xong — stop chờ watchdog thoát hẳn nên sau đó VM không còn bị Interrupt.
+func (sb *sandbox) watch(ctx context.Context) (stop func()) {
+	runCtx, cancel := ctx, context.CancelFunc(func() {})
+	if sb.limits.Timeout > 0 {
+		runCtx, cancel = context.WithTimeout(ctx, sb.limits.Timeout)
+	}
+
+	var tickC <-chan time.Time
+	var ticker *time.Ticker
+	var baseAlloc uint64
+	var sample []metrics.Sample
+	if sb.limits.MaxAllocBytes > 0 {
+		sample = []metrics.Sample{{Name: allocMetricName}}
+		baseAlloc = readAlloc(sample)
+		ticker = time.NewTicker(allocSampleInterval)
+		tickC = ticker.C
+	}
+
+	sb.finished.Add(1)
+	go func() {
+		defer sb.finished.Done()
+		for {
+			select {
+			case <-sb.done:
+				return
+			case <-runCtx.Done():
+				if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+					limit := sb.limits.Timeout.String()
+					if ctx.Err() != nil {
+						limit = "deadline của ctx caller"
+					}
+					sb.trip(LimitTimeout, limit)
+				} else {
+					sb.trip(LimitCanceled, "ctx")
+				}
+				return
+			case <-tickC:
+				if used := readAlloc(sample) - baseAlloc; used > sb.limits.MaxAllocBytes {
+					sb.trip(LimitMemory, fmt.Sprintf("%dB toàn process", sb.limits.MaxAllocBytes))
+					return
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(sb.done)
+		sb.finished.Wait()
+		if ticker != nil {
+			ticker.Stop()
+		}
+		cancel()
+	}
+}
+
+// wrapError chuyển lỗi goja sang ScriptLimitError khi VM bị ngắt bởi sandbox.
+func (sb *sandbox) wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var le *ScriptLimitError
+	if errors.As(err, &le) {
+		return le
+	}
+	sb.mu.Lock()
+	tripped := sb.tripped
+	sb.mu.Unlock()
+	if tripped != nil {
+		return tripped
+	}
+	return err
+}
+
+func readAlloc(sample []metrics.Sample) uint64 {
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
+
+// --- Instrument ngân sách lệnh ---
+
+type insertion struct {
+	offset int
+	text   string
+}
+
+// instrumentScript chèn lời gọi đếm ngân sách vào đầu mỗi thân vòng lặp và thân hàm.
+// goja không có hook đếm lệnh nên đây là cách duy nhất chặn vòng lặp dài mà không phụ thuộc wall-clock.
+// Script dùng tên budgetFuncName (khai báo biến / tham số cùng tên sẽ che hàm đếm) bị từ chối.
+func instrumentScript(src string) (string, error) {
+	prg, err := parser.ParseFile(nil, "", src, 0)
+	if err != nil {
+		return "", err
+	}
+	tick := budgetFuncName + "();"
+	var ins []insertion
+	addBody := func(body ast.Statement) {
+		if body == nil {
+			return
+		}
+		if blk, ok := body.(*ast.BlockStatement); ok {
+			// Giữ directive prologue ('use strict') ở đầu thân hàm — chèn sau nó.
+			offset, text := idxOffset(blk.LeftBrace)+1, tick
+			for _, st := range blk.List {
+				es, ok := st.(*ast.ExpressionStatement)
+				if !ok {
+					break
+				}
+				if _, isStr := es.Expression.(*ast.StringLiteral); !isStr {
+					break
+				}
+				offset, text = idxOffset(es.Idx1()), ";"+tick
+			}
+			ins = append(ins, insertion{offset: offset, text: text})
+			return
+		}
+		// Thân không phải block: bọc {tick; stmt} — "}" đặt sau dấu ";" kết thúc statement (do x; while(...)).
+		ins = append(ins, insertion{offset: idxOffset(body.Idx0()), text: "{" + tick})
+		ins = append(ins, insertion{offset: statementEnd(src, idxOffset(body.Idx1())), text: "}"})
+	}
+
+	identType := reflect.TypeOf(ast.Identifier{})
+	shadowed := false
+	visited := make(map[uintptr]bool)
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		switch v.Kind() {
+		case reflect.Ptr:
+			if v.IsNil() || visited[v.Pointer()] {
+				return
+			}
+			visited[v.Pointer()] = true
+			switch n := v.Interface().(type) {
+			case *file.File:
+				return
+			case *ast.ForStatement:
+				addBody(n.Body)
+			case *ast.ForInStatement:
+				addBody(n.Body)
+			case *ast.ForOfStatement:
+				addBody(n.Body)
+			case *ast.WhileStatement:
+				addBody(n.Body)
+			case *ast.DoWhileStatement:
+				addBody(n.Body)
+			case *ast.FunctionLiteral:
+				if n.Body != nil {
+					addBody(n.Body)
+				}
+			case *ast.ArrowFunctionLiteral:
+				if blk, ok := n.Body.(*ast.BlockStatement); ok {
+					addBody(blk)
+				}
+			}
+			walk(v.Elem())
+		case reflect.Interface:
+			if !v.IsNil() {
+				walk(v.Elem())
+			}
+		case reflect.Struct:
+			if v.Type() == identType && v.Interface().(ast.Identifier).Name == budgetFuncName {
+				shadowed = true
+				return
+			}
+			for i := 0; i < v.NumField(); i++ {
+				if v.Type().Field(i).IsExported() {
+					walk(v.Field(i))
+				}
+			}
+		case reflect.Slice:
+			for i := 0; i < v.Len(); i++ {
+				walk(v.Index(i))
+			}
+		}
+	}
+	walk(reflect.ValueOf(prg))
+	if shadowed {
+		return "", fmt.Errorf("tên %s dành riêng cho sandbox, script không được dùng", budgetFuncName)
+	}
+
+	if len(ins) == 0 {
+		return src, nil
+	}
+	// Chèn từ cuối về đầu để offset phía trước không bị lệch; cùng offset giữ thứ tự phát hiện.
+	sort.SliceStable(ins, func(i, j int) bool { return ins[i].offset > ins[j].offset })
+	out := src
+	for _, in := range ins {
+		if in.offset < 0 || in.offset > len(out) {
+			return "", fmt.Errorf("instrument offset %d ngoài phạm vi", in.offset)
+		}
+		out = out[:in.offset] + in.text + out[in.offset:]
+	}
+	return out, nil
+}
+
+// statementEnd trả offset sau dấu ";" kết thúc statement (nếu có) tính từ end.
+func statementEnd(src string, end int) int {
+	for i := end; i < len(src); i++ {
+		switch src[i] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case ';':
+			return i + 1
+		}
+		break
+	}
+	return end
+}
+
+// idxOffset chuyển file.Idx (base 1 khi ParseFile không có FileSet) sang offset byte.
+func idxOffset(idx file.Idx) int {
+	return int(idx) - 1
+}
@@ -0,0 +1,79 @@
+// Package handler — Handler dry-run / replay Rule Engine.
+package handler
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v3"
+
+	basehdl "meta_commerce/internal/api/base/handler"
+	"meta_commerce/internal/api/ruleintel/dto"
+	ruleintelsvc "meta_commerce/internal/api/ruleintel/service"
+	"meta_commerce/internal/common"
+)
+
+// NewReplayRuleHandler tạo handler cho POST /rule-intelligence/replay.
+// Chạy lại trace lịch sử với logic / param ứng viên, trả diff từng trace và số quyết định sẽ đổi.
+func NewReplayRuleHandler() (fiber.Handler, error) {
+	svc, err := ruleintelsvc.NewRuleEngineService()
+	if err != nil {
+		return nil, fmt.Errorf("tạo RuleEngineService: %w", err)
+	}
+	return func(c fiber.Ctx) error {
+		return handleReplayRuleWithService(c, svc)
+	}, nil
+}
+
+func handleReplayRuleWithService(c fiber.Ctx, svc *ruleintelsvc.RuleEngineService) error {
+	return basehdl.SafeHandlerWrapper(c, func() error {
+		var req dto.ReplayRuleRequest
+		if err := c.Bind().JSON(&req); err != nil {
+			c.Status(common.StatusBadRequest).JSON(fiber.Map{
+				"code": common.ErrCodeValidationFormat.Code, "message": "Body JSON không hợp lệ", "status": "error",
+			})
+			return nil
+		}
+
+		orgID := getActiveOrgID(c)
+		if orgID == "" {
+			c.Status(common.StatusBadRequest).JSON(fiber.Map{
+				"code": common.ErrCodeValidationFormat.Code, "message": "Chưa chọn tổ chức", "status": "error",
+			})
+			return nil
+		}
+
+		input := &ruleintelsvc.ReplayInput{
+			OwnerOrganizationID: orgID,
+			Candidate: ruleintelsvc.ReplayCandidate{
+				LogicID:        req.Candidate.LogicID,
+				LogicVersion:   req.Candidate.LogicVersion,
+				Script:         req.Candidate.Script,
+				ParamSetID:     req.Candidate.ParamSetID,
+				ParamVersion:   req.Candidate.ParamVersion,
+				ParamsOverride: req.Candidate.ParamsOverride,
+			},
+			Selection: ruleintelsvc.ReplaySelection{
+				TraceIDs:       req.Selection.TraceIDs,
+				RuleID:         req.Selection.RuleID,
+				RuleCode:       req.Selection.RuleCode,
+				From:           req.Selection.From,
+				To:             req.Selection.To,
+				EntityObjectID: req.Selection.EntityObjectID,
+				OnlySuccess:    req.Selection.OnlySuccess,
+				Limit:          req.Selection.Limit,
+			},
+		}
+
+		result, err := svc.Replay(c.Context(), input)
+		if err != nil {
+			errCode, msg, statusCode := common.GetErrorResponseInfo(err, "Replay rule thất bại")
+			c.Status(statusCode).JSON(fiber.Map{"code": errCode, "message": msg, "status": "error"})
+			return nil
+		}
+
+		c.Status(common.StatusOK).JSON(fiber.Map{
+			"code": common.StatusOK, "message": "Thành công", "data": result, "status": "success",
+		})
+		return nil
+	})
+}
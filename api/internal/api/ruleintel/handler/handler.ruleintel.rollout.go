@@ -0,0 +1,123 @@
+// Package handler — Handler promotion workflow Rule Definition (stage, promote, rollback, audit).
+package handler
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	basehdl "meta_commerce/internal/api/base/handler"
+	"meta_commerce/internal/api/ruleintel/dto"
+	ruleintelsvc "meta_commerce/internal/api/ruleintel/service"
+	"meta_commerce/internal/common"
+)
+
+// RuleRolloutHandler xử lý /rule-intelligence/rollout/:ruleId/*.
+type RuleRolloutHandler struct {
+	svc *ruleintelsvc.RulePromotionService
+}
+
+// NewRuleRolloutHandler tạo handler.
+func NewRuleRolloutHandler() (*RuleRolloutHandler, error) {
+	svc, err := ruleintelsvc.NewRulePromotionService()
+	if err != nil {
+		return nil, fmt.Errorf("tạo RulePromotionService: %w", err)
+	}
+	return &RuleRolloutHandler{svc: svc}, nil
+}
+
+// HandleSetStage POST /rule-intelligence/rollout/:ruleId/stage — đổi stage / promote / retire rule.
+func (h *RuleRolloutHandler) HandleSetStage(c fiber.Ctx) error {
+	return basehdl.SafeHandlerWrapper(c, func() error {
+		var req dto.RuleStageRequest
+		if err := c.Bind().JSON(&req); err != nil {
+			c.Status(common.StatusBadRequest).JSON(fiber.Map{
+				"code": common.ErrCodeValidationFormat.Code, "message": "Body JSON không hợp lệ", "status": "error",
+			})
+			return nil
+		}
+		if req.Stage == "" {
+			c.Status(common.StatusBadRequest).JSON(fiber.Map{
+				"code": common.ErrCodeValidationInput.Code, "message": "stage là bắt buộc", "status": "error",
+			})
+			return nil
+		}
+
+		rule, err := h.svc.SetStage(c.Context(), &ruleintelsvc.RuleStageInput{
+			RuleID:        c.Params("ruleId"),
+			Stage:         req.Stage,
+			LogicVersion:  req.LogicVersion,
+			ParamVersion:  req.ParamVersion,
+			OutputVersion: req.OutputVersion,
+			CanaryPercent: req.CanaryPercent,
+			CanaryOrgIDs:  req.CanaryOrgIDs,
+			Reason:        req.Reason,
+			UserID:        getUserID(c),
+			OrgID:         activeOrgObjectID(c),
+		})
+		if err != nil {
+			errCode, msg, statusCode := common.GetErrorResponseInfo(err, "Đổi stage rule thất bại")
+			c.Status(statusCode).JSON(fiber.Map{"code": errCode, "message": msg, "status": "error"})
+			return nil
+		}
+		c.Status(common.StatusOK).JSON(fiber.Map{
+			"code": common.StatusOK, "message": "Thành công", "data": rule, "status": "success",
+		})
+		return nil
+	})
+}
+
+// HandleRollback POST /rule-intelligence/rollout/:ruleId/rollback — quay về version active trước đó.
+func (h *RuleRolloutHandler) HandleRollback(c fiber.Ctx) error {
+	return basehdl.SafeHandlerWrapper(c, func() error {
+		var req dto.RuleRollbackRequest
+		if len(c.Body()) > 0 {
+			if err := c.Bind().JSON(&req); err != nil {
+				c.Status(common.StatusBadRequest).JSON(fiber.Map{
+					"code": common.ErrCodeValidationFormat.Code, "message": "Body JSON không hợp lệ", "status": "error",
+				})
+				return nil
+			}
+		}
+
+		rule, err := h.svc.Rollback(c.Context(), c.Params("ruleId"), getUserID(c), req.Reason, activeOrgObjectID(c))
+		if err != nil {
+			errCode, msg, statusCode := common.GetErrorResponseInfo(err, "Rollback rule thất bại")
+			c.Status(statusCode).JSON(fiber.Map{"code": errCode, "message": msg, "status": "error"})
+			return nil
+		}
+		c.Status(common.StatusOK).JSON(fiber.Map{
+			"code": common.StatusOK, "message": "Thành công", "data": rule, "status": "success",
+		})
+		return nil
+	})
+}
+
+// HandleListAudits GET /rule-intelligence/rollout/:ruleId/audits?limit= — lịch sử promotion, mới nhất trước.
+func (h *RuleRolloutHandler) HandleListAudits(c fiber.Ctx) error {
+	return basehdl.SafeHandlerWrapper(c, func() error {
+		limit, _ := strconv.ParseInt(c.Query("limit"), 10, 64)
+		audits, err := h.svc.ListAudits(c.Context(), c.Params("ruleId"), activeOrgObjectID(c), limit)
+		if err != nil {
+			errCode, msg, statusCode := common.GetErrorResponseInfo(err, "Lấy lịch sử promotion thất bại")
+			c.Status(statusCode).JSON(fiber.Map{"code": errCode, "message": msg, "status": "error"})
+			return nil
+		}
+		c.Status(common.StatusOK).JSON(fiber.Map{
+			"code": common.StatusOK, "message": "Thành công", "data": audits, "status": "success",
+		})
+		return nil
+	})
+}
+
+func getUserID(c fiber.Ctx) string {
+	userID, _ := c.Locals("user_id").(string)
+	return userID
+}
+
+func activeOrgObjectID(c fiber.Ctx) primitive.ObjectID {
+	orgID, _ := primitive.ObjectIDFromHex(getActiveOrgID(c))
+	return orgID
+}
@@ -0,0 +1,89 @@
+// Package handler — CRUD handler cho Rule Test Case và API chạy test Logic Script.
+package handler
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v3"
+
+	basehdl "meta_commerce/internal/api/base/handler"
+	"meta_commerce/internal/api/ruleintel/dto"
+	"meta_commerce/internal/api/ruleintel/models"
+	"meta_commerce/internal/api/ruleintel/service"
+	"meta_commerce/internal/common"
+)
+
+// RuleTestCaseHandler CRUD cho Rule Test Case.
+type RuleTestCaseHandler struct {
+	*basehdl.BaseHandler[models.RuleTestCase, dto.RuleTestCaseCreateInput, dto.RuleTestCaseUpdateInput]
+	svc      *service.RuleTestCaseService
+	logicSvc *service.LogicScriptService
+}
+
+// NewRuleTestCaseHandler tạo RuleTestCaseHandler.
+func NewRuleTestCaseHandler() (*RuleTestCaseHandler, error) {
+	svc, err := service.NewRuleTestCaseService()
+	if err != nil {
+		return nil, fmt.Errorf("tạo RuleTestCaseService: %w", err)
+	}
+	logicSvc, err := service.NewLogicScriptService()
+	if err != nil {
+		return nil, fmt.Errorf("tạo LogicScriptService: %w", err)
+	}
+	h := &RuleTestCaseHandler{
+		BaseHandler: basehdl.NewBaseHandler[models.RuleTestCase, dto.RuleTestCaseCreateInput, dto.RuleTestCaseUpdateInput](svc),
+		svc:         svc,
+		logicSvc:    logicSvc,
+	}
+	h.SetFilterOptions(basehdl.FilterOptions{
+		DeniedFields:     []string{"isSystem"},
+		AllowedOperators: []string{"$eq", "$gt", "$gte", "$lt", "$lte", "$in", "$nin", "$exists"},
+		MaxFields:        10,
+	})
+	return h, nil
+}
+
+// HandleRunTests POST /rule-intelligence/test-case/run — chạy mọi test case của một version logic, trả pass/fail từng case.
+func (h *RuleTestCaseHandler) HandleRunTests(c fiber.Ctx) error {
+	return basehdl.SafeHandlerWrapper(c, func() error {
+		var req dto.RunLogicTestsRequest
+		if err := c.Bind().JSON(&req); err != nil {
+			c.Status(common.StatusBadRequest).JSON(fiber.Map{
+				"code": common.ErrCodeValidationFormat.Code, "message": "Body JSON không hợp lệ", "status": "error",
+			})
+			return nil
+		}
+		if req.LogicID == "" || req.LogicVersion <= 0 {
+			c.Status(common.StatusBadRequest).JSON(fiber.Map{
+				"code": common.ErrCodeValidationInput.Code, "message": "logic_id và logic_version là bắt buộc", "status": "error",
+			})
+			return nil
+		}
+
+		logic := models.LogicScript{LogicID: req.LogicID, LogicVersion: req.LogicVersion, Script: req.Script, EntryFunction: req.EntryFunction, OwnerOrganizationID: activeOrgObjectID(c)}
+		if req.Script == "" {
+			stored, err := h.logicSvc.FindVersionInScope(c.Context(), req.LogicID, req.LogicVersion, activeOrgObjectID(c))
+			if err != nil {
+				if errors.Is(err, common.ErrNotFound) {
+					err = common.NewError(common.ErrCodeValidationInput, fmt.Sprintf("Không tìm thấy logic %s v%d", req.LogicID, req.LogicVersion), common.StatusNotFound, nil)
+				}
+				errCode, msg, statusCode := common.GetErrorResponseInfo(err, "Load logic thất bại")
+				c.Status(statusCode).JSON(fiber.Map{"code": errCode, "message": msg, "status": "error"})
+				return nil
+			}
+			logic = stored
+		}
+
+		report, err := h.svc.RunForLogic(c.Context(), &logic)
+		if err != nil {
+			errCode, msg, statusCode := common.GetErrorResponseInfo(err, "Chạy test case thất bại")
+			c.Status(statusCode).JSON(fiber.Map{"code": errCode, "message": msg, "status": "error"})
+			return nil
+		}
+		c.Status(common.StatusOK).JSON(fiber.Map{
+			"code": common.StatusOK, "message": "Thành công", "data": report, "status": "success",
+		})
+		return nil
+	})
+}
@@ -15,6 +15,7 @@ import (
 
 // scriptClassification — Logic Script: raw metrics → crm_classification.
 // Input: ctx.layers.raw = { totalSpent, orderCount, lastOrderAt, revenueLast30d, revenueLast90d, orderCountOnline, orderCountOffline, hasConversation, conversationTags }
+//        ctx.layers.custom = { tags, fields } — tag và trường tùy chỉnh shop gắn cho khách (script mặc định không dùng; rule tùy chỉnh của org đọc được)
 // Params: valueVip, valueHigh, valueMedium, valueLow, lifecycleActive, lifecycleCooling, lifecycleInactive, loyaltyCore, loyaltyRepeat, momentumRising, momentumStableLo, momentumStableHi
 // Output: crm_classification (valueTier, lifecycleStage, journeyStage, channel, loyaltyStage, momentumStage)
 var scriptClassification = `function evaluate(ctx) {
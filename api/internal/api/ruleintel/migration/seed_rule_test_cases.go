@@ -0,0 +1,184 @@
+// Package migration — Seed test case cho Logic Script hệ thống (Ads kill SL-A, CRM classification, CIX).
+// Publish version mới của các logic này bị chặn khi các test case dưới đây fail.
+package migration
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"meta_commerce/internal/api/ruleintel/models"
+	"meta_commerce/internal/api/ruleintel/service"
+)
+
+// SeedRuleTestCases seed test case hệ thống — gọi sau khi seed Logic Script.
+func SeedRuleTestCases(ctx context.Context) error {
+	systemOrgID := GetSystemOrgIDForSeed(ctx)
+	svc, err := service.NewRuleTestCaseService()
+	if err != nil {
+		return err
+	}
+	for _, tc := range systemRuleTestCases(systemOrgID) {
+		if _, err := svc.Upsert(ctx, bson.M{"test_case_id": tc.TestCaseID}, tc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// systemRuleTestCases test case hệ thống. Params để trống — script dùng giá trị mặc định trùng param set seed.
+func systemRuleTestCases(systemOrgID primitive.ObjectID) []models.RuleTestCase {
+	cases := []models.RuleTestCase{
+		// ---- Ads kill SL-A ----
+		{
+			TestCaseID: "TC_ADS_KILL_SL_A_NEW_FILTERED", LogicID: "LOGIC_ADS_KILL_SL_A",
+			Name:               "Campaign NEW không đề xuất kill",
+			Layers:             map[string]interface{}{"layer1": map[string]interface{}{"lifecycle": "NEW", "spendPct_7d": 0.9, "runtimeMinutes": 600}},
+			EntityRef:          models.EntityRef{Domain: "ads", ObjectType: "campaign", ObjectID: "test"},
+			ExpectedOutput:     nil,
+			MatchMode:          models.TestMatchExact,
+			ExpectedReportKeys: []string{"log", "result"},
+		},
+		{
+			TestCaseID: "TC_ADS_KILL_SL_A_CONV_RATE_EXCEPTION", LogicID: "LOGIC_ADS_KILL_SL_A",
+			Name: "Conv rate Pancake > 20% không kill dù CPA mess cao",
+			Layers: map[string]interface{}{
+				"layer1": map[string]interface{}{"lifecycle": "STABLE", "convRate_7d": 0.3, "spendPct_7d": 0.5, "runtimeMinutes": 180, "cpaMess_7d": 250000, "mqs_7d": 0.5},
+				"raw":    map[string]interface{}{"meta": map[string]interface{}{"mess": 1}},
+			},
+			EntityRef:      models.EntityRef{Domain: "ads", ObjectType: "campaign", ObjectID: "test"},
+			ExpectedOutput: nil,
+			MatchMode:      models.TestMatchExact,
+		},
+		{
+			TestCaseID: "TC_ADS_KILL_SL_A_MQS_DECREASE", LogicID: "LOGIC_ADS_KILL_SL_A",
+			Name: "MQS >= 2 nhường cho sl_a_decrease",
+			Layers: map[string]interface{}{
+				"layer1": map[string]interface{}{"lifecycle": "STABLE", "convRate_7d": 0.05, "spendPct_7d": 0.5, "runtimeMinutes": 180, "cpaMess_7d": 250000, "mqs_7d": 2.5},
+				"raw":    map[string]interface{}{"meta": map[string]interface{}{"mess": 1}},
+			},
+			EntityRef:      models.EntityRef{Domain: "ads", ObjectType: "campaign", ObjectID: "test"},
+			ExpectedOutput: nil,
+			MatchMode:      models.TestMatchExact,
+		},
+		{
+			TestCaseID: "TC_ADS_KILL_SL_A_MATCH_PAUSE", LogicID: "LOGIC_ADS_KILL_SL_A",
+			Name: "CPA mess cao, mess < 3, MQS < 1 → PAUSE",
+			Layers: map[string]interface{}{
+				"layer1": map[string]interface{}{"lifecycle": "STABLE", "convRate_7d": 0.05, "spendPct_7d": 0.5, "runtimeMinutes": 180, "cpaMess_7d": 250000, "mqs_7d": 0.5},
+				"raw":    map[string]interface{}{"meta": map[string]interface{}{"mess": 1}},
+			},
+			EntityRef:          models.EntityRef{Domain: "ads", ObjectType: "campaign", ObjectID: "test"},
+			ExpectedOutput:     map[string]interface{}{"action_code": "PAUSE", "ruleCode": "sl_a"},
+			MatchMode:          models.TestMatchPartial,
+			ExpectedReportKeys: []string{"log", "result"},
+		},
+
+		// ---- CRM classification ----
+		{
+			TestCaseID: "TC_CRM_CLASSIFICATION_VIP_OMNICHANNEL", LogicID: "LOGIC_CRM_CLASSIFICATION",
+			Name: "Khách chi tiêu lớn, mua cả online/offline → top, repeat, omnichannel, core, rising",
+			Layers: map[string]interface{}{"raw": map[string]interface{}{
+				"totalSpent": 60000000, "orderCount": 6, "revenueLast30d": 20000000, "revenueLast90d": 30000000,
+				"orderCountOnline": 3, "orderCountOffline": 3, "hasConversation": true,
+			}},
+			EntityRef: models.EntityRef{Domain: "crm", ObjectType: "customer", ObjectID: "test"},
+			ExpectedOutput: map[string]interface{}{
+				"valueTier": "top", "journeyStage": "repeat", "channel": "omnichannel", "loyaltyStage": "core", "momentumStage": "rising",
+			},
+			MatchMode:          models.TestMatchPartial,
+			ExpectedReportKeys: []string{"log"},
+		},
+		{
+			TestCaseID: "TC_CRM_CLASSIFICATION_SPAM_VISITOR", LogicID: "LOGIC_CRM_CLASSIFICATION",
+			Name: "Chưa mua, hội thoại gắn tag spam → blocked_spam",
+			Layers: map[string]interface{}{"raw": map[string]interface{}{
+				"orderCount": 0, "hasConversation": true, "conversationTags": []interface{}{"Spam"},
+			}},
+			EntityRef: models.EntityRef{Domain: "crm", ObjectType: "customer", ObjectID: "test"},
+			ExpectedOutput: map[string]interface{}{
+				"valueTier": "new", "lifecycleStage": "", "journeyStage": "blocked_spam", "channel": "", "loyaltyStage": "", "momentumStage": "lost",
+			},
+			MatchMode: models.TestMatchExact,
+		},
+		{
+			TestCaseID: "TC_CRM_CLASSIFICATION_ENGAGED", LogicID: "LOGIC_CRM_CLASSIFICATION",
+			Name:           "Chưa mua, có hội thoại → engaged",
+			Layers:         map[string]interface{}{"raw": map[string]interface{}{"orderCount": 0, "hasConversation": true}},
+			EntityRef:      models.EntityRef{Domain: "crm", ObjectType: "customer", ObjectID: "test"},
+			ExpectedOutput: map[string]interface{}{"valueTier": "new", "journeyStage": "engaged", "loyaltyStage": ""},
+			MatchMode:      models.TestMatchPartial,
+		},
+
+		// ---- CIX ----
+		{
+			TestCaseID: "TC_CIX_LAYER1_NEW", LogicID: "LOGIC_CIX_LAYER1_STAGE",
+			Name:               "Chưa có turn → new",
+			Layers:             map[string]interface{}{"cix_raw": map[string]interface{}{"turns": []interface{}{}}},
+			ExpectedOutput:     map[string]interface{}{"stage": "new"},
+			MatchMode:          models.TestMatchExact,
+			ExpectedReportKeys: []string{"input", "log"},
+		},
+		{
+			TestCaseID: "TC_CIX_LAYER1_CONSULTING", LogicID: "LOGIC_CIX_LAYER1_STAGE",
+			Name: "Từ 3 turn → consulting",
+			Layers: map[string]interface{}{"cix_raw": map[string]interface{}{"turns": []interface{}{
+				map[string]interface{}{"from": "customer"}, map[string]interface{}{"from": "page"}, map[string]interface{}{"from": "customer"},
+			}}},
+			ExpectedOutput: map[string]interface{}{"stage": "consulting"},
+			MatchMode:      models.TestMatchExact,
+		},
+		{
+			TestCaseID: "TC_CIX_LAYER2_ADJUST_VIP", LogicID: "LOGIC_CIX_LAYER2_ADJUST",
+			Name: "Khách VIP + warning → danger",
+			Layers: map[string]interface{}{
+				"cix_layer2":           map[string]interface{}{"riskLevelRaw": "warning"},
+				"cix_customer_context": map[string]interface{}{"valueTier": "top"},
+			},
+			ExpectedOutput: map[string]interface{}{"riskLevelAdj": "danger", "adjustmentReason": "vip_customer_complaint", "ruleId": "ADJUST_RISK_VIP_v1"},
+			MatchMode:      models.TestMatchExact,
+		},
+		{
+			TestCaseID: "TC_CIX_LAYER2_ADJUST_NON_VIP", LogicID: "LOGIC_CIX_LAYER2_ADJUST",
+			Name: "Khách thường giữ nguyên risk",
+			Layers: map[string]interface{}{
+				"cix_layer2":           map[string]interface{}{"riskLevelRaw": "warning"},
+				"cix_customer_context": map[string]interface{}{"valueTier": "low"},
+			},
+			ExpectedOutput: map[string]interface{}{"riskLevelAdj": "warning", "adjustmentReason": "", "ruleId": ""},
+			MatchMode:      models.TestMatchExact,
+		},
+		{
+			TestCaseID: "TC_CIX_FLAGS_VIP_AT_RISK", LogicID: "LOGIC_CIX_FLAGS",
+			Name: "VIP + danger → flag vip_at_risk",
+			Layers: map[string]interface{}{
+				"cix_layer2_adj":       map[string]interface{}{"riskLevelAdj": "danger"},
+				"cix_customer_context": map[string]interface{}{"valueTier": "high"},
+			},
+			ExpectedOutput: map[string]interface{}{"flags": []interface{}{
+				map[string]interface{}{"name": "vip_at_risk", "severity": "critical"},
+			}},
+			MatchMode: models.TestMatchPartial,
+		},
+		{
+			TestCaseID: "TC_CIX_ACTIONS_ESCALATE", LogicID: "LOGIC_CIX_ACTIONS",
+			Name:           "Flag vip_at_risk → escalate_to_senior",
+			Layers:         map[string]interface{}{"cix_flags": map[string]interface{}{"flags": []interface{}{map[string]interface{}{"name": "vip_at_risk"}}}},
+			ExpectedOutput: map[string]interface{}{"actionSuggestions": []interface{}{"escalate_to_senior"}},
+			MatchMode:      models.TestMatchExact,
+		},
+		{
+			TestCaseID: "TC_CIX_ACTIONS_NONE", LogicID: "LOGIC_CIX_ACTIONS",
+			Name:           "Không có flag → none",
+			Layers:         map[string]interface{}{"cix_flags": map[string]interface{}{"flags": []interface{}{}}},
+			ExpectedOutput: map[string]interface{}{"actionSuggestions": []interface{}{"none"}},
+			MatchMode:      models.TestMatchExact,
+		},
+	}
+	for i := range cases {
+		cases[i].OwnerOrganizationID = systemOrgID
+		cases[i].IsSystem = true
+	}
+	return cases
+}
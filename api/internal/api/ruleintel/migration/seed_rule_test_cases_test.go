@@ -0,0 +1,43 @@
+package migration
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"meta_commerce/internal/api/ruleintel/models"
+	"meta_commerce/internal/api/ruleintel/service"
+)
+
+// Test case seed phải pass với script seed — nếu không, init sẽ seed logic mà không publish được version mới.
+func TestSystemRuleTestCases_PassAgainstSeededScripts(t *testing.T) {
+	scripts := map[string]string{
+		"LOGIC_ADS_KILL_SL_A":      scriptSlA,
+		"LOGIC_CRM_CLASSIFICATION": scriptClassification,
+		"LOGIC_CIX_LAYER1_STAGE":   scriptCixLayer1Stage,
+		"LOGIC_CIX_LAYER2_ADJUST":  scriptCixLayer2Adjust,
+		"LOGIC_CIX_FLAGS":          scriptCixFlags,
+		"LOGIC_CIX_ACTIONS":        scriptCixActions,
+	}
+	byLogic := map[string][]models.RuleTestCase{}
+	for _, tc := range systemRuleTestCases(primitive.NilObjectID) {
+		byLogic[tc.LogicID] = append(byLogic[tc.LogicID], tc)
+	}
+	for logicID, cases := range byLogic {
+		script, ok := scripts[logicID]
+		if !ok {
+			t.Fatalf("test case trỏ tới logic không có trong seed: %s", logicID)
+		}
+		logic := &models.LogicScript{LogicID: logicID, LogicVersion: 1, EntryFunction: "evaluate", Script: script}
+		report, err := service.RunLogicTestCases(context.Background(), logic, cases, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", logicID, err)
+		}
+		for _, res := range report.Results {
+			if !res.Passed {
+				t.Errorf("%s / %s fail: %v %s", logicID, res.TestCaseID, res.Failures, res.Error)
+			}
+		}
+	}
+}
@@ -30,6 +30,44 @@ type OutputRef struct {
 	OutputVersion int   `json:"output_version" bson:"output_version"`
 }
 
+// Lifecycle của rule và version ứng viên.
+// Status của RuleDefinition: draft | active | retired. Stage của Rollout: draft | shadow | canary.
+const (
+	RuleStageDraft   = "draft"
+	RuleStageShadow  = "shadow"
+	RuleStageCanary  = "canary"
+	RuleStageActive  = "active"
+	RuleStageRetired = "retired"
+)
+
+// RuleVersionRefs bộ tham chiếu version của rule (logic + param + output) tại một thời điểm.
+type RuleVersionRefs struct {
+	RuleVersion int       `json:"rule_version" bson:"rule_version"`
+	LogicRef    LogicRef  `json:"logic_ref" bson:"logic_ref"`
+	ParamRef    ParamRef  `json:"param_ref" bson:"param_ref"`
+	OutputRef   OutputRef `json:"output_ref" bson:"output_ref"`
+	PromotedAt  int64     `json:"promoted_at,omitempty" bson:"promoted_at,omitempty"`
+	PromotedBy  string    `json:"promoted_by,omitempty" bson:"promoted_by,omitempty"`
+}
+
+// RuleRollout version ứng viên đang rollout song song version active.
+//   - draft: chỉ lưu, không chạy.
+//   - shadow: chạy song song version active, chỉ ghi trace (rollout_stage=shadow), không ảnh hưởng kết quả.
+//   - canary: entity thuộc CanaryPercent (hash ổn định theo entity) hoặc org trong CanaryOrgIDs dùng version ứng viên.
+type RuleRollout struct {
+	Stage         string          `json:"stage" bson:"stage"`
+	Candidate     RuleVersionRefs `json:"candidate" bson:"candidate"`
+	CanaryPercent int             `json:"canary_percent,omitempty" bson:"canary_percent,omitempty"`
+	CanaryOrgIDs  []string        `json:"canary_org_ids,omitempty" bson:"canary_org_ids,omitempty"`
+	UpdatedAt     int64           `json:"updated_at" bson:"updated_at"`
+	UpdatedBy     string          `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+}
+
+// ActiveRefs bộ tham chiếu version đang active của rule.
+func (r *RuleDefinition) ActiveRefs() RuleVersionRefs {
+	return RuleVersionRefs{RuleVersion: r.RuleVersion, LogicRef: r.LogicRef, ParamRef: r.ParamRef, OutputRef: r.OutputRef}
+}
+
 // RuleDefinition document lưu trong collection rule_definitions.
 type RuleDefinition struct {
 	ID         primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
@@ -48,6 +86,8 @@ type RuleDefinition struct {
 	OwnerOrganizationID    primitive.ObjectID `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1"` // System Org = rule hệ thống (seed)
 	IsSystem               bool               `json:"-" bson:"isSystem" index:"single:1"`                               // true = dữ liệu hệ thống, không thể xóa
 	Metadata               map[string]string  `json:"metadata,omitempty" bson:"metadata,omitempty"`
+	Rollout                *RuleRollout       `json:"rollout,omitempty" bson:"rollout,omitempty"`                 // Version ứng viên (draft/shadow/canary)
+	VersionHistory         []RuleVersionRefs  `json:"version_history,omitempty" bson:"version_history,omitempty"` // Các version active trước đó — rollback lấy phần tử cuối
 	CreatedAt              int64              `json:"createdAt" bson:"createdAt"`
 	UpdatedAt              int64              `json:"updatedAt" bson:"updatedAt"`
 }
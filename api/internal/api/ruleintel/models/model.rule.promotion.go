@@ -0,0 +1,30 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Action của RulePromotionAudit.
+const (
+	RulePromotionActionStage    = "stage"
+	RulePromotionActionPromote  = "promote"
+	RulePromotionActionRollback = "rollback"
+	RulePromotionActionRetire   = "retire"
+)
+
+// RulePromotionAudit document lưu trong collection rule_run_promotion_audits.
+// Mỗi lần đổi stage / promote / rollback / retire một rule đều ghi lại người thực hiện.
+type RulePromotionAudit struct {
+	ID                  primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	RuleID              string             `json:"rule_id" bson:"rule_id" index:"single:1"`
+	Domain              string             `json:"domain" bson:"domain"`
+	Action              string             `json:"action" bson:"action"`
+	FromStage           string             `json:"from_stage" bson:"from_stage"`
+	ToStage             string             `json:"to_stage" bson:"to_stage"`
+	FromRefs            *RuleVersionRefs   `json:"from_refs,omitempty" bson:"from_refs,omitempty"`
+	ToRefs              *RuleVersionRefs   `json:"to_refs,omitempty" bson:"to_refs,omitempty"`
+	CanaryPercent       int                `json:"canary_percent,omitempty" bson:"canary_percent,omitempty"`
+	CanaryOrgIDs        []string           `json:"canary_org_ids,omitempty" bson:"canary_org_ids,omitempty"`
+	Reason              string             `json:"reason,omitempty" bson:"reason,omitempty"`
+	UserID              string             `json:"user_id" bson:"user_id" index:"single:1"`
+	OwnerOrganizationID primitive.ObjectID `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1"`
+	CreatedAt           int64              `json:"createdAt" bson:"createdAt" index:"single:-1"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// MatchMode của RuleTestCase.
+const (
+	TestMatchExact   = "exact"   // Output phải bằng đúng expected_output
+	TestMatchPartial = "partial" // Chỉ so các key có trong expected_output (đệ quy), key thừa trong output bỏ qua
+)
+
+// RuleTestCase document lưu trong collection rule_cfg_test_cases.
+// Test case gắn với Logic Script: input (layers, params, entity_ref) và kỳ vọng về output / report.
+// Publish (status=active) một version Logic Script bị chặn khi có test case fail.
+type RuleTestCase struct {
+	ID           primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	TestCaseID   string             `json:"test_case_id" bson:"test_case_id" index:"single:1"`
+	LogicID      string             `json:"logic_id" bson:"logic_id" index:"single:1"`
+	LogicVersion int                `json:"logic_version" bson:"logic_version"` // 0 = áp dụng mọi version của logic
+	Name         string             `json:"name" bson:"name"`
+	Description  string             `json:"description,omitempty" bson:"description,omitempty"`
+	// Input
+	Layers       map[string]interface{} `json:"layers" bson:"layers"`
+	ParamSetID   string                 `json:"param_set_id,omitempty" bson:"param_set_id,omitempty"` // Load params từ param set rồi merge Params lên trên
+	ParamVersion int                    `json:"param_version,omitempty" bson:"param_version,omitempty"`
+	Params       map[string]interface{} `json:"params,omitempty" bson:"params,omitempty"`
+	EntityRef    EntityRef              `json:"entity_ref" bson:"entity_ref"`
+	// Kỳ vọng
+	ExpectedOutput     interface{} `json:"expected_output" bson:"expected_output"`
+	MatchMode          string      `json:"match_mode" bson:"match_mode"` // exact | partial (mặc định exact)
+	ExpectedReportKeys []string    `json:"expected_report_keys,omitempty" bson:"expected_report_keys,omitempty"`
+
+	OwnerOrganizationID primitive.ObjectID `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1"`
+	IsSystem            bool               `json:"-" bson:"isSystem" index:"single:1"` // true = dữ liệu hệ thống, không thể xóa
+	CreatedAt           int64              `json:"createdAt" bson:"createdAt"`
+	UpdatedAt           int64              `json:"updatedAt" bson:"updatedAt"`
+}
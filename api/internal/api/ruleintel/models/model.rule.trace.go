@@ -12,6 +12,16 @@ type EntityRef struct {
 	OwnerOrganizationID  string `json:"ownerOrganizationId" bson:"ownerOrganizationId"`
 }
 
+// ExecutionStatus của RuleExecutionTrace.
+const (
+	TraceStatusSuccess = "success"
+	TraceStatusError   = "error"
+	// TraceStatusTimeout script bị ngắt do quá thời gian (ScriptTimeout hoặc deadline ctx caller).
+	TraceStatusTimeout = "timeout"
+	// TraceStatusLimitExceeded script bị ngắt do vượt trần bộ nhớ / ngân sách lệnh / ctx bị hủy.
+	TraceStatusLimitExceeded = "limit_exceeded"
+)
+
 // RuleExecutionTrace document lưu trong collection rule_execution_logs.
 // Full rule execution trace cho mỗi lần chạy — phục vụ debugging, audit, observability.
 type RuleExecutionTrace struct {
@@ -32,4 +42,10 @@ type RuleExecutionTrace struct {
 	ExecutionTime      int64                  `json:"execution_time" bson:"execution_time"`
 	Timestamp          int64                  `json:"timestamp" bson:"timestamp" index:"single:1"`
 	EntityRef          EntityRef              `json:"entity_ref" bson:"entity_ref"`
+	// RolloutStage version nào của rule đã chạy: active | canary | shadow (rỗng = trace trước khi có rollout).
+	RolloutStage string `json:"rollout_stage,omitempty" bson:"rollout_stage,omitempty" index:"single:1"`
+	// ShadowOfTraceID trace của version active chạy cùng lượt (chỉ có ở trace shadow).
+	ShadowOfTraceID string `json:"shadow_of_trace_id,omitempty" bson:"shadow_of_trace_id,omitempty"`
+	// ShadowDiffKeys key output khác với version active (chỉ có ở trace shadow).
+	ShadowDiffKeys []string `json:"shadow_diff_keys,omitempty" bson:"shadow_diff_keys,omitempty"`
 }
@@ -24,6 +24,25 @@ func Register(v1 fiber.Router, r *apirouter.Router) error {
 	}
 	apirouter.RegisterRouteWithMiddleware(v1, "/rule-intelligence/run", "POST", "", []fiber.Handler{actionMiddleware, orgContextMiddleware}, runHandler)
 
+	// Dry-run / replay logic-param ứng viên trên trace lịch sử — không ghi trace, chỉ trả diff.
+	// Chạy script tùy ý trên dữ liệu trace → cùng quyền với sửa Logic Script.
+	replayHandler, err := ruleintelhdl.NewReplayRuleHandler()
+	if err != nil {
+		return fmt.Errorf("tạo ReplayRuleHandler: %w", err)
+	}
+	apirouter.RegisterRouteWithMiddleware(v1, "/rule-intelligence/replay", "POST", "", []fiber.Handler{middleware.AuthMiddleware("LogicScript.Update"), orgContextMiddleware}, replayHandler)
+
+	// Promotion workflow — stage draft/shadow/canary, promote active, retire, rollback và audit theo user
+	rolloutHandler, err := ruleintelhdl.NewRuleRolloutHandler()
+	if err != nil {
+		return fmt.Errorf("tạo RuleRolloutHandler: %w", err)
+	}
+	ruleUpdateMiddleware := middleware.AuthMiddleware("RuleDefinition.Update")
+	ruleReadMiddleware := middleware.AuthMiddleware("RuleDefinition.Read")
+	apirouter.RegisterRouteWithMiddleware(v1, "/rule-intelligence/rollout", "POST", "/:ruleId/stage", []fiber.Handler{ruleUpdateMiddleware, orgContextMiddleware}, rolloutHandler.HandleSetStage)
+	apirouter.RegisterRouteWithMiddleware(v1, "/rule-intelligence/rollout", "POST", "/:ruleId/rollback", []fiber.Handler{ruleUpdateMiddleware, orgContextMiddleware}, rolloutHandler.HandleRollback)
+	apirouter.RegisterRouteWithMiddleware(v1, "/rule-intelligence/rollout", "GET", "/:ruleId/audits", []fiber.Handler{ruleReadMiddleware, orgContextMiddleware}, rolloutHandler.HandleListAudits)
+
 	// Xem rule execution log theo trace_id — link từ proposal "Xem log tạo đề xuất"
 	logHandler, err := ruleintelhdl.NewGetTraceLogHandler()
 	if err != nil {
@@ -48,11 +67,19 @@ func Register(v1 fiber.Router, r *apirouter.Router) error {
 	if err != nil {
 		return fmt.Errorf("tạo OutputContractHandler: %w", err)
 	}
+	testCaseHandler, err := ruleintelhdl.NewRuleTestCaseHandler()
+	if err != nil {
+		return fmt.Errorf("tạo RuleTestCaseHandler: %w", err)
+	}
+
+	// Chạy test case của một version logic (script đã lưu hoặc bản đang soạn)
+	apirouter.RegisterRouteWithMiddleware(v1, "/rule-intelligence/test-case", "POST", "/run", []fiber.Handler{middleware.AuthMiddleware("RuleTestCase.Read"), orgContextMiddleware}, testCaseHandler.HandleRunTests)
 
 	r.RegisterCRUDRoutes(v1, "/rule-intelligence/definition", defHandler, apirouter.ReadWriteConfig, "RuleDefinition")
-	r.RegisterCRUDRoutes(v1, "/rule-intelligence/logic", logicHandler, apirouter.ReadWriteConfig, "LogicScript")
+	r.RegisterCRUDRoutes(v1, "/rule-intelligence/logic", logicHandler, apirouter.GatedWriteConfig, "LogicScript")
 	r.RegisterCRUDRoutes(v1, "/rule-intelligence/param-set", paramHandler, apirouter.ReadWriteConfig, "ParamSet")
 	r.RegisterCRUDRoutes(v1, "/rule-intelligence/output-contract", outputHandler, apirouter.ReadWriteConfig, "OutputContract")
+	r.RegisterCRUDRoutes(v1, "/rule-intelligence/test-case", testCaseHandler, apirouter.ReadWriteConfig, "RuleTestCase")
 
 	return nil
 }
@@ -0,0 +1,264 @@
+// Package service — Cache in-process cho Rule Engine.
+//
+// Cache definition / logic / param set / output contract và program goja đã biên dịch (kèm pool VM warm).
+// Invalidate qua events.OnDataChanged khi document thay đổi trong process; TTL chặn dữ liệu cũ khi instance khác sửa Mongo.
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"meta_commerce/internal/api/events"
+	"meta_commerce/internal/api/ruleintel/engine"
+	"meta_commerce/internal/api/ruleintel/models"
+	"meta_commerce/internal/global"
+	"meta_commerce/internal/worker/metrics"
+)
+
+const (
+	// ruleCacheTTL thời gian sống entry definition / logic / param / output.
+	ruleCacheTTL = 60 * time.Second
+)
+
+type ttlEntry[T any] struct {
+	val       T
+	expiresAt time.Time
+}
+
+// ttlCache map có TTL + đếm hit/miss. ttl = 0: không hết hạn (chỉ xóa khi invalidate).
+type ttlCache[T any] struct {
+	ttl   time.Duration
+	mu    sync.RWMutex
+	items map[string]ttlEntry[T]
+
+	hits, misses, invalidations atomic.Int64
+}
+
+func newTTLCache[T any](ttl time.Duration) *ttlCache[T] {
+	return &ttlCache[T]{ttl: ttl, items: make(map[string]ttlEntry[T])}
+}
+
+func (c *ttlCache[T]) get(key string) (T, bool) {
+	c.mu.RLock()
+	e, ok := c.items[key]
+	c.mu.RUnlock()
+	if ok && (c.ttl == 0 || time.Now().Before(e.expiresAt)) {
+		c.hits.Add(1)
+		return e.val, true
+	}
+	c.misses.Add(1)
+	var zero T
+	return zero, false
+}
+
+func (c *ttlCache[T]) set(key string, val T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items[key] = ttlEntry[T]{val: val, expiresAt: time.Now().Add(c.ttl)}
+}
+
+func (c *ttlCache[T]) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items = make(map[string]ttlEntry[T])
+	c.invalidations.Add(1)
+}
+
+func (c *ttlCache[T]) size() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.items)
+}
+
+func (c *ttlCache[T]) metric() metrics.CacheMetric {
+	return metrics.CacheMetric{
+		Hits:          c.hits.Load(),
+		Misses:        c.misses.Load(),
+		Invalidations: c.invalidations.Load(),
+		Size:          c.size(),
+	}
+}
+
+// ruleEngineCache cache dùng chung cho mọi RuleEngineService trong process
+// (các module gọi NewRuleEngineService theo từng lần chạy nên cache không thể nằm trên instance service).
+type ruleEngineCache struct {
+	executor *engine.ScriptExecutor
+
+	rules    *ttlCache[*models.RuleDefinition]
+	logics   *ttlCache[*models.LogicScript]
+	params   *ttlCache[map[string]interface{}]
+	outputs  *ttlCache[*models.OutputContract]
+	programs *ttlCache[*engine.ProgramPool]
+
+	// compileMu tránh nhiều goroutine biên dịch cùng một script khi cache miss đồng thời.
+	compileMu sync.Mutex
+}
+
+var (
+	defaultRuleCache     *ruleEngineCache
+	defaultRuleCacheOnce sync.Once
+)
+
+// getRuleEngineCache trả về cache dùng chung, khởi tạo và đăng ký metrics ở lần gọi đầu.
+func getRuleEngineCache() *ruleEngineCache {
+	defaultRuleCacheOnce.Do(func() {
+		c := &ruleEngineCache{
+			executor: engine.NewScriptExecutor("evaluate"),
+			rules:    newTTLCache[*models.RuleDefinition](ruleCacheTTL),
+			logics:   newTTLCache[*models.LogicScript](ruleCacheTTL),
+			params:   newTTLCache[map[string]interface{}](ruleCacheTTL),
+			outputs:  newTTLCache[*models.OutputContract](ruleCacheTTL),
+			programs: newTTLCache[*engine.ProgramPool](0),
+		}
+		metrics.RegisterCacheMetrics("ruleintel.rule_definitions", c.rules.metric)
+		metrics.RegisterCacheMetrics("ruleintel.logic_scripts", c.logics.metric)
+		metrics.RegisterCacheMetrics("ruleintel.param_sets", c.params.metric)
+		metrics.RegisterCacheMetrics("ruleintel.output_contracts", c.outputs.metric)
+		metrics.RegisterCacheMetrics("ruleintel.programs", c.programMetric)
+		defaultRuleCache = c
+	})
+	return defaultRuleCache
+}
+
+// programMetric thống kê program cache kèm tổng số lần tái sử dụng VM của các pool.
+func (c *ruleEngineCache) programMetric() metrics.CacheMetric {
+	m := c.programs.metric()
+	var agg engine.PoolStats
+	c.programs.mu.RLock()
+	for _, e := range c.programs.items {
+		st := e.val.Stats()
+		agg.Reused += st.Reused
+		agg.Created += st.Created
+		agg.Discarded += st.Discarded
+	}
+	c.programs.mu.RUnlock()
+	m.Extra = map[string]int64{"vmReused": agg.Reused, "vmCreated": agg.Created, "vmDiscarded": agg.Discarded}
+	return m
+}
+
+// program trả về pool cho script, biên dịch nếu chưa có. Khóa theo logicID + version + hash nội dung,
+// nên sửa script tại chỗ (cùng version) vẫn được biên dịch lại.
+func (c *ruleEngineCache) program(logic *models.LogicScript) (*engine.ProgramPool, error) {
+	sum := sha256.Sum256([]byte(logic.Script))
+	key := fmt.Sprintf("%s:%d:%s", logic.LogicID, logic.LogicVersion, hex.EncodeToString(sum[:8]))
+	if p, ok := c.programs.get(key); ok {
+		return p, nil
+	}
+	c.compileMu.Lock()
+	defer c.compileMu.Unlock()
+	c.programs.mu.RLock()
+	e, ok := c.programs.items[key]
+	c.programs.mu.RUnlock()
+	if ok {
+		return e.val, nil
+	}
+	prg, err := c.executor.Compile(logic.Script)
+	if err != nil {
+		return nil, err
+	}
+	p := engine.NewProgramPool(c.executor, prg)
+	c.programs.set(key, p)
+	return p, nil
+}
+
+// invalidateCollection xóa cache tương ứng collection vừa thay đổi.
+func (c *ruleEngineCache) invalidateCollection(collection string) {
+	switch collection {
+	case global.MongoDB_ColNames.RuleDefinitions:
+		c.rules.purge()
+	case global.MongoDB_ColNames.RuleLogicDefinitions:
+		c.logics.purge()
+		c.programs.purge()
+	case global.MongoDB_ColNames.RuleParamSets:
+		c.params.purge()
+	case global.MongoDB_ColNames.RuleOutputDefinitions:
+		c.outputs.purge()
+	}
+}
+
+// InvalidateRuleEngineCache xóa toàn bộ cache Rule Engine (definition, logic, param, output, program).
+func InvalidateRuleEngineCache() {
+	c := getRuleEngineCache()
+	c.rules.purge()
+	c.logics.purge()
+	c.params.purge()
+	c.outputs.purge()
+	c.programs.purge()
+}
+
+// RegisterRuleEngineCacheInvalidation đăng ký OnDataChanged để invalidate cache khi LogicScript / ParamSet /
+// RuleDefinition / OutputContract đổi qua BaseServiceMongoImpl (gọi một lần từ init.registry).
+func RegisterRuleEngineCacheInvalidation() {
+	c := getRuleEngineCache()
+	events.OnDataChanged(func(ctx context.Context, e events.DataChangeEvent) {
+		c.invalidateCollection(e.CollectionName)
+	})
+}
+
+// cloneParams bản sao sâu param set lấy từ cache — caller / script không sửa được map lồng nhau dùng chung.
+func cloneParams(src map[string]interface{}) map[string]interface{} {
+	if src == nil {
+		return map[string]interface{}{}
+	}
+	return deepCopyMap(src)
+}
+
+// deepCopyMap bản sao sâu map JSON-like (map / slice lồng nhau); giá trị khác giữ nguyên.
+func deepCopyMap(src map[string]interface{}) map[string]interface{} {
+	if src == nil {
+		return nil
+	}
+	dst := make(map[string]interface{}, len(src))
+	for k, v := range src {
+		dst[k] = deepCopyValue(v)
+	}
+	return dst
+}
+
+func deepCopyValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		return deepCopyMap(t)
+	case bson.M:
+		return bson.M(deepCopyMap(t))
+	case []interface{}:
+		return deepCopySlice(t)
+	case primitive.A:
+		return primitive.A(deepCopySlice(t))
+	case []map[string]interface{}:
+		out := make([]map[string]interface{}, len(t))
+		for i := range t {
+			out[i] = deepCopyMap(t[i])
+		}
+		return out
+	case bson.D:
+		out := make(bson.D, len(t))
+		for i, e := range t {
+			out[i] = bson.E{Key: e.Key, Value: deepCopyValue(e.Value)}
+		}
+		return out
+	case []string:
+		return append([]string(nil), t...)
+	default:
+		return v
+	}
+}
+
+func deepCopySlice(src []interface{}) []interface{} {
+	if src == nil {
+		return nil
+	}
+	dst := make([]interface{}, len(src))
+	for i, v := range src {
+		dst[i] = deepCopyValue(v)
+	}
+	return dst
+}
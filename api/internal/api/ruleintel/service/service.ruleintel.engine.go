@@ -7,6 +7,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,16 +16,17 @@ import (
 	"meta_commerce/internal/api/ruleintel/engine"
 	"meta_commerce/internal/api/ruleintel/models"
 	"meta_commerce/internal/common"
+	"meta_commerce/internal/logger"
 )
 
 // RuleEngineService service chạy Rule Engine.
 type RuleEngineService struct {
-	executor       *engine.ScriptExecutor
-	ruleSvc        *RuleDefinitionService
-	logicSvc       *LogicScriptService
-	paramSvc       *ParamSetService
-	outputSvc      *OutputContractService
-	traceSvc       *RuleExecutionTraceService
+	cache     *ruleEngineCache
+	ruleSvc   *RuleDefinitionService
+	logicSvc  *LogicScriptService
+	paramSvc  *ParamSetService
+	outputSvc *OutputContractService
+	traceSvc  *RuleExecutionTraceService
 }
 
 // NewRuleEngineService tạo service.
@@ -50,7 +52,7 @@ func NewRuleEngineService() (*RuleEngineService, error) {
 		return nil, fmt.Errorf("RuleExecutionTraceService: %w", err)
 	}
 	return &RuleEngineService{
-		executor:  engine.NewScriptExecutor("evaluate"),
+		cache:     getRuleEngineCache(),
 		ruleSvc:   ruleSvc,
 		logicSvc:  logicSvc,
 		paramSvc:  paramSvc,
@@ -71,6 +73,7 @@ type RunInput struct {
 }
 
 // Run chạy rule theo rule_id, trả về output và report.
+// Rule đang rollout: canary → entity thuộc nhóm canary chạy version ứng viên; shadow → version ứng viên chạy nền, chỉ ghi trace.
 func (s *RuleEngineService) Run(ctx context.Context, input *RunInput) (*engine.RunResult, error) {
 	// 1. Load Rule Definition
 	rule, err := s.loadRule(ctx, input.RuleID, input.Domain)
@@ -78,16 +81,40 @@ func (s *RuleEngineService) Run(ctx context.Context, input *RunInput) (*engine.R
 		return nil, err
 	}
 
-	// 2. Load Logic Script
-	logic, err := s.loadLogic(ctx, rule.LogicRef.LogicID, rule.LogicRef.LogicVersion)
+	refs, stage := resolveRolloutRefs(rule, input.EntityRef)
+	// Shadow chạy trên bản sao input chụp trước lần chạy active — script có thể sửa layers, caller có thể dùng lại map.
+	var shadowInput *RunInput
+	if !input.SkipTrace && rule.Rollout != nil && rule.Rollout.Stage == models.RuleStageShadow {
+		shadowInput = cloneRunInput(input)
+	}
+	result, trace, err := s.execute(ctx, rule, refs, stage, input)
+	if trace != nil && !input.SkipTrace {
+		if err := s.saveTrace(ctx, trace); err != nil {
+			// Log nhưng không fail
+			_ = err
+		}
+		if shadowInput != nil {
+			s.startShadow(context.WithoutCancel(ctx), rule, shadowInput, trace)
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
+	return result, nil
+}
+
+// execute resolve logic/param/output theo refs, chạy script và dựng trace (chưa ghi). trace nil khi lỗi trước lúc chạy script.
+func (s *RuleEngineService) execute(ctx context.Context, rule *models.RuleDefinition, refs models.RuleVersionRefs, stage string, input *RunInput) (*engine.RunResult, *models.RuleExecutionTrace, error) {
+	// 2. Load Logic Script — version ứng viên được chạy dù logic chưa active
+	logic, err := s.loadLogic(ctx, refs.LogicRef.LogicID, refs.LogicRef.LogicVersion, stage != models.RuleStageActive)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	// 3. Load Parameter Set
-	params, err := s.loadParams(ctx, rule.ParamRef.ParamSetID, rule.ParamRef.ParamVersion)
+	params, err := s.loadParams(ctx, refs.ParamRef.ParamSetID, refs.ParamRef.ParamVersion)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Merge params_override
@@ -96,7 +123,7 @@ func (s *RuleEngineService) Run(ctx context.Context, input *RunInput) (*engine.R
 	}
 
 	// 4. Load Output Contract (để validate, có thể bỏ qua nếu chưa implement validation)
-	_, _ = s.loadOutput(ctx, rule.OutputRef.OutputID, rule.OutputRef.OutputVersion)
+	outputContract, _ := s.loadOutput(ctx, refs.OutputRef.OutputID, refs.OutputRef.OutputVersion)
 
 	// 5. Build EvalContext
 	evalCtx := &engine.EvalContext{
@@ -105,36 +132,42 @@ func (s *RuleEngineService) Run(ctx context.Context, input *RunInput) (*engine.R
 		EntityRef: input.EntityRef,
 	}
 
-	// 6. Run script
+	// 6. Run script — program đã biên dịch + VM warm từ cache
 	traceID := uuid.New().String()
-	evalResult, execTime, err := s.executor.Run(ctx, logic.Script, evalCtx)
+	start := time.Now()
+	var evalResult *engine.EvalResult
+	pool, err := s.cache.program(logic)
+	if err == nil {
+		evalResult, err = pool.Run(ctx, evalCtx)
+	}
+	execTime := time.Since(start).Milliseconds()
 
 	now := time.Now().UnixMilli()
-	status := "success"
+	status := traceStatusFromError(err)
 	errMsg := ""
 	if err != nil {
-		status = "error"
 		errMsg = err.Error()
 	}
 
-	// 7. Ghi trace — mọi lần chạy đều phải có explanation.log (audit, debug)
+	// 7. Dựng trace — mọi lần chạy đều phải có explanation.log (audit, debug)
 	trace := &models.RuleExecutionTrace{
 		TraceID:            traceID,
 		RuleID:             rule.RuleID,
-		RuleVersion:        rule.RuleVersion,
+		RuleVersion:        refs.RuleVersion,
 		LogicID:            logic.LogicID,
 		LogicVersion:       logic.LogicVersion,
-		ParamSetID:         rule.ParamRef.ParamSetID,
-		ParamVersion:       rule.ParamRef.ParamVersion,
-		InputSnapshot:     input.Layers,
+		ParamSetID:         refs.ParamRef.ParamSetID,
+		ParamVersion:       refs.ParamRef.ParamVersion,
+		InputSnapshot:      input.Layers,
 		ParametersSnapshot: params,
 		OutputObject:       nil,
 		ExecutionStatus:    status,
-		ErrorMessage:      errMsg,
-		Explanation:       nil,
-		ExecutionTime:     execTime,
-		Timestamp:         now,
-		EntityRef:         input.EntityRef,
+		ErrorMessage:       errMsg,
+		Explanation:        nil,
+		ExecutionTime:      execTime,
+		Timestamp:          now,
+		EntityRef:          input.EntityRef,
+		RolloutStage:       stage,
 	}
 
 	if evalResult != nil {
@@ -142,24 +175,17 @@ func (s *RuleEngineService) Run(ctx context.Context, input *RunInput) (*engine.R
 		trace.Explanation = evalResult.Report
 	} else if err != nil {
 		// Khi lỗi: vẫn ghi explanation để có log cho mọi lần chạy
-		trace.Explanation = map[string]interface{}{"log": errMsg, "result": "error"}
-	}
-
-	if !input.SkipTrace {
-		if err := s.saveTrace(ctx, trace); err != nil {
-			// Log nhưng không fail
-			_ = err
-		}
+		trace.Explanation = map[string]interface{}{"log": errMsg, "result": status}
 	}
 
 	if err != nil {
-		return nil, err
+		return nil, trace, err
 	}
 
 	// 8. Build RunResult
 	outputType := "action"
-	if oc, _ := s.loadOutput(ctx, rule.OutputRef.OutputID, rule.OutputRef.OutputVersion); oc != nil {
-		outputType = oc.OutputType
+	if outputContract != nil {
+		outputType = outputContract.OutputType
 	}
 
 	return &engine.RunResult{
@@ -172,12 +198,114 @@ func (s *RuleEngineService) Run(ctx context.Context, input *RunInput) (*engine.R
 		TraceID:      traceID,
 		LogicID:      logic.LogicID,
 		LogicVersion: logic.LogicVersion,
-		ParamSetID:   rule.ParamRef.ParamSetID,
-		ParamVersion: rule.ParamRef.ParamVersion,
-	}, nil
+		ParamSetID:   refs.ParamRef.ParamSetID,
+		ParamVersion: refs.ParamRef.ParamVersion,
+		RolloutStage: stage,
+	}, trace, nil
+}
+
+const (
+	// shadowRunTimeout giới hạn tổng thời gian chạy nền version shadow (load + script).
+	shadowRunTimeout = 10 * time.Second
+	// maxConcurrentShadowRuns số lần chạy shadow đồng thời tối đa trong process.
+	maxConcurrentShadowRuns = 8
+)
+
+// shadowSlots semaphore cho runShadow — hết slot thì bỏ lần chạy shadow (chỉ mất trace so sánh, không ảnh hưởng kết quả).
+var shadowSlots = make(chan struct{}, maxConcurrentShadowRuns)
+
+// startShadow chạy runShadow nền nếu còn slot; hết slot thì bỏ qua và ghi log.
+func (s *RuleEngineService) startShadow(ctx context.Context, rule *models.RuleDefinition, input *RunInput, activeTrace *models.RuleExecutionTrace) {
+	select {
+	case shadowSlots <- struct{}{}:
+	default:
+		logger.GetAppLogger().WithFields(map[string]interface{}{
+			"rule_id":  rule.RuleID,
+			"trace_id": activeTrace.TraceID,
+		}).Warn("[RuleEngine] Bỏ lần chạy shadow: đã đủ số lần chạy shadow đồng thời")
+		return
+	}
+	go func() {
+		defer func() { <-shadowSlots }()
+		s.runShadow(ctx, rule, input, activeTrace)
+	}()
+}
+
+// runShadow chạy version ứng viên shadow trên cùng input, ghi trace rollout_stage=shadow kèm key output khác version active.
+// Kết quả không trả về caller; lỗi chỉ nằm trong trace.
+func (s *RuleEngineService) runShadow(ctx context.Context, rule *models.RuleDefinition, input *RunInput, activeTrace *models.RuleExecutionTrace) {
+	ctx, cancel := context.WithTimeout(ctx, shadowRunTimeout)
+	defer cancel()
+	_, trace, _ := s.execute(ctx, rule, rule.Rollout.Candidate, models.RuleStageShadow, input)
+	if trace == nil {
+		return
+	}
+	trace.ShadowOfTraceID = activeTrace.TraceID
+	trace.ShadowDiffKeys = changedKeys(normalizeForReplay(activeTrace.OutputObject), normalizeForReplay(trace.OutputObject))
+	if err := s.saveTrace(ctx, trace); err != nil {
+		logger.GetAppLogger().WithError(err).WithFields(map[string]interface{}{
+			"rule_id":  rule.RuleID,
+			"trace_id": trace.TraceID,
+		}).Error("[RuleEngine] Ghi trace shadow thất bại")
+	}
+}
+
+// cloneRunInput bản sao sâu Layers / ParamsOverride của input (chạy shadow không dùng chung map với lần chạy active).
+func cloneRunInput(input *RunInput) *RunInput {
+	out := *input
+	out.Layers = deepCopyMap(input.Layers)
+	out.ParamsOverride = deepCopyMap(input.ParamsOverride)
+	return &out
+}
+
+// resolveRolloutRefs chọn version chạy cho entity: version ứng viên khi rule đang canary và entity thuộc nhóm canary,
+// ngược lại version active.
+func resolveRolloutRefs(rule *models.RuleDefinition, ref models.EntityRef) (models.RuleVersionRefs, string) {
+	ro := rule.Rollout
+	if ro == nil || ro.Stage != models.RuleStageCanary {
+		return rule.ActiveRefs(), models.RuleStageActive
+	}
+	for _, orgID := range ro.CanaryOrgIDs {
+		if orgID != "" && orgID == ref.OwnerOrganizationID {
+			return ro.Candidate, models.RuleStageCanary
+		}
+	}
+	key := ref.ObjectID
+	if key == "" {
+		key = ref.OwnerOrganizationID
+	}
+	if ro.CanaryPercent > 0 && canaryBucket(rule.RuleID, key) < ro.CanaryPercent {
+		return ro.Candidate, models.RuleStageCanary
+	}
+	return rule.ActiveRefs(), models.RuleStageActive
+}
+
+// canaryBucket bucket 0–99 ổn định theo rule + entity — cùng entity luôn rơi vào cùng nhóm khi tăng dần canary_percent.
+func canaryBucket(ruleID, key string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(ruleID + ":" + key))
+	return int(h.Sum32() % 100)
+}
+
+// traceStatusFromError map lỗi executor sang ExecutionStatus của trace — timeout/limit tách riêng để lọc và cảnh báo.
+func traceStatusFromError(err error) string {
+	switch {
+	case err == nil:
+		return models.TraceStatusSuccess
+	case engine.IsScriptTimeout(err):
+		return models.TraceStatusTimeout
+	case engine.IsScriptLimitError(err):
+		return models.TraceStatusLimitExceeded
+	default:
+		return models.TraceStatusError
+	}
 }
 
 func (s *RuleEngineService) loadRule(ctx context.Context, ruleID, domain string) (*models.RuleDefinition, error) {
+	key := ruleID + "|" + domain
+	if rule, ok := s.cache.rules.get(key); ok {
+		return rule, nil
+	}
 	filter := bson.M{"rule_id": ruleID, "domain": domain, "status": "active"}
 	rule, err := s.ruleSvc.FindOne(ctx, filter, nil)
 	if err != nil {
@@ -186,11 +314,21 @@ func (s *RuleEngineService) loadRule(ctx context.Context, ruleID, domain string)
 		}
 		return nil, err
 	}
+	s.cache.rules.set(key, &rule)
 	return &rule, nil
 }
 
-func (s *RuleEngineService) loadLogic(ctx context.Context, logicID string, logicVersion int) (*models.LogicScript, error) {
+// loadLogic load logic theo version; anyStatus true cho version ứng viên (shadow/canary) chưa active.
+func (s *RuleEngineService) loadLogic(ctx context.Context, logicID string, logicVersion int, anyStatus bool) (*models.LogicScript, error) {
+	key := fmt.Sprintf("%s:%d", logicID, logicVersion)
 	filter := bson.M{"logic_id": logicID, "logic_version": logicVersion, "status": "active"}
+	if anyStatus {
+		key += ":any"
+		delete(filter, "status")
+	}
+	if logic, ok := s.cache.logics.get(key); ok {
+		return logic, nil
+	}
 	logic, err := s.logicSvc.FindOne(ctx, filter, nil)
 	if err != nil {
 		if errors.Is(err, common.ErrNotFound) {
@@ -198,10 +336,16 @@ func (s *RuleEngineService) loadLogic(ctx context.Context, logicID string, logic
 		}
 		return nil, err
 	}
+	s.cache.logics.set(key, &logic)
 	return &logic, nil
 }
 
+// loadParams trả về bản sao parameters — caller được phép merge params_override.
 func (s *RuleEngineService) loadParams(ctx context.Context, paramSetID string, paramVersion int) (map[string]interface{}, error) {
+	key := fmt.Sprintf("%s:%d", paramSetID, paramVersion)
+	if params, ok := s.cache.params.get(key); ok {
+		return cloneParams(params), nil
+	}
 	filter := bson.M{"param_set_id": paramSetID, "param_version": paramVersion}
 	ps, err := s.paramSvc.FindOne(ctx, filter, nil)
 	if err != nil {
@@ -210,18 +354,25 @@ func (s *RuleEngineService) loadParams(ctx context.Context, paramSetID string, p
 		}
 		return nil, err
 	}
-	if ps.Parameters == nil {
-		return map[string]interface{}{}, nil
+	params := ps.Parameters
+	if params == nil {
+		params = map[string]interface{}{}
 	}
-	return ps.Parameters, nil
+	s.cache.params.set(key, params)
+	return cloneParams(params), nil
 }
 
 func (s *RuleEngineService) loadOutput(ctx context.Context, outputID string, outputVersion int) (*models.OutputContract, error) {
+	key := fmt.Sprintf("%s:%d", outputID, outputVersion)
+	if oc, ok := s.cache.outputs.get(key); ok {
+		return oc, nil
+	}
 	filter := bson.M{"output_id": outputID, "output_version": outputVersion}
 	oc, err := s.outputSvc.FindOne(ctx, filter, nil)
 	if err != nil {
 		return nil, nil
 	}
+	s.cache.outputs.set(key, &oc)
 	return &oc, nil
 }
 
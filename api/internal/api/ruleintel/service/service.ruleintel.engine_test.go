@@ -0,0 +1,84 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"meta_commerce/internal/api/ruleintel/models"
+)
+
+func newRolloutRule(ro *models.RuleRollout) *models.RuleDefinition {
+	return &models.RuleDefinition{
+		RuleID:      "RULE_TEST",
+		RuleVersion: 3,
+		LogicRef:    models.LogicRef{LogicID: "LOGIC_TEST", LogicVersion: 1},
+		ParamRef:    models.ParamRef{ParamSetID: "PARAM_TEST", ParamVersion: 1},
+		Rollout:     ro,
+	}
+}
+
+func TestResolveRolloutRefs_NoCanaryUsesActive(t *testing.T) {
+	candidate := models.RuleVersionRefs{LogicRef: models.LogicRef{LogicID: "LOGIC_TEST", LogicVersion: 2}}
+	for _, ro := range []*models.RuleRollout{nil, {Stage: models.RuleStageShadow, Candidate: candidate, CanaryPercent: 100}} {
+		refs, stage := resolveRolloutRefs(newRolloutRule(ro), models.EntityRef{ObjectID: "x"})
+		if stage != models.RuleStageActive || refs.LogicRef.LogicVersion != 1 {
+			t.Fatalf("rollout %+v phải chạy version active, got %s %+v", ro, stage, refs)
+		}
+	}
+}
+
+func TestResolveRolloutRefs_CanaryOrgAndPercent(t *testing.T) {
+	candidate := models.RuleVersionRefs{LogicRef: models.LogicRef{LogicID: "LOGIC_TEST", LogicVersion: 2}}
+	rule := newRolloutRule(&models.RuleRollout{Stage: models.RuleStageCanary, Candidate: candidate, CanaryOrgIDs: []string{"org-canary"}})
+	if _, stage := resolveRolloutRefs(rule, models.EntityRef{ObjectID: "a", OwnerOrganizationID: "org-canary"}); stage != models.RuleStageCanary {
+		t.Fatalf("org trong canary_org_ids phải chạy canary, got %s", stage)
+	}
+	if _, stage := resolveRolloutRefs(rule, models.EntityRef{ObjectID: "a", OwnerOrganizationID: "org-other"}); stage != models.RuleStageActive {
+		t.Fatalf("org ngoài canary với percent 0 phải chạy active, got %s", stage)
+	}
+
+	rule.Rollout.CanaryPercent = 30
+	canary := 0
+	for i := 0; i < 1000; i++ {
+		ref := models.EntityRef{ObjectID: fmt.Sprintf("entity-%d", i)}
+		_, first := resolveRolloutRefs(rule, ref)
+		if _, again := resolveRolloutRefs(rule, ref); again != first {
+			t.Fatalf("bucket canary phải ổn định theo entity")
+		}
+		if first == models.RuleStageCanary {
+			canary++
+		}
+	}
+	if canary < 200 || canary > 400 {
+		t.Fatalf("tỉ lệ canary lệch xa 30%%: %d/1000", canary)
+	}
+}
+
+func TestCloneRunInput_ShadowDoesNotShareLayers(t *testing.T) {
+	input := &RunInput{
+		RuleID: "RULE_TEST",
+		Layers: map[string]interface{}{
+			"raw": map[string]interface{}{"spend": 10, "tags": []interface{}{"a"}},
+		},
+	}
+	shadow := cloneRunInput(input)
+	input.Layers["raw"].(map[string]interface{})["spend"] = 99
+	input.Layers["raw"].(map[string]interface{})["tags"].([]interface{})[0] = "b"
+	raw := shadow.Layers["raw"].(map[string]interface{})
+	if raw["spend"] != 10 || raw["tags"].([]interface{})[0] != "a" {
+		t.Fatalf("layers của shadow phải là bản sao sâu, got %v", raw)
+	}
+}
+
+// Param set trong cache dùng chung giữa các lần chạy — bản sao phải sâu.
+func TestCloneParams_DeepCopy(t *testing.T) {
+	cached := map[string]interface{}{"tiers": []interface{}{map[string]interface{}{"min": 1}}}
+	cp := cloneParams(cached)
+	cp["tiers"].([]interface{})[0].(map[string]interface{})["min"] = 99
+	if cached["tiers"].([]interface{})[0].(map[string]interface{})["min"] != 1 {
+		t.Fatal("sửa bản sao làm đổi param set trong cache")
+	}
+	if cloneParams(nil) == nil {
+		t.Fatal("params nil → map rỗng")
+	}
+}
@@ -2,8 +2,14 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
 	basesvc "meta_commerce/internal/api/base/service"
 	"meta_commerce/internal/common"
 	"meta_commerce/internal/global"
@@ -11,6 +17,8 @@ import (
 )
 
 // LogicScriptService CRUD cho Logic Script.
+// Publish (status=active) một version bị chặn khi test case của logic fail — xem RuleTestCaseService.
+// Kiểm tra chỉ nằm ở InsertOne / UpdateOne / UpdateById / Upsert — route dùng apirouter.GatedWriteConfig.
 type LogicScriptService struct {
 	*basesvc.BaseServiceMongoImpl[models.LogicScript]
 }
@@ -25,3 +33,126 @@ func NewLogicScriptService() (*LogicScriptService, error) {
 		BaseServiceMongoImpl: basesvc.NewBaseServiceMongo[models.LogicScript](coll),
 	}, nil
 }
+
+// InsertOne override — tạo version active phải pass test case.
+func (s *LogicScriptService) InsertOne(ctx context.Context, data models.LogicScript) (models.LogicScript, error) {
+	if data.Status == models.RuleStageActive {
+		if err := s.checkTests(ctx, &data); err != nil {
+			return data, err
+		}
+	}
+	return s.BaseServiceMongoImpl.InsertOne(ctx, data)
+}
+
+// UpdateOne override — publish / sửa script của version active phải pass test case.
+func (s *LogicScriptService) UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts *options.UpdateOptions) (models.LogicScript, error) {
+	if err := s.checkPublish(ctx, filter, update); err != nil {
+		var zero models.LogicScript
+		return zero, err
+	}
+	return s.BaseServiceMongoImpl.UpdateOne(ctx, filter, update, opts)
+}
+
+// UpdateById override — như UpdateOne.
+func (s *LogicScriptService) UpdateById(ctx context.Context, id primitive.ObjectID, data interface{}) (models.LogicScript, error) {
+	if err := s.checkPublish(ctx, bson.M{"_id": id}, data); err != nil {
+		var zero models.LogicScript
+		return zero, err
+	}
+	return s.BaseServiceMongoImpl.UpdateById(ctx, id, data)
+}
+
+// Upsert override — như UpdateOne (seed cũng đi qua đây nên script seed phải pass test case đã seed).
+func (s *LogicScriptService) Upsert(ctx context.Context, filter interface{}, data interface{}) (models.LogicScript, error) {
+	if err := s.checkPublish(ctx, filter, data); err != nil {
+		var zero models.LogicScript
+		return zero, err
+	}
+	return s.BaseServiceMongoImpl.Upsert(ctx, filter, data)
+}
+
+// Publish chuyển version logic của tổ chức ownerOrgID sang active sau khi chạy test case.
+func (s *LogicScriptService) Publish(ctx context.Context, logicID string, logicVersion int, ownerOrgID primitive.ObjectID) (models.LogicScript, error) {
+	return s.UpdateOne(ctx, bson.M{"logic_id": logicID, "logic_version": logicVersion, "ownerOrganizationId": ownerOrgID},
+		&basesvc.UpdateData{Set: map[string]interface{}{"status": models.RuleStageActive}}, nil)
+}
+
+// FindVersionInScope version logic trong phạm vi tổ chức: của tổ chức đang làm việc trước, rồi System Org.
+func (s *LogicScriptService) FindVersionInScope(ctx context.Context, logicID string, logicVersion int, orgID primitive.ObjectID) (models.LogicScript, error) {
+	for _, ownerID := range orgScopeIDs(ctx, orgID) {
+		logic, err := s.BaseServiceMongoImpl.FindOne(ctx, bson.M{"logic_id": logicID, "logic_version": logicVersion, "ownerOrganizationId": ownerID}, nil)
+		if err == nil || !errors.Is(err, common.ErrNotFound) {
+			return logic, err
+		}
+	}
+	var zero models.LogicScript
+	return zero, fmt.Errorf("không tìm thấy logic %s v%d: %w", logicID, logicVersion, common.ErrNotFound)
+}
+
+// checkPublish dựng document sau update; chạy test khi kết quả là version active có script mới hoặc vừa được publish.
+func (s *LogicScriptService) checkPublish(ctx context.Context, filter interface{}, update interface{}) error {
+	updateData, err := basesvc.ToUpdateData(update)
+	if err != nil || updateData.Set == nil {
+		return nil
+	}
+	candidate, err := s.BaseServiceMongoImpl.FindOne(ctx, filter, nil)
+	found := err == nil
+	if err != nil && !errors.Is(err, common.ErrNotFound) {
+		return err
+	}
+	before := candidate
+	if v, ok := updateData.Set["status"].(string); ok {
+		candidate.Status = v
+	}
+	if v, ok := updateData.Set["script"].(string); ok {
+		candidate.Script = v
+	}
+	if v, ok := updateData.Set["entry_function"].(string); ok {
+		candidate.EntryFunction = v
+	}
+	if v, ok := updateData.Set["logic_id"].(string); ok && candidate.LogicID == "" {
+		candidate.LogicID = v
+	}
+	if v, ok := toInt(updateData.Set["logic_version"]); ok && candidate.LogicVersion == 0 {
+		candidate.LogicVersion = v
+	}
+	if candidate.Status != models.RuleStageActive {
+		return nil
+	}
+	if found && before.Status == models.RuleStageActive && before.Script == candidate.Script && before.EntryFunction == candidate.EntryFunction {
+		return nil
+	}
+	return s.checkTests(ctx, &candidate)
+}
+
+// checkTests chạy test case của logic, trả lỗi validation kèm báo cáo khi có case fail.
+func (s *LogicScriptService) checkTests(ctx context.Context, logic *models.LogicScript) error {
+	testSvc, err := NewRuleTestCaseService()
+	if err != nil {
+		return fmt.Errorf("RuleTestCaseService: %w", err)
+	}
+	report, err := testSvc.RunForLogic(ctx, logic)
+	if err != nil {
+		return err
+	}
+	if !report.OK() {
+		return common.NewError(common.ErrCodeValidationInput,
+			fmt.Sprintf("Logic %s v%d fail %d/%d test case — không thể publish", logic.LogicID, logic.LogicVersion, report.Failed, report.Total),
+			common.StatusBadRequest, report)
+	}
+	return nil
+}
+
+func toInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case int32:
+		return int(n), true
+	case int64:
+		return int(n), true
+	case float64:
+		return int(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,343 @@
+// Package service — Promotion workflow cho Rule Definition: draft → shadow → canary → active → retired, rollback.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	basesvc "meta_commerce/internal/api/base/service"
+	"meta_commerce/internal/api/ruleintel/models"
+	"meta_commerce/internal/common"
+	"meta_commerce/internal/cta"
+	"meta_commerce/internal/global"
+	"meta_commerce/internal/logger"
+)
+
+const (
+	// maxRuleVersionHistory số version active cũ giữ lại trên rule để rollback.
+	maxRuleVersionHistory = 20
+)
+
+// RulePromotionAuditService CRUD cho audit promotion.
+type RulePromotionAuditService struct {
+	*basesvc.BaseServiceMongoImpl[models.RulePromotionAudit]
+}
+
+// NewRulePromotionAuditService tạo RulePromotionAuditService.
+func NewRulePromotionAuditService() (*RulePromotionAuditService, error) {
+	coll, ok := global.RegistryCollections.Get(global.MongoDB_ColNames.RulePromotionAudits)
+	if !ok {
+		return nil, fmt.Errorf("collection %s chưa đăng ký: %w", global.MongoDB_ColNames.RulePromotionAudits, common.ErrNotFound)
+	}
+	return &RulePromotionAuditService{
+		BaseServiceMongoImpl: basesvc.NewBaseServiceMongo[models.RulePromotionAudit](coll),
+	}, nil
+}
+
+// RulePromotionService đổi stage, promote, rollback rule và ghi audit.
+type RulePromotionService struct {
+	ruleSvc  *RuleDefinitionService
+	logicSvc *LogicScriptService
+	paramSvc *ParamSetService
+	auditSvc *RulePromotionAuditService
+}
+
+// NewRulePromotionService tạo service.
+func NewRulePromotionService() (*RulePromotionService, error) {
+	ruleSvc, err := NewRuleDefinitionService()
+	if err != nil {
+		return nil, fmt.Errorf("RuleDefinitionService: %w", err)
+	}
+	logicSvc, err := NewLogicScriptService()
+	if err != nil {
+		return nil, fmt.Errorf("LogicScriptService: %w", err)
+	}
+	paramSvc, err := NewParamSetService()
+	if err != nil {
+		return nil, fmt.Errorf("ParamSetService: %w", err)
+	}
+	auditSvc, err := NewRulePromotionAuditService()
+	if err != nil {
+		return nil, fmt.Errorf("RulePromotionAuditService: %w", err)
+	}
+	return &RulePromotionService{ruleSvc: ruleSvc, logicSvc: logicSvc, paramSvc: paramSvc, auditSvc: auditSvc}, nil
+}
+
+// RuleStageInput input đổi stage rule.
+type RuleStageInput struct {
+	RuleID string `json:"-"`
+	// Stage: draft | shadow | canary (version ứng viên), active (promote ứng viên), retired (ngừng rule).
+	Stage string `json:"stage"`
+	// Version ứng viên — 0 = giữ version ứng viên hiện tại (hoặc version active khi chưa có ứng viên).
+	LogicVersion  int                `json:"logic_version,omitempty"`
+	ParamVersion  int                `json:"param_version,omitempty"`
+	OutputVersion int                `json:"output_version,omitempty"`
+	CanaryPercent int                `json:"canary_percent,omitempty"`
+	CanaryOrgIDs  []string           `json:"canary_org_ids,omitempty"`
+	Reason        string             `json:"reason,omitempty"`
+	UserID        string             `json:"-"`
+	OrgID         primitive.ObjectID `json:"-"`
+}
+
+// SetStage chuyển rule / version ứng viên sang stage mới.
+func (s *RulePromotionService) SetStage(ctx context.Context, input *RuleStageInput) (*models.RuleDefinition, error) {
+	rule, err := s.findRule(ctx, input.RuleID, input.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	fromStage := currentStage(rule)
+	fromRefs := rule.ActiveRefs()
+	now := time.Now().UnixMilli()
+
+	switch input.Stage {
+	case models.RuleStageDraft, models.RuleStageShadow, models.RuleStageCanary:
+		candidate := s.candidateRefs(rule, input)
+		if _, err := s.validateRefs(ctx, candidate, rule.OwnerOrganizationID); err != nil {
+			return nil, err
+		}
+		if input.Stage == models.RuleStageCanary && input.CanaryPercent <= 0 && len(input.CanaryOrgIDs) == 0 {
+			return nil, common.NewError(common.ErrCodeValidationInput, "Canary cần canary_percent > 0 hoặc canary_org_ids", common.StatusBadRequest, nil)
+		}
+		if input.CanaryPercent < 0 || input.CanaryPercent > 100 {
+			return nil, common.NewError(common.ErrCodeValidationInput, "canary_percent phải trong khoảng 0–100", common.StatusBadRequest, nil)
+		}
+		rollout := models.RuleRollout{
+			Stage:         input.Stage,
+			Candidate:     candidate,
+			CanaryPercent: input.CanaryPercent,
+			CanaryOrgIDs:  input.CanaryOrgIDs,
+			UpdatedAt:     now,
+			UpdatedBy:     input.UserID,
+		}
+		updated, err := s.updateRule(ctx, rule.ID, &basesvc.UpdateData{Set: map[string]interface{}{"rollout": rollout}})
+		if err != nil {
+			return nil, err
+		}
+		s.audit(ctx, rule, input, models.RulePromotionActionStage, fromStage, input.Stage, &fromRefs, &candidate)
+		return updated, nil
+
+	case models.RuleStageActive:
+		candidate := s.candidateRefs(rule, input)
+		logic, err := s.validateRefs(ctx, candidate, rule.OwnerOrganizationID)
+		if err != nil {
+			return nil, err
+		}
+		// Engine chỉ chạy logic active cho version active — publish logic ứng viên (phải pass test case) trước khi promote.
+		// Chỉ publish logic của chính tổ chức sở hữu rule; logic System Org chưa active thì không publish hộ được.
+		if logic.Status != models.RuleStageActive {
+			if logic.OwnerOrganizationID != rule.OwnerOrganizationID {
+				return nil, common.NewError(common.ErrCodeValidationInput,
+					fmt.Sprintf("Logic %s v%d thuộc tổ chức khác và chưa active — không thể publish khi promote", logic.LogicID, logic.LogicVersion),
+					common.StatusBadRequest, nil)
+			}
+			if _, err := s.logicSvc.Publish(ctx, logic.LogicID, logic.LogicVersion, rule.OwnerOrganizationID); err != nil {
+				return nil, err
+			}
+		}
+		candidate.RuleVersion = rule.RuleVersion + 1
+		candidate.PromotedAt = now
+		candidate.PromotedBy = input.UserID
+		history := appendVersionHistory(rule.VersionHistory, fromRefs)
+		updated, err := s.updateRule(ctx, rule.ID, &basesvc.UpdateData{
+			Set: map[string]interface{}{
+				"rule_version":    candidate.RuleVersion,
+				"logic_ref":       candidate.LogicRef,
+				"param_ref":       candidate.ParamRef,
+				"output_ref":      candidate.OutputRef,
+				"status":          models.RuleStageActive,
+				"version_history": history,
+			},
+			Unset: map[string]interface{}{"rollout": ""},
+		})
+		if err != nil {
+			return nil, err
+		}
+		s.audit(ctx, rule, input, models.RulePromotionActionPromote, fromStage, models.RuleStageActive, &fromRefs, &candidate)
+		return updated, nil
+
+	case models.RuleStageRetired:
+		updated, err := s.updateRule(ctx, rule.ID, &basesvc.UpdateData{
+			Set:   map[string]interface{}{"status": models.RuleStageRetired},
+			Unset: map[string]interface{}{"rollout": ""},
+		})
+		if err != nil {
+			return nil, err
+		}
+		s.audit(ctx, rule, input, models.RulePromotionActionRetire, fromStage, models.RuleStageRetired, &fromRefs, nil)
+		return updated, nil
+	}
+	return nil, common.NewError(common.ErrCodeValidationInput, fmt.Sprintf("Stage không hợp lệ: %s", input.Stage), common.StatusBadRequest, nil)
+}
+
+// Rollback đưa rule về version active trước đó (phần tử cuối của version_history), bỏ rollout đang chạy.
+func (s *RulePromotionService) Rollback(ctx context.Context, ruleID, userID, reason string, orgID primitive.ObjectID) (*models.RuleDefinition, error) {
+	rule, err := s.findRule(ctx, ruleID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if len(rule.VersionHistory) == 0 {
+		return nil, common.NewError(common.ErrCodeBusinessOperation, "Rule chưa có version trước để rollback", common.StatusConflict, nil)
+	}
+	fromStage := currentStage(rule)
+	fromRefs := rule.ActiveRefs()
+	prev := rule.VersionHistory[len(rule.VersionHistory)-1]
+	target := prev
+	target.RuleVersion = rule.RuleVersion + 1
+	target.PromotedAt = time.Now().UnixMilli()
+	target.PromotedBy = userID
+
+	updated, err := s.updateRule(ctx, rule.ID, &basesvc.UpdateData{
+		Set: map[string]interface{}{
+			"rule_version":    target.RuleVersion,
+			"logic_ref":       target.LogicRef,
+			"param_ref":       target.ParamRef,
+			"output_ref":      target.OutputRef,
+			"status":          models.RuleStageActive,
+			"version_history": rule.VersionHistory[:len(rule.VersionHistory)-1],
+		},
+		Unset: map[string]interface{}{"rollout": ""},
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.audit(ctx, rule, &RuleStageInput{Reason: reason, UserID: userID, OrgID: orgID}, models.RulePromotionActionRollback, fromStage, models.RuleStageActive, &fromRefs, &target)
+	return updated, nil
+}
+
+// ListAudits lịch sử promotion của rule do tổ chức đang làm việc hoặc System Org thực hiện, mới nhất trước.
+func (s *RulePromotionService) ListAudits(ctx context.Context, ruleID string, orgID primitive.ObjectID, limit int64) ([]models.RulePromotionAudit, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(limit)
+	filter := bson.M{"rule_id": ruleID, "ownerOrganizationId": bson.M{"$in": orgScopeIDs(ctx, orgID)}}
+	audits, err := s.auditSvc.Find(ctx, filter, opts)
+	if err != nil && !errors.Is(err, common.ErrNotFound) {
+		return nil, err
+	}
+	return audits, nil
+}
+
+// findRule rule do chính tổ chức đang làm việc sở hữu — đổi stage / rollback rule System Org ảnh hưởng mọi tổ chức
+// nên chỉ làm được khi đang làm việc trong System Org.
+func (s *RulePromotionService) findRule(ctx context.Context, ruleID string, orgID primitive.ObjectID) (*models.RuleDefinition, error) {
+	if orgID.IsZero() {
+		return nil, fmt.Errorf("không tìm thấy rule %s: %w", ruleID, common.ErrNotFound)
+	}
+	rule, err := s.ruleSvc.FindOne(ctx, bson.M{"rule_id": ruleID, "ownerOrganizationId": orgID}, nil)
+	if err != nil {
+		if errors.Is(err, common.ErrNotFound) {
+			return nil, fmt.Errorf("không tìm thấy rule %s của tổ chức: %w", ruleID, common.ErrNotFound)
+		}
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (s *RulePromotionService) updateRule(ctx context.Context, id primitive.ObjectID, update *basesvc.UpdateData) (*models.RuleDefinition, error) {
+	updated, err := s.ruleSvc.UpdateOne(ctx, bson.M{"_id": id}, update, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// candidateRefs ghép version ứng viên từ input; trường 0 lấy từ ứng viên hiện tại, rồi tới version active.
+func (s *RulePromotionService) candidateRefs(rule *models.RuleDefinition, input *RuleStageInput) models.RuleVersionRefs {
+	refs := rule.ActiveRefs()
+	if rule.Rollout != nil {
+		refs = rule.Rollout.Candidate
+	}
+	if input.LogicVersion > 0 {
+		refs.LogicRef = models.LogicRef{LogicID: rule.LogicRef.LogicID, LogicVersion: input.LogicVersion}
+	}
+	if input.ParamVersion > 0 {
+		refs.ParamRef = models.ParamRef{ParamSetID: rule.ParamRef.ParamSetID, ParamVersion: input.ParamVersion}
+	}
+	if input.OutputVersion > 0 {
+		refs.OutputRef = models.OutputRef{OutputID: rule.OutputRef.OutputID, OutputVersion: input.OutputVersion}
+	}
+	refs.RuleVersion = rule.RuleVersion
+	refs.PromotedAt = 0
+	refs.PromotedBy = ""
+	return refs
+}
+
+// validateRefs kiểm tra logic / param ứng viên tồn tại trong phạm vi tổ chức sở hữu rule (kèm System Org)
+// — logic ở mọi status, bản draft được phép chạy shadow/canary.
+func (s *RulePromotionService) validateRefs(ctx context.Context, refs models.RuleVersionRefs, ownerOrgID primitive.ObjectID) (*models.LogicScript, error) {
+	scope := bson.M{"$in": orgScopeIDs(ctx, ownerOrgID)}
+	logic, err := s.logicSvc.FindOne(ctx, bson.M{"logic_id": refs.LogicRef.LogicID, "logic_version": refs.LogicRef.LogicVersion, "ownerOrganizationId": scope}, nil)
+	if err != nil {
+		if errors.Is(err, common.ErrNotFound) {
+			return nil, common.NewError(common.ErrCodeValidationInput, fmt.Sprintf("Không tìm thấy logic %s v%d", refs.LogicRef.LogicID, refs.LogicRef.LogicVersion), common.StatusBadRequest, nil)
+		}
+		return nil, err
+	}
+	if _, err := s.paramSvc.FindOne(ctx, bson.M{"param_set_id": refs.ParamRef.ParamSetID, "param_version": refs.ParamRef.ParamVersion, "ownerOrganizationId": scope}, nil); err != nil {
+		if errors.Is(err, common.ErrNotFound) {
+			return nil, common.NewError(common.ErrCodeValidationInput, fmt.Sprintf("Không tìm thấy param set %s v%d", refs.ParamRef.ParamSetID, refs.ParamRef.ParamVersion), common.StatusBadRequest, nil)
+		}
+		return nil, err
+	}
+	return &logic, nil
+}
+
+// audit ghi RulePromotionAudit — lỗi ghi audit không làm fail thao tác đã thực hiện.
+func (s *RulePromotionService) audit(ctx context.Context, rule *models.RuleDefinition, input *RuleStageInput, action, fromStage, toStage string, fromRefs, toRefs *models.RuleVersionRefs) {
+	doc := models.RulePromotionAudit{
+		RuleID:              rule.RuleID,
+		Domain:              rule.Domain,
+		Action:              action,
+		FromStage:           fromStage,
+		ToStage:             toStage,
+		FromRefs:            fromRefs,
+		ToRefs:              toRefs,
+		CanaryPercent:       input.CanaryPercent,
+		CanaryOrgIDs:        input.CanaryOrgIDs,
+		Reason:              input.Reason,
+		UserID:              input.UserID,
+		OwnerOrganizationID: input.OrgID,
+		CreatedAt:           time.Now().UnixMilli(),
+	}
+	if _, err := s.auditSvc.InsertOne(ctx, doc); err != nil {
+		logger.GetAppLogger().WithError(err).WithFields(map[string]interface{}{
+			"rule_id": rule.RuleID,
+			"action":  action,
+		}).Error("[RulePromotion] Ghi audit promotion thất bại")
+	}
+}
+
+// orgScopeIDs tổ chức được đọc dữ liệu Rule Intelligence: tổ chức đang làm việc trước, rồi System Org (dữ liệu seed dùng chung).
+func orgScopeIDs(ctx context.Context, orgID primitive.ObjectID) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, 0, 2)
+	if !orgID.IsZero() {
+		ids = append(ids, orgID)
+	}
+	if systemOrgID, err := cta.GetSystemOrganizationID(ctx); err == nil && !systemOrgID.IsZero() && systemOrgID != orgID {
+		ids = append(ids, systemOrgID)
+	}
+	return ids
+}
+
+// currentStage stage hiển thị của rule: stage rollout nếu có, ngược lại status rule.
+func currentStage(rule *models.RuleDefinition) string {
+	if rule.Rollout != nil && rule.Rollout.Stage != "" {
+		return rule.Rollout.Stage
+	}
+	return rule.Status
+}
+
+func appendVersionHistory(history []models.RuleVersionRefs, refs models.RuleVersionRefs) []models.RuleVersionRefs {
+	out := append(append([]models.RuleVersionRefs{}, history...), refs)
+	if len(out) > maxRuleVersionHistory {
+		out = out[len(out)-maxRuleVersionHistory:]
+	}
+	return out
+}
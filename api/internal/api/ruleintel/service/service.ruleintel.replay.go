@@ -0,0 +1,425 @@
+// Package service — Dry-run / replay Rule Engine trên trace lịch sử.
+//
+// Chạy lại input_snapshot của RuleExecutionTrace với logic / param version ứng viên (không ghi trace),
+// so sánh output + report với lần chạy gốc để biết quyết định nào sẽ đổi trước khi publish.
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"meta_commerce/internal/api/ruleintel/engine"
+	"meta_commerce/internal/api/ruleintel/models"
+	"meta_commerce/internal/common"
+)
+
+const (
+	// ReplayDefaultLimit số trace tối đa mặc định mỗi lần replay.
+	ReplayDefaultLimit = 100
+	// ReplayMaxLimit trần số trace mỗi lần replay.
+	ReplayMaxLimit = 1000
+	// replayMaxTransitions số cặp chuyển đổi giá trị giữ lại mỗi key trong thống kê.
+	replayMaxTransitions = 20
+)
+
+// ReplayCandidate logic / param ứng viên. Trường trống = dùng đúng version đã ghi trong trace.
+type ReplayCandidate struct {
+	LogicID      string `json:"logic_id,omitempty"`
+	LogicVersion int    `json:"logic_version,omitempty"`
+	// Script chạy thẳng script chưa lưu (ưu tiên hơn LogicID/LogicVersion).
+	Script         string                 `json:"script,omitempty"`
+	ParamSetID     string                 `json:"param_set_id,omitempty"`
+	ParamVersion   int                    `json:"param_version,omitempty"`
+	ParamsOverride map[string]interface{} `json:"params_override,omitempty"`
+}
+
+// ReplaySelection chọn trace cần replay. Phải có ít nhất một tiêu chí.
+type ReplaySelection struct {
+	TraceIDs []string `json:"trace_ids,omitempty"`
+	RuleID   string   `json:"rule_id,omitempty"`
+	RuleCode string   `json:"rule_code,omitempty"`
+	// From / To cửa sổ timestamp (ms).
+	From           int64  `json:"from,omitempty"`
+	To             int64  `json:"to,omitempty"`
+	EntityObjectID string `json:"entity_object_id,omitempty"`
+	// OnlySuccess true: bỏ trace gốc lỗi / timeout.
+	OnlySuccess bool `json:"only_success,omitempty"`
+	Limit       int  `json:"limit,omitempty"`
+}
+
+// ReplayInput input dry-run.
+type ReplayInput struct {
+	OwnerOrganizationID string          `json:"-"`
+	Candidate           ReplayCandidate `json:"candidate"`
+	Selection           ReplaySelection `json:"selection"`
+}
+
+// ReplayTraceDiff kết quả replay một trace.
+type ReplayTraceDiff struct {
+	TraceID           string           `json:"trace_id"`
+	RuleID            string           `json:"rule_id"`
+	EntityRef         models.EntityRef `json:"entity_ref"`
+	Timestamp         int64            `json:"timestamp"`
+	OriginalStatus    string           `json:"original_status"`
+	CandidateStatus   string           `json:"candidate_status"`
+	OriginalOutput    interface{}      `json:"original_output"`
+	CandidateOutput   interface{}      `json:"candidate_output"`
+	OutputChanged     bool             `json:"output_changed"`
+	ChangedOutputKeys []string         `json:"changed_output_keys,omitempty"`
+	ChangedReportKeys []string         `json:"changed_report_keys,omitempty"`
+	CandidateReport   interface{}      `json:"candidate_report,omitempty"`
+	Error             string           `json:"error,omitempty"`
+}
+
+// ReplayResult tổng hợp dry-run.
+type ReplayResult struct {
+	Total         int `json:"total"`
+	Unchanged     int `json:"unchanged"`
+	Flipped       int `json:"flipped"`
+	ReportChanged int `json:"report_changed"`
+	Errors        int `json:"errors"`
+	// FlipsByKey số trace đổi giá trị theo từng key output ("$" = output không phải object).
+	FlipsByKey map[string]int `json:"flips_by_key"`
+	// Transitions "cũ → mới" theo key output (chỉ giá trị scalar).
+	Transitions map[string]map[string]int `json:"transitions"`
+	Traces      []ReplayTraceDiff         `json:"traces"`
+}
+
+// Replay chạy lại trace lịch sử với candidate, không ghi rule_execution_logs.
+func (s *RuleEngineService) Replay(ctx context.Context, input *ReplayInput) (*ReplayResult, error) {
+	traces, err := s.findReplayTraces(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	// Logic / param ứng viên và logic của trace chỉ đọc trong phạm vi tổ chức (kèm System Org).
+	orgID, _ := primitive.ObjectIDFromHex(input.OwnerOrganizationID)
+
+	var candidatePool *engine.ProgramPool
+	if input.Candidate.Script != "" {
+		prg, err := s.cache.executor.Compile(input.Candidate.Script)
+		if err != nil {
+			return nil, common.NewError(common.ErrCodeValidationInput, fmt.Sprintf("Script ứng viên không biên dịch được: %v", err), common.StatusBadRequest, err)
+		}
+		candidatePool = engine.NewProgramPool(s.cache.executor, prg)
+	} else if input.Candidate.LogicID != "" {
+		logic, err := s.loadLogicAnyStatus(ctx, input.Candidate.LogicID, input.Candidate.LogicVersion, orgID)
+		if err != nil {
+			return nil, err
+		}
+		if candidatePool, err = s.cache.program(logic); err != nil {
+			return nil, common.NewError(common.ErrCodeValidationInput, fmt.Sprintf("Logic ứng viên %s v%d không biên dịch được: %v", logic.LogicID, logic.LogicVersion, err), common.StatusBadRequest, err)
+		}
+	}
+
+	var candidateParams map[string]interface{}
+	if input.Candidate.ParamSetID != "" {
+		if candidateParams, err = s.loadParamsInScope(ctx, input.Candidate.ParamSetID, input.Candidate.ParamVersion, orgID); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &ReplayResult{
+		FlipsByKey:  map[string]int{},
+		Transitions: map[string]map[string]int{},
+		Traces:      make([]ReplayTraceDiff, 0, len(traces)),
+	}
+	for i := range traces {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		diff := s.replayOne(ctx, &traces[i], orgID, candidatePool, candidateParams, input.Candidate.ParamsOverride)
+		result.add(diff)
+	}
+	return result, nil
+}
+
+// replayOne chạy lại một trace và so sánh với kết quả gốc.
+func (s *RuleEngineService) replayOne(ctx context.Context, trace *models.RuleExecutionTrace, orgID primitive.ObjectID, candidatePool *engine.ProgramPool, candidateParams, override map[string]interface{}) ReplayTraceDiff {
+	diff := ReplayTraceDiff{
+		TraceID:        trace.TraceID,
+		RuleID:         trace.RuleID,
+		EntityRef:      trace.EntityRef,
+		Timestamp:      trace.Timestamp,
+		OriginalStatus: trace.ExecutionStatus,
+		OriginalOutput: normalizeForReplay(trace.OutputObject),
+	}
+
+	pool := candidatePool
+	if pool == nil {
+		logic, err := s.loadLogicAnyStatus(ctx, trace.LogicID, trace.LogicVersion, orgID)
+		if err == nil {
+			pool, err = s.cache.program(logic)
+		}
+		if err != nil {
+			diff.CandidateStatus = models.TraceStatusError
+			diff.Error = err.Error()
+			return diff
+		}
+	}
+
+	params := candidateParams
+	if params == nil {
+		params, _ = normalizeForReplay(trace.ParametersSnapshot).(map[string]interface{})
+	}
+	merged := cloneParams(params)
+	for k, v := range override {
+		merged[k] = v
+	}
+	layers, _ := normalizeForReplay(trace.InputSnapshot).(map[string]interface{})
+	if layers == nil {
+		layers = map[string]interface{}{}
+	}
+
+	evalResult, err := pool.Run(ctx, &engine.EvalContext{Layers: layers, Params: merged, EntityRef: trace.EntityRef})
+	diff.CandidateStatus = traceStatusFromError(err)
+	if err != nil {
+		diff.Error = err.Error()
+		diff.OutputChanged = diff.OriginalStatus == models.TraceStatusSuccess
+		return diff
+	}
+
+	diff.CandidateOutput = normalizeForReplay(evalResult.Output)
+	diff.CandidateReport = evalResult.Report
+	diff.ChangedOutputKeys = changedKeys(diff.OriginalOutput, diff.CandidateOutput)
+	diff.OutputChanged = len(diff.ChangedOutputKeys) > 0 || diff.OriginalStatus != models.TraceStatusSuccess
+	// "log" là diễn giải tự do — đổi câu chữ không tính là report thay đổi.
+	origReport, _ := normalizeForReplay(trace.Explanation).(map[string]interface{})
+	newReport, _ := normalizeForReplay(evalResult.Report).(map[string]interface{})
+	delete(origReport, "log")
+	delete(newReport, "log")
+	diff.ChangedReportKeys = changedKeys(origReport, newReport)
+	return diff
+}
+
+// add cộng dồn một trace vào tổng hợp.
+func (r *ReplayResult) add(diff ReplayTraceDiff) {
+	r.Total++
+	r.Traces = append(r.Traces, diff)
+	if diff.Error != "" {
+		r.Errors++
+	}
+	if len(diff.ChangedReportKeys) > 0 {
+		r.ReportChanged++
+	}
+	if !diff.OutputChanged {
+		r.Unchanged++
+		return
+	}
+	r.Flipped++
+	for _, k := range diff.ChangedOutputKeys {
+		r.FlipsByKey[k]++
+		from, to := valueAtKey(diff.OriginalOutput, k), valueAtKey(diff.CandidateOutput, k)
+		if !isScalar(from) || !isScalar(to) {
+			continue
+		}
+		tr := r.Transitions[k]
+		if tr == nil {
+			tr = map[string]int{}
+			r.Transitions[k] = tr
+		}
+		label := fmt.Sprintf("%v → %v", from, to)
+		if _, ok := tr[label]; ok || len(tr) < replayMaxTransitions {
+			tr[label]++
+		}
+	}
+}
+
+// findReplayTraces tìm trace theo selection, luôn giới hạn trong org đang thao tác.
+func (s *RuleEngineService) findReplayTraces(ctx context.Context, input *ReplayInput) ([]models.RuleExecutionTrace, error) {
+	sel := input.Selection
+	filter := bson.M{}
+	if input.OwnerOrganizationID != "" {
+		filter["entity_ref.ownerOrganizationId"] = input.OwnerOrganizationID
+	}
+	hasCriteria := false
+	if len(sel.TraceIDs) > 0 {
+		filter["trace_id"] = bson.M{"$in": sel.TraceIDs}
+		hasCriteria = true
+	}
+	if sel.RuleID != "" {
+		filter["rule_id"] = sel.RuleID
+		hasCriteria = true
+	} else if sel.RuleCode != "" {
+		ids, err := s.ruleSvc.Distinct(ctx, "rule_id", bson.M{"rule_code": sel.RuleCode})
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return nil, fmt.Errorf("không tìm thấy rule_code %s: %w", sel.RuleCode, common.ErrNotFound)
+		}
+		filter["rule_id"] = bson.M{"$in": ids}
+		hasCriteria = true
+	}
+	if sel.From > 0 || sel.To > 0 {
+		window := bson.M{}
+		if sel.From > 0 {
+			window["$gte"] = sel.From
+		}
+		if sel.To > 0 {
+			window["$lte"] = sel.To
+		}
+		filter["timestamp"] = window
+		hasCriteria = true
+	}
+	if sel.EntityObjectID != "" {
+		filter["entity_ref.objectId"] = sel.EntityObjectID
+		hasCriteria = true
+	}
+	if !hasCriteria {
+		return nil, common.NewError(common.ErrCodeValidationInput, "Cần ít nhất một tiêu chí chọn trace (trace_ids, rule_id, rule_code, from/to, entity_object_id)", common.StatusBadRequest, nil)
+	}
+	if sel.OnlySuccess {
+		filter["execution_status"] = models.TraceStatusSuccess
+	}
+
+	limit := sel.Limit
+	if limit <= 0 {
+		limit = ReplayDefaultLimit
+	}
+	if limit > ReplayMaxLimit {
+		limit = ReplayMaxLimit
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: -1}}).SetLimit(int64(limit))
+	traces, err := s.traceSvc.Find(ctx, filter, opts)
+	if err != nil && !errors.Is(err, common.ErrNotFound) {
+		return nil, err
+	}
+	return traces, nil
+}
+
+// loadLogicAnyStatus load logic theo version bất kể status — replay được cả bản draft / đã retire.
+// Chỉ đọc logic của tổ chức (trước) hoặc System Org.
+func (s *RuleEngineService) loadLogicAnyStatus(ctx context.Context, logicID string, logicVersion int, orgID primitive.ObjectID) (*models.LogicScript, error) {
+	for _, ownerID := range orgScopeIDs(ctx, orgID) {
+		logic, err := s.logicSvc.FindOne(ctx, bson.M{"logic_id": logicID, "logic_version": logicVersion, "ownerOrganizationId": ownerID}, nil)
+		if err == nil {
+			return &logic, nil
+		}
+		if !errors.Is(err, common.ErrNotFound) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("không tìm thấy logic %s v%d: %w", logicID, logicVersion, common.ErrNotFound)
+}
+
+// loadParamsInScope param set ứng viên của tổ chức (trước) hoặc System Org — không qua cache param của engine
+// (cache theo id:version, không phân biệt tổ chức).
+func (s *RuleEngineService) loadParamsInScope(ctx context.Context, paramSetID string, paramVersion int, orgID primitive.ObjectID) (map[string]interface{}, error) {
+	for _, ownerID := range orgScopeIDs(ctx, orgID) {
+		ps, err := s.paramSvc.FindOne(ctx, bson.M{"param_set_id": paramSetID, "param_version": paramVersion, "ownerOrganizationId": ownerID}, nil)
+		if err == nil {
+			if ps.Parameters == nil {
+				return map[string]interface{}{}, nil
+			}
+			return ps.Parameters, nil
+		}
+		if !errors.Is(err, common.ErrNotFound) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("không tìm thấy param set %s v%d: %w", paramSetID, paramVersion, common.ErrNotFound)
+}
+
+// normalizeForReplay đưa giá trị đọc từ Mongo (primitive.D/M/A) hoặc export từ goja về map/slice/scalar JSON
+// để so sánh ổn định (int64 vs float64, D vs map) và để script đọc input_snapshot như lúc chạy gốc.
+func normalizeForReplay(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	v = toPlain(v)
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return v
+	}
+	return out
+}
+
+func toPlain(v interface{}) interface{} {
+	switch t := v.(type) {
+	case primitive.D:
+		m := make(map[string]interface{}, len(t))
+		for _, e := range t {
+			m[e.Key] = toPlain(e.Value)
+		}
+		return m
+	case primitive.M:
+		return toPlain(map[string]interface{}(t))
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			m[k] = toPlain(e)
+		}
+		return m
+	case primitive.A:
+		return toPlain([]interface{}(t))
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, e := range t {
+			out[i] = toPlain(e)
+		}
+		return out
+	case primitive.ObjectID:
+		return t.Hex()
+	case primitive.DateTime:
+		return t.Time().UnixMilli()
+	case time.Time:
+		return t.UnixMilli()
+	}
+	return v
+}
+
+// changedKeys trả về key top-level khác nhau giữa hai output; output không phải object → "$".
+func changedKeys(a, b interface{}) []string {
+	ma, okA := a.(map[string]interface{})
+	mb, okB := b.(map[string]interface{})
+	if !okA || !okB {
+		if reflect.DeepEqual(a, b) {
+			return nil
+		}
+		return []string{"$"}
+	}
+	var keys []string
+	for k, va := range ma {
+		if !reflect.DeepEqual(va, mb[k]) {
+			keys = append(keys, k)
+		}
+	}
+	for k := range mb {
+		if _, ok := ma[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func valueAtKey(v interface{}, key string) interface{} {
+	if key == "$" {
+		return v
+	}
+	if m, ok := v.(map[string]interface{}); ok {
+		return m[key]
+	}
+	return nil
+}
+
+func isScalar(v interface{}) bool {
+	switch v.(type) {
+	case nil, string, bool, float64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNormalizeForReplay_MongoDocumentMatchesGojaExport(t *testing.T) {
+	fromMongo := primitive.D{
+		{Key: "action", Value: "KILL"},
+		{Key: "score", Value: int32(3)},
+		{Key: "tags", Value: primitive.A{"a", primitive.D{{Key: "k", Value: int64(1)}}}},
+	}
+	fromGoja := map[string]interface{}{
+		"action": "KILL",
+		"score":  int64(3),
+		"tags":   []interface{}{"a", map[string]interface{}{"k": float64(1)}},
+	}
+	if keys := changedKeys(normalizeForReplay(fromMongo), normalizeForReplay(fromGoja)); len(keys) != 0 {
+		t.Fatalf("cùng output phải không có diff, got %v", keys)
+	}
+}
+
+func TestReplayResult_CountsFlipsAndTransitions(t *testing.T) {
+	r := &ReplayResult{FlipsByKey: map[string]int{}, Transitions: map[string]map[string]int{}}
+	orig := normalizeForReplay(map[string]interface{}{"valueTier": "low", "journeyStage": "first"})
+	cand := normalizeForReplay(map[string]interface{}{"valueTier": "medium", "journeyStage": "first"})
+	keys := changedKeys(orig, cand)
+	if !reflect.DeepEqual(keys, []string{"valueTier"}) {
+		t.Fatalf("changedKeys sai: %v", keys)
+	}
+	r.add(ReplayTraceDiff{OriginalOutput: orig, CandidateOutput: cand, ChangedOutputKeys: keys, OutputChanged: true})
+	r.add(ReplayTraceDiff{OriginalOutput: orig, CandidateOutput: orig})
+	if r.Total != 2 || r.Flipped != 1 || r.Unchanged != 1 {
+		t.Fatalf("tổng hợp sai: %+v", r)
+	}
+	if r.Transitions["valueTier"]["low → medium"] != 1 {
+		t.Fatalf("transition sai: %v", r.Transitions)
+	}
+}
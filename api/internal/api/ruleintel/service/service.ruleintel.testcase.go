@@ -0,0 +1,268 @@
+// Package service — Test case cho Logic Script: chạy qua engine.ScriptExecutor, so output / report với kỳ vọng.
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	basesvc "meta_commerce/internal/api/base/service"
+	"meta_commerce/internal/api/ruleintel/engine"
+	"meta_commerce/internal/api/ruleintel/models"
+	"meta_commerce/internal/common"
+	"meta_commerce/internal/global"
+)
+
+// RuleTestCaseService CRUD cho test case Logic Script.
+type RuleTestCaseService struct {
+	*basesvc.BaseServiceMongoImpl[models.RuleTestCase]
+}
+
+// NewRuleTestCaseService tạo RuleTestCaseService.
+func NewRuleTestCaseService() (*RuleTestCaseService, error) {
+	coll, ok := global.RegistryCollections.Get(global.MongoDB_ColNames.RuleTestCases)
+	if !ok {
+		return nil, fmt.Errorf("collection %s chưa đăng ký: %w", global.MongoDB_ColNames.RuleTestCases, common.ErrNotFound)
+	}
+	return &RuleTestCaseService{
+		BaseServiceMongoImpl: basesvc.NewBaseServiceMongo[models.RuleTestCase](coll),
+	}, nil
+}
+
+// LogicTestResult kết quả một test case.
+type LogicTestResult struct {
+	TestCaseID    string                 `json:"test_case_id"`
+	Name          string                 `json:"name"`
+	Passed        bool                   `json:"passed"`
+	Failures      []string               `json:"failures,omitempty"`
+	Output        interface{}            `json:"output,omitempty"`
+	Report        map[string]interface{} `json:"report,omitempty"`
+	Error         string                 `json:"error,omitempty"`
+	ExecutionTime int64                  `json:"execution_time"`
+}
+
+// LogicTestReport kết quả chạy toàn bộ test case của một version logic.
+type LogicTestReport struct {
+	LogicID      string            `json:"logic_id"`
+	LogicVersion int               `json:"logic_version"`
+	Total        int               `json:"total"`
+	Passed       int               `json:"passed"`
+	Failed       int               `json:"failed"`
+	Results      []LogicTestResult `json:"results"`
+}
+
+// OK true khi không có test case fail (logic chưa có test case cũng coi là OK).
+func (r *LogicTestReport) OK() bool {
+	return r.Failed == 0
+}
+
+// FindForLogic test case áp dụng cho version logic: logic_version = version hoặc 0 (mọi version),
+// thuộc tổ chức publish logic hoặc System Org — test case của tổ chức khác không chặn / không lộ qua publish.
+func (s *RuleTestCaseService) FindForLogic(ctx context.Context, logicID string, logicVersion int, orgID primitive.ObjectID) ([]models.RuleTestCase, error) {
+	filter := bson.M{
+		"logic_id":            logicID,
+		"logic_version":       bson.M{"$in": []int{0, logicVersion}},
+		"ownerOrganizationId": bson.M{"$in": orgScopeIDs(ctx, orgID)},
+	}
+	cases, err := s.Find(ctx, filter, nil)
+	if err != nil && !errors.Is(err, common.ErrNotFound) {
+		return nil, err
+	}
+	sort.SliceStable(cases, func(i, j int) bool { return cases[i].TestCaseID < cases[j].TestCaseID })
+	return cases, nil
+}
+
+// RunForLogic chạy mọi test case của logic (script có thể chưa lưu) qua engine.ScriptExecutor.
+// Test case lấy theo tổ chức sở hữu logic (logic.OwnerOrganizationID) cộng System Org.
+func (s *RuleTestCaseService) RunForLogic(ctx context.Context, logic *models.LogicScript) (*LogicTestReport, error) {
+	cases, err := s.FindForLogic(ctx, logic.LogicID, logic.LogicVersion, logic.OwnerOrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	paramSvc, err := NewParamSetService()
+	if err != nil {
+		return nil, fmt.Errorf("ParamSetService: %w", err)
+	}
+	return RunLogicTestCases(ctx, logic, cases, func(ctx context.Context, id string, version int) (map[string]interface{}, error) {
+		ps, err := paramSvc.FindOne(ctx, bson.M{"param_set_id": id, "param_version": version}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("param set %s v%d: %w", id, version, err)
+		}
+		return ps.Parameters, nil
+	})
+}
+
+// ParamLoader load Parameters của param set theo id + version.
+type ParamLoader func(ctx context.Context, paramSetID string, paramVersion int) (map[string]interface{}, error)
+
+// RunLogicTestCases biên dịch script một lần rồi chạy từng test case. loadParams nil: bỏ qua param_set_id của test case.
+func RunLogicTestCases(ctx context.Context, logic *models.LogicScript, cases []models.RuleTestCase, loadParams ParamLoader) (*LogicTestReport, error) {
+	report := &LogicTestReport{LogicID: logic.LogicID, LogicVersion: logic.LogicVersion, Results: []LogicTestResult{}}
+	if len(cases) == 0 {
+		return report, nil
+	}
+	entry := logic.EntryFunction
+	if entry == "" {
+		entry = "evaluate"
+	}
+	exec := engine.NewScriptExecutor(entry)
+	prg, compileErr := exec.Compile(logic.Script)
+
+	for i := range cases {
+		tc := &cases[i]
+		res := LogicTestResult{TestCaseID: tc.TestCaseID, Name: tc.Name}
+		if compileErr != nil {
+			res.Error = compileErr.Error()
+			res.Failures = []string{"script không biên dịch được"}
+			report.add(res)
+			continue
+		}
+		params := map[string]interface{}{}
+		if tc.ParamSetID != "" && loadParams != nil {
+			loaded, err := loadParams(ctx, tc.ParamSetID, tc.ParamVersion)
+			if err != nil {
+				res.Error = err.Error()
+				res.Failures = []string{"không load được param set"}
+				report.add(res)
+				continue
+			}
+			for k, v := range asMap(normalizeForReplay(loaded)) {
+				params[k] = v
+			}
+		}
+		for k, v := range asMap(normalizeForReplay(tc.Params)) {
+			params[k] = v
+		}
+		evalCtx := &engine.EvalContext{
+			Layers:    asMap(normalizeForReplay(tc.Layers)),
+			Params:    params,
+			EntityRef: tc.EntityRef,
+		}
+
+		start := time.Now()
+		out, err := exec.RunProgram(ctx, prg, evalCtx)
+		res.ExecutionTime = time.Since(start).Milliseconds()
+		if err != nil {
+			res.Error = err.Error()
+			res.Failures = []string{"script lỗi khi chạy"}
+			report.add(res)
+			continue
+		}
+		res.Output = normalizeForReplay(out.Output)
+		res.Report = out.Report
+		res.Failures = checkTestExpectation(tc, res.Output, out.Report)
+		res.Passed = len(res.Failures) == 0
+		report.add(res)
+	}
+	return report, nil
+}
+
+func (r *LogicTestReport) add(res LogicTestResult) {
+	r.Total++
+	if res.Passed {
+		r.Passed++
+	} else {
+		r.Failed++
+	}
+	r.Results = append(r.Results, res)
+}
+
+// checkTestExpectation so output và report với kỳ vọng của test case, trả danh sách lỗi (rỗng = pass).
+func checkTestExpectation(tc *models.RuleTestCase, output interface{}, report map[string]interface{}) []string {
+	var failures []string
+	expected := normalizeForReplay(tc.ExpectedOutput)
+	if tc.MatchMode == models.TestMatchPartial {
+		failures = append(failures, matchPartial(expected, output, "output")...)
+	} else if !reflect.DeepEqual(expected, output) {
+		failures = append(failures, fmt.Sprintf("output: kỳ vọng %s, thực tế %s", describeValue(expected), describeValue(output)))
+	}
+	for _, key := range tc.ExpectedReportKeys {
+		if _, ok := lookupPath(report, key); !ok {
+			failures = append(failures, fmt.Sprintf("report thiếu key %q", key))
+		}
+	}
+	return failures
+}
+
+// matchPartial kiểm tra actual chứa expected: object so từng key của expected (đệ quy), array so cùng độ dài từng phần tử.
+func matchPartial(expected, actual interface{}, path string) []string {
+	switch exp := expected.(type) {
+	case map[string]interface{}:
+		act, ok := actual.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: kỳ vọng object, thực tế %s", path, describeValue(actual))}
+		}
+		keys := make([]string, 0, len(exp))
+		for k := range exp {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var failures []string
+		for _, k := range keys {
+			av, present := act[k]
+			if !present {
+				failures = append(failures, fmt.Sprintf("%s.%s: thiếu key", path, k))
+				continue
+			}
+			failures = append(failures, matchPartial(exp[k], av, path+"."+k)...)
+		}
+		return failures
+	case []interface{}:
+		act, ok := actual.([]interface{})
+		if !ok || len(act) != len(exp) {
+			return []string{fmt.Sprintf("%s: kỳ vọng %s, thực tế %s", path, describeValue(expected), describeValue(actual))}
+		}
+		var failures []string
+		for i := range exp {
+			failures = append(failures, matchPartial(exp[i], act[i], fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return failures
+	default:
+		if !reflect.DeepEqual(expected, actual) {
+			return []string{fmt.Sprintf("%s: kỳ vọng %s, thực tế %s", path, describeValue(expected), describeValue(actual))}
+		}
+		return nil
+	}
+}
+
+// lookupPath tìm key trong report; hỗ trợ đường dẫn chấm (vd. "input.raw").
+func lookupPath(m map[string]interface{}, path string) (interface{}, bool) {
+	var cur interface{} = m
+	for _, part := range strings.Split(path, ".") {
+		obj, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = obj[part]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func describeValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	s := string(data)
+	if len(s) > 200 {
+		s = s[:200] + "…"
+	}
+	return s
+}
+
+func asMap(v interface{}) map[string]interface{} {
+	if m, ok := v.(map[string]interface{}); ok {
+		return m
+	}
+	return map[string]interface{}{}
+}
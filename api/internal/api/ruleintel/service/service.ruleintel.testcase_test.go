@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"meta_commerce/internal/api/ruleintel/models"
+)
+
+const testcaseScript = `function evaluate(ctx) {
+  var n = ctx.layers.raw.n;
+  return { output: { doubled: n * 2, label: n > 1 ? 'many' : 'one', extra: true }, report: { log: 'ok', input: { n: n } } };
+}`
+
+func TestRunLogicTestCases_ExactPartialAndReportKeys(t *testing.T) {
+	logic := &models.LogicScript{LogicID: "LOGIC_T", LogicVersion: 2, Script: testcaseScript}
+	cases := []models.RuleTestCase{
+		{TestCaseID: "exact_ok", Layers: map[string]interface{}{"raw": map[string]interface{}{"n": 1}},
+			ExpectedOutput: map[string]interface{}{"doubled": 2, "label": "one", "extra": true}, MatchMode: models.TestMatchExact},
+		{TestCaseID: "exact_extra_key", Layers: map[string]interface{}{"raw": map[string]interface{}{"n": 1}},
+			ExpectedOutput: map[string]interface{}{"doubled": 2}, MatchMode: models.TestMatchExact},
+		{TestCaseID: "partial_ok", Layers: map[string]interface{}{"raw": map[string]interface{}{"n": 3}},
+			ExpectedOutput: map[string]interface{}{"label": "many"}, MatchMode: models.TestMatchPartial,
+			ExpectedReportKeys: []string{"log", "input.n"}},
+		{TestCaseID: "partial_wrong", Layers: map[string]interface{}{"raw": map[string]interface{}{"n": 3}},
+			ExpectedOutput: map[string]interface{}{"doubled": 7}, MatchMode: models.TestMatchPartial},
+		{TestCaseID: "report_key_missing", Layers: map[string]interface{}{"raw": map[string]interface{}{"n": 3}},
+			ExpectedOutput: map[string]interface{}{}, MatchMode: models.TestMatchPartial, ExpectedReportKeys: []string{"result"}},
+	}
+	report, err := RunLogicTestCases(context.Background(), logic, cases, nil)
+	if err != nil {
+		t.Fatalf("RunLogicTestCases lỗi: %v", err)
+	}
+	want := map[string]bool{"exact_ok": true, "exact_extra_key": false, "partial_ok": true, "partial_wrong": false, "report_key_missing": false}
+	for _, res := range report.Results {
+		if res.Passed != want[res.TestCaseID] {
+			t.Errorf("%s: passed=%v, failures=%v", res.TestCaseID, res.Passed, res.Failures)
+		}
+	}
+	if report.Total != 5 || report.Passed != 2 || report.Failed != 3 || report.OK() {
+		t.Fatalf("tổng hợp sai: %+v", report)
+	}
+}
+
+func TestRunLogicTestCases_ScriptErrorsFailEveryCase(t *testing.T) {
+	logic := &models.LogicScript{LogicID: "LOGIC_T", LogicVersion: 1, Script: `function evaluate(ctx) { return {`}
+	cases := []models.RuleTestCase{{TestCaseID: "a"}, {TestCaseID: "b"}}
+	report, err := RunLogicTestCases(context.Background(), logic, cases, nil)
+	if err != nil {
+		t.Fatalf("RunLogicTestCases lỗi: %v", err)
+	}
+	if report.Failed != 2 || report.Results[0].Error == "" {
+		t.Fatalf("script không biên dịch được phải fail mọi case: %+v", report)
+	}
+}
+
+func TestRunLogicTestCases_NoCasesIsOK(t *testing.T) {
+	report, err := RunLogicTestCases(context.Background(), &models.LogicScript{Script: testcaseScript}, nil, nil)
+	if err != nil || !report.OK() || report.Total != 0 {
+		t.Fatalf("logic chưa có test case phải OK: %+v %v", report, err)
+	}
+}
@@ -3,9 +3,11 @@ package webhookhdl
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	basehdl "meta_commerce/internal/api/base/handler"
+	pcsvc "meta_commerce/internal/api/pc/service"
 	webhookdto "meta_commerce/internal/api/webhook/dto"
 	webhookmodels "meta_commerce/internal/api/webhook/models"
 	webhooksvc "meta_commerce/internal/api/webhook/service"
@@ -13,11 +15,15 @@ import (
 	"meta_commerce/internal/logger"
 
 	"github.com/gofiber/fiber/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // PancakePosWebhookHandler chỉ lưu webhook Pancake POS vào webhook_log, không xử lý order/product/customer.
 type PancakePosWebhookHandler struct {
 	webhookLogService *webhooksvc.WebhookLogService
+	signatureService  *webhooksvc.WebhookSignatureService
+	pcPosShopService  *pcsvc.PcPosShopService
 }
 
 // NewPancakePosWebhookHandler tạo mới PancakePosWebhookHandler
@@ -26,12 +32,23 @@ func NewPancakePosWebhookHandler() (*PancakePosWebhookHandler, error) {
 	if err != nil {
 		return nil, err
 	}
+	signatureService, err := webhooksvc.NewWebhookSignatureService()
+	if err != nil {
+		return nil, err
+	}
+	pcPosShopService, err := pcsvc.NewPcPosShopService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pc pos shop service: %v", err)
+	}
 	return &PancakePosWebhookHandler{
 		webhookLogService: webhookLogService,
+		signatureService:  signatureService,
+		pcPosShopService:  pcPosShopService,
 	}, nil
 }
 
 // HandlePancakePosWebhook nhận webhook từ Pancake POS, lưu vào webhook_log và trả 200 OK.
+// Chữ ký HMAC kiểm theo secret của tổ chức sở hữu shop; mode enforce từ chối 401, grace chỉ log.
 func (h *PancakePosWebhookHandler) HandlePancakePosWebhook(c fiber.Ctx) error {
 	return basehdl.SafeHandlerWrapper(c, func() error {
 		log := logger.GetAppLogger()
@@ -40,11 +57,25 @@ func (h *PancakePosWebhookHandler) HandlePancakePosWebhook(c fiber.Ctx) error {
 		var req webhookdto.PancakePosWebhookRequest
 		parseErr := c.Bind().Body(&req)
 
-		webhookLog, logErr := h.saveWebhookLog(ctx, c, "pancake_pos", req, rawBody, parseErr)
+		orgID := h.resolveOwnerOrgID(ctx, req.Payload.ShopID)
+		sig := h.signatureService.Verify(ctx, "pancake_pos", orgID, signedRequestFromCtx(c))
+
+		webhookLog, logErr := h.saveWebhookLog(ctx, c, "pancake_pos", req, rawBody, parseErr, orgID, sig)
 		if logErr != nil {
 			log.WithError(logErr).Warn("🔔 [PANCAKE POS WEBHOOK] Không thể lưu webhook log")
 		}
 
+		if sig.Rejected() {
+			log.WithFields(map[string]interface{}{"signatureStatus": sig.Status, "reason": sig.Reason, "ip": c.IP()}).Warn("🔔 [PANCAKE POS WEBHOOK] Từ chối webhook: chữ ký không hợp lệ")
+			c.Status(common.StatusUnauthorized).JSON(fiber.Map{
+				"code": common.ErrCodeAuth.Code, "message": "Chữ ký webhook không hợp lệ", "status": "error",
+			})
+			return nil
+		}
+		if sig.Mode == webhooksvc.SignatureModeGrace && sig.Status != webhooksvc.SignatureStatusValid {
+			log.WithFields(map[string]interface{}{"signatureStatus": sig.Status, "reason": sig.Reason}).Warn("🔔 [PANCAKE POS WEBHOOK] Chữ ký không hợp lệ (grace mode, vẫn xử lý)")
+		}
+
 		if webhookLog != nil && parseErr == nil {
 			_ = h.webhookLogService.UpdateProcessedStatus(ctx, webhookLog.ID, true, "")
 		}
@@ -56,7 +87,19 @@ func (h *PancakePosWebhookHandler) HandlePancakePosWebhook(c fiber.Ctx) error {
 	})
 }
 
-func (h *PancakePosWebhookHandler) saveWebhookLog(ctx context.Context, c fiber.Ctx, source string, req webhookdto.PancakePosWebhookRequest, rawBody string, parseErr error) (*webhookmodels.WebhookLog, error) {
+// resolveOwnerOrgID tìm tổ chức sở hữu shop (pc_pos_shops) để lấy secret chữ ký.
+func (h *PancakePosWebhookHandler) resolveOwnerOrgID(ctx context.Context, shopID int) *primitive.ObjectID {
+	if shopID == 0 || h.pcPosShopService == nil {
+		return nil
+	}
+	shop, err := h.pcPosShopService.FindOne(ctx, bson.M{"shopId": int64(shopID)}, nil)
+	if err != nil || shop.OwnerOrganizationID.IsZero() {
+		return nil
+	}
+	return &shop.OwnerOrganizationID
+}
+
+func (h *PancakePosWebhookHandler) saveWebhookLog(ctx context.Context, c fiber.Ctx, source string, req webhookdto.PancakePosWebhookRequest, rawBody string, parseErr error, orgID *primitive.ObjectID, sig webhooksvc.SignatureResult) (*webhookmodels.WebhookLog, error) {
 	now := time.Now().UnixMilli()
 	requestHeaders := make(map[string]string)
 	c.Request().Header.VisitAll(func(key, value []byte) {
@@ -84,5 +127,6 @@ func (h *PancakePosWebhookHandler) saveWebhookLog(ctx context.Context, c fiber.C
 		}(),
 		IPAddress: c.IP(), UserAgent: c.Get("User-Agent"), ReceivedAt: now, CreatedAt: now, UpdatedAt: now,
 	}
+	applySignatureToLog(&webhookLog, orgID, sig)
 	return h.webhookLogService.CreateWebhookLog(ctx, webhookLog)
 }
@@ -27,6 +27,7 @@ type PancakeWebhookHandler struct {
 	fbCustomerService     *fbsvc.FbCustomerService
 	fbPageService         *fbsvc.FbPageService
 	webhookLogService *webhooksvc.WebhookLogService
+	signatureService  *webhooksvc.WebhookSignatureService
 }
 
 // NewPancakeWebhookHandler tạo mới PancakeWebhookHandler
@@ -51,16 +52,22 @@ func NewPancakeWebhookHandler() (*PancakeWebhookHandler, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create webhook log service: %v", err)
 	}
+	signatureService, err := webhooksvc.NewWebhookSignatureService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook signature service: %v", err)
+	}
 	return &PancakeWebhookHandler{
 		fbConversationService: fbConversationService,
 		fbMessageService:      fbMessageService,
 		fbCustomerService:     fbCustomerService,
 		fbPageService:         fbPageService,
 		webhookLogService:     webhookLogService,
+		signatureService:      signatureService,
 	}, nil
 }
 
 // HandlePancakeWebhook xử lý webhook từ Pancake
+// Chữ ký HMAC kiểm theo secret của tổ chức sở hữu page; mode enforce từ chối 401, grace chỉ log.
 func (h *PancakeWebhookHandler) HandlePancakeWebhook(c fiber.Ctx) error {
 	return basehdl.SafeHandlerWrapper(c, func() error {
 		log := logger.GetAppLogger()
@@ -69,11 +76,25 @@ func (h *PancakeWebhookHandler) HandlePancakeWebhook(c fiber.Ctx) error {
 		var req webhookdto.PancakeWebhookRequest
 		parseErr := c.Bind().Body(&req)
 
-		webhookLog, logErr := h.saveWebhookLog(ctx, c, "pancake", req, rawBody, parseErr)
+		orgID := h.resolveOwnerOrgID(ctx, req.Payload)
+		sig := h.signatureService.Verify(ctx, "pancake", orgID, signedRequestFromCtx(c))
+
+		webhookLog, logErr := h.saveWebhookLog(ctx, c, "pancake", req, rawBody, parseErr, orgID, sig)
 		if logErr != nil {
 			log.WithError(logErr).Warn("🔔 [PANCAKE WEBHOOK] Không thể lưu webhook log")
 		}
 
+		if sig.Rejected() {
+			log.WithFields(map[string]interface{}{"signatureStatus": sig.Status, "reason": sig.Reason, "ip": c.IP()}).Warn("🔔 [PANCAKE WEBHOOK] Từ chối webhook: chữ ký không hợp lệ")
+			c.Status(common.StatusUnauthorized).JSON(fiber.Map{
+				"code": common.ErrCodeAuth.Code, "message": "Chữ ký webhook không hợp lệ", "status": "error",
+			})
+			return nil
+		}
+		if sig.Mode == webhooksvc.SignatureModeGrace && sig.Status != webhooksvc.SignatureStatusValid {
+			log.WithFields(map[string]interface{}{"signatureStatus": sig.Status, "reason": sig.Reason}).Warn("🔔 [PANCAKE WEBHOOK] Chữ ký không hợp lệ (grace mode, vẫn xử lý)")
+		}
+
 		if parseErr != nil {
 			c.Status(common.StatusOK).JSON(fiber.Map{
 				"code": common.StatusOK, "message": "Webhook đã được nhận và lưu log", "status": "success",
@@ -129,12 +150,7 @@ func (h *PancakeWebhookHandler) handleConversationEvent(ctx context.Context, pay
 	if !ok {
 		return fmt.Errorf("không tìm thấy conversation ID trong dữ liệu")
 	}
-	pageId := payload.PageID
-	if pageId == "" {
-		if pageIdFromData, ok := conversationData["page_uid"].(string); ok {
-			pageId = pageIdFromData
-		}
-	}
+	pageId := pancakePageID(payload)
 	// Upsert customer từ conversation payload vào fb_customers (nếu có) để MergeFromFbCustomer tìm thấy khi hook chạy.
 	// Nhiều khách chỉ có hội thoại, chưa nhận webhook customer_updated — cần tạo fb_customer từ conversation.
 	if pageId != "" && h.fbPageService != nil && h.fbCustomerService != nil {
@@ -311,12 +327,7 @@ func (h *PancakeWebhookHandler) handleMessageEvent(ctx context.Context, payload
 	if !ok {
 		return fmt.Errorf("không tìm thấy conversation_id trong dữ liệu message")
 	}
-	pageId := payload.PageID
-	if pageId == "" {
-		if pageIdFromData, ok := messageData["page_id"].(string); ok {
-			pageId = pageIdFromData
-		}
-	}
+	pageId := pancakePageID(payload)
 	panCakeData := make(map[string]interface{})
 	for k, v := range messageData {
 		if k != "messages" {
@@ -366,7 +377,42 @@ func (h *PancakeWebhookHandler) handleCustomerEvent(ctx context.Context, payload
 	return err
 }
 
-func (h *PancakeWebhookHandler) saveWebhookLog(ctx context.Context, c fiber.Ctx, source string, req webhookdto.PancakeWebhookRequest, rawBody string, parseErr error) (*webhookmodels.WebhookLog, error) {
+// resolveOwnerOrgID tìm tổ chức sở hữu page (fb_pages) để lấy secret chữ ký — page lấy giống đường xử lý event.
+func (h *PancakeWebhookHandler) resolveOwnerOrgID(ctx context.Context, payload webhookdto.PancakeWebhookPayload) *primitive.ObjectID {
+	pageId := pancakePageID(payload)
+	if pageId == "" || h.fbPageService == nil {
+		return nil
+	}
+	page, err := h.fbPageService.FindOneByPageID(ctx, pageId)
+	if err != nil || page.OwnerOrganizationID.IsZero() {
+		return nil
+	}
+	return &page.OwnerOrganizationID
+}
+
+// pancakePageID page của event: payload.page_id, rồi page_id / page_uid trong data hoặc object conversation / message.
+// Dùng chung cho xác thực chữ ký và xử lý event — page để chọn secret phải là page event ghi vào.
+func pancakePageID(payload webhookdto.PancakeWebhookPayload) string {
+	if payload.PageID != "" {
+		return payload.PageID
+	}
+	sources := []map[string]interface{}{payload.Data}
+	for _, key := range []string{"conversation", "message"} {
+		if nested, ok := payload.Data[key].(map[string]interface{}); ok {
+			sources = append(sources, nested)
+		}
+	}
+	for _, data := range sources {
+		for _, key := range []string{"page_id", "page_uid"} {
+			if s, ok := data[key].(string); ok && s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+func (h *PancakeWebhookHandler) saveWebhookLog(ctx context.Context, c fiber.Ctx, source string, req webhookdto.PancakeWebhookRequest, rawBody string, parseErr error, orgID *primitive.ObjectID, sig webhooksvc.SignatureResult) (*webhookmodels.WebhookLog, error) {
 	now := time.Now().UnixMilli()
 	requestHeaders := make(map[string]string)
 	c.Request().Header.VisitAll(func(key, value []byte) {
@@ -394,5 +440,6 @@ func (h *PancakeWebhookHandler) saveWebhookLog(ctx context.Context, c fiber.Ctx,
 		}(),
 		IPAddress: c.IP(), UserAgent: c.Get("User-Agent"), ReceivedAt: now, CreatedAt: now, UpdatedAt: now,
 	}
+	applySignatureToLog(&webhookLog, orgID, sig)
 	return h.webhookLogService.CreateWebhookLog(ctx, webhookLog)
 }
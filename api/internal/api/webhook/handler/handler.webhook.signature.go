@@ -0,0 +1,33 @@
+// Package webhookhdl - helper chữ ký webhook dùng chung cho Pancake / Pancake POS.
+package webhookhdl
+
+import (
+	webhookmodels "meta_commerce/internal/api/webhook/models"
+	webhooksvc "meta_commerce/internal/api/webhook/service"
+
+	"github.com/gofiber/fiber/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// signedRequestFromCtx lấy raw body và header chữ ký từ request.
+func signedRequestFromCtx(c fiber.Ctx) webhooksvc.SignedRequest {
+	return webhooksvc.SignedRequest{
+		RawBody:   c.Body(),
+		Signature: c.Get(webhooksvc.HeaderWebhookSignature),
+		Timestamp: c.Get(webhooksvc.HeaderWebhookTimestamp),
+		Nonce:     c.Get(webhooksvc.HeaderWebhookNonce),
+	}
+}
+
+// applySignatureToLog ghi kết quả xác thực chữ ký vào webhook log; bị từ chối thì ghi lý do vào processError.
+func applySignatureToLog(webhookLog *webhookmodels.WebhookLog, orgID *primitive.ObjectID, sig webhooksvc.SignatureResult) {
+	if orgID != nil {
+		webhookLog.OwnerOrganizationID = *orgID
+	}
+	webhookLog.SignatureStatus = sig.Status
+	webhookLog.SignatureMode = sig.Mode
+	if sig.Rejected() {
+		webhookLog.Rejected = true
+		webhookLog.ProcessError = "Signature " + sig.Status + ": " + sig.Reason
+	}
+}
@@ -20,6 +20,12 @@ type WebhookLog struct {
 	RequestBody    map[string]interface{} `json:"requestBody" bson:"requestBody"`                            // Body của request (toàn bộ payload)
 	RawBody        string                 `json:"rawBody,omitempty" bson:"rawBody,omitempty"`                   // Raw body string (để debug)
 
+	// ===== SIGNATURE INFO =====
+	OwnerOrganizationID primitive.ObjectID `json:"ownerOrganizationId,omitempty" bson:"ownerOrganizationId,omitempty" index:"single:1"` // Tổ chức resolve từ pageId/shopId (dùng secret của tổ chức)
+	SignatureStatus     string             `json:"signatureStatus,omitempty" bson:"signatureStatus,omitempty" index:"single:1"`   // valid, unsigned, invalid, stale_timestamp, replayed, not_configured, unknown_organization
+	SignatureMode       string             `json:"signatureMode,omitempty" bson:"signatureMode,omitempty"`                         // enforce hoặc grace (chỉ log khi sai chữ ký)
+	Rejected            bool               `json:"rejected,omitempty" bson:"rejected,omitempty" index:"single:1"`                  // Bị từ chối vì chữ ký (mode enforce), không xử lý
+
 	// ===== PROCESSING INFO =====
 	Processed    bool   `json:"processed" bson:"processed" index:"single:1"`     // Đã xử lý thành công chưa
 	ProcessError string `json:"processError,omitempty" bson:"processError,omitempty"` // Lỗi nếu có trong quá trình xử lý
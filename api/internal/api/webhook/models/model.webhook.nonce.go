@@ -0,0 +1,19 @@
+// Package models chứa các model thuộc domain Webhook.
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WebhookNonce lưu nonce đã dùng của webhook có chữ ký — chống replay trong cửa sổ timestamp.
+// Unique (source, nonce): insert trùng = request bị phát lại. TTL tự xóa sau khi hết cửa sổ.
+type WebhookNonce struct {
+	ID                  primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	Source              string             `json:"source" bson:"source" index:"compound:source_nonce_unique"` // "pancake" hoặc "pancake_pos"
+	Nonce               string             `json:"nonce" bson:"nonce" index:"compound:source_nonce_unique"`   // Header nonce, không có thì dùng chữ ký
+	OwnerOrganizationID primitive.ObjectID `json:"ownerOrganizationId,omitempty" bson:"ownerOrganizationId,omitempty"`
+	ExpiresAt           time.Time          `json:"expiresAt" bson:"expiresAt" index:"single:1,ttl:0"` // TTL: xóa khi hết cửa sổ chống replay
+	CreatedAt           int64              `json:"createdAt" bson:"createdAt"`
+}
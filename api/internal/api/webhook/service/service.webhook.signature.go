@@ -0,0 +1,243 @@
+// Package webhooksvc chứa service cho domain Webhook.
+// File: service.webhook.signature.go — xác thực chữ ký HMAC và chống replay cho webhook Pancake / Pancake POS.
+package webhooksvc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	authsvc "meta_commerce/internal/api/auth/service"
+	webhookmodels "meta_commerce/internal/api/webhook/models"
+	"meta_commerce/internal/common"
+	"meta_commerce/internal/global"
+	"meta_commerce/internal/keyring"
+	"meta_commerce/internal/logger"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Config item (auth_organization_config_items) cho chữ ký webhook — mỗi tổ chức một secret riêng.
+const (
+	ConfigKeyPancakeWebhookSecret    = "webhook_pancake_secret"
+	ConfigKeyPancakePosWebhookSecret = "webhook_pancake_pos_secret"
+	ConfigKeyWebhookSignatureMode    = "webhook_signature_mode" // enforce (mặc định) | grace
+)
+
+func init() {
+	// Secret ký webhook lưu mã hóa bằng keyring như các secret khác (cmd/reencrypt_secrets xoay key).
+	authsvc.RegisterSecretConfigKeys(ConfigKeyPancakeWebhookSecret, ConfigKeyPancakePosWebhookSecret)
+}
+
+// Mode xác thực chữ ký.
+const (
+	SignatureModeEnforce = "enforce" // Sai chữ ký → từ chối (401), không xử lý
+	SignatureModeGrace   = "grace"   // Sai chữ ký → chỉ log, vẫn xử lý (giai đoạn rollout)
+)
+
+// Trạng thái chữ ký ghi vào webhook_logs.signatureStatus.
+const (
+	SignatureStatusValid         = "valid"
+	SignatureStatusUnsigned      = "unsigned"
+	SignatureStatusInvalid       = "invalid"
+	SignatureStatusStale         = "stale_timestamp"
+	SignatureStatusReplayed      = "replayed"
+	SignatureStatusNotConfigured = "not_configured"       // Tổ chức chưa cấu hình secret → chấp nhận như trước
+	SignatureStatusUnknownOrg    = "unknown_organization" // Không xác định được tổ chức (page / shop) → không biết secret, từ chối
+)
+
+// Header chữ ký. Chuỗi ký: "<timestamp>.<rawBody>" hoặc "<timestamp>.<nonce>.<rawBody>" khi có nonce.
+const (
+	HeaderWebhookSignature = "X-Webhook-Signature" // hex HMAC-SHA256, chấp nhận tiền tố "sha256="
+	HeaderWebhookTimestamp = "X-Webhook-Timestamp" // Unix giây hoặc mili giây
+	HeaderWebhookNonce     = "X-Webhook-Nonce"
+)
+
+// SignatureTolerance cửa sổ lệch timestamp cho phép (cũng là thời gian giữ nonce).
+const SignatureTolerance = 5 * time.Minute
+
+// SignedRequest dữ liệu cần cho xác thực một request webhook.
+// Không có header chữ ký → fallback field "signature" trong body: ký trên raw JSON của "payload", timestamp = payload.timestamp.
+type SignedRequest struct {
+	RawBody   []byte
+	Signature string // Header X-Webhook-Signature
+	Timestamp string // Header X-Webhook-Timestamp
+	Nonce     string // Header X-Webhook-Nonce
+}
+
+// SignatureResult kết quả xác thực.
+type SignatureResult struct {
+	Status string
+	Mode   string
+	Reason string
+}
+
+// Rejected request phải bị từ chối (mode enforce và chữ ký không hợp lệ).
+func (r SignatureResult) Rejected() bool {
+	return r.Mode == SignatureModeEnforce && r.Status != SignatureStatusValid && r.Status != SignatureStatusNotConfigured
+}
+
+// WebhookSignatureService đọc secret theo tổ chức và giữ nonce đã dùng.
+type WebhookSignatureService struct {
+	configItemService *authsvc.OrganizationConfigItemService
+	nonceCollection   *mongo.Collection
+}
+
+// NewWebhookSignatureService tạo mới WebhookSignatureService
+func NewWebhookSignatureService() (*WebhookSignatureService, error) {
+	nonceCollection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.WebhookNonces)
+	if !exist {
+		return nil, fmt.Errorf("failed to get webhook_nonces collection: %v", common.ErrNotFound)
+	}
+	configItemService, err := authsvc.NewOrganizationConfigItemService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create organization config item service: %w", err)
+	}
+	return &WebhookSignatureService{
+		configItemService: configItemService,
+		nonceCollection:   nonceCollection,
+	}, nil
+}
+
+// Verify xác thực chữ ký request theo secret của tổ chức (source: "pancake" | "pancake_pos").
+// Tổ chức chưa cấu hình secret → not_configured (chấp nhận, chỉ ghi nhận). orgID nil → unknown_organization với mode
+// enforce mặc định: không có tổ chức thì không có secret lẫn mode grace, event giả mạo không được lọt qua.
+func (s *WebhookSignatureService) Verify(ctx context.Context, source string, orgID *primitive.ObjectID, req SignedRequest) SignatureResult {
+	if orgID == nil || orgID.IsZero() {
+		return SignatureResult{Status: SignatureStatusUnknownOrg, Mode: SignatureModeEnforce, Reason: "không xác định được tổ chức"}
+	}
+	secret, err := s.configSecret(ctx, *orgID, secretConfigKey(source))
+	mode := SignatureModeEnforce
+	if strings.EqualFold(s.configString(ctx, *orgID, ConfigKeyWebhookSignatureMode), SignatureModeGrace) {
+		mode = SignatureModeGrace
+	}
+	if err != nil {
+		// Có secret nhưng không giải mã được (thiếu key trong ENCRYPTION_KEYS) — không coi là chưa cấu hình
+		logger.GetAppLogger().WithError(err).WithFields(map[string]interface{}{
+			"organizationId": orgID.Hex(), "source": source,
+		}).Error("[Webhook] Không giải mã được secret chữ ký")
+		return SignatureResult{Status: SignatureStatusInvalid, Mode: mode, Reason: "không giải mã được secret"}
+	}
+	if secret == "" {
+		return SignatureResult{Status: SignatureStatusNotConfigured, Reason: "tổ chức chưa cấu hình secret"}
+	}
+
+	now := time.Now()
+	status, nonce, reason := VerifySignature([]byte(secret), req, now)
+	res := SignatureResult{Status: status, Mode: mode, Reason: reason}
+	if status != SignatureStatusValid {
+		return res
+	}
+	fresh, err := s.claimNonce(ctx, source, nonce, *orgID, now)
+	if err != nil {
+		// Lỗi DB không được biến request hợp lệ thành bị từ chối
+		res.Reason = "không kiểm tra được nonce: " + err.Error()
+		return res
+	}
+	if !fresh {
+		res.Status = SignatureStatusReplayed
+		res.Reason = "nonce đã được dùng"
+	}
+	return res
+}
+
+// claimNonce ghi nonce; trả false nếu nonce đã tồn tại (request phát lại).
+func (s *WebhookSignatureService) claimNonce(ctx context.Context, source, nonce string, orgID primitive.ObjectID, now time.Time) (bool, error) {
+	doc := webhookmodels.WebhookNonce{
+		Source: source, Nonce: nonce, OwnerOrganizationID: orgID,
+		ExpiresAt: now.Add(2 * SignatureTolerance), CreatedAt: now.UnixMilli(),
+	}
+	if _, err := s.nonceCollection.InsertOne(ctx, doc); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *WebhookSignatureService) configString(ctx context.Context, orgID primitive.ObjectID, key string) string {
+	item, err := s.configItemService.GetByOwnerOrganizationIDAndKey(ctx, orgID, key)
+	if err != nil || item == nil {
+		return ""
+	}
+	v, _ := item.Value.(string)
+	return strings.TrimSpace(v)
+}
+
+// configSecret giải mã secret (key đăng ký qua authsvc.RegisterSecretConfigKeys). Plaintext cũ chưa chạy
+// cmd/reencrypt_secrets đọc nguyên văn.
+func (s *WebhookSignatureService) configSecret(ctx context.Context, orgID primitive.ObjectID, key string) (string, error) {
+	plain, err := keyring.DecryptString(s.configString(ctx, orgID, key))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(plain), nil
+}
+
+func secretConfigKey(source string) string {
+	if source == "pancake_pos" {
+		return ConfigKeyPancakePosWebhookSecret
+	}
+	return ConfigKeyPancakeWebhookSecret
+}
+
+// VerifySignature kiểm tra chữ ký + timestamp (không kiểm tra nonce). Trả status, nonce dùng chống replay và lý do.
+func VerifySignature(secret []byte, req SignedRequest, now time.Time) (status, nonce, reason string) {
+	signature, timestamp, signed := req.Signature, req.Timestamp, []byte(nil)
+	if signature != "" {
+		if req.Nonce != "" {
+			signed = []byte(timestamp + "." + req.Nonce + "." + string(req.RawBody))
+		} else {
+			signed = []byte(timestamp + "." + string(req.RawBody))
+		}
+	} else {
+		// Fallback: field "signature" trong body, ký trên raw JSON của "payload"
+		var body struct {
+			Payload   json.RawMessage `json:"payload"`
+			Signature string          `json:"signature"`
+		}
+		if err := json.Unmarshal(req.RawBody, &body); err != nil || body.Signature == "" || len(body.Payload) == 0 {
+			return SignatureStatusUnsigned, "", "thiếu chữ ký"
+		}
+		var p struct {
+			Timestamp int64 `json:"timestamp"`
+		}
+		_ = json.Unmarshal(body.Payload, &p)
+		signature, timestamp = body.Signature, strconv.FormatInt(p.Timestamp, 10)
+		signed = []byte(timestamp + "." + string(body.Payload))
+	}
+
+	ts, err := strconv.ParseInt(strings.TrimSpace(timestamp), 10, 64)
+	if err != nil || ts <= 0 {
+		return SignatureStatusInvalid, "", "timestamp không hợp lệ"
+	}
+	if ts > 1e12 { // mili giây
+		ts /= 1000
+	}
+	if skew := now.Sub(time.Unix(ts, 0)); skew > SignatureTolerance || skew < -SignatureTolerance {
+		return SignatureStatusStale, "", fmt.Sprintf("timestamp lệch %s", skew.Round(time.Second))
+	}
+
+	got, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(signature), "sha256="))
+	if err != nil {
+		return SignatureStatusInvalid, "", "chữ ký không phải hex"
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(signed)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return SignatureStatusInvalid, "", "chữ ký không khớp"
+	}
+	nonce = req.Nonce
+	if nonce == "" {
+		nonce = hex.EncodeToString(got)
+	}
+	return SignatureStatusValid, nonce, ""
+}
@@ -0,0 +1,93 @@
+package webhooksvc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func sign(secret, msg string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(msg))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignature_Header(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	body := `{"payload":{"eventType":"message_received","pageId":"p1"}}`
+	ts := strconv.FormatInt(now.Unix(), 10)
+
+	req := SignedRequest{RawBody: []byte(body), Signature: "sha256=" + sign("s3cret", ts+".n1."+body), Timestamp: ts, Nonce: "n1"}
+	status, nonce, _ := VerifySignature([]byte("s3cret"), req, now)
+	if status != SignatureStatusValid || nonce != "n1" {
+		t.Fatalf("status=%s nonce=%s, muốn valid/n1", status, nonce)
+	}
+
+	req.RawBody = []byte(body + " ")
+	if status, _, _ := VerifySignature([]byte("s3cret"), req, now); status != SignatureStatusInvalid {
+		t.Errorf("body bị sửa: status=%s, muốn invalid", status)
+	}
+
+	if status, _, _ := VerifySignature([]byte("s3cret"), SignedRequest{RawBody: []byte(body)}, now); status != SignatureStatusUnsigned {
+		t.Errorf("không có chữ ký: status=%s, muốn unsigned", status)
+	}
+
+	old := strconv.FormatInt(now.Add(-10*time.Minute).UnixMilli(), 10)
+	stale := SignedRequest{RawBody: []byte(body), Signature: sign("s3cret", old+"."+body), Timestamp: old}
+	if status, _, _ := VerifySignature([]byte("s3cret"), stale, now); status != SignatureStatusStale {
+		t.Errorf("timestamp cũ: status=%s, muốn stale_timestamp", status)
+	}
+}
+
+func TestVerifySignature_BodyFieldFallback(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	payload := `{"eventType":"order_created","shopId":7,"timestamp":` + strconv.FormatInt(now.UnixMilli(), 10) + `}`
+	sig := sign("pos", strconv.FormatInt(now.UnixMilli(), 10)+"."+payload)
+	body := `{"payload":` + payload + `,"signature":"` + sig + `"}`
+
+	status, nonce, reason := VerifySignature([]byte("pos"), SignedRequest{RawBody: []byte(body)}, now)
+	if status != SignatureStatusValid {
+		t.Fatalf("status=%s (%s), muốn valid", status, reason)
+	}
+	if nonce != sig {
+		t.Errorf("không có header nonce thì nonce = chữ ký, got %s", nonce)
+	}
+	if status, _, _ := VerifySignature([]byte("khac"), SignedRequest{RawBody: []byte(body)}, now); status != SignatureStatusInvalid {
+		t.Errorf("sai secret: status=%s, muốn invalid", status)
+	}
+}
+
+func TestVerify_UnknownOrganizationRejected(t *testing.T) {
+	svc := &WebhookSignatureService{}
+	for _, orgID := range []*primitive.ObjectID{nil, {}} {
+		res := svc.Verify(context.Background(), "pancake", orgID, SignedRequest{RawBody: []byte(`{}`)})
+		if res.Status != SignatureStatusUnknownOrg || !res.Rejected() {
+			t.Fatalf("không xác định được tổ chức phải bị từ chối: %+v", res)
+		}
+	}
+}
+
+func TestSignatureResult_Rejected(t *testing.T) {
+	cases := []struct {
+		res  SignatureResult
+		want bool
+	}{
+		{SignatureResult{Status: SignatureStatusInvalid, Mode: SignatureModeEnforce}, true},
+		{SignatureResult{Status: SignatureStatusReplayed, Mode: SignatureModeEnforce}, true},
+		{SignatureResult{Status: SignatureStatusInvalid, Mode: SignatureModeGrace}, false},
+		{SignatureResult{Status: SignatureStatusValid, Mode: SignatureModeEnforce}, false},
+		{SignatureResult{Status: SignatureStatusNotConfigured}, false},
+		{SignatureResult{Status: SignatureStatusUnknownOrg, Mode: SignatureModeEnforce}, true},
+	}
+	for _, c := range cases {
+		if got := c.res.Rejected(); got != c.want {
+			t.Errorf("%+v: Rejected()=%v, muốn %v", c.res, got, c.want)
+		}
+	}
+}
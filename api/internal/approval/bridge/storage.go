@@ -44,26 +44,78 @@ func (s *MongoStorage) Update(ctx context.Context, doc *pkgapproval.ActionPendin
 	if !ok {
 		return fmt.Errorf("không tìm thấy collection action_pending_approval")
 	}
+	_, err := coll.UpdateOne(ctx, bson.M{"_id": doc.ID}, bson.M{"$set": updateSet(doc)})
+	return err
+}
+
+// UpdateIfStatus cập nhật document theo _id khi status hiện tại = fromStatus. Trả false khi không khớp.
+func (s *MongoStorage) UpdateIfStatus(ctx context.Context, doc *pkgapproval.ActionPending, fromStatus string) (bool, error) {
+	coll, ok := global.RegistryCollections.Get(global.MongoDB_ColNames.ActionPendingApproval)
+	if !ok {
+		return false, fmt.Errorf("không tìm thấy collection action_pending_approval")
+	}
+	res, err := coll.UpdateOne(ctx, bson.M{"_id": doc.ID, "status": fromStatus}, bson.M{"$set": updateSet(doc)})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+// PushApproval thêm lượt duyệt bằng $push — không ghi đè lượt duyệt đồng thời của người khác.
+// Filter: status=pending, approver chưa có trong approvals, approvals có đúng expectedCount phần tử.
+func (s *MongoStorage) PushApproval(ctx context.Context, id primitive.ObjectID, decision pkgapproval.ApprovalDecision, expectedCount int, now int64) (bool, error) {
+	coll, ok := global.RegistryCollections.Get(global.MongoDB_ColNames.ActionPendingApproval)
+	if !ok {
+		return false, fmt.Errorf("không tìm thấy collection action_pending_approval")
+	}
+	filter := bson.M{
+		"_id":              id,
+		"status":           pkgapproval.StatusPending,
+		"approvals.userId": bson.M{"$ne": decision.UserID},
+		fmt.Sprintf("approvals.%d", expectedCount): bson.M{"$exists": false},
+	}
+	if expectedCount > 0 {
+		filter[fmt.Sprintf("approvals.%d", expectedCount-1)] = bson.M{"$exists": true}
+	}
+	update := bson.M{
+		"$push": bson.M{"approvals": decision},
+		"$set":  bson.M{"updatedAt": now},
+	}
+	res, err := coll.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+// updateSet các field thay đổi trong vòng đời đề xuất (Update / UpdateIfStatus).
+func updateSet(doc *pkgapproval.ActionPending) bson.M {
 	set := bson.M{
-		"status":          doc.Status,
-		"approvedAt":      doc.ApprovedAt,
-		"rejectedAt":      doc.RejectedAt,
-		"rejectedBy":      doc.RejectedBy,
-		"decisionNote":    doc.DecisionNote,
-		"executedAt":      doc.ExecutedAt,
-		"executeResponse": doc.ExecuteResponse,
-		"executeError":    doc.ExecuteError,
-		"retryCount":      doc.RetryCount,
-		"maxRetries":      doc.MaxRetries,
-		"updatedAt":       doc.UpdatedAt,
+		"status":             doc.Status,
+		"approvedAt":         doc.ApprovedAt,
+		"approvedBy":         doc.ApprovedBy,
+		"approvals":          doc.Approvals,
+		"rejectedAt":         doc.RejectedAt,
+		"rejectedBy":         doc.RejectedBy,
+		"decisionNote":       doc.DecisionNote,
+		"executedAt":         doc.ExecutedAt,
+		"executeResponse":    doc.ExecuteResponse,
+		"executeError":       doc.ExecuteError,
+		"retryCount":         doc.RetryCount,
+		"maxRetries":         doc.MaxRetries,
+		"updatedAt":          doc.UpdatedAt,
+		"expiredAt":          doc.ExpiredAt,
+		"escalationLevel":    doc.EscalationLevel,
+		"nextEscalationAt":   doc.NextEscalationAt,
+		"compensation":       doc.Compensation,
+		"revertedByActionId": doc.RevertedByActionID,
 	}
 	if doc.NextRetryAt != nil {
 		set["nextRetryAt"] = *doc.NextRetryAt
 	} else {
 		set["nextRetryAt"] = nil
 	}
-	_, err := coll.UpdateOne(ctx, bson.M{"_id": doc.ID}, bson.M{"$set": set})
-	return err
+	return set
 }
 
 // FindByIdempotencyKey tìm action đã xử lý theo payload.idempotencyKey (Vision 08 idempotency enforce).
@@ -76,9 +128,9 @@ func (s *MongoStorage) FindByIdempotencyKey(ctx context.Context, idempotencyKey
 		return nil, nil
 	}
 	filter := bson.M{
-		"ownerOrganizationId": ownerOrgID,
+		"ownerOrganizationId":    ownerOrgID,
 		"payload.idempotencyKey": idempotencyKey,
-		"status": bson.M{"$in": []string{pkgapproval.StatusExecuted, pkgapproval.StatusRejected, pkgapproval.StatusFailed}},
+		"status":                 bson.M{"$in": []string{pkgapproval.StatusExecuted, pkgapproval.StatusRejected, pkgapproval.StatusFailed}},
 	}
 	var doc pkgapproval.ActionPending
 	err := coll.FindOne(ctx, filter).Decode(&doc)
@@ -172,6 +224,38 @@ func (s *MongoStorage) FindQueued(ctx context.Context, domain string, limit int)
 	return out, nil
 }
 
+// FindDueForSweep item status=pending (mọi org) đã hết hạn hoặc tới mốc SLA escalation.
+func (s *MongoStorage) FindDueForSweep(ctx context.Context, now int64, limit int) ([]pkgapproval.ActionPending, error) {
+	coll, ok := global.RegistryCollections.Get(global.MongoDB_ColNames.ActionPendingApproval)
+	if !ok {
+		return nil, fmt.Errorf("không tìm thấy collection action_pending_approval")
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+	filter := bson.M{
+		"status": pkgapproval.StatusPending,
+		"$or": []bson.M{
+			{"expiresAt": bson.M{"$gt": 0, "$lte": now}},
+			{"nextEscalationAt": bson.M{"$gt": 0, "$lte": now}},
+		},
+	}
+	opts := mongoopts.Find().SetSort(bson.D{{Key: "proposedAt", Value: 1}}).SetLimit(int64(limit))
+	cursor, err := coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	var out []pkgapproval.ActionPending
+	if err := cursor.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	if out == nil {
+		out = []pkgapproval.ActionPending{}
+	}
+	return out, nil
+}
+
 // Find danh sách với filter (domain, status, limit, sort) — phục vụ frontend xem.
 func (s *MongoStorage) Find(ctx context.Context, ownerOrgID primitive.ObjectID, filter pkgapproval.FindFilter) ([]pkgapproval.ActionPending, error) {
 	coll, ok := global.RegistryCollections.Get(global.MongoDB_ColNames.ActionPendingApproval)
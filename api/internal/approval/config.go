@@ -55,6 +55,33 @@ func GetApprovalMode(ctx context.Context, ownerOrgID primitive.ObjectID, domain,
 	return pkgapproval.ApprovalModeManualRequired, nil
 }
 
+// resolvePolicy tìm ApprovalPolicy áp cho đề xuất từ ApprovalModeConfig.Policies (scope cụ thể → default).
+func resolvePolicy(ctx context.Context, doc *pkgapproval.ActionPending) (*pkgapproval.ApprovalPolicy, error) {
+	cfg, err := findApprovalModeConfig(ctx, doc.OwnerOrganizationID, doc.Domain, scopeKeyFromPayload(doc.Payload))
+	if err != nil || cfg == nil {
+		return nil, err
+	}
+	return cfg.MatchPolicy(doc.ActionType, doc.Payload), nil
+}
+
+// resolveExpiry tìm ExpiryPolicy (TTL, auto-decision, mốc SLA) từ ApprovalModeConfig theo actionType.
+func resolveExpiry(ctx context.Context, doc *pkgapproval.ActionPending) (*pkgapproval.ExpiryPolicy, error) {
+	cfg, err := findApprovalModeConfig(ctx, doc.OwnerOrganizationID, doc.Domain, scopeKeyFromPayload(doc.Payload))
+	if err != nil || cfg == nil {
+		return nil, err
+	}
+	return cfg.ExpiryFor(doc.ActionType), nil
+}
+
+// scopeKeyFromPayload scopeKey của đề xuất — hiện dùng adAccountId (domain ads).
+func scopeKeyFromPayload(payload map[string]interface{}) string {
+	if payload == nil {
+		return ""
+	}
+	s, _ := payload["adAccountId"].(string)
+	return s
+}
+
 // findApprovalModeConfig tìm config theo (ownerOrgID, domain, scopeKey).
 // Ưu tiên scopeKey cụ thể; nếu không có thì thử scopeKey="" (default).
 func findApprovalModeConfig(ctx context.Context, ownerOrgID primitive.ObjectID, domain, scopeKey string) (*pkgapproval.ApprovalModeConfig, error) {
@@ -24,17 +24,18 @@ func Init() {
 		defaultEngine = pkgapproval.NewEngine(storage, notifier)
 		// ResolveImmediate (Vision 08): đọc ApprovalModeConfig → auto Approve nếu mode=auto
 		pkgapproval.SetResolver(pkgapproval.ResolverFunc(resolveImmediate))
+		// Duyệt nhiều bước / quorum: đọc ApprovalModeConfig.Policies
+		pkgapproval.SetPolicyResolver(pkgapproval.PolicyResolverFunc(resolvePolicy))
+		// Hết hạn chờ duyệt + SLA escalation: đọc ApprovalModeConfig.Expiry / ActionExpiry
+		pkgapproval.SetExpiryResolver(pkgapproval.ExpiryResolverFunc(resolveExpiry))
 	})
 }
 
 // resolveImmediate quyết định có nên auto-approve ngay sau Propose không.
 func resolveImmediate(ctx context.Context, doc *pkgapproval.ActionPending) bool {
-	scopeKey := ""
+	scopeKey := scopeKeyFromPayload(doc.Payload)
 	ruleCode := ""
 	if doc.Payload != nil {
-		if s, ok := doc.Payload["adAccountId"].(string); ok {
-			scopeKey = s
-		}
 		if s, ok := doc.Payload["ruleCode"].(string); ok {
 			ruleCode = s
 		}
@@ -3,9 +3,12 @@ package approval
 
 import (
 	"context"
+	"time"
 
+	"meta_commerce/internal/global"
 	pkgapproval "meta_commerce/pkg/approval"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -46,18 +49,38 @@ func ProposeAndApproveAuto(ctx context.Context, domain string, input ProposeInpu
 	}, ownerOrgID)
 }
 
-// Approve duyệt đề xuất. Delegate sang engine.
-func Approve(ctx context.Context, actionId string, ownerOrgID primitive.ObjectID) (*pkgapproval.ActionPending, error) {
+// Approve ghi lượt duyệt của approver. Delegate sang engine (enforce policy nhiều bước / quorum).
+func Approve(ctx context.Context, actionId string, ownerOrgID primitive.ObjectID, approver Approver) (*pkgapproval.ActionPending, error) {
 	Init()
-	return GetEngine().Approve(ctx, actionId, ownerOrgID)
+	return GetEngine().Approve(ctx, actionId, ownerOrgID, approver)
 }
 
 // Reject từ chối đề xuất. Delegate sang engine.
-func Reject(ctx context.Context, actionId string, ownerOrgID primitive.ObjectID, decisionNote, rejectedBy string) (*pkgapproval.ActionPending, error) {
+func Reject(ctx context.Context, actionId string, ownerOrgID primitive.ObjectID, decisionNote string, rejectedBy Approver) (*pkgapproval.ActionPending, error) {
 	Init()
 	return GetEngine().Reject(ctx, actionId, ownerOrgID, decisionNote, rejectedBy)
 }
 
+// ResolveApprover dựng Approver từ user_id + active_role_id (auth context); tên role đọc từ collection roles.
+func ResolveApprover(ctx context.Context, userID, activeRoleID string) Approver {
+	a := Approver{UserID: userID, RoleID: activeRoleID}
+	roleOID, err := primitive.ObjectIDFromHex(activeRoleID)
+	if err != nil {
+		return a
+	}
+	coll, ok := global.RegistryCollections.Get(global.MongoDB_ColNames.Roles)
+	if !ok {
+		return a
+	}
+	var role struct {
+		Name string `bson:"name"`
+	}
+	if err := coll.FindOne(ctx, bson.M{"_id": roleOID}).Decode(&role); err == nil {
+		a.Role = role.Name
+	}
+	return a
+}
+
 // Execute thực thi thủ công đề xuất đã duyệt (status=queued). Dùng cho test — user trigger thay vì chờ worker.
 func Execute(ctx context.Context, actionId string, ownerOrgID primitive.ObjectID) (*pkgapproval.ActionPending, error) {
 	Init()
@@ -130,9 +153,27 @@ func NotifyFailed(ctx context.Context, doc *pkgapproval.ActionPending) {
 	GetEngine().NotifyFailed(ctx, doc)
 }
 
+// Sweep xử lý đề xuất pending đã hết hạn / tới mốc SLA (worker approval_expiry gọi).
+func Sweep(ctx context.Context, limit int) (pkgapproval.SweepResult, error) {
+	Init()
+	return GetEngine().Sweep(ctx, time.Now().UnixMilli(), limit)
+}
+
+// Revert đề xuất (hoặc duyệt luôn khi input.Execute) action nghịch đảo cho action đã thực thi.
+func Revert(ctx context.Context, actionId string, ownerOrgID primitive.ObjectID, requester Approver, input RevertInput, baseURL string) (*pkgapproval.ActionPending, error) {
+	Init()
+	return GetEngine().Revert(ctx, actionId, ownerOrgID, requester, input, baseURL)
+}
+
 // ActionPending re-export từ pkg/approval để callers không cần import pkg.
 type ActionPending = pkgapproval.ActionPending
 
+// Approver re-export từ pkg/approval.
+type Approver = pkgapproval.Approver
+
+// RevertInput re-export từ pkg/approval.
+type RevertInput = pkgapproval.RevertInput
+
 // FindFilter re-export từ pkg/approval.
 type FindFilter = pkgapproval.FindFilter
 
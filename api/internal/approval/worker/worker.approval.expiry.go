@@ -0,0 +1,83 @@
+// Package worker — ApprovalExpiryWorker quét action_pending_approval status=pending:
+// hết hạn → auto-reject (expired) / auto-approve theo ApprovalModeConfig; tới mốc SLA → escalation qua notifytrigger.
+// Tách package riêng để tránh import cycle (approval -> notifytrigger -> delivery -> worker).
+package worker
+
+import (
+	"context"
+	"time"
+
+	"meta_commerce/internal/approval"
+	"meta_commerce/internal/logger"
+	coreworker "meta_commerce/internal/worker"
+)
+
+// ApprovalExpiryWorker sweep định kỳ đề xuất chờ duyệt quá hạn / tới mốc SLA (mọi domain).
+type ApprovalExpiryWorker struct {
+	interval  time.Duration
+	batchSize int
+}
+
+// NewApprovalExpiryWorker tạo mới ApprovalExpiryWorker.
+func NewApprovalExpiryWorker(interval time.Duration, batchSize int) *ApprovalExpiryWorker {
+	if interval < 15*time.Second {
+		interval = 1 * time.Minute
+	}
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	return &ApprovalExpiryWorker{interval: interval, batchSize: batchSize}
+}
+
+// Start chạy worker trong vòng lặp. Đọc schedule mỗi vòng (hỗ trợ thay đổi qua API).
+func (w *ApprovalExpiryWorker) Start(ctx context.Context) {
+	log := logger.GetAppLogger()
+
+	log.WithFields(map[string]interface{}{
+		"interval":  w.interval.String(),
+		"batchSize": w.batchSize,
+	}).Info("⏰ [APPROVAL_EXPIRY] Starting Approval Expiry Worker...")
+
+	for {
+		interval, batchSize := coreworker.GetEffectiveWorkerSchedule(coreworker.WorkerApprovalExpiry, w.interval, w.batchSize)
+
+		select {
+		case <-ctx.Done():
+			log.Info("⏰ [APPROVAL_EXPIRY] Approval Expiry Worker stopped")
+			return
+		case <-time.After(interval):
+		}
+
+		if !coreworker.IsWorkerActive(coreworker.WorkerApprovalExpiry) {
+			continue
+		}
+		p := coreworker.GetPriority(coreworker.WorkerApprovalExpiry, coreworker.PriorityNormal)
+		if coreworker.ShouldThrottle(p) {
+			continue
+		}
+		w.process(ctx, coreworker.GetEffectiveBatchSize(batchSize, p))
+	}
+}
+
+func (w *ApprovalExpiryWorker) process(ctx context.Context, batchSize int) {
+	log := logger.GetAppLogger()
+	defer func() {
+		if r := recover(); r != nil {
+			log.WithFields(map[string]interface{}{"panic": r}).Error("⏰ [APPROVAL_EXPIRY] Panic khi xử lý, sẽ tiếp tục lần sau")
+		}
+	}()
+
+	res, err := approval.Sweep(ctx, batchSize)
+	if err != nil {
+		log.WithError(err).Error("⏰ [APPROVAL_EXPIRY] Lỗi sweep đề xuất chờ duyệt")
+		return
+	}
+	if res.Expired+res.AutoApproved+res.Escalated+res.Errors > 0 {
+		log.WithFields(map[string]interface{}{
+			"expired":      res.Expired,
+			"autoApproved": res.AutoApproved,
+			"escalated":    res.Escalated,
+			"errors":       res.Errors,
+		}).Info("⏰ [APPROVAL_EXPIRY] Đã xử lý đề xuất quá hạn / tới mốc SLA")
+	}
+}
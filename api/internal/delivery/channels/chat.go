@@ -0,0 +1,191 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	notifmodels "meta_commerce/internal/api/notification/models"
+)
+
+// Giới hạn nội dung của Slack / Discord (vượt quá → API trả 400).
+const (
+	slackMaxSectionText   = 3000
+	slackMaxHeaderText    = 150
+	slackMaxButtonText    = 75
+	discordMaxTitle       = 256
+	discordMaxDescription = 4096
+	discordMaxButtonLabel = 80
+	discordButtonsPerRow  = 5
+	discordMaxRows        = 5
+	discordEmbedColor     = 0x1F6FEB
+)
+
+// SendSlack gửi qua Slack incoming webhook (hoặc webhook tương thích Slack: Mattermost, Rocket.Chat).
+// Subject → header block, Content → section mrkdwn, CTA → button trong actions block.
+func SendSlack(ctx context.Context, sender *notifmodels.NotificationChannelSender, webhookURL string, template *RenderedTemplate) (*WebhookResponse, error) {
+	body, err := json.Marshal(buildSlackBody(sender, template))
+	if err != nil {
+		return nil, &PermanentError{Err: err}
+	}
+	return postChatWebhook(ctx, webhookURL, body)
+}
+
+// SendDiscord gửi qua Discord webhook: Subject/Content → embed, CTA → link button.
+func SendDiscord(ctx context.Context, sender *notifmodels.NotificationChannelSender, webhookURL string, template *RenderedTemplate) (*WebhookResponse, error) {
+	body, err := json.Marshal(buildDiscordBody(sender, template))
+	if err != nil {
+		return nil, &PermanentError{Err: err}
+	}
+	// with_components=true: webhook không thuộc application vẫn gửi được link button
+	sep := "?"
+	if strings.Contains(webhookURL, "?") {
+		sep = "&"
+	}
+	return postChatWebhook(ctx, webhookURL+sep+"with_components=true", body)
+}
+
+func buildSlackBody(sender *notifmodels.NotificationChannelSender, template *RenderedTemplate) map[string]interface{} {
+	content := escapeSlack(template.Content)
+	blocks := []map[string]interface{}{}
+	if s := strings.TrimSpace(template.Subject); s != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "header",
+			"text": map[string]interface{}{"type": "plain_text", "text": truncateRunes(s, slackMaxHeaderText), "emoji": true},
+		})
+	}
+	if strings.TrimSpace(content) != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "section",
+			"text": map[string]interface{}{"type": "mrkdwn", "text": truncateRunes(content, slackMaxSectionText)},
+		})
+	}
+	buttons := []map[string]interface{}{}
+	for i, cta := range template.CTAs {
+		if cta.Action == "" {
+			continue
+		}
+		button := map[string]interface{}{
+			"type":      "button",
+			"text":      map[string]interface{}{"type": "plain_text", "text": truncateRunes(cta.Label, slackMaxButtonText), "emoji": true},
+			"url":       cta.Action,
+			"action_id": fmt.Sprintf("cta_%d", i),
+		}
+		switch cta.Style {
+		case "primary":
+			button["style"] = "primary"
+		case "danger":
+			button["style"] = "danger"
+		}
+		buttons = append(buttons, button)
+	}
+	if len(buttons) > 0 {
+		blocks = append(blocks, map[string]interface{}{"type": "actions", "elements": buttons})
+	}
+
+	// text: fallback cho notification đẩy / client không hiển thị block
+	text := template.Subject
+	if text == "" {
+		text = content
+	}
+	body := map[string]interface{}{"text": truncateRunes(text, slackMaxSectionText), "blocks": blocks}
+	if sender != nil {
+		if sender.ChatUsername != "" {
+			body["username"] = sender.ChatUsername
+		}
+		if sender.ChatIconURL != "" {
+			body["icon_url"] = sender.ChatIconURL
+		}
+	}
+	return body
+}
+
+func buildDiscordBody(sender *notifmodels.NotificationChannelSender, template *RenderedTemplate) map[string]interface{} {
+	embed := map[string]interface{}{
+		"description": truncateRunes(template.Content, discordMaxDescription),
+		"color":       discordEmbedColor,
+		"timestamp":   time.Now().UTC().Format(time.RFC3339),
+	}
+	if s := strings.TrimSpace(template.Subject); s != "" {
+		embed["title"] = truncateRunes(s, discordMaxTitle)
+	}
+	body := map[string]interface{}{
+		"embeds":           []map[string]interface{}{embed},
+		"allowed_mentions": map[string]interface{}{"parse": []string{}}, // Không ping @everyone / role từ nội dung payload
+	}
+
+	rows := []map[string]interface{}{}
+	row := []map[string]interface{}{}
+	for _, cta := range template.CTAs {
+		if cta.Action == "" || len(rows) == discordMaxRows {
+			continue
+		}
+		// style 5 = link button
+		row = append(row, map[string]interface{}{"type": 2, "style": 5, "label": truncateRunes(cta.Label, discordMaxButtonLabel), "url": cta.Action})
+		if len(row) == discordButtonsPerRow {
+			rows = append(rows, map[string]interface{}{"type": 1, "components": row})
+			row = []map[string]interface{}{}
+		}
+	}
+	if len(row) > 0 && len(rows) < discordMaxRows {
+		rows = append(rows, map[string]interface{}{"type": 1, "components": row})
+	}
+	if len(rows) > 0 {
+		body["components"] = rows
+	}
+	if sender != nil {
+		if sender.ChatUsername != "" {
+			body["username"] = sender.ChatUsername
+		}
+		if sender.ChatIconURL != "" {
+			body["avatar_url"] = sender.ChatIconURL
+		}
+	}
+	return body
+}
+
+// postChatWebhook POST JSON tới incoming webhook; 4xx (trừ 408/425/429) → PermanentError.
+func postChatWebhook(ctx context.Context, webhookURL string, body []byte) (*WebhookResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", webhookURL, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, &PermanentError{Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: defaultWebhookTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBodyBytes))
+	result := &WebhookResponse{StatusCode: resp.StatusCode, Body: string(respBody)}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err := fmt.Errorf("chat webhook returned status %d: %s", resp.StatusCode, result.Body)
+		if isPermanentStatus(resp.StatusCode) {
+			return result, &PermanentError{Err: err}
+		}
+		return result, err
+	}
+	return result, nil
+}
+
+// escapeSlack escape ký tự điều khiển của Slack mrkdwn (&, <, >); định dạng *bold*, _italic_ giữ nguyên.
+func escapeSlack(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// truncateRunes cắt s tối đa n rune (kể cả "…").
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,145 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	notifmodels "meta_commerce/internal/api/notification/models"
+)
+
+func TestSendSlack_BlocksAndButtons(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(b, &got)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	sender := &notifmodels.NotificationChannelSender{ChatUsername: "Ads Bot"}
+	rendered := &RenderedTemplate{
+		Subject: "Camp bị tắt",
+		Content: "*ROAS* < 1 & CPA > 200k",
+		CTAs:    []RenderedCTA{{Label: "Xem", Action: "https://x.test/c/1", Style: "primary"}, {Label: "Bỏ qua"}},
+	}
+	if _, err := SendSlack(context.Background(), sender, srv.URL, rendered); err != nil {
+		t.Fatalf("SendSlack lỗi: %v", err)
+	}
+	if got["username"] != "Ads Bot" || got["text"] != "Camp bị tắt" {
+		t.Errorf("body = %v", got)
+	}
+	blocks := got["blocks"].([]interface{})
+	if len(blocks) != 3 {
+		t.Fatalf("muốn header + section + actions, got %d block", len(blocks))
+	}
+	section := blocks[1].(map[string]interface{})["text"].(map[string]interface{})
+	if section["text"] != "*ROAS* &lt; 1 &amp; CPA &gt; 200k" {
+		t.Errorf("section chưa escape: %v", section["text"])
+	}
+	buttons := blocks[2].(map[string]interface{})["elements"].([]interface{})
+	if len(buttons) != 1 || buttons[0].(map[string]interface{})["style"] != "primary" {
+		t.Errorf("CTA không có URL phải bị bỏ, got %v", buttons)
+	}
+}
+
+func TestSendDiscord_EmbedAndLinkButtons(t *testing.T) {
+	var got map[string]interface{}
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		b, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(b, &got)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	ctas := make([]RenderedCTA, 7)
+	for i := range ctas {
+		ctas[i] = RenderedCTA{Label: "Nút", Action: "https://x.test"}
+	}
+	resp, err := SendDiscord(context.Background(), nil, srv.URL, &RenderedTemplate{Subject: "Cảnh báo", Content: "@everyone nội dung", CTAs: ctas})
+	if err != nil || resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("SendDiscord: %v %+v", err, resp)
+	}
+	if query != "with_components=true" {
+		t.Errorf("query = %q", query)
+	}
+	embed := got["embeds"].([]interface{})[0].(map[string]interface{})
+	if embed["title"] != "Cảnh báo" || embed["description"] != "@everyone nội dung" {
+		t.Errorf("embed = %v", embed)
+	}
+	rows := got["components"].([]interface{})
+	if len(rows) != 2 || len(rows[0].(map[string]interface{})["components"].([]interface{})) != discordButtonsPerRow {
+		t.Errorf("7 nút phải chia 2 hàng (5 + 2), got %v", rows)
+	}
+	if parse := got["allowed_mentions"].(map[string]interface{})["parse"].([]interface{}); len(parse) != 0 {
+		t.Errorf("không được cho phép mention, got %v", parse)
+	}
+}
+
+func TestSendDiscord_PermanentOn4xx(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+	if _, err := SendDiscord(context.Background(), nil, srv.URL, &RenderedTemplate{Content: "x"}); !IsPermanent(err) {
+		t.Errorf("404 (webhook bị xóa) phải là lỗi vĩnh viễn, got %v", err)
+	}
+}
+
+func TestSendMessenger(t *testing.T) {
+	var got map[string]interface{}
+	var token string
+	status, respBody := http.StatusOK, `{"recipient_id":"1","message_id":"m1"}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = r.URL.Query().Get("access_token")
+		b, _ := io.ReadAll(r.Body)
+		got = nil
+		_ = json.Unmarshal(b, &got)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(respBody))
+	}))
+	defer srv.Close()
+	old := messengerGraphURL
+	messengerGraphURL = srv.URL
+	defer func() { messengerGraphURL = old }()
+
+	sender := &notifmodels.NotificationChannelSender{FacebookPageID: "p1", MessengerTag: "ACCOUNT_UPDATE"}
+	rendered := &RenderedTemplate{Subject: "Đơn mới", Content: "DH01", CTAs: []RenderedCTA{{Label: "Xem đơn hàng chi tiết ngay", Action: "https://x.test/o/1"}}}
+	if _, err := SendMessenger(context.Background(), sender, "tok", "psid1", rendered); err != nil {
+		t.Fatalf("SendMessenger lỗi: %v", err)
+	}
+	if token != "tok" || got["messaging_type"] != "MESSAGE_TAG" || got["tag"] != "ACCOUNT_UPDATE" {
+		t.Errorf("token=%q body=%v", token, got)
+	}
+	payload := got["message"].(map[string]interface{})["attachment"].(map[string]interface{})["payload"].(map[string]interface{})
+	button := payload["buttons"].([]interface{})[0].(map[string]interface{})
+	if payload["text"] != "Đơn mới\n\nDH01" || len([]rune(button["title"].(string))) > messengerMaxButtonTitle {
+		t.Errorf("button template = %v", payload)
+	}
+
+	if _, err := SendMessenger(context.Background(), &notifmodels.NotificationChannelSender{}, "tok", "psid1", &RenderedTemplate{Content: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	if got["messaging_type"] != "UPDATE" || got["message"].(map[string]interface{})["text"] != "x" {
+		t.Errorf("không CTA → tin nhắn text UPDATE, got %v", got)
+	}
+
+	status, respBody = http.StatusBadRequest, `{"error":{"message":"limit","code":613}}`
+	if _, err := SendMessenger(context.Background(), sender, "tok", "psid1", rendered); err == nil || IsPermanent(err) {
+		t.Errorf("rate limit (613) phải retry được, got %v", err)
+	}
+	status, respBody = http.StatusBadRequest, `{"error":{"message":"No matching user found","code":100}}`
+	_, err := SendMessenger(context.Background(), sender, "tok", "psid1", rendered)
+	if !IsPermanent(err) || !strings.Contains(err.Error(), "No matching user") {
+		t.Errorf("PSID sai phải là lỗi vĩnh viễn, got %v", err)
+	}
+	if _, err := SendMessenger(context.Background(), sender, "", "psid1", rendered); !IsPermanent(err) {
+		t.Errorf("thiếu token phải là lỗi vĩnh viễn, got %v", err)
+	}
+}
@@ -14,6 +14,7 @@ type RenderedTemplate struct {
 	Subject string
 	Content string
 	CTAs    []RenderedCTA
+	Format  string // Format escape của Content: text | html | markdown | json (rỗng = text)
 }
 
 // RenderedCTA là CTA đã được render
@@ -54,6 +55,6 @@ func SendEmail(ctx context.Context, sender *notifmodels.NotificationChannelSende
 	msg.SetHeader("Subject", template.Subject)
 	msg.SetBody("text/html", htmlContent)
 
-	dialer := gomail.NewDialer(sender.SMTPHost, sender.SMTPPort, sender.SMTPUsername, sender.SMTPPassword)
+	dialer := gomail.NewDialer(sender.SMTPHost, sender.SMTPPort, sender.SMTPUsername, sender.SMTPPassword.String())
 	return dialer.DialAndSend(msg)
 }
@@ -0,0 +1,122 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	notifmodels "meta_commerce/internal/api/notification/models"
+)
+
+// messengerGraphURL base Graph API của Send API (biến để test thay bằng server giả).
+var messengerGraphURL = "https://graph.facebook.com/v21.0"
+
+// Giới hạn của Messenger Send API.
+const (
+	messengerMaxText         = 2000 // Tin nhắn text
+	messengerMaxTemplateText = 640  // Text của button template
+	messengerMaxButtons      = 3
+	messengerMaxButtonTitle  = 20
+)
+
+// messengerTransientCodes mã lỗi Graph tạm thời (rate limit, lỗi phía Facebook) — được retry dù HTTP 400.
+var messengerTransientCodes = map[int]bool{1: true, 2: true, 4: true, 17: true, 32: true, 613: true, 1200: true}
+
+// SendMessenger gửi tin nhắn từ Facebook page (sender.FacebookPageID) tới PSID của nhân viên.
+// pageAccessToken lấy từ fb_pages lúc gửi. CTA có URL → button template (tối đa 3 nút), không có → tin nhắn text.
+// Sender có MessengerTag → messaging_type MESSAGE_TAG (gửi ngoài cửa sổ 24h), không thì UPDATE.
+func SendMessenger(ctx context.Context, sender *notifmodels.NotificationChannelSender, pageAccessToken string, psid string, template *RenderedTemplate) (*WebhookResponse, error) {
+	if pageAccessToken == "" {
+		return nil, &PermanentError{Err: fmt.Errorf("page %s chưa có pageAccessToken", sender.FacebookPageID)}
+	}
+	body, err := json.Marshal(buildMessengerBody(sender, psid, template))
+	if err != nil {
+		return nil, &PermanentError{Err: err}
+	}
+
+	endpoint := messengerGraphURL + "/me/messages?access_token=" + url.QueryEscape(pageAccessToken)
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, &PermanentError{Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: defaultWebhookTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		// Không trả err gốc: *url.Error chứa URL có access token
+		return nil, fmt.Errorf("messenger send API: %s", strings.ReplaceAll(err.Error(), url.QueryEscape(pageAccessToken), "***"))
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBodyBytes))
+	result := &WebhookResponse{StatusCode: resp.StatusCode, Body: string(respBody)}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return result, nil
+	}
+
+	var graphErr struct {
+		Error struct {
+			Message     string `json:"message"`
+			Code        int    `json:"code"`
+			IsTransient bool   `json:"is_transient"`
+		} `json:"error"`
+	}
+	_ = json.Unmarshal(respBody, &graphErr)
+	err = fmt.Errorf("messenger send API status %d (code %d): %s", resp.StatusCode, graphErr.Error.Code, graphErr.Error.Message)
+	if isPermanentStatus(resp.StatusCode) && !graphErr.Error.IsTransient && !messengerTransientCodes[graphErr.Error.Code] {
+		return result, &PermanentError{Err: err}
+	}
+	return result, err
+}
+
+func buildMessengerBody(sender *notifmodels.NotificationChannelSender, psid string, template *RenderedTemplate) map[string]interface{} {
+	text := strings.TrimSpace(template.Content)
+	if s := strings.TrimSpace(template.Subject); s != "" {
+		text = s + "\n\n" + text
+	}
+
+	buttons := []map[string]interface{}{}
+	for _, cta := range template.CTAs {
+		if cta.Action == "" || len(buttons) == messengerMaxButtons {
+			continue
+		}
+		buttons = append(buttons, map[string]interface{}{
+			"type":  "web_url",
+			"url":   cta.Action,
+			"title": truncateRunes(cta.Label, messengerMaxButtonTitle),
+		})
+	}
+
+	var message map[string]interface{}
+	if len(buttons) > 0 {
+		message = map[string]interface{}{
+			"attachment": map[string]interface{}{
+				"type": "template",
+				"payload": map[string]interface{}{
+					"template_type": "button",
+					"text":          truncateRunes(text, messengerMaxTemplateText),
+					"buttons":       buttons,
+				},
+			},
+		}
+	} else {
+		message = map[string]interface{}{"text": truncateRunes(text, messengerMaxText)}
+	}
+
+	body := map[string]interface{}{
+		"recipient":      map[string]interface{}{"id": psid},
+		"message":        message,
+		"messaging_type": "UPDATE",
+	}
+	if sender != nil && sender.MessengerTag != "" {
+		body["messaging_type"] = "MESSAGE_TAG"
+		body["tag"] = sender.MessengerTag
+	}
+	return body
+}
@@ -94,6 +94,13 @@ func SendTelegram(ctx context.Context, sender *notifmodels.NotificationChannelSe
 	if messageThreadID != nil {
 		payload["message_thread_id"] = *messageThreadID
 	}
+	// Content đã escape theo format lúc render template
+	switch template.Format {
+	case "html":
+		payload["parse_mode"] = "HTML"
+	case "markdown":
+		payload["parse_mode"] = "MarkdownV2"
+	}
 
 	if len(inlineKeyboard) > 0 {
 		keyboard := map[string]interface{}{
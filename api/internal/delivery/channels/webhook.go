@@ -3,52 +3,204 @@ package channels
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
 	"time"
+
+	notifmodels "meta_commerce/internal/api/notification/models"
 )
 
-// SendWebhook gửi webhook
-func SendWebhook(ctx context.Context, webhookURL string, template *RenderedTemplate, historyID string, baseURL string) error {
-	// Format CTAs thành JSON
-	actions := []map[string]interface{}{}
-	for _, cta := range template.CTAs {
-		actions = append(actions, map[string]interface{}{
-			"label": cta.Label,
-			"url":   cta.Action,
-			"style": cta.Style,
-		})
-	}
+// Header chữ ký webhook gửi đi. Chuỗi ký: "<timestamp>.<body>", giá trị "sha256=<hex HMAC-SHA256>".
+const (
+	HeaderWebhookSignature  = "X-Signature"
+	HeaderWebhookTimestamp  = "X-Signature-Timestamp"
+	HeaderWebhookDeliveryID = "X-Delivery-Id" // Queue item ID — ổn định qua các lần retry để bên nhận chống trùng
+	HeaderWebhookEventType  = "X-Event-Type"
+)
 
-	payload := map[string]interface{}{
-		"content":   template.Content,
-		"timestamp": time.Now().Unix(),
-		"actions":   actions,
-	}
+const (
+	defaultWebhookTimeout = 10 * time.Second
+	maxWebhookTimeout     = 60 * time.Second
+	maxResponseBodyBytes  = 4096 // Giới hạn response body lưu vào delivery history
+)
 
-	jsonData, err := json.Marshal(payload)
+// WebhookMessage dữ liệu một lần gửi webhook.
+type WebhookMessage struct {
+	URL        string
+	EventType  string
+	Payload    map[string]interface{}
+	DeliveryID string
+}
+
+// WebhookResponse response của bên nhận (lưu vào delivery history).
+type WebhookResponse struct {
+	StatusCode int
+	Body       string
+}
+
+// PermanentError lỗi không nên retry (4xx, template lỗi, URL sai) → queue item vào dead-letter ngay.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// IsPermanent kiểm tra lỗi có phải PermanentError.
+func IsPermanent(err error) bool {
+	var pe *PermanentError
+	return errors.As(err, &pe)
+}
+
+// SendWebhook gửi webhook theo cấu hình sender: payload template, header tùy chỉnh, auth (bearer/basic),
+// chữ ký HMAC (X-Signature) khi sender có WebhookSecret. Trả response (status + body đã cắt) kể cả khi non-2xx.
+func SendWebhook(ctx context.Context, sender *notifmodels.NotificationChannelSender, msg WebhookMessage, rendered *RenderedTemplate, baseURL string) (*WebhookResponse, error) {
+	if sender == nil {
+		sender = &notifmodels.NotificationChannelSender{}
+	}
+	now := time.Now().Unix()
+	body, err := buildWebhookBody(sender.WebhookPayloadTemplate, msg, rendered, now)
 	if err != nil {
-		return err
+		return nil, &PermanentError{Err: err}
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", webhookURL, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", msg.URL, bytes.NewBuffer(body))
 	if err != nil {
-		return err
+		return nil, &PermanentError{Err: err}
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	for k, v := range sender.WebhookHeaders {
+		req.Header.Set(k, v)
+	}
+	switch strings.ToLower(sender.WebhookAuthType) {
+	case "bearer":
+		req.Header.Set("Authorization", "Bearer "+sender.WebhookAuthToken.String())
+	case "basic":
+		cred := base64.StdEncoding.EncodeToString([]byte(sender.WebhookAuthUsername + ":" + sender.WebhookAuthPassword.String()))
+		req.Header.Set("Authorization", "Basic "+cred)
+	}
+	if msg.DeliveryID != "" {
+		req.Header.Set(HeaderWebhookDeliveryID, msg.DeliveryID)
+	}
+	if msg.EventType != "" {
+		req.Header.Set(HeaderWebhookEventType, msg.EventType)
+	}
+	if sender.WebhookSecret != "" {
+		ts := strconv.FormatInt(now, 10)
+		req.Header.Set(HeaderWebhookTimestamp, ts)
+		req.Header.Set(HeaderWebhookSignature, "sha256="+SignWebhookBody([]byte(sender.WebhookSecret), ts, body))
+	}
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	timeout := defaultWebhookTimeout
+	if sender.WebhookTimeoutSeconds > 0 {
+		timeout = time.Duration(sender.WebhookTimeoutSeconds) * time.Second
+		if timeout > maxWebhookTimeout {
+			timeout = maxWebhookTimeout
+		}
+	}
+	client := &http.Client{Timeout: timeout}
 	resp, err := client.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBodyBytes))
+	result := &WebhookResponse{StatusCode: resp.StatusCode, Body: string(respBody)}
+
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+		err := fmt.Errorf("webhook returned status %d", resp.StatusCode)
+		if isPermanentStatus(resp.StatusCode) {
+			return result, &PermanentError{Err: err}
+		}
+		return result, err
 	}
 
-	return nil
+	return result, nil
+}
+
+// SignWebhookBody hex HMAC-SHA256(secret, "<timestamp>.<body>").
+func SignWebhookBody(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// isPermanentStatus 4xx là lỗi phía cấu hình/dữ liệu → không retry; trừ 408, 425, 429 (tạm thời).
+func isPermanentStatus(code int) bool {
+	if code < 400 || code >= 500 {
+		return false
+	}
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests:
+		return false
+	}
+	return true
+}
+
+// buildWebhookBody body mặc định {content, timestamp, actions} hoặc render payload template của sender.
+// Template (text/template) nhận: .Content, .Subject, .EventType, .Timestamp, .Actions, .Payload, .DeliveryID; hàm json để escape giá trị.
+func buildWebhookBody(payloadTemplate string, msg WebhookMessage, rendered *RenderedTemplate, now int64) ([]byte, error) {
+	// Format CTAs thành JSON
+	actions := []map[string]interface{}{}
+	for _, cta := range rendered.CTAs {
+		actions = append(actions, map[string]interface{}{
+			"label": cta.Label,
+			"url":   cta.Action,
+			"style": cta.Style,
+		})
+	}
+
+	if strings.TrimSpace(payloadTemplate) == "" {
+		return json.Marshal(map[string]interface{}{
+			"content":   rendered.Content,
+			"timestamp": now,
+			"actions":   actions,
+		})
+	}
+
+	tpl, err := ParseWebhookPayloadTemplate(payloadTemplate)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	data := map[string]interface{}{
+		"Content":    rendered.Content,
+		"Subject":    rendered.Subject,
+		"EventType":  msg.EventType,
+		"Timestamp":  now,
+		"Actions":    actions,
+		"Payload":    msg.Payload,
+		"DeliveryID": msg.DeliveryID,
+	}
+	if err := tpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("render webhook payload template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ParseWebhookPayloadTemplate parse payload template của sender.
+func ParseWebhookPayloadTemplate(payloadTemplate string) (*template.Template, error) {
+	tpl, err := template.New("webhook_payload").Option("missingkey=zero").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(payloadTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("parse webhook payload template: %w", err)
+	}
+	return tpl, nil
 }
@@ -0,0 +1,85 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	notifmodels "meta_commerce/internal/api/notification/models"
+)
+
+func TestSendWebhook_SignedWithTemplateAndAuth(t *testing.T) {
+	var gotBody []byte
+	var gotHeader http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotHeader = r.Header.Clone()
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	sender := &notifmodels.NotificationChannelSender{
+		WebhookSecret:          "s3cret",
+		WebhookHeaders:         map[string]string{"X-Team": "ads"},
+		WebhookAuthType:        "bearer",
+		WebhookAuthToken:       "tok",
+		WebhookPayloadTemplate: `{"text":{{json .Content}},"event":{{json .EventType}},"campaign":{{json .Payload.campaignId}}}`,
+	}
+	msg := WebhookMessage{URL: srv.URL, EventType: "ads_action_executed", Payload: map[string]interface{}{"campaignId": "c_1"}, DeliveryID: "q1"}
+	resp, err := SendWebhook(context.Background(), sender, msg, &RenderedTemplate{Content: `Đã "tắt" camp`}, "")
+	if err != nil {
+		t.Fatalf("SendWebhook lỗi: %v", err)
+	}
+	if resp.StatusCode != http.StatusAccepted || resp.Body != `{"ok":true}` {
+		t.Errorf("response = %+v", resp)
+	}
+
+	var body map[string]string
+	if err := json.Unmarshal(gotBody, &body); err != nil {
+		t.Fatalf("body không phải JSON: %s", gotBody)
+	}
+	if body["text"] != `Đã "tắt" camp` || body["event"] != "ads_action_executed" || body["campaign"] != "c_1" {
+		t.Errorf("body = %v", body)
+	}
+	if gotHeader.Get("Authorization") != "Bearer tok" || gotHeader.Get("X-Team") != "ads" || gotHeader.Get(HeaderWebhookDeliveryID) != "q1" {
+		t.Errorf("header thiếu: %v", gotHeader)
+	}
+	ts := gotHeader.Get(HeaderWebhookTimestamp)
+	if want := "sha256=" + SignWebhookBody([]byte("s3cret"), ts, gotBody); gotHeader.Get(HeaderWebhookSignature) != want {
+		t.Errorf("X-Signature = %q, muốn %q", gotHeader.Get(HeaderWebhookSignature), want)
+	}
+}
+
+func TestSendWebhook_PermanentVsRetryable(t *testing.T) {
+	status := http.StatusBadRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("nope"))
+	}))
+	defer srv.Close()
+
+	msg := WebhookMessage{URL: srv.URL}
+	resp, err := SendWebhook(context.Background(), nil, msg, &RenderedTemplate{}, "")
+	if err == nil || !IsPermanent(err) {
+		t.Fatalf("400 phải là lỗi vĩnh viễn, err = %v", err)
+	}
+	if resp == nil || resp.StatusCode != http.StatusBadRequest || resp.Body != "nope" {
+		t.Errorf("response non-2xx vẫn phải được ghi lại: %+v", resp)
+	}
+
+	for _, code := range []int{http.StatusTooManyRequests, http.StatusBadGateway} {
+		status = code
+		if _, err := SendWebhook(context.Background(), nil, msg, &RenderedTemplate{}, ""); err == nil || IsPermanent(err) {
+			t.Errorf("%d phải retry được, err = %v", code, err)
+		}
+	}
+
+	sender := &notifmodels.NotificationChannelSender{WebhookPayloadTemplate: "{{.Content"}
+	if _, err := SendWebhook(context.Background(), sender, msg, &RenderedTemplate{}, ""); !IsPermanent(err) {
+		t.Errorf("template lỗi phải là lỗi vĩnh viễn, err = %v", err)
+	}
+}
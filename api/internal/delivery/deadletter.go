@@ -0,0 +1,101 @@
+package delivery
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"meta_commerce/internal/logger"
+	"meta_commerce/internal/worker/metrics"
+)
+
+const (
+	// GaugeDeadLetterDepth tên gauge số entry dead-letter chưa xử lý.
+	GaugeDeadLetterDepth = "delivery_dlq_depth"
+	// defaultDeadLetterAlertThreshold ngưỡng cảnh báo mặc định (env DELIVERY_DLQ_ALERT_THRESHOLD).
+	defaultDeadLetterAlertThreshold = 100
+	// deadLetterAlertCooldown khoảng nhắc lại khi depth vẫn trên ngưỡng.
+	deadLetterAlertCooldown = time.Hour
+	// legacyMigrateBatch số queue item dead_letter cũ chuyển sang collection mỗi lần cleanup.
+	legacyMigrateBatch = 100
+)
+
+// DeadLetterAlertCallback gọi khi số entry dead-letter vượt ngưỡng.
+type DeadLetterAlertCallback func(depth, threshold int64, byChannel map[string]int64)
+
+var (
+	deadLetterAlertCallback DeadLetterAlertCallback
+	deadLetterAlertMu       sync.Mutex
+	deadLetterLastAlertAt   time.Time
+)
+
+// RegisterDeadLetterAlertCallback đăng ký callback cảnh báo dead-letter (systemalert gửi qua hệ thống thông báo).
+func RegisterDeadLetterAlertCallback(fn DeadLetterAlertCallback) {
+	deadLetterAlertMu.Lock()
+	deadLetterAlertCallback = fn
+	deadLetterAlertMu.Unlock()
+}
+
+// deadLetterAlertThreshold đọc ngưỡng từ env DELIVERY_DLQ_ALERT_THRESHOLD (<= 0 hoặc sai định dạng → mặc định).
+func deadLetterAlertThreshold() int64 {
+	if v := strings.TrimSpace(os.Getenv("DELIVERY_DLQ_ALERT_THRESHOLD")); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultDeadLetterAlertThreshold
+}
+
+// shouldAlertDeadLetter quyết định có gửi cảnh báo: lần đầu vượt ngưỡng, hoặc vẫn trên ngưỡng sau cooldown.
+// Depth xuống dưới ngưỡng thì reset để lần vượt tiếp theo cảnh báo ngay.
+func shouldAlertDeadLetter(depth, threshold int64, now time.Time) bool {
+	deadLetterAlertMu.Lock()
+	defer deadLetterAlertMu.Unlock()
+	if depth < threshold {
+		deadLetterLastAlertAt = time.Time{}
+		return false
+	}
+	if !deadLetterLastAlertAt.IsZero() && now.Sub(deadLetterLastAlertAt) < deadLetterAlertCooldown {
+		return false
+	}
+	deadLetterLastAlertAt = now
+	return true
+}
+
+// checkDeadLetters chạy trong cleanup job: chuyển queue item dead_letter cũ sang collection,
+// cập nhật gauge delivery_dlq_depth và cảnh báo khi vượt ngưỡng.
+func (p *Processor) checkDeadLetters(ctx context.Context) {
+	log := logger.GetAppLogger()
+	if _, err := p.deadLetterService.MigrateLegacy(ctx, legacyMigrateBatch); err != nil {
+		log.WithError(err).Error("📦 [CLEANUP] Failed to migrate legacy dead-letter items")
+	}
+
+	byChannel, err := p.deadLetterService.DepthByChannel(ctx)
+	if err != nil {
+		log.WithError(err).Error("📦 [CLEANUP] Failed to count dead-letter items")
+		return
+	}
+	var depth int64
+	for _, n := range byChannel {
+		depth += n
+	}
+	metrics.SetGauge(GaugeDeadLetterDepth, depth)
+
+	threshold := deadLetterAlertThreshold()
+	if !shouldAlertDeadLetter(depth, threshold, time.Now()) {
+		return
+	}
+	deadLetterAlertMu.Lock()
+	fn := deadLetterAlertCallback
+	deadLetterAlertMu.Unlock()
+	log.WithFields(map[string]interface{}{
+		"depth":     depth,
+		"threshold": threshold,
+	}).Warn("📦 [CLEANUP] Dead-letter vượt ngưỡng cảnh báo")
+	if fn != nil {
+		go fn(depth, threshold, byChannel)
+	}
+}
@@ -0,0 +1,30 @@
+package delivery
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShouldAlertDeadLetter_CooldownAndReset(t *testing.T) {
+	deadLetterLastAlertAt = time.Time{}
+	now := time.Now()
+
+	if shouldAlertDeadLetter(50, 100, now) {
+		t.Fatal("dưới ngưỡng không được cảnh báo")
+	}
+	if !shouldAlertDeadLetter(100, 100, now) {
+		t.Fatal("chạm ngưỡng phải cảnh báo")
+	}
+	if shouldAlertDeadLetter(150, 100, now.Add(10*time.Minute)) {
+		t.Fatal("trong cooldown không cảnh báo lại")
+	}
+	if !shouldAlertDeadLetter(150, 100, now.Add(deadLetterAlertCooldown)) {
+		t.Fatal("hết cooldown mà vẫn trên ngưỡng phải nhắc lại")
+	}
+
+	// Xuống dưới ngưỡng rồi vượt lại → cảnh báo ngay
+	shouldAlertDeadLetter(10, 100, now.Add(deadLetterAlertCooldown+time.Minute))
+	if !shouldAlertDeadLetter(120, 100, now.Add(deadLetterAlertCooldown+2*time.Minute)) {
+		t.Fatal("vượt ngưỡng lần mới phải cảnh báo ngay")
+	}
+}
@@ -3,6 +3,7 @@ package delivery
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"sync"
@@ -10,9 +11,11 @@ import (
 
 	deliverysvc "meta_commerce/internal/api/delivery/service"
 	deliverymodels "meta_commerce/internal/api/delivery/models"
+	fbsvc "meta_commerce/internal/api/fb/service"
 	notifmodels "meta_commerce/internal/api/notification/models"
 	notifsvc "meta_commerce/internal/api/notification/service"
 	basesvc "meta_commerce/internal/api/base/service"
+	"meta_commerce/internal/common"
 	"meta_commerce/internal/delivery/channels"
 	"meta_commerce/internal/logger"
 	"meta_commerce/internal/notification"
@@ -27,9 +30,11 @@ import (
 // Nhận: sender, recipient, content đã render
 // Gửi đi
 type Processor struct {
-	queueService   *deliverysvc.DeliveryQueueService
-	historyService *deliverysvc.DeliveryHistoryService
+	queueService      *deliverysvc.DeliveryQueueService
+	historyService    *deliverysvc.DeliveryHistoryService
+	deadLetterService *deliverysvc.DeliveryDeadLetterService
 	senderService  *notifsvc.NotificationSenderService
+	fbPageService  *fbsvc.FbPageService
 	baseURL        string
 }
 
@@ -45,16 +50,28 @@ func NewProcessor(baseURL string) (*Processor, error) {
 		return nil, fmt.Errorf("failed to create history service: %w", err)
 	}
 
+	deadLetterService, err := deliverysvc.NewDeliveryDeadLetterService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dead-letter service: %w", err)
+	}
+
 	senderService, err := notifsvc.NewNotificationSenderService()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create sender service: %w", err)
 	}
 
+	fbPageService, err := fbsvc.NewFbPageService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fb page service: %w", err)
+	}
+
 	return &Processor{
-		queueService:   queueService,
-		historyService: historyService,
-		senderService:  senderService,
-		baseURL:        baseURL,
+		queueService:      queueService,
+		historyService:    historyService,
+		deadLetterService: deadLetterService,
+		senderService:     senderService,
+		fbPageService:     fbPageService,
+		baseURL:           baseURL,
 	}, nil
 }
 
@@ -127,15 +144,15 @@ func (p *Processor) processDeliveryItem(ctx context.Context, item *deliverymodel
 }
 
 // handleRetryOrFail xử lý retry logic cho mọi error case
-// Nếu chưa hết retry: tăng retryCount, set nextRetryAt, reset về pending
-// Nếu đã hết retry: đánh dấu failed và xóa khỏi queue
+// Nếu chưa hết retry: tăng retryCount, set nextRetryAt (backoff 2^n giây), reset về pending
+// Nếu đã hết retry hoặc lỗi vĩnh viễn (channels.PermanentError): chuyển sang delivery_job_dead_letters (xóa khỏi queue)
 func (p *Processor) handleRetryOrFail(ctx context.Context, item *deliverymodels.DeliveryQueueItem, err error) error {
 	log := logger.GetAppLogger()
 	
 	// Tăng retryCount
 	item.RetryCount++
 	
-	if item.RetryCount < item.MaxRetries {
+	if item.RetryCount < item.MaxRetries && !channels.IsPermanent(err) {
 		// Chưa hết retry, schedule retry
 		item.Status = "pending"
 		backoffSeconds := int64(math.Pow(2, float64(item.RetryCount)))
@@ -161,28 +178,42 @@ func (p *Processor) handleRetryOrFail(ctx context.Context, item *deliverymodels.
 		// Đã tắt log Info để giảm log (chỉ log Error/Warn)
 		return err // Return error để caller biết cần retry
 	} else {
-		// Đã hết số lần retry, đánh dấu failed và xóa khỏi queue
+		// Hết retry hoặc lỗi vĩnh viễn: chuyển dead-letter để xem lại / replay thủ công
+		now := time.Now().Unix()
+		item.Status = "dead_letter"
+		item.DeadLetteredAt = &now
+		item.Error = err.Error()
+		_, moveErr := p.deadLetterService.MoveFromQueue(ctx, item, item.Error, channels.IsPermanent(err))
+		if moveErr != nil {
+			// Không ghi được dead-letter: đánh dấu dead_letter trong queue, cleanup job chuyển lại sau
+			log.WithError(moveErr).WithField("queueItemId", item.ID.Hex()).Warn("📦 [DELIVERY] Failed to move queue item to dead-letter collection")
+		}
 		updateData := basesvc.UpdateData{
 			Set: map[string]interface{}{
-				"status":    "failed",
-				"error":     err.Error(),
-				"updatedAt": time.Now().Unix(),
+				"status":         item.Status,
+				"retryCount":     item.RetryCount,
+				"error":          err.Error(),
+				"deadLetteredAt": item.DeadLetteredAt,
+				"updatedAt":      now,
 			},
 		}
-		_, updateErr := p.queueService.UpdateOne(ctx, bson.M{"_id": item.ID}, updateData, nil)
-		if updateErr != nil {
-			log.WithError(updateErr).WithField("queueItemId", item.ID.Hex()).Error("📦 [DELIVERY] Failed to mark queue item as failed")
-			return fmt.Errorf("failed to mark queue item as failed: %w", updateErr)
+		if moveErr != nil {
+			_, updateErr := p.queueService.UpdateOne(ctx, bson.M{"_id": item.ID}, updateData, nil)
+			if updateErr != nil {
+				log.WithError(updateErr).WithField("queueItemId", item.ID.Hex()).Error("📦 [DELIVERY] Failed to move queue item to dead-letter")
+				return fmt.Errorf("failed to move queue item to dead-letter: %w", updateErr)
+			}
 		}
+		log.WithError(err).WithFields(map[string]interface{}{
+			"queueItemId": item.ID.Hex(),
+			"channelType": item.ChannelType,
+			"retryCount":  item.RetryCount,
+			"permanent":   channels.IsPermanent(err),
+		}).Warn("📦 [DELIVERY] Queue item chuyển dead-letter")
 		
-		// Xóa queue item (cleanup)
-		deleteErr := p.queueService.DeleteOne(ctx, bson.M{"_id": item.ID})
-		if deleteErr != nil {
-			log.WithError(deleteErr).WithField("queueItemId", item.ID.Hex()).Warn("📦 [DELIVERY] Failed to delete failed queue item (đã đánh dấu failed, sẽ không được filter ra nữa)")
-		} else {
-			// Đã tắt log Info để giảm log (chỉ log Error/Warn)
+		if channels.IsPermanent(err) {
+			return fmt.Errorf("permanent failure: %w", err)
 		}
-		
 		return fmt.Errorf("max retries exceeded: %w", err)
 	}
 }
@@ -262,6 +293,21 @@ func (p *Processor) ProcessQueueItem(ctx context.Context, item *deliverymodels.D
 		return p.handleRetryOrFail(ctx, item, err)
 	}
 
+	// 3. Bản replay từ dead-letter: đã gửi thành công cùng idempotency key → không gửi lại
+	if item.IdempotencyKey != "" {
+		sent, err := p.historyService.AlreadySent(ctx, item.IdempotencyKey)
+		if err != nil {
+			return p.handleRetryOrFail(ctx, item, fmt.Errorf("failed to check idempotency key: %w", err))
+		}
+		if sent {
+			log.WithFields(map[string]interface{}{
+				"queueItemId":    item.ID.Hex(),
+				"idempotencyKey": item.IdempotencyKey,
+			}).Warn("📦 [DELIVERY] Bản replay đã được gửi trước đó, bỏ qua")
+			return p.queueService.DeleteOne(ctx, bson.M{"_id": item.ID})
+		}
+	}
+
 	// 4. Parse CTAs từ JSON string (nếu có)
 	var renderedCTAs []channels.RenderedCTA
 	if len(item.CTAs) > 0 {
@@ -280,6 +326,7 @@ func (p *Processor) ProcessQueueItem(ctx context.Context, item *deliverymodels.D
 		Subject: item.Subject,
 		Content: item.Content,
 		CTAs:    renderedCTAs,
+		Format:  item.ContentFormat,
 	}
 
 	// 6. Tạo history record (trước khi gửi)
@@ -300,6 +347,7 @@ func (p *Processor) ProcessQueueItem(ctx context.Context, item *deliverymodels.D
 		Status:              "pending",
 		Content:             rendered.Content,
 		RetryCount:          item.RetryCount,
+		IdempotencyKey:      item.IdempotencyKey,
 		CreatedAt:           time.Now().Unix(),
 	}
 
@@ -314,7 +362,11 @@ func (p *Processor) ProcessQueueItem(ctx context.Context, item *deliverymodels.D
 	}
 
 	// 7. Gửi notification (CTAs đã có tracking URLs từ Notification System)
-	sendErr := p.sendNotification(ctx, sender, item.ChannelType, item.Recipient, rendered, historyID.Hex())
+	resp, sendErr := p.sendNotification(ctx, sender, item, rendered, historyID.Hex())
+	if resp != nil {
+		history.ResponseStatus = resp.StatusCode
+		history.ResponseBody = resp.Body
+	}
 	if sendErr != nil {
 		history.Status = "failed"
 		history.Error = sendErr.Error()
@@ -350,20 +402,54 @@ func (p *Processor) ProcessQueueItem(ctx context.Context, item *deliverymodels.D
 
 
 
-// sendNotification gửi notification qua channel tương ứng
-func (p *Processor) sendNotification(ctx context.Context, sender *notifmodels.NotificationChannelSender, channelType string, recipient string, rendered *channels.RenderedTemplate, historyID string) error {
-	switch channelType {
+// sendNotification gửi notification qua channel tương ứng.
+// Response (webhook, Slack, Discord, Messenger) được lưu vào delivery history.
+func (p *Processor) sendNotification(ctx context.Context, sender *notifmodels.NotificationChannelSender, item *deliverymodels.DeliveryQueueItem, rendered *channels.RenderedTemplate, historyID string) (*channels.WebhookResponse, error) {
+	switch item.ChannelType {
 	case "email":
-		return channels.SendEmail(ctx, sender, recipient, rendered, historyID, p.baseURL)
+		return nil, channels.SendEmail(ctx, sender, item.Recipient, rendered, historyID, p.baseURL)
 	case "telegram":
-		return channels.SendTelegram(ctx, sender, recipient, rendered, historyID, p.baseURL)
+		return nil, channels.SendTelegram(ctx, sender, item.Recipient, rendered, historyID, p.baseURL)
 	case "webhook":
-		return channels.SendWebhook(ctx, recipient, rendered, historyID, p.baseURL)
+		return channels.SendWebhook(ctx, sender, channels.WebhookMessage{
+			URL:        item.Recipient,
+			EventType:  item.EventType,
+			Payload:    item.Payload,
+			DeliveryID: item.ID.Hex(),
+		}, rendered, p.baseURL)
+	case "slack":
+		return channels.SendSlack(ctx, sender, item.Recipient, rendered)
+	case "discord":
+		return channels.SendDiscord(ctx, sender, item.Recipient, rendered)
+	case "messenger":
+		token, err := p.pageAccessToken(ctx, sender)
+		if err != nil {
+			return nil, err
+		}
+		return channels.SendMessenger(ctx, sender, token, item.Recipient, rendered)
 	default:
-		return fmt.Errorf("unsupported channel type: %s", channelType)
+		return nil, &channels.PermanentError{Err: fmt.Errorf("unsupported channel type: %s", item.ChannelType)}
 	}
 }
 
+// pageAccessToken token của page gửi Messenger, đọc từ fb_pages mỗi lần gửi (token được làm mới qua đồng bộ page).
+func (p *Processor) pageAccessToken(ctx context.Context, sender *notifmodels.NotificationChannelSender) (string, error) {
+	if sender.FacebookPageID == "" {
+		return "", &channels.PermanentError{Err: fmt.Errorf("sender %s chưa cấu hình facebookPageId", sender.ID.Hex())}
+	}
+	page, err := p.fbPageService.FindOneByPageID(ctx, sender.FacebookPageID)
+	if err != nil {
+		if errors.Is(err, common.ErrNotFound) {
+			return "", &channels.PermanentError{Err: fmt.Errorf("không tìm thấy fb page %s", sender.FacebookPageID)}
+		}
+		return "", fmt.Errorf("đọc fb page %s: %w", sender.FacebookPageID, err)
+	}
+	if sender.OwnerOrganizationID != nil && page.OwnerOrganizationID != *sender.OwnerOrganizationID {
+		return "", &channels.PermanentError{Err: fmt.Errorf("fb page %s không thuộc tổ chức của sender", sender.FacebookPageID)}
+	}
+	return page.PageAccessToken.String(), nil
+}
+
 // StartCleanupJob bắt đầu background job để dọn dẹp items bị kẹt
 func (p *Processor) StartCleanupJob(ctx context.Context) {
 	cleanupInterval := 1 * time.Minute // Chạy mỗi 1 phút
@@ -399,6 +485,9 @@ func (p *Processor) StartCleanupJob(ctx context.Context) {
 				}
 				log := logger.GetAppLogger()
 
+				// Dead-letter: chuyển item dead_letter còn trong queue, cập nhật depth + cảnh báo
+				p.checkDeadLetters(ctx)
+
 				// Tìm items bị kẹt
 				effBatch := worker.GetEffectiveBatchSize(batchSize, prio)
 				stuckItems, err := p.queueService.FindStuckItems(ctx, staleMinutes, effBatch)
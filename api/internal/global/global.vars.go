@@ -20,6 +20,14 @@ type MongoDB_Auth_CollectionName struct {
 	Organizations           string // Tên collection cho tổ chức
 	OrganizationConfigItems string // Tên collection cho config item (1 document per key): auth_organization_config_items
 	AccessTokens            string // Tên collection cho token
+	AuthSessions            string // Tên collection cho phiên đăng nhập theo thiết bị (refresh token)
+	AuthRevokedSessions     string // Tên collection cho danh sách phiên bị thu hồi (TTL)
+	ServiceAccounts         string // Tên collection cho service account (agent, bot tích hợp) theo tổ chức
+	APIKeys                 string // Tên collection cho API key của service account (chỉ lưu hash)
+	RateLimitPolicies       string // Tên collection cho policy rate limit theo tổ chức / nhóm route
+	RateLimitCounters       string // Tên collection cho bộ đếm rate limit (fixed-window, TTL)
+	RecordShareGrants       string // Tên collection cho grant chia sẻ theo bản ghi (tổ chức → tổ chức/user)
+	RecordShareAccesses     string // Tên collection cho nhật ký truy cập bản ghi qua grant
 	FbPages                 string // Tên collection cho trang Facebook
 	FbConvesations          string // Tên collection cho cuộc trò chuyện trên Facebook
 	FbMessages              string // Tên collection cho metadata tin nhắn trên Facebook
@@ -48,10 +56,15 @@ type MongoDB_Auth_CollectionName struct {
 	NotificationChannels     string // Tên collection cho notification channels
 	NotificationTemplates    string // Tên collection cho notification templates
 	NotificationRoutingRules string // Tên collection cho notification routing rules
+	NotificationDedupMarks   string // Tên collection cho dedup key đã gửi của routing rule (TTL)
+	NotificationDigests      string // Tên collection cho buffer digest của routing rule
+	NotificationPreferences  string // Tên collection cho tùy chọn nhận thông báo của user
+	NotificationInbox        string // Tên collection cho hộp thư in-app của user (TTL)
 
 	// Delivery System Collections (Hệ thống 1 - Gửi)
-	DeliveryQueue   string // Tên collection cho delivery queue (đổi từ notification_queue)
-	DeliveryHistory string // Tên collection cho delivery history (đổi từ notification_history)
+	DeliveryQueue       string // Tên collection cho delivery queue (đổi từ notification_queue)
+	DeliveryHistory     string // Tên collection cho delivery history (đổi từ notification_history)
+	DeliveryDeadLetters string // Tên collection cho queue item gửi thất bại hẳn (dead-letter)
 
 	// CTA Module Collections
 	CTALibrary  string // Tên collection cho CTA library
@@ -65,6 +78,7 @@ type MongoDB_Auth_CollectionName struct {
 
 	// Webhook Logs Collection
 	WebhookLogs string // Tên collection cho webhook logs (để debug)
+	WebhookNonces string // Nonce webhook đã dùng (chống replay, TTL): webhook_run_nonces
 
 	// Module 1: Content Storage Collections (tất cả đều có prefix "content_" để nhất quán)
 	ContentNodes      string // Tên collection cho content nodes (L1-L6): content_nodes
@@ -101,6 +115,24 @@ type MongoDB_Auth_CollectionName struct {
 	CustomerIntelCompute string // customer_intel_compute
 	// CustomerIntelRuns — lớp A: mỗi lần chạy intel khách; customer_customers giữ pointer mới nhất.
 	CustomerIntelRuns string // customer_intel_runs
+	// CustomerDuplicateCandidates — hàng đợi cặp khách nghi trùng chờ nhân viên duyệt (gộp thủ công).
+	CustomerDuplicateCandidates string // customer_duplicate_candidates
+	// CustomerMergeHistory — lịch sử gộp thủ công, giữ snapshot khách bị gộp để tách lại.
+	CustomerMergeHistory string // customer_merge_history
+	// CustomerSegments — định nghĩa phân khúc khách động (DSL điều kiện trên metrics + classification).
+	CustomerSegments string // customer_segments
+	// CustomerSegmentMembers — thành viên hiện tại của từng phân khúc.
+	CustomerSegmentMembers string // customer_segment_members
+	// CustomerSegmentCounts — số thành viên phân khúc theo ngày.
+	CustomerSegmentCounts string // customer_segment_counts
+	// CustomerPrivacyRequests — yêu cầu export / xóa dữ liệu cá nhân khách (bản ghi kiểm toán).
+	CustomerPrivacyRequests string // customer_privacy_requests
+	// CustomerPrivacyExportItems — bản chụp document của yêu cầu export (TTL).
+	CustomerPrivacyExportItems string // customer_privacy_export_items
+	// CustomerFieldDefinitions — định nghĩa trường tùy chỉnh của khách theo tổ chức.
+	CustomerFieldDefinitions string // customer_field_definitions
+	// CustomerTags — danh mục tag khách (tên, màu) theo tổ chức.
+	CustomerTags string // customer_tags
 
 	// Module Meta Ads (tiền tố meta_)
 	MetaAdAccounts  string // meta_ad_accounts: ad accounts (act_xxx)
@@ -155,6 +187,8 @@ type MongoDB_Auth_CollectionName struct {
 	RuleParamSets        string // rule_param_sets: Parameter Set
 	RuleOutputDefinitions string // rule_output_definitions: Output Contract
 	RuleExecutionLogs    string // rule_execution_logs: Execution Trace
+	RulePromotionAudits  string // rule_run_promotion_audits: audit đổi stage / promote / rollback rule
+	RuleTestCases        string // rule_cfg_test_cases: test case của Logic Script (chặn publish khi fail)
 
 	// Module CIX — Contextual Conversation Intelligence
 	CixAnalysisResults string // cix_analysis_results: lớp A mỗi lần chạy (success/failed), rawFacts tóm tắt, parentJobId, causalOrderingAt, sequence
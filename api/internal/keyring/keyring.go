@@ -0,0 +1,212 @@
+// Package keyring — khóa mã hóa AES-GCM có phiên bản cho secret lưu trong DB (sender config, API key, access token, webhook secret).
+//
+// Ciphertext có dạng "enc:<keyId>:<base64(nonce|ciphertext)>" — key id nằm trong chuỗi nên nhiều key cùng giải mã được
+// trong lúc xoay vòng; mã hóa luôn dùng key active. Ciphertext cũ (base64 không prefix, key dẫn xuất từ JWT_SECRET)
+// vẫn giải mã bằng key "legacy" cho tới khi chạy cmd/reencrypt_secrets.
+package keyring
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+const (
+	// Prefix tiền tố của mọi ciphertext do keyring tạo ra.
+	Prefix = "enc:"
+	// LegacyKeyID id của key dẫn xuất từ JWT_SECRET (định dạng trước khi có keyring).
+	LegacyKeyID = "legacy"
+
+	keySize = 32 // AES-256
+)
+
+var (
+	// ErrNotInitialized keyring chưa được Init (server / CLI phải gọi sau khi đọc config).
+	ErrNotInitialized = errors.New("keyring chưa được khởi tạo")
+	// ErrUnknownKey ciphertext dùng key id không có trong keyring.
+	ErrUnknownKey = errors.New("không có key để giải mã")
+)
+
+// Keyring tập key theo id; activeID dùng để mã hóa.
+type Keyring struct {
+	activeID string
+	keys     map[string][]byte
+	order    []string // Thứ tự khai báo (để liệt kê)
+}
+
+var (
+	defaultRing *Keyring
+	defaultMu   sync.RWMutex
+)
+
+// New tạo keyring từ spec "id1:base64key1,id2:base64key2" (key 32 byte).
+// activeID rỗng = key đầu tiên trong spec. legacySecret (JWT_SECRET) khác rỗng → thêm key "legacy" chỉ để giải mã;
+// spec rỗng thì key legacy làm key active (giữ hành vi cũ, nên cấu hình ENCRYPTION_KEYS trước khi xoay JWT_SECRET).
+func New(spec, activeID, legacySecret string) (*Keyring, error) {
+	kr := &Keyring{keys: make(map[string][]byte)}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(part, ":")
+		id = strings.TrimSpace(id)
+		if !ok || id == "" {
+			return nil, fmt.Errorf("ENCRYPTION_KEYS: mục '%s' phải có dạng id:base64key", part)
+		}
+		if id == LegacyKeyID || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("ENCRYPTION_KEYS: key id '%s' không hợp lệ", id)
+		}
+		if _, dup := kr.keys[id]; dup {
+			return nil, fmt.Errorf("ENCRYPTION_KEYS: key id '%s' bị trùng", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("ENCRYPTION_KEYS: key '%s' không phải base64: %w", id, err)
+		}
+		if len(key) != keySize {
+			return nil, fmt.Errorf("ENCRYPTION_KEYS: key '%s' phải dài %d byte (hiện %d)", id, keySize, len(key))
+		}
+		kr.keys[id] = key
+		kr.order = append(kr.order, id)
+	}
+
+	if legacySecret != "" {
+		hash := sha256.Sum256([]byte(legacySecret + "_sender_config_encryption_key"))
+		kr.keys[LegacyKeyID] = hash[:]
+		kr.order = append(kr.order, LegacyKeyID)
+	}
+
+	switch {
+	case activeID != "":
+		if _, ok := kr.keys[activeID]; !ok {
+			return nil, fmt.Errorf("ENCRYPTION_ACTIVE_KEY_ID '%s' không có trong ENCRYPTION_KEYS", activeID)
+		}
+		kr.activeID = activeID
+	case len(kr.order) > 0:
+		kr.activeID = kr.order[0]
+	default:
+		return nil, errors.New("keyring cần ít nhất một key (ENCRYPTION_KEYS hoặc JWT_SECRET)")
+	}
+	return kr, nil
+}
+
+// Init tạo keyring mặc định dùng cho toàn process (Secret, delivery sender config).
+func Init(spec, activeID, legacySecret string) error {
+	kr, err := New(spec, activeID, legacySecret)
+	if err != nil {
+		return err
+	}
+	SetDefault(kr)
+	return nil
+}
+
+// SetDefault đặt keyring mặc định (test dùng để thay keyring).
+func SetDefault(kr *Keyring) {
+	defaultMu.Lock()
+	defaultRing = kr
+	defaultMu.Unlock()
+}
+
+// Default trả keyring mặc định.
+func Default() (*Keyring, error) {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	if defaultRing == nil {
+		return nil, ErrNotInitialized
+	}
+	return defaultRing, nil
+}
+
+// ActiveKeyID id key đang dùng để mã hóa.
+func (k *Keyring) ActiveKeyID() string { return k.activeID }
+
+// KeyIDs các key id có trong keyring (theo thứ tự khai báo, legacy cuối).
+func (k *Keyring) KeyIDs() []string { return append([]string(nil), k.order...) }
+
+// Encrypt mã hóa bằng key active → "enc:<keyId>:<base64>".
+func (k *Keyring) Encrypt(plaintext []byte) (string, error) {
+	gcm, err := newGCM(k.keys[k.activeID])
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, plaintext, nil)
+	return Prefix + k.activeID + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt giải mã ciphertext có prefix theo key id trong chuỗi; không prefix → coi là định dạng cũ (key legacy).
+func (k *Keyring) Decrypt(ciphertext string) ([]byte, error) {
+	keyID, encoded, ok := Parse(ciphertext)
+	if !ok {
+		keyID, encoded = LegacyKeyID, ciphertext
+	}
+	key, found := k.keys[keyID]
+	if !found {
+		return nil, fmt.Errorf("%w: key id '%s'", ErrUnknownKey, keyID)
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode base64: %w", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonceSize := gcm.NonceSize()
+	if len(raw) < nonceSize {
+		return nil, errors.New("ciphertext too short")
+	}
+	plaintext, err := gcm.Open(nil, raw[:nonceSize], raw[nonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt (key '%s'): %w", keyID, err)
+	}
+	return plaintext, nil
+}
+
+// IsCurrent ciphertext đã dùng key active (không cần mã hóa lại khi xoay key).
+func (k *Keyring) IsCurrent(ciphertext string) bool {
+	keyID, _, ok := Parse(ciphertext)
+	return ok && keyID == k.activeID
+}
+
+// Parse tách "enc:<keyId>:<payload>". ok=false nếu chuỗi không có prefix.
+func Parse(value string) (keyID, payload string, ok bool) {
+	rest, found := strings.CutPrefix(value, Prefix)
+	if !found {
+		return "", "", false
+	}
+	keyID, payload, found = strings.Cut(rest, ":")
+	if !found || keyID == "" {
+		return "", "", false
+	}
+	return keyID, payload, true
+}
+
+// IsEncrypted chuỗi có định dạng ciphertext của keyring.
+func IsEncrypted(value string) bool {
+	_, _, ok := Parse(value)
+	return ok
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return gcm, nil
+}
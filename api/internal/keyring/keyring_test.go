@@ -0,0 +1,124 @@
+package keyring
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), keySize)))
+}
+
+func TestKeyring_RotateKeepsOldKeyForDecrypt(t *testing.T) {
+	oldRing, err := New("k1:"+testKey('a'), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct, err := oldRing.Encrypt([]byte("smtp-pass"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(ct, "enc:k1:") {
+		t.Fatalf("ciphertext thiếu key id: %s", ct)
+	}
+
+	newRing, err := New("k2:"+testKey('b')+",k1:"+testKey('a'), "k2", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newRing.IsCurrent(ct) {
+		t.Fatal("ciphertext k1 không phải key active k2")
+	}
+	plain, err := newRing.Decrypt(ct)
+	if err != nil || string(plain) != "smtp-pass" {
+		t.Fatalf("giải mã bằng key cũ: %q, %v", plain, err)
+	}
+
+	onlyNew, _ := New("k2:"+testKey('b'), "", "")
+	if _, err := onlyNew.Decrypt(ct); err == nil {
+		t.Fatal("thiếu key k1 phải lỗi")
+	}
+}
+
+func TestKeyring_DecryptsLegacyJwtDerivedCiphertext(t *testing.T) {
+	// Định dạng trước keyring: base64(nonce|ct), key = sha256(JWT_SECRET + suffix)
+	hash := sha256.Sum256([]byte("jwt-secret_sender_config_encryption_key"))
+	block, _ := aes.NewCipher(hash[:])
+	gcm, _ := cipher.NewGCM(block)
+	nonce := make([]byte, gcm.NonceSize())
+	_, _ = rand.Read(nonce)
+	legacy := base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(`{"botToken":"x"}`), nil))
+
+	kr, err := New("k1:"+testKey('a'), "", "jwt-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kr.ActiveKeyID() != "k1" {
+		t.Fatalf("active = %s, want k1", kr.ActiveKeyID())
+	}
+	plain, err := kr.Decrypt(legacy)
+	if err != nil || string(plain) != `{"botToken":"x"}` {
+		t.Fatalf("legacy decrypt: %q, %v", plain, err)
+	}
+}
+
+func TestNew_Validation(t *testing.T) {
+	cases := map[string]struct{ spec, active string }{
+		"short key":      {"k1:" + base64.StdEncoding.EncodeToString([]byte("short")), ""},
+		"duplicate id":   {"k1:" + testKey('a') + ",k1:" + testKey('b'), ""},
+		"unknown active": {"k1:" + testKey('a'), "k9"},
+		"reserved id":    {"legacy:" + testKey('a'), ""},
+		"no keys":        {"", ""},
+	}
+	for name, c := range cases {
+		if _, err := New(c.spec, c.active, ""); err == nil {
+			t.Errorf("%s: cần lỗi", name)
+		}
+	}
+}
+
+func TestSecret_BSONRoundTrip(t *testing.T) {
+	kr, _ := New("k1:"+testKey('a'), "", "")
+	SetDefault(kr)
+	defer SetDefault(nil)
+
+	type doc struct {
+		Token Secret `bson:"token"`
+		Empty Secret `bson:"empty"`
+	}
+	raw, err := bson.Marshal(doc{Token: "page-token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored bson.M
+	_ = bson.Unmarshal(raw, &stored)
+	if s, _ := stored["token"].(string); !strings.HasPrefix(s, "enc:k1:") {
+		t.Fatalf("lưu phải là ciphertext, got %v", stored["token"])
+	}
+	if stored["empty"] != "" {
+		t.Fatalf("chuỗi rỗng giữ rỗng, got %v", stored["empty"])
+	}
+
+	var back doc
+	if err := bson.Unmarshal(raw, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.Token != "page-token" {
+		t.Fatalf("đọc ra = %q", back.Token)
+	}
+
+	// Plaintext cũ trong DB đọc ra nguyên văn
+	legacyRaw, _ := bson.Marshal(bson.M{"token": "plain-old"})
+	var legacy doc
+	_ = bson.Unmarshal(legacyRaw, &legacy)
+	if legacy.Token != "plain-old" {
+		t.Fatalf("plaintext cũ = %q", legacy.Token)
+	}
+}
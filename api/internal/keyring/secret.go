@@ -0,0 +1,92 @@
+package keyring
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// Secret chuỗi bí mật: lưu Mongo dạng ciphertext (key active), đọc ra là plaintext.
+// JSON giữ plaintext như string thường. Giá trị cũ chưa mã hóa (không prefix "enc:") đọc ra nguyên văn —
+// cmd/reencrypt_secrets mã hóa chúng.
+type Secret string
+
+// String trả plaintext.
+func (s Secret) String() string { return string(s) }
+
+// MarshalBSONValue mã hóa khi ghi Mongo (kể cả qua bson.M / utility.ToMap). Chuỗi rỗng giữ rỗng.
+func (s Secret) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	value := string(s)
+	if value != "" && !IsEncrypted(value) {
+		kr, err := Default()
+		if err != nil {
+			return 0, nil, err
+		}
+		if value, err = kr.Encrypt([]byte(value)); err != nil {
+			return 0, nil, err
+		}
+	}
+	return bsontype.String, bsoncore.AppendString(nil, value), nil
+}
+
+// UnmarshalBSONValue giải mã khi đọc Mongo. Không giải mã được (thiếu key) → giữ ciphertext và log, không làm hỏng cả document.
+func (s *Secret) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	switch t {
+	case bsontype.Null, bsontype.Undefined:
+		*s = ""
+		return nil
+	case bsontype.String:
+	default:
+		return fmt.Errorf("keyring.Secret: không đọc được kiểu BSON %s", t)
+	}
+	value, _, ok := bsoncore.ReadString(data)
+	if !ok {
+		return fmt.Errorf("keyring.Secret: chuỗi BSON không hợp lệ")
+	}
+	if !IsEncrypted(value) {
+		*s = Secret(value)
+		return nil
+	}
+	kr, err := Default()
+	if err == nil {
+		var plain []byte
+		if plain, err = kr.Decrypt(value); err == nil {
+			*s = Secret(plain)
+			return nil
+		}
+	}
+	keyID, _, _ := Parse(value)
+	logrus.WithError(err).WithField("keyId", keyID).Warn("🔐 [KEYRING] Không giải mã được secret, giữ nguyên ciphertext")
+	*s = Secret(value)
+	return nil
+}
+
+// EncryptString mã hóa giá trị bí mật (lưu ngoài Mongo, vd. file token). Rỗng hoặc đã mã hóa → giữ nguyên.
+func EncryptString(value string) (string, error) {
+	if value == "" || IsEncrypted(value) {
+		return value, nil
+	}
+	kr, err := Default()
+	if err != nil {
+		return "", err
+	}
+	return kr.Encrypt([]byte(value))
+}
+
+// DecryptString giải mã giá trị từ EncryptString; chuỗi không prefix (plaintext cũ) trả nguyên văn.
+func DecryptString(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	kr, err := Default()
+	if err != nil {
+		return "", err
+	}
+	plain, err := kr.Decrypt(value)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
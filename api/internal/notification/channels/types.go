@@ -5,6 +5,7 @@ type RenderedTemplate struct {
 	Subject string
 	Content string
 	CTAs    []RenderedCTA
+	Format  string // Format escape của Content: text | html | markdown | json (rỗng = text)
 }
 
 // RenderedCTA là CTA đã được render
@@ -46,6 +46,7 @@ func GetSeverityFromEventType(eventType string) string {
 		strings.Contains(eventType, "_alert") ||
 		strings.Contains(eventType, "_timeout") ||
 		strings.Contains(eventType, "_overload") ||
+		strings.Contains(eventType, "_escalation") ||
 		strings.Contains(eventType, "ads_action_pending_approval") ||
 		strings.Contains(eventType, "ads_action_executed_failed") ||
 		strings.Contains(eventType, "ads_chs_kill") {
@@ -0,0 +1,92 @@
+// Package inboxlive — Đẩy realtime hộp thư in-app của user qua WebSocket (cùng kiểu hub với decisionlive).
+//
+// Key subscribe = (org, user): một user đăng nhập nhiều tab / thiết bị nhận cùng sự kiện.
+package inboxlive
+
+import (
+	"sync"
+
+	notifmodels "meta_commerce/internal/api/notification/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Loại sự kiện gửi qua WebSocket hộp thư.
+const (
+	EventNotification = "notification" // Thông báo mới (Item)
+	EventUnreadCount  = "unread_count" // Số chưa đọc thay đổi (sau mark-read ở tab khác)
+)
+
+// InboxLiveEvent một frame JSON gửi tới client.
+type InboxLiveEvent struct {
+	Type        string                             `json:"type"`
+	Item        *notifmodels.NotificationInboxItem `json:"item,omitempty"`
+	UnreadCount int64                              `json:"unreadCount"`
+}
+
+// subscriberBuffer số frame giữ cho một client chậm trước khi bỏ frame.
+const subscriberBuffer = 64
+
+type subscription struct {
+	ch chan InboxLiveEvent
+}
+
+type hub struct {
+	mu   sync.Mutex
+	subs map[string][]*subscription
+}
+
+var defaultHub = &hub{subs: make(map[string][]*subscription)}
+
+func key(orgID, userID primitive.ObjectID) string {
+	return orgID.Hex() + ":" + userID.Hex()
+}
+
+// Subscribe đăng ký nhận sự kiện hộp thư của user trong org. Gọi cancel khi đóng WebSocket.
+func Subscribe(orgID, userID primitive.ObjectID) (<-chan InboxLiveEvent, func()) {
+	return defaultHub.subscribe(key(orgID, userID))
+}
+
+// Publish đẩy sự kiện tới mọi WebSocket đang mở của user trong org. Không block: client chậm bị bỏ frame.
+func Publish(orgID, userID primitive.ObjectID, ev InboxLiveEvent) {
+	defaultHub.broadcast(key(orgID, userID), ev)
+}
+
+func (h *hub) subscribe(k string) (<-chan InboxLiveEvent, func()) {
+	sub := &subscription{ch: make(chan InboxLiveEvent, subscriberBuffer)}
+	h.mu.Lock()
+	h.subs[k] = append(h.subs[k], sub)
+	h.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			list := h.subs[k]
+			for i, s := range list {
+				if s == sub {
+					h.subs[k] = append(list[:i:i], list[i+1:]...)
+					break
+				}
+			}
+			if len(h.subs[k]) == 0 {
+				delete(h.subs, k)
+			}
+			close(sub.ch)
+		})
+	}
+	return sub.ch, cancel
+}
+
+// broadcast giữ lock trong lúc gửi: cancel (close channel) không chạy xen giữa → không gửi vào channel đã đóng.
+func (h *hub) broadcast(k string, ev InboxLiveEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, sub := range h.subs[k] {
+		select {
+		case sub.ch <- ev:
+		default:
+		}
+	}
+}
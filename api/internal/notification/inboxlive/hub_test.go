@@ -0,0 +1,48 @@
+package inboxlive
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSubscribePublish(t *testing.T) {
+	org, user, other := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	tab1, cancel1 := Subscribe(org, user)
+	tab2, cancel2 := Subscribe(org, user)
+	defer cancel2()
+	otherCh, cancelOther := Subscribe(org, other)
+	defer cancelOther()
+
+	Publish(org, user, InboxLiveEvent{Type: EventUnreadCount, UnreadCount: 3})
+	for i, ch := range []<-chan InboxLiveEvent{tab1, tab2} {
+		select {
+		case ev := <-ch:
+			if ev.UnreadCount != 3 {
+				t.Errorf("tab %d: got %+v", i+1, ev)
+			}
+		default:
+			t.Errorf("tab %d không nhận được sự kiện", i+1)
+		}
+	}
+	select {
+	case ev := <-otherCh:
+		t.Errorf("user khác không được nhận, got %+v", ev)
+	default:
+	}
+
+	cancel1()
+	cancel1() // gọi lại không panic
+	if _, ok := <-tab1; ok {
+		t.Error("channel phải đóng sau cancel")
+	}
+	Publish(org, user, InboxLiveEvent{Type: EventUnreadCount}) // không gửi vào channel đã đóng
+
+	// Client chậm: quá buffer thì bỏ frame, không block
+	for i := 0; i < subscriberBuffer+10; i++ {
+		Publish(org, user, InboxLiveEvent{Type: EventUnreadCount})
+	}
+	if len(tab2) != subscriberBuffer {
+		t.Errorf("buffer = %d, muốn %d", len(tab2), subscriberBuffer)
+	}
+}
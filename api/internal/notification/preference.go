@@ -0,0 +1,58 @@
+package notification
+
+import (
+	notifmodels "meta_commerce/internal/api/notification/models"
+)
+
+// ResolvePreferences chọn preference áp dụng cho event của từng user: eventType khớp → domain khớp → mặc định.
+// Bản ghi cụ thể nhất thắng kể cả khi là hủy đăng ký; chỉ trả user còn đăng ký, Channels rỗng → in_app.
+// Thứ tự kết quả theo thứ tự xuất hiện đầu tiên của user trong prefs.
+func ResolvePreferences(prefs []notifmodels.NotificationPreference, eventType, domain string) []notifmodels.NotificationPreference {
+	rank := func(p *notifmodels.NotificationPreference) int {
+		switch {
+		case p.EventType != "":
+			if p.EventType == eventType {
+				return 3
+			}
+		case p.Domain != "":
+			if p.Domain == domain {
+				return 2
+			}
+		default:
+			return 1
+		}
+		return 0
+	}
+
+	best := make(map[string]int)
+	order := make([]string, 0)
+	for i := range prefs {
+		r := rank(&prefs[i])
+		if r == 0 {
+			continue
+		}
+		uid := prefs[i].UserID.Hex()
+		cur, seen := best[uid]
+		if !seen {
+			order = append(order, uid)
+			best[uid] = i
+			continue
+		}
+		if r > rank(&prefs[cur]) {
+			best[uid] = i
+		}
+	}
+
+	result := make([]notifmodels.NotificationPreference, 0, len(order))
+	for _, uid := range order {
+		p := prefs[best[uid]]
+		if !p.Subscribed {
+			continue
+		}
+		if len(p.Channels) == 0 {
+			p.Channels = []string{notifmodels.PreferenceChannelInApp}
+		}
+		result = append(result, p)
+	}
+	return result
+}
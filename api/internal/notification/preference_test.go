@@ -0,0 +1,39 @@
+package notification
+
+import (
+	"reflect"
+	"testing"
+
+	notifmodels "meta_commerce/internal/api/notification/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestResolvePreferences(t *testing.T) {
+	an, binh, chi, dung := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	prefs := []notifmodels.NotificationPreference{
+		// An: đăng ký cả domain ads nhưng hủy riêng ads_budget_exhausted
+		{UserID: an, Domain: "ads", Subscribed: true, Channels: []string{"email"}},
+		{UserID: an, EventType: "ads_budget_exhausted", Subscribed: false},
+		// Bình: mặc định nhận mọi event, chưa chọn kênh
+		{UserID: binh, Subscribed: true},
+		// Chi: hủy mặc định nhưng đăng ký riêng eventType
+		{UserID: chi, Subscribed: false},
+		{UserID: chi, EventType: "ads_budget_exhausted", Subscribed: true, Channels: []string{"in_app", "telegram"}, TelegramChatID: "1"},
+		// Dũng: chỉ đăng ký domain khác → không áp dụng
+		{UserID: dung, Domain: "order", Subscribed: true},
+	}
+
+	got := ResolvePreferences(prefs, "ads_budget_exhausted", "ads")
+	if len(got) != 2 || got[0].UserID != binh || got[1].UserID != chi {
+		t.Fatalf("muốn [Bình, Chi], got %+v", got)
+	}
+	if !reflect.DeepEqual(got[0].Channels, []string{"in_app"}) {
+		t.Errorf("kênh rỗng phải mặc định in_app, got %v", got[0].Channels)
+	}
+
+	got = ResolvePreferences(prefs, "ads_spend_spike", "ads")
+	if len(got) != 2 || got[0].UserID != an || got[0].Channels[0] != "email" || got[1].UserID != binh {
+		t.Errorf("eventType khác trong domain ads: muốn [An(email), Bình], got %+v", got)
+	}
+}
@@ -15,6 +15,7 @@ import (
 type Route struct {
 	OrganizationID primitive.ObjectID
 	ChannelID      primitive.ObjectID
+	Rule           *notifmodels.NotificationRoutingRule // Rule sinh ra route — quiet hours / dedup / digest
 }
 
 // Router xử lý việc tìm routing rules và tạo routes
@@ -135,6 +136,7 @@ func (r *Router) FindRoutes(ctx context.Context, eventType string, domain string
 				routes = append(routes, Route{
 					OrganizationID: orgID,
 					ChannelID:      channel.ID,
+					Rule:           &rule,
 				})
 			}
 		}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	notifmodels "meta_commerce/internal/api/notification/models"
@@ -11,6 +12,7 @@ import (
 	"meta_commerce/internal/common"
 	"meta_commerce/internal/cta"
 	"meta_commerce/internal/notification/channels"
+	"meta_commerce/internal/notification/tmpl"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -87,29 +89,12 @@ func (t *Template) FindTemplate(ctx context.Context, eventType string, channelTy
 // RenderedTemplate và RenderedCTA đã được di chuyển vào channels package để tránh import cycle
 
 // Render render template với payload và organization ID
+// Subject và action của CTA render dạng text; Content escape theo Format của template (rỗng → mặc định theo channelType).
 // CTAs sẽ được render từ CTACodes (nếu có) hoặc từ CTAs cũ (backward compatibility)
 func (t *Template) Render(ctx context.Context, template *notifmodels.NotificationTemplate, payload map[string]interface{}, organizationID primitive.ObjectID, baseURL string) (*channels.RenderedTemplate, error) {
-	// Render subject
-	subject := template.Subject
-	for _, variable := range template.Variables {
-		value, exists := payload[variable]
-		if !exists {
-			value = ""
-		}
-		placeholder := "{{" + variable + "}}"
-		subject = strings.ReplaceAll(subject, placeholder, fmt.Sprintf("%v", value))
-	}
-
-	// Render content
-	content := template.Content
-	for _, variable := range template.Variables {
-		value, exists := payload[variable]
-		if !exists {
-			value = ""
-		}
-		placeholder := "{{" + variable + "}}"
-		content = strings.ReplaceAll(content, placeholder, fmt.Sprintf("%v", value))
-	}
+	format := ResolveTemplateFormat(template)
+	subject := renderTemplateText(template.Subject, template.Variables, payload, tmpl.FormatText)
+	content := renderTemplateText(template.Content, template.Variables, payload, format)
 
 	// Render CTAs
 	renderedCTAs := []channels.RenderedCTA{}
@@ -150,14 +135,7 @@ func (t *Template) Render(ctx context.Context, template *notifmodels.Notificatio
 			}
 
 			// Render variables trong Action
-			for _, variable := range template.Variables {
-				value, exists := payload[variable]
-				if !exists {
-					value = ""
-				}
-				placeholder := "{{" + variable + "}}"
-				renderedCTA.Action = strings.ReplaceAll(renderedCTA.Action, placeholder, fmt.Sprintf("%v", value))
-			}
+			renderedCTA.Action = renderTemplateText(renderedCTA.Action, template.Variables, payload, tmpl.FormatText)
 
 			// Render {{baseUrl}} đặc biệt
 			if baseUrl, exists := payload["baseUrl"]; exists {
@@ -173,6 +151,104 @@ func (t *Template) Render(ctx context.Context, template *notifmodels.Notificatio
 		Subject: subject,
 		Content: content,
 		CTAs:    renderedCTAs,
+		Format:  format,
 	}, nil
 }
 
+// ResolveTemplateFormat format escape của Content: khai báo trên template, không có → mặc định theo channelType.
+func ResolveTemplateFormat(template *notifmodels.NotificationTemplate) string {
+	if template.Format != "" {
+		return template.Format
+	}
+	return tmpl.DefaultFormatForChannel(template.ChannelType)
+}
+
+// renderTemplateText render src bằng ngôn ngữ template (tmpl).
+// Template cũ không parse được (đã lưu trước khi có validation) → thay thế {{variable}} như trước.
+func renderTemplateText(src string, variables []string, payload map[string]interface{}, format string) string {
+	parsed, err := tmpl.Parse(src)
+	if err == nil {
+		return parsed.Execute(payload, format)
+	}
+	for _, variable := range variables {
+		value, exists := payload[variable]
+		if !exists {
+			value = ""
+		}
+		src = strings.ReplaceAll(src, "{{"+variable+"}}", fmt.Sprintf("%v", value))
+	}
+	return src
+}
+
+// TemplatePreview kết quả preview template với payload mẫu.
+type TemplatePreview struct {
+	Subject             string   `json:"subject"`
+	Content             string   `json:"content"`
+	CTAActions          []string `json:"ctaActions,omitempty"`
+	Format              string   `json:"format"`
+	MissingVariables    []string `json:"missingVariables"`    // Template dùng nhưng payload không có
+	UnusedVariables     []string `json:"unusedVariables"`     // Khai báo trong variables nhưng template không dùng
+	UndeclaredVariables []string `json:"undeclaredVariables"` // Template dùng nhưng không khai báo trong variables (gồm cả field của item trong #each)
+}
+
+// PreviewTemplate render subject, content và action của CTA cũ với payload mẫu, kèm báo cáo biến.
+// format rỗng → ResolveTemplateFormat. CTACodes không render (cần DB) — preview chỉ phần nội dung.
+func PreviewTemplate(template *notifmodels.NotificationTemplate, payload map[string]interface{}, format string) (*TemplatePreview, error) {
+	if format == "" {
+		format = ResolveTemplateFormat(template)
+	}
+	preview := &TemplatePreview{Format: format, CTAActions: []string{}}
+	missing := make(map[string]bool)
+	refs := make(map[string]bool)
+	render := func(name, src, f string) (string, error) {
+		parsed, err := tmpl.Parse(src)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", name, err)
+		}
+		for _, r := range parsed.Refs() {
+			refs[r] = true
+		}
+		out, report := parsed.ExecuteReport(payload, f)
+		for _, m := range report.Missing {
+			missing[m] = true
+		}
+		return out, nil
+	}
+
+	var err error
+	if preview.Subject, err = render("subject", template.Subject, tmpl.FormatText); err != nil {
+		return nil, err
+	}
+	if preview.Content, err = render("content", template.Content, format); err != nil {
+		return nil, err
+	}
+	for i, c := range template.CTAs {
+		action, err := render(fmt.Sprintf("ctas[%d].action", i), c.Action, tmpl.FormatText)
+		if err != nil {
+			return nil, err
+		}
+		preview.CTAActions = append(preview.CTAActions, action)
+	}
+
+	declared := make(map[string]bool, len(template.Variables))
+	preview.UnusedVariables = []string{}
+	for _, v := range template.Variables {
+		declared[v] = true
+		if !refs[v] {
+			preview.UnusedVariables = append(preview.UnusedVariables, v)
+		}
+	}
+	preview.UndeclaredVariables = []string{}
+	for r := range refs {
+		if !declared[r] {
+			preview.UndeclaredVariables = append(preview.UndeclaredVariables, r)
+		}
+	}
+	preview.MissingVariables = make([]string, 0, len(missing))
+	for m := range missing {
+		preview.MissingVariables = append(preview.MissingVariables, m)
+	}
+	sort.Strings(preview.UndeclaredVariables)
+	sort.Strings(preview.MissingVariables)
+	return preview, nil
+}
@@ -0,0 +1,51 @@
+package notification
+
+import (
+	"reflect"
+	"testing"
+
+	notifmodels "meta_commerce/internal/api/notification/models"
+)
+
+func TestPreviewTemplate(t *testing.T) {
+	tpl := &notifmodels.NotificationTemplate{
+		ChannelType: "email",
+		Subject:     "Đơn {{orderCode}}",
+		Content:     "<p>{{customer.name}} — {{total | currency}}</p>{{#if note}}<i>{{note}}</i>{{/if}}",
+		Variables:   []string{"orderCode", "customer", "total", "legacyVar"},
+		CTAs:        []notifmodels.NotificationCTA{{Label: "Xem", Action: "{{baseUrl}}/orders/{{orderCode}}"}},
+	}
+	payload := map[string]interface{}{
+		"orderCode": "DH01",
+		"customer":  map[string]interface{}{"name": "<An>"},
+		"total":     150000,
+	}
+
+	preview, err := PreviewTemplate(tpl, payload, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.Format != "html" {
+		t.Errorf("email mặc định html, got %s", preview.Format)
+	}
+	if want := "<p>&lt;An&gt; — 150.000 ₫</p>"; preview.Content != want {
+		t.Errorf("content = %q, muốn %q", preview.Content, want)
+	}
+	if preview.Subject != "Đơn DH01" || !reflect.DeepEqual(preview.CTAActions, []string{"/orders/DH01"}) {
+		t.Errorf("subject/cta = %q %v", preview.Subject, preview.CTAActions)
+	}
+	if want := []string{"baseUrl", "note"}; !reflect.DeepEqual(preview.MissingVariables, want) {
+		t.Errorf("missing = %v, muốn %v", preview.MissingVariables, want)
+	}
+	if want := []string{"legacyVar"}; !reflect.DeepEqual(preview.UnusedVariables, want) {
+		t.Errorf("unused = %v, muốn %v", preview.UnusedVariables, want)
+	}
+	if want := []string{"baseUrl", "note"}; !reflect.DeepEqual(preview.UndeclaredVariables, want) {
+		t.Errorf("undeclared = %v, muốn %v", preview.UndeclaredVariables, want)
+	}
+
+	tpl.Content = "{{#if note}}không đóng"
+	if _, err := PreviewTemplate(tpl, payload, ""); err == nil {
+		t.Error("template lỗi cú pháp phải trả lỗi")
+	}
+}
@@ -0,0 +1,161 @@
+package notification
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	notifmodels "meta_commerce/internal/api/notification/models"
+)
+
+// DefaultTimezone múi giờ mặc định cho giờ yên lặng khi rule và tổ chức chưa cấu hình.
+const DefaultTimezone = "Asia/Ho_Chi_Minh"
+
+// ConfigKeyTimezone config item (auth_organization_config_items) múi giờ của tổ chức.
+const ConfigKeyTimezone = "timezone"
+
+// EventTypeNotificationDigest eventType template cho bản tổng hợp digest (không có template → nội dung mặc định).
+const EventTypeNotificationDigest = "notification_digest"
+
+// Hành động chống spam áp dụng cho một route.
+const (
+	ThrottleSend     = "send"
+	ThrottleSuppress = "suppressed"
+	ThrottleDigest   = "digested"
+)
+
+// Lý do chặn / gom ghi vào delivery history.
+const (
+	ThrottleReasonDedup      = "dedup"
+	ThrottleReasonQuietHours = "quiet_hours"
+	ThrottleReasonDigest     = "digest"
+)
+
+var dedupKeyVarPattern = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_.]+)\s*\}\}`)
+
+// RenderDedupKey render DedupKey của rule từ payload: {{eventType}} và {{field}} (hỗ trợ a.b lồng nhau).
+// Field thiếu → chuỗi rỗng. Rỗng tpl → "".
+func RenderDedupKey(tpl string, eventType string, payload map[string]interface{}) string {
+	if strings.TrimSpace(tpl) == "" {
+		return ""
+	}
+	return dedupKeyVarPattern.ReplaceAllStringFunc(tpl, func(m string) string {
+		name := dedupKeyVarPattern.FindStringSubmatch(m)[1]
+		if name == "eventType" {
+			return eventType
+		}
+		var cur interface{} = payload
+		for _, part := range strings.Split(name, ".") {
+			obj, ok := cur.(map[string]interface{})
+			if !ok {
+				return ""
+			}
+			cur = obj[part]
+		}
+		if cur == nil {
+			return ""
+		}
+		return fmt.Sprintf("%v", cur)
+	})
+}
+
+// parseClock parse "HH:MM" → số phút trong ngày.
+func parseClock(s string) (int, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("giờ '%s' không đúng định dạng HH:MM", s)
+	}
+	h, errH := strconv.Atoi(parts[0])
+	m, errM := strconv.Atoi(parts[1])
+	if errH != nil || errM != nil || h < 0 || h > 23 || m < 0 || m > 59 {
+		return 0, fmt.Errorf("giờ '%s' không đúng định dạng HH:MM", s)
+	}
+	return h*60 + m, nil
+}
+
+// QuietHoursEnd trả (true, thời điểm kết thúc) nếu t nằm trong giờ yên lặng theo loc.
+// Start == End hoặc cấu hình sai → không có giờ yên lặng.
+func QuietHoursEnd(qh *notifmodels.RoutingQuietHours, t time.Time, loc *time.Location) (bool, time.Time) {
+	if qh == nil {
+		return false, time.Time{}
+	}
+	start, errS := parseClock(qh.Start)
+	end, errE := parseClock(qh.End)
+	if errS != nil || errE != nil || start == end {
+		return false, time.Time{}
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	local := t.In(loc)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	minute := local.Hour()*60 + local.Minute()
+	at := func(day time.Time, m int) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), m/60, m%60, 0, 0, loc)
+	}
+
+	if start < end {
+		if minute >= start && minute < end {
+			return true, at(midnight, end)
+		}
+		return false, time.Time{}
+	}
+	// Qua đêm: [start, 24:00) ∪ [00:00, end)
+	if minute >= start {
+		return true, at(midnight.AddDate(0, 0, 1), end)
+	}
+	if minute < end {
+		return true, at(midnight, end)
+	}
+	return false, time.Time{}
+}
+
+// BypassesQuietHours severity được gửi ngay cả trong giờ yên lặng. Rule không khai báo → chỉ critical.
+func BypassesQuietHours(qh *notifmodels.RoutingQuietHours, severity string) bool {
+	if qh == nil {
+		return true
+	}
+	if len(qh.BypassSeverities) == 0 {
+		return severity == SeverityCritical
+	}
+	for _, s := range qh.BypassSeverities {
+		if s == severity {
+			return true
+		}
+	}
+	return false
+}
+
+// DigestFlushAt thời điểm gửi bản tổng hợp cho event tại t: cuối cửa sổ N phút (căn theo epoch để
+// các event cùng cửa sổ gom chung); rơi vào giờ yên lặng và severity không được bypass → dời tới lúc kết thúc giờ yên lặng.
+func DigestFlushAt(rule *notifmodels.NotificationRoutingRule, severity string, t time.Time, loc *time.Location) time.Time {
+	interval := time.Duration(rule.DigestMinutes) * time.Minute
+	if interval <= 0 {
+		return t
+	}
+	flushAt := t.Truncate(interval).Add(interval)
+	if quiet, end := QuietHoursEnd(rule.QuietHours, flushAt, loc); quiet && !BypassesQuietHours(rule.QuietHours, severity) {
+		flushAt = end
+	}
+	return flushAt
+}
+
+// DecideThrottle quyết định hành động cho một route (chưa xét dedup — cần DB):
+// giờ yên lặng + severity không được bypass → digest (nếu rule bật digest) hoặc suppressed; rule bật digest → digested; còn lại send.
+func DecideThrottle(rule *notifmodels.NotificationRoutingRule, severity string, t time.Time, loc *time.Location) (action string, reason string) {
+	if rule == nil {
+		return ThrottleSend, ""
+	}
+	if quiet, _ := QuietHoursEnd(rule.QuietHours, t, loc); quiet && !BypassesQuietHours(rule.QuietHours, severity) {
+		if rule.DigestMinutes > 0 {
+			return ThrottleDigest, ThrottleReasonQuietHours
+		}
+		return ThrottleSuppress, ThrottleReasonQuietHours
+	}
+	if rule.DigestMinutes > 0 {
+		return ThrottleDigest, ThrottleReasonDigest
+	}
+	return ThrottleSend, ""
+}
@@ -0,0 +1,102 @@
+package notification
+
+import (
+	"testing"
+	"time"
+
+	notifmodels "meta_commerce/internal/api/notification/models"
+)
+
+func TestRenderDedupKey(t *testing.T) {
+	payload := map[string]interface{}{
+		"campaignId": "c_1",
+		"metrics":    map[string]interface{}{"roas": 1.5},
+	}
+	got := RenderDedupKey("{{eventType}}:{{ campaignId }}:{{metrics.roas}}:{{missing}}", "ads_circuit_breaker", payload)
+	if want := "ads_circuit_breaker:c_1:1.5:"; got != want {
+		t.Errorf("RenderDedupKey = %q, muốn %q", got, want)
+	}
+	if RenderDedupKey("  ", "x", payload) != "" {
+		t.Error("template rỗng phải trả chuỗi rỗng")
+	}
+}
+
+func TestQuietHoursEnd(t *testing.T) {
+	loc := time.FixedZone("ICT", 7*3600)
+	overnight := &notifmodels.RoutingQuietHours{Start: "22:00", End: "07:00"}
+	cases := []struct {
+		at      time.Time
+		quiet   bool
+		wantEnd time.Time
+	}{
+		{time.Date(2026, 10, 17, 23, 30, 0, 0, loc), true, time.Date(2026, 10, 18, 7, 0, 0, 0, loc)},
+		{time.Date(2026, 10, 18, 6, 59, 0, 0, loc), true, time.Date(2026, 10, 18, 7, 0, 0, 0, loc)},
+		{time.Date(2026, 10, 18, 7, 0, 0, 0, loc), false, time.Time{}},
+		{time.Date(2026, 10, 17, 12, 0, 0, 0, loc), false, time.Time{}},
+		// Thời điểm UTC được quy về múi giờ của tổ chức
+		{time.Date(2026, 10, 17, 16, 0, 0, 0, time.UTC), true, time.Date(2026, 10, 18, 7, 0, 0, 0, loc)},
+	}
+	for _, c := range cases {
+		quiet, end := QuietHoursEnd(overnight, c.at, loc)
+		if quiet != c.quiet || !end.Equal(c.wantEnd) {
+			t.Errorf("%v: quiet=%v end=%v, muốn %v %v", c.at, quiet, end, c.quiet, c.wantEnd)
+		}
+	}
+
+	sameDay := &notifmodels.RoutingQuietHours{Start: "12:00", End: "13:30"}
+	if quiet, end := QuietHoursEnd(sameDay, time.Date(2026, 10, 17, 12, 15, 0, 0, loc), loc); !quiet || end.Hour() != 13 || end.Minute() != 30 {
+		t.Errorf("12:15 phải trong giờ yên lặng tới 13:30, got %v %v", quiet, end)
+	}
+	if quiet, _ := QuietHoursEnd(&notifmodels.RoutingQuietHours{Start: "25:00", End: "07:00"}, time.Now(), loc); quiet {
+		t.Error("cấu hình sai phải bị bỏ qua")
+	}
+}
+
+func TestDecideThrottle(t *testing.T) {
+	loc := time.FixedZone("ICT", 7*3600)
+	night := time.Date(2026, 10, 17, 23, 0, 0, 0, loc)
+	day := time.Date(2026, 10, 17, 10, 0, 0, 0, loc)
+	rule := &notifmodels.NotificationRoutingRule{QuietHours: &notifmodels.RoutingQuietHours{Start: "22:00", End: "07:00"}}
+
+	if action, reason := DecideThrottle(rule, SeverityHigh, night, loc); action != ThrottleSuppress || reason != ThrottleReasonQuietHours {
+		t.Errorf("high trong giờ yên lặng: %s/%s, muốn suppressed/quiet_hours", action, reason)
+	}
+	if action, _ := DecideThrottle(rule, SeverityCritical, night, loc); action != ThrottleSend {
+		t.Errorf("critical mặc định bypass giờ yên lặng, got %s", action)
+	}
+	if action, _ := DecideThrottle(rule, SeverityHigh, day, loc); action != ThrottleSend {
+		t.Errorf("ngoài giờ yên lặng phải gửi, got %s", action)
+	}
+
+	rule.QuietHours.BypassSeverities = []string{SeverityHigh}
+	if action, _ := DecideThrottle(rule, SeverityCritical, night, loc); action != ThrottleSuppress {
+		t.Errorf("bypassSeverities khai báo rõ thay thế mặc định, got %s", action)
+	}
+
+	rule.DigestMinutes = 30
+	if action, reason := DecideThrottle(rule, SeverityMedium, night, loc); action != ThrottleDigest || reason != ThrottleReasonQuietHours {
+		t.Errorf("digest + giờ yên lặng: %s/%s, muốn digested/quiet_hours", action, reason)
+	}
+	if action, reason := DecideThrottle(rule, SeverityMedium, day, loc); action != ThrottleDigest || reason != ThrottleReasonDigest {
+		t.Errorf("digest ban ngày: %s/%s, muốn digested/digest", action, reason)
+	}
+}
+
+func TestDigestFlushAt(t *testing.T) {
+	loc := time.FixedZone("ICT", 7*3600)
+	rule := &notifmodels.NotificationRoutingRule{DigestMinutes: 15}
+
+	at := time.Date(2026, 10, 17, 10, 7, 30, 0, loc)
+	if got, want := DigestFlushAt(rule, SeverityMedium, at, loc), time.Date(2026, 10, 17, 10, 15, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("flushAt = %v, muốn %v", got, want)
+	}
+
+	rule.QuietHours = &notifmodels.RoutingQuietHours{Start: "22:00", End: "07:00"}
+	night := time.Date(2026, 10, 17, 23, 50, 0, 0, loc)
+	if got, want := DigestFlushAt(rule, SeverityMedium, night, loc), time.Date(2026, 10, 18, 7, 0, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("flushAt trong giờ yên lặng = %v, muốn dời tới %v", got, want)
+	}
+	if got, want := DigestFlushAt(rule, SeverityCritical, night, loc), time.Date(2026, 10, 18, 0, 0, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("critical không bị dời qua giờ yên lặng: %v, muốn %v", got, want)
+	}
+}
@@ -0,0 +1,38 @@
+package tmpl
+
+import (
+	"bytes"
+	"encoding/json"
+	"html"
+	"strings"
+)
+
+// markdownV2Special ký tự phải escape trong Telegram MarkdownV2.
+const markdownV2Special = "_*[]()~`>#+-=|{}.!\\"
+
+// Escape escape giá trị biến theo format output. Format lạ → không escape.
+func Escape(format, s string) string {
+	switch format {
+	case FormatHTML:
+		return html.EscapeString(s)
+	case FormatMarkdown:
+		var b strings.Builder
+		for _, r := range s {
+			if strings.ContainsRune(markdownV2Special, r) {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		}
+		return b.String()
+	case FormatJSON:
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(s); err != nil {
+			return s
+		}
+		out := strings.TrimSuffix(buf.String(), "\n")
+		return out[1 : len(out)-1]
+	}
+	return s
+}
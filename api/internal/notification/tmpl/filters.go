@@ -0,0 +1,216 @@
+package tmpl
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultDateLayout layout mặc định của filter date (kiểu Việt Nam).
+const DefaultDateLayout = "02/01/2006 15:04"
+
+// DefaultDateTimezone múi giờ mặc định của filter date.
+const DefaultDateTimezone = "Asia/Ho_Chi_Minh"
+
+type filterFunc func(v interface{}, args []string) interface{}
+
+// filters bảng filter hỗ trợ. Input không hợp lệ → trả nguyên giá trị (không làm hỏng cả thông báo).
+var filters = map[string]filterFunc{
+	"number":   filterNumber,
+	"currency": filterCurrency,
+	"percent":  filterPercent,
+	"date":     filterDate,
+	"upper":    func(v interface{}, _ []string) interface{} { return strings.ToUpper(toString(v)) },
+	"lower":    func(v interface{}, _ []string) interface{} { return strings.ToLower(toString(v)) },
+	"trim":     func(v interface{}, _ []string) interface{} { return strings.TrimSpace(toString(v)) },
+	"truncate": filterTruncate,
+	"default":  filterDefault,
+	"join":     filterJoin,
+	"length":   filterLength,
+	"json":     filterJSON,
+}
+
+// argInt lấy tham số thứ i dạng số nguyên, thiếu/sai → def.
+func argInt(args []string, i, def int) int {
+	if i >= len(args) {
+		return def
+	}
+	n, err := strconv.Atoi(args[i])
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+func argString(args []string, i int, def string) string {
+	if i >= len(args) {
+		return def
+	}
+	return args[i]
+}
+
+// {{amount | number}} → 1.234.567; {{ratio | number 2}} → 1,23 (kiểu Việt Nam: "." nghìn, "," thập phân).
+func filterNumber(v interface{}, args []string) interface{} {
+	f, ok := toFloat(v)
+	if !ok {
+		return v
+	}
+	return formatNumber(f, argInt(args, 0, 0), ".", ",")
+}
+
+// {{amount | currency}} → 1.234.567 ₫; {{amount | currency USD}} → $1,234.56.
+func filterCurrency(v interface{}, args []string) interface{} {
+	f, ok := toFloat(v)
+	if !ok {
+		return v
+	}
+	switch code := strings.ToUpper(argString(args, 0, "VND")); code {
+	case "VND":
+		return formatNumber(f, 0, ".", ",") + " ₫"
+	case "USD":
+		return signed(f, "$"+formatNumber(math.Abs(f), 2, ",", "."))
+	case "EUR":
+		return signed(f, "€"+formatNumber(math.Abs(f), 2, ",", "."))
+	default:
+		return formatNumber(f, 2, ",", ".") + " " + code
+	}
+}
+
+func signed(f float64, s string) string {
+	if f < 0 {
+		return "-" + s
+	}
+	return s
+}
+
+// {{ctr | percent}} → 2,5% — giá trị đã là phần trăm.
+func filterPercent(v interface{}, args []string) interface{} {
+	f, ok := toFloat(v)
+	if !ok {
+		return v
+	}
+	return formatNumber(f, argInt(args, 0, 1), ".", ",") + "%"
+}
+
+// {{createdAt | date}} / {{createdAt | date "02/01/2006" "UTC"}}.
+// Nhận time.Time, primitive.DateTime, RFC3339, unix giây hoặc mili giây.
+func filterDate(v interface{}, args []string) interface{} {
+	t, ok := toTime(v)
+	if !ok {
+		return v
+	}
+	loc, err := time.LoadLocation(argString(args, 1, DefaultDateTimezone))
+	if err != nil {
+		loc = time.UTC
+	}
+	return t.In(loc).Format(argString(args, 0, DefaultDateLayout))
+}
+
+func toTime(v interface{}) (time.Time, bool) {
+	switch x := v.(type) {
+	case time.Time:
+		return x, true
+	case primitive.DateTime:
+		return x.Time(), true
+	case string:
+		if t, err := time.Parse(time.RFC3339, x); err == nil {
+			return t, true
+		}
+	}
+	f, ok := toFloat(v)
+	if !ok || f <= 0 {
+		return time.Time{}, false
+	}
+	// > 1e11 chắc chắn là mili giây (1e11 giây ≈ năm 5138)
+	if f > 1e11 {
+		return time.UnixMilli(int64(f)), true
+	}
+	return time.Unix(int64(f), 0), true
+}
+
+// {{message | truncate 100}} — cắt theo rune, thêm "…".
+func filterTruncate(v interface{}, args []string) interface{} {
+	s := toString(v)
+	n := argInt(args, 0, 0)
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "…"
+}
+
+// {{name | default "Khách"}} — giá trị rỗng/thiếu → mặc định.
+func filterDefault(v interface{}, args []string) interface{} {
+	if truthy(v) {
+		return v
+	}
+	return argString(args, 0, "")
+}
+
+// {{tags | join ", "}}.
+func filterJoin(v interface{}, args []string) interface{} {
+	rv := reflect.ValueOf(v)
+	if v == nil || (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) {
+		return v
+	}
+	parts := make([]string, rv.Len())
+	for i := range parts {
+		parts[i] = toString(rv.Index(i).Interface())
+	}
+	return strings.Join(parts, argString(args, 0, ", "))
+}
+
+func filterLength(v interface{}, _ []string) interface{} {
+	if s, ok := v.(string); ok {
+		return utf8.RuneCountInString(s)
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return rv.Len()
+	}
+	return 0
+}
+
+// {{payload | json}} — chuỗi JSON của giá trị (dùng với {{{ }}} khi nhúng object vào webhook).
+func filterJSON(v interface{}, _ []string) interface{} {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return toString(v)
+	}
+	return string(b)
+}
+
+// formatNumber làm tròn tới decimals chữ số, nhóm nghìn bằng thousandSep.
+func formatNumber(f float64, decimals int, thousandSep, decimalSep string) string {
+	if decimals < 0 {
+		decimals = 0
+	}
+	s := strconv.FormatFloat(f, 'f', decimals, 64)
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	intPart, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, frac = s[:i], s[i+1:]
+	}
+	var b strings.Builder
+	if neg {
+		b.WriteByte('-')
+	}
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteString(thousandSep)
+		}
+		b.WriteRune(c)
+	}
+	if frac != "" {
+		b.WriteString(decimalSep)
+		b.WriteString(frac)
+	}
+	return b.String()
+}
@@ -0,0 +1,338 @@
+package tmpl
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Report kết quả render phục vụ preview: biến template dùng nhưng payload không có.
+type Report struct {
+	Missing []string
+}
+
+type frame struct {
+	data  interface{}
+	loop  bool
+	index int
+	count int
+	key   string
+}
+
+type renderer struct {
+	format  string
+	stack   []frame
+	missing map[string]bool
+	out     strings.Builder
+}
+
+// Execute render template với payload; output biến được escape theo format (FormatText/HTML/Markdown/JSON).
+// Biến không có → chuỗi rỗng (giống cách thay thế cũ).
+func (t *Template) Execute(data map[string]interface{}, format string) string {
+	out, _ := t.ExecuteReport(data, format)
+	return out
+}
+
+// ExecuteReport như Execute, kèm danh sách biến bị thiếu trong payload.
+func (t *Template) ExecuteReport(data map[string]interface{}, format string) (string, Report) {
+	r := &renderer{format: format, stack: []frame{{data: data}}, missing: make(map[string]bool)}
+	r.render(t.nodes)
+	report := Report{Missing: make([]string, 0, len(r.missing))}
+	for k := range r.missing {
+		report.Missing = append(report.Missing, k)
+	}
+	sort.Strings(report.Missing)
+	return r.out.String(), report
+}
+
+func (r *renderer) render(nodes []node) {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case textNode:
+			r.out.WriteString(n.text)
+		case varNode:
+			v, _ := r.lookup(n.expr.path)
+			for _, f := range n.expr.filters {
+				v = filters[f.name](v, f.args)
+			}
+			s := toString(v)
+			if !n.raw {
+				s = Escape(r.format, s)
+			}
+			r.out.WriteString(s)
+		case ifNode:
+			ok := r.evalCondition(n.cond)
+			if n.negate {
+				ok = !ok
+			}
+			if ok {
+				r.render(n.then)
+			} else {
+				r.render(n.els)
+			}
+		case eachNode:
+			r.renderEach(n)
+		}
+	}
+}
+
+func (r *renderer) renderEach(n eachNode) {
+	v, _ := r.lookup(n.path)
+	rv := reflect.ValueOf(v)
+	switch {
+	case v != nil && (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && rv.Len() > 0:
+		for i := 0; i < rv.Len(); i++ {
+			r.stack = append(r.stack, frame{data: rv.Index(i).Interface(), loop: true, index: i, count: rv.Len()})
+			r.render(n.body)
+			r.stack = r.stack[:len(r.stack)-1]
+		}
+	case v != nil && rv.Kind() == reflect.Map && rv.Len() > 0:
+		keys := make([]string, 0, rv.Len())
+		values := make(map[string]interface{}, rv.Len())
+		for _, k := range rv.MapKeys() {
+			ks := fmt.Sprintf("%v", k.Interface())
+			keys = append(keys, ks)
+			values[ks] = rv.MapIndex(k).Interface()
+		}
+		sort.Strings(keys)
+		for i, k := range keys {
+			r.stack = append(r.stack, frame{data: values[k], loop: true, index: i, count: len(keys), key: k})
+			r.render(n.body)
+			r.stack = r.stack[:len(r.stack)-1]
+		}
+	default:
+		r.render(n.els)
+	}
+}
+
+func (r *renderer) evalCondition(c condition) bool {
+	left, _ := r.lookup(c.left)
+	if c.op == "" {
+		return truthy(left)
+	}
+	right := c.right.literal
+	if c.right.path != "" {
+		right, _ = r.lookup(c.right.path)
+	}
+	lf, lok := toFloat(left)
+	rf, rok := toFloat(right)
+	if lok && rok {
+		switch c.op {
+		case "==":
+			return lf == rf
+		case "!=":
+			return lf != rf
+		case ">":
+			return lf > rf
+		case ">=":
+			return lf >= rf
+		case "<":
+			return lf < rf
+		case "<=":
+			return lf <= rf
+		}
+	}
+	switch c.op {
+	case "==":
+		return equalLoose(left, right)
+	case "!=":
+		return !equalLoose(left, right)
+	}
+	ls, rs := toString(left), toString(right)
+	switch c.op {
+	case ">":
+		return ls > rs
+	case ">=":
+		return ls >= rs
+	case "<":
+		return ls < rs
+	case "<=":
+		return ls <= rs
+	}
+	return false
+}
+
+// lookup tra path theo scope từ trong ra ngoài. Không thấy → ghi nhận missing (chỉ khi đã qua hết scope).
+func (r *renderer) lookup(path string) (interface{}, bool) {
+	segments := strings.Split(path, ".")
+	top := r.stack[len(r.stack)-1]
+
+	var cur interface{}
+	switch root := segments[0]; {
+	case root == "this":
+		cur = top.data
+	case strings.HasPrefix(root, "@"):
+		lf := r.loopFrame()
+		if lf == nil {
+			r.missing[path] = true
+			return nil, false
+		}
+		switch root {
+		case "@index":
+			return lf.index, true
+		case "@number":
+			return lf.index + 1, true
+		case "@first":
+			return lf.index == 0, true
+		case "@last":
+			return lf.index == lf.count-1, true
+		case "@key":
+			return lf.key, true
+		}
+		r.missing[path] = true
+		return nil, false
+	default:
+		found := false
+		for i := len(r.stack) - 1; i >= 0; i-- {
+			if v, ok := field(r.stack[i].data, root); ok {
+				cur, found = v, true
+				break
+			}
+		}
+		if !found {
+			r.missing[path] = true
+			return nil, false
+		}
+	}
+
+	for _, seg := range segments[1:] {
+		v, ok := field(cur, seg)
+		if !ok {
+			r.missing[path] = true
+			return nil, false
+		}
+		cur = v
+	}
+	return cur, true
+}
+
+func (r *renderer) loopFrame() *frame {
+	for i := len(r.stack) - 1; i >= 0; i-- {
+		if r.stack[i].loop {
+			return &r.stack[i]
+		}
+	}
+	return nil
+}
+
+// field lấy key của map hoặc phần tử theo chỉ số của slice.
+func field(v interface{}, key string) (interface{}, bool) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		val, ok := m[key]
+		return val, ok
+	case primitive.M:
+		val, ok := m[key]
+		return val, ok
+	case primitive.D:
+		for _, e := range m {
+			if e.Key == key {
+				return e.Value, true
+			}
+		}
+		return nil, false
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		val := rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()))
+		if !val.IsValid() {
+			return nil, false
+		}
+		return val.Interface(), true
+	case reflect.Slice, reflect.Array:
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= rv.Len() {
+			return nil, false
+		}
+		return rv.Index(i).Interface(), true
+	}
+	return nil, false
+}
+
+func truthy(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	switch x := v.(type) {
+	case bool:
+		return x
+	case string:
+		return x != ""
+	}
+	if f, ok := toFloat(v); ok {
+		return f != 0
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return rv.Len() > 0
+	case reflect.Ptr, reflect.Interface:
+		return !rv.IsNil()
+	}
+	return true
+}
+
+func equalLoose(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if ab, ok := a.(bool); ok {
+		bb, ok := b.(bool)
+		return ok && ab == bb
+	}
+	return toString(a) == toString(b)
+}
+
+// toFloat chuyển số (kể cả chuỗi số) sang float64.
+func toFloat(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case int:
+		return float64(x), true
+	case int32:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case float32:
+		return float64(x), true
+	case float64:
+		return x, true
+	case uint:
+		return float64(x), true
+	case uint32:
+		return float64(x), true
+	case uint64:
+		return float64(x), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// toString định dạng giá trị ra chuỗi; float không dùng ký hiệu mũ.
+func toString(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(x), 'f', -1, 32)
+	case time.Time:
+		return x.Format(time.RFC3339)
+	case primitive.ObjectID:
+		return x.Hex()
+	}
+	return fmt.Sprintf("%v", v)
+}
@@ -0,0 +1,353 @@
+// Package tmpl — ngôn ngữ template cho notification template (Subject, Content, CTA action).
+//
+// Cú pháp (tương thích {{variable}} cũ):
+//   - {{path}}                  biến (a.b.c, items.0.name, this, @index, @number, @first, @last, @key), tự escape theo format kênh
+//   - {{{path}}}                biến không escape
+//   - {{path | filter arg ...}} filter: number, currency, percent, date, upper, lower, trim, truncate, default, join, length, json
+//   - {{#if cond}}…{{else}}…{{/if}}, {{#unless cond}}…{{/unless}} — cond: path hoặc "path op giá_trị" (==, !=, >, >=, <, <=)
+//   - {{#each path}}…{{else}}(mảng rỗng)…{{/each}} — trong vòng lặp tra biến theo item trước, rồi tới scope ngoài
+//   - {{! chú thích }}
+package tmpl
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Format escape của output theo kênh.
+const (
+	FormatText     = "text"     // Không escape (Telegram không parse_mode, subject email)
+	FormatHTML     = "html"     // Email HTML, Telegram parse_mode=HTML
+	FormatMarkdown = "markdown" // Telegram parse_mode=MarkdownV2
+	FormatJSON     = "json"     // Content là tài liệu JSON, biến nằm trong chuỗi JSON (webhook tự dựng body)
+)
+
+// ValidFormats danh sách format hợp lệ.
+var ValidFormats = []string{FormatText, FormatHTML, FormatMarkdown, FormatJSON}
+
+// DefaultFormatForChannel format mặc định khi template không khai báo.
+// Webhook mặc định text vì body mặc định json.Marshal cả Content; chọn json khi Content tự là tài liệu JSON.
+func DefaultFormatForChannel(channelType string) string {
+	if channelType == "email" {
+		return FormatHTML
+	}
+	return FormatText
+}
+
+// ParseError lỗi cú pháp template, kèm vị trí (byte offset) trong nguồn.
+type ParseError struct {
+	Pos int
+	Msg string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("template lỗi tại vị trí %d: %s", e.Pos, e.Msg)
+}
+
+// Template template đã parse, dùng lại được cho nhiều payload.
+type Template struct {
+	nodes []node
+	refs  map[string]bool // Tên biến gốc được tham chiếu (segment đầu của path)
+}
+
+type node interface{}
+
+type textNode struct{ text string }
+
+type varNode struct {
+	expr expr
+	raw  bool
+}
+
+type ifNode struct {
+	cond   condition
+	negate bool
+	then   []node
+	els    []node
+}
+
+type eachNode struct {
+	path string
+	body []node
+	els  []node
+}
+
+type expr struct {
+	path    string
+	filters []filterCall
+}
+
+type filterCall struct {
+	name string
+	args []string
+}
+
+type condition struct {
+	left  string
+	op    string
+	right operand
+}
+
+type operand struct {
+	path    string // Khác rỗng → so với biến
+	literal interface{}
+}
+
+var (
+	pathPattern = regexp.MustCompile(`^(@?[A-Za-z_][A-Za-z0-9_]*)(\.[A-Za-z0-9_]+)*$`)
+	condOps     = []string{"==", "!=", ">=", "<=", ">", "<"}
+)
+
+// Parse parse nguồn template. Lỗi cú pháp (block không đóng, filter lạ, biến sai tên) → *ParseError.
+func Parse(src string) (*Template, error) {
+	p := &parser{src: src, refs: make(map[string]bool)}
+	nodes, end, err := p.parseUntil(nil)
+	if err != nil {
+		return nil, err
+	}
+	if end != "" {
+		return nil, &ParseError{Pos: p.pos, Msg: fmt.Sprintf("{{%s}} không có block mở tương ứng", end)}
+	}
+	return &Template{nodes: nodes, refs: p.refs}, nil
+}
+
+// Refs tên biến gốc template tham chiếu (đã sort). Biến trong #each có thể là field của item.
+func (t *Template) Refs() []string {
+	out := make([]string, 0, len(t.refs))
+	for k := range t.refs {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+type parser struct {
+	src  string
+	pos  int
+	refs map[string]bool
+}
+
+// parseUntil parse tới khi gặp tag đóng/else thuộc stop; trả tag đã dừng ("" = hết nguồn).
+func (p *parser) parseUntil(stop []string) ([]node, string, error) {
+	nodes := []node{}
+	for p.pos < len(p.src) {
+		open := strings.Index(p.src[p.pos:], "{{")
+		if open < 0 {
+			nodes = append(nodes, textNode{p.src[p.pos:]})
+			p.pos = len(p.src)
+			break
+		}
+		if open > 0 {
+			nodes = append(nodes, textNode{p.src[p.pos : p.pos+open]})
+		}
+		tagStart := p.pos + open
+
+		raw := strings.HasPrefix(p.src[tagStart:], "{{{")
+		openLen, closeTok := 2, "}}"
+		if raw {
+			openLen, closeTok = 3, "}}}"
+		}
+		closeIdx := strings.Index(p.src[tagStart+openLen:], closeTok)
+		if closeIdx < 0 {
+			return nil, "", &ParseError{Pos: tagStart, Msg: "thiếu " + closeTok}
+		}
+		tag := strings.TrimSpace(p.src[tagStart+openLen : tagStart+openLen+closeIdx])
+		p.pos = tagStart + openLen + closeIdx + len(closeTok)
+
+		if raw {
+			e, err := p.parseExpr(tag, tagStart)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, varNode{expr: e, raw: true})
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(tag, "!"):
+			continue
+		case tag == "else" || strings.HasPrefix(tag, "/"):
+			for _, s := range stop {
+				if tag == s {
+					return nodes, tag, nil
+				}
+			}
+			return nil, "", &ParseError{Pos: tagStart, Msg: fmt.Sprintf("{{%s}} không đúng vị trí", tag)}
+		case strings.HasPrefix(tag, "#"):
+			n, err := p.parseBlock(tag, tagStart)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, n)
+		default:
+			e, err := p.parseExpr(tag, tagStart)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, varNode{expr: e})
+		}
+	}
+	if len(stop) > 0 {
+		return nil, "", &ParseError{Pos: p.pos, Msg: fmt.Sprintf("thiếu {{%s}}", stop[len(stop)-1])}
+	}
+	return nodes, "", nil
+}
+
+func (p *parser) parseBlock(tag string, pos int) (node, error) {
+	name, arg := tag[1:], ""
+	if i := strings.IndexAny(name, " \t"); i >= 0 {
+		name, arg = name[:i], strings.TrimSpace(name[i+1:])
+	}
+	if arg == "" {
+		return nil, &ParseError{Pos: pos, Msg: fmt.Sprintf("{{#%s}} thiếu điều kiện", name)}
+	}
+	closeTag := "/" + name
+
+	switch name {
+	case "if", "unless":
+		cond, err := p.parseCondition(arg, pos)
+		if err != nil {
+			return nil, err
+		}
+		then, end, err := p.parseUntil([]string{"else", closeTag})
+		if err != nil {
+			return nil, err
+		}
+		var els []node
+		if end == "else" {
+			if els, _, err = p.parseUntil([]string{closeTag}); err != nil {
+				return nil, err
+			}
+		}
+		return ifNode{cond: cond, negate: name == "unless", then: then, els: els}, nil
+	case "each":
+		if !pathPattern.MatchString(arg) {
+			return nil, &ParseError{Pos: pos, Msg: fmt.Sprintf("{{#each}} cần tên biến, nhận '%s'", arg)}
+		}
+		p.addRef(arg)
+		body, end, err := p.parseUntil([]string{"else", closeTag})
+		if err != nil {
+			return nil, err
+		}
+		var els []node
+		if end == "else" {
+			if els, _, err = p.parseUntil([]string{closeTag}); err != nil {
+				return nil, err
+			}
+		}
+		return eachNode{path: arg, body: body, els: els}, nil
+	}
+	return nil, &ParseError{Pos: pos, Msg: fmt.Sprintf("block '#%s' không hỗ trợ (chỉ #if, #unless, #each)", name)}
+}
+
+func (p *parser) parseCondition(s string, pos int) (condition, error) {
+	for _, op := range condOps {
+		if i := strings.Index(s, " "+op+" "); i >= 0 {
+			left, right := strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+len(op)+2:])
+			if !pathPattern.MatchString(left) {
+				return condition{}, &ParseError{Pos: pos, Msg: fmt.Sprintf("điều kiện '%s': vế trái phải là biến", s)}
+			}
+			p.addRef(left)
+			rhs, err := p.parseOperand(right, pos)
+			if err != nil {
+				return condition{}, err
+			}
+			return condition{left: left, op: op, right: rhs}, nil
+		}
+	}
+	if !pathPattern.MatchString(s) {
+		return condition{}, &ParseError{Pos: pos, Msg: fmt.Sprintf("điều kiện '%s' không hợp lệ", s)}
+	}
+	p.addRef(s)
+	return condition{left: s}, nil
+}
+
+func (p *parser) parseOperand(s string, pos int) (operand, error) {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return operand{literal: s[1 : len(s)-1]}, nil
+	}
+	if s == "true" || s == "false" {
+		return operand{literal: s == "true"}, nil
+	}
+	if s == "null" || s == "nil" {
+		return operand{literal: nil}, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return operand{literal: f}, nil
+	}
+	if pathPattern.MatchString(s) {
+		p.addRef(s)
+		return operand{path: s}, nil
+	}
+	return operand{}, &ParseError{Pos: pos, Msg: fmt.Sprintf("giá trị so sánh '%s' không hợp lệ", s)}
+}
+
+func (p *parser) parseExpr(s string, pos int) (expr, error) {
+	parts := splitOutsideQuotes(s, '|')
+	path := strings.TrimSpace(parts[0])
+	if !pathPattern.MatchString(path) {
+		return expr{}, &ParseError{Pos: pos, Msg: fmt.Sprintf("tên biến '%s' không hợp lệ", path)}
+	}
+	p.addRef(path)
+	e := expr{path: path}
+	for _, part := range parts[1:] {
+		tokens := splitArgs(strings.TrimSpace(part))
+		if len(tokens) == 0 {
+			return expr{}, &ParseError{Pos: pos, Msg: "filter rỗng sau '|'"}
+		}
+		if _, ok := filters[tokens[0]]; !ok {
+			return expr{}, &ParseError{Pos: pos, Msg: fmt.Sprintf("filter '%s' không tồn tại", tokens[0])}
+		}
+		e.filters = append(e.filters, filterCall{name: tokens[0], args: tokens[1:]})
+	}
+	return e, nil
+}
+
+func (p *parser) addRef(path string) {
+	root := strings.SplitN(path, ".", 2)[0]
+	if root == "this" || strings.HasPrefix(root, "@") {
+		return
+	}
+	p.refs[root] = true
+}
+
+// splitOutsideQuotes tách s theo sep, bỏ qua sep nằm trong dấu nháy.
+func splitOutsideQuotes(s string, sep byte) []string {
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// splitArgs tách "name arg1 \"arg 2\"" thành token, bỏ dấu nháy.
+func splitArgs(s string) []string {
+	var out []string
+	for _, tok := range splitOutsideQuotes(s, ' ') {
+		tok = strings.TrimSpace(tok)
+		if tok == "" {
+			continue
+		}
+		if len(tok) >= 2 && (tok[0] == '"' || tok[0] == '\'') && tok[len(tok)-1] == tok[0] {
+			tok = tok[1 : len(tok)-1]
+		}
+		out = append(out, tok)
+	}
+	return out
+}
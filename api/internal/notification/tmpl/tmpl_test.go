@@ -0,0 +1,128 @@
+package tmpl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func render(t *testing.T, src string, data map[string]interface{}, format string) string {
+	t.Helper()
+	tp, err := Parse(src)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", src, err)
+	}
+	return tp.Execute(data, format)
+}
+
+func TestExecuteVariablesAndBlocks(t *testing.T) {
+	data := map[string]interface{}{
+		"customer": map[string]interface{}{"name": "An"},
+		"total":    1234567.0,
+		"vip":      true,
+		"items": []interface{}{
+			map[string]interface{}{"name": "Áo", "qty": 2},
+			map[string]interface{}{"name": "Quần", "qty": 1},
+		},
+		"empty": []interface{}{},
+	}
+	cases := []struct {
+		src, want string
+	}{
+		{"Chào {{customer.name}}!", "Chào An!"},
+		{"{{ missing }}|", "|"},
+		{"{{#if vip}}VIP{{else}}thường{{/if}}", "VIP"},
+		{"{{#unless vip}}thường{{else}}VIP{{/unless}}", "VIP"},
+		{"{{#if total > 1000000}}lớn{{/if}}", "lớn"},
+		{`{{#if customer.name == "An"}}ok{{/if}}`, "ok"},
+		{"{{#each items}}{{@number}}.{{name}} x{{qty}} ({{customer.name}}){{#unless @last}}, {{/unless}}{{/each}}", "1.Áo x2 (An), 2.Quần x1 (An)"},
+		{"{{#each empty}}x{{else}}không có{{/each}}", "không có"},
+		{"{{! ghi chú }}a", "a"},
+		{"{{total | number}}", "1.234.567"},
+		{"{{total | currency}}", "1.234.567 ₫"},
+		{"{{total | currency USD}}", "$1,234,567.00"},
+		{"{{missing | default \"Khách\"}}", "Khách"},
+		{"{{customer.name | upper}}", "AN"},
+	}
+	for _, c := range cases {
+		if got := render(t, c.src, data, FormatText); got != c.want {
+			t.Errorf("%q → %q, muốn %q", c.src, got, c.want)
+		}
+	}
+}
+
+func TestFilters(t *testing.T) {
+	cases := []struct {
+		src  string
+		data map[string]interface{}
+		want string
+	}{
+		{"{{v | number 2}}", map[string]interface{}{"v": 1234.5}, "1.234,50"},
+		{"{{v | number}}", map[string]interface{}{"v": -9876543}, "-9.876.543"},
+		{"{{v | percent}}", map[string]interface{}{"v": 2.456}, "2,5%"},
+		{"{{v | date}}", map[string]interface{}{"v": int64(1760659200)}, "17/10/2025 07:00"},
+		{`{{v | date "2006-01-02" "UTC"}}`, map[string]interface{}{"v": "2025-10-17T01:02:03Z"}, "2025-10-17"},
+		{"{{v | truncate 3}}", map[string]interface{}{"v": "Xin chào"}, "Xin…"},
+		{`{{v | join " / "}}`, map[string]interface{}{"v": []string{"a", "b"}}, "a / b"},
+		{"{{v | length}}", map[string]interface{}{"v": []int{1, 2, 3}}, "3"},
+		{"{{v | number}}", map[string]interface{}{"v": "abc"}, "abc"},
+	}
+	for _, c := range cases {
+		if got := render(t, c.src, c.data, FormatText); got != c.want {
+			t.Errorf("%q → %q, muốn %q", c.src, got, c.want)
+		}
+	}
+}
+
+func TestEscapePerFormat(t *testing.T) {
+	data := map[string]interface{}{"v": `<b>"A_1.5"</b>`}
+	cases := []struct {
+		src, format, want string
+	}{
+		{"{{v}}", FormatText, `<b>"A_1.5"</b>`},
+		{"{{v}}", FormatHTML, "&lt;b&gt;&#34;A_1.5&#34;&lt;/b&gt;"},
+		{"{{v}}", FormatMarkdown, `<b\>"A\_1\.5"</b\>`},
+		{"{{v}}", FormatJSON, `<b>\"A_1.5\"</b>`},
+		{"{{{v}}}", FormatHTML, `<b>"A_1.5"</b>`},
+	}
+	for _, c := range cases {
+		if got := render(t, c.src, data, c.format); got != c.want {
+			t.Errorf("%s %q → %q, muốn %q", c.format, c.src, got, c.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	bad := []string{
+		"{{#if vip}}không đóng",
+		"{{/if}}",
+		"{{#each items}}x{{/if}}",
+		"{{name | khongco}}",
+		"{{ten bien}}",
+		"{{#switch x}}{{/switch}}",
+		"{{name",
+		"{{else}}",
+	}
+	for _, src := range bad {
+		if _, err := Parse(src); err == nil {
+			t.Errorf("Parse(%q) phải lỗi", src)
+		}
+	}
+}
+
+func TestRefsAndMissing(t *testing.T) {
+	tp, err := Parse("{{a.b}} {{#each items}}{{name}}{{/each}} {{#if c > d}}{{/if}} {{@index}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tp.Refs(), []string{"a", "c", "d", "items", "name"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Refs = %v, muốn %v", got, want)
+	}
+	_, report := tp.ExecuteReport(map[string]interface{}{
+		"a":     map[string]interface{}{},
+		"items": []interface{}{map[string]interface{}{"name": "x"}},
+		"c":     1,
+	}, FormatText)
+	if want := []string{"@index", "a.b", "d"}; !reflect.DeepEqual(report.Missing, want) {
+		t.Errorf("Missing = %v, muốn %v", report.Missing, want)
+	}
+}
@@ -0,0 +1,211 @@
+package notifytrigger
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	deliverymodels "meta_commerce/internal/api/delivery/models"
+	notifmodels "meta_commerce/internal/api/notification/models"
+	"meta_commerce/internal/common"
+	"meta_commerce/internal/delivery"
+	"meta_commerce/internal/global"
+	"meta_commerce/internal/logger"
+	"meta_commerce/internal/notification"
+	"meta_commerce/internal/notification/tmpl"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxDigestLines số dòng tối đa trong bản tổng hợp mặc định (phần còn lại gộp thành "… và N thông báo khác").
+const maxDigestLines = 30
+
+// FlushDigests gom các digest entry đã tới hạn (flushAt <= now) thành một queue item cho mỗi
+// (rule, org, channel, recipient, flushAt). Trả số bản tổng hợp đã enqueue.
+func FlushDigests(ctx context.Context, limit int) (int, error) {
+	collection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.NotificationDigests)
+	if !exist {
+		return 0, fmt.Errorf("failed to get notification_digests collection: %v", common.ErrNotFound)
+	}
+	queue, err := delivery.NewQueue()
+	if err != nil {
+		return 0, fmt.Errorf("tạo delivery queue: %w", err)
+	}
+	template, err := notification.NewTemplate()
+	if err != nil {
+		return 0, fmt.Errorf("tạo template: %w", err)
+	}
+
+	now := time.Now().Unix()
+	opts := options.Find().SetSort(bson.D{{Key: "flushAt", Value: 1}, {Key: "createdAt", Value: 1}}).SetLimit(int64(limit))
+	cursor, err := collection.Find(ctx, bson.M{"status": notifmodels.DigestEntryStatusPending, "flushAt": bson.M{"$lte": now}}, opts)
+	if err != nil {
+		return 0, fmt.Errorf("tìm digest entry: %w", err)
+	}
+	var entries []notifmodels.NotificationDigestEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return 0, fmt.Errorf("đọc digest entry: %w", err)
+	}
+
+	log := logger.GetAppLogger()
+	flushed := 0
+	for _, group := range groupDigestEntries(entries) {
+		ids := make([]primitive.ObjectID, 0, len(group))
+		for _, e := range group {
+			ids = append(ids, e.ID)
+		}
+		itemID := primitive.NewObjectID()
+		// Claim trước khi enqueue — nhiều instance cùng chạy không gửi trùng
+		res, err := collection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}, "status": notifmodels.DigestEntryStatusPending},
+			bson.M{"$set": bson.M{"status": notifmodels.DigestEntryStatusFlushed, "digestQueueItemId": itemID, "flushedAt": now}})
+		if err != nil || res.ModifiedCount == 0 {
+			continue
+		}
+
+		item := buildDigestQueueItem(ctx, template, group, itemID)
+		if err := queue.Enqueue(ctx, []*deliverymodels.DeliveryQueueItem{item}); err != nil {
+			log.WithError(err).WithField("ruleId", group[0].RuleID.Hex()).Error("🔔 [NOTIFICATION_DIGEST] Lỗi enqueue bản tổng hợp, trả entry về pending")
+			_, _ = collection.UpdateMany(ctx, bson.M{"digestQueueItemId": itemID},
+				bson.M{"$set": bson.M{"status": notifmodels.DigestEntryStatusPending}, "$unset": bson.M{"digestQueueItemId": "", "flushedAt": ""}})
+			continue
+		}
+		flushed++
+	}
+	return flushed, nil
+}
+
+// groupDigestEntries nhóm entry theo (rule, org, channel, recipient, flushAt), giữ thứ tự thời gian.
+func groupDigestEntries(entries []notifmodels.NotificationDigestEntry) [][]notifmodels.NotificationDigestEntry {
+	index := make(map[string]int)
+	groups := [][]notifmodels.NotificationDigestEntry{}
+	for _, e := range entries {
+		key := fmt.Sprintf("%s|%s|%s|%s|%d", e.RuleID.Hex(), e.OwnerOrganizationID.Hex(), e.ChannelID.Hex(), e.Recipient, e.FlushAt)
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], e)
+	}
+	return groups
+}
+
+// buildDigestQueueItem render bản tổng hợp: template eventType notification_digest của org nếu có, không thì nội dung mặc định.
+// Priority cao nhất và MaxRetries lớn nhất trong nhóm.
+func buildDigestQueueItem(ctx context.Context, template *notification.Template, group []notifmodels.NotificationDigestEntry, itemID primitive.ObjectID) *deliverymodels.DeliveryQueueItem {
+	first := group[0]
+	subject, content := BuildDigestContent(first.ChannelType, group)
+	format := ""
+	if first.ChannelType == "email" {
+		format = tmpl.FormatHTML
+	}
+	eventTypes := digestEventTypes(group)
+	payload := map[string]interface{}{
+		"count":      len(group),
+		"eventTypes": strings.Join(eventTypes, ", "),
+		"items":      content,
+		"ruleId":     first.RuleID.Hex(),
+	}
+	if tpl, err := template.FindTemplate(ctx, notification.EventTypeNotificationDigest, first.ChannelType, first.OwnerOrganizationID); err == nil {
+		if rendered, err := template.Render(ctx, tpl, payload, first.OwnerOrganizationID, ""); err == nil {
+			subject, content, format = rendered.Subject, rendered.Content, rendered.Format
+		}
+	}
+
+	priority, maxRetries := first.Priority, first.MaxRetries
+	for _, e := range group[1:] {
+		if e.Priority > 0 && (priority == 0 || e.Priority < priority) {
+			priority = e.Priority
+		}
+		if e.MaxRetries > maxRetries {
+			maxRetries = e.MaxRetries
+		}
+	}
+	last := group[len(group)-1]
+	return &deliverymodels.DeliveryQueueItem{
+		ID:                  itemID,
+		EventType:           notification.EventTypeNotificationDigest,
+		OwnerOrganizationID: first.OwnerOrganizationID,
+		SenderID:            last.SenderID,
+		SenderConfig:        last.SenderConfig,
+		ChannelType:         first.ChannelType,
+		Recipient:           first.Recipient,
+		Subject:             subject,
+		Content:             content,
+		ContentFormat:       format,
+		Payload:             payload,
+		MaxRetries:          maxRetries,
+		Priority:            priority,
+	}
+}
+
+// BuildDigestContent nội dung tổng hợp mặc định: mỗi thông báo một dòng (dòng đầu của nội dung đã render),
+// dòng giống nhau gộp kèm số lần "(xN)". Email dùng HTML, kênh khác dùng text.
+func BuildDigestContent(channelType string, group []notifmodels.NotificationDigestEntry) (subject, content string) {
+	lines := []string{}
+	counts := make(map[string]int)
+	for _, e := range group {
+		line := digestLine(e)
+		if counts[line] == 0 {
+			lines = append(lines, line)
+		}
+		counts[line]++
+	}
+
+	subject = fmt.Sprintf("Tổng hợp %d thông báo", len(group))
+	rendered := make([]string, 0, maxDigestLines+1)
+	for i, line := range lines {
+		if i == maxDigestLines {
+			rest := 0
+			for _, l := range lines[i:] {
+				rest += counts[l]
+			}
+			rendered = append(rendered, fmt.Sprintf("… và %d thông báo khác", rest))
+			break
+		}
+		if counts[line] > 1 {
+			line = fmt.Sprintf("%s (x%d)", line, counts[line])
+		}
+		rendered = append(rendered, line)
+	}
+
+	if channelType == "email" {
+		return subject, "<p><b>" + subject + "</b></p><ul><li>" + strings.Join(rendered, "</li><li>") + "</li></ul>"
+	}
+	return subject, "📬 " + subject + "\n\n• " + strings.Join(rendered, "\n• ")
+}
+
+func digestLine(e notifmodels.NotificationDigestEntry) string {
+	if s := strings.TrimSpace(e.Subject); s != "" {
+		return s
+	}
+	content := strings.TrimSpace(e.Content)
+	if i := strings.IndexByte(content, '\n'); i >= 0 {
+		content = strings.TrimSpace(content[:i])
+	}
+	if r := []rune(content); len(r) > 200 {
+		content = string(r[:200]) + "…"
+	}
+	if content == "" {
+		return e.EventType
+	}
+	return content
+}
+
+func digestEventTypes(group []notifmodels.NotificationDigestEntry) []string {
+	seen := make(map[string]bool)
+	types := []string{}
+	for _, e := range group {
+		if !seen[e.EventType] {
+			seen[e.EventType] = true
+			types = append(types, e.EventType)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
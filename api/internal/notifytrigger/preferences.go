@@ -0,0 +1,225 @@
+package notifytrigger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	authsvc "meta_commerce/internal/api/auth/service"
+	deliverymodels "meta_commerce/internal/api/delivery/models"
+	notifmodels "meta_commerce/internal/api/notification/models"
+	notifsvc "meta_commerce/internal/api/notification/service"
+	"meta_commerce/internal/delivery"
+	"meta_commerce/internal/logger"
+	"meta_commerce/internal/notification"
+	"meta_commerce/internal/notification/tmpl"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// preferenceFanOut gửi event tới các user đã đăng ký trong org theo NotificationPreference.
+// in_app → lưu hộp thư + đẩy WebSocket ngay; email / telegram → qua throttler (giờ yên lặng / chống trùng / digest
+// theo routing rule của org cho event, như item routing) rồi trả queue item để enqueue cùng item của routing.
+// skip: recipient (channelType|recipient) đã có trong item routing (trước throttle) → không gửi trùng.
+// Trả về: queue item email/telegram cần enqueue ngay, số thông báo in-app đã lưu.
+func preferenceFanOut(ctx context.Context, template *notification.Template, senderService *notifsvc.NotificationSenderService,
+	throttler *Throttler, routes []notification.Route, eventType, domain, severity string, payload map[string]interface{},
+	organizationID primitive.ObjectID, baseURL string, skip map[string]bool) ([]*deliverymodels.DeliveryQueueItem, int, error) {
+
+	prefService, err := notifsvc.NewNotificationPreferenceService()
+	if err != nil {
+		return nil, 0, fmt.Errorf("tạo preference service: %w", err)
+	}
+	candidates, err := prefService.FindCandidates(ctx, organizationID, eventType, domain)
+	if err != nil {
+		return nil, 0, fmt.Errorf("tìm preference: %w", err)
+	}
+	subscribers := notification.ResolvePreferences(candidates, eventType, domain)
+	if len(subscribers) == 0 {
+		return nil, 0, nil
+	}
+
+	log := logger.GetAppLogger().WithFields(map[string]interface{}{
+		"eventType":      eventType,
+		"organizationId": organizationID.Hex(),
+	})
+
+	// Render một lần cho mỗi kênh; kênh không có template → nil (bỏ qua kênh đó)
+	renderedByChannel := map[string]*renderedForUser{}
+	render := func(channelType string) *renderedForUser {
+		if r, ok := renderedByChannel[channelType]; ok {
+			return r
+		}
+		r := renderForPreference(ctx, template, channelType, eventType, payload, organizationID, baseURL)
+		if r == nil {
+			log.WithField("channelType", channelType).Debug("🔔 [NOTIFICATION] Không có template cho kênh preference, bỏ qua")
+		}
+		renderedByChannel[channelType] = r
+		return r
+	}
+
+	var inboxService *notifsvc.NotificationInboxService
+	var userService *authsvc.UserService
+	queueItems := make([]*deliverymodels.DeliveryQueueItem, 0)
+	inAppCount := 0
+	now := time.Now().Unix()
+	priority := notification.GetPriorityFromSeverity(severity)
+	maxRetries := notification.GetMaxRetriesFromSeverity(severity)
+
+	for _, pref := range subscribers {
+		for _, channelType := range pref.Channels {
+			rendered := render(channelType)
+			if rendered == nil {
+				continue
+			}
+
+			if channelType == notifmodels.PreferenceChannelInApp {
+				if inboxService == nil {
+					if inboxService, err = notifsvc.NewNotificationInboxService(); err != nil {
+						return queueItems, inAppCount, fmt.Errorf("tạo inbox service: %w", err)
+					}
+				}
+				if _, err := inboxService.Deliver(ctx, notifmodels.NotificationInboxItem{
+					UserID:              pref.UserID,
+					OwnerOrganizationID: organizationID,
+					EventType:           eventType,
+					Domain:              domain,
+					Severity:            severity,
+					Subject:             rendered.Subject,
+					Content:             rendered.Content,
+					CTAs:                rendered.inboxCTAs,
+				}); err != nil {
+					log.WithError(err).WithField("userId", pref.UserID.Hex()).Warn("🔔 [NOTIFICATION] Lưu thông báo in-app thất bại")
+					continue
+				}
+				inAppCount++
+				continue
+			}
+
+			recipient := pref.TelegramChatID
+			if channelType == notifmodels.PreferenceChannelEmail {
+				if userService == nil {
+					if userService, err = authsvc.NewUserService(); err != nil {
+						return queueItems, inAppCount, fmt.Errorf("tạo user service: %w", err)
+					}
+				}
+				user, err := userService.FindOneById(ctx, pref.UserID)
+				if err != nil {
+					log.WithError(err).WithField("userId", pref.UserID.Hex()).Warn("🔔 [NOTIFICATION] Không tìm thấy user của preference")
+					continue
+				}
+				recipient = user.Email
+			}
+			if recipient == "" || skip[channelType+"|"+recipient] {
+				continue
+			}
+			skip[channelType+"|"+recipient] = true
+
+			sender, senderID, err := findSenderForChannel(ctx, senderService, &notifmodels.NotificationChannel{ChannelType: channelType}, organizationID)
+			if err != nil {
+				log.WithError(err).WithField("channelType", channelType).Warn("🔔 [NOTIFICATION] Không tìm thấy sender cho preference, bỏ qua")
+				continue
+			}
+			var encryptedSenderConfig string
+			if j, err := json.Marshal(sender); err == nil {
+				encryptedSenderConfig, _ = delivery.EncryptSenderConfig(j)
+			}
+
+			queueItems = append(queueItems, &deliverymodels.DeliveryQueueItem{
+				ID:                  primitive.NewObjectID(),
+				EventType:           eventType,
+				OwnerOrganizationID: organizationID,
+				SenderID:            senderID,
+				SenderConfig:        encryptedSenderConfig,
+				ChannelType:         channelType,
+				Recipient:           recipient,
+				Subject:             rendered.Subject,
+				Content:             rendered.Content,
+				ContentFormat:       rendered.Format,
+				CTAs:                rendered.ctaJSONs,
+				Payload:             payload,
+				Status:              "pending",
+				MaxRetries:          maxRetries,
+				Priority:            priority,
+				CreatedAt:           now,
+				UpdatedAt:           now,
+			})
+		}
+	}
+	return throttler.Apply(ctx, preferenceRoute(routes, organizationID), severity, queueItems), inAppCount, nil
+}
+
+// preferenceRoute route dùng để throttle item preference: rule routing của chính org cho event (ưu tiên rule có cấu hình
+// chống spam). ChannelID rỗng — dedup mark / digest của preference tách khỏi channel routing. Org không có rule → không throttle.
+func preferenceRoute(routes []notification.Route, organizationID primitive.ObjectID) notification.Route {
+	out := notification.Route{OrganizationID: organizationID}
+	for _, route := range routes {
+		if route.OrganizationID != organizationID || route.Rule == nil {
+			continue
+		}
+		rule := route.Rule
+		if rule.QuietHours != nil || rule.DigestMinutes > 0 || rule.DedupWindowMinutes > 0 {
+			out.Rule = rule
+			return out
+		}
+		if out.Rule == nil {
+			out.Rule = rule
+		}
+	}
+	return out
+}
+
+// renderedForUser nội dung đã render cho một kênh preference.
+type renderedForUser struct {
+	Subject   string
+	Content   string
+	Format    string
+	ctaJSONs  []string
+	inboxCTAs []notifmodels.InboxCTA
+}
+
+// renderForPreference render template của kênh. in_app không có template riêng → dùng template telegram dạng text
+// (hộp thư hiển thị text thuần, không escape MarkdownV2).
+func renderForPreference(ctx context.Context, template *notification.Template, channelType, eventType string,
+	payload map[string]interface{}, organizationID primitive.ObjectID, baseURL string) *renderedForUser {
+
+	tpl, err := template.FindTemplate(ctx, eventType, channelType, organizationID)
+	if err != nil && channelType == notifmodels.PreferenceChannelInApp {
+		if tpl, err = template.FindTemplate(ctx, eventType, notifmodels.PreferenceChannelTelegram, organizationID); err == nil {
+			fallback := *tpl
+			fallback.Format = tmpl.FormatText
+			tpl = &fallback
+		}
+	}
+	if err != nil {
+		return nil
+	}
+
+	rendered, err := template.Render(ctx, tpl, payload, organizationID, baseURL)
+	if err != nil {
+		logger.GetAppLogger().WithError(err).WithFields(map[string]interface{}{
+			"eventType":   eventType,
+			"channelType": channelType,
+		}).Error("🔔 [NOTIFICATION] Lỗi render template cho preference")
+		return nil
+	}
+
+	out := &renderedForUser{Subject: rendered.Subject, Content: rendered.Content, Format: rendered.Format}
+	for _, cta := range rendered.CTAs {
+		if j, err := json.Marshal(cta); err == nil {
+			out.ctaJSONs = append(out.ctaJSONs, string(j))
+		}
+		out.inboxCTAs = append(out.inboxCTAs, notifmodels.InboxCTA{Label: cta.Label, Action: cta.Action, Style: cta.Style})
+	}
+	return out
+}
+
+// queueRecipientKeys tập channelType|recipient của các item routing (để preference không gửi trùng).
+func queueRecipientKeys(items []*deliverymodels.DeliveryQueueItem) map[string]bool {
+	keys := make(map[string]bool, len(items))
+	for _, item := range items {
+		keys[item.ChannelType+"|"+item.Recipient] = true
+	}
+	return keys
+}
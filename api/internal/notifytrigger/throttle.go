@@ -0,0 +1,250 @@
+package notifytrigger
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	authsvc "meta_commerce/internal/api/auth/service"
+	deliverymodels "meta_commerce/internal/api/delivery/models"
+	deliverysvc "meta_commerce/internal/api/delivery/service"
+	notifmodels "meta_commerce/internal/api/notification/models"
+	"meta_commerce/internal/common"
+	"meta_commerce/internal/global"
+	"meta_commerce/internal/logger"
+	"meta_commerce/internal/notification"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Throttler áp dụng giờ yên lặng / chống trùng / digest của routing rule lên queue items của một route.
+// Item bị chặn hoặc gom không vào queue nhưng vẫn ghi delivery history (status suppressed / digested).
+type Throttler struct {
+	dedupCollection   *mongo.Collection
+	digestCollection  *mongo.Collection
+	historyService    *deliverysvc.DeliveryHistoryService
+	configItemService *authsvc.OrganizationConfigItemService
+
+	mu        sync.Mutex
+	locations map[string]*time.Location
+}
+
+// NewThrottler tạo mới Throttler
+func NewThrottler() (*Throttler, error) {
+	dedupCollection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.NotificationDedupMarks)
+	if !exist {
+		return nil, fmt.Errorf("failed to get notification_dedup_marks collection: %v", common.ErrNotFound)
+	}
+	digestCollection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.NotificationDigests)
+	if !exist {
+		return nil, fmt.Errorf("failed to get notification_digests collection: %v", common.ErrNotFound)
+	}
+	historyService, err := deliverysvc.NewDeliveryHistoryService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create delivery history service: %w", err)
+	}
+	configItemService, err := authsvc.NewOrganizationConfigItemService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create organization config item service: %w", err)
+	}
+	return &Throttler{
+		dedupCollection:   dedupCollection,
+		digestCollection:  digestCollection,
+		historyService:    historyService,
+		configItemService: configItemService,
+		locations:         make(map[string]*time.Location),
+	}, nil
+}
+
+var (
+	defaultThrottlerMu sync.Mutex
+	defaultThrottler   *Throttler
+)
+
+// SetDefaultThrottler gán Throttler dùng chung (tạo một lần lúc khởi động server) cho TriggerProgrammatic và trigger handler.
+func SetDefaultThrottler(t *Throttler) {
+	defaultThrottlerMu.Lock()
+	defer defaultThrottlerMu.Unlock()
+	defaultThrottler = t
+}
+
+// DefaultThrottler trả Throttler dùng chung — cache location theo org sống cùng process thay vì theo từng lần trigger.
+// Chưa được gán thì tạo ở lần gọi đầu; tạo lỗi (collection chưa đăng ký) → lần gọi sau thử lại.
+func DefaultThrottler() (*Throttler, error) {
+	defaultThrottlerMu.Lock()
+	defer defaultThrottlerMu.Unlock()
+	if defaultThrottler != nil {
+		return defaultThrottler, nil
+	}
+	t, err := NewThrottler()
+	if err != nil {
+		return nil, err
+	}
+	defaultThrottler = t
+	return t, nil
+}
+
+// Apply trả các queue item cần enqueue ngay cho route. Rule không cấu hình chống spam → trả nguyên items.
+// Lỗi DB khi kiểm tra dedup / ghi digest không được làm mất thông báo → gửi bình thường. Throttler nil → không chặn.
+func (t *Throttler) Apply(ctx context.Context, route notification.Route, severity string, items []*deliverymodels.DeliveryQueueItem) []*deliverymodels.DeliveryQueueItem {
+	rule := route.Rule
+	if t == nil || rule == nil || len(items) == 0 || (rule.QuietHours == nil && rule.DigestMinutes <= 0 && rule.DedupWindowMinutes <= 0) {
+		return items
+	}
+	log := logger.GetAppLogger()
+	now := time.Now()
+	loc := t.location(ctx, rule, route.OrganizationID)
+	eventType, payload := items[0].EventType, items[0].Payload
+
+	action, reason := notification.DecideThrottle(rule, severity, now, loc)
+	if action != notification.ThrottleSuppress && rule.DedupWindowMinutes > 0 {
+		if key := notification.RenderDedupKey(rule.DedupKey, eventType, payload); key != "" {
+			fresh, err := t.claimDedup(ctx, rule, route, eventType, key, now)
+			if err != nil {
+				log.WithError(err).WithField("ruleId", rule.ID.Hex()).Warn("🔔 [NOTIFICATION] Không kiểm tra được dedup key, gửi bình thường")
+			} else if !fresh {
+				action, reason = notification.ThrottleSuppress, notification.ThrottleReasonDedup
+			}
+		}
+	}
+
+	switch action {
+	case notification.ThrottleSuppress:
+		for _, item := range items {
+			t.recordHistory(ctx, item, rule, severity, notification.ThrottleSuppress, reason)
+		}
+		log.WithFields(map[string]interface{}{
+			"ruleId": rule.ID.Hex(), "eventType": eventType, "reason": reason, "items": len(items),
+		}).Info("🔔 [NOTIFICATION] Đã chặn thông báo theo routing rule")
+		return nil
+	case notification.ThrottleDigest:
+		flushAt := notification.DigestFlushAt(rule, severity, now, loc)
+		if err := t.bufferDigest(ctx, route, severity, reason, items, flushAt); err != nil {
+			log.WithError(err).WithField("ruleId", rule.ID.Hex()).Warn("🔔 [NOTIFICATION] Không ghi được digest buffer, gửi ngay")
+			return items
+		}
+		return nil
+	}
+	return items
+}
+
+// claimDedup ghi dedup key; false nếu key còn trong cửa sổ chống trùng.
+// Mark đã hết hạn nhưng TTL chưa kịp xóa → gia hạn lại và coi như key mới.
+func (t *Throttler) claimDedup(ctx context.Context, rule *notifmodels.NotificationRoutingRule, route notification.Route, eventType, key string, now time.Time) (bool, error) {
+	expiresAt := now.Add(time.Duration(rule.DedupWindowMinutes) * time.Minute)
+	mark := notifmodels.NotificationDedupMark{
+		RuleID: rule.ID, OwnerOrganizationID: route.OrganizationID, ChannelID: route.ChannelID,
+		Key: key, EventType: eventType, ExpiresAt: expiresAt, CreatedAt: now.Unix(),
+	}
+	if _, err := t.dedupCollection.InsertOne(ctx, mark); err == nil {
+		return true, nil
+	} else if !mongo.IsDuplicateKeyError(err) {
+		return false, err
+	}
+	res, err := t.dedupCollection.UpdateOne(ctx, bson.M{
+		"ruleId": rule.ID, "ownerOrganizationId": route.OrganizationID, "channelId": route.ChannelID,
+		"key": key, "expiresAt": bson.M{"$lte": now},
+	}, bson.M{"$set": bson.M{"expiresAt": expiresAt, "eventType": eventType, "createdAt": now.Unix()}})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// bufferDigest ghi history digested + entry chờ gom cho từng item.
+func (t *Throttler) bufferDigest(ctx context.Context, route notification.Route, severity, reason string, items []*deliverymodels.DeliveryQueueItem, flushAt time.Time) error {
+	now := time.Now().Unix()
+	entries := make([]interface{}, 0, len(items))
+	historyIDs := make([]primitive.ObjectID, 0, len(items))
+	for _, item := range items {
+		historyID := t.recordHistory(ctx, item, route.Rule, severity, notification.ThrottleDigest, reason)
+		historyIDs = append(historyIDs, historyID)
+		entries = append(entries, notifmodels.NotificationDigestEntry{
+			ID:                  primitive.NewObjectID(),
+			RuleID:              route.Rule.ID,
+			OwnerOrganizationID: route.OrganizationID,
+			ChannelID:           route.ChannelID,
+			ChannelType:         item.ChannelType,
+			Recipient:           item.Recipient,
+			SenderID:            item.SenderID,
+			SenderConfig:        item.SenderConfig,
+			EventType:           item.EventType,
+			Severity:            severity,
+			Subject:             item.Subject,
+			Content:             item.Content,
+			Payload:             item.Payload,
+			Priority:            item.Priority,
+			MaxRetries:          item.MaxRetries,
+			HistoryID:           historyID,
+			Status:              notifmodels.DigestEntryStatusPending,
+			FlushAt:             flushAt.Unix(),
+			CreatedAt:           now,
+		})
+	}
+	if _, err := t.digestCollection.InsertMany(ctx, entries); err != nil {
+		// Không gom được → item sẽ gửi ngay, bỏ history digested để không đếm hai lần
+		_, _ = t.historyService.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": historyIDs}})
+		return err
+	}
+	return nil
+}
+
+// recordHistory ghi delivery history cho item không vào queue (suppressed / digested).
+func (t *Throttler) recordHistory(ctx context.Context, item *deliverymodels.DeliveryQueueItem, rule *notifmodels.NotificationRoutingRule, severity, status, reason string) primitive.ObjectID {
+	ruleID := rule.ID
+	history := deliverymodels.DeliveryHistory{
+		ID:                  primitive.NewObjectID(),
+		QueueItemID:         item.ID,
+		EventType:           item.EventType,
+		OwnerOrganizationID: item.OwnerOrganizationID,
+		Domain:              notification.GetDomainFromEventType(item.EventType),
+		Severity:            severity,
+		ChannelType:         item.ChannelType,
+		Recipient:           item.Recipient,
+		Status:              status,
+		Content:             item.Content,
+		RoutingRuleID:       &ruleID,
+		ThrottleReason:      reason,
+		CreatedAt:           time.Now().Unix(),
+	}
+	if _, err := t.historyService.InsertOne(ctx, history); err != nil {
+		logger.GetAppLogger().WithError(err).WithField("eventType", item.EventType).Warn("🔔 [NOTIFICATION] Không ghi được delivery history")
+	}
+	return history.ID
+}
+
+// location múi giờ áp dụng giờ yên lặng / digest: rule → config "timezone" của tổ chức nhận → mặc định.
+func (t *Throttler) location(ctx context.Context, rule *notifmodels.NotificationRoutingRule, orgID primitive.ObjectID) *time.Location {
+	name := ""
+	if rule.QuietHours != nil {
+		name = strings.TrimSpace(rule.QuietHours.Timezone)
+	}
+	if name == "" {
+		if item, err := t.configItemService.GetByOwnerOrganizationIDAndKey(ctx, orgID, notification.ConfigKeyTimezone); err == nil && item != nil {
+			name, _ = item.Value.(string)
+			name = strings.TrimSpace(name)
+		}
+	}
+	if name == "" {
+		name = notification.DefaultTimezone
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if loc, ok := t.locations[name]; ok {
+		return loc
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		logger.GetAppLogger().WithError(err).WithField("timezone", name).Warn("🔔 [NOTIFICATION] Timezone không hợp lệ, dùng mặc định")
+		if loc, err = time.LoadLocation(notification.DefaultTimezone); err != nil {
+			loc = time.UTC
+		}
+	}
+	t.locations[name] = loc
+	return loc
+}
@@ -30,7 +30,10 @@ import (
 //   - organizationID: org nhận thông báo (ví dụ: System Organization)
 //   - baseURL: base URL cho CTA (có thể rỗng nếu không dùng CTA)
 //
-// Trả về: số item đã enqueue, error
+// Ngoài routing rule, fan-out tới user đã đăng ký event (NotificationPreference): in_app lưu hộp thư + đẩy WebSocket,
+// email / telegram qua throttler theo routing rule của org rồi enqueue cùng item routing (bỏ recipient đã có từ routing).
+//
+// Trả về: số item đã enqueue + số thông báo in-app đã lưu, error
 func TriggerProgrammatic(ctx context.Context, eventType string, payload map[string]interface{}, organizationID primitive.ObjectID, baseURL string) (int, error) {
 	if payload == nil {
 		payload = make(map[string]interface{})
@@ -63,6 +66,11 @@ func TriggerProgrammatic(ctx context.Context, eventType string, payload map[stri
 		return 0, fmt.Errorf("tạo sender service: %w", err)
 	}
 
+	throttler, err := DefaultThrottler()
+	if err != nil {
+		logger.GetAppLogger().WithError(err).Warn("🔔 [NOTIFICATION] Không tạo được throttler, gửi không áp dụng quiet hours / dedup / digest")
+	}
+
 	domain := notification.GetDomainFromEventType(eventType)
 	severity := notification.GetSeverityFromEventType(eventType)
 	orgIDPtr := &organizationID
@@ -72,11 +80,12 @@ func TriggerProgrammatic(ctx context.Context, eventType string, payload map[stri
 		return 0, fmt.Errorf("tìm routes: %w", err)
 	}
 	if len(routes) == 0 {
-		logger.GetAppLogger().WithField("eventType", eventType).Warn("🔔 [NOTIFICATION] Không có routes cho eventType")
-		return 0, nil
+		// Vẫn tiếp tục: user có thể đã đăng ký event qua preference
+		logger.GetAppLogger().WithField("eventType", eventType).Debug("🔔 [NOTIFICATION] Không có routes cho eventType")
 	}
 
 	queueItems := make([]*deliverymodels.DeliveryQueueItem, 0)
+	routedRecipients := make(map[string]bool) // channelType|recipient của item routing, kể cả item bị throttle chặn / gom
 	now := time.Now().Unix()
 
 	for _, route := range routes {
@@ -123,10 +132,12 @@ func TriggerProgrammatic(ctx context.Context, eventType string, payload map[stri
 			recipients = channel.Recipients
 		case "telegram":
 			recipients = channel.ChatIDs
-		case "webhook":
+		case "webhook", "slack", "discord":
 			if channel.WebhookURL != "" {
 				recipients = []string{channel.WebhookURL}
 			}
+		case "messenger":
+			recipients = channel.PSIDs
 		default:
 			continue
 		}
@@ -153,8 +164,9 @@ func TriggerProgrammatic(ctx context.Context, eventType string, payload map[stri
 		priority := notification.GetPriorityFromSeverity(severity)
 		maxRetries := notification.GetMaxRetriesFromSeverity(severity)
 
+		routeItems := make([]*deliverymodels.DeliveryQueueItem, 0, len(recipients))
 		for _, recipient := range recipients {
-			queueItems = append(queueItems, &deliverymodels.DeliveryQueueItem{
+			routeItems = append(routeItems, &deliverymodels.DeliveryQueueItem{
 				ID:                  primitive.NewObjectID(),
 				EventType:           eventType,
 				OwnerOrganizationID: route.OrganizationID,
@@ -164,6 +176,7 @@ func TriggerProgrammatic(ctx context.Context, eventType string, payload map[stri
 				Recipient:           recipient,
 				Subject:             rendered.Subject,
 				Content:             rendered.Content,
+				ContentFormat:       rendered.Format,
 				CTAs:                ctaJSONs,
 				Payload:             payload,
 				Status:              "pending",
@@ -174,16 +187,27 @@ func TriggerProgrammatic(ctx context.Context, eventType string, payload map[stri
 				UpdatedAt:           now,
 			})
 		}
+		for key := range queueRecipientKeys(routeItems) {
+			routedRecipients[key] = true
+		}
+		queueItems = append(queueItems, throttler.Apply(ctx, route, severity, routeItems)...)
+	}
+
+	// Fan-out theo preference của user trong org (in_app lưu ngay, email/telegram enqueue chung)
+	prefItems, inAppCount, err := preferenceFanOut(ctx, template, senderService, throttler, routes, eventType, domain, severity, payload, organizationID, baseURL, routedRecipients)
+	if err != nil {
+		logger.GetAppLogger().WithError(err).WithField("eventType", eventType).Warn("🔔 [NOTIFICATION] Fan-out preference thất bại")
 	}
+	queueItems = append(queueItems, prefItems...)
 
 	if len(queueItems) == 0 {
-		return 0, nil
+		return inAppCount, nil
 	}
 
 	if err := queue.Enqueue(ctx, queueItems); err != nil {
-		return 0, fmt.Errorf("enqueue: %w", err)
+		return inAppCount, fmt.Errorf("enqueue: %w", err)
 	}
-	return len(queueItems), nil
+	return len(queueItems) + inAppCount, nil
 }
 
 // findSenderForChannel tìm sender cho channel.
@@ -0,0 +1,78 @@
+// Package worker — NotificationDigestWorker gom digest entry của routing rule tới hạn (flushAt <= now)
+// thành một bản tổng hợp cho mỗi (rule, org, channel, recipient) và đưa vào hàng đợi delivery.
+// Tách package riêng để tránh import cycle (notifytrigger -> delivery -> worker).
+package worker
+
+import (
+	"context"
+	"time"
+
+	"meta_commerce/internal/logger"
+	"meta_commerce/internal/notifytrigger"
+	coreworker "meta_commerce/internal/worker"
+)
+
+// NotificationDigestWorker flush định kỳ buffer digest của routing rule.
+type NotificationDigestWorker struct {
+	interval  time.Duration
+	batchSize int
+}
+
+// NewNotificationDigestWorker tạo mới NotificationDigestWorker.
+func NewNotificationDigestWorker(interval time.Duration, batchSize int) *NotificationDigestWorker {
+	if interval < 15*time.Second {
+		interval = 1 * time.Minute
+	}
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	return &NotificationDigestWorker{interval: interval, batchSize: batchSize}
+}
+
+// Start chạy worker trong vòng lặp. Đọc schedule mỗi vòng (hỗ trợ thay đổi qua API).
+func (w *NotificationDigestWorker) Start(ctx context.Context) {
+	log := logger.GetAppLogger()
+
+	log.WithFields(map[string]interface{}{
+		"interval":  w.interval.String(),
+		"batchSize": w.batchSize,
+	}).Info("📬 [NOTIFICATION_DIGEST] Starting Notification Digest Worker...")
+
+	for {
+		interval, batchSize := coreworker.GetEffectiveWorkerSchedule(coreworker.WorkerNotificationDigest, w.interval, w.batchSize)
+
+		select {
+		case <-ctx.Done():
+			log.Info("📬 [NOTIFICATION_DIGEST] Notification Digest Worker stopped")
+			return
+		case <-time.After(interval):
+		}
+
+		if !coreworker.IsWorkerActive(coreworker.WorkerNotificationDigest) {
+			continue
+		}
+		p := coreworker.GetPriority(coreworker.WorkerNotificationDigest, coreworker.PriorityNormal)
+		if coreworker.ShouldThrottle(p) {
+			continue
+		}
+		w.process(ctx, coreworker.GetEffectiveBatchSize(batchSize, p))
+	}
+}
+
+func (w *NotificationDigestWorker) process(ctx context.Context, batchSize int) {
+	log := logger.GetAppLogger()
+	defer func() {
+		if r := recover(); r != nil {
+			log.WithFields(map[string]interface{}{"panic": r}).Error("📬 [NOTIFICATION_DIGEST] Panic khi xử lý, sẽ tiếp tục lần sau")
+		}
+	}()
+
+	flushed, err := notifytrigger.FlushDigests(ctx, batchSize)
+	if err != nil {
+		log.WithError(err).Error("📬 [NOTIFICATION_DIGEST] Lỗi flush digest")
+		return
+	}
+	if flushed > 0 {
+		log.WithField("digests", flushed).Info("📬 [NOTIFICATION_DIGEST] Đã enqueue bản tổng hợp")
+	}
+}
@@ -6,9 +6,12 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
 	"meta_commerce/internal/cta"
+	"meta_commerce/internal/delivery"
 	"meta_commerce/internal/logger"
 	"meta_commerce/internal/notifytrigger"
 	"meta_commerce/internal/worker"
@@ -16,6 +19,7 @@ import (
 
 const (
 	eventTypeSystemResourceOverload = "system_resource_overload"
+	eventTypeSystemDeliveryDLQ      = "system_delivery_dlq_backlog"
 )
 
 // Register đăng ký callback gửi cảnh báo khi tài nguyên quá tải.
@@ -23,6 +27,7 @@ const (
 // Cảnh báo gửi qua hệ thống thông báo (routing rules → channels → delivery queue).
 func Register() {
 	worker.RegisterOverloadAlertCallback(sendOverloadAlert)
+	delivery.RegisterDeadLetterAlertCallback(sendDeadLetterAlert)
 }
 
 // sendOverloadAlert gửi thông báo qua hệ thống thông báo khi CPU/RAM/disk quá tải.
@@ -77,3 +82,46 @@ func sendOverloadAlert(metrics worker.ResourceMetrics, state string) {
 		"queued":      queued,
 	}).Info("⚙️ [SYSTEM_ALERT] Đã gửi cảnh báo tài nguyên quá tải qua hệ thống thông báo")
 }
+
+// sendDeadLetterAlert gửi thông báo khi số item dead-letter của Delivery vượt ngưỡng (system_delivery_dlq_backlog).
+func sendDeadLetterAlert(depth, threshold int64, byChannel map[string]int64) {
+	ctx := context.Background()
+	systemOrgID, err := cta.GetSystemOrganizationID(ctx)
+	if err != nil {
+		logger.GetAppLogger().WithError(err).Error("⚙️ [SYSTEM_ALERT] Không lấy được System Organization ID")
+		return
+	}
+
+	baseURL := os.Getenv("BASE_URL")
+	if baseURL == "" {
+		baseURL = "https://localhost"
+	}
+
+	channels := make([]string, 0, len(byChannel))
+	for ch, n := range byChannel {
+		channels = append(channels, fmt.Sprintf("%s=%d", ch, n))
+	}
+	sort.Strings(channels)
+
+	payload := map[string]interface{}{
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+		"depth":     fmt.Sprintf("%d", depth),
+		"threshold": fmt.Sprintf("%d", threshold),
+		"byChannel": strings.Join(channels, ", "),
+	}
+
+	queued, err := notifytrigger.TriggerProgrammatic(ctx, eventTypeSystemDeliveryDLQ, payload, systemOrgID, baseURL)
+	if err != nil {
+		logger.GetAppLogger().WithError(err).WithFields(map[string]interface{}{
+			"depth":     depth,
+			"threshold": threshold,
+		}).Error("⚙️ [SYSTEM_ALERT] Không gửi được cảnh báo dead-letter qua hệ thống thông báo")
+		return
+	}
+	if queued == 0 {
+		logger.GetAppLogger().WithFields(map[string]interface{}{
+			"eventType": eventTypeSystemDeliveryDLQ,
+			"depth":     depth,
+		}).Warn("⚙️ [SYSTEM_ALERT] Không có routing rule/channel nào cho system_delivery_dlq_backlog")
+	}
+}
@@ -41,6 +41,13 @@ func (c *Cache) Get(key string) (interface{}, bool) {
 	return value, exists
 }
 
+// Delete xóa một key khỏi cache
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.items, key)
+}
+
 // cleanupLoop dọn dẹp cache định kỳ
 func (c *Cache) cleanupLoop() {
 	ticker := time.NewTicker(c.cleanup)
@@ -1,6 +1,9 @@
 package utility
 
 import (
+	"errors"
+	"fmt"
+
 	authmodels "meta_commerce/internal/api/auth/models"
 
 	"github.com/dgrijalva/jwt-go"
@@ -51,3 +54,30 @@ func CreateToken(secretKey string, id string, time string, randomNum string) (ma
 	m["token"] = tokenString // thiết lập dữ liệu phản hồi
 	return m, nil
 }
+
+// SignToken ký claims bằng HS256 (token theo phiên: access / refresh).
+func SignToken(secretKey string, claims authmodels.JwtToken) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secretKey))
+}
+
+// ParseToken kiểm tra chữ ký HS256 và hạn (exp, nếu có) hoàn toàn trong bộ nhớ, trả về claims.
+// Token cũ không có exp vẫn hợp lệ ở đây — người gọi phân biệt bằng SessionID rỗng.
+func ParseToken(secretKey string, tokenString string) (*authmodels.JwtToken, error) {
+	claims := &authmodels.JwtToken{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return []byte(secretKey), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// IsTokenExpired lỗi từ ParseToken là do token đã hết hạn (exp).
+func IsTokenExpired(err error) bool {
+	var ve *jwt.ValidationError
+	return errors.As(err, &ve) && ve.Errors&jwt.ValidationErrorExpired != 0
+}
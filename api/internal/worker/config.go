@@ -18,6 +18,7 @@ const (
 	WorkerReportRedisTouchFlush    = "report_redis_touch_flush"
 	WorkerDelivery                 = "notification_delivery_processor"
 	WorkerDeliveryCleanup          = "notification_delivery_cleanup"
+	WorkerNotificationDigest       = "notification_digest_flush"
 	WorkerCommandCleanup           = "notification_command_cleanup"
 	WorkerAgentCommandCleanup      = "notification_agent_command_cleanup"
 	WorkerAgentActivityCleanup     = "notification_agent_activity_cleanup"
@@ -43,6 +44,7 @@ const (
 	WorkerLearningEvaluation       = "learning_evaluation"
 	WorkerLearningInsightAggregate = "learning_insight_aggregate"
 	WorkerIdentityBackfill         = "identity_backfill"
+	WorkerApprovalExpiry           = "approval_expiry"
 )
 
 // WorkerMetadata mô tả worker (module, domain, mô tả chức năng).
@@ -61,6 +63,7 @@ var workerMetadataMap = map[string]WorkerMetadata{
 	WorkerReportRedisTouchFlush:    {Module: "report", Domain: "system", Description: "Một worker, ba nhịp flush Redis→MarkDirty (ads/order/customer); env REPORT_REDIS_TOUCH_FLUSH_INTERVAL_*_SEC + POLL_TICK"},
 	WorkerDelivery:                 {Module: "notification", Domain: "notification", Description: "Xử lý hàng đợi gửi thông báo (email, Telegram, SMS...)"},
 	WorkerDeliveryCleanup:          {Module: "notification", Domain: "notification", Description: "Dọn các item bị kẹt trong hàng đợi delivery"},
+	WorkerNotificationDigest:       {Module: "notification", Domain: "notification", Description: "Gom thông báo digest của routing rule tới hạn thành một bản tổng hợp và đưa vào hàng đợi delivery"},
 	WorkerCommandCleanup:           {Module: "notification", Domain: "system", Description: "Dọn command cũ hết hạn"},
 	WorkerAgentCommandCleanup:      {Module: "notification", Domain: "system", Description: "Dọn agent command cũ hết hạn"},
 	WorkerAgentActivityCleanup:     {Module: "notification", Domain: "system", Description: "Dọn agent activity log cũ"},
@@ -86,6 +89,7 @@ var workerMetadataMap = map[string]WorkerMetadata{
 	WorkerLearningEvaluation:       {Module: "learning", Domain: "learning", Description: "Batch tính evaluation (outcome_class, error_attribution) cho learning_cases"},
 	WorkerLearningInsightAggregate: {Module: "learning", Domain: "learning", Description: "Aggregate anonymized learning stats cross-merchant (Phase 3)"},
 	WorkerIdentityBackfill:         {Module: "identity", Domain: "system", Description: "Backfill uid, sourceIds, links cho document cũ (4 lớp identity)"},
+	WorkerApprovalExpiry:           {Module: "approval", Domain: "system", Description: "Đề xuất chờ duyệt quá hạn → auto-reject (expired) / auto-approve theo ApprovalModeConfig; gửi escalation tại mốc SLA"},
 }
 
 // GetAllWorkerMetadata trả về metadata tất cả workers (để API GET).
@@ -110,6 +114,7 @@ var defaultWorkerPriorities = map[string]Priority{
 	WorkerReportRedisTouchFlush:    PriorityNormal,
 	WorkerDelivery:                 PriorityHigh,
 	WorkerDeliveryCleanup:          PriorityLow,
+	WorkerNotificationDigest:       PriorityNormal,
 	WorkerCommandCleanup:           PriorityLow,
 	WorkerAgentCommandCleanup:      PriorityLow,
 	WorkerAgentActivityCleanup:     PriorityLow,
@@ -135,6 +140,7 @@ var defaultWorkerPriorities = map[string]Priority{
 	WorkerLearningEvaluation:       PriorityLowest,
 	WorkerLearningInsightAggregate: PriorityLowest,
 	WorkerIdentityBackfill:         PriorityLowest,
+	WorkerApprovalExpiry:           PriorityNormal,
 }
 
 // priorityOverrides override mức ưu tiên qua API (runtime). Ưu tiên cao hơn env.
@@ -169,7 +175,7 @@ func SetPriorityOverride(workerName string, priority Priority) {
 var AllWorkerNames = []string{
 	WorkerReportDirtyAds, WorkerReportDirtyOrder, WorkerReportDirtyCustomer,
 	WorkerReportRedisTouchFlush,
-	WorkerDelivery, WorkerDeliveryCleanup,
+	WorkerDelivery, WorkerDeliveryCleanup, WorkerNotificationDigest,
 	WorkerCommandCleanup, WorkerAgentCommandCleanup, WorkerAgentActivityCleanup,
 	WorkerCrmPendingMerge, WorkerCrmBulk,
 	WorkerAdsExecution, WorkerAdsAutoPropose, WorkerAdsCircuitBreaker,
@@ -187,6 +193,7 @@ var AllWorkerNames = []string{
 	WorkerLearningEvaluation,
 	WorkerLearningInsightAggregate,
 	WorkerIdentityBackfill,
+	WorkerApprovalExpiry,
 }
 
 // GetAllEffectivePriorities trả về map worker_name → priority hiệu dụng (1–5) cho tất cả workers.
@@ -3,9 +3,11 @@ package worker
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	crmqueue "meta_commerce/internal/api/aidecision/crmqueue"
 	crmmodels "meta_commerce/internal/api/crm/models"
@@ -237,6 +239,84 @@ func (w *CrmBulkWorker) processJob(ctx context.Context, svc *crmvc.CrmCustomerSe
 			"message": "Đã đưa recalculate batch vào queue AI Decision; consumer gọi RecalculateCustomersBatch (không cập nhật progress từng bước qua bulk job)",
 		}, nil
 
+	case crmmodels.CrmBulkJobDuplicateScan:
+		dupSvc, err := crmvc.NewCrmDuplicateCandidateService()
+		if err != nil {
+			return nil, err
+		}
+		result, err := dupSvc.ScanDuplicates(ctx, job.OwnerOrganizationID, crmvc.CrmDuplicateMinScore, parseInt(params, "limit", 0))
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{
+			"customersScanned": result.CustomersScanned,
+			"pairsScored":      result.PairsScored,
+			"candidates":       result.Candidates,
+		}, nil
+
+	case crmmodels.CrmBulkJobSegmentRefresh:
+		segSvc, err := crmvc.NewCrmSegmentService()
+		if err != nil {
+			return nil, err
+		}
+		segmentIDHex, _ := getString(params, "segmentId")
+		if segmentIDHex == "" {
+			refreshed, err := segSvc.RefreshOrgSegments(ctx, job.OwnerOrganizationID)
+			if err != nil {
+				return nil, err
+			}
+			return bson.M{"segmentsRefreshed": refreshed}, nil
+		}
+		segmentID, err := primitive.ObjectIDFromHex(segmentIDHex)
+		if err != nil {
+			return nil, fmt.Errorf("segmentId không hợp lệ: %w", err)
+		}
+		seg, err := segSvc.FindSegment(ctx, job.OwnerOrganizationID, segmentID)
+		if err != nil {
+			return nil, err
+		}
+		if seg.IsDisabled {
+			return bson.M{"skipped": "segment_disabled"}, nil
+		}
+		result, err := segSvc.RefreshSegment(ctx, seg)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{
+			"customersScanned": result.CustomersScanned,
+			"memberCount":      result.MemberCount,
+			"entered":          result.Entered,
+			"exited":           result.Exited,
+			"baseline":         result.Baseline,
+		}, nil
+
+	case crmmodels.CrmBulkJobDataExport, crmmodels.CrmBulkJobDataErasure:
+		privacySvc, err := crmvc.NewCrmPrivacyRequestService()
+		if err != nil {
+			return nil, err
+		}
+		requestIDHex, _ := getString(params, "requestId")
+		requestID, err := primitive.ObjectIDFromHex(requestIDHex)
+		if err != nil {
+			return nil, fmt.Errorf("requestId không hợp lệ: %w", err)
+		}
+		jobID := job.ID
+		onProgress := func(p bson.M) {
+			_ = w.bulkJobSvc.UpdateProgress(ctx, jobID, p)
+		}
+		return privacySvc.RunRequest(ctx, job.OwnerOrganizationID, requestID, job.Progress, onProgress)
+
+	case crmmodels.CrmBulkJobTagApply:
+		tagSvc, err := crmvc.NewCrmCustomerTagService()
+		if err != nil {
+			return nil, err
+		}
+		jobID := job.ID
+		onProgress := func(p bson.M) {
+			_ = w.bulkJobSvc.UpdateProgress(ctx, jobID, p)
+		}
+		return tagSvc.RunTagApply(ctx, job.OwnerOrganizationID, params, job.Progress, onProgress)
+
 	default:
 		return nil, nil
 	}
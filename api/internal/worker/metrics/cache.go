@@ -0,0 +1,52 @@
+package metrics
+
+import "sync"
+
+// CacheMetric thống kê một cache in-process (hit/miss/size).
+type CacheMetric struct {
+	Hits          int64   `json:"hits"`
+	Misses        int64   `json:"misses"`
+	Invalidations int64   `json:"invalidations"`
+	Size          int     `json:"size"`
+	HitRate       float64 `json:"hitRate"`
+	// Extra số liệu riêng của cache (vd. VM pool reused/created).
+	Extra map[string]int64 `json:"extra,omitempty"`
+}
+
+// CacheMetricProvider trả về snapshot thống kê của cache tại thời điểm gọi.
+type CacheMetricProvider func() CacheMetric
+
+var (
+	cacheProviders   = make(map[string]CacheMetricProvider)
+	cacheProvidersMu sync.RWMutex
+)
+
+// RegisterCacheMetrics đăng ký provider thống kê cache theo tên. Đăng ký lại cùng tên sẽ ghi đè.
+func RegisterCacheMetrics(name string, fn CacheMetricProvider) {
+	if name == "" || fn == nil {
+		return
+	}
+	cacheProvidersMu.Lock()
+	defer cacheProvidersMu.Unlock()
+	cacheProviders[name] = fn
+}
+
+// GetAllCacheMetrics trả về thống kê mọi cache đã đăng ký — đi cùng job metrics.
+func GetAllCacheMetrics() map[string]CacheMetric {
+	cacheProvidersMu.RLock()
+	list := make(map[string]CacheMetricProvider, len(cacheProviders))
+	for k, v := range cacheProviders {
+		list[k] = v
+	}
+	cacheProvidersMu.RUnlock()
+
+	result := make(map[string]CacheMetric, len(list))
+	for name, fn := range list {
+		m := fn()
+		if total := m.Hits + m.Misses; total > 0 {
+			m.HitRate = float64(m.Hits) / float64(total)
+		}
+		result[name] = m
+	}
+	return result
+}
@@ -0,0 +1,29 @@
+package metrics
+
+import "sync"
+
+var (
+	gauges   = make(map[string]int64)
+	gaugesMu sync.RWMutex
+)
+
+// SetGauge ghi giá trị hiện tại của một gauge (vd. delivery_dlq_depth). Ghi đè giá trị cũ.
+func SetGauge(name string, value int64) {
+	if name == "" {
+		return
+	}
+	gaugesMu.Lock()
+	gauges[name] = value
+	gaugesMu.Unlock()
+}
+
+// GetAllGauges trả về snapshot mọi gauge.
+func GetAllGauges() map[string]int64 {
+	gaugesMu.RLock()
+	defer gaugesMu.RUnlock()
+	result := make(map[string]int64, len(gauges))
+	for k, v := range gauges {
+		result[k] = v
+	}
+	return result
+}
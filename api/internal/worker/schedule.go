@@ -54,6 +54,8 @@ var defaultWorkerSchedules = map[string]struct {
 	WorkerLearningEvaluation:     {5 * time.Minute, 50}, // Batch tính evaluation cho learning_cases
 	WorkerLearningInsightAggregate: {6 * time.Hour, 1}, // Phase 3: aggregate cross-merchant (anonymized)
 	WorkerIdentityBackfill:   {10 * time.Minute, 500}, // interval 10 phút, batch 500 doc/collection
+	WorkerApprovalExpiry:     {1 * time.Minute, 100},  // sweep đề xuất chờ duyệt quá hạn / tới mốc SLA
+	WorkerNotificationDigest: {1 * time.Minute, 500},  // gom digest entry tới hạn thành bản tổng hợp
 	// report_redis_touch_flush: poll tick ~3s; flush touch trong RAM ff:rt:* → MarkDirty (chu kỳ theo REPORT_REDIS_TOUCH_*)
 	WorkerReportRedisTouchFlush: {3 * time.Second, 0},
 }
@@ -2,6 +2,9 @@
 package approval
 
 import (
+	"strconv"
+	"strings"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -14,6 +17,112 @@ type ApprovalModeConfig struct {
 	ScopeKey            string             `json:"scopeKey" bson:"scopeKey" index:"single:1;compound:approval_mode_lookup"` // adAccountId, planId, "" (default)
 	Mode                string             `json:"mode" bson:"mode"`                                                       // manual_required | auto_by_rule | fully_auto
 	ActionOverrides     map[string]string  `json:"actionOverrides,omitempty" bson:"actionOverrides,omitempty"`           // actionType -> mode
+	Policies            []ApprovalPolicy   `json:"policies,omitempty" bson:"policies,omitempty"`                         // Policy nhiều bước / quorum — policy khớp đầu tiên được áp
+	Expiry              *ExpiryPolicy      `json:"expiry,omitempty" bson:"expiry,omitempty"`                             // TTL mặc định của domain/scope
+	ActionExpiry        map[string]ExpiryPolicy `json:"actionExpiry,omitempty" bson:"actionExpiry,omitempty"`            // actionType -> TTL riêng
+}
+
+// ExpiryPolicy TTL chờ duyệt, quyết định tự động khi hết hạn và các mốc SLA escalation.
+// Ví dụ: đề xuất kill ads hết giá trị sau 3h, nhắc sau 30 phút và 2h:
+//
+//	{TTLMinutes: 180, OnExpire: "reject", EscalateAfterMinutes: [30, 120]}
+type ExpiryPolicy struct {
+	TTLMinutes           int    `json:"ttlMinutes" bson:"ttlMinutes"`                                         // 0 = không hết hạn
+	OnExpire             string `json:"onExpire,omitempty" bson:"onExpire,omitempty"`                         // reject (mặc định) | approve
+	EscalateAfterMinutes []int  `json:"escalateAfterMinutes,omitempty" bson:"escalateAfterMinutes,omitempty"` // Mốc SLA tính từ lúc propose
+}
+
+// ExpiryFor trả ExpiryPolicy cho actionType: ActionExpiry ưu tiên, fallback Expiry. nil nếu không cấu hình.
+func (c *ApprovalModeConfig) ExpiryFor(actionType string) *ExpiryPolicy {
+	if c == nil {
+		return nil
+	}
+	for k, p := range c.ActionExpiry {
+		if strings.EqualFold(k, actionType) {
+			return &p
+		}
+	}
+	return c.Expiry
+}
+
+// ApprovalPolicy yêu cầu N người duyệt và/hoặc chuỗi role duyệt theo thứ tự.
+// Ví dụ: tăng budget ads > 5tr cần media_buyer rồi manager:
+//
+//	{ActionTypes: ["INCREASE"], AmountField: "value", MinAmount: 5000000, RoleChain: ["media_buyer", "manager"]}
+type ApprovalPolicy struct {
+	Name        string   `json:"name,omitempty" bson:"name,omitempty"`
+	ActionTypes []string `json:"actionTypes,omitempty" bson:"actionTypes,omitempty"` // Rỗng = mọi actionType của domain
+	AmountField string   `json:"amountField,omitempty" bson:"amountField,omitempty"` // Key số trong payload; rỗng = không xét ngưỡng
+	MinAmount   float64  `json:"minAmount,omitempty" bson:"minAmount,omitempty"`     // Policy chỉ áp khi payload[amountField] >= minAmount
+	// RequiredApprovals số user khác nhau cần duyệt. 0 = len(RoleChain), tối thiểu 1.
+	RequiredApprovals int `json:"requiredApprovals,omitempty" bson:"requiredApprovals,omitempty"`
+	// RoleChain role phải duyệt lần lượt theo thứ tự (so theo tên role, không phân biệt hoa thường).
+	RoleChain []string `json:"roleChain,omitempty" bson:"roleChain,omitempty"`
+}
+
+// MatchPolicy trả policy đầu tiên khớp actionType + ngưỡng payload; nil nếu không có.
+func (c *ApprovalModeConfig) MatchPolicy(actionType string, payload map[string]interface{}) *ApprovalPolicy {
+	if c == nil {
+		return nil
+	}
+	for i := range c.Policies {
+		if c.Policies[i].Matches(actionType, payload) {
+			return &c.Policies[i]
+		}
+	}
+	return nil
+}
+
+// Matches kiểm tra policy có áp cho actionType + payload không.
+func (p *ApprovalPolicy) Matches(actionType string, payload map[string]interface{}) bool {
+	if len(p.ActionTypes) > 0 {
+		found := false
+		for _, a := range p.ActionTypes {
+			if strings.EqualFold(strings.TrimSpace(a), actionType) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if p.AmountField == "" {
+		return true
+	}
+	amount, ok := toFloat(payload[p.AmountField])
+	return ok && amount >= p.MinAmount
+}
+
+// Required số lượt duyệt cần theo policy.
+func (p *ApprovalPolicy) Required() int {
+	n := p.RequiredApprovals
+	if n < len(p.RoleChain) {
+		n = len(p.RoleChain)
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	}
+	return 0, false
 }
 
 // Mode constants — Approval modes theo Vision 08.
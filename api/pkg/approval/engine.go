@@ -2,7 +2,9 @@ package approval
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -26,6 +28,8 @@ var (
 	deferredDomains = make(map[string]bool)              // domain dùng queue thay vì execute ngay
 	registryMutex   sync.RWMutex
 	resolver        Resolver // ResolveImmediate: đọc config → auto Approve nếu mode=auto (Vision 08)
+	policyResolver  PolicyResolver // Policy nhiều bước / quorum từ ApprovalModeConfig.Policies
+	expiryResolver  ExpiryResolver // TTL chờ duyệt + mốc SLA escalation
 
 	// OnActionClosed callback khi action đóng vòng đời (executed/rejected/failed).
 	// closureType: executed | rejected | failed — truyền sang Learning (Phase 4).
@@ -39,6 +43,13 @@ func SetResolver(r Resolver) {
 	resolver = r
 }
 
+// SetPolicyResolver inject PolicyResolver cho duyệt nhiều bước / quorum (internal/approval gọi khi Init).
+func SetPolicyResolver(r PolicyResolver) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	policyResolver = r
+}
+
 // RegisterExecutor đăng ký executor cho domain.
 func (e *Engine) RegisterExecutor(domain string, ex Executor) {
 	registryMutex.Lock()
@@ -67,6 +78,21 @@ func (e *Engine) RegisterEventTypes(domain string, types map[string]string) {
 	eventTypes[domain] = types
 }
 
+// errNotPending đề xuất đã được quyết định (duyệt / từ chối / hết hạn) bởi request hoặc instance khác.
+var errNotPending = errors.New("đề xuất không còn pending")
+
+// claimPending ghi doc (đã đổi status) chỉ khi bản trong storage còn pending — chỉ một bên quyết định và thực thi.
+func (e *Engine) claimPending(ctx context.Context, doc *ActionPending) error {
+	claimed, err := e.storage.UpdateIfStatus(ctx, doc, StatusPending)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return errNotPending
+	}
+	return nil
+}
+
 // ProposeInput input cho Propose.
 type ProposeInput struct {
 	ActionType       string
@@ -75,6 +101,7 @@ type ProposeInput struct {
 	EventTypePending string
 	ApprovePath      string
 	RejectPath       string
+	RevertOfActionID string // Đề xuất revert: id action gốc (Engine.Revert gán)
 }
 
 // Propose thêm đề xuất vào queue.
@@ -101,19 +128,12 @@ func (e *Engine) Propose(ctx context.Context, domain string, input ProposeInput,
 		}
 	}
 
-	doc := &ActionPending{
-		Domain:              domain,
-		ActionType:          input.ActionType,
-		Reason:              input.Reason,
-		TraceID:             extractStr(input.Payload, "traceId"),
-		DecisionID:          extractStr(input.Payload, "decisionId"),
-		DecisionCaseID:      extractStr(input.Payload, "decisionCaseId"),
-		Payload:             input.Payload,
-		ProposedAt:          now,
-		Status:              StatusPending,
-		OwnerOrganizationID: ownerOrgID,
-		CreatedAt:           now,
-		UpdatedAt:           now,
+	doc := newActionPending(domain, input, ownerOrgID, now)
+	if err := e.applyPolicy(ctx, doc); err != nil {
+		return nil, err
+	}
+	if err := e.applyExpiry(ctx, doc); err != nil {
+		return nil, err
 	}
 	if err := e.storage.Insert(ctx, doc); err != nil {
 		return nil, fmt.Errorf("insert: %w", err)
@@ -124,10 +144,20 @@ func (e *Engine) Propose(ctx context.Context, domain string, input ProposeInput,
 	r := resolver
 	registryMutex.RUnlock()
 	if r != nil && r.ShouldApproveImmediately(ctx, doc) {
-		_, err := e.approveAndExecute(ctx, doc, now)
-		return doc, err
+		if res, err := e.approveAndExecute(ctx, doc, Approver{}, now); err != nil || res != doc || doc.Status != StatusPending {
+			return doc, err
+		}
+		// Policy cần người duyệt → vẫn pending, gửi notify như bình thường
 	}
 
+	e.notifyPending(ctx, doc, input, baseURL)
+	return doc, nil
+}
+
+// notifyPending gửi thông báo đề xuất chờ duyệt (kèm link approve/reject).
+func (e *Engine) notifyPending(ctx context.Context, doc *ActionPending, input ProposeInput, baseURL string) {
+	domain := doc.Domain
+	ownerOrgID := doc.OwnerOrganizationID
 	eventType := input.EventTypePending
 	if eventType == "" {
 		eventType = "approval_pending_" + domain
@@ -150,11 +180,36 @@ func (e *Engine) Propose(ctx context.Context, domain string, input ProposeInput,
 		"rejectUrl":  baseURL + rejectPath,
 		"timestamp":  time.Now().Format(time.RFC3339),
 	}
+	if doc.ExpiresAt > 0 {
+		payload["expiresAt"] = doc.ExpiresAt
+	}
+	if doc.RequiredApprovals > 0 {
+		payload["requiredApprovals"] = doc.RequiredApprovals
+		payload["requiredRoles"] = doc.RequiredRoles
+	}
 	for k, v := range doc.Payload {
 		payload[k] = v
 	}
 	_, _ = e.notifier.Notify(ctx, eventType, payload, ownerOrgID, baseURL)
-	return doc, nil
+}
+
+// newActionPending tạo doc pending từ ProposeInput.
+func newActionPending(domain string, input ProposeInput, ownerOrgID primitive.ObjectID, now int64) *ActionPending {
+	return &ActionPending{
+		Domain:              domain,
+		ActionType:          input.ActionType,
+		Reason:              input.Reason,
+		TraceID:             extractStr(input.Payload, "traceId"),
+		DecisionID:          extractStr(input.Payload, "decisionId"),
+		DecisionCaseID:      extractStr(input.Payload, "decisionCaseId"),
+		Payload:             input.Payload,
+		ProposedAt:          now,
+		Status:              StatusPending,
+		RevertOfActionID:    input.RevertOfActionID,
+		OwnerOrganizationID: ownerOrgID,
+		CreatedAt:           now,
+		UpdatedAt:           now,
+	}
 }
 
 // ProposeAndApproveAuto tạo proposal và approve ngay (cho action auto).
@@ -171,30 +226,129 @@ func (e *Engine) ProposeAndApproveAuto(ctx context.Context, domain string, input
 			return existing, nil
 		}
 	}
-	doc := &ActionPending{
-		Domain:              domain,
-		ActionType:          input.ActionType,
-		Reason:              input.Reason,
-		TraceID:             extractStr(input.Payload, "traceId"),
-		DecisionID:          extractStr(input.Payload, "decisionId"),
-		DecisionCaseID:      extractStr(input.Payload, "decisionCaseId"),
-		Payload:             input.Payload,
-		ProposedAt:          now,
-		Status:              StatusPending,
-		OwnerOrganizationID: ownerOrgID,
-		CreatedAt:           now,
-		UpdatedAt:           now,
+	doc := newActionPending(domain, input, ownerOrgID, now)
+	if err := e.applyPolicy(ctx, doc); err != nil {
+		return nil, err
+	}
+	if err := e.applyExpiry(ctx, doc); err != nil {
+		return nil, err
 	}
 	if err := e.storage.Insert(ctx, doc); err != nil {
 		return nil, fmt.Errorf("insert: %w", err)
 	}
 	// Không gửi notify pending — chạy approve logic ngay
-	return e.approveAndExecute(ctx, doc, now)
+	result, err := e.approveAndExecute(ctx, doc, Approver{}, now)
+	if err == nil && result == doc && doc.Status == StatusPending {
+		// Policy cần người duyệt → không auto được, chuyển sang luồng chờ duyệt
+		e.notifyPending(ctx, result, input, "")
+	}
+	return result, err
+}
+
+// applyPolicy gắn yêu cầu duyệt (RequiredApprovals, RequiredRoles) từ PolicyResolver vào doc trước khi insert.
+// Snapshot lúc propose — đổi config sau đó không ảnh hưởng đề xuất đang chờ.
+func (e *Engine) applyPolicy(ctx context.Context, doc *ActionPending) error {
+	registryMutex.RLock()
+	pr := policyResolver
+	registryMutex.RUnlock()
+	if pr == nil {
+		return nil
+	}
+	policy, err := pr.ResolvePolicy(ctx, doc)
+	if err != nil {
+		return fmt.Errorf("approval policy: %w", err)
+	}
+	if policy == nil {
+		return nil
+	}
+	doc.RequiredApprovals = policy.Required()
+	doc.RequiredRoles = append([]string(nil), policy.RoleChain...)
+	return nil
+}
+
+// recordApproval ghi quyết định duyệt vào doc và kiểm tra policy.
+// Trả true khi đủ điều kiện thực thi; false khi còn chờ thêm người duyệt.
+// Hệ thống (approver.UserID rỗng) không tính vào quorum — đề xuất có policy luôn cần người duyệt.
+func recordApproval(doc *ActionPending, approver Approver, note string, now int64) (bool, error) {
+	if approver.UserID == "" {
+		if doc.RequiredApprovals > 0 {
+			return false, nil
+		}
+		doc.Approvals = append(doc.Approvals, ApprovalDecision{UserID: ApproverSystem, Decision: DecisionApprove, DecidedAt: now})
+		doc.ApprovedBy = ApproverSystem
+		return true, nil
+	}
+	approved := approvedDecisions(doc)
+	for _, d := range approved {
+		if d.UserID == approver.UserID {
+			return false, fmt.Errorf("user %s đã duyệt đề xuất này", approver.UserID)
+		}
+	}
+	if step := len(approved); step < len(doc.RequiredRoles) {
+		if want := doc.RequiredRoles[step]; !strings.EqualFold(strings.TrimSpace(want), strings.TrimSpace(approver.Role)) {
+			return false, fmt.Errorf("bước duyệt %d cần role %s, role hiện tại: %s", step+1, want, approver.Role)
+		}
+	}
+	doc.Approvals = append(doc.Approvals, ApprovalDecision{
+		UserID: approver.UserID, Role: approver.Role, RoleID: approver.RoleID,
+		Decision: DecisionApprove, Note: note, DecidedAt: now,
+	})
+	if len(approved)+1 < doc.RequiredApprovals {
+		return false, nil
+	}
+	doc.ApprovedBy = approver.UserID
+	return true, nil
+}
+
+// approvedDecisions các lượt duyệt của người (bỏ system, bỏ reject).
+func approvedDecisions(doc *ActionPending) []ApprovalDecision {
+	var out []ApprovalDecision
+	for _, d := range doc.Approvals {
+		if d.Decision == DecisionApprove && d.UserID != ApproverSystem {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// pushApprovalAttempts số lần thử lại khi lượt duyệt đồng thời làm đổi approvals giữa lúc đọc và ghi.
+const pushApprovalAttempts = 3
+
+// pushApproval ghi lượt duyệt của người bằng $push có điều kiện (pending, chưa duyệt, đúng số lượt đã đọc)
+// rồi đọc lại doc để đánh giá policy trên bản mới nhất — người duyệt đồng thời không ghi đè nhau.
+func (e *Engine) pushApproval(ctx context.Context, doc *ActionPending, approver Approver, now int64) (*ActionPending, bool, error) {
+	for attempt := 0; attempt < pushApprovalAttempts; attempt++ {
+		expected := len(doc.Approvals)
+		if _, err := recordApproval(doc, approver, "", now); err != nil {
+			return nil, false, err
+		}
+		pushed, err := e.storage.PushApproval(ctx, doc.ID, doc.Approvals[expected], expected, now)
+		if err != nil {
+			return nil, false, err
+		}
+		fresh, err := e.storage.FindById(ctx, doc.ID, doc.OwnerOrganizationID)
+		if err != nil {
+			return nil, false, err
+		}
+		if fresh.Status != StatusPending {
+			return nil, false, errNotPending
+		}
+		if pushed {
+			ready := len(approvedDecisions(fresh)) >= fresh.RequiredApprovals
+			if ready {
+				fresh.ApprovedBy = approver.UserID
+			}
+			return fresh, ready, nil
+		}
+		doc = fresh
+	}
+	return nil, false, fmt.Errorf("đề xuất đang được duyệt đồng thời, thử lại sau")
 }
 
 // approveAndExecute chạy logic approve + execute cho doc đã insert.
 // Idempotency (Vision 08): nếu payload.idempotencyKey đã xử lý → skip, trả doc cũ.
-func (e *Engine) approveAndExecute(ctx context.Context, doc *ActionPending, now int64) (*ActionPending, error) {
+// Policy nhiều bước / quorum: chưa đủ người duyệt → lưu lượt duyệt, doc giữ status=pending.
+func (e *Engine) approveAndExecute(ctx context.Context, doc *ActionPending, approver Approver, now int64) (*ActionPending, error) {
 	idempotencyKey := ""
 	if doc.Payload != nil {
 		if s, ok := doc.Payload["idempotencyKey"].(string); ok && s != "" {
@@ -208,21 +362,56 @@ func (e *Engine) approveAndExecute(ctx context.Context, doc *ActionPending, now
 		}
 	}
 
+	var ready bool
+	var err error
+	if approver.UserID == "" {
+		if ready, err = recordApproval(doc, approver, "", now); err != nil {
+			return nil, err
+		}
+		if !ready {
+			return doc, nil
+		}
+	} else {
+		if doc, ready, err = e.pushApproval(ctx, doc, approver, now); err != nil {
+			return nil, err
+		}
+	}
+	if !ready {
+		if et := e.getEventType(doc.Domain, "approval_step"); et != "" {
+			p := map[string]interface{}{
+				"actionId": doc.ID.Hex(), "actionType": doc.ActionType,
+				"approvedBy": approver.UserID, "approverRole": approver.Role,
+				"approvalCount": len(approvedDecisions(doc)), "requiredApprovals": doc.RequiredApprovals,
+				"timestamp": time.Now().Format(time.RFC3339),
+			}
+			for k, v := range doc.Payload {
+				p[k] = v
+			}
+			_, _ = e.notifier.Notify(ctx, et, p, doc.OwnerOrganizationID, "")
+		}
+		return doc, nil
+	}
+
 	doc.ApprovedAt = now
 	doc.UpdatedAt = now
 
+	// Chiếm đề xuất (pending → queued / approved) trước khi thực thi: Approve đồng thời hoặc sweep hết hạn
+	// trên instance khác không thực thi lần hai.
 	if isDeferredExecutionDomain(doc.Domain) {
 		doc.Status = StatusQueued
 		doc.RetryCount = 0
 		doc.MaxRetries = MaxRetriesDefault
 		doc.NextRetryAt = nil
-		if err := e.storage.Update(ctx, doc); err != nil {
+		if err := e.claimPending(ctx, doc); err != nil {
 			return nil, err
 		}
 		return doc, nil
 	}
 
 	doc.Status = StatusApproved
+	if err := e.claimPending(ctx, doc); err != nil {
+		return nil, err
+	}
 	registryMutex.RLock()
 	ex := executors[doc.Domain]
 	registryMutex.RUnlock()
@@ -235,7 +424,7 @@ func (e *Engine) approveAndExecute(ctx context.Context, doc *ActionPending, now
 			doc.ExecuteResponse = map[string]interface{}{"error": execErr.Error()}
 		} else {
 			doc.Status = StatusExecuted
-			doc.ExecuteResponse = resp
+			doc.ExecuteResponse = CaptureCompensation(doc, resp)
 		}
 	} else {
 		doc.Status = StatusExecuted
@@ -263,8 +452,9 @@ func (e *Engine) approveAndExecute(ctx context.Context, doc *ActionPending, now
 	return doc, nil
 }
 
-// Approve duyệt đề xuất.
-func (e *Engine) Approve(ctx context.Context, actionId string, ownerOrgID primitive.ObjectID) (*ActionPending, error) {
+// Approve ghi lượt duyệt của approver. Đề xuất có policy nhiều bước chỉ thực thi khi đủ quorum / chuỗi role;
+// trước đó trả doc status=pending kèm Approvals.
+func (e *Engine) Approve(ctx context.Context, actionId string, ownerOrgID primitive.ObjectID, approver Approver) (*ActionPending, error) {
 	oid, err := primitive.ObjectIDFromHex(actionId)
 	if err != nil {
 		return nil, fmt.Errorf("actionId không hợp lệ")
@@ -276,12 +466,18 @@ func (e *Engine) Approve(ctx context.Context, actionId string, ownerOrgID primit
 	if doc.Status != StatusPending {
 		return nil, fmt.Errorf("đề xuất không còn pending: %s", doc.Status)
 	}
+	if approver.UserID == "" {
+		return nil, fmt.Errorf("thiếu thông tin người duyệt")
+	}
 	now := time.Now().UnixMilli()
-	return e.approveAndExecute(ctx, doc, now)
+	if doc.ExpiresAt > 0 && doc.ExpiresAt <= now {
+		return nil, fmt.Errorf("đề xuất đã hết hạn chờ duyệt")
+	}
+	return e.approveAndExecute(ctx, doc, approver, now)
 }
 
-// Reject từ chối đề xuất.
-func (e *Engine) Reject(ctx context.Context, actionId string, ownerOrgID primitive.ObjectID, decisionNote, rejectedBy string) (*ActionPending, error) {
+// Reject từ chối đề xuất. Một lượt từ chối đóng đề xuất dù policy cần nhiều người duyệt.
+func (e *Engine) Reject(ctx context.Context, actionId string, ownerOrgID primitive.ObjectID, decisionNote string, rejectedBy Approver) (*ActionPending, error) {
 	oid, err := primitive.ObjectIDFromHex(actionId)
 	if err != nil {
 		return nil, fmt.Errorf("actionId không hợp lệ")
@@ -296,10 +492,14 @@ func (e *Engine) Reject(ctx context.Context, actionId string, ownerOrgID primiti
 	now := time.Now().UnixMilli()
 	doc.Status = StatusRejected
 	doc.RejectedAt = now
-	doc.RejectedBy = rejectedBy
+	doc.RejectedBy = rejectedBy.UserID
 	doc.DecisionNote = decisionNote
+	doc.Approvals = append(doc.Approvals, ApprovalDecision{
+		UserID: rejectedBy.UserID, Role: rejectedBy.Role, RoleID: rejectedBy.RoleID,
+		Decision: DecisionReject, Note: decisionNote, DecidedAt: now,
+	})
 	doc.UpdatedAt = now
-	if err := e.storage.Update(ctx, doc); err != nil {
+	if err := e.claimPending(ctx, doc); err != nil {
 		return nil, err
 	}
 	if et := e.getEventType(doc.Domain, "rejected"); et != "" {
@@ -369,7 +569,7 @@ func (e *Engine) Cancel(ctx context.Context, actionId string, ownerOrgID primiti
 	now := time.Now().UnixMilli()
 	doc.Status = StatusCancelled
 	doc.UpdatedAt = now
-	if err := e.storage.Update(ctx, doc); err != nil {
+	if err := e.claimPending(ctx, doc); err != nil {
 		return nil, fmt.Errorf("cập nhật: %w", err)
 	}
 	if et := e.getEventType(doc.Domain, "cancelled"); et != "" {
@@ -465,7 +665,7 @@ func (e *Engine) ExecuteOne(ctx context.Context, actionId string, ownerOrgID pri
 	resp, execErr := ex.Execute(ctx, doc)
 	if execErr == nil {
 		doc.Status = StatusExecuted
-		doc.ExecuteResponse = resp
+		doc.ExecuteResponse = CaptureCompensation(doc, resp)
 		doc.ExecutedAt = now
 		doc.ExecuteError = ""
 		doc.NextRetryAt = nil
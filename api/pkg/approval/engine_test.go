@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -22,16 +23,23 @@ func newMockStorage() *mockStorage {
 	}
 }
 
+// copyDoc bản sao như đọc lại từ DB — engine sửa doc trong bộ nhớ không làm đổi bản đã lưu.
+func copyDoc(doc *ActionPending) *ActionPending {
+	cp := *doc
+	cp.Approvals = append([]ApprovalDecision(nil), doc.Approvals...)
+	return &cp
+}
+
 func (m *mockStorage) Insert(ctx context.Context, doc *ActionPending) error {
 	if doc.ID.IsZero() {
 		doc.ID = primitive.NewObjectID()
 	}
-	m.docs[doc.ID.Hex()] = doc
+	m.docs[doc.ID.Hex()] = copyDoc(doc)
 	return nil
 }
 
 func (m *mockStorage) Update(ctx context.Context, doc *ActionPending) error {
-	m.docs[doc.ID.Hex()] = doc
+	m.docs[doc.ID.Hex()] = copyDoc(doc)
 	if doc.Status == StatusExecuted || doc.Status == StatusRejected || doc.Status == StatusFailed {
 		if doc.Payload != nil {
 			if idk, ok := doc.Payload["idempotencyKey"].(string); ok && idk != "" {
@@ -42,12 +50,34 @@ func (m *mockStorage) Update(ctx context.Context, doc *ActionPending) error {
 	return nil
 }
 
+func (m *mockStorage) UpdateIfStatus(ctx context.Context, doc *ActionPending, fromStatus string) (bool, error) {
+	if d, ok := m.docs[doc.ID.Hex()]; !ok || d.Status != fromStatus {
+		return false, nil
+	}
+	return true, m.Update(ctx, doc)
+}
+
+func (m *mockStorage) PushApproval(ctx context.Context, id primitive.ObjectID, decision ApprovalDecision, expectedCount int, now int64) (bool, error) {
+	d, ok := m.docs[id.Hex()]
+	if !ok || d.Status != StatusPending || len(d.Approvals) != expectedCount {
+		return false, nil
+	}
+	for _, a := range d.Approvals {
+		if a.UserID == decision.UserID {
+			return false, nil
+		}
+	}
+	d.Approvals = append(d.Approvals, decision)
+	d.UpdatedAt = now
+	return true, nil
+}
+
 func (m *mockStorage) FindById(ctx context.Context, id primitive.ObjectID, ownerOrgID primitive.ObjectID) (*ActionPending, error) {
 	d, ok := m.docs[id.Hex()]
 	if !ok {
 		return nil, fmt.Errorf("không tìm thấy")
 	}
-	return d, nil
+	return copyDoc(d), nil
 }
 
 func (m *mockStorage) FindByIdempotencyKey(ctx context.Context, idempotencyKey string, ownerOrgID primitive.ObjectID) (*ActionPending, error) {
@@ -73,10 +103,26 @@ func (m *mockStorage) Count(ctx context.Context, ownerOrgID primitive.ObjectID,
 	return 0, nil
 }
 
-// mockNotifier không gửi gì.
-type mockNotifier struct{}
+func (m *mockStorage) FindDueForSweep(ctx context.Context, now int64, limit int) ([]ActionPending, error) {
+	var out []ActionPending
+	for _, d := range m.docs {
+		if d.Status != StatusPending {
+			continue
+		}
+		if (d.ExpiresAt > 0 && d.ExpiresAt <= now) || (d.NextEscalationAt > 0 && d.NextEscalationAt <= now) {
+			out = append(out, *d)
+		}
+	}
+	return out, nil
+}
+
+// mockNotifier không gửi gì, chỉ ghi lại eventType.
+type mockNotifier struct {
+	events []string
+}
 
 func (m *mockNotifier) Notify(ctx context.Context, eventType string, payload map[string]interface{}, ownerOrgID primitive.ObjectID, baseURL string) (int, error) {
+	m.events = append(m.events, eventType)
 	return 0, nil
 }
 
@@ -187,3 +233,183 @@ func TestExecuteOne_IdempotencySkip_WhenAlreadyProcessed(t *testing.T) {
 		t.Errorf("ExecuteOne phải trả doc đã xử lý (idempotency skip), got id %s", result.ID.Hex())
 	}
 }
+
+// budgetPolicy policy test: INCREASE >= 5tr cần media_buyer rồi manager.
+var budgetPolicy = ApprovalModeConfig{Policies: []ApprovalPolicy{{
+	ActionTypes: []string{"INCREASE"}, AmountField: "value", MinAmount: 5000000,
+	RoleChain: []string{"media_buyer", "manager"},
+}}}
+
+func newPolicyEngine(t *testing.T, executed *int) (*Engine, *mockStorage) {
+	t.Helper()
+	SetPolicyResolver(PolicyResolverFunc(func(ctx context.Context, doc *ActionPending) (*ApprovalPolicy, error) {
+		return budgetPolicy.MatchPolicy(doc.ActionType, doc.Payload), nil
+	}))
+	t.Cleanup(func() { SetPolicyResolver(nil) })
+	storage := newMockStorage()
+	engine := NewEngine(storage, &mockNotifier{})
+	engine.RegisterExecutor("ads", ExecutorFunc(func(ctx context.Context, doc *ActionPending) (map[string]interface{}, error) {
+		*executed++
+		return map[string]interface{}{"ok": true}, nil
+	}))
+	return engine, storage
+}
+
+func TestApprovalPolicy_Matches(t *testing.T) {
+	p := &budgetPolicy.Policies[0]
+	if !p.Matches("increase", map[string]interface{}{"value": 6000000}) {
+		t.Error("INCREASE 6tr phải khớp policy")
+	}
+	if p.Matches("INCREASE", map[string]interface{}{"value": "1000000"}) {
+		t.Error("INCREASE 1tr không được khớp policy")
+	}
+	if p.Matches("KILL", map[string]interface{}{"value": 6000000}) {
+		t.Error("KILL không được khớp policy")
+	}
+	if got := (&ApprovalPolicy{RequiredApprovals: 3, RoleChain: []string{"a"}}).Required(); got != 3 {
+		t.Errorf("Required = %d, muốn 3", got)
+	}
+	if got := (&ApprovalPolicy{}).Required(); got != 1 {
+		t.Errorf("Required mặc định = %d, muốn 1", got)
+	}
+}
+
+func TestApprove_RoleChainEnforcedBeforeExecute(t *testing.T) {
+	ctx := context.Background()
+	executed := 0
+	engine, _ := newPolicyEngine(t, &executed)
+	ownerID := primitive.NewObjectID()
+
+	doc, err := engine.Propose(ctx, "ads", ProposeInput{
+		ActionType: "INCREASE",
+		Payload:    map[string]interface{}{"adAccountId": "act_1", "campaignId": "c_1", "value": 8000000},
+	}, ownerID, "")
+	if err != nil {
+		t.Fatalf("Propose lỗi: %v", err)
+	}
+	if doc.RequiredApprovals != 2 || len(doc.RequiredRoles) != 2 {
+		t.Fatalf("policy chưa gắn vào doc: %+v", doc)
+	}
+
+	// Manager duyệt trước media buyer → sai thứ tự
+	if _, err := engine.Approve(ctx, doc.ID.Hex(), ownerID, Approver{UserID: "u_manager", Role: "manager"}); err == nil {
+		t.Fatal("phải lỗi khi manager duyệt trước media_buyer")
+	}
+
+	res, err := engine.Approve(ctx, doc.ID.Hex(), ownerID, Approver{UserID: "u_buyer", Role: "Media_Buyer"})
+	if err != nil {
+		t.Fatalf("Approve bước 1 lỗi: %v", err)
+	}
+	if res.Status != StatusPending || executed != 0 {
+		t.Fatalf("sau bước 1 phải còn pending và chưa execute, status=%s executed=%d", res.Status, executed)
+	}
+
+	// Cùng user không được duyệt hai lần
+	if _, err := engine.Approve(ctx, doc.ID.Hex(), ownerID, Approver{UserID: "u_buyer", Role: "manager"}); err == nil {
+		t.Fatal("phải lỗi khi cùng user duyệt lần hai")
+	}
+
+	res, err = engine.Approve(ctx, doc.ID.Hex(), ownerID, Approver{UserID: "u_manager", Role: "manager", RoleID: "r_1"})
+	if err != nil {
+		t.Fatalf("Approve bước 2 lỗi: %v", err)
+	}
+	if res.Status != StatusExecuted || executed != 1 {
+		t.Fatalf("đủ chuỗi role phải execute, status=%s executed=%d", res.Status, executed)
+	}
+	if res.ApprovedBy != "u_manager" || len(res.Approvals) != 2 {
+		t.Errorf("approvals không đúng: approvedBy=%s approvals=%+v", res.ApprovedBy, res.Approvals)
+	}
+	if a := res.Approvals[1]; a.Role != "manager" || a.RoleID != "r_1" || a.Decision != DecisionApprove || a.DecidedAt == 0 {
+		t.Errorf("decision thiếu thông tin: %+v", a)
+	}
+}
+
+func TestPropose_AutoModeStillRequiresPolicyApprovers(t *testing.T) {
+	ctx := context.Background()
+	executed := 0
+	engine, _ := newPolicyEngine(t, &executed)
+	SetResolver(ResolverFunc(func(ctx context.Context, doc *ActionPending) bool { return true }))
+	t.Cleanup(func() { SetResolver(nil) })
+	ownerID := primitive.NewObjectID()
+
+	doc, err := engine.Propose(ctx, "ads", ProposeInput{
+		ActionType: "INCREASE",
+		Payload:    map[string]interface{}{"adAccountId": "act_1", "campaignId": "c_1", "value": 8000000},
+	}, ownerID, "")
+	if err != nil {
+		t.Fatalf("Propose lỗi: %v", err)
+	}
+	if doc.Status != StatusPending || executed != 0 {
+		t.Fatalf("auto mode không được bỏ qua policy, status=%s executed=%d", doc.Status, executed)
+	}
+
+	// Dưới ngưỡng → auto như cũ, ghi nhận system
+	small, err := engine.Propose(ctx, "ads", ProposeInput{
+		ActionType: "INCREASE",
+		Payload:    map[string]interface{}{"adAccountId": "act_1", "campaignId": "c_1", "value": 100000},
+	}, ownerID, "")
+	if err != nil {
+		t.Fatalf("Propose lỗi: %v", err)
+	}
+	if small.Status != StatusExecuted || small.ApprovedBy != ApproverSystem {
+		t.Errorf("dưới ngưỡng phải auto execute bởi system, status=%s approvedBy=%s", small.Status, small.ApprovedBy)
+	}
+}
+
+func TestReject_RecordsDecision(t *testing.T) {
+	ctx := context.Background()
+	executed := 0
+	engine, _ := newPolicyEngine(t, &executed)
+	ownerID := primitive.NewObjectID()
+
+	doc, err := engine.Propose(ctx, "ads", ProposeInput{
+		ActionType: "INCREASE",
+		Payload:    map[string]interface{}{"adAccountId": "act_1", "campaignId": "c_1", "value": 8000000},
+	}, ownerID, "")
+	if err != nil {
+		t.Fatalf("Propose lỗi: %v", err)
+	}
+	if _, err := engine.Approve(ctx, doc.ID.Hex(), ownerID, Approver{UserID: "u_buyer", Role: "media_buyer"}); err != nil {
+		t.Fatalf("Approve lỗi: %v", err)
+	}
+	res, err := engine.Reject(ctx, doc.ID.Hex(), ownerID, "vượt ngân sách", Approver{UserID: "u_manager", Role: "manager"})
+	if err != nil {
+		t.Fatalf("Reject lỗi: %v", err)
+	}
+	if res.Status != StatusRejected || res.RejectedBy != "u_manager" || executed != 0 {
+		t.Fatalf("reject sai: %+v", res)
+	}
+	last := res.Approvals[len(res.Approvals)-1]
+	if last.Decision != DecisionReject || last.Role != "manager" || last.Note != "vượt ngân sách" {
+		t.Errorf("decision reject không đúng: %+v", last)
+	}
+}
+
+func TestApprove_ConcurrentApproversBothRecorded(t *testing.T) {
+	ctx := context.Background()
+	executed := 0
+	engine, _ := newExpiryEngine(t, nil, &executed)
+	SetPolicyResolver(PolicyResolverFunc(func(ctx context.Context, doc *ActionPending) (*ApprovalPolicy, error) {
+		return &ApprovalPolicy{RequiredApprovals: 2}, nil
+	}))
+	t.Cleanup(func() { SetPolicyResolver(nil) })
+	ownerID := primitive.NewObjectID()
+	doc := proposeKill(t, engine, ownerID)
+
+	// Hai người duyệt cùng đọc doc chưa có lượt duyệt nào
+	stale := reload(t, engine, doc)
+	if _, err := engine.Approve(ctx, doc.ID.Hex(), ownerID, Approver{UserID: "u1"}); err != nil {
+		t.Fatalf("Approve u1 lỗi: %v", err)
+	}
+	res, err := engine.approveAndExecute(ctx, stale, Approver{UserID: "u2"}, time.Now().UnixMilli())
+	if err != nil {
+		t.Fatalf("Approve u2 lỗi: %v", err)
+	}
+	if res.Status != StatusExecuted || executed != 1 {
+		t.Fatalf("đủ quorum phải execute một lần, status=%s executed=%d", res.Status, executed)
+	}
+	got := reload(t, engine, doc)
+	if len(approvedDecisions(got)) != 2 || got.ApprovedBy != "u2" {
+		t.Errorf("lượt duyệt bị ghi đè: approvedBy=%s approvals=%+v", got.ApprovedBy, got.Approvals)
+	}
+}
@@ -0,0 +1,187 @@
+// Package approval — Hết hạn chờ duyệt, SLA escalation và quyết định tự động khi hết hạn.
+package approval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// SetExpiryResolver inject ExpiryResolver cho TTL / SLA escalation (internal/approval gọi khi Init).
+func SetExpiryResolver(r ExpiryResolver) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	expiryResolver = r
+}
+
+// applyExpiry gắn ExpiresAt, ExpireAction, mốc SLA từ ExpiryResolver vào doc trước khi insert.
+func (e *Engine) applyExpiry(ctx context.Context, doc *ActionPending) error {
+	registryMutex.RLock()
+	er := expiryResolver
+	registryMutex.RUnlock()
+	if er == nil {
+		return nil
+	}
+	policy, err := er.ResolveExpiry(ctx, doc)
+	if err != nil {
+		return fmt.Errorf("approval expiry: %w", err)
+	}
+	if policy == nil {
+		return nil
+	}
+	if policy.TTLMinutes > 0 {
+		doc.ExpiresAt = doc.ProposedAt + int64(policy.TTLMinutes)*int64(time.Minute/time.Millisecond)
+		doc.ExpireAction = ExpireActionReject
+		if policy.OnExpire == ExpireActionApprove {
+			doc.ExpireAction = ExpireActionApprove
+		}
+	}
+	mins := append([]int(nil), policy.EscalateAfterMinutes...)
+	sort.Ints(mins)
+	for _, m := range mins {
+		if m <= 0 {
+			continue
+		}
+		at := doc.ProposedAt + int64(m)*int64(time.Minute/time.Millisecond)
+		if doc.ExpiresAt > 0 && at >= doc.ExpiresAt {
+			break
+		}
+		if n := len(doc.EscalationAt); n > 0 && doc.EscalationAt[n-1] == at {
+			continue
+		}
+		doc.EscalationAt = append(doc.EscalationAt, at)
+	}
+	if len(doc.EscalationAt) > 0 {
+		doc.NextEscalationAt = doc.EscalationAt[0]
+	}
+	return nil
+}
+
+// SweepResult thống kê một lượt sweep.
+type SweepResult struct {
+	Expired      int `json:"expired"`
+	AutoApproved int `json:"autoApproved"`
+	Escalated    int `json:"escalated"`
+	Errors       int `json:"errors"`
+}
+
+// Sweep xử lý đề xuất pending tới hạn: hết hạn → auto-reject (status=expired) hoặc auto-approve theo policy;
+// tới mốc SLA → gửi escalation. Worker gọi định kỳ.
+func (e *Engine) Sweep(ctx context.Context, now int64, limit int) (SweepResult, error) {
+	var res SweepResult
+	list, err := e.storage.FindDueForSweep(ctx, now, limit)
+	if err != nil {
+		return res, err
+	}
+	for i := range list {
+		doc := &list[i]
+		if doc.Status != StatusPending {
+			continue
+		}
+		if doc.ExpiresAt > 0 && doc.ExpiresAt <= now {
+			approved, err := e.expire(ctx, doc, now)
+			switch {
+			case errors.Is(err, errNotPending):
+				// Đã được duyệt / từ chối đồng thời, hoặc instance khác sweep trước — bỏ qua
+			case err != nil:
+				res.Errors++
+			case approved:
+				res.AutoApproved++
+			default:
+				res.Expired++
+			}
+			continue
+		}
+		if doc.NextEscalationAt > 0 && doc.NextEscalationAt <= now {
+			switch err := e.escalate(ctx, doc, now); {
+			case errors.Is(err, errNotPending):
+			case err != nil:
+				res.Errors++
+			default:
+				res.Escalated++
+			}
+		}
+	}
+	return res, nil
+}
+
+// expire quyết định tự động khi hết hạn. Trả true khi đã auto-approve.
+// ExpireAction=approve nhưng policy nhiều bước cần người duyệt → không auto-approve được, chuyển expired.
+// Ghi có điều kiện status=pending — đề xuất vừa được quyết định ở nơi khác → errNotPending, không thực thi / notify.
+func (e *Engine) expire(ctx context.Context, doc *ActionPending, now int64) (bool, error) {
+	doc.ExpiredAt = now
+	doc.NextEscalationAt = 0
+	if doc.ExpireAction == ExpireActionApprove && doc.RequiredApprovals == 0 {
+		doc.DecisionNote = "Tự động duyệt khi hết hạn chờ duyệt"
+		if _, err := e.approveAndExecute(ctx, doc, Approver{}, now); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	doc.Status = StatusExpired
+	doc.RejectedAt = now
+	doc.RejectedBy = ApproverSystem
+	doc.DecisionNote = "Hết hạn chờ duyệt"
+	doc.UpdatedAt = now
+	doc.Approvals = append(doc.Approvals, ApprovalDecision{
+		UserID: ApproverSystem, Decision: DecisionExpire, Note: doc.DecisionNote, DecidedAt: now,
+	})
+	if err := e.claimPending(ctx, doc); err != nil {
+		return false, err
+	}
+	if et := e.getEventType(doc.Domain, "expired"); et != "" {
+		p := map[string]interface{}{
+			"actionId": doc.ID.Hex(), "actionType": doc.ActionType,
+			"proposedAt": doc.ProposedAt, "expiredAt": doc.ExpiredAt,
+			"timestamp": time.Now().Format(time.RFC3339),
+		}
+		for k, v := range doc.Payload {
+			p[k] = v
+		}
+		_, _ = e.notifier.Notify(ctx, et, p, doc.OwnerOrganizationID, "")
+	}
+	if OnActionClosed != nil {
+		OnActionClosed(ctx, doc.Domain, doc, StatusExpired)
+	}
+	return false, nil
+}
+
+// escalate gửi notify escalation cho mốc SLA hiện tại rồi chuyển sang mốc kế tiếp (chỉ khi đề xuất còn pending).
+// EventType: đăng ký "escalation" qua RegisterEventTypes, mặc định approval_escalation_<domain>.
+func (e *Engine) escalate(ctx context.Context, doc *ActionPending, now int64) error {
+	doc.EscalationLevel++
+	doc.NextEscalationAt = 0
+	for _, at := range doc.EscalationAt {
+		if at > now {
+			doc.NextEscalationAt = at
+			break
+		}
+	}
+	doc.UpdatedAt = now
+	claimed, err := e.storage.UpdateIfStatus(ctx, doc, StatusPending)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return errNotPending
+	}
+	eventType := e.getEventType(doc.Domain, "escalation")
+	if eventType == "" {
+		eventType = "approval_escalation_" + doc.Domain
+	}
+	p := map[string]interface{}{
+		"actionId": doc.ID.Hex(), "domain": doc.Domain, "actionType": doc.ActionType, "reason": doc.Reason,
+		"proposedAt": doc.ProposedAt, "expiresAt": doc.ExpiresAt,
+		"escalationLevel": doc.EscalationLevel, "pendingMinutes": (now - doc.ProposedAt) / int64(time.Minute/time.Millisecond),
+		"approvalCount": len(approvedDecisions(doc)), "requiredApprovals": doc.RequiredApprovals,
+		"timestamp": time.Now().Format(time.RFC3339),
+	}
+	for k, v := range doc.Payload {
+		p[k] = v
+	}
+	_, _ = e.notifier.Notify(ctx, eventType, p, doc.OwnerOrganizationID, "")
+	return nil
+}
@@ -0,0 +1,142 @@
+// Package approval — Unit test cho hết hạn chờ duyệt, SLA escalation và auto-decision.
+package approval
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const minuteMs = int64(time.Minute / time.Millisecond)
+
+func newExpiryEngine(t *testing.T, policy *ExpiryPolicy, executed *int) (*Engine, *mockNotifier) {
+	t.Helper()
+	SetExpiryResolver(ExpiryResolverFunc(func(ctx context.Context, doc *ActionPending) (*ExpiryPolicy, error) {
+		return policy, nil
+	}))
+	t.Cleanup(func() { SetExpiryResolver(nil) })
+	notifier := &mockNotifier{}
+	engine := NewEngine(newMockStorage(), notifier)
+	engine.RegisterExecutor("ads", ExecutorFunc(func(ctx context.Context, doc *ActionPending) (map[string]interface{}, error) {
+		*executed++
+		return map[string]interface{}{"ok": true}, nil
+	}))
+	return engine, notifier
+}
+
+func reload(t *testing.T, engine *Engine, doc *ActionPending) *ActionPending {
+	t.Helper()
+	got, err := engine.FindById(context.Background(), doc.ID.Hex(), doc.OwnerOrganizationID)
+	if err != nil {
+		t.Fatalf("FindById lỗi: %v", err)
+	}
+	return got
+}
+
+func proposeKill(t *testing.T, engine *Engine, ownerID primitive.ObjectID) *ActionPending {
+	t.Helper()
+	doc, err := engine.Propose(context.Background(), "ads", ProposeInput{
+		ActionType: "KILL",
+		Payload:    map[string]interface{}{"adAccountId": "act_1"},
+	}, ownerID, "")
+	if err != nil {
+		t.Fatalf("Propose lỗi: %v", err)
+	}
+	return doc
+}
+
+func TestSweep_EscalatesThenExpires(t *testing.T) {
+	ctx := context.Background()
+	executed := 0
+	engine, notifier := newExpiryEngine(t, &ExpiryPolicy{TTLMinutes: 180, EscalateAfterMinutes: []int{120, 30, 240}}, &executed)
+	ownerID := primitive.NewObjectID()
+	doc := proposeKill(t, engine, ownerID)
+
+	if doc.ExpiresAt != doc.ProposedAt+180*minuteMs || doc.ExpireAction != ExpireActionReject {
+		t.Fatalf("expiry chưa gắn: expiresAt=%d action=%s", doc.ExpiresAt, doc.ExpireAction)
+	}
+	// Mốc 240 phút sau hạn → bỏ; còn 30, 120 theo thứ tự
+	if len(doc.EscalationAt) != 2 || doc.NextEscalationAt != doc.ProposedAt+30*minuteMs {
+		t.Fatalf("mốc SLA sai: %v next=%d", doc.EscalationAt, doc.NextEscalationAt)
+	}
+
+	res, err := engine.Sweep(ctx, doc.ProposedAt+31*minuteMs, 10)
+	if err != nil || res.Escalated != 1 {
+		t.Fatalf("sweep 1: res=%+v err=%v", res, err)
+	}
+	doc = reload(t, engine, doc)
+	if doc.EscalationLevel != 1 || doc.NextEscalationAt != doc.ProposedAt+120*minuteMs {
+		t.Fatalf("sau escalation 1: level=%d next=%d", doc.EscalationLevel, doc.NextEscalationAt)
+	}
+	if last := notifier.events[len(notifier.events)-1]; last != "approval_escalation_ads" {
+		t.Errorf("event escalation = %s", last)
+	}
+
+	res, _ = engine.Sweep(ctx, doc.ProposedAt+181*minuteMs, 10)
+	doc = reload(t, engine, doc)
+	if res.Expired != 1 || doc.Status != StatusExpired || doc.ExpiredAt == 0 || executed != 0 {
+		t.Fatalf("phải expired: res=%+v status=%s", res, doc.Status)
+	}
+	if last := doc.Approvals[len(doc.Approvals)-1]; last.Decision != DecisionExpire || last.UserID != ApproverSystem {
+		t.Errorf("decision expire sai: %+v", last)
+	}
+	if _, err := engine.Approve(ctx, doc.ID.Hex(), ownerID, Approver{UserID: "u_1"}); err == nil {
+		t.Error("không được duyệt đề xuất đã expired")
+	}
+}
+
+func TestSweep_AutoApproveOnExpiry(t *testing.T) {
+	executed := 0
+	engine, _ := newExpiryEngine(t, &ExpiryPolicy{TTLMinutes: 60, OnExpire: ExpireActionApprove}, &executed)
+	doc := proposeKill(t, engine, primitive.NewObjectID())
+
+	res, err := engine.Sweep(context.Background(), doc.ProposedAt+61*minuteMs, 10)
+	if err != nil || res.AutoApproved != 1 {
+		t.Fatalf("res=%+v err=%v", res, err)
+	}
+	doc = reload(t, engine, doc)
+	if doc.Status != StatusExecuted || executed != 1 || doc.ApprovedBy != ApproverSystem || doc.ExpiredAt == 0 {
+		t.Errorf("auto-approve sai: status=%s executed=%d approvedBy=%s", doc.Status, executed, doc.ApprovedBy)
+	}
+}
+
+func TestSweep_AutoApproveBlockedByPolicy(t *testing.T) {
+	executed := 0
+	engine, _ := newExpiryEngine(t, &ExpiryPolicy{TTLMinutes: 60, OnExpire: ExpireActionApprove}, &executed)
+	SetPolicyResolver(PolicyResolverFunc(func(ctx context.Context, doc *ActionPending) (*ApprovalPolicy, error) {
+		return &ApprovalPolicy{RequiredApprovals: 2}, nil
+	}))
+	t.Cleanup(func() { SetPolicyResolver(nil) })
+	doc := proposeKill(t, engine, primitive.NewObjectID())
+
+	res, _ := engine.Sweep(context.Background(), doc.ProposedAt+61*minuteMs, 10)
+	doc = reload(t, engine, doc)
+	if res.Expired != 1 || doc.Status != StatusExpired || executed != 0 {
+		t.Errorf("policy cần người duyệt → phải expired, res=%+v status=%s", res, doc.Status)
+	}
+}
+
+func TestExpire_LosesClaimToConcurrentApprove(t *testing.T) {
+	ctx := context.Background()
+	executed := 0
+	engine, notifier := newExpiryEngine(t, &ExpiryPolicy{TTLMinutes: 60}, &executed)
+	doc := proposeKill(t, engine, primitive.NewObjectID())
+	// Sweep đã đọc doc pending, rồi người duyệt Approve trước khi sweep ghi
+	stale := reload(t, engine, doc)
+	if _, err := engine.Approve(ctx, doc.ID.Hex(), doc.OwnerOrganizationID, Approver{UserID: "u1"}); err != nil {
+		t.Fatalf("Approve lỗi: %v", err)
+	}
+	events := len(notifier.events)
+
+	if _, err := engine.expire(ctx, stale, doc.ProposedAt+61*minuteMs); err != errNotPending {
+		t.Fatalf("expire phải thua claim, err=%v", err)
+	}
+	if executed != 1 || len(notifier.events) != events {
+		t.Errorf("chỉ được thực thi một lần: executed=%d events=%v", executed, notifier.events[events:])
+	}
+	if got := reload(t, engine, doc); got.Status != StatusExecuted || got.ApprovedBy != "u1" {
+		t.Errorf("kết quả Approve bị ghi đè: status=%s approvedBy=%s", got.Status, got.ApprovedBy)
+	}
+}
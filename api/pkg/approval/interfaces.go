@@ -27,6 +27,12 @@ type FindWithPaginationFilter struct {
 type Storage interface {
 	Insert(ctx context.Context, doc *ActionPending) error
 	Update(ctx context.Context, doc *ActionPending) error
+	// UpdateIfStatus cập nhật như Update nhưng chỉ khi status hiện tại = fromStatus (compare-and-set).
+	// Trả false khi không khớp — request / instance khác đã quyết định trước.
+	UpdateIfStatus(ctx context.Context, doc *ActionPending, fromStatus string) (bool, error)
+	// PushApproval thêm một lượt duyệt ($push) khi đề xuất còn pending, approver chưa duyệt và
+	// approvals đang có đúng expectedCount phần tử (version). Trả false khi điều kiện không khớp.
+	PushApproval(ctx context.Context, id primitive.ObjectID, decision ApprovalDecision, expectedCount int, now int64) (bool, error)
 	FindById(ctx context.Context, id primitive.ObjectID, ownerOrgID primitive.ObjectID) (*ActionPending, error)
 	FindPending(ctx context.Context, ownerOrgID primitive.ObjectID, domain string, limit int) ([]ActionPending, error)
 	// FindQueued danh sách item status=queued để worker xử lý (filter nextRetryAt null hoặc <= now).
@@ -39,6 +45,8 @@ type Storage interface {
 	Count(ctx context.Context, ownerOrgID primitive.ObjectID, domain, status string, fromProposedAt, toProposedAt int64) (int64, error)
 	// FindByIdempotencyKey tìm action đã xử lý (executed/rejected/failed) theo idempotencyKey trong payload.
 	FindByIdempotencyKey(ctx context.Context, idempotencyKey string, ownerOrgID primitive.ObjectID) (*ActionPending, error)
+	// FindDueForSweep item status=pending (mọi org) đã tới hạn: expiresAt <= now hoặc nextEscalationAt <= now.
+	FindDueForSweep(ctx context.Context, now int64, limit int) ([]ActionPending, error)
 }
 
 // Notifier gửi thông báo. App cung cấp implementation (notifytrigger).
@@ -64,6 +72,32 @@ func (f ResolverFunc) ShouldApproveImmediately(ctx context.Context, doc *ActionP
 	return f(ctx, doc)
 }
 
+// PolicyResolver tìm ApprovalPolicy áp cho action (nhiều bước / quorum). nil = một lượt duyệt là đủ.
+// App inject implementation (internal/approval) — đọc ApprovalModeConfig.Policies.
+type PolicyResolver interface {
+	ResolvePolicy(ctx context.Context, doc *ActionPending) (*ApprovalPolicy, error)
+}
+
+// PolicyResolverFunc adapter cho function.
+type PolicyResolverFunc func(ctx context.Context, doc *ActionPending) (*ApprovalPolicy, error)
+
+func (f PolicyResolverFunc) ResolvePolicy(ctx context.Context, doc *ActionPending) (*ApprovalPolicy, error) {
+	return f(ctx, doc)
+}
+
+// ExpiryResolver tìm ExpiryPolicy (TTL, auto-decision, mốc SLA) cho action. nil = không hết hạn.
+// App inject implementation (internal/approval) — đọc ApprovalModeConfig.Expiry / ActionExpiry.
+type ExpiryResolver interface {
+	ResolveExpiry(ctx context.Context, doc *ActionPending) (*ExpiryPolicy, error)
+}
+
+// ExpiryResolverFunc adapter cho function.
+type ExpiryResolverFunc func(ctx context.Context, doc *ActionPending) (*ExpiryPolicy, error)
+
+func (f ExpiryResolverFunc) ResolveExpiry(ctx context.Context, doc *ActionPending) (*ExpiryPolicy, error) {
+	return f(ctx, doc)
+}
+
 // ExecutorFunc adapter cho function.
 type ExecutorFunc func(ctx context.Context, doc *ActionPending) (map[string]interface{}, error)
 
@@ -0,0 +1,144 @@
+// Package approval — Revert (compensation): action nghịch đảo cho action đã thực thi.
+package approval
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CaptureCompensation tách Compensation (nếu executor trả) khỏi response, gắn vào doc.
+// Engine gọi sau Execute; worker tự thực thi (deferred domain) cũng phải gọi trước khi lưu kết quả.
+func CaptureCompensation(doc *ActionPending, resp map[string]interface{}) map[string]interface{} {
+	if resp == nil {
+		return resp
+	}
+	raw, ok := resp[ResponseKeyCompensation]
+	if !ok {
+		return resp
+	}
+	delete(resp, ResponseKeyCompensation)
+	switch c := raw.(type) {
+	case *Compensation:
+		if c != nil && c.ActionType != "" {
+			doc.Compensation = c
+		}
+	case Compensation:
+		if c.ActionType != "" {
+			doc.Compensation = &c
+		}
+	}
+	return resp
+}
+
+// RevertInput tham số revert.
+type RevertInput struct {
+	Reason  string
+	Execute bool // true = người yêu cầu duyệt luôn (vẫn qua policy nhiều bước); false = propose chờ duyệt
+}
+
+// Revert tạo action nghịch đảo từ Compensation của action đã thực thi và liên kết hai chiều
+// (RevertOfActionID trên action mới, RevertedByActionID trên action gốc).
+func (e *Engine) Revert(ctx context.Context, actionId string, ownerOrgID primitive.ObjectID, requester Approver, input RevertInput, baseURL string) (*ActionPending, error) {
+	original, err := e.FindById(ctx, actionId, ownerOrgID)
+	if err != nil {
+		return nil, err
+	}
+	if original.Status != StatusExecuted {
+		return nil, fmt.Errorf("chỉ revert được đề xuất đã thực thi (status=executed), hiện tại: %s", original.Status)
+	}
+	if original.Compensation == nil || original.Compensation.ActionType == "" {
+		return nil, fmt.Errorf("action %s không hỗ trợ revert (executor không ghi nhận trạng thái trước)", original.ActionType)
+	}
+	if original.RevertedByActionID != "" {
+		if prev, err := e.FindById(ctx, original.RevertedByActionID, ownerOrgID); err == nil && prev != nil && !isRevertRetryable(prev.Status) {
+			return nil, fmt.Errorf("action đã có revert %s (status=%s)", prev.ID.Hex(), prev.Status)
+		}
+	}
+	if input.Execute && requester.UserID == "" {
+		return nil, fmt.Errorf("thiếu thông tin người duyệt")
+	}
+
+	now := time.Now().UnixMilli()
+	payload := make(map[string]interface{}, len(original.Compensation.Payload)+2)
+	for k, v := range original.Compensation.Payload {
+		payload[k] = v
+	}
+	payload["revertOfActionId"] = original.ID.Hex()
+	// Mỗi lần revert một key mới: revert trước bị từ chối/hết hạn không được chặn lần thử lại (trùng ms).
+	payload["idempotencyKey"] = "revert:" + original.ID.Hex() + ":" + primitive.NewObjectID().Hex()
+	reason := input.Reason
+	if reason == "" {
+		reason = "Revert " + original.ActionType + " (" + original.ID.Hex() + ")"
+		if original.Compensation.Description != "" {
+			reason += ": " + original.Compensation.Description
+		}
+	}
+	proposeInput := ProposeInput{
+		ActionType:       original.Compensation.ActionType,
+		Reason:           reason,
+		Payload:          payload,
+		RevertOfActionID: original.ID.Hex(),
+	}
+
+	var revert *ActionPending
+	if input.Execute {
+		revert, err = e.proposeAndApprove(ctx, original.Domain, proposeInput, ownerOrgID, requester, baseURL)
+	} else {
+		revert, err = e.Propose(ctx, original.Domain, proposeInput, ownerOrgID, baseURL)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	original.RevertedByActionID = revert.ID.Hex()
+	original.UpdatedAt = now
+	if err := e.storage.Update(ctx, original); err != nil {
+		return revert, fmt.Errorf("liên kết action gốc: %w", err)
+	}
+	return revert, nil
+}
+
+// proposeAndApprove tạo đề xuất và ghi lượt duyệt của approver ngay (policy vẫn áp dụng).
+// Chưa đủ quorum → giữ pending và notify như Propose.
+func (e *Engine) proposeAndApprove(ctx context.Context, domain string, input ProposeInput, ownerOrgID primitive.ObjectID, approver Approver, baseURL string) (*ActionPending, error) {
+	registryMutex.RLock()
+	_, hasExecutor := executors[domain]
+	registryMutex.RUnlock()
+	if !hasExecutor {
+		return nil, fmt.Errorf("domain %s chưa đăng ký executor", domain)
+	}
+	if err := validatePayload(domain, input.ActionType, input.Payload); err != nil {
+		return nil, fmt.Errorf("validation: %w", err)
+	}
+	now := time.Now().UnixMilli()
+	doc := newActionPending(domain, input, ownerOrgID, now)
+	if err := e.applyPolicy(ctx, doc); err != nil {
+		return nil, err
+	}
+	if err := e.applyExpiry(ctx, doc); err != nil {
+		return nil, err
+	}
+	if err := e.storage.Insert(ctx, doc); err != nil {
+		return nil, fmt.Errorf("insert: %w", err)
+	}
+	result, err := e.approveAndExecute(ctx, doc, approver, now)
+	if err != nil {
+		return nil, err
+	}
+	if result == doc && doc.Status == StatusPending {
+		e.notifyPending(ctx, doc, input, baseURL)
+	}
+	return result, nil
+}
+
+// isRevertRetryable revert trước đó đã đóng mà không thực thi → cho phép revert lại.
+func isRevertRetryable(status string) bool {
+	switch status {
+	case StatusRejected, StatusCancelled, StatusExpired, StatusFailed:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+// Package approval — Unit test cho compensation và revert action đã thực thi.
+package approval
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newRevertEngine executor PAUSE trả compensation RESUME; RESUME không trả compensation.
+func newRevertEngine(t *testing.T, executed *[]string) *Engine {
+	t.Helper()
+	engine := NewEngine(newMockStorage(), &mockNotifier{})
+	engine.RegisterExecutor("ads", ExecutorFunc(func(ctx context.Context, doc *ActionPending) (map[string]interface{}, error) {
+		*executed = append(*executed, doc.ActionType)
+		resp := map[string]interface{}{"ok": true}
+		if doc.ActionType == "PAUSE" {
+			resp[ResponseKeyCompensation] = &Compensation{
+				ActionType:    "RESUME",
+				Payload:       map[string]interface{}{"adAccountId": doc.Payload["adAccountId"], "campaignId": doc.Payload["campaignId"]},
+				PreviousState: map[string]interface{}{"status": "ACTIVE"},
+			}
+		}
+		return resp, nil
+	}))
+	return engine
+}
+
+func executePause(t *testing.T, engine *Engine, ownerID primitive.ObjectID) *ActionPending {
+	t.Helper()
+	ctx := context.Background()
+	doc, err := engine.Propose(ctx, "ads", ProposeInput{
+		ActionType: "PAUSE",
+		Payload:    map[string]interface{}{"adAccountId": "act_1", "campaignId": "c_1"},
+	}, ownerID, "")
+	if err != nil {
+		t.Fatalf("Propose lỗi: %v", err)
+	}
+	doc, err = engine.Approve(ctx, doc.ID.Hex(), ownerID, Approver{UserID: "u1"})
+	if err != nil {
+		t.Fatalf("Approve lỗi: %v", err)
+	}
+	if doc.Status != StatusExecuted {
+		t.Fatalf("status = %s, muốn executed", doc.Status)
+	}
+	return doc
+}
+
+func TestCaptureCompensation(t *testing.T) {
+	doc := &ActionPending{}
+	resp := CaptureCompensation(doc, map[string]interface{}{
+		"ok":                    true,
+		ResponseKeyCompensation: Compensation{ActionType: "SET_BUDGET", Payload: map[string]interface{}{"value": int64(100000)}},
+	})
+	if _, ok := resp[ResponseKeyCompensation]; ok {
+		t.Error("compensation phải được tách khỏi response")
+	}
+	if doc.Compensation == nil || doc.Compensation.ActionType != "SET_BUDGET" {
+		t.Fatalf("compensation = %+v", doc.Compensation)
+	}
+
+	doc = &ActionPending{}
+	CaptureCompensation(doc, map[string]interface{}{ResponseKeyCompensation: &Compensation{}})
+	if doc.Compensation != nil {
+		t.Error("compensation thiếu actionType phải bị bỏ qua")
+	}
+}
+
+func TestRevert_ProposeLinksOriginal(t *testing.T) {
+	ctx := context.Background()
+	var executed []string
+	engine := newRevertEngine(t, &executed)
+	ownerID := primitive.NewObjectID()
+
+	original := executePause(t, engine, ownerID)
+	if original.Compensation == nil {
+		t.Fatal("action PAUSE phải lưu compensation")
+	}
+	if _, ok := original.ExecuteResponse[ResponseKeyCompensation]; ok {
+		t.Error("executeResponse không được chứa compensation")
+	}
+
+	revert, err := engine.Revert(ctx, original.ID.Hex(), ownerID, Approver{UserID: "u1"}, RevertInput{}, "")
+	if err != nil {
+		t.Fatalf("Revert lỗi: %v", err)
+	}
+	if revert.Status != StatusPending || revert.ActionType != "RESUME" {
+		t.Fatalf("revert = %s/%s, muốn RESUME/pending", revert.ActionType, revert.Status)
+	}
+	if revert.RevertOfActionID != original.ID.Hex() || revert.Payload["campaignId"] != "c_1" {
+		t.Errorf("revert không trỏ về action gốc: %+v", revert)
+	}
+	if got := reload(t, engine, original); got.RevertedByActionID != revert.ID.Hex() {
+		t.Errorf("revertedByActionId = %q, muốn %q", got.RevertedByActionID, revert.ID.Hex())
+	}
+	if len(executed) != 1 {
+		t.Errorf("revert chưa duyệt không được thực thi, executed = %v", executed)
+	}
+
+	if _, err := engine.Revert(ctx, original.ID.Hex(), ownerID, Approver{UserID: "u1"}, RevertInput{}, ""); err == nil {
+		t.Error("revert lần hai khi revert trước còn pending phải lỗi")
+	}
+}
+
+func TestRevert_ExecuteAndRetryAfterReject(t *testing.T) {
+	ctx := context.Background()
+	var executed []string
+	engine := newRevertEngine(t, &executed)
+	ownerID := primitive.NewObjectID()
+	original := executePause(t, engine, ownerID)
+
+	first, err := engine.Revert(ctx, original.ID.Hex(), ownerID, Approver{UserID: "u1"}, RevertInput{}, "")
+	if err != nil {
+		t.Fatalf("Revert lỗi: %v", err)
+	}
+	if _, err := engine.Reject(ctx, first.ID.Hex(), ownerID, "không cần", Approver{UserID: "u2"}); err != nil {
+		t.Fatalf("Reject lỗi: %v", err)
+	}
+
+	second, err := engine.Revert(ctx, original.ID.Hex(), ownerID, Approver{UserID: "u1"}, RevertInput{Execute: true}, "")
+	if err != nil {
+		t.Fatalf("Revert sau khi revert trước bị từ chối phải được phép: %v", err)
+	}
+	if second.Status != StatusExecuted {
+		t.Fatalf("status = %s, muốn executed", second.Status)
+	}
+	if len(executed) != 2 || executed[1] != "RESUME" {
+		t.Errorf("executed = %v, muốn [PAUSE RESUME]", executed)
+	}
+	if got := reload(t, engine, original); got.RevertedByActionID != second.ID.Hex() {
+		t.Errorf("revertedByActionId = %q, muốn %q", got.RevertedByActionID, second.ID.Hex())
+	}
+
+	if _, err := engine.Revert(ctx, second.ID.Hex(), ownerID, Approver{UserID: "u1"}, RevertInput{}, ""); err == nil {
+		t.Error("action không có compensation phải không revert được")
+	}
+}
@@ -21,6 +21,19 @@ type ActionPending struct {
 	RejectedAt           int64                  `json:"rejectedAt,omitempty" bson:"rejectedAt,omitempty"`
 	RejectedBy           string                 `json:"rejectedBy,omitempty" bson:"rejectedBy,omitempty"`
 	DecisionNote         string                 `json:"decisionNote,omitempty" bson:"decisionNote,omitempty"`
+	ApprovedBy           string                 `json:"approvedBy,omitempty" bson:"approvedBy,omitempty"`       // User duyệt bước cuối (đủ quorum)
+	Approvals            []ApprovalDecision     `json:"approvals,omitempty" bson:"approvals,omitempty"`         // Lịch sử quyết định duyệt/từ chối theo thứ tự
+	RequiredApprovals    int                    `json:"requiredApprovals,omitempty" bson:"requiredApprovals,omitempty"` // Số lượt duyệt cần theo policy (0 = không áp policy)
+	RequiredRoles        []string               `json:"requiredRoles,omitempty" bson:"requiredRoles,omitempty"` // Chuỗi role cần duyệt theo thứ tự (policy)
+	ExpiresAt            int64                  `json:"expiresAt,omitempty" bson:"expiresAt,omitempty" index:"single:1"` // Hết hạn chờ duyệt (Unix ms), 0 = không hết hạn
+	ExpireAction         string                 `json:"expireAction,omitempty" bson:"expireAction,omitempty"`             // reject | approve — quyết định tự động khi hết hạn
+	ExpiredAt            int64                  `json:"expiredAt,omitempty" bson:"expiredAt,omitempty"`
+	EscalationAt         []int64                `json:"escalationAt,omitempty" bson:"escalationAt,omitempty"`             // Mốc SLA gửi escalation (Unix ms, tăng dần)
+	EscalationLevel      int                    `json:"escalationLevel,omitempty" bson:"escalationLevel,omitempty"`       // Số mốc SLA đã gửi escalation
+	NextEscalationAt     int64                  `json:"nextEscalationAt,omitempty" bson:"nextEscalationAt,omitempty" index:"single:1"` // Mốc SLA kế tiếp, 0 = hết mốc
+	Compensation         *Compensation          `json:"compensation,omitempty" bson:"compensation,omitempty"`             // Action bù trừ (trạng thái trước khi thực thi) — executor trả về
+	RevertOfActionID     string                 `json:"revertOfActionId,omitempty" bson:"revertOfActionId,omitempty"`     // Action gốc mà action này revert
+	RevertedByActionID   string                 `json:"revertedByActionId,omitempty" bson:"revertedByActionId,omitempty"` // Action revert gần nhất của action này
 	ExecutedAt           int64                  `json:"executedAt,omitempty" bson:"executedAt,omitempty"`
 	ExecuteResponse      map[string]interface{} `json:"executeResponse,omitempty" bson:"executeResponse,omitempty"`
 	ExecuteError         string                 `json:"executeError,omitempty" bson:"executeError,omitempty"`
@@ -32,6 +45,47 @@ type ActionPending struct {
 	UpdatedAt            int64                  `json:"updatedAt" bson:"updatedAt"`
 }
 
+// Compensation action nghịch đảo để revert một action đã thực thi.
+// Executor trả qua ExecuteResponse[ResponseKeyCompensation]; engine chuyển vào ActionPending.Compensation.
+type Compensation struct {
+	ActionType    string                 `json:"actionType" bson:"actionType"`
+	Payload       map[string]interface{} `json:"payload" bson:"payload"`
+	PreviousState map[string]interface{} `json:"previousState,omitempty" bson:"previousState,omitempty"` // Trạng thái trước khi thực thi (hiển thị / audit)
+	Description   string                 `json:"description,omitempty" bson:"description,omitempty"`
+}
+
+// ResponseKeyCompensation key trong response của Executor chứa *Compensation (tuỳ chọn).
+const ResponseKeyCompensation = "compensation"
+
+// ApprovalDecision một quyết định duyệt/từ chối: ai, role nào, lúc nào.
+type ApprovalDecision struct {
+	UserID    string `json:"userId" bson:"userId"`
+	Role      string `json:"role,omitempty" bson:"role,omitempty"`     // Tên role (dùng so với RoleChain)
+	RoleID    string `json:"roleId,omitempty" bson:"roleId,omitempty"` // active_role_id lúc quyết định
+	Decision  string `json:"decision" bson:"decision"`                 // approve | reject
+	Note      string `json:"note,omitempty" bson:"note,omitempty"`
+	DecidedAt int64  `json:"decidedAt" bson:"decidedAt"`
+}
+
+// Approver người ra quyết định. UserID rỗng = hệ thống (auto-approve).
+type Approver struct {
+	UserID string
+	Role   string
+	RoleID string
+}
+
+const (
+	DecisionApprove = "approve"
+	DecisionReject  = "reject"
+	DecisionExpire  = "expire"
+
+	ExpireActionReject  = "reject"
+	ExpireActionApprove = "approve"
+
+	// ApproverSystem UserID ghi nhận khi hệ thống tự duyệt (ResolveImmediate, ProposeAndApproveAuto).
+	ApproverSystem = "system"
+)
+
 const (
 	StatusPending  = "pending"
 	StatusApproved = "approved"
@@ -40,6 +94,7 @@ const (
 	StatusExecuted = "executed"
 	StatusFailed   = "failed"
 	StatusCancelled = "cancelled" // User hủy đề xuất trước khi duyệt
+	StatusExpired   = "expired"   // Quá hạn chờ duyệt — tự động từ chối (hoặc không auto-approve được)
 )
 
 // MaxRetriesDefault số lần retry mặc định cho domain dùng queue.